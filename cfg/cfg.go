@@ -1,28 +1,955 @@
 package cfg
 
 import (
+	"fmt"
+	"github.com/BurntSushi/toml"
 	log "github.com/Sirupsen/logrus"
 	"github.com/mitchellh/go-homedir"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// config.yaml or config.toml (see Load for the list of locations)
+type Config struct {
+	// index URL(s) (tried in order)
+	Registry StringList `yaml:"registry"`
+	// release providers to consult by default
+	Providers StringList `yaml:"providers"`
+	// vendor (e.g. "temurin") versions that don't specify one (e.g. `jabba install 21`) are resolved within
+	DefaultVendor string `yaml:"default_vendor"`
+	// directory to keep downloaded archives in
+	CacheDir string `yaml:"cache_dir"`
+	// true to keep downloaded archives in $JABBA_HOME/cache (unless cache_dir is set)
+	KeepDownloads bool  `yaml:"keep_downloads"`
+	Offline       *bool `yaml:"offline"`
+	// named sets of environment variables / PATH entries that can be applied on top of any JDK
+	// (e.g. `jabba use 1.17 --profile debug`)
+	Profiles map[string]Profile `yaml:"profiles"`
+	// max total size of installed JDKs (e.g. "2G") (see `jabba size-budget`)
+	SizeBudget string `yaml:"size_budget"`
+	// how long to wait for other jabba processes (installing / uninstalling JDKs, etc.) to finish (e.g. "30s", "5m")
+	LockTimeout string `yaml:"lock_timeout"`
+	// PEM file(s) with root certificates to trust in addition to the system ones (e.g. corporate MITM proxy CA)
+	CACert StringList `yaml:"cacert"`
+	// proxy URL to use when neither HTTPS_PROXY nor HTTP_PROXY is set (NO_PROXY is honored)
+	Proxy string `yaml:"proxy"`
+	// "true" (~/.m2/settings.xml) or path to Maven settings.xml to take proxy (unless "proxy" is set) and credentials
+	// of mirrors (Authorization is sent with requests to URLs under mirror's <url>) from
+	MavenSettings string `yaml:"maven_settings"`
+	// credentials to send with requests to URLs under the specified prefixes (e.g. Artifactory / Nexus repositories
+	// JDK archives are mirrored to) (entries of all the config files add up)
+	Auth []AuthEntry `yaml:"auth"`
+	// HTTP timeouts (e.g. "10s", "30m", 0 to disable): establishing connection (TCP + TLS handshake),
+	// waiting for data (response headers / next chunk of the body) and the whole request (download included)
+	ConnectTimeout string `yaml:"connect_timeout"`
+	ReadTimeout    string `yaml:"read_timeout"`
+	Timeout        string `yaml:"timeout"`
+	// record installs & activations (timestamp, selector, version, user) in $JABBA_HOME/history.log
+	// (see `jabba history`)
+	History *bool `yaml:"history"`
+	// how long history entries are kept for (e.g. "90d", "2160h"), forever by default
+	HistoryRetention string `yaml:"history_retention"`
+	// how to let user know (at most once a week) that new LTS line of Java is available ("stderr" or
+	// "cmd:<command>" (run with JABBA_LTS_MAJOR & JABBA_LTS_VERSION set)), nothing by default
+	LTSNotify StringList `yaml:"lts_notify"`
+	// LTS lines (e.g. 25) not to notify about
+	LTSNotifyIgnore StringList `yaml:"lts_notify_ignore"`
+	// false to stop `jabba use` / `jabba current` from warning (at most once a day per JDK) that JDK is past end of
+	// support or has been superseded by a newer release of the line
+	EOLWarnings *bool `yaml:"eol_warnings"`
+	// URL (or file://) of vendor end-of-support dates (same format as the built-in ones (see `jabba outdated`))
+	// to use on top of the built-in ones (fetched by `jabba outdated` at most once a week)
+	EOLData string `yaml:"eol_data"`
+	// false to disable `jabba self-update` & `jabba version --check` (e.g. when jabba is provisioned by other means)
+	SelfUpdate *bool `yaml:"self_update"`
+	// directory JDKs are installed into (e.g. on a secondary disk or a shared network mount), relative paths are
+	// resolved against jabba home
+	JDKDir string `yaml:"jdk_dir"`
+	// "true" (or "auto") to deduplicate files of installed JDKs against content-addressed store (cloning them (APFS,
+	// btrfs, xfs) or, if file system doesn't support it, hard-linking), "clone" / "hardlink" to use one method only
+	// (see `jabba dedupe` & `jabba gc`)
+	Dedupe string `yaml:"dedupe"`
+	// true to generate CDS archive (`java -Xshare:dump`) of every JDK once it's installed (see `jabba cds regenerate`)
+	CDS bool `yaml:"cds"`
+	// true to verify checksum embedded into jabba binary (see `jabba verify-self`) every time jabba starts
+	VerifySelf bool `yaml:"verify_self"`
+	// architecture to install / list JDKs for unless specified otherwise (e.g. "amd64" to always go through
+	// Rosetta 2 on Apple Silicon)
+	Arch string `yaml:"arch"`
+	// default --output of the commands that support it ("plain" or "json")
+	Output string `yaml:"output"`
+	// stable path `jabba link system <selector>` points at the JDK (/usr/local/opt/jabba-default by default)
+	SystemLink string `yaml:"system_link"`
+	// command (run through sh -c / cmd /C) that is given JSON describing JDK that is about to be uninstalled (on stdin)
+	// and can veto the removal (by exiting with non-zero code or printing {"allow": false, "reason": "..."})
+	UninstallPolicy string `yaml:"uninstall_policy"`
+	// true to make `jabba use` install JDK if there is none matching the selector (same as `jabba use --install`)
+	AutoInstall bool `yaml:"auto_install"`
+	// what to do with every JDK once it's installed (before it's moved into place)
+	PostInstall PostInstallHooks `yaml:"post_install"`
+	// CA certificates Java processes should trust (e.g. the one of TLS-intercepting proxy) without importing them into
+	// cacerts of every JDK
+	Truststore TruststoreConfig `yaml:"truststore"`
+	// named jabba homes `jabba home use` switches between (name -> path (e.g. "work: ~/work/.jabba"))
+	Homes map[string]string `yaml:"homes"`
+	// files (glob patterns) with aliases (e.g. maintained by the team / pushed by MDM) (unlike other lists, alias
+	// files of all the config files add up)
+	AliasFiles StringList `yaml:"alias_files"`
+	// keys that cannot be overridden by the config files loaded after this one, environment variables or flags
+	// (meant to be used in machine config (e.g. to enforce mirrors on CI runners))
+	Locked StringList `yaml:"locked"`
+}
+
+type AuthEntry struct {
+	// URL prefix (e.g. "https://artifactory.example.com/artifactory/jdk/") (the longest matching prefix wins)
+	URL string `yaml:"url"`
+	// "Authorization: Bearer <token>" (e.g. Artifactory access token)
+	Token string `yaml:"token"`
+	// HTTP basic auth
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// any other header (e.g. "X-JFrog-Art-Api: <api key>")
+	Header string `yaml:"header"`
+}
+
+type Profile struct {
+	// values can reference other variables (e.g. $JAVA_HOME (of the JDK being activated))
+	Env map[string]string `yaml:"env"`
+	// directories to prepend to PATH
+	Path StringList `yaml:"path"`
+}
+
+type PostInstallHooks struct {
+	// PEM file(s) with CA certificates (e.g. corporate root CA) to import into JDK's cacerts (with keytool)
+	CACerts StringList `yaml:"cacerts"`
+	// file with java.security properties (e.g. jdk.tls.disabledAlgorithms) to set in JDK's java.security
+	JavaSecurity string `yaml:"java_security"`
+	// command (run through sh -c / cmd /C) with JAVA_HOME & JABBA_VERSION of the JDK set
+	Run string `yaml:"run"`
+}
+
+// IsEmpty returns true if there is nothing to do.
+func (h PostInstallHooks) IsEmpty() bool {
+	return len(h.CACerts) == 0 && h.JavaSecurity == "" && h.Run == ""
+}
+
+type TruststoreConfig struct {
+	// PEM file(s) with CA certificates to add to (a copy of) JDK's cacerts, `jabba use`, `jabba exec` & `jabba env`
+	// point javax.net.ssl.trustStore at
+	CACerts StringList `yaml:"cacerts"`
+	// variable to pass -Djavax.net.ssl.trustStore through ("JAVA_TOOL_OPTIONS" (default) or "JDK_JAVA_OPTIONS")
+	Env string `yaml:"env"`
+}
+
+// StringList can be specified either as a single value or as a list.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err == nil {
+		*l = StringList{value}
+		return nil
+	}
+	var values []string
+	if err := unmarshal(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+var config *Config
+
+var registryOverride []string
+
+var offline bool
+
+var cacertOverride []string
+
+var insecure bool
+
+var timeoutOverride = make(map[string]time.Duration)
+
+// Dir returns jabba home, which is $JABBA_HOME, ~/.jabba or (if user has no (usable) home directory,
+// e.g. when running as a system service or under an arbitrary UID in a container) $XDG_DATA_HOME/jabba.
 func Dir() string {
 	home := os.Getenv("JABBA_HOME")
 	if home != "" {
 		return filepath.Clean(home)
 	}
+	return DefaultDir()
+}
+
+// DefaultDir returns jabba home to use when JABBA_HOME is not set.
+func DefaultDir() string {
+	dir, err := userHomeDir()
+	if err == nil {
+		return filepath.Join(dir, ".jabba")
+	}
+	if xdgDataHome := os.Getenv("XDG_DATA_HOME"); xdgDataHome != "" {
+		return filepath.Join(filepath.Clean(xdgDataHome), "jabba")
+	}
+	log.Fatal("Unable to locate jabba home (" + err.Error() + ").\n" +
+		"Set JABBA_HOME to a writable directory (e.g. \"export JABBA_HOME=/opt/jabba\")")
+	return ""
+}
+
+// JDKDir returns directory JDKs (and links to them (1.8 -> 1.8.0, default, system@..., etc.)) are kept in,
+// which is $JABBA_JDK_DIR, "jdk_dir" in config.yaml or $JABBA_HOME/jdk (default).
+func JDKDir() string {
+	value := os.Getenv("JABBA_JDK_DIR")
+	if value == "" || isLocked("jdk_dir", "JABBA_JDK_DIR", value) {
+		value = Load().JDKDir
+	}
+	if value == "" {
+		return filepath.Join(Dir(), "jdk")
+	}
+	if !filepath.IsAbs(value) {
+		return filepath.Join(Dir(), value)
+	}
+	return filepath.Clean(value)
+}
+
+// jabba home -> true if it's read-only (see StateDir)
+var readOnlyDirs = make(map[string]bool)
+
+// StateDir returns directory mutable state (aliases, history, caches, locks) is kept in.
+// It's jabba home unless jabba home is read-only (e.g. bind-mounted into a container with :ro) and $JABBA_OVERLAY
+// is set, in which case it's the overlay (so that one set of JDKs installed on the host could be shared by
+// many containers).
+func StateDir() string {
+	dir := Dir()
+	overlay := os.Getenv("JABBA_OVERLAY")
+	if overlay == "" {
+		return dir
+	}
+	readOnly, ok := readOnlyDirs[dir]
+	if !ok {
+		readOnly = isReadOnly(dir)
+		readOnlyDirs[dir] = readOnly
+		if readOnly {
+			log.Debug(dir, " is read-only, keeping state in ", overlay, " (JABBA_OVERLAY)")
+		}
+	}
+	if readOnly {
+		return filepath.Clean(overlay)
+	}
+	return dir
+}
+
+// isReadOnly returns true if dir exists but files cannot be created in it.
+var isReadOnly = func(dir string) bool {
+	if _, err := os.Stat(dir); err != nil {
+		return false
+	}
+	f, err := ioutil.TempFile(dir, ".jabba-w-")
+	if err != nil {
+		return true
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return false
+}
+
+func userHomeDir() (string, error) {
 	dir, err := homedir.Dir()
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
-	return filepath.Join(dir, ".jabba")
+	// e.g. HOME is unset and passwd entry is missing or points to "/"
+	if dir == "" || filepath.Clean(dir) == string(filepath.Separator) {
+		return "", fmt.Errorf("home directory is \"%s\"", dir)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// machine-level config (e.g. the one baked into CI runner image)
+var machineConfigFile = func() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "jabba", "config.yaml")
+	}
+	return "/etc/jabba/config.yaml"
+}()
+
+// Load merges (in order) machine config (/etc/jabba/config.yaml or %ProgramData%\jabba\config.yaml),
+// user config ($JABBA_HOME/config.yaml) and job config ($JABBA_CONFIG (if set)).
+// Machine & user config can also be written in TOML (config.toml next to where config.yaml would be),
+// same goes for job config with .toml extension.
+// Values specified in the file loaded later replace the ones loaded before (lists are not concatenated),
+// unless key is "locked" by one of the previous files.
+func Load() *Config {
+	if config == nil {
+		config = &Config{}
+		locked := make(map[string]string)
+		for _, file := range []string{locate(machineConfigFile), locate(filepath.Join(Dir(), "config.yaml")),
+			os.Getenv("JABBA_CONFIG")} {
+			if file == "" {
+				continue
+			}
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				if os.IsNotExist(err) && file != os.Getenv("JABBA_CONFIG") {
+					continue
+				}
+				log.Fatal(err)
+			}
+			layer, err := parseConfig(file, b)
+			if err != nil {
+				log.Fatal(file + " is not valid: " + err.Error())
+			}
+			merge(config, layer, file, locked)
+		}
+	}
+	return config
+}
+
+// Use makes Load return c (config merged earlier (see command.ReadState)) instead of reading config files.
+func Use(c *Config) {
+	config = c
+}
+
+// locate returns config.toml if there is one (and no config.yaml) next to the file, file itself otherwise.
+func locate(file string) string {
+	alt := strings.TrimSuffix(file, filepath.Ext(file)) + ".toml"
+	if _, err := os.Stat(alt); err != nil {
+		return file
+	}
+	if _, err := os.Stat(file); err == nil {
+		log.Warn(alt, " is ignored (", file, " takes precedence)")
+		return file
+	}
+	return alt
+}
+
+func parseConfig(file string, b []byte) (*Config, error) {
+	if strings.EqualFold(filepath.Ext(file), ".toml") {
+		// keys are the same as in config.yaml
+		var m map[string]interface{}
+		if _, err := toml.Decode(string(b), &m); err != nil {
+			return nil, err
+		}
+		var err error
+		if b, err = yaml.Marshal(m); err != nil {
+			return nil, err
+		}
+	}
+	var c Config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func merge(dst *Config, src *Config, file string, locked map[string]string) {
+	set := func(key string, isSet bool, apply func()) {
+		if !isSet {
+			return
+		}
+		if lockedBy, ok := locked[key]; ok {
+			log.Warn(file, ": \"", key, "\" is locked by ", lockedBy, " (ignoring)")
+			return
+		}
+		apply()
+	}
+	set("registry", len(src.Registry) != 0, func() { dst.Registry = src.Registry })
+	set("providers", len(src.Providers) != 0, func() { dst.Providers = src.Providers })
+	set("default_vendor", src.DefaultVendor != "", func() { dst.DefaultVendor = src.DefaultVendor })
+	set("cache_dir", src.CacheDir != "", func() { dst.CacheDir = src.CacheDir })
+	set("keep_downloads", src.KeepDownloads, func() { dst.KeepDownloads = src.KeepDownloads })
+	set("offline", src.Offline != nil, func() { dst.Offline = src.Offline })
+	set("size_budget", src.SizeBudget != "", func() { dst.SizeBudget = src.SizeBudget })
+	set("lock_timeout", src.LockTimeout != "", func() { dst.LockTimeout = src.LockTimeout })
+	set("cacert", len(src.CACert) != 0, func() { dst.CACert = src.CACert })
+	set("proxy", src.Proxy != "", func() { dst.Proxy = src.Proxy })
+	set("maven_settings", src.MavenSettings != "", func() { dst.MavenSettings = src.MavenSettings })
+	set("auth", len(src.Auth) != 0, func() { dst.Auth = append(dst.Auth, src.Auth...) })
+	set("connect_timeout", src.ConnectTimeout != "", func() { dst.ConnectTimeout = src.ConnectTimeout })
+	set("read_timeout", src.ReadTimeout != "", func() { dst.ReadTimeout = src.ReadTimeout })
+	set("timeout", src.Timeout != "", func() { dst.Timeout = src.Timeout })
+	set("history", src.History != nil, func() { dst.History = src.History })
+	set("history_retention", src.HistoryRetention != "", func() { dst.HistoryRetention = src.HistoryRetention })
+	set("lts_notify", len(src.LTSNotify) != 0, func() { dst.LTSNotify = src.LTSNotify })
+	set("lts_notify_ignore", len(src.LTSNotifyIgnore) != 0, func() { dst.LTSNotifyIgnore = src.LTSNotifyIgnore })
+	set("eol_warnings", src.EOLWarnings != nil, func() { dst.EOLWarnings = src.EOLWarnings })
+	set("eol_data", src.EOLData != "", func() { dst.EOLData = src.EOLData })
+	set("self_update", src.SelfUpdate != nil, func() { dst.SelfUpdate = src.SelfUpdate })
+	set("jdk_dir", src.JDKDir != "", func() { dst.JDKDir = src.JDKDir })
+	set("dedupe", src.Dedupe != "", func() { dst.Dedupe = src.Dedupe })
+	set("cds", src.CDS, func() { dst.CDS = src.CDS })
+	set("verify_self", src.VerifySelf, func() { dst.VerifySelf = src.VerifySelf })
+	set("arch", src.Arch != "", func() { dst.Arch = src.Arch })
+	set("output", src.Output != "", func() { dst.Output = src.Output })
+	set("system_link", src.SystemLink != "", func() { dst.SystemLink = src.SystemLink })
+	set("uninstall_policy", src.UninstallPolicy != "", func() { dst.UninstallPolicy = src.UninstallPolicy })
+	set("auto_install", src.AutoInstall, func() { dst.AutoInstall = src.AutoInstall })
+	set("post_install", !src.PostInstall.IsEmpty(), func() { dst.PostInstall = src.PostInstall })
+	set("truststore", len(src.Truststore.CACerts) != 0 || src.Truststore.Env != "",
+		func() { dst.Truststore = src.Truststore })
+	// homes are merged by name
+	set("homes", len(src.Homes) != 0, func() {
+		if dst.Homes == nil {
+			dst.Homes = make(map[string]string)
+		}
+		for name, path := range src.Homes {
+			dst.Homes[name] = path
+		}
+	})
+	// the ones that come later take precedence (see AliasFiles)
+	set("alias_files", len(src.AliasFiles) != 0, func() { dst.AliasFiles = append(dst.AliasFiles, src.AliasFiles...) })
+	// profiles are merged by name
+	set("profiles", len(src.Profiles) != 0, func() {
+		if dst.Profiles == nil {
+			dst.Profiles = make(map[string]Profile)
+		}
+		for name, profile := range src.Profiles {
+			dst.Profiles[name] = profile
+		}
+	})
+	for _, key := range src.Locked {
+		if _, ok := locked[key]; !ok {
+			locked[key] = file
+		}
+	}
+	dst.Locked = append(dst.Locked, src.Locked...)
+}
+
+// isLocked returns true if key cannot be overridden (see Config.Locked).
+// If value (of the override) is not empty, a warning saying that it's ignored is logged.
+func isLocked(key string, source string, value string) bool {
+	for _, k := range Load().Locked {
+		if k == key {
+			if value != "" {
+				log.Warn(source, " is ignored (\"", key, "\" is locked by machine config)")
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// SetRegistry overrides index URL(s) (e.g. with the value of --registry).
+func SetRegistry(urls []string) {
+	registryOverride = urls
+}
+
+// Registry returns index URL(s) to try (in order).
+// --registry takes precedence over $JABBA_INDEX, which takes precedence over "registry" in config.yaml.
+func Registry() []string {
+	if !isLocked("registry", "--registry/JABBA_INDEX", strings.Join(registryOverride, "")+os.Getenv("JABBA_INDEX")) {
+		if len(registryOverride) != 0 {
+			return registryOverride
+		}
+		if registry := splitList(os.Getenv("JABBA_INDEX")); len(registry) != 0 {
+			return registry
+		}
+	}
+	if registry := Load().Registry; len(registry) != 0 {
+		return registry
+	}
+	return []string{"https://github.com/shyiko/jabba/raw/master/index.json"}
+}
+
+// SetOffline turns offline mode on/off (e.g. with the value of --offline).
+func SetOffline(value bool) {
+	offline = value
+}
+
+// Offline returns true if index should be served from the local cache only
+// (--offline, JABBA_OFFLINE=1 or "offline: true" in config.yaml).
+func Offline() bool {
+	value := os.Getenv("JABBA_OFFLINE")
+	override := value
+	if offline {
+		override = "true"
+	}
+	if isLocked("offline", "--offline/JABBA_OFFLINE", override) {
+		return Load().Offline != nil && *Load().Offline
+	}
+	if offline {
+		return true
+	}
+	if value != "" {
+		v, _ := strconv.ParseBool(value)
+		return v
+	}
+	return Load().Offline != nil && *Load().Offline
+}
+
+// SetCACerts adds PEM file(s) with extra root certificates (e.g. with the value of --cacert).
+func SetCACerts(files []string) {
+	cacertOverride = files
+}
+
+// CACerts returns PEM files with root certificates to trust in addition to the system ones
+// ("cacert" in config.yaml + $JABBA_CACERT + --cacert).
+func CACerts() []string {
+	var files []string
+	files = append(files, Load().CACert...)
+	if !isLocked("cacert", "--cacert/JABBA_CACERT", strings.Join(cacertOverride, "")+os.Getenv("JABBA_CACERT")) {
+		files = append(files, splitList(os.Getenv("JABBA_CACERT"))...)
+		files = append(files, cacertOverride...)
+	}
+	return files
+}
+
+// SetInsecure turns TLS certificate verification off (--insecure).
+func SetInsecure(value bool) {
+	insecure = value
+}
+
+// Insecure returns true if TLS certificates should not be verified (unless "insecure" is locked by machine config).
+func Insecure() bool {
+	if !insecure {
+		return false
+	}
+	return !isLocked("insecure", "--insecure", "true")
+}
+
+// Proxy returns "proxy" from config.yaml (see Config.Proxy).
+func Proxy() string {
+	return Load().Proxy
+}
+
+// Auth returns credentials of URL prefixes ("auth" of all the config files), with environment variables in values
+// (e.g. "${ARTIFACTORY_TOKEN}") expanded.
+func Auth() []AuthEntry {
+	var r []AuthEntry
+	for _, entry := range Load().Auth {
+		entry.Token = os.ExpandEnv(entry.Token)
+		entry.Username = os.ExpandEnv(entry.Username)
+		entry.Password = os.ExpandEnv(entry.Password)
+		entry.Header = os.ExpandEnv(entry.Header)
+		r = append(r, entry)
+	}
+	return r
+}
+
+// MavenSettings returns path to Maven settings.xml to read proxy & mirror credentials from
+// ($JABBA_MAVEN_SETTINGS or "maven_settings" in config.yaml ("true" meaning ~/.m2/settings.xml)),
+// "" (don't) by default.
+func MavenSettings() string {
+	value := os.Getenv("JABBA_MAVEN_SETTINGS")
+	if value == "" || isLocked("maven_settings", "JABBA_MAVEN_SETTINGS", value) {
+		value = Load().MavenSettings
+	}
+	if v, err := strconv.ParseBool(value); err == nil {
+		if !v {
+			return ""
+		}
+		home, err := userHomeDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(home, ".m2", "settings.xml")
+	}
+	return value
+}
+
+// directory to keep downloaded archives in ("" means archives are not cached)
+// ($JABBA_CACHE_DIR or "cache_dir" in config.yaml, $JABBA_HOME/cache if "keep_downloads" is true)
+func CacheDir() string {
+	cacheDir := os.Getenv("JABBA_CACHE_DIR")
+	if cacheDir == "" || isLocked("cache_dir", "JABBA_CACHE_DIR", cacheDir) {
+		cacheDir = Load().CacheDir
+	}
+	if cacheDir == "" {
+		if KeepDownloads() {
+			return filepath.Join(StateDir(), "cache")
+		}
+		return ""
+	}
+	return filepath.Clean(cacheDir)
+}
+
+// KeepDownloads returns true if downloaded archives should be kept in $JABBA_HOME/cache when there is no cache_dir
+// ($JABBA_KEEP_DOWNLOADS or "keep_downloads" in config.yaml), false by default.
+func KeepDownloads() bool {
+	value := os.Getenv("JABBA_KEEP_DOWNLOADS")
+	if value != "" && !isLocked("keep_downloads", "JABBA_KEEP_DOWNLOADS", value) {
+		v, _ := strconv.ParseBool(value)
+		return v
+	}
+	return Load().KeepDownloads
+}
+
+// TempDir returns directory jabba keeps temporary files (downloads that are not cached, installers' scratch space,
+// JDKs of `jabba try`, etc.) in.
+func TempDir() string {
+	return filepath.Join(StateDir(), "tmp")
+}
+
+// release providers to consult by default (see `jabba ls-remote --help`)
+func Providers() []string {
+	providers := splitList(os.Getenv("JABBA_PROVIDERS"))
+	if len(providers) == 0 || isLocked("providers", "JABBA_PROVIDERS", os.Getenv("JABBA_PROVIDERS")) {
+		providers = Load().Providers
+	}
+	if len(providers) == 0 {
+		return []string{"index"}
+	}
+	return providers
+}
+
+// DefaultVendor returns vendor to resolve versions that don't specify one within ("" if there is none)
+// & where it came from ($JABBA_DEFAULT_VENDOR or "default_vendor" in config.yaml).
+func DefaultVendor() (vendor string, source string) {
+	vendor = os.Getenv("JABBA_DEFAULT_VENDOR")
+	if vendor != "" && !isLocked("default_vendor", "JABBA_DEFAULT_VENDOR", vendor) {
+		return vendor, "JABBA_DEFAULT_VENDOR"
+	}
+	if vendor = Load().DefaultVendor; vendor != "" {
+		return vendor, "\"default_vendor\" in config.yaml"
+	}
+	return "", ""
+}
+
+// LockTimeout returns how long to wait for other jabba processes to release the lock of jabba home
+// ($JABBA_LOCK_TIMEOUT or "lock_timeout" in config.yaml (e.g. "30s", "5m" or just 30 (seconds)), 5 minutes by default).
+func LockTimeout() time.Duration {
+	value := os.Getenv("JABBA_LOCK_TIMEOUT")
+	if value == "" || isLocked("lock_timeout", "JABBA_LOCK_TIMEOUT", value) {
+		value = Load().LockTimeout
+	}
+	if value == "" {
+		return 5 * time.Minute
+	}
+	return parseDuration(value, "lock timeout")
+}
+
+// SetTimeout overrides "connect_timeout", "read_timeout" or "timeout" (e.g. with the value of --connect-timeout).
+func SetTimeout(key string, value time.Duration) {
+	timeoutOverride[key] = value
+}
+
+// ConnectTimeout returns how long to wait for connection to be established (TCP + TLS handshake), 30s by default.
+func ConnectTimeout() time.Duration {
+	return timeout("connect_timeout", Load().ConnectTimeout, 30*time.Second)
+}
+
+// ReadTimeout returns how long to wait for the next chunk of data (including response headers) before
+// giving up on the connection (stalled downloads are then resumed by the next attempt), 1m by default.
+func ReadTimeout() time.Duration {
+	return timeout("read_timeout", Load().ReadTimeout, time.Minute)
+}
+
+// Timeout returns how long a single HTTP request (download included) may take, 30m by default.
+func Timeout() time.Duration {
+	return timeout("timeout", Load().Timeout, 30*time.Minute)
+}
+
+// timeout returns --<key> (if specified), <key> in config.yaml or def (0 means no timeout).
+func timeout(key string, value string, def time.Duration) time.Duration {
+	if override, ok := timeoutOverride[key]; ok &&
+		!isLocked(key, "--"+strings.Replace(key, "_", "-", -1), override.String()) {
+		return override
+	}
+	if value == "" {
+		return def
+	}
+	return parseDuration(value, strings.Replace(key, "_", " ", -1))
+}
+
+// parseDuration is ParseDuration that fails loudly.
+func parseDuration(value string, what string) time.Duration {
+	d, err := ParseDuration(value)
+	if err != nil {
+		log.Fatal("\"" + value + "\" is not a valid " + what + " (expected something like 30s or 5m)")
+	}
+	return d
+}
+
+// ParseDuration parses "30s", "5m", "90d" or just 30 (seconds).
+func ParseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		if seconds, serr := strconv.Atoi(value); serr == nil {
+			return time.Duration(seconds) * time.Second, nil
+		}
+		if days, derr := strconv.Atoi(strings.TrimSuffix(value, "d")); derr == nil && strings.HasSuffix(value, "d") {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	}
+	return d, err
+}
+
+// History returns true if installs & activations should be recorded ($JABBA_HISTORY or "history" in config.yaml).
+func History() bool {
+	value := os.Getenv("JABBA_HISTORY")
+	if value != "" && !isLocked("history", "JABBA_HISTORY", value) {
+		v, _ := strconv.ParseBool(value)
+		return v
+	}
+	return Load().History != nil && *Load().History
+}
+
+// HistoryRetention returns how long history entries are kept for (0 means forever).
+func HistoryRetention() time.Duration {
+	if value := Load().HistoryRetention; value != "" {
+		return parseDuration(value, "history retention")
+	}
+	return 0
+}
+
+// LTSNotify returns notifiers new LTS lines should be announced through ($JABBA_LTS_NOTIFY or "lts_notify" in
+// config.yaml). JABBA_LTS_NOTIFY=off turns notifications off.
+func LTSNotify() []string {
+	value := os.Getenv("JABBA_LTS_NOTIFY")
+	if value != "" && !isLocked("lts_notify", "JABBA_LTS_NOTIFY", value) {
+		if off, err := strconv.ParseBool(value); (err == nil && !off) || value == "off" {
+			return nil
+		}
+		return splitList(value)
+	}
+	return Load().LTSNotify
+}
+
+// LTSNotifyIgnore returns LTS lines (majors, e.g. "25") user doesn't want to be notified about.
+func LTSNotifyIgnore() []string {
+	return Load().LTSNotifyIgnore
+}
+
+// EOLWarnings returns false if `jabba use` / `jabba current` shouldn't warn about JDKs past end of support or
+// superseded by newer releases ($JABBA_EOL_WARNINGS or "eol_warnings" in config.yaml), true by default.
+func EOLWarnings() bool {
+	value := os.Getenv("JABBA_EOL_WARNINGS")
+	if value != "" && !isLocked("eol_warnings", "JABBA_EOL_WARNINGS", value) {
+		v, _ := strconv.ParseBool(value)
+		return v
+	}
+	return Load().EOLWarnings == nil || *Load().EOLWarnings
+}
+
+// EOLData returns URL of vendor end-of-support dates to use on top of the built-in ones ($JABBA_EOL_DATA or
+// "eol_data" in config.yaml), "" by default.
+func EOLData() string {
+	value := os.Getenv("JABBA_EOL_DATA")
+	if value == "" || isLocked("eol_data", "JABBA_EOL_DATA", value) {
+		value = Load().EOLData
+	}
+	return value
+}
+
+// SelfUpdate returns false if jabba is not allowed to check for / install new versions of itself
+// ($JABBA_SELF_UPDATE or "self_update" in config.yaml), true by default.
+func SelfUpdate() bool {
+	value := os.Getenv("JABBA_SELF_UPDATE")
+	if value != "" && !isLocked("self_update", "JABBA_SELF_UPDATE", value) {
+		v, _ := strconv.ParseBool(value)
+		return v
+	}
+	return Load().SelfUpdate == nil || *Load().SelfUpdate
+}
+
+// Dedupe returns how files of installed JDKs should be deduplicated ("auto", "clone" or "hardlink") ("" if they
+// shouldn't) ($JABBA_DEDUPE or "dedupe" in config.yaml, "" by default).
+func Dedupe() string {
+	value := os.Getenv("JABBA_DEDUPE")
+	if value == "" || isLocked("dedupe", "JABBA_DEDUPE", value) {
+		value = Load().Dedupe
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	if v, err := strconv.ParseBool(value); err == nil {
+		if v {
+			return "auto"
+		}
+		return ""
+	}
+	return value
+}
+
+// CDS returns true if CDS archive should be generated for every installed JDK
+// ($JABBA_CDS or "cds" in config.yaml), false by default.
+func CDS() bool {
+	value := os.Getenv("JABBA_CDS")
+	if value != "" && !isLocked("cds", "JABBA_CDS", value) {
+		v, _ := strconv.ParseBool(value)
+		return v
+	}
+	return Load().CDS
+}
+
+// VerifySelf returns true if jabba binary should be checked for truncation / tampering on every start
+// ($JABBA_VERIFY_SELF or "verify_self" in config.yaml), false by default.
+func VerifySelf() bool {
+	value := os.Getenv("JABBA_VERIFY_SELF")
+	if value != "" && !isLocked("verify_self", "JABBA_VERIFY_SELF", value) {
+		v, _ := strconv.ParseBool(value)
+		return v
+	}
+	return Load().VerifySelf
+}
+
+// Arch returns architecture to install / list JDKs for when --arch is not specified
+// ($JABBA_ARCH or "arch" in config.yaml, "" (meaning the one jabba is running on) by default).
+func Arch() string {
+	value := os.Getenv("JABBA_ARCH")
+	if value == "" || isLocked("arch", "JABBA_ARCH", value) {
+		value = Load().Arch
+	}
+	return value
+}
+
+// Output returns output format to use when --output is not specified
+// ($JABBA_OUTPUT or "output" in config.yaml, "plain" by default).
+func Output() string {
+	value := os.Getenv("JABBA_OUTPUT")
+	if value == "" || isLocked("output", "JABBA_OUTPUT", value) {
+		value = Load().Output
+	}
+	if value == "" {
+		return "plain"
+	}
+	return value
+}
+
+// SystemLink returns path of the system-wide link to the default JDK
+// ($JABBA_SYSTEM_LINK or "system_link" in config.yaml, /usr/local/opt/jabba-default by default).
+func SystemLink() string {
+	value := os.Getenv("JABBA_SYSTEM_LINK")
+	if value == "" || isLocked("system_link", "JABBA_SYSTEM_LINK", value) {
+		value = Load().SystemLink
+	}
+	if value == "" {
+		return "/usr/local/opt/jabba-default"
+	}
+	return filepath.Clean(value)
+}
+
+// UninstallPolicy returns command to consult before uninstalling JDK
+// ($JABBA_UNINSTALL_POLICY or "uninstall_policy" in config.yaml, "" (none) by default).
+func UninstallPolicy() string {
+	value := os.Getenv("JABBA_UNINSTALL_POLICY")
+	if value == "" || isLocked("uninstall_policy", "JABBA_UNINSTALL_POLICY", value) {
+		value = Load().UninstallPolicy
+	}
+	return value
+}
+
+// AutoInstall returns true if `jabba use` should install JDK if there is none matching the selector
+// ($JABBA_AUTO_INSTALL or "auto_install" in config.yaml), false by default.
+func AutoInstall() bool {
+	value := os.Getenv("JABBA_AUTO_INSTALL")
+	if value != "" && !isLocked("auto_install", "JABBA_AUTO_INSTALL", value) {
+		v, _ := strconv.ParseBool(value)
+		return v
+	}
+	return Load().AutoInstall
+}
+
+// PostInstall returns what to do with every JDK once it's installed ("post_install" in config.yaml, nothing by
+// default). Relative paths (of cacerts & java_security) are resolved against jabba home.
+func PostInstall() PostInstallHooks {
+	hooks := Load().PostInstall
+	var cacerts StringList
+	for _, file := range hooks.CACerts {
+		cacerts = append(cacerts, homePath(file))
+	}
+	hooks.CACerts = cacerts
+	if hooks.JavaSecurity != "" {
+		hooks.JavaSecurity = homePath(hooks.JavaSecurity)
+	}
+	return hooks
+}
+
+// Truststore returns CA certificates to generate truststore of the active JDK with ("truststore" in config.yaml,
+// $JABBA_TRUSTSTORE ("off" to disable) replacing "truststore.cacerts"), none by default. Relative paths are resolved
+// against jabba home.
+func Truststore() TruststoreConfig {
+	c := Load().Truststore
+	if value := os.Getenv("JABBA_TRUSTSTORE"); value != "" && !isLocked("truststore", "JABBA_TRUSTSTORE", value) {
+		if v, err := strconv.ParseBool(value); value == "off" || err == nil && !v {
+			c.CACerts = nil
+		} else if err != nil {
+			c.CACerts = splitList(value)
+		}
+	}
+	var cacerts StringList
+	for _, file := range c.CACerts {
+		cacerts = append(cacerts, homePath(file))
+	}
+	c.CACerts = cacerts
+	if c.Env == "" {
+		c.Env = "JAVA_TOOL_OPTIONS"
+	}
+	return c
+}
+
+// AliasFiles returns files (glob patterns) to read aliases from (in addition to the ones defined with `jabba alias`),
+// lowest precedence first ("alias_files" of all the config files + $JABBA_ALIAS_FILES). Relative paths are resolved
+// against jabba home.
+func AliasFiles() []string {
+	files := Load().AliasFiles
+	if value := os.Getenv("JABBA_ALIAS_FILES"); value != "" && !isLocked("alias_files", "JABBA_ALIAS_FILES", value) {
+		files = append(files, splitList(value)...)
+	}
+	var r []string
+	for _, file := range files {
+		r = append(r, homePath(file))
+	}
+	return r
+}
+
+func homePath(path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.Join(Dir(), path)
+	}
+	return filepath.Clean(path)
+}
+
+// Homes returns named jabba homes ("homes" in config.yaml) (name -> path). Homes declared in the config of the
+// default home (~/.jabba/config.yaml) are visible from any other home (unless redeclared). Relative paths are resolved
+// against the default home.
+func Homes() map[string]string {
+	r := make(map[string]string)
+	if def := DefaultDir(); def != Dir() {
+		file := locate(filepath.Join(def, "config.yaml"))
+		if b, err := ioutil.ReadFile(file); err == nil {
+			if c, err := parseConfig(file, b); err == nil {
+				for name, path := range c.Homes {
+					r[name] = path
+				}
+			} else {
+				log.Warn(file + " is not valid: " + err.Error())
+			}
+		}
+	}
+	for name, path := range Load().Homes {
+		r[name] = path
+	}
+	for name, path := range r {
+		if expanded, err := homedir.Expand(path); err == nil {
+			path = expanded
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(DefaultDir(), path)
+		}
+		r[name] = filepath.Clean(path)
+	}
+	return r
+}
+
+// GetProfile returns profile defined in config.yaml (ok is false if there is no such profile).
+func GetProfile(name string) (profile Profile, ok bool) {
+	profile, ok = Load().Profiles[name]
+	return
 }
 
-func Index() string {
-	registry := os.Getenv("JABBA_INDEX")
-	if registry == "" {
-		registry = "https://github.com/shyiko/jabba/raw/master/index.json"
+// "a, b,c" -> ["a", "b", "c"]
+func splitList(value string) []string {
+	var r []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			r = append(r, v)
+		}
 	}
-	return registry
+	return r
 }
@@ -0,0 +1,200 @@
+package cfg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+func TestLoadOverlays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, content string) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	prevMachineConfigFile := machineConfigFile
+	defer func() { machineConfigFile = prevMachineConfigFile; config = nil }()
+	machineConfigFile = write("machine.yaml", "registry: https://mirror.example.com/index.json\n"+
+		"cache_dir: /var/cache/jabba\nlocked: [registry]\n")
+	os.Setenv("JABBA_HOME", dir)
+	defer os.Unsetenv("JABBA_HOME")
+	write("config.yaml", "providers: [index, zulu]\noffline: false\n")
+	os.Setenv("JABBA_CONFIG", write("job.yaml", "registry: https://example.com/index.json\n"+
+		"providers: adoptium\noffline: true\n"))
+	defer os.Unsetenv("JABBA_CONFIG")
+	os.Setenv("JABBA_INDEX", "https://example.com/index.json")
+	defer os.Unsetenv("JABBA_INDEX")
+	config = nil
+	if actual, expected := Registry(), []string{"https://mirror.example.com/index.json"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if actual, expected := Providers(), []string{"adoptium"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if actual, expected := CacheDir(), filepath.Clean("/var/cache/jabba"); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if actual, expected := Offline(), true; actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	prevMachineConfigFile := machineConfigFile
+	defer func() { machineConfigFile = prevMachineConfigFile; config = nil }()
+	machineConfigFile = filepath.Join(dir, "machine.yaml")
+	os.Setenv("JABBA_HOME", dir)
+	defer os.Unsetenv("JABBA_HOME")
+	err = ioutil.WriteFile(filepath.Join(dir, "config.toml"), []byte(`
+registry = ["https://mirror.example.com/index.json", "https://example.com/index.json"]
+default_vendor = "temurin"
+arch = "amd64"
+output = "json"
+lock_timeout = 30
+[profiles.debug]
+env = { JAVA_TOOL_OPTIONS = "-agentlib:jdwp=transport=dt_socket,server=y,suspend=n" }
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config = nil
+	if actual, expected := Registry(), []string{"https://mirror.example.com/index.json",
+		"https://example.com/index.json"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if vendor, _ := DefaultVendor(); vendor != "temurin" {
+		t.Fatalf("actual: %v != expected: %v", vendor, "temurin")
+	}
+	if actual, expected := Arch()+" "+Output(), "amd64 json"; actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if actual, expected := LockTimeout(), 30*time.Second; actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if profile, ok := GetProfile("debug"); !ok || len(profile.Env) != 1 {
+		t.Fatalf("actual: %v (%v) != expected: debug profile", profile, ok)
+	}
+	// config.yaml takes precedence
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte("arch: arm64\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config = nil
+	os.Setenv("JABBA_OUTPUT", "plain")
+	defer os.Unsetenv("JABBA_OUTPUT")
+	if actual, expected := Arch()+" "+Output(), "arm64 plain"; actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestStateDir(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	prevIsReadOnly := isReadOnly
+	defer func() { isReadOnly = prevIsReadOnly; readOnlyDirs = make(map[string]bool) }()
+	readOnly := false
+	isReadOnly = func(string) bool { return readOnly }
+	if actual := StateDir(); actual != home {
+		t.Fatalf("actual: %v != expected: %v", actual, home)
+	}
+	overlay := filepath.Join(home, "overlay")
+	os.Setenv("JABBA_OVERLAY", overlay)
+	defer os.Unsetenv("JABBA_OVERLAY")
+	if actual := StateDir(); actual != home {
+		t.Fatalf("writable home is not supposed to be overlaid (actual: %v)", actual)
+	}
+	readOnlyDirs, readOnly = make(map[string]bool), true
+	if actual := StateDir(); actual != overlay {
+		t.Fatalf("actual: %v != expected: %v", actual, overlay)
+	}
+}
+
+func TestJDKDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-cfg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	prevMachineConfigFile := machineConfigFile
+	defer func() { machineConfigFile = prevMachineConfigFile; config = nil }()
+	machineConfigFile = filepath.Join(dir, "machine.yaml")
+	os.Setenv("JABBA_HOME", dir)
+	defer os.Unsetenv("JABBA_HOME")
+	config = nil
+	if actual, expected := JDKDir(), filepath.Join(dir, "jdk"); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte("jdk_dir: ../jdks\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config = nil
+	if actual, expected := JDKDir(), filepath.Join(filepath.Dir(dir), "jdks"); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	os.Setenv("JABBA_JDK_DIR", "/mnt/jdks/")
+	defer os.Unsetenv("JABBA_JDK_DIR")
+	if actual, expected := JDKDir(), filepath.Clean("/mnt/jdks"); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestHomes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	prevHome, prevDisableCache := os.Getenv("HOME"), homedir.DisableCache
+	defer func() {
+		os.Setenv("HOME", prevHome)
+		// homedir caches whatever it resolves (even with DisableCache on)
+		homedir.Dir()
+		homedir.DisableCache = prevDisableCache
+		config = nil
+	}()
+	os.Setenv("HOME", dir)
+	homedir.DisableCache = true
+	write := func(file string, content string) {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(dir, ".jabba", "config.yaml"), "homes:\n  personal: ~/.jabba\n  work: work\n  ci: /opt/ci\n")
+	write(filepath.Join(dir, "work", "config.yaml"), "homes:\n  ci: /srv/ci\n")
+	os.Setenv("JABBA_HOME", filepath.Join(dir, "work"))
+	defer os.Unsetenv("JABBA_HOME")
+	config = nil
+	// homes named in ~/.jabba/config.yaml are visible from the other homes
+	expected := map[string]string{
+		"personal": filepath.Join(dir, ".jabba"),
+		"work":     filepath.Join(dir, ".jabba", "work"),
+		"ci":       filepath.Clean("/srv/ci"),
+	}
+	if actual := Homes(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
@@ -1,25 +1,275 @@
 package command
 
 import (
-	"github.com/shyiko/jabba/cfg"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"gopkg.in/yaml.v2"
 )
 
-func SetAlias(name string, ver string) (err error) {
+// Aliases live in cfg.StateDir() (aliases.json (name -> value)). When it's an overlay of the (read-only) jabba home,
+// aliases defined in jabba home are visible too (unless overridden or removed ("" value) in the overlay).
+// Names can be namespaced (e.g. "work/backend") and values can reference other aliases (e.g. lts -> work/backend)
+// (see ResolveAlias). <name>.alias files (kept by older versions of jabba) are still read.
+// Aliases can also come from files listed in config (see cfg.AliasFiles) (e.g. corp-default maintained centrally),
+// which are read-only and take the lowest precedence (user can override / remove ("" value) them in aliases.json).
+
+const aliasesFileName = "aliases.json"
+
+var aliasNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.+-]*(/[A-Za-z0-9_][A-Za-z0-9_.+-]*)*$`)
+
+func SetAlias(name string, ver string) error {
+	if ver != "" && !aliasNameRegexp.MatchString(name) {
+		return fmt.Errorf("\"%s\" is not a valid alias name (e.g. default, lts, work/backend)", name)
+	}
+	if ver != "" {
+		// value can be another alias (even the one that is yet to be defined), but not the one that leads back
+		_, _, err := resolveAlias(name, func(n string) string {
+			if n == name {
+				return ver
+			}
+			return GetAlias(n)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	dir := cfg.StateDir()
+	if err := ensureWritableDir(dir); err != nil {
+		return err
+	}
+	aliases, err := readAliasesFile(dir)
+	if err != nil {
+		return err
+	}
 	if ver == "" {
-		err = os.Remove(filepath.Join(cfg.Dir(), name+".alias"))
+		delete(aliases, name)
+		if dir != cfg.Dir() {
+			if value, _ := lookupAlias(cfg.Dir(), name); value != "" {
+				// alias is defined in jabba home, which can't be changed
+				aliases[name] = ""
+			}
+		}
+		if included, _, _ := includedAliases(); included[name] != "" {
+			aliases[name] = ""
+		}
 	} else {
-		err = ioutil.WriteFile(filepath.Join(cfg.Dir(), name+".alias"), []byte(ver), 0666)
+		aliases[name] = ver
+	}
+	invalidateState()
+	if err := writeAliasesFile(dir, aliases); err != nil {
+		return err
 	}
-	return
+	if ver == "" {
+		if err := removeAliasEnv(dir, name); err != nil {
+			return err
+		}
+	}
+	// superseded by aliases.json
+	if err := os.Remove(filepath.Join(dir, name+".alias")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
 
+// GetAlias returns value of the alias as is (without following the chain (see ResolveAlias)), "" if there is none.
 func GetAlias(name string) string {
-	b, err := ioutil.ReadFile(filepath.Join(cfg.Dir(), name+".alias"))
-	if err != nil {
+	value, ok := lookupAlias(cfg.StateDir(), name)
+	if !ok && cfg.StateDir() != cfg.Dir() {
+		value, ok = lookupAlias(cfg.Dir(), name)
+	}
+	if !ok {
+		included, _, _ := includedAliases()
+		value = included[name]
+	}
+	return value
+}
+
+// AliasSource returns alias file (see cfg.AliasFiles) alias comes from ("" if it's defined with `jabba alias` (or not
+// defined at all)).
+func AliasSource(name string) string {
+	if _, ok := lookupAlias(cfg.StateDir(), name); ok {
 		return ""
 	}
-	return string(b)
+	if cfg.StateDir() != cfg.Dir() {
+		if _, ok := lookupAlias(cfg.Dir(), name); ok {
+			return ""
+		}
+	}
+	_, sources, _ := includedAliases()
+	return sources[name]
+}
+
+// includedAliases returns aliases defined in alias files (see cfg.AliasFiles) (name -> value) along with the file
+// each of them comes from (name -> file). Files that come later take precedence.
+func includedAliases() (aliases map[string]string, sources map[string]string, err error) {
+	aliases, sources = make(map[string]string), make(map[string]string)
+	for _, pattern := range cfg.AliasFiles() {
+		// files that don't exist (e.g. dotfiles repo that is not checked out) are skipped
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("alias_files: \"%s\" is not a valid pattern (%v)", pattern, err)
+		}
+		if len(files) == 0 {
+			log.Debug("alias_files: ", pattern, " didn't match anything")
+		}
+		for _, file := range files {
+			m, err := readAliasFile(file)
+			if err != nil {
+				return nil, nil, err
+			}
+			for name, value := range m {
+				aliases[name], sources[name] = value, file
+			}
+		}
+	}
+	return aliases, sources, nil
+}
+
+// readAliasFile reads name -> value map from JSON (same as aliases.json) or YAML file.
+func readAliasFile(file string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var aliases map[string]string
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		err = json.Unmarshal(b, &aliases)
+	} else {
+		err = yaml.Unmarshal(b, &aliases)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid (%v)", file, err)
+	}
+	for name := range aliases {
+		if !aliasNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("%s: \"%s\" is not a valid alias name (e.g. default, lts, work/backend)", file,
+				name)
+		}
+	}
+	return aliases, nil
+}
+
+func lookupAlias(dir string, name string) (value string, ok bool) {
+	aliases, _ := readAliasesFile(dir)
+	if value, ok := aliases[name]; ok {
+		return value, true
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, name+".alias"))
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// ResolveAlias follows the chain of aliases (e.g. lts -> work/backend -> zulu@1.17) returning the selector it ends
+// with (selector itself if it's not an alias).
+func ResolveAlias(selector string) (string, error) {
+	return resolveAliasWith(selector, GetAlias)
+}
+
+func resolveAliasWith(selector string, get func(string) string) (string, error) {
+	resolved, _, err := resolveAlias(selector, get)
+	// versions can't contain "/" (URLs aside)
+	if err == nil && strings.Contains(resolved, "/") && !strings.Contains(resolved, "://") {
+		return "", fmt.Errorf("\"%s\" alias is not defined", resolved)
+	}
+	return resolved, err
+}
+
+// resolveAlias returns the selector alias chain ends with along with the aliases it went through.
+func resolveAlias(selector string, get func(string) string) (string, []string, error) {
+	var chain []string
+	for {
+		value := strings.TrimSpace(get(selector))
+		if value == "" {
+			break
+		}
+		chain = append(chain, selector)
+		for _, name := range chain {
+			if name == value {
+				return "", nil, fmt.Errorf("Alias cycle (%s -> %s)", strings.Join(chain, " -> "), value)
+			}
+		}
+		selector = value
+	}
+	return selector, chain, nil
+}
+
+// Aliases returns names of all the aliases (e.g. "default", "work/backend") in alphabetical order.
+func Aliases() ([]string, error) {
+	dirs := []string{cfg.StateDir()}
+	if dirs[0] != cfg.Dir() {
+		dirs = append(dirs, cfg.Dir())
+	}
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] && GetAlias(name) != "" {
+			names = append(names, name)
+		}
+		seen[name] = true
+	}
+	included, _, err := includedAliases()
+	if err != nil {
+		return nil, err
+	}
+	for name := range included {
+		add(name)
+	}
+	for _, dir := range dirs {
+		aliases, err := readAliasesFile(dir)
+		if err != nil {
+			return nil, err
+		}
+		for name := range aliases {
+			add(name)
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "*.alias"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			add(strings.TrimSuffix(filepath.Base(file), ".alias"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func readAliasesFile(dir string) (map[string]string, error) {
+	file := filepath.Join(dir, aliasesFileName)
+	aliases := make(map[string]string)
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return aliases, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &aliases); err != nil {
+		return nil, fmt.Errorf("%s is not valid (%v)", file, err)
+	}
+	return aliases, nil
+}
+
+func writeAliasesFile(dir string, aliases map[string]string) error {
+	b, err := json.MarshalIndent(aliases, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, aliasesFileName)
+	tmp := file + ".jabba-tmp"
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
 }
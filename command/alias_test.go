@@ -0,0 +1,131 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	for _, dir := range []string{"1.8.0", "zulu@1.17.0"} {
+		if err := os.MkdirAll(filepath.Join(home, "jdk", dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// written by older versions of jabba
+	ok(ioutil.WriteFile(filepath.Join(home, "default.alias"), []byte("1.8"), 0666))
+	ok(SetAlias("work/backend", "zulu@1.17"))
+	ok(SetAlias("lts", "work/backend"))
+	// forward references are fine
+	ok(SetAlias("work/android", "work/next"))
+	if actual, expected := GetAlias("default"), "1.8"; actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	for selector, expected := range map[string]string{
+		"lts": "zulu@1.17.0", "work/backend": "zulu@1.17.0", "default": "1.8.0", "1.8": "1.8.0",
+	} {
+		actual, err := Resolve(selector)
+		if err != nil || actual != expected {
+			t.Fatalf("%s: actual: %v (%v) != expected: %v", selector, actual, err, expected)
+		}
+	}
+	if _, err := Resolve("work/android"); err == nil {
+		t.Fatal("work/android -> work/next should have failed to resolve")
+	}
+	if err := SetAlias("work/backend", "lts"); err == nil {
+		t.Fatal("cycle should have been rejected")
+	}
+	if err := SetAlias("../lts", "1.8"); err == nil {
+		t.Fatal("invalid name should have been rejected")
+	}
+	names, err := Aliases()
+	ok(err)
+	if expected := []string{"default", "lts", "work/android", "work/backend"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("actual: %v != expected: %v", names, expected)
+	}
+	// moved into aliases.json
+	ok(SetAlias("default", "zulu@1.17"))
+	if _, err := os.Stat(filepath.Join(home, "default.alias")); !os.IsNotExist(err) {
+		t.Fatal("default.alias should have been removed")
+	}
+	ok(SetAlias("work/android", ""))
+	aliases, err := readAliasesFile(home)
+	ok(err)
+	if expected := map[string]string{
+		"default": "zulu@1.17", "lts": "work/backend", "work/backend": "zulu@1.17",
+	}; !reflect.DeepEqual(aliases, expected) {
+		t.Fatalf("actual: %v != expected: %v", aliases, expected)
+	}
+	// links of the aliases referencing the updated one are updated too
+	ok(LinkAlias("lts"))
+	ok(SetAlias("work/backend", "1.8"))
+	ok(LinkAlias("work/backend"))
+	if actual, _ := os.Readlink(filepath.Join(home, "jdk", "lts")); actual != "1.8.0" {
+		t.Fatalf("actual: %v != expected: %v", actual, "1.8.0")
+	}
+	if _, err := os.Lstat(filepath.Join(home, "jdk", "work")); !os.IsNotExist(err) {
+		t.Fatal("namespaced aliases should not be linked")
+	}
+}
+
+func TestAliasFiles(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ok(os.MkdirAll(filepath.Join(home, "aliases.d"), 0755))
+	corp := filepath.Join(home, "aliases.d", "corp.json")
+	ok(ioutil.WriteFile(corp, []byte(`{"corp-default": "temurin@1.21", "corp/legacy": "zulu@1.8"}`), 0644))
+	team := filepath.Join(home, "team.yaml")
+	ok(ioutil.WriteFile(team, []byte("corp-default: corp/legacy\nteam/ci: corp-default\n"), 0644))
+	os.Setenv("JABBA_ALIAS_FILES", "aliases.d/*.json,"+team+",missing/*.yaml")
+	defer os.Unsetenv("JABBA_ALIAS_FILES")
+	// files that come later take precedence
+	for name, expected := range map[string]string{"corp-default": "zulu@1.8", "team/ci": "zulu@1.8"} {
+		actual, err := ResolveAlias(name)
+		if err != nil || actual != expected {
+			t.Fatalf("%s: actual: %v (%v) != expected: %v", name, actual, err, expected)
+		}
+	}
+	if actual := AliasSource("corp/legacy"); actual != corp {
+		t.Fatalf("actual: %v != expected: %v", actual, corp)
+	}
+	// user aliases take precedence over the files
+	ok(SetAlias("team/ci", "1.17"))
+	if actual := GetAlias("team/ci"); actual != "1.17" || AliasSource("team/ci") != "" {
+		t.Fatalf("actual: %v (%v) != expected: 1.17", actual, AliasSource("team/ci"))
+	}
+	// ... and can hide them
+	ok(SetAlias("corp/legacy", ""))
+	names, err := Aliases()
+	ok(err)
+	if expected := []string{"corp-default", "team/ci"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("actual: %v != expected: %v", names, expected)
+	}
+	ok(ioutil.WriteFile(team, []byte("../escape: 1.8\n"), 0644))
+	if _, err := Aliases(); err == nil {
+		t.Fatal("invalid alias name should have been rejected")
+	}
+}
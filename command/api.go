@@ -0,0 +1,213 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+// APIEvent is a line of the (newline-delimited JSON) response to POST /v1/install.
+type APIEvent struct {
+	// "progress", "done" or "error"
+	Event      string         `json:"event"`
+	Downloaded int64          `json:"downloaded,omitempty"`
+	Total      int64          `json:"total,omitempty"`
+	Result     *InstallResult `json:"result,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// ServeAPI serves JSON API (meant to be used by IDE/editor plugins) over unix socket until l is closed:
+//
+//	GET  /v1/installed                                    -> [JDK]
+//	GET  /v1/remote?os=&arch=&libc=&range=&channel=       -> [JDK]
+//	GET  /v1/resolve?selector=<version, range or alias>    -> JDK (404 if nothing installed matches)
+//	POST /v1/install?selector=&arch=&libc=&any=&channel=  -> APIEvent per line
+//
+// Errors (other than the ones of /v1/install) are reported as {"error": "..."} (with 4xx/5xx status code).
+func ServeAPI(l net.Listener) error {
+	return http.Serve(l, apiHandler())
+}
+
+// ListenAPI listens on unix socket (stale socket left behind by a process that died is removed).
+// Socket is accessible only to the current user.
+func ListenAPI(socket string) (net.Listener, error) {
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("%s is already in use by another process", socket)
+	}
+	os.Remove(socket)
+	l, err := listenUnix(socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+func apiHandler() http.Handler {
+	var installMutex sync.Mutex
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/installed", apiMethod("GET", func(w http.ResponseWriter, r *http.Request) {
+		vs, err := Ls()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		jdks := []JDK{}
+		for _, v := range vs {
+			jdks = append(jdks, DescribeInstalled(v))
+		}
+		writeAPIResponse(w, jdks)
+	}))
+	mux.HandleFunc("/v1/remote", apiMethod("GET", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		var rng *semver.Range
+		if value := q.Get("range"); value != "" {
+			var err error
+			if rng, err = semver.ParseRange(value); err != nil {
+				writeAPIError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		channel := q.Get("channel")
+		if channel != "" {
+			if err := ValidateChannel(channel); err != nil {
+				writeAPIError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		goos, arch := q.Get("os"), NormalizeArch(q.Get("arch"))
+		if goos == "" {
+			goos = runtime.GOOS
+		}
+		if arch == "" {
+			arch = HostArch()
+		}
+		goos, err := TargetOS(goos, q.Get("libc"))
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		releaseMap, err := LsRemote(goos, arch)
+		if err != nil {
+			writeAPIError(w, http.StatusBadGateway, err)
+			return
+		}
+		releaseMap = FilterReleases(releaseMap, rng, channel)
+		var vs []*semver.Version
+		for v := range releaseMap {
+			vs = append(vs, v)
+		}
+		sort.Sort(sort.Reverse(semver.VersionSlice(vs)))
+		jdks := []JDK{}
+		for _, v := range vs {
+			jdks = append(jdks, DescribeRemote(v, releaseMap[v], goos, arch))
+		}
+		writeAPIResponse(w, jdks)
+	}))
+	mux.HandleFunc("/v1/resolve", apiMethod("GET", func(w http.ResponseWriter, r *http.Request) {
+		selector := r.URL.Query().Get("selector")
+		if selector == "" {
+			writeAPIError(w, http.StatusBadRequest, errors.New("selector is required"))
+			return
+		}
+		resolved, err := Resolve(selector)
+		if err != nil {
+			writeAPIError(w, http.StatusNotFound, err)
+			return
+		}
+		v, err := semver.ParseVersion(resolved)
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeAPIResponse(w, DescribeInstalled(v))
+	}))
+	mux.HandleFunc("/v1/install", apiMethod("POST", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		selector := q.Get("selector")
+		if selector == "" {
+			writeAPIError(w, http.StatusBadRequest, errors.New("selector is required"))
+			return
+		}
+		ignoreRecommended, _ := strconv.ParseBool(q.Get("any"))
+		channel := q.Get("channel")
+		if channel != "" {
+			if err := ValidateChannel(channel); err != nil {
+				writeAPIError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		send := func(event APIEvent) {
+			enc.Encode(event)
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		// installs are serialized (the same JDK could be requested by multiple windows at once)
+		installMutex.Lock()
+		defer installMutex.Unlock()
+		lock, err := LockHome()
+		if err != nil {
+			send(APIEvent{Event: "error", Error: err.Error()})
+			return
+		}
+		defer lock.Unlock()
+		result, err := Install(selector, InstallOptions{
+			Arch:    q.Get("arch"),
+			Libc:    q.Get("libc"),
+			Any:     ignoreRecommended,
+			Channel: channel,
+			Progress: func(downloaded int64, total int64) {
+				send(APIEvent{Event: "progress", Downloaded: downloaded, Total: total})
+			},
+		})
+		if err == nil {
+			err = LinkLatest()
+		}
+		if err != nil {
+			send(APIEvent{Event: "error", Error: err.Error()})
+			return
+		}
+		send(APIEvent{Event: "done", Result: result})
+	}))
+	return mux
+}
+
+func apiMethod(method string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeAPIError(w, http.StatusMethodNotAllowed, errors.New(r.Method+" is not allowed"))
+			return
+		}
+		log.Debug(r.Method, " ", r.URL)
+		handler(w, r)
+	}
+}
+
+func writeAPIResponse(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
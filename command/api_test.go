@@ -0,0 +1,79 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	for _, ver := range []string{"1.8.0", "1.11.0"} {
+		ok(os.MkdirAll(filepath.Join(home, "jdk", ver, "bin"), 0755))
+	}
+	server := httptest.NewServer(apiHandler())
+	defer server.Close()
+	get := func(path string, expectedStatus int, v interface{}) {
+		res, err := http.Get(server.URL + path)
+		ok(err)
+		defer res.Body.Close()
+		if res.StatusCode != expectedStatus {
+			t.Fatalf("%s: actual: %v != expected: %v", path, res.StatusCode, expectedStatus)
+		}
+		ok(json.NewDecoder(res.Body).Decode(v))
+	}
+	var installed []JDK
+	get("/v1/installed", http.StatusOK, &installed)
+	if len(installed) != 2 || installed[0].Version != "1.11.0" || installed[1].Version != "1.8.0" {
+		t.Fatalf("unexpected %v", installed)
+	}
+	var jdk JDK
+	get("/v1/resolve?selector=1.8", http.StatusOK, &jdk)
+	if actual, expected := jdk.Path, filepath.Join(home, "jdk", "1.8.0"); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	var apiErr map[string]string
+	get("/v1/resolve?selector=1.9", http.StatusNotFound, &apiErr)
+	get("/v1/install?selector=1.8", http.StatusMethodNotAllowed, &apiErr)
+	if apiErr["error"] == "" {
+		t.Fatal("expected error message")
+	}
+}
+
+func TestListenUnixCreatesPrivateSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket access is governed by ACLs on Windows")
+	}
+	dir, err := ioutil.TempDir("", "jabba-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+	l, err := listenUnix(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	stat, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := stat.Mode().Perm(); actual != 0600 {
+		t.Fatalf("actual: %v != expected: %v", actual, os.FileMode(0600))
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package command
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates socket with 0600 permissions right away (instead of chmod-ing it after the fact, which would
+// leave a window for other users to connect in).
+func listenUnix(socket string) (net.Listener, error) {
+	umask := syscall.Umask(0177)
+	defer syscall.Umask(umask)
+	return net.Listen("unix", socket)
+}
@@ -0,0 +1,8 @@
+package command
+
+import "net"
+
+// listenUnix creates socket (access is governed by the ACL of the directory it's created in).
+func listenUnix(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
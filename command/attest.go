@@ -0,0 +1,184 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// `jabba attest` describes where installed JDK came from as in-toto Statement (https://in-toto.io/Statement/v1)
+// with SLSA provenance (https://slsa.dev/provenance/v1) predicate, every file of the JDK being a subject. Kept outside
+// of $JABBA_HOME (or signed (e.g. `cosign attest-blob`)), it lets `jabba verify-tree` detect tampering even if the
+// metadata (see installMeta) was tampered with too.
+
+const (
+	attestationType        = "https://in-toto.io/Statement/v1"
+	attestationPredicate   = "https://slsa.dev/provenance/v1"
+	attestationBuildType   = "https://github.com/shyiko/jabba/install/v1"
+	attestationBuilderID   = "https://github.com/shyiko/jabba"
+	attestationSymlinkNote = "symlinkTarget"
+)
+
+// Attestation is what `jabba attest` prints.
+type Attestation struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Provenance           `json:"predicate"`
+}
+
+// ResourceDescriptor is a file of the JDK (subject) or archive JDK was installed from (dependency).
+type ResourceDescriptor struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+	// {"symlinkTarget": "..."} in case of a symlink (digest is that of the target path), {"signer": "..."} in case of
+	// a signed archive
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Provenance is SLSA provenance predicate (the subset of it jabba fills in).
+type Provenance struct {
+	BuildDefinition struct {
+		BuildType string `json:"buildType"`
+		// version, os & arch
+		ExternalParameters   map[string]string    `json:"externalParameters"`
+		ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+			// jabba, go & extractor (see installerMeta)
+			Version map[string]string `json:"version,omitempty"`
+		} `json:"builder"`
+		Metadata struct {
+			StartedOn  *time.Time `json:"startedOn,omitempty"`
+			FinishedOn *time.Time `json:"finishedOn,omitempty"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+// Attest returns provenance of the installed JDK matching the selector (as recorded at install time).
+func Attest(selector string) (*Attestation, error) {
+	ver, meta, err := resolveWithMeta(selector)
+	if err != nil {
+		return nil, err
+	}
+	a := &Attestation{Type: attestationType, PredicateType: attestationPredicate, Subject: []ResourceDescriptor{}}
+	var paths []string
+	for path := range meta.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		sum := meta.Files[path]
+		subject := ResourceDescriptor{Name: path}
+		if strings.HasPrefix(sum, "-> ") {
+			target := strings.TrimPrefix(sum, "-> ")
+			digest := sha256.Sum256([]byte(target))
+			sum = hex.EncodeToString(digest[:])
+			subject.Annotations = map[string]string{attestationSymlinkNote: target}
+		}
+		subject.Digest = map[string]string{"sha256": sum}
+		a.Subject = append(a.Subject, subject)
+	}
+	p := &a.Predicate
+	p.BuildDefinition.BuildType = attestationBuildType
+	p.BuildDefinition.ExternalParameters = map[string]string{"version": ver, "os": meta.OS, "arch": meta.Arch}
+	archive := ResourceDescriptor{URI: meta.URL, Digest: map[string]string{}}
+	if meta.SHA256 != "" {
+		archive.Digest["sha256"] = meta.SHA256
+	}
+	if meta.Signer != "" {
+		archive.Annotations = map[string]string{"signer": meta.Signer}
+	}
+	p.BuildDefinition.ResolvedDependencies = []ResourceDescriptor{archive}
+	p.RunDetails.Builder.ID = attestationBuilderID
+	if meta.Installer != nil {
+		p.RunDetails.Builder.Version = map[string]string{"jabba": meta.Installer.Jabba, "go": meta.Installer.Go,
+			"extractor": meta.Installer.Extractor, "host": meta.Installer.Host}
+	}
+	installedAt := meta.InstalledAt
+	p.RunDetails.Metadata.StartedOn, p.RunDetails.Metadata.FinishedOn = meta.StartedAt, &installedAt
+	return a, nil
+}
+
+// ReadAttestation reads attestation `jabba attest` produced.
+func ReadAttestation(file string) (*Attestation, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var a Attestation
+	if err := json.Unmarshal(b, &a); err != nil || a.Type != attestationType {
+		return nil, fmt.Errorf("%s is not an attestation produced by `jabba attest`", file)
+	}
+	return &a, nil
+}
+
+// files returns path -> sha256 (or "-> <target>" in case of a symlink) (see installMeta.Files).
+func (a *Attestation) files() map[string]string {
+	r := make(map[string]string)
+	for _, s := range a.Subject {
+		if target, ok := s.Annotations[attestationSymlinkNote]; ok {
+			r[s.Name] = "-> " + target
+		} else {
+			r[s.Name] = s.Digest["sha256"]
+		}
+	}
+	return r
+}
+
+// VerifyTree re-hashes files of the installed JDK matching the selector, comparing them to the ones attestation
+// lists (nil means metadata recorded at install time (same as Verify)), returning a short summary if they match.
+func VerifyTree(selector string, a *Attestation) (string, error) {
+	if a == nil {
+		return Verify(selector)
+	}
+	ver, err := Resolve(selector)
+	if err != nil {
+		return "", err
+	}
+	if attested := a.Predicate.BuildDefinition.ExternalParameters["version"]; attested != ver {
+		return "", fmt.Errorf("attestation is for %s (not %s)", attested, ver)
+	}
+	expected := a.files()
+	problems, err := diffTree(filepath.Join(cfg.JDKDir(), ver), expected)
+	if err != nil {
+		return "", err
+	}
+	if len(problems) != 0 {
+		return "", fmt.Errorf("%s does not match the attestation:\n  %s", ver, strings.Join(problems, "\n  "))
+	}
+	summary := fmt.Sprintf("%s matches the attestation (%d files", ver, len(expected))
+	if deps := a.Predicate.BuildDefinition.ResolvedDependencies; len(deps) != 0 {
+		summary += ", installed from " + deps[0].URI
+	}
+	return summary + ")", nil
+}
+
+// resolveWithMeta resolves selector to the installed JDK, failing if JDK has no metadata.
+func resolveWithMeta(selector string) (string, *installMeta, error) {
+	ver, err := Resolve(selector)
+	if err != nil {
+		return "", nil, err
+	}
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("There is no metadata recorded for %s (it was either installed by an older "+
+				"version of jabba or `jabba link`ed)", ver)
+		}
+		return "", nil, err
+	}
+	return ver, meta, nil
+}
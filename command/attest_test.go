@@ -0,0 +1,64 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAttest(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	dir := filepath.Join(home, "jdk", "1.17.0")
+	bin := filepath.Dir(expectedJavaPath(dir, runtime.GOOS))
+	ok(os.MkdirAll(bin, 0755))
+	java := filepath.Join(bin, "java")
+	ok(ioutil.WriteFile(java, []byte("java"), 0755))
+	if runtime.GOOS != "windows" {
+		ok(os.Symlink("java", filepath.Join(bin, "java-link")))
+	}
+	ok(recordInstall(&InstallResult{Version: "1.17.0", Path: dir, URL: "https://example.com/jdk.tar.gz", Type: "tgz",
+		SHA256: "0123", OS: runtime.GOOS, Arch: "amd64"}, nil))
+	a, err := Attest("1.17")
+	ok(err)
+	deps := a.Predicate.BuildDefinition.ResolvedDependencies
+	if len(deps) != 1 || deps[0].URI != "https://example.com/jdk.tar.gz" || deps[0].Digest["sha256"] != "0123" {
+		t.Fatalf("actual: %+v", deps)
+	}
+	if v := a.Predicate.RunDetails.Builder.Version; v["extractor"] != "built-in (tar+gzip)" || v["jabba"] == "" {
+		t.Fatalf("actual: %v", v)
+	}
+	b, err := json.Marshal(a)
+	ok(err)
+	file := filepath.Join(home, "attestation.json")
+	ok(ioutil.WriteFile(file, b, 0644))
+	a, err = ReadAttestation(file)
+	ok(err)
+	_, err = VerifyTree("1.17", a)
+	ok(err)
+	// metadata is "fixed up" after file was tampered with
+	ok(ioutil.WriteFile(java, []byte("tampered"), 0755))
+	ok(recordInstall(&InstallResult{Version: "1.17.0", Path: dir, URL: "https://example.com/jdk.tar.gz"}, nil))
+	_, err = VerifyTree("1.17", nil)
+	ok(err)
+	_, err = VerifyTree("1.17", a)
+	if err == nil || !strings.Contains(err.Error(), "modified: "+filepath.ToSlash(strings.TrimPrefix(java, dir+"/"))) {
+		t.Fatalf("expected tampering to be detected (got %v)", err)
+	}
+	installFakeJDKs(t, home, "1.8.0")
+	if _, err := VerifyTree("1.8.0", a); err == nil {
+		t.Fatal("attestation of another JDK should have been rejected")
+	}
+}
@@ -0,0 +1,191 @@
+package command
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// binaryArchs returns architectures executable (ELF, PE or Mach-O (universal (fat) ones included)) is built for
+// (e.g. ["amd64", "arm64"] in case of macOS universal binary).
+func binaryArchs(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return nil, fmt.Errorf("%s is not an executable (%v)", file, err)
+	}
+	var archs []string
+	switch {
+	case bytes.Equal(magic, []byte("\x7fELF")):
+		ef, err := elf.NewFile(f)
+		if err != nil {
+			return nil, err
+		}
+		archs = append(archs, elfArch(ef))
+	case bytes.Equal(magic[:2], []byte("MZ")):
+		pf, err := pe.NewFile(f)
+		if err != nil {
+			return nil, err
+		}
+		archs = append(archs, peArch(pf.Machine))
+	default:
+		ff, err := macho.NewFatFile(f)
+		if err == macho.ErrNotFat {
+			mf, err := macho.NewFile(f)
+			if err != nil {
+				return nil, err
+			}
+			archs = append(archs, machoArch(mf.Cpu))
+		} else if err != nil {
+			return nil, err
+		} else {
+			for _, arch := range ff.Arches {
+				archs = append(archs, machoArch(arch.Cpu))
+			}
+		}
+	}
+	return uniqueSorted(archs), nil
+}
+
+func elfArch(f *elf.File) string {
+	switch f.Machine {
+	case elf.EM_X86_64:
+		return "amd64"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_386:
+		return "386"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_PPC64:
+		if f.ByteOrder == binary.LittleEndian {
+			return "ppc64le"
+		}
+		return "ppc64"
+	case elf.EM_S390:
+		return "s390x"
+	case elf.EM_RISCV:
+		return "riscv64"
+	}
+	return f.Machine.String()
+}
+
+func peArch(machine uint16) string {
+	switch machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		return "amd64"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		return "arm64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		return "386"
+	}
+	return fmt.Sprintf("0x%x", machine)
+}
+
+func machoArch(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "amd64"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.Cpu386:
+		return "386"
+	}
+	return cpu.String()
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool)
+	var r []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			r = append(r, value)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+// JVM library java launcher loads (relative to JAVA_HOME) (the first one that exists is checked)
+var libjvmPatterns = []string{
+	"lib/server/libjvm.*",
+	"jre/lib/server/libjvm.*",
+	"jre/lib/*/server/libjvm.so",
+	"bin/server/jvm.dll",
+	"jre/bin/server/jvm.dll",
+}
+
+// jdkArchs returns architectures JDK (java launcher & JVM library) installed into dir can run on natively
+// (nil if they cannot be determined). goos is the OS JDK is for ("" meaning runtime.GOOS).
+func jdkArchs(dir string, goos string) []string {
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	java := expectedJavaPath(dir, goos)
+	archs, err := binaryArchs(java)
+	if err != nil {
+		return nil
+	}
+	home := filepath.Dir(filepath.Dir(java))
+	for _, pattern := range libjvmPatterns {
+		matches, _ := filepath.Glob(filepath.Join(home, filepath.FromSlash(pattern)))
+		if len(matches) == 0 {
+			continue
+		}
+		libArchs, err := binaryArchs(matches[0])
+		if err != nil {
+			break
+		}
+		// launcher might be universal while JVM is not (or vice versa)
+		supported := make(map[string]bool)
+		for _, arch := range libArchs {
+			supported[arch] = true
+		}
+		var r []string
+		for _, arch := range archs {
+			if supported[arch] {
+				r = append(r, arch)
+			}
+		}
+		return r
+	}
+	return archs
+}
+
+// InstalledArchs returns architectures installed JDK can run on natively (more than one in case of universal builds)
+// (nil if unknown).
+func InstalledArchs(ver string) []string {
+	goos := ""
+	if meta, err := readInstallMeta(ver); err == nil {
+		if len(meta.Archs) != 0 {
+			return meta.Archs
+		}
+		goos = meta.OS
+	}
+	return jdkArchs(filepath.Join(cfg.JDKDir(), ver), goos)
+}
+
+// BuiltFor reports whether installed JDK can run natively on arch (see InstalledArchs).
+func BuiltFor(ver string, arch string) bool {
+	for _, a := range InstalledArchs(ver) {
+		if a == arch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// thinMachO returns (empty) 64-bit Mach-O executable for the cpu.
+func thinMachO(cpu macho.Cpu) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, macho.FileHeader{Magic: macho.Magic64, Cpu: cpu, Type: macho.TypeExec})
+	// reserved
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	return buf.Bytes()
+}
+
+// fatMachO returns universal binary made of thinMachO(cpu) for every cpu.
+func fatMachO(cpus ...macho.Cpu) []byte {
+	var header, body bytes.Buffer
+	binary.Write(&header, binary.BigEndian, []uint32{macho.MagicFat, uint32(len(cpus))})
+	offset := uint32(8 + 20*len(cpus))
+	for _, cpu := range cpus {
+		b := thinMachO(cpu)
+		binary.Write(&header, binary.BigEndian, []uint32{uint32(cpu), 0, offset + uint32(body.Len()), uint32(len(b)), 0})
+		body.Write(b)
+	}
+	return append(header.Bytes(), body.Bytes()...)
+}
+
+func TestJDKArchs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-arch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(file string, content []byte) {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, content, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// test binary is an executable of the host platform
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := filepath.Join(dir, "host")
+	write(expectedJavaPath(host, runtime.GOOS), b)
+	if actual, expected := jdkArchs(host, ""), []string{runtime.GOARCH}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	universal := filepath.Join(dir, "universal")
+	write(expectedJavaPath(universal, "darwin"), fatMachO(macho.CpuAmd64, macho.CpuArm64))
+	if actual, expected := jdkArchs(universal, "darwin"), []string{"amd64", "arm64"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	// universal launcher, single-arch JVM
+	write(filepath.Join(universal, "Contents", "Home", "lib", "server", "libjvm.dylib"), thinMachO(macho.CpuArm64))
+	if actual, expected := jdkArchs(universal, "darwin"), []string{"arm64"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	broken := filepath.Join(dir, "broken")
+	write(expectedJavaPath(broken, "linux"), []byte("#!/bin/sh\n"))
+	if actual := jdkArchs(broken, "linux"); actual != nil {
+		t.Fatalf("actual: %v != expected: %v", actual, nil)
+	}
+}
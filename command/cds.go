@@ -0,0 +1,134 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// cdsMeta records (default) class data sharing archive generated by jabba (see dumpCDS).
+type cdsMeta struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	// paths of the archives relative to the JDK dir (e.g. "lib/server/classes.jsa")
+	Archives []string `json:"archives,omitempty"`
+	// why archive couldn't be generated (if it couldn't)
+	Error string `json:"error,omitempty"`
+}
+
+// where CDS archives are kept (relative to JAVA_HOME)
+var cdsArchivePatterns = []string{
+	"lib/server/*.jsa",
+	"lib/*/server/*.jsa",
+	"jre/lib/server/*.jsa",
+	"jre/lib/*/server/*.jsa",
+	"bin/server/*.jsa",
+	"jre/bin/server/*.jsa",
+}
+
+// dumpCDS (re)generates default CDS archive (the one AppCDS / dynamic archives are layered on top of) of the JDK
+// installed into dir with `java -Xshare:dump` (and `-XX:-UseCompressedOops -Xshare:dump` if JDK ships
+// classes_nocoops.jsa).
+func dumpCDS(dir string) (*cdsMeta, error) {
+	java := expectedJavaPath(dir, runtime.GOOS)
+	if _, err := os.Stat(java); err != nil {
+		return nil, err
+	}
+	home := filepath.Dir(filepath.Dir(java))
+	runs := [][]string{{"-Xshare:dump"}}
+	for _, file := range cdsArchives(home) {
+		if filepath.Base(file) == "classes_nocoops.jsa" {
+			runs = append(runs, []string{"-XX:-UseCompressedOops", "-Xshare:dump"})
+			break
+		}
+	}
+	for _, args := range runs {
+		out, err := outputOf(exec.Command(java, args...))
+		if err != nil {
+			return nil, fmt.Errorf("`java %s` failed: %v\n%s", strings.Join(args, " "), err, strings.TrimSpace(out))
+		}
+	}
+	r := &cdsMeta{GeneratedAt: time.Now().UTC()}
+	for _, file := range cdsArchives(home) {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		r.Archives = append(r.Archives, filepath.ToSlash(rel))
+	}
+	if len(r.Archives) == 0 {
+		// e.g. OpenJ9, which has its own (-Xshareclasses) cache
+		return nil, fmt.Errorf("`java -Xshare:dump` didn't produce any archives under %s", home)
+	}
+	return r, nil
+}
+
+func cdsArchives(home string) []string {
+	var r []string
+	for _, pattern := range cdsArchivePatterns {
+		matches, _ := filepath.Glob(filepath.Join(home, filepath.FromSlash(pattern)))
+		r = append(r, matches...)
+	}
+	return r
+}
+
+// generateCDSOnInstall runs dumpCDS if opts.CDS (or "cds" in config.yaml) is true (failures are logged, not returned,
+// as JDK is usable without the archive).
+func generateCDSOnInstall(result *InstallResult, opts InstallOptions) *cdsMeta {
+	if !opts.CDS && !cfg.CDS() {
+		return nil
+	}
+	log.Info("Generating CDS archive of ", result.Version)
+	cds, err := dumpCDS(result.Path)
+	if err != nil {
+		log.Warn("Failed to generate CDS archive of ", result.Version, " (", err, ")")
+		return &cdsMeta{GeneratedAt: time.Now().UTC(), Error: err.Error()}
+	}
+	return cds
+}
+
+// RegenerateCDS (re)generates CDS archive of the installed JDK matching the selector (see InstallOptions.CDS),
+// returning paths of the archives. JDK's metadata is updated accordingly (so that `jabba verify` keeps passing).
+func RegenerateCDS(selector string) ([]string, error) {
+	ver, err := Resolve(selector)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(cfg.JDKDir(), ver)
+	cds, err := dumpCDS(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordCDS(ver, cds); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var r []string
+	for _, archive := range cds.Archives {
+		r = append(r, filepath.Join(dir, filepath.FromSlash(archive)))
+	}
+	return r, nil
+}
+
+// recordCDS stores cds in the metadata of JDK (refreshing checksums of the archives).
+func recordCDS(ver string, cds *cdsMeta) error {
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(cfg.JDKDir(), ver)
+	for _, archive := range cds.Archives {
+		sum, err := sha256OfFile(filepath.Join(dir, filepath.FromSlash(archive)))
+		if err != nil {
+			return err
+		}
+		meta.Files[archive] = sum
+	}
+	meta.CDS = cds
+	return writeInstallMeta(meta)
+}
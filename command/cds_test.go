@@ -0,0 +1,69 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestRegenerateCDS(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("java stub is a shell script")
+	}
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	dir := filepath.Join(home, "jdk", "1.17.0")
+	java := expectedJavaPath(dir, runtime.GOOS)
+	server := filepath.Join(filepath.Dir(filepath.Dir(java)), "lib", "server")
+	ok(os.MkdirAll(filepath.Dir(java), 0755))
+	ok(os.MkdirAll(server, 0755))
+	// JDK 15+ ship both archives
+	ok(ioutil.WriteFile(filepath.Join(server, "classes.jsa"), []byte("shipped"), 0644))
+	ok(ioutil.WriteFile(filepath.Join(server, "classes_nocoops.jsa"), []byte("shipped"), 0644))
+	ok(ioutil.WriteFile(java, []byte(`#!/bin/sh
+dir="$(dirname "$0")/../lib/server"
+case "$*" in
+  "-Xshare:dump") echo "dumped" > "$dir/classes.jsa" ;;
+  "-XX:-UseCompressedOops -Xshare:dump") echo "dumped" > "$dir/classes_nocoops.jsa" ;;
+  *) exit 1 ;;
+esac
+`), 0755))
+	ok(recordInstall(&InstallResult{Version: "1.17.0", Path: dir, URL: "https://example.com/jdk.tar.gz"}, nil))
+	archives, err := RegenerateCDS("1.17")
+	ok(err)
+	if expected := []string{filepath.Join(server, "classes.jsa"), filepath.Join(server, "classes_nocoops.jsa")}; !reflect.DeepEqual(archives, expected) {
+		t.Fatalf("actual: %v != expected: %v", archives, expected)
+	}
+	for _, archive := range archives {
+		if b, _ := ioutil.ReadFile(archive); string(b) != "dumped\n" {
+			t.Fatalf("%s wasn't regenerated", archive)
+		}
+	}
+	// checksums of the archives are updated
+	_, err = Verify("1.17")
+	ok(err)
+	meta, err := readInstallMeta("1.17.0")
+	ok(err)
+	if meta.CDS == nil || len(meta.CDS.Archives) != 2 || meta.CDS.Error != "" {
+		t.Fatalf("actual: %+v", meta.CDS)
+	}
+	// failure is recorded (but doesn't fail the install)
+	ok(ioutil.WriteFile(java, []byte("#!/bin/sh\necho 'Error: unsupported' >&2\nexit 1\n"), 0755))
+	if cds := generateCDSOnInstall(&InstallResult{Version: "1.17.0", Path: dir}, InstallOptions{CDS: true}); cds == nil || cds.Error == "" {
+		t.Fatalf("actual: %+v", cds)
+	}
+	if cds := generateCDSOnInstall(&InstallResult{Version: "1.17.0", Path: dir}, InstallOptions{}); cds != nil {
+		t.Fatalf("actual: %+v != expected: nil", cds)
+	}
+}
@@ -0,0 +1,124 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/ioprogress"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Checksum calculates sha256 of a local file or a remote URL (without saving it to disk).
+// Result is in the form expected by the index / install (i.e. "<url>#sha256=<hex>").
+func Checksum(src string) (string, error) {
+	// "<qualifier>+<url>" is accepted too
+	if matched, _ := regexp.MatchString("^\\w+[+]\\w+://", src); matched {
+		src = src[strings.Index(src, "+")+1:]
+	}
+	var r io.Reader
+	var size int64 = -1
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		req, err := http.NewRequest("GET", src, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Cookie", "oraclelicense=accept-securebackup-cookie")
+		res, err := newDownloadClient().Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		if res.StatusCode >= 400 {
+			return "", errors.New("GET " + src + " returned " + strconv.Itoa(res.StatusCode))
+		}
+		r, size = res.Body, res.ContentLength
+	} else {
+		f, err := os.Open(strings.TrimPrefix(src, "file://"))
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if stat, err := f.Stat(); err == nil {
+			size = stat.Size()
+		}
+		r = f
+	}
+	// stdout is reserved for the checksum
+	sum, err := sha256Of(&ioprogress.Reader{
+		Reader:   r,
+		Size:     size,
+		DrawFunc: newProgressDrawFunc(src, 0),
+	})
+	if err != nil {
+		return "", err
+	}
+	return "sha256=" + sum, nil
+}
+
+func sha256Of(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fragment is what can follow "#" in the URL of an archive, i.e.
+// "sha256=<hex>[&sig=<signature url>&key=<key>]" (see verifySignature for sig & key).
+type fragment struct {
+	checksum string
+	sig      string
+	key      string
+}
+
+// splitFragment splits "<url>#sha256=<hex>&sig=...&key=..." into "<url>" and fragment.
+func splitFragment(url string) (string, fragment, error) {
+	var f fragment
+	i := strings.LastIndex(url, "#")
+	if i == -1 {
+		return url, f, nil
+	}
+	for _, param := range strings.Split(url[i+1:], "&") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return "", f, fmt.Errorf("Unsupported URL fragment \"%s\" (expected #sha256=<hex>)", param)
+		}
+		switch kv[0] {
+		case "sha256":
+			f.checksum = strings.ToLower(param)
+		case "sig":
+			f.sig = kv[1]
+		case "key":
+			f.key = kv[1]
+		default:
+			return "", f, fmt.Errorf("Unsupported URL fragment \"%s\" (expected #sha256=<hex>)", param)
+		}
+	}
+	if (f.sig == "") != (f.key == "") {
+		return "", f, fmt.Errorf("Both sig and key have to be specified (%s)", url[i:])
+	}
+	return url[:i], f, nil
+}
+
+func sha256OfFile(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return sha256Of(f)
+}
+
+// verifyChecksum compares sha256 (hex) against "sha256=<hex>".
+func verifyChecksum(sum string, checksum string) error {
+	if "sha256="+sum != checksum {
+		return fmt.Errorf("Checksum mismatch (expected %s, got sha256=%s)", checksum, sum)
+	}
+	return nil
+}
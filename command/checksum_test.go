@@ -0,0 +1,54 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checksum_test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "jdk.tar.gz")
+	if err := ioutil.WriteFile(file, []byte("jdk"), 0644); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := "sha256=b2b7e3b5fba4d5e5e3e8d5b4e0b0ed1e0fe5f5c1fdbf2e39fa9f7f1ef5ec3b4c"
+	actual, err := Checksum("tgz+file://" + file)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sum, err := sha256OfFile(file)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := verifyChecksum(sum, actual); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := verifyChecksum(sum, expected); err == nil {
+		t.Fatalf("expected checksum mismatch")
+	}
+	url, f, err := splitFragment("https://example.com/jdk.tar.gz#" + actual)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if url != "https://example.com/jdk.tar.gz" || f.checksum != actual {
+		t.Fatalf("actual: %v, %v != expected: %v, %v", url, f.checksum, "https://example.com/jdk.tar.gz", actual)
+	}
+	url, f, err = splitFragment("https://example.com/jdk.tar.gz#" + actual + "&sig=.asc&key=adoptium")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if expected := (fragment{actual, ".asc", "adoptium"}); url != "https://example.com/jdk.tar.gz" || f != expected {
+		t.Fatalf("actual: %v, %v != expected: %v, %v", url, f, "https://example.com/jdk.tar.gz", expected)
+	}
+	for _, rejected := range []string{"#md5=0", "#sig=.asc", "#sha256=", "#" + actual + "&"} {
+		if _, _, err := splitFragment("https://example.com/jdk.tar.gz" + rejected); err == nil {
+			t.Fatalf("expected %s to be rejected", rejected)
+		}
+	}
+}
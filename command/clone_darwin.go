@@ -0,0 +1,17 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// cloneFile creates dst sharing data blocks with src (APFS clonefile(2)).
+func cloneFile(src string, dst string) error {
+	// cp -c fails if file system doesn't support cloning (instead of falling back to copying)
+	out, err := outputOf(exec.Command("/bin/cp", "-c", src, dst))
+	if err != nil {
+		return fmt.Errorf("cp -c %s %s failed: %v (%s)", src, dst, err, strings.TrimSpace(out))
+	}
+	return nil
+}
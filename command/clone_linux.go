@@ -0,0 +1,33 @@
+package command
+
+import (
+	"os"
+	"syscall"
+)
+
+// FICLONE (_IOW(0x94, 9, int))
+const ficlone = 0x40049409
+
+// cloneFile creates dst sharing data blocks with src (reflink) (btrfs, xfs (reflink=1), etc.).
+func cloneFile(src string, dst string) error {
+	s, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	info, err := s.Stat()
+	if err != nil {
+		return err
+	}
+	d, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, d.Fd(), ficlone, s.Fd())
+	d.Close()
+	if errno != 0 {
+		os.Remove(dst)
+		return &os.PathError{Op: "ioctl(FICLONE)", Path: dst, Err: errno}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package command
+
+import "errors"
+
+func cloneFile(src string, dst string) error {
+	return errors.New("cloning is not supported on this platform")
+}
@@ -0,0 +1,120 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+// compatIssue is a known incompatibility between (old) JDKs and (new) host OS versions. Unlike
+// Release.Requires (declared by the index entry), these are kept here and apply to every distribution.
+type compatIssue struct {
+	os   string
+	arch string // "" means any
+	// archive type ("" means any)
+	fileType string
+	// Java major versions affected (inclusive)
+	minMajor, maxMajor int
+	// major -> first update the issue is fixed in (majors that are not listed are affected whatever the update)
+	fixedIn map[int]int
+	// host property ("macos", "glibc" or "cgroup2") & range of its versions affected ("" means unbounded)
+	host     string
+	min, max string
+	message  string
+	// true if JDK is known not to install / start at all (as opposed to misbehave)
+	fatal bool
+}
+
+var compatIssues = []compatIssue{
+	{os: "darwin", fileType: "dmg", minMajor: 1, maxMajor: 7, host: "macos", min: "11",
+		message: "Apple / Oracle JDK 6/7 installers (.dmg) are known to fail on macOS 11+", fatal: true},
+	{os: "darwin", arch: "arm64", minMajor: 1, maxMajor: 99, host: "macos", min: "14.4", max: "14.4.0",
+		message: "Java processes may crash with SIGKILL on macOS 14.4 (fixed in 14.4.1)"},
+	{os: "linux", minMajor: 8, maxMajor: 14, fixedIn: map[int]int{8: 372, 11: 16}, host: "cgroup2",
+		message: "container memory / CPU limits (cgroup v2) are ignored (JVM sizes heap & thread pools after the " +
+			"host) (fixed in 8u372 / 11.0.16 / 15+)"},
+	{os: "linux", minMajor: 17, maxMajor: 99, host: "glibc", max: "2.16",
+		message: "JDK 17+ builds require glibc 2.17+"},
+}
+
+// hostInCgroup2Container returns "1" if jabba runs inside a container on a cgroup v2 host ("" otherwise).
+var hostInCgroup2Container = func() (string, error) {
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err != nil {
+		return "", nil
+	}
+	if os.Getenv("container") != "" {
+		return "1", nil
+	}
+	for _, file := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(file); err == nil {
+			return "1", nil
+		}
+	}
+	return "", nil
+}
+
+// javaUpdate returns update number of the Java version (292 for 1.8.292-10 & 1.8.0-292, 16 for 1.11.0-16).
+func javaUpdate(v *semver.Version) int {
+	if v.Patch() != 0 {
+		return int(v.Patch())
+	}
+	pre := strings.SplitN(v.Prerelease(), ".", 2)[0]
+	update, _ := strconv.Atoi(pre)
+	return update
+}
+
+// checkCompat warns about known issues of JDK ver (distributed as fileType) on this host, failing if JDK is known
+// not to work at all (unless ignore is true).
+func checkCompat(ver *semver.Version, arch string, fileType string, ignore bool) error {
+	for _, issue := range hostCompatIssues(runtime.GOOS, arch, fileType, ver) {
+		if issue.fatal && !ignore {
+			return fmt.Errorf("%s is not compatible with this host: %s (use --ignore-compat to install anyway)",
+				ver, issue.message)
+		}
+		log.Warn(ver, ": ", issue.message)
+	}
+	return nil
+}
+
+func hostCompatIssues(goos string, arch string, fileType string, ver *semver.Version) []compatIssue {
+	major := javaMajor(ver)
+	if major == 0 {
+		return nil
+	}
+	var r []compatIssue
+	for _, issue := range compatIssues {
+		if issue.os != goos || (issue.arch != "" && issue.arch != arch) ||
+			(issue.fileType != "" && issue.fileType != fileType) ||
+			major < issue.minMajor || major > issue.maxMajor {
+			continue
+		}
+		if fixed, ok := issue.fixedIn[major]; ok && javaUpdate(ver) >= fixed {
+			continue
+		}
+		var detect func() (string, error)
+		switch issue.host {
+		case "macos":
+			detect = hostMacOSVersion
+		case "glibc":
+			detect = hostGlibcVersion
+		case "cgroup2":
+			detect = hostInCgroup2Container
+		}
+		actual, err := detect()
+		if err != nil || actual == "" {
+			log.Debug("Skipping compatibility check (", issue.host, " is unknown)")
+			continue
+		}
+		if (issue.min != "" && compareDotted(actual, issue.min) < 0) ||
+			(issue.max != "" && compareDotted(actual, issue.max) > 0) {
+			continue
+		}
+		r = append(r, issue)
+	}
+	return r
+}
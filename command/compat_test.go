@@ -0,0 +1,52 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+func TestHostCompatIssues(t *testing.T) {
+	prevHostGlibcVersion := hostGlibcVersion
+	defer func() { hostGlibcVersion = prevHostGlibcVersion }()
+	prevHostMacOSVersion := hostMacOSVersion
+	defer func() { hostMacOSVersion = prevHostMacOSVersion }()
+	prevHostInCgroup2Container := hostInCgroup2Container
+	defer func() { hostInCgroup2Container = prevHostInCgroup2Container }()
+	hostGlibcVersion = func() (string, error) { return "2.12", nil }
+	hostInCgroup2Container = func() (string, error) { return "1", nil }
+	for _, scenario := range []struct {
+		os, arch, fileType, macOS, version string
+		issues                             int
+		fatal                              bool
+	}{
+		{"darwin", "amd64", "dmg", "12.6", "1.6.65", 1, true},
+		{"darwin", "amd64", "dmg", "10.15.7", "1.6.65", 0, false},
+		{"darwin", "amd64", "tgz", "12.6", "1.7.80", 0, false},
+		{"darwin", "arm64", "tgz", "14.4", "1.17.0-10", 1, false},
+		{"darwin", "arm64", "tgz", "14.4.1", "1.17.0-10", 0, false},
+		{"linux", "amd64", "tgz", "", "1.8.282", 1, false},
+		{"linux", "amd64", "tgz", "", "adopt@1.8.0-292", 1, false},
+		{"linux", "amd64", "tgz", "", "zulu@1.8.372", 0, false},
+		{"linux", "amd64", "tgz", "", "1.11.0-11", 1, false},
+		{"linux", "amd64", "tgz", "", "1.11.0-16", 0, false},
+		{"linux", "amd64", "tgz", "", "1.17.0-1", 1, false},
+		{"linux", "amd64", "tgz", "", "graalvm@21.0.1", 0, false},
+	} {
+		macOS := scenario.macOS
+		hostMacOSVersion = func() (string, error) { return macOS, nil }
+		ver, err := semver.ParseVersion(scenario.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		issues := hostCompatIssues(scenario.os, scenario.arch, scenario.fileType, ver)
+		if len(issues) != scenario.issues || (len(issues) != 0 && issues[0].fatal != scenario.fatal) {
+			t.Fatalf("%v: actual: %v != expected: %d (fatal: %v)", scenario, issues, scenario.issues, scenario.fatal)
+		}
+	}
+	hostInCgroup2Container = func() (string, error) { return "", nil }
+	ver, _ := semver.ParseVersion("1.8.282")
+	if issues := hostCompatIssues("linux", "amd64", "tgz", ver); len(issues) != 0 {
+		t.Fatalf("actual: %v != expected: none", issues)
+	}
+}
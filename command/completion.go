@@ -0,0 +1,173 @@
+package command
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// completion scripts delegate to `jabba __complete --current=<word being completed> -- <preceding words>`,
+// which prints candidates (one per line, optionally followed by a tab and a description)
+var completions = map[string]string{
+	// bash has no way to show descriptions
+	"bash": `_jabba_completion() {
+    local IFS=$'\n'
+    COMPREPLY=($(%[1]s __complete --descriptions=off --current="${COMP_WORDS[COMP_CWORD]}" -- "${COMP_WORDS[@]:1:$((COMP_CWORD-1))}" 2>/dev/null))
+}
+complete -o default -F _jabba_completion jabba
+`,
+	"zsh": `_jabba_completion() {
+    local -a candidates values displays
+    candidates=("${(@f)$(%[1]s __complete%[2]s --current="${words[CURRENT]}" -- "${(@)words[2,CURRENT-1]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        local c
+        for c in $candidates; do
+            values+=("${c%%$'\t'*}")
+            if [[ "$c" == *$'\t'* ]]; then
+                displays+=("${c%%$'\t'*}  -- ${c#*$'\t'}")
+            else
+                displays+=("$c")
+            fi
+        done
+        compadd -Q -l -d displays -- $values
+    else
+        _files
+    fi
+}
+# compdef is defined by compinit (oh-my-zsh runs it before loading plugins)
+if (( $+functions[compdef] )); then
+    compdef _jabba_completion jabba
+fi
+`,
+	"fish": `function __jabba_completion
+    %[1]s __complete%[2]s --current=(commandline -ct) -- (commandline -opc)[2..-1] 2>/dev/null
+end
+complete -c jabba -e
+complete -c jabba -f -a '(__jabba_completion)'
+`,
+	"pwsh": `Register-ArgumentCompleter -Native -CommandName jabba -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 |
+        Where-Object { $_.Extent.EndOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+    & %[1]s __complete%[2]s "--current=$wordToComplete" -- @words 2>$null | ForEach-Object {
+        $value, $description = $_ -split "` + "`" + `t", 2
+        if (-not $description) { $description = $value }
+        [System.Management.Automation.CompletionResult]::new($value, $value, 'ParameterValue', $description)
+    }
+}
+`,
+}
+
+// Completion returns completion script for the specified shell (bash, zsh, fish or powershell).
+// bin is the path to jabba executable (see ShellIntegration).
+// descriptions controls whether candidates are shown with descriptions (on shells that support them).
+func Completion(shell string, bin string, descriptions bool) (string, error) {
+	if alias, ok := shellAliases[shell]; ok {
+		shell = alias
+	}
+	script, ok := completions[shell]
+	if !ok {
+		return "", fmt.Errorf("Unsupported shell \"%s\" (must be one of bash, zsh, fish, powershell)", shell)
+	}
+	var opts string
+	if !descriptions {
+		opts = " --descriptions=off"
+	}
+	return fmt.Sprintf(script, binExpr(shell, bin), opts), nil
+}
+
+// first line of the oh-my-zsh plugin generated by jabba (see OhMyZshPlugin)
+const ohMyZshPluginMarker = "# generated by `jabba completion zsh --oh-my-zsh`"
+
+// OhMyZshPlugin returns jabba.plugin.zsh (shell integration (see ShellIntegration) + zsh completion) meant to be put
+// into $ZSH_CUSTOM/plugins/jabba, where it shadows jabba plugins that come with oh-my-zsh (or were installed by hand)
+// (see checkCompletion).
+func OhMyZshPlugin(bin string, descriptions bool) (string, error) {
+	integration, err := ShellIntegration("zsh", bin)
+	if err != nil {
+		return "", err
+	}
+	completion, err := Completion("zsh", bin, descriptions)
+	if err != nil {
+		return "", err
+	}
+	return ohMyZshPluginMarker + "\n" +
+		"# (to be saved as $ZSH_CUSTOM/plugins/jabba/jabba.plugin.zsh, with jabba listed in plugins=(...))\n\n" +
+		"# whatever other jabba plugins might have defined\n" +
+		"unalias jabba 2>/dev/null\n" +
+		"unfunction _jabba 2>/dev/null\n\n" +
+		integration + "\n" + completion, nil
+}
+
+// rebuilding the list of remote versions means parsing (and sorting) the whole index (and possibly querying vendor
+// APIs), which is way too slow to be done every time <TAB> is pressed
+var completionCacheTTL = time.Hour
+
+func completionCacheDir() string {
+	return filepath.Join(cfg.StateDir(), "cache", "completion")
+}
+
+// CachedRemoteVersions returns versions (latest first) available from the default providers (see LsRemote).
+// The list is cached in $JABBA_HOME/cache/completion for an hour (or until `jabba refresh`).
+func CachedRemoteVersions(goos, arch string) ([]string, error) {
+	h := sha1.Sum([]byte(strings.Join(append(cfg.Providers(), cfg.Registry()...), ",")))
+	file := filepath.Join(completionCacheDir(), "remote-"+goos+"-"+arch+"-"+hex.EncodeToString(h[:8]))
+	if vs, ok := readCompletionCache(file); ok {
+		return vs, nil
+	}
+	releaseMap, err := LsRemote(goos, arch)
+	if err != nil {
+		return nil, err
+	}
+	vs := make([]*semver.Version, 0, len(releaseMap))
+	for v := range releaseMap {
+		vs = append(vs, v)
+	}
+	sort.Sort(sort.Reverse(semver.VersionSlice(vs)))
+	r := make([]string, len(vs))
+	for i, v := range vs {
+		r[i] = v.String()
+	}
+	writeCompletionCache(file, r)
+	return r, nil
+}
+
+func readCompletionCache(file string) ([]string, bool) {
+	stat, err := os.Stat(file)
+	if err != nil || time.Since(stat.ModTime()) > completionCacheTTL {
+		return nil, false
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	if len(b) == 0 {
+		return []string{}, true
+	}
+	return strings.Split(string(b), "\n"), true
+}
+
+// errors are ignored (cache is an optimization)
+func writeCompletionCache(file string, values []string) {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return
+	}
+	if err := ioutil.WriteFile(file+".tmp", []byte(strings.Join(values, "\n")), 0644); err == nil {
+		os.Rename(file+".tmp", file)
+	}
+}
+
+// invalidateCompletionCache is called whenever index changes.
+func invalidateCompletionCache() {
+	os.RemoveAll(completionCacheDir())
+}
@@ -0,0 +1,164 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// completion is expected to stay within budget with an index of that size (see README.md)
+const (
+	completionIndexSize = 5000
+	completionBudget    = 100 * time.Millisecond
+)
+
+func writeLargeIndex(t testing.TB, file string, n int) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `{"%s": {"%s": {"jdk@vendor": {`, runtime.GOOS, runtime.GOARCH)
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `"1.%d.%d": "tgz+https://example.com/%d.tar.gz"`, 8+i/100, i%100, i)
+	}
+	b.WriteString("}}}}")
+	if err := ioutil.WriteFile(file, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachedRemoteVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("JABBA_HOME", dir)
+	defer os.Unsetenv("JABBA_HOME")
+	index := filepath.Join(dir, "index.json")
+	writeLargeIndex(t, index, completionIndexSize)
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	vs, err := CachedRemoteVersions(runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != completionIndexSize || vs[0] != "vendor@1.57.99" {
+		t.Fatalf("actual: %v (%v) != expected: %v (vendor@1.57.99 first)", len(vs), vs[0], completionIndexSize)
+	}
+	// cached copy is served until it expires (or index is refreshed)
+	writeLargeIndex(t, index, 1)
+	start := time.Now()
+	vs, err = CachedRemoteVersions(runtime.GOOS, runtime.GOARCH)
+	if elapsed := time.Since(start); elapsed > completionBudget {
+		t.Fatalf("cached lookup took %v (budget: %v)", elapsed, completionBudget)
+	}
+	if err != nil || len(vs) != completionIndexSize {
+		t.Fatalf("actual: %v (%v) != expected: %v", len(vs), err, completionIndexSize)
+	}
+	invalidateCompletionCache()
+	vs, err = CachedRemoteVersions(runtime.GOOS, runtime.GOARCH)
+	if err != nil || len(vs) != 1 {
+		t.Fatalf("actual: %v (%v) != expected: 1", vs, err)
+	}
+}
+
+func BenchmarkCachedRemoteVersions(b *testing.B) {
+	dir, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("JABBA_HOME", dir)
+	defer os.Unsetenv("JABBA_HOME")
+	index := filepath.Join(dir, "index.json")
+	writeLargeIndex(b, index, completionIndexSize)
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := CachedRemoteVersions(runtime.GOOS, runtime.GOARCH); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestCompletionInShells loads completion scripts into real shells (the ones that are installed) and checks that
+// words are passed to `jabba __complete` (stubbed) as expected & candidates make it back.
+func TestCompletionInShells(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "jabba-completion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bin := filepath.Join(dir, "jabba stub")
+	argsFile := filepath.Join(dir, "args")
+	stub := "#!/bin/sh\n" +
+		"printf '%s\\n' \"$@\" > '" + argsFile + "'\n" +
+		"case \" $* \" in *\" --descriptions=off \"*) printf 'amd64\\narm64\\n' ;;\n" +
+		"*) printf 'amd64\\tx86-64\\narm64\\tAArch64\\n' ;; esac\n"
+	if err := ioutil.WriteFile(bin, []byte(stub), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, scenario := range []struct {
+		shell, run   string
+		descriptions bool
+		expected     []string
+	}{
+		{"bash", "COMP_WORDS=(jabba install --arch a); COMP_CWORD=3; _jabba_completion; " +
+			"printf '%s\\n' \"${COMPREPLY[@]}\"", false, []string{"amd64", "arm64"}},
+		// compadd & compdef are only available inside completion system
+		{"zsh", "words=(jabba install --arch a); CURRENT=4; _jabba_completion", true,
+			[]string{"-Q", "-l", "-d", "displays", "--", "amd64", "arm64"}},
+		{"fish", "complete -C 'jabba install --arch a'", true, []string{"amd64\tx86-64", "arm64\tAArch64"}},
+	} {
+		sh, err := exec.LookPath(scenario.shell)
+		if err != nil {
+			t.Logf("%s is not installed (skipping)", scenario.shell)
+			continue
+		}
+		script, err := Completion(scenario.shell, bin, scenario.descriptions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(dir, "completion."+scenario.shell)
+		if err := ioutil.WriteFile(file, []byte(script), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"-c", ". '" + file + "'; " + scenario.run}
+		switch scenario.shell {
+		case "zsh":
+			args = []string{"-f", "-c", "compdef() { :; }; compadd() { print -rl -- \"$@\"; }; " + args[1]}
+		case "fish":
+			args = []string{"-c", "source '" + file + "'; " + scenario.run}
+		}
+		out, err := exec.Command(sh, args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", scenario.shell, err, out)
+		}
+		if actual := strings.Split(strings.TrimSpace(string(out)), "\n"); !reflect.DeepEqual(actual, scenario.expected) {
+			t.Fatalf("%s: actual: %q != expected: %q", scenario.shell, actual, scenario.expected)
+		}
+		b, err := ioutil.ReadFile(argsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := strings.Fields(string(b)); actual[len(actual)-4] != "--current=a" ||
+			!reflect.DeepEqual(actual[len(actual)-3:], []string{"--", "install", "--arch"}) {
+			t.Fatalf("%s: unexpected `jabba __complete` arguments: %q", scenario.shell, actual)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// GraalVM components (native-image, js, python, ...) are managed with `gu` (GraalVM Updater) GraalVM ships with.
+// Components added with `jabba component add` are recorded in the metadata of JDK (see installMeta.Components) so that
+// they could be added again when JDK is replaced (see Upgrade) or installed elsewhere (see Export & ImportLockfile).
+
+// Component is an entry of `gu list`.
+type Component struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	// true if component was added with `jabba component add` (and so is re-added whenever JDK is re-installed)
+	Recorded bool `json:"recorded"`
+}
+
+// guPath returns path to `gu` of the GraalVM installed into dir.
+func guPath(dir string) (string, error) {
+	home := filepath.Dir(filepath.Dir(expectedJavaPath(dir, runtime.GOOS)))
+	gu := "gu"
+	if runtime.GOOS == "windows" {
+		gu = "gu.cmd"
+	}
+	for _, sub := range []string{"bin", filepath.Join("lib", "installer", "bin")} {
+		path := filepath.Join(home, sub, gu)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s doesn't come with gu (either it's not GraalVM or it's GraalVM for JDK 17.0.7+ / 21+, "+
+		"which ships native-image built-in & has languages installed as Maven dependencies)", filepath.Base(dir))
+}
+
+// runGu runs `gu args...` of the GraalVM installed into dir.
+func runGu(dir string, args ...string) (string, error) {
+	gu, err := guPath(dir)
+	if err != nil {
+		return "", err
+	}
+	home := filepath.Dir(filepath.Dir(gu))
+	if filepath.Base(home) == "installer" {
+		home = filepath.Dir(filepath.Dir(home))
+	}
+	cmd := exec.Command(gu, args...)
+	cmd.Env = append(os.Environ(), "JAVA_HOME="+home, "GRAALVM_HOME="+home)
+	out, err := outputOf(cmd)
+	if err != nil {
+		return out, fmt.Errorf("`gu %s` failed: %v\n%s", strings.Join(args, " "), err, strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// parseGuList parses output of `gu list` (table, header of which is separated from the rows with "-----").
+func parseGuList(out string) []Component {
+	var r []Component
+	rows := false
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "---") {
+			rows = true
+			continue
+		}
+		if fields := strings.Fields(line); rows && len(fields) >= 2 {
+			r = append(r, Component{ID: fields[0], Version: fields[1]})
+		}
+	}
+	return r
+}
+
+// ListComponents returns components of the installed GraalVM matching the selector.
+func ListComponents(selector string) ([]Component, error) {
+	ver, err := Resolve(selector)
+	if err != nil {
+		return nil, err
+	}
+	out, err := runGu(filepath.Join(cfg.JDKDir(), ver), "list")
+	if err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]bool)
+	for _, id := range recordedComponents(ver) {
+		recorded[id] = true
+	}
+	r := parseGuList(out)
+	for i := range r {
+		r[i].Recorded = recorded[r[i].ID]
+	}
+	return r, nil
+}
+
+// AddComponents installs components (e.g. "native-image") into the installed GraalVM matching the selector,
+// returning components recorded for it afterwards.
+func AddComponents(selector string, ids []string) ([]string, error) {
+	ver, err := Resolve(selector)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runGu(filepath.Join(cfg.JDKDir(), ver), append([]string{"install", "-n"}, ids...)...); err != nil {
+		return nil, err
+	}
+	return recordComponents(ver, ids, nil)
+}
+
+// RemoveComponents uninstalls components from the installed GraalVM matching the selector, returning components
+// recorded for it afterwards.
+func RemoveComponents(selector string, ids []string) ([]string, error) {
+	ver, err := Resolve(selector)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runGu(filepath.Join(cfg.JDKDir(), ver), append([]string{"remove"}, ids...)...); err != nil {
+		return nil, err
+	}
+	return recordComponents(ver, nil, ids)
+}
+
+func recordedComponents(ver string) []string {
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		return nil
+	}
+	return meta.Components
+}
+
+// missingComponents returns ids that are not recorded for JDK ver.
+func missingComponents(ver string, ids []string) []string {
+	recorded := make(map[string]bool)
+	for _, id := range recordedComponents(ver) {
+		recorded[id] = true
+	}
+	var r []string
+	for _, id := range ids {
+		if !recorded[id] {
+			r = append(r, id)
+		}
+	}
+	return r
+}
+
+// recordComponents updates the list of components in the metadata of JDK (refreshing checksums of the files, as
+// components change what's installed).
+func recordComponents(ver string, added []string, removed []string) ([]string, error) {
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Warn("There is no metadata recorded for ", ver, " (components won't be re-added on re-install)")
+			return nil, nil
+		}
+		return nil, err
+	}
+	set := make(map[string]bool)
+	for _, id := range append(meta.Components, added...) {
+		set[id] = true
+	}
+	for _, id := range removed {
+		delete(set, id)
+	}
+	meta.Components = nil
+	for id := range set {
+		meta.Components = append(meta.Components, id)
+	}
+	sort.Strings(meta.Components)
+	dir := filepath.Join(cfg.JDKDir(), ver)
+	if meta.Files, err = digestTree(dir); err != nil {
+		return nil, err
+	}
+	meta.Size = diskUsage(dir)
+	if err := writeInstallMeta(meta); err != nil {
+		return nil, err
+	}
+	return meta.Components, nil
+}
+
+// addComponentsOnInstall adds opts.Components to JDK that has just been installed (failures are logged, not returned,
+// as JDK is usable without them).
+func addComponentsOnInstall(result *InstallResult, opts InstallOptions) {
+	if len(opts.Components) == 0 {
+		return
+	}
+	log.Info("Adding ", strings.Join(opts.Components, ", "), " to ", result.Version)
+	if _, err := AddComponents(result.Version, opts.Components); err != nil {
+		log.Warn("Failed to add components to ", result.Version, " (", err, ")")
+	}
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestComponents(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("gu stub is a shell script")
+	}
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	dir := filepath.Join(home, "jdk", "graalvm@22.3.0")
+	bin := filepath.Dir(expectedJavaPath(dir, runtime.GOOS))
+	ok(os.MkdirAll(bin, 0755))
+	ok(ioutil.WriteFile(filepath.Join(bin, "java"), nil, 0755))
+	// components are kept as files in $JAVA_HOME/components (one per component)
+	ok(ioutil.WriteFile(filepath.Join(bin, "gu"), []byte(`#!/bin/sh
+dir="$JAVA_HOME/components"
+mkdir -p "$dir"
+cmd="$1"; shift
+case "$cmd" in
+  install) [ "$1" = "-n" ] && shift; for c in "$@"; do echo "22.3.0" > "$dir/$c"; done ;;
+  remove) for c in "$@"; do rm "$dir/$c" || exit 1; done ;;
+  list)
+    echo "ComponentId              Version             Component name"
+    echo "-----------------------------------------------------------"
+    echo "graalvm                  22.3.0              GraalVM Core"
+    for c in $(ls "$dir"); do echo "$c                  $(cat "$dir/$c")              $c"; done ;;
+  *) exit 1 ;;
+esac
+`), 0755))
+	ok(recordInstall(&InstallResult{Version: "graalvm@22.3.0", Path: dir, URL: "https://example.com/jdk.tar.gz",
+		Type: "tgz", SHA256: "0"}, nil))
+	recorded, err := AddComponents("graalvm@22", []string{"native-image", "js"})
+	ok(err)
+	if expected := []string{"js", "native-image"}; !reflect.DeepEqual(recorded, expected) {
+		t.Fatalf("actual: %v != expected: %v", recorded, expected)
+	}
+	// checksums of the files are updated
+	_, err = Verify("graalvm@22.3.0")
+	ok(err)
+	recorded, err = RemoveComponents("graalvm@22", []string{"js"})
+	ok(err)
+	if expected := []string{"native-image"}; !reflect.DeepEqual(recorded, expected) {
+		t.Fatalf("actual: %v != expected: %v", recorded, expected)
+	}
+	components, err := ListComponents("graalvm@22")
+	ok(err)
+	if expected := []Component{{ID: "graalvm", Version: "22.3.0"},
+		{ID: "native-image", Version: "22.3.0", Recorded: true}}; !reflect.DeepEqual(components, expected) {
+		t.Fatalf("actual: %v != expected: %v", components, expected)
+	}
+	lock, err := Export()
+	ok(err)
+	if len(lock.JDKs) != 1 || !reflect.DeepEqual(lock.JDKs[0].Components, []string{"native-image"}) {
+		t.Fatalf("actual: %+v != expected: components to be exported", lock.JDKs)
+	}
+	installFakeJDKs(t, home, "1.17.0")
+	if _, err := AddComponents("1.17.0", []string{"native-image"}); err == nil {
+		t.Fatal("JDK without gu should have been rejected")
+	}
+}
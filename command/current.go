@@ -4,7 +4,6 @@ import (
 	"github.com/shyiko/jabba/cfg"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 )
 
@@ -13,7 +12,7 @@ var lookPath = exec.LookPath
 func Current() string {
 	javaPath, err := lookPath("java")
 	if err == nil {
-		prefix := filepath.Join(cfg.Dir(), "jdk") + string(os.PathSeparator)
+		prefix := cfg.JDKDir() + string(os.PathSeparator)
 		if strings.HasPrefix(javaPath, prefix) {
 			index := strings.Index(javaPath[len(prefix):], string(os.PathSeparator))
 			if index != -1 {
@@ -10,7 +10,7 @@ func TestCurrent(t *testing.T) {
 	var prevLookPath = lookPath
 	defer func() { lookPath = prevLookPath }()
 	lookPath = func(file string) (string, error) {
-		return filepath.Join(cfg.Dir(), "jdk", "1.8.0", "Contents", "Home", "bin", "java"), nil
+		return filepath.Join(cfg.JDKDir(), "1.8.0", "Contents", "Home", "bin", "java"), nil
 	}
 	actual := Current()
 	expected := "1.8.0"
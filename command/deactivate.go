@@ -1,24 +1,18 @@
 package command
 
 import (
-	"github.com/shyiko/jabba/cfg"
 	"os"
-	"path/filepath"
-	"regexp"
 )
 
-func Deactivate() ([]string, error) {
-	pth, _ := os.LookupEnv("PATH")
-	rgxp := regexp.MustCompile(regexp.QuoteMeta(filepath.Join(cfg.Dir(), "jdk")) + "[^:]+[:]")
+func Deactivate() (*EnvChange, error) {
 	// strip references to ~/.jabba/jdk/*, otherwise leave unchanged
-	pth = rgxp.ReplaceAllString(pth, "")
+	pth, profileVars := undoProfiles(stripJDKs(os.Getenv("PATH")))
 	javaHome, overrideWasSet := os.LookupEnv("JAVA_HOME_BEFORE_JABBA")
 	if !overrideWasSet {
 		javaHome, _ = os.LookupEnv("JAVA_HOME")
 	}
-	return []string{
-		"export PATH=\"" + pth + "\"",
-		"export JAVA_HOME=\"" + javaHome + "\"",
-		"unset JAVA_HOME_BEFORE_JABBA",
-	}, nil
+	// options pointing at truststore of the JDK (see withTruststore)
+	set, truststoreUnset := exportTruststore([]string{"PATH=" + pth, "JAVA_HOME=" + javaHome}, "", "")
+	unset := append(append([]string{"JAVA_HOME_BEFORE_JABBA"}, profileVars...), deactivatedJDKEnv()...)
+	return &EnvChange{Set: set, Unset: append(unset, truststoreUnset...)}, nil
 }
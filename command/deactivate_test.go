@@ -4,6 +4,7 @@ import (
 	"github.com/shyiko/jabba/cfg"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -22,7 +23,7 @@ func TestDeactivate(t *testing.T) {
 		"export JAVA_HOME=\"/system-jdk\"",
 		"unset JAVA_HOME_BEFORE_JABBA",
 	}
-	if !reflect.DeepEqual(actual, expected) {
+	if actual := strings.Split(actual.Script(""), "\n"); !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("actual: %v != expected: %v", actual, expected)
 	}
 }
@@ -42,7 +43,7 @@ func TestDeactivateInUnusedEnv(t *testing.T) {
 		"export JAVA_HOME=\"/system-jdk\"",
 		"unset JAVA_HOME_BEFORE_JABBA",
 	}
-	if !reflect.DeepEqual(actual, expected) {
+	if actual := strings.Split(actual.Script(""), "\n"); !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("actual: %v != expected: %v", actual, expected)
 	}
 }
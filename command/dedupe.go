@@ -0,0 +1,346 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// Files of installed JDKs can be deduplicated (see cfg.Dedupe) against content-addressed store
+// (<cfg.JDKDir()>/.store/<sha256[:2]>/<sha256>-<permissions>) (the same archive installed under two versions (e.g.
+// temurin@1.21.0-3 and a pinned URL) then takes the space of one). Files are either cloned (copy-on-write, so
+// modifying one copy doesn't affect the others) or hard-linked. Checksums recorded at install time (installMeta.Files)
+// are used as keys & blobs JDK references are recorded in its metadata (installMeta.Blobs) (see GC). JDK dir (and so
+// the store) can be shared by multiple jabba homes (see cfg.JDKDir), which is why the store keeps track of the homes
+// metadata of which references its blobs (see storeHomes).
+
+// files smaller than that are not worth it
+const dedupeMinSize = 64 * 1024
+
+// DedupeResult is the outcome of deduplicating single JDK.
+type DedupeResult struct {
+	Version string `json:"version"`
+	// number of files that were replaced with clones / links of the blobs (added by other JDKs)
+	Files int `json:"files"`
+	// bytes saved
+	Saved int64 `json:"saved"`
+}
+
+func storeDir() string {
+	return filepath.Join(cfg.JDKDir(), ".store")
+}
+
+// storeHomes returns jabba homes (current one included) that have deduplicated JDKs against the store.
+func storeHomes() []string {
+	homes := []string{cfg.Dir()}
+	b, _ := ioutil.ReadFile(filepath.Join(storeDir(), "homes"))
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" && line != cfg.Dir() {
+			homes = append(homes, line)
+		}
+	}
+	return homes
+}
+
+// registerStoreHome adds current jabba home to storeHomes (unless it's there already).
+func registerStoreHome() error {
+	for _, home := range storeHomes()[1:] {
+		if home == cfg.Dir() {
+			return nil
+		}
+	}
+	if err := os.MkdirAll(storeDir(), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(storeDir(), "homes"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(f, cfg.Dir())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Dedupe deduplicates files of the installed JDKs (all of them if none is specified) ("auto" (clone, falling back to
+// hard-linking), "clone" or "hardlink" method). Only JDKs with metadata (installed by this version of jabba) are
+// considered.
+func Dedupe(versions []string, method string) ([]DedupeResult, error) {
+	if len(versions) == 0 {
+		vs, err := Ls()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vs {
+			if _, err := os.Stat(metaFile(v.String())); err == nil {
+				versions = append(versions, v.String())
+			}
+		}
+	}
+	var r []DedupeResult
+	for _, selector := range versions {
+		ver, err := Resolve(selector)
+		if err != nil {
+			return r, err
+		}
+		result, err := dedupeInstall(ver, method)
+		if err != nil {
+			return r, err
+		}
+		r = append(r, *result)
+	}
+	return r, nil
+}
+
+// dedupeOnInstall runs dedupeInstall if enabled in config.yaml (failures are logged, not returned).
+func dedupeOnInstall(ver string) {
+	method := cfg.Dedupe()
+	if method == "" {
+		return
+	}
+	result, err := dedupeInstall(ver, method)
+	if err != nil {
+		log.Warn("Failed to deduplicate ", ver, " (", err, ")")
+		return
+	}
+	if result.Files != 0 {
+		log.Info("Deduplicated ", result.Files, " file(s) of ", ver, " (", FormatSize(result.Saved), " saved)")
+	}
+}
+
+func dedupeInstall(ver string, method string) (*DedupeResult, error) {
+	switch method {
+	case "auto", "clone", "hardlink":
+	default:
+		return nil, fmt.Errorf("Unsupported dedupe method \"%s\" (expected auto, clone or hardlink)", method)
+	}
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("There is no metadata recorded for %s (it was either installed by an older "+
+				"version of jabba or `jabba link`ed)", ver)
+		}
+		return nil, err
+	}
+	if err := registerStoreHome(); err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(cfg.JDKDir(), ver)
+	previous := make(map[string]bool)
+	for _, key := range meta.Blobs {
+		previous[key] = true
+	}
+	// `jabba cds regenerate` rewrites archives (which, if hard-linked, would corrupt the blob)
+	skip := make(map[string]bool)
+	if meta.CDS != nil {
+		for _, archive := range meta.CDS.Archives {
+			skip[archive] = true
+		}
+	}
+	result := &DedupeResult{Version: ver}
+	blobs := make(map[string]bool)
+	for rel, sum := range meta.Files {
+		if strings.HasPrefix(sum, "-> ") || skip[rel] {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Size() < dedupeMinSize {
+			continue
+		}
+		// links share permissions
+		key := fmt.Sprintf("%s-%o", sum, info.Mode().Perm())
+		if previous[key] {
+			blobs[key] = true
+			continue
+		}
+		blob := filepath.Join(storeDir(), sum[:2], key)
+		replaced, err := shareBlob(path, blob, sum, method)
+		if err != nil {
+			log.Debug("Not deduplicating ", path, " (", err, ")")
+			continue
+		}
+		blobs[key] = true
+		if replaced {
+			result.Files++
+			result.Saved += info.Size()
+		}
+	}
+	meta.Blobs = make([]string, 0, len(blobs))
+	for key := range blobs {
+		meta.Blobs = append(meta.Blobs, key)
+	}
+	sort.Strings(meta.Blobs)
+	return result, writeInstallMeta(meta)
+}
+
+// shareBlob replaces file with a clone / link of the blob (replaced is true) or, if there is no (intact) blob yet,
+// turns file into one.
+func shareBlob(file string, blob string, sum string, method string) (replaced bool, err error) {
+	if _, err := os.Stat(blob); err == nil {
+		if stat, err := os.Stat(file); err == nil {
+			if blobStat, err := os.Stat(blob); err == nil && os.SameFile(stat, blobStat) {
+				return false, nil
+			}
+		}
+		// blob might have been modified through one of the hard links
+		if actual, err := sha256OfFile(blob); err != nil || actual != sum {
+			log.Warn(blob, " is corrupted (replacing)")
+			os.Remove(blob)
+		} else {
+			tmp := file + ".jabba-dedupe"
+			os.Remove(tmp)
+			if err := share(blob, tmp, method); err != nil {
+				return false, err
+			}
+			if err := os.Rename(tmp, file); err != nil {
+				os.Remove(tmp)
+				return false, err
+			}
+			return true, nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return false, err
+	}
+	return false, share(file, blob, method)
+}
+
+func share(src string, dst string, method string) error {
+	if method == "hardlink" {
+		return os.Link(src, dst)
+	}
+	err := cloneFile(src, dst)
+	if err != nil && method == "auto" {
+		log.Debug("Cloning ", src, " failed (", err, "), hard-linking instead")
+		return os.Link(src, dst)
+	}
+	return err
+}
+
+// unshare replaces file with a copy of its own, so that it can be modified in place without modifying the blob it
+// might be a hard link to.
+func unshare(file string) error {
+	info, err := os.Lstat(file)
+	if err != nil || !info.Mode().IsRegular() {
+		return err
+	}
+	tmp := file + ".jabba-unshare"
+	os.Remove(tmp)
+	if err := copyFileTo(file, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := chmod(tmp, info.Mode().Perm()); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// pruneBlobs removes blobs none of the files of JDK (in dir) match anymore (e.g. the ones modified by post_install)
+// from meta.Blobs (meta.Files are expected to be up-to-date).
+func pruneBlobs(meta *installMeta, dir string) {
+	if len(meta.Blobs) == 0 {
+		return
+	}
+	keys := make(map[string]bool)
+	for rel, sum := range meta.Files {
+		info, err := os.Lstat(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err == nil && info.Mode().IsRegular() {
+			keys[fmt.Sprintf("%s-%o", sum, info.Mode().Perm())] = true
+		}
+	}
+	var blobs []string
+	for _, key := range meta.Blobs {
+		if keys[key] {
+			blobs = append(blobs, key)
+		}
+	}
+	meta.Blobs = blobs
+}
+
+// GCResult is the outcome of GC.
+type GCResult struct {
+	// number of blobs removed
+	Removed int `json:"removed"`
+	// their total size
+	Size int64 `json:"size"`
+}
+
+// GC removes blobs (see Dedupe) that are no longer referenced by any of the installed JDKs (of any of the jabba
+// homes sharing the store).
+func GC() (*GCResult, error) {
+	r := &CleanReport{}
+	err := gc(r)
+	return &GCResult{Removed: len(r.Removed), Size: r.Freed}, err
+}
+
+// PlanGC returns blobs GC would remove (without removing anything).
+func PlanGC() (*CleanReport, error) {
+	r := &CleanReport{DryRun: true}
+	if err := gc(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func gc(r *CleanReport) error {
+	referenced := make(map[string]bool)
+	for _, home := range storeHomes() {
+		// (homes that are gone are skipped)
+		metas, _ := filepath.Glob(filepath.Join(home, "meta", "*.json"))
+		for _, file := range metas {
+			meta, err := readInstallMetaFile(file)
+			if err != nil {
+				// better safe than sorry (blobs JDK references are not known)
+				return fmt.Errorf("%s is not valid (%v)", file, err)
+			}
+			for _, key := range meta.Blobs {
+				referenced[key] = true
+			}
+		}
+	}
+	dirs, _ := ioutil.ReadDir(storeDir())
+	kept := 0
+	for _, d := range dirs {
+		// (not a blob dir (e.g. "homes"))
+		if !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(storeDir(), d.Name())
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if referenced[f.Name()] {
+				kept++
+				continue
+			}
+			if err := r.remove(filepath.Join(dir, f.Name())); err != nil {
+				return err
+			}
+		}
+		if !r.DryRun {
+			// fails unless empty
+			os.Remove(dir)
+		}
+	}
+	if kept == 0 && !r.DryRun {
+		// homes are registered again by the next Dedupe
+		os.Remove(filepath.Join(storeDir(), "homes"))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestDedupe(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	os.Setenv("JABBA_DEDUPE", "hardlink")
+	defer os.Unsetenv("JABBA_DEDUPE")
+	payload := bytes.Repeat([]byte("jvm"), dedupeMinSize)
+	for _, ver := range []string{"1.8.0", "1.8.1"} {
+		dir := filepath.Join(home, "jdk", ver)
+		ok(os.MkdirAll(filepath.Join(dir, "lib"), 0755))
+		ok(ioutil.WriteFile(filepath.Join(dir, "lib", "modules"), payload, 0644))
+		ok(ioutil.WriteFile(filepath.Join(dir, "release"), []byte(ver), 0644))
+		recordManagedInstall(&InstallResult{Version: ver, Path: dir}, InstallOptions{})
+	}
+	a, err := os.Stat(filepath.Join(home, "jdk", "1.8.0", "lib", "modules"))
+	ok(err)
+	b, err := os.Stat(filepath.Join(home, "jdk", "1.8.1", "lib", "modules"))
+	ok(err)
+	if !os.SameFile(a, b) {
+		t.Fatal("expected lib/modules to be deduplicated")
+	}
+	for _, ver := range []string{"1.8.0", "1.8.1"} {
+		if _, err := Verify(ver); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results, err := Dedupe(nil, "hardlink")
+	ok(err)
+	if len(results) != 2 || results[0].Files+results[1].Files != 0 {
+		t.Fatalf("actual: %v != expected: nothing to deduplicate", results)
+	}
+	_, err = Uninstall("1.8.0")
+	ok(err)
+	gc, err := GC()
+	ok(err)
+	if gc.Removed != 0 {
+		t.Fatalf("actual: %v != expected: %v", gc.Removed, 0)
+	}
+	_, err = Uninstall("1.8.1")
+	ok(err)
+	plan, err := PlanGC()
+	ok(err)
+	if len(plan.Removed) != 1 || plan.Freed != int64(len(payload)) {
+		t.Fatalf("actual: %v != expected: 1 blob (%d bytes)", plan, len(payload))
+	}
+	gc, err = GC()
+	ok(err)
+	if gc.Removed != 1 || gc.Size != int64(len(payload)) {
+		t.Fatalf("actual: %v != expected: %v", *gc, GCResult{Removed: 1, Size: int64(len(payload))})
+	}
+	if files, _ := ioutil.ReadDir(storeDir()); len(files) != 0 {
+		t.Fatalf("expected %s to be empty", storeDir())
+	}
+}
+
+func TestApplyPostInstallToDeduplicatedJDK(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	os.Setenv("JABBA_DEDUPE", "hardlink")
+	defer os.Unsetenv("JABBA_DEDUPE")
+	security := append(bytes.Repeat([]byte("#\n"), dedupeMinSize), "jdk.tls.disabledAlgorithms=SSLv3\n"...)
+	for _, ver := range []string{"1.8.0", "1.8.1"} {
+		dir := filepath.Join(home, "jdk", ver)
+		ok(os.MkdirAll(filepath.Join(dir, "conf", "security"), 0755))
+		ok(ioutil.WriteFile(filepath.Join(dir, "conf", "security", "java.security"), security, 0644))
+		recordManagedInstall(&InstallResult{Version: ver, Path: dir}, InstallOptions{})
+	}
+	ok(ioutil.WriteFile(filepath.Join(home, "java.security"), []byte("jdk.tls.disabledAlgorithms=SSLv3, TLSv1\n"),
+		0644))
+	cfg.Use(&cfg.Config{PostInstall: cfg.PostInstallHooks{JavaSecurity: "java.security"}})
+	defer cfg.Use(nil)
+	_, err = ApplyPostInstall("1.8.0")
+	ok(err)
+	b, err := ioutil.ReadFile(filepath.Join(home, "jdk", "1.8.1", "conf", "security", "java.security"))
+	ok(err)
+	if !bytes.Equal(b, security) {
+		t.Fatal("java.security of 1.8.1 was modified along with the one of 1.8.0")
+	}
+	for _, ver := range []string{"1.8.0", "1.8.1"} {
+		if _, err := Verify(ver); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta, err := readInstallMeta("1.8.0")
+	ok(err)
+	if len(meta.Blobs) != 0 {
+		t.Fatalf("actual: %v != expected: []", meta.Blobs)
+	}
+}
+
+func TestGCKeepsBlobsOfOtherHomes(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(dir)
+	personal, work, jdks := filepath.Join(dir, "personal"), filepath.Join(dir, "work"), filepath.Join(dir, "jdk")
+	os.Setenv("JABBA_JDK_DIR", jdks)
+	defer os.Unsetenv("JABBA_JDK_DIR")
+	defer os.Unsetenv("JABBA_HOME")
+	payload := bytes.Repeat([]byte("jvm"), dedupeMinSize)
+	for home, ver := range map[string]string{personal: "1.8.0", work: "1.8.1"} {
+		os.Setenv("JABBA_HOME", home)
+		jdk := filepath.Join(jdks, ver)
+		ok(os.MkdirAll(filepath.Join(jdk, "lib"), 0755))
+		ok(ioutil.WriteFile(filepath.Join(jdk, "lib", "modules"), payload, 0644))
+		recordManagedInstall(&InstallResult{Version: ver, Path: jdk}, InstallOptions{})
+		_, err := Dedupe([]string{ver}, "hardlink")
+		ok(err)
+	}
+	os.Setenv("JABBA_HOME", personal)
+	ok(os.Remove(metaFile("1.8.0")))
+	gc, err := GC()
+	ok(err)
+	if gc.Removed != 0 {
+		t.Fatalf("actual: %v != expected: 0 (blob is still referenced by 1.8.1 of %s)", gc.Removed, work)
+	}
+}
@@ -0,0 +1,62 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// JDK is what `jabba ls`, `ls-remote`, `current` & `which` output with --output=json.
+type JDK struct {
+	Version string `json:"version"`
+	// qualifier (e.g. "zulu" in case of "zulu@1.8.72")
+	Vendor string `json:"vendor,omitempty"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+	// architectures installed JDK can run on natively (see InstalledArchs)
+	Archs []string `json:"archs,omitempty"`
+	Path  string   `json:"path,omitempty"`
+	URL   string   `json:"url,omitempty"`
+	// bytes on disk
+	Size int64 `json:"size,omitempty"`
+	// see Release.Recommended
+	Recommended bool `json:"recommended,omitempty"`
+	// "ga" or "ea" (see Release.Channel) (remote JDKs only)
+	Channel string `json:"channel,omitempty"`
+	// see Pin (installed JDKs only)
+	Pinned bool `json:"pinned,omitempty"`
+	// see Release.Notice (remote JDKs only)
+	Notice string `json:"notice,omitempty"`
+}
+
+// DescribeInstalled describes JDK installed under $JABBA_HOME/jdk.
+func DescribeInstalled(ver *semver.Version) JDK {
+	path := filepath.Join(cfg.JDKDir(), ver.String())
+	return JDK{Version: ver.String(), Vendor: ver.Qualifier(), Archs: InstalledArchs(ver.String()), Path: path,
+		Size: diskUsage(path), Pinned: IsPinned(ver.String())}
+}
+
+// DescribeRemote describes JDK available for install.
+func DescribeRemote(ver *semver.Version, release Release, os, arch string) JDK {
+	return JDK{Version: ver.String(), Vendor: ver.Qualifier(), OS: os, Arch: arch, URL: release.URL,
+		Recommended: release.Recommended, Channel: channelOf(ver, release), Notice: noticeOf(release)}
+}
+
+// diskUsage returns total size of the files under dir (0 if dir is inaccessible).
+func diskUsage(dir string) int64 {
+	// system@... JDKs are symlinks
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return 0
+	}
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+func TestDescribe(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	dir := filepath.Join(home, "jdk", "zulu@1.8.72")
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "bin", "java"), []byte("java"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "release"), []byte("1.8"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v, _ := semver.ParseVersion("zulu@1.8.72")
+	actual := DescribeInstalled(v)
+	expected := JDK{Version: "zulu@1.8.72", Vendor: "zulu", Path: dir, Size: 7}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	actual = DescribeRemote(v, Release{URL: "tgz+https://example.com/jdk.tar.gz"}, "linux", "amd64")
+	expected = JDK{Version: "zulu@1.8.72", Vendor: "zulu", OS: "linux", Arch: "amd64",
+		URL: "tgz+https://example.com/jdk.tar.gz", Channel: "ga"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
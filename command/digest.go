@@ -0,0 +1,66 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+var digestSelectorRegexp = regexp.MustCompile(`^sha256:[0-9a-fA-F]{64}$`)
+
+// IsDigestSelector returns true if selector is in the form of sha256:<hex>, i.e. refers to the index entry of the
+// archive with that checksum (#sha256=<hex> in its URL) (vendor & version are taken from the entry, so that exactly
+// the same archive is installed even if version gets republished).
+func IsDigestSelector(selector string) bool {
+	return strings.HasPrefix(selector, "sha256:")
+}
+
+// resolveDigestFor finds release (for os/arch) archive of which has the checksum selector (sha256:<hex>) refers to.
+func resolveDigestFor(selector string, os, arch string, opts InstallOptions) (*semver.Version, Release, error) {
+	if !digestSelectorRegexp.MatchString(selector) {
+		return nil, Release{}, fmt.Errorf("\"%s\" is not a valid digest (expected sha256:<64 hex digits>)", selector)
+	}
+	checksum := "sha256=" + strings.ToLower(strings.TrimPrefix(selector, "sha256:"))
+	releaseMap, err := opts.lsRemote(os, arch)
+	if err != nil {
+		return nil, Release{}, err
+	}
+	var vs []*semver.Version
+	for v, release := range releaseMap {
+		if _, f, err := splitFragment(release.URL); err == nil && f.checksum == checksum {
+			vs = append(vs, v)
+		}
+	}
+	if len(vs) == 0 {
+		return nil, Release{}, fmt.Errorf("There is no release with %s (%s/%s)", selector, os, arch)
+	}
+	sort.Sort(sort.Reverse(semver.VersionSlice(vs)))
+	if len(vs) > 1 {
+		var published []string
+		for _, v := range vs {
+			published = append(published, v.String())
+		}
+		log.Warn(selector, " is published as ", strings.Join(published, ", "), " (picking ", vs[0], ")")
+	}
+	log.Info("Resolving ", selector, " as ", vs[0])
+	return vs[0], releaseMap[vs[0]], nil
+}
+
+// checkInstalledDigest makes sure installed JDK was installed from the archive selector (sha256:<hex>) refers to
+// (as opposed to the one that was published under the same version before).
+func checkInstalledDigest(ver string, selector string) error {
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		log.Debug("Can't tell whether ", ver, " was installed from ", selector, " (", err, ")")
+		return nil
+	}
+	if meta.SHA256 != "" && !strings.EqualFold("sha256:"+meta.SHA256, selector) {
+		return fmt.Errorf("%s is installed from a different archive (sha256:%s) (`jabba uninstall %s` first)",
+			ver, meta.SHA256, ver)
+	}
+	return nil
+}
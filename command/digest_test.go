@@ -0,0 +1,76 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestInstallByDigest(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	var urls, digests []string
+	for _, name := range []string{"a", "b"} {
+		archive := filepath.Join(home, name+".tar.gz")
+		f, err := os.Create(archive)
+		ok(err)
+		gw := gzip.NewWriter(f)
+		tw := tar.NewWriter(gw)
+		for _, file := range []string{java, "jdk/release"} {
+			ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(name))}))
+			_, err = tw.Write([]byte(name))
+			ok(err)
+		}
+		ok(tw.Close())
+		ok(gw.Close())
+		ok(f.Close())
+		sum, err := sha256OfFile(archive)
+		ok(err)
+		urls = append(urls, "tgz+file://"+filepath.ToSlash(archive)+"#sha256="+sum)
+		digests = append(digests, "sha256:"+sum)
+	}
+	writeIndex := func(name string, releases string) {
+		index := filepath.Join(home, name)
+		ok(ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"`+runtime.GOARCH+`": {"jdk@zulu": {`+releases+
+			`}}}}`), 0644))
+		cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	}
+	defer cfg.SetRegistry(nil)
+	writeIndex("index.json", `"1.17.0": "`+urls[0]+`", "1.17.1": "`+urls[1]+`"`)
+	result, err := Install(digests[0], InstallOptions{})
+	ok(err)
+	if result.Version != "zulu@1.17.0" {
+		t.Fatalf("actual: %v != expected: %v", result.Version, "zulu@1.17.0")
+	}
+	if _, err := PlanInstall("sha256:"+strings.Repeat("0", 64), InstallOptions{}); err == nil {
+		t.Fatal("expected PlanInstall to fail (no such release)")
+	}
+	if _, err := PlanInstall("sha256:abc", InstallOptions{}); err == nil {
+		t.Fatal("expected PlanInstall to fail (invalid digest)")
+	}
+	// 1.17.0 gets republished
+	writeIndex("index-republished.json", `"1.17.0": "`+urls[1]+`"`)
+	_, err = PlanInstall(digests[1], InstallOptions{})
+	if err == nil || !strings.Contains(err.Error(), "different archive") {
+		t.Fatalf("expected PlanInstall to fail (installed from a different archive) (%v)", err)
+	}
+}
@@ -0,0 +1,266 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// DoctorFinding is the outcome of one of the `jabba doctor` checks.
+type DoctorFinding struct {
+	Check string `json:"check"`
+	// "ok", "warning" or "error"
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// actionable suggestion (if any)
+	Fix string `json:"fix,omitempty"`
+}
+
+var doctorChecks = []struct {
+	name string
+	run  func() []DoctorFinding
+}{
+	{"links", checkLinks},
+	{"installs", checkInstalls},
+	{"architecture", checkArchitecture},
+	{"aliases", checkAliases},
+	{"environment", checkEnvironment},
+	{"completion", checkCompletion},
+	{"registry", checkRegistry},
+	{"temp files", checkTempFiles},
+}
+
+// Doctor checks the health of $JABBA_HOME (and the environment jabba runs in).
+// Checks that found nothing wrong are reported with "ok" status.
+func Doctor() []DoctorFinding {
+	var r []DoctorFinding
+	for _, check := range doctorChecks {
+		findings := check.run()
+		if len(findings) == 0 {
+			findings = []DoctorFinding{{Status: "ok"}}
+		}
+		for _, finding := range findings {
+			finding.Check = check.name
+			r = append(r, finding)
+		}
+	}
+	return r
+}
+
+func checkLinks() []DoctorFinding {
+	var r []DoctorFinding
+	jdkDir := cfg.JDKDir()
+	files, _ := ioutil.ReadDir(jdkDir)
+	for _, f := range files {
+		if f.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		path := filepath.Join(jdkDir, f.Name())
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		target, _ := os.Readlink(path)
+		fix := "rm " + path
+		if strings.HasPrefix(f.Name(), "system@") {
+			fix = "jabba unlink " + f.Name()
+		}
+		r = append(r, DoctorFinding{Status: "error", Message: path + " -> " + target + " is broken", Fix: fix})
+	}
+	return r
+}
+
+func checkInstalls() []DoctorFinding {
+	var r []DoctorFinding
+	jdkDir := cfg.JDKDir()
+	files, _ := ioutil.ReadDir(jdkDir)
+	for _, f := range files {
+		if !f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		java := filepath.Join(jdkDir, f.Name())
+		if runtime.GOOS == "darwin" {
+			java = filepath.Join(java, "Contents", "Home")
+		}
+		java = filepath.Join(java, "bin", "java")
+		if runtime.GOOS == "windows" {
+			java += ".exe"
+		}
+		if _, err := os.Stat(java); err != nil {
+			r = append(r, DoctorFinding{Status: "error", Message: java + " is missing",
+				Fix: "jabba uninstall " + f.Name() + " && jabba install " + f.Name()})
+		}
+	}
+	return r
+}
+
+// checkArchitecture flags JDKs that are not built for the host architecture (e.g. x64-only JDKs that run under
+// Rosetta 2 on Apple Silicon).
+func checkArchitecture() []DoctorFinding {
+	var r []DoctorFinding
+	host := HostArch()
+	vs, _ := Ls()
+	for _, v := range vs {
+		archs := InstalledArchs(v.String())
+		if len(archs) == 0 {
+			continue
+		}
+		native, emulated := false, false
+		for _, arch := range archs {
+			native = native || arch == host
+			// macOS (Rosetta 2) & Windows on ARM run x64 binaries
+			emulated = emulated || (host == "arm64" && arch == "amd64" &&
+				(runtime.GOOS == "darwin" || runtime.GOOS == "windows"))
+		}
+		if native {
+			continue
+		}
+		var fix string
+		if v.Qualifier() != "system" {
+			fix = "jabba install " + v.TrimTo(semver.VPMinor) + " --arch " + host
+		}
+		if emulated {
+			translator := "x64 emulation"
+			if runtime.GOOS == "darwin" {
+				translator = "Rosetta 2"
+			}
+			r = append(r, DoctorFinding{Status: "warning",
+				Message: fmt.Sprintf("%s is %s-only (runs under %s)", v, strings.Join(archs, "+"), translator),
+				Fix:     fix})
+		} else {
+			r = append(r, DoctorFinding{Status: "error",
+				Message: fmt.Sprintf("%s is built for %s (can't run on %s)", v, strings.Join(archs, "+"), host),
+				Fix:     fix})
+		}
+	}
+	return r
+}
+
+func checkAliases() []DoctorFinding {
+	var r []DoctorFinding
+	if _, _, err := includedAliases(); err != nil {
+		r = append(r, DoctorFinding{Status: "error", Message: err.Error(),
+			Fix: "fix the file (or remove it from alias_files in config.yaml)"})
+	}
+	names, _ := Aliases()
+	for _, name := range names {
+		value, err := ResolveAlias(name)
+		if err != nil {
+			r = append(r, DoctorFinding{Status: "error", Message: err.Error(),
+				Fix: "jabba alias " + name + " <installed version> (or jabba unalias " + name + ")"})
+			continue
+		}
+		if _, err := LsBestMatch(value); err != nil {
+			r = append(r, DoctorFinding{Status: "error",
+				Message: fmt.Sprintf("\"%s\" alias points to %s, which is not installed", name, value),
+				Fix:     "jabba install " + value + " (or jabba alias " + name + " <installed version>)"})
+		}
+	}
+	return r
+}
+
+func checkEnvironment() []DoctorFinding {
+	current := Current()
+	if current == "" {
+		return nil
+	}
+	expected := filepath.Join(cfg.JDKDir(), current)
+	if runtime.GOOS == "darwin" {
+		expected = filepath.Join(expected, "Contents", "Home")
+	}
+	if javaHome := os.Getenv("JAVA_HOME"); filepath.Clean(javaHome) != expected {
+		return []DoctorFinding{{Status: "error",
+			Message: fmt.Sprintf("java on PATH is %s while JAVA_HOME is \"%s\"", current, javaHome),
+			Fix:     "jabba use " + current}}
+	}
+	return nil
+}
+
+func checkRegistry() []DoctorFinding {
+	if cfg.Offline() {
+		return []DoctorFinding{{Status: "ok", Message: "skipped (offline mode)"}}
+	}
+	var r []DoctorFinding
+	urls := cfg.Registry()
+	for _, url := range urls {
+		if _, err := fetch(url); err != nil {
+			r = append(r, DoctorFinding{Status: "warning", Message: url + " is unreachable (" + err.Error() + ")"})
+		}
+	}
+	if len(r) == len(urls) {
+		for i := range r {
+			r[i].Status = "error"
+			r[i].Fix = "check network / proxy settings (or point --registry / JABBA_INDEX to a reachable mirror)"
+		}
+	}
+	return r
+}
+
+func checkTempFiles() []DoctorFinding {
+	var files []string
+	for _, pattern := range legacyTempPatterns {
+		matches, _ := filepath.Glob(filepath.Join(os.TempDir(), pattern))
+		files = append(files, matches...)
+	}
+	matches, _ := filepath.Glob(filepath.Join(cfg.TempDir(), "jabba-*"))
+	for _, match := range matches {
+		if !strings.HasSuffix(match, ".lock") {
+			files = append(files, match)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	var size int64
+	for _, file := range files {
+		size += diskUsage(file)
+	}
+	return []DoctorFinding{{Status: "warning",
+		Message: fmt.Sprintf("%d leftover temp file(s) (%s) (interrupted downloads / installs / tries)", len(files),
+			FormatSize(size)),
+		Fix: "jabba cache clean"}}
+}
+
+// checkCompletion flags oh-my-zsh jabba plugins (other than the one generated by `jabba completion zsh --oh-my-zsh`)
+// as, depending on the order they are loaded in, they either override `jabba completion zsh` (and `jabba` function)
+// or get overridden by it.
+func checkCompletion() []DoctorFinding {
+	omz := os.Getenv("ZSH")
+	if omz == "" {
+		return nil
+	}
+	custom := os.Getenv("ZSH_CUSTOM")
+	if custom == "" {
+		custom = filepath.Join(omz, "custom")
+	}
+	plugin := filepath.Join(custom, "plugins", "jabba", "jabba.plugin.zsh")
+	dirs := []string{filepath.Dir(plugin)}
+	// custom plugin shadows the bundled one
+	if _, err := os.Stat(plugin); err != nil {
+		dirs = append(dirs, filepath.Join(omz, "plugins", "jabba"))
+	}
+	var r []DoctorFinding
+	for _, dir := range dirs {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "jabba.plugin.zsh"))
+		if err == nil && strings.HasPrefix(string(b), ohMyZshPluginMarker) {
+			continue
+		}
+		var files []string
+		for _, name := range []string{"jabba.plugin.zsh", "_jabba"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				files = append(files, filepath.Join(dir, name))
+			}
+		}
+		if len(files) != 0 {
+			r = append(r, DoctorFinding{Status: "warning",
+				Message: strings.Join(files, ", ") + " (oh-my-zsh plugin) conflicts with `jabba completion zsh`",
+				Fix:     "jabba completion zsh --oh-my-zsh > " + plugin})
+		}
+	}
+	return r
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestDoctor(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	os.Setenv("JABBA_OFFLINE", "1")
+	defer os.Unsetenv("JABBA_OFFLINE")
+	jdkDir := filepath.Join(home, "jdk")
+	ok(os.MkdirAll(filepath.Join(jdkDir, "1.8.0"), 0755))
+	ok(os.Symlink(filepath.Join(home, "missing"), filepath.Join(jdkDir, "system@1.7.0")))
+	ok(SetAlias("default", "1.11"))
+	// JDK built for another architecture
+	prevNativeArch := nativeArch
+	defer func() { nativeArch = prevNativeArch }()
+	nativeArch = func() string { return "s390x" }
+	java := expectedJavaPath(filepath.Join(jdkDir, "zulu@1.17.0"), runtime.GOOS)
+	ok(os.MkdirAll(filepath.Dir(java), 0755))
+	exe, err := os.Executable()
+	ok(err)
+	b, err := ioutil.ReadFile(exe)
+	ok(err)
+	ok(ioutil.WriteFile(java, b, 0755))
+	errors := make(map[string]bool)
+	for _, finding := range Doctor() {
+		if finding.Status == "error" {
+			errors[finding.Check] = true
+		}
+	}
+	for _, check := range []string{"links", "installs", "architecture", "aliases"} {
+		if !errors[check] {
+			t.Fatalf("expected \"%s\" check to fail (%v)", check, errors)
+		}
+	}
+	if errors["registry"] {
+		t.Fatal("registry should not be checked in offline mode")
+	}
+}
+
+func TestCheckCompletion(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	omz, err := ioutil.TempDir("", "oh-my-zsh")
+	ok(err)
+	defer os.RemoveAll(omz)
+	os.Setenv("ZSH", omz)
+	defer os.Unsetenv("ZSH")
+	os.Unsetenv("ZSH_CUSTOM")
+	if findings := checkCompletion(); len(findings) != 0 {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+	bundled := filepath.Join(omz, "plugins", "jabba")
+	ok(os.MkdirAll(bundled, 0755))
+	ok(ioutil.WriteFile(filepath.Join(bundled, "_jabba"), []byte("#compdef jabba\n"), 0644))
+	findings := checkCompletion()
+	if len(findings) != 1 || findings[0].Status != "warning" {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+	// plugin generated by jabba shadows the bundled one
+	plugin, err := OhMyZshPlugin("/usr/local/bin/jabba", true)
+	ok(err)
+	custom := filepath.Join(omz, "custom", "plugins", "jabba")
+	ok(os.MkdirAll(custom, 0755))
+	ok(ioutil.WriteFile(filepath.Join(custom, "jabba.plugin.zsh"), []byte(plugin), 0644))
+	if findings := checkCompletion(); len(findings) != 0 {
+		t.Fatalf("unexpected findings: %+v", findings)
+	}
+}
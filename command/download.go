@@ -0,0 +1,382 @@
+package command
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"github.com/mitchellh/ioprogress"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/command/flock"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProgressFunc is called with the number of bytes downloaded so far and the total (-1 if unknown).
+type ProgressFunc func(downloaded int64, total int64)
+
+var downloadAttempts = 5
+var downloadBackoff = time.Second
+
+type RedirectTracer struct {
+	Transport http.RoundTripper
+}
+
+func (self RedirectTracer) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	transport := self.Transport
+	if transport == nil {
+		if transport, err = httpTransport(); err != nil {
+			return
+		}
+	}
+	resp, err = transport.RoundTrip(req)
+	if err != nil {
+		return
+	}
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+		log.Debug("Following ", resp.StatusCode, " redirect to ", resp.Header.Get("Location"))
+	}
+	return
+}
+
+func newDownloadClient() *http.Client {
+	client := newHTTPClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("too many redirects")
+		}
+		if len(via) != 0 {
+			// https://github.com/golang/go/issues/4800
+			for attr, val := range via[0].Header {
+				if _, ok := req.Header[attr]; !ok {
+					req.Header[attr] = val
+				}
+			}
+		}
+		return nil
+	}
+	return client
+}
+
+// download saves url to a file named after the hash of the url (and checksum, if any), so that an interrupted download
+// can be resumed (using HTTP Range) by the next attempt (or the next jabba run).
+// Unless cache dir is configured (in which case cached=true), the file is stored in the temp dir (see tempDir).
+// Cached copy is only used if it matches checksum ("sha256=...", if specified) or, if there is none, sha256 recorded
+// when it was downloaded (otherwise it's downloaded again).
+// sum is the sha256 of the file.
+// Progress is drawn to stderr unless progress is specified.
+func download(url string, fileType string, checksum string, progress ProgressFunc) (file string, sum string,
+	cached bool, err error) {
+	file, cached = downloadPath(url, fileType, checksum)
+	if !cached {
+		// keeps CleanTemp away from the partial download
+		lock := flock.New(file + ".lock")
+		if err = lock.Lock(); err != nil {
+			return
+		}
+		defer lock.UnlockAndRemove()
+		sum, err = downloadWithRetry(url, file, 0600, progress)
+		return
+	}
+	cacheDir := filepath.Dir(file)
+	if err = mkdirShared(cacheDir); err != nil {
+		return
+	}
+	// cache dir can be shared by multiple users/processes
+	lock := flock.New(file + ".lock")
+	log.Debug("Acquiring ", lock.Path())
+	if err = lock.Lock(); err != nil {
+		return
+	}
+	defer lock.Unlock()
+	if _, err = os.Stat(file); err == nil {
+		if sum, err = verifyCachedArchive(file, checksum); err == nil {
+			log.Info("Using ", file, " (cached)")
+			// `jabba cache clean --older-than` goes by the last use (error is ignored as file might be owned by
+			// another user)
+			now := time.Now()
+			os.Chtimes(file, now, now)
+			return file, sum, true, nil
+		}
+		// disk corruption, archive re-published under the same URL, etc.
+		log.Warn("Cached copy of ", url, " is corrupt (", err, "). Downloading it again")
+		if err = removeDownload(file); err != nil {
+			return
+		}
+	}
+	partialFile := file + ".part"
+	if sum, err = downloadWithRetry(url, partialFile, 0664, progress); err != nil {
+		return
+	}
+	if err = ioutil.WriteFile(file+".sha256", []byte(sum+"\n"), 0664); err != nil {
+		return
+	}
+	return file, sum, true, os.Rename(partialFile, file)
+}
+
+// verifyCachedArchive checks file (in cache dir) against checksum or, if it's empty, sha256 recorded by download
+// (<file>.sha256), returning sha256 of the file.
+func verifyCachedArchive(file string, checksum string) (string, error) {
+	sum, err := sha256OfFile(file)
+	if err != nil {
+		return "", err
+	}
+	if checksum == "" {
+		b, err := ioutil.ReadFile(file + ".sha256")
+		if os.IsNotExist(err) {
+			// cached by an older version of jabba
+			return sum, ioutil.WriteFile(file+".sha256", []byte(sum+"\n"), 0664)
+		}
+		if err != nil {
+			return "", err
+		}
+		checksum = "sha256=" + strings.TrimSpace(string(b))
+	}
+	return sum, verifyChecksum(sum, checksum)
+}
+
+// removeDownload removes file downloaded by download (along with its recorded sha256, if any).
+func removeDownload(file string) error {
+	for _, path := range []string{file, file + ".sha256"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// downloadPath returns path url is downloaded to by download (cached is true if file is in cache dir).
+// Archives are keyed by url+checksum, i.e. archive re-published under the same URL (with index updated accordingly)
+// doesn't clash with the one cached before.
+func downloadPath(url string, fileType string, checksum string) (file string, cached bool) {
+	key := url
+	if checksum != "" {
+		key += "#" + checksum
+	}
+	name := fmt.Sprintf("jabba-d-%x", sha1.Sum([]byte(key)))
+	if fileType == "exe" {
+		name += ".exe"
+	}
+	if cacheDir := cfg.CacheDir(); cacheDir != "" {
+		return filepath.Join(cacheDir, name), true
+	}
+	return filepath.Join(tempDir(), name), false
+}
+
+// mkdirShared creates group-writable dir (with setgid bit set so that everything created inside
+// inherits group of the directory).
+// Existing directories are left untouched (e.g. when created by an administrator).
+func mkdirShared(dir string) error {
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return err
+	}
+	// MkdirAll is subject to umask
+	return os.Chmod(dir, 0775|os.ModeSetgid)
+}
+
+func downloadWithRetry(url string, file string, perm os.FileMode, progress ProgressFunc) (sum string, err error) {
+	log.Debug("Saving ", url, " to ", file)
+	backoff := downloadBackoff
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		sum, retry, err = resumeDownload(url, file, perm, progress)
+		if err == nil || !retry || attempt >= downloadAttempts {
+			return
+		}
+		log.Warn("Download of ", url, " failed (", err, "). Retrying in ", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// resumeDownload appends whatever is missing to the (possibly partial) file.
+// Resuming is conditional (If-Range) on the ETag / Last-Modified of the response the file was started from (recorded in
+// <file>.validator), so that the file isn't stitched together from two different versions of the archive (e.g. when
+// it's re-published under the same URL).
+// sha256 is calculated as bytes are written (only the part downloaded before (if any) has to be read back).
+// retry is true if err is considered to be transient.
+func resumeDownload(url string, file string, perm os.FileMode, progress ProgressFunc) (sum string, retry bool, err error) {
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, perm)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	// OpenFile is subject to umask (error is ignored as file might be owned by another user)
+	f.Chmod(perm)
+	stat, err := f.Stat()
+	if err != nil {
+		return
+	}
+	offset := stat.Size()
+	validatorFile := file + ".validator"
+	var validator string
+	if offset != 0 {
+		if b, _ := ioutil.ReadFile(validatorFile); len(b) != 0 {
+			validator = strings.TrimSpace(string(b))
+		} else {
+			// there is no telling whether what's there is the beginning of the same file
+			log.Debug(file, " has no ETag / Last-Modified recorded, starting over")
+			if err = f.Truncate(0); err != nil {
+				return
+			}
+			offset = 0
+		}
+	}
+	req, err := newDownloadRequest(url, offset, validator)
+	if err != nil {
+		return
+	}
+	res, err := newDownloadClient().Do(req)
+	if err != nil {
+		return "", true, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusPartialContent:
+		if start, _ := parseContentRange(res.Header.Get("Content-Range")); start != offset {
+			// server ignored our offset, start over
+			return "", true, restartDownload(f, "unexpected Content-Range "+res.Header.Get("Content-Range"))
+		}
+	case res.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		if _, total := parseContentRange(res.Header.Get("Content-Range")); total == offset {
+			// already complete
+			sum, err = sha256Of(io.NewSectionReader(f, 0, offset))
+			return sum, false, err
+		}
+		return "", true, restartDownload(f, "GET "+url+" returned "+strconv.Itoa(res.StatusCode))
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return "", true, fmt.Errorf("GET %s returned %d", url, res.StatusCode)
+	case res.StatusCode >= 400:
+		return "", false, fmt.Errorf("GET %s returned %d", url, res.StatusCode)
+	default:
+		// either range requests are not supported, file has changed since (If-Range) or there was nothing to resume
+		if offset != 0 {
+			log.Debug(url, " can't be resumed, starting over")
+		}
+		if err = f.Truncate(0); err != nil {
+			return
+		}
+		offset = 0
+		if err = writeResumeValidator(validatorFile, res.Header, perm); err != nil {
+			return
+		}
+	}
+	h := sha256.New()
+	if _, err = io.Copy(h, io.NewSectionReader(f, 0, offset)); err != nil {
+		return
+	}
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return
+	}
+	progressTracker := &ioprogress.Reader{
+		Reader:   res.Body,
+		Size:     res.ContentLength,
+		DrawFunc: progressDrawFunc(url, offset, progress),
+	}
+	n, err := io.Copy(io.MultiWriter(f, h), progressTracker)
+	if err != nil {
+		return "", true, err
+	}
+	if res.ContentLength >= 0 && n != res.ContentLength {
+		return "", true, io.ErrUnexpectedEOF
+	}
+	// nothing left to resume
+	if err = os.Remove(validatorFile); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	return hex.EncodeToString(h.Sum(nil)), false, nil
+}
+
+// writeResumeValidator records what resuming the download started by the response has to be conditional on (see
+// resumeDownload): strong ETag or, if there is none, Last-Modified (file is removed if response has neither, i.e.
+// download can't be resumed safely).
+func writeResumeValidator(file string, header http.Header, perm os.FileMode) error {
+	validator := header.Get("ETag")
+	if strings.HasPrefix(validator, "W/") || validator == "" {
+		// weak ETags can't be used in If-Range
+		validator = header.Get("Last-Modified")
+	}
+	if validator == "" {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return ioutil.WriteFile(file, []byte(validator+"\n"), perm)
+}
+
+// progressDrawFunc reports progress of the download (offset is the number of bytes downloaded before)
+// to the listener (or stderr if there is none).
+func progressDrawFunc(url string, offset int64, progress ProgressFunc) ioprogress.DrawFunc {
+	if progress == nil {
+		// stdout is reserved for the output of the command (e.g. `jabba install --json`)
+		return newProgressDrawFunc(url, offset)
+	}
+	return func(n int64, size int64) error {
+		// (-1, -1) marks the end of the progress
+		if n < 0 {
+			return nil
+		}
+		if size >= 0 {
+			size += offset
+		}
+		progress(offset+n, size)
+		return nil
+	}
+}
+
+// newDownloadRequest returns GET request for the url (starting at offset if it's not 0 (and only if ETag /
+// Last-Modified of the file still matches validator, if any (If-Range))).
+func newDownloadRequest(url string, offset int64, validator string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(url, "zulu") {
+		req.Header.Set("Referer", "http://www.azul.com/downloads/zulu/")
+	}
+	req.Header.Set("Cookie", "oraclelicense=accept-securebackup-cookie")
+	if offset > 0 {
+		log.Debug("Resuming download of ", url, " from byte ", offset)
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+	return req, nil
+}
+
+func restartDownload(f *os.File, reason string) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	return fmt.Errorf("%s (partial download discarded)", reason)
+}
+
+// "bytes 100-199/200" -> 100, 200; "bytes */200" -> -1, 200
+func parseContentRange(value string) (start int64, total int64) {
+	start, total = -1, -1
+	value = strings.TrimPrefix(value, "bytes ")
+	slash := strings.Index(value, "/")
+	if slash == -1 {
+		return
+	}
+	if dash := strings.Index(value, "-"); dash != -1 && dash < slash {
+		start, _ = strconv.ParseInt(value[:dash], 10, 64)
+	}
+	if t, err := strconv.ParseInt(value[slash+1:], 10, 64); err == nil {
+		total = t
+	}
+	return
+}
@@ -0,0 +1,209 @@
+package command
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDownloadResumesAfterConnectionDrop(t *testing.T) {
+	prevBackoff := downloadBackoff
+	defer func() { downloadBackoff = prevBackoff }()
+	downloadBackoff = time.Millisecond
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		if len(ranges) == 1 {
+			// send half of the content and drop the connection
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	file, sum, _, err := download(server.URL+"/jdk.tar.gz", "tgz", "", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.Remove(file)
+	actual, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !bytes.Equal(actual, content) {
+		t.Fatalf("downloaded content does not match (%d bytes != %d bytes)", len(actual), len(content))
+	}
+	// sha256 calculated while downloading has to cover the part downloaded before the connection drop too
+	if expected := fmt.Sprintf("%x", sha256.Sum256(content)); sum != expected {
+		t.Fatalf("actual: %v != expected: %v", sum, expected)
+	}
+	if len(ranges) != 2 || ranges[1] != "bytes="+strconv.Itoa(len(content)/2)+"-" {
+		t.Fatalf("unexpected Range headers: %v", ranges)
+	}
+}
+
+func TestDownloadStartsOverIfFileHasChanged(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	url := server.URL + "/jdk.tar.gz"
+	file, _ := downloadPath(url, "tgz", "")
+	for _, validator := range []string{`"v1"`, ""} {
+		ranges = nil
+		// leftover of the download of the previous version of the file
+		if err := ioutil.WriteFile(file, []byte("previous version"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if validator != "" {
+			if err := ioutil.WriteFile(file+".validator", []byte(validator+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, _, _, err := download(url, "tgz", "", nil); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		actual, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !bytes.Equal(actual, content) {
+			t.Fatalf("downloaded content does not match (%d bytes != %d bytes)", len(actual), len(content))
+		}
+		// resumed only if there is a validator to make it conditional on
+		if expected := validator != ""; len(ranges) != 1 || (ranges[0] != "") != expected {
+			t.Fatalf("unexpected Range headers: %v", ranges)
+		}
+		if _, err := os.Stat(file + ".validator"); !os.IsNotExist(err) {
+			t.Fatalf("expected %s.validator to be removed (%v)", file, err)
+		}
+		os.Remove(file)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	for _, scenario := range []struct {
+		value string
+		start int64
+		total int64
+	}{
+		{"bytes 100-199/200", 100, 200},
+		{"bytes */200", -1, 200},
+		{"bytes 0-99/*", 0, -1},
+		{"", -1, -1},
+	} {
+		start, total := parseContentRange(scenario.value)
+		if start != scenario.start || total != scenario.total {
+			t.Fatalf("%q: actual: %v, %v != expected: %v, %v", scenario.value, start, total, scenario.start, scenario.total)
+		}
+	}
+}
+
+func TestDownloadIsServedFromCacheDir(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "download_test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	prevCacheDir := os.Getenv("JABBA_CACHE_DIR")
+	defer os.Setenv("JABBA_CACHE_DIR", prevCacheDir)
+	os.Setenv("JABBA_CACHE_DIR", filepath.Join(cacheDir, "shared"))
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+	for i := 0; i < 2; i++ {
+		file, _, cached, err := download(server.URL+"/jdk.zip", "zip", "", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !cached || filepath.Dir(file) != filepath.Join(cacheDir, "shared") {
+			t.Fatalf("%s is expected to be cached", file)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("actual: %v != expected: %v", requests, 1)
+	}
+}
+
+func TestDownloadReplacesCorruptCachedArchive(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "download_test")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	prevCacheDir := os.Getenv("JABBA_CACHE_DIR")
+	defer os.Setenv("JABBA_CACHE_DIR", prevCacheDir)
+	os.Setenv("JABBA_CACHE_DIR", cacheDir)
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+	url := server.URL + "/jdk.zip"
+	file, sum, _, err := download(url, "zip", "", nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// archives pinned to a checksum are cached separately
+	pinned, _, _, err := download(url, "zip", "sha256="+sum, nil)
+	if err != nil || pinned == file {
+		t.Fatalf("%s (%v) was expected to differ from %s", pinned, err, file)
+	}
+	expectedRequests := 2
+	for _, c := range []struct {
+		file     string
+		checksum string
+	}{{file, ""}, {pinned, "sha256=" + sum}} {
+		// bit rot
+		if err := ioutil.WriteFile(c.file, []byte("c0ntent"), 0664); err != nil {
+			t.Fatal(err)
+		}
+		if _, actual, _, err := download(url, "zip", c.checksum, nil); err != nil || actual != sum {
+			t.Fatalf("actual: %v (%v) != expected: %v", actual, err, sum)
+		}
+		if b, _ := ioutil.ReadFile(c.file); string(b) != "content" {
+			t.Fatalf("actual: %s != expected: content", b)
+		}
+		expectedRequests++
+		if requests != expectedRequests {
+			t.Fatalf("actual: %v != expected: %v", requests, expectedRequests)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// DuReport is what `jabba du` outputs.
+type DuReport struct {
+	// largest first
+	JDKs  []DuEntry `json:"jdks"`
+	Total int64     `json:"total"`
+	// total size of the JDKs `jabba prune` would remove (the ones not referenced by aliases, current shell or
+	// project files)
+	Reclaimable int64 `json:"reclaimable"`
+	// size of the download cache (see cfg.CacheDir) (0 if there is none)
+	Cache int64 `json:"cache,omitempty"`
+}
+
+type DuEntry struct {
+	Version string `json:"version"`
+	Size    int64  `json:"size"`
+	// why JDK is kept (e.g. "alias default") ("" if it's reclaimable)
+	ReferencedBy string `json:"referencedBy,omitempty"`
+}
+
+// Du measures disk usage of JDKs installed under cfg.JDKDir() (links to system JDKs are not counted).
+// Project files (.jabbarc, .java-version, .tool-versions) found under dirs are considered (the same way `jabba prune`
+// does it) when telling which JDKs are reclaimable.
+func Du(dirs []string) (*DuReport, error) {
+	keep, err := referencedVersions(dirs)
+	if err != nil {
+		return nil, err
+	}
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	report := &DuReport{JDKs: []DuEntry{}}
+	for _, v := range vs {
+		if strings.HasPrefix(v.String(), "system@") {
+			continue
+		}
+		entry := DuEntry{Version: v.String(), Size: DescribeInstalled(v).Size, ReferencedBy: keep[v.String()]}
+		report.Total += entry.Size
+		if entry.ReferencedBy == "" {
+			report.Reclaimable += entry.Size
+		}
+		report.JDKs = append(report.JDKs, entry)
+	}
+	sort.SliceStable(report.JDKs, func(i, j int) bool { return report.JDKs[i].Size > report.JDKs[j].Size })
+	if dir := cfg.CacheDir(); dir != "" {
+		report.Cache = diskUsage(dir)
+	}
+	return report, nil
+}
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDu(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	for ver, size := range map[string]int{"1.8.0": 100, "zulu@1.17.0": 300, "1.11.0": 200} {
+		dir := filepath.Join(home, "jdk", ver, "lib")
+		ok(os.MkdirAll(dir, 0755))
+		ok(ioutil.WriteFile(filepath.Join(dir, "modules"), make([]byte, size), 0644))
+	}
+	ok(SetAlias("default", "zulu@1.17"))
+	project := filepath.Join(home, "project")
+	ok(os.MkdirAll(project, 0755))
+	ok(ioutil.WriteFile(filepath.Join(project, ".jabbarc"), []byte("1.8\n"), 0644))
+	report, err := Du([]string{project})
+	ok(err)
+	expected := &DuReport{
+		JDKs: []DuEntry{
+			{Version: "zulu@1.17.0", Size: 300, ReferencedBy: "alias default"},
+			{Version: "1.11.0", Size: 200},
+			{Version: "1.8.0", Size: 100, ReferencedBy: filepath.Join(project, ".jabbarc")},
+		},
+		Total:       600,
+		Reclaimable: 200,
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Fatalf("actual: %+v != expected: %+v", report, expected)
+	}
+}
@@ -0,0 +1,128 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// EnvFormats are the formats `jabba env` can print environment in.
+var EnvFormats = []string{"sh", "bash", "zsh", "fish", "pwsh", "github-actions", "azure"}
+
+// Env returns JAVA_HOME & PATH (with JDK's bin prepended (and other $JABBA_HOME/jdk/* entries removed)) to use JDK
+// matching the selector with (without going through `jabba` shell function (e.g. in CI)), along with variables
+// attached to the JDK / alias (see SetJDKEnv).
+func Env(selector string) (*EnvChange, error) {
+	resolved, err := ResolveAlias(selector)
+	if err != nil {
+		return nil, err
+	}
+	ver, err := LsBestMatch(resolved)
+	if err != nil {
+		return nil, err
+	}
+	env, err := useEnv(filepath.Join(cfg.JDKDir(), ver))
+	if err != nil {
+		return nil, err
+	}
+	env, _ = withJDKEnv(env, selector, ver)
+	env, _ = withTruststore(env, filepath.Join(cfg.JDKDir(), ver))
+	var set []string
+	for _, kv := range env {
+		// JAVA_HOME_BEFORE_JABBA & JABBA_JDK_ENV only make sense in interactive shell
+		if key, _ := splitEnv(kv); key != "JAVA_HOME_BEFORE_JABBA" && key != jdkEnvVar {
+			set = append(set, kv)
+		}
+	}
+	return &EnvChange{Set: set}, nil
+}
+
+// Export renders change in one of EnvFormats:
+// shells get code to evaluate (see Script),
+// "github-actions" gets lines to append to $GITHUB_ENV / $GITHUB_PATH (as shell code (see ExportToGitHubActions)),
+// "azure" gets Azure Pipelines logging commands (##vso[task.setvariable ...], ##vso[task.prependpath ...]).
+func (c *EnvChange) Export(format string) (string, error) {
+	switch format {
+	case "sh", "bash", "zsh", "fish", "pwsh":
+		return c.Script(format), nil
+	case "github-actions":
+		javaHome, bin := c.javaHome()
+		r := "echo " + quoteShell("bash", "JAVA_HOME="+javaHome) + " >> \"$GITHUB_ENV\"\n"
+		for _, kv := range c.extraVars() {
+			r += "echo " + quoteShell("bash", kv) + " >> \"$GITHUB_ENV\"\n"
+		}
+		return r + "echo " + quoteShell("bash", bin) + " >> \"$GITHUB_PATH\"", nil
+	case "azure":
+		javaHome, bin := c.javaHome()
+		r := "##vso[task.setvariable variable=JAVA_HOME]" + javaHome + "\n"
+		for _, kv := range c.extraVars() {
+			key, value := splitEnv(kv)
+			r += "##vso[task.setvariable variable=" + key + "]" + value + "\n"
+		}
+		return r + "##vso[task.prependpath]" + bin, nil
+	}
+	return "", fmt.Errorf("Unsupported format \"%s\" (must be one of %s)", format, strings.Join(EnvFormats, ", "))
+}
+
+// ExportToGitHubActions appends JAVA_HOME to $GITHUB_ENV & JDK's bin to $GITHUB_PATH (so that subsequent steps of
+// the job use JDK), returning false if neither is set (i.e. jabba is not running inside GitHub Actions).
+func (c *EnvChange) ExportToGitHubActions() (bool, error) {
+	envFile, pathFile := os.Getenv("GITHUB_ENV"), os.Getenv("GITHUB_PATH")
+	if envFile == "" || pathFile == "" {
+		return false, nil
+	}
+	javaHome, bin := c.javaHome()
+	lines := [][2]string{{envFile, "JAVA_HOME=" + javaHome}}
+	for _, kv := range c.extraVars() {
+		lines = append(lines, [2]string{envFile, kv})
+	}
+	lines = append(lines, [2]string{pathFile, bin})
+	for _, line := range lines {
+		if strings.ContainsAny(line[1], "\r\n") {
+			return true, fmt.Errorf("%s cannot be exported (contains line break)", line[1])
+		}
+	}
+	for _, line := range lines {
+		log.Debug("Appending ", line[1], " to ", line[0])
+		if err := appendLine(line[0], line[1]); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// extraVars returns variables (in "key=value" format) other than PATH & JAVA_HOME (see SetJDKEnv).
+func (c *EnvChange) extraVars() []string {
+	var r []string
+	for _, kv := range c.Set {
+		if key, _ := splitEnv(kv); key != "PATH" && key != "JAVA_HOME" {
+			r = append(r, kv)
+		}
+	}
+	return r
+}
+
+func (c *EnvChange) javaHome() (javaHome string, bin string) {
+	for _, kv := range c.Set {
+		if key, value := splitEnv(kv); key == "JAVA_HOME" {
+			javaHome = value
+		}
+	}
+	return javaHome, filepath.Join(javaHome, "bin")
+}
+
+func appendLine(file string, line string) error {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,72 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEnvExport(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "1.8.0", "1.11.0")
+	change, err := Env("1.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	javaHome := filepath.Join(home, "jdk", "1.8.0")
+	if runtime.GOOS == "darwin" {
+		javaHome = filepath.Join(javaHome, "Contents", "Home")
+	}
+	bin := filepath.Join(javaHome, "bin")
+	if len(change.Set) != 2 || !strings.HasPrefix(change.Set[0], "PATH="+bin+string(os.PathListSeparator)) ||
+		change.Set[1] != "JAVA_HOME="+javaHome {
+		t.Fatalf("unexpected change: %v", change.Set)
+	}
+	azure, err := change.Export("azure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "##vso[task.setvariable variable=JAVA_HOME]" + javaHome + "\n##vso[task.prependpath]" + bin
+	if azure != expected {
+		t.Fatalf("actual: %v != expected: %v", azure, expected)
+	}
+	if _, err := change.Export("cmd"); err == nil {
+		t.Fatal("expected Export to fail (unsupported format)")
+	}
+	os.Unsetenv("GITHUB_ENV")
+	if exported, err := change.ExportToGitHubActions(); exported || err != nil {
+		t.Fatalf("expected nothing to be exported outside of GitHub Actions (%v)", err)
+	}
+	envFile, pathFile := filepath.Join(home, "github_env"), filepath.Join(home, "github_path")
+	if err := ioutil.WriteFile(envFile, []byte("FOO=bar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("GITHUB_ENV", envFile)
+	defer os.Unsetenv("GITHUB_ENV")
+	os.Setenv("GITHUB_PATH", pathFile)
+	defer os.Unsetenv("GITHUB_PATH")
+	if exported, err := change.ExportToGitHubActions(); !exported || err != nil {
+		t.Fatalf("expected JDK to be exported (%v)", err)
+	}
+	for file, expected := range map[string]string{
+		envFile:  "FOO=bar\nJAVA_HOME=" + javaHome + "\n",
+		pathFile: bin + "\n",
+	} {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expected {
+			t.Fatalf("actual: %v != expected: %v", string(b), expected)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// `jabba exec --env-file` moves variables (the ones attached to the JDK / alias (see SetJDKEnv) and the ones matching
+// --env-file-var) out of command's environment into a file JABBA_ENV_FILE points to, so that command (or the script it
+// runs) could load them on its own instead of failing to start with E2BIG ("argument list too long") (Windows refuses
+// environment blocks longer than 32767 characters). File consists of NUL-terminated KEY=VALUE entries (same as
+// /proc/<pid>/environ) and is removed once command exits.
+
+const envFileVar = "JABBA_ENV_FILE"
+
+const (
+	// move variables into env file only if environment would exceed the limits otherwise
+	EnvFileAuto = "auto"
+	// move variables into env file no matter what
+	EnvFileAlways = "always"
+	EnvFileNever  = "never"
+)
+
+// ValidateEnvFileMode checks that mode is one of "auto", "always" or "never" ("" being the same as "never").
+func ValidateEnvFileMode(mode string) error {
+	switch mode {
+	case "", EnvFileAuto, EnvFileAlways, EnvFileNever:
+		return nil
+	}
+	return fmt.Errorf("--env-file must be either %s, %s or %s (not %s)", EnvFileAuto, EnvFileAlways, EnvFileNever,
+		mode)
+}
+
+// envLimits returns how many bytes environment & arguments of a process can take altogether (ARG_MAX (see execve(2)),
+// CreateProcess' environment block) & how many bytes a single KEY=VALUE can take (MAX_ARG_STRLEN on Linux, 0 if
+// there is no such limit).
+func envLimits(goos string) (total int, single int) {
+	switch goos {
+	case "windows":
+		// arguments don't count
+		return 32767, 0
+	case "linux":
+		// ARG_MAX is 1/4 of the stack size limit (8MB by default)
+		return 2 * 1024 * 1024, 128 * 1024
+	default:
+		// ARG_MAX of macOS prior to 11, BSDs
+		return 256 * 1024, 0
+	}
+}
+
+// exceedsEnvLimits tells whether process with the environment & arguments would fail to start on goos.
+func exceedsEnvLimits(goos string, env []string, args []string) bool {
+	total, single := envLimits(goos)
+	strs := env
+	if goos != "windows" {
+		strs = append(append([]string(nil), env...), args...)
+	}
+	size := 0
+	for _, s := range strs {
+		if single != 0 && len(s)+1 > single {
+			return true
+		}
+		// NUL terminator + pointer to the string (argv / envp)
+		size += len(s) + 1
+		if goos != "windows" {
+			size += 8
+		}
+	}
+	return size > total
+}
+
+// splitEnvFile returns variables of env that stay in the environment & the ones that go into env file (move tells
+// which ones can) given the mode ("auto", "always" or "never" (see ValidateEnvFileMode)).
+func splitEnvFile(goos string, env []string, args []string, mode string,
+	move func(key string) bool) (kept []string, moved []string) {
+	if mode == "" || mode == EnvFileNever || mode == EnvFileAuto && !exceedsEnvLimits(goos, env, args) {
+		return env, nil
+	}
+	for _, kv := range env {
+		key, _ := splitEnv(kv)
+		if move(key) {
+			moved = append(moved, kv)
+		} else {
+			kept = append(kept, kv)
+		}
+	}
+	if mode == EnvFileAuto && exceedsEnvLimits(goos, kept, args) {
+		log.Warn("Environment is too large even without the variables moved into ", envFileVar,
+			" (see --env-file-var)")
+	}
+	return kept, moved
+}
+
+// envFileMatcher returns a function that tells whether variable can be moved into env file (it has to be either one
+// of the keys or match one of the patterns (e.g. "MATRIX_*")). PATH, JAVA_HOME & the like always stay in the
+// environment.
+func envFileMatcher(keys []string, patterns []string) func(key string) bool {
+	set := make(map[string]bool)
+	for _, key := range keys {
+		set[key] = true
+	}
+	return func(key string) bool {
+		switch key {
+		case "PATH", "JAVA_HOME", "JAVA_HOME_BEFORE_JABBA", jdkEnvVar, envFileVar:
+			return false
+		}
+		if set[key] {
+			return true
+		}
+		for _, pattern := range patterns {
+			if matched, _ := filepath.Match(pattern, key); matched {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// writeEnvFile writes variables into file (see the format above).
+func writeEnvFile(file string, env []string) error {
+	var b bytes.Buffer
+	for _, kv := range env {
+		b.WriteString(kv)
+		b.WriteByte(0)
+	}
+	return ioutil.WriteFile(file, b.Bytes(), 0600)
+}
+
+// withEnvFile moves variables into env file (see splitEnvFile), returning resulting environment & a function that
+// removes the file (to be called once command exits).
+func withEnvFile(env []string, args []string, opts ExecOptions, jdkKeys []string) ([]string, func(), error) {
+	kept, moved := splitEnvFile(runtime.GOOS, env, args, opts.EnvFile, envFileMatcher(jdkKeys, opts.EnvFileVars))
+	if len(moved) == 0 {
+		return env, func() {}, nil
+	}
+	dir, release, err := mkTempDir("jabba-env-")
+	if err != nil {
+		return nil, nil, err
+	}
+	file := filepath.Join(dir, "env")
+	if err := writeEnvFile(file, moved); err != nil {
+		release()
+		return nil, nil, err
+	}
+	log.Debug("Moved ", len(moved), " variable(s) into ", file)
+	return append(kept, envFileVar+"="+file), release, nil
+}
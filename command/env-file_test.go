@@ -0,0 +1,78 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSplitEnvFile(t *testing.T) {
+	large := "MATRIX_TOOLCHAINS=" + strings.Repeat("x", 200*1024)
+	env := []string{"PATH=/bin", "JAVA_HOME=/jdk", "MATRIX_OS=linux", large, "FOO=bar"}
+	move := envFileMatcher([]string{"FOO"}, []string{"MATRIX_*"})
+	if kept, moved := splitEnvFile("linux", env[:3], nil, EnvFileAuto, move); !reflect.DeepEqual(kept, env[:3]) ||
+		len(moved) != 0 {
+		t.Fatalf("actual: %v / %v != expected: nothing to be moved", kept, moved)
+	}
+	kept, moved := splitEnvFile("linux", env, nil, EnvFileAuto, move)
+	if expected := []string{"PATH=/bin", "JAVA_HOME=/jdk"}; !reflect.DeepEqual(kept, expected) {
+		t.Fatalf("actual: %v != expected: %v", kept, expected)
+	}
+	if expected := []string{"MATRIX_OS=linux", large, "FOO=bar"}; !reflect.DeepEqual(moved, expected) {
+		t.Fatalf("actual: %d variables != expected: %d", len(moved), len(expected))
+	}
+	// 200K is over the limit of a single variable on Linux, not on Windows (as a whole)
+	if exceedsEnvLimits("darwin", env, nil) != false || exceedsEnvLimits("windows", env, nil) != true ||
+		exceedsEnvLimits("linux", env, nil) != true {
+		t.Fatal("limits are off")
+	}
+	if kept, _ := splitEnvFile("linux", env, nil, EnvFileAlways, move); len(kept) != 2 {
+		t.Fatalf("actual: %v != expected: PATH & JAVA_HOME to be kept", kept)
+	}
+	if kept, _ := splitEnvFile("linux", env, nil, EnvFileNever, move); len(kept) != len(env) {
+		t.Fatalf("actual: %v != expected: nothing to be moved", kept)
+	}
+}
+
+func TestExecEnvFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available")
+	}
+	for _, key := range []string{"PATH", "JAVA_HOME", "JAVA_HOME_BEFORE_JABBA", "MATRIX_OS"} {
+		prev, ok := os.LookupEnv(key)
+		defer func(key string) {
+			if ok {
+				os.Setenv(key, prev)
+			} else {
+				os.Unsetenv(key)
+			}
+		}(key)
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "1.8.0")
+	os.Setenv("MATRIX_OS", "linux")
+	out := filepath.Join(home, "env-file")
+	code, err := Exec("1.8", ExecOptions{EnvFile: EnvFileAlways, EnvFileVars: []string{"MATRIX_*"}}, []string{"sh", "-c",
+		`echo "$JABBA_ENV_FILE" > ` + out + ` && test -z "${MATRIX_OS+x}" && ` +
+			`test "$(tr '\0' '\n' < "$JABBA_ENV_FILE")" = MATRIX_OS=linux && exit 3`})
+	if err != nil || code != 3 {
+		t.Fatalf("actual: %v (%v) != expected: 3", code, err)
+	}
+	file, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(strings.TrimSpace(string(file))); !os.IsNotExist(err) {
+		t.Fatalf("%s should have been removed once command exited", file)
+	}
+}
@@ -0,0 +1,124 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// EnvChange is a change to the environment of the shell `jabba` shell function is called from
+// (see ShellIntegration).
+type EnvChange struct {
+	// "key=value"
+	Set   []string
+	Unset []string
+}
+
+var envKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Script renders change as code for the shell to evaluate (values are quoted, so that paths containing spaces,
+// quotes, $, etc. are taken literally).
+// nushell (which cannot evaluate code) gets JSON ({"set": {...}, "unset": [...]}) instead.
+// Any other shell (e.g. "ON" (passed by shell integration generated by older versions of jabba)) gets
+// `export KEY="value"` / `unset KEY` lines.
+func (c *EnvChange) Script(shell string) string {
+	if alias, ok := shellAliases[shell]; ok {
+		shell = alias
+	}
+	if shell == "nushell" {
+		set := make(map[string]interface{})
+		for _, kv := range c.Set {
+			key, value := splitEnv(kv)
+			if key == "PATH" {
+				set[key] = filepath.SplitList(value)
+			} else {
+				set[key] = value
+			}
+		}
+		unset := c.Unset
+		if unset == nil {
+			unset = []string{}
+		}
+		b, _ := json.Marshal(map[string]interface{}{"set": set, "unset": unset})
+		return string(b)
+	}
+	var out []string
+	for _, kv := range c.Set {
+		key, value := splitEnv(kv)
+		switch shell {
+		case "bash", "zsh":
+			out = append(out, "export "+key+"="+quoteShell(shell, value))
+		case "fish":
+			values := []string{value}
+			if key == "PATH" {
+				values = filepath.SplitList(value)
+			}
+			line := "set -gx " + key
+			for _, v := range values {
+				line += " " + quoteShell(shell, v)
+			}
+			out = append(out, line)
+		case "pwsh":
+			out = append(out, "$env:"+key+" = "+quoteShell(shell, value))
+		default:
+			out = append(out, "export "+key+"=\""+value+"\"")
+		}
+	}
+	for _, key := range c.Unset {
+		switch shell {
+		case "fish":
+			out = append(out, "set -e "+key)
+		case "pwsh":
+			out = append(out, "Remove-Item -ErrorAction SilentlyContinue env:"+key)
+		default:
+			out = append(out, "unset "+key)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func splitEnv(kv string) (string, string) {
+	split := strings.SplitN(kv, "=", 2)
+	return split[0], split[1]
+}
+
+func validateEnvKey(key string) error {
+	if !envKeyRegexp.MatchString(key) {
+		return fmt.Errorf("\"%s\" is not a valid environment variable name", key)
+	}
+	return nil
+}
+
+// stripJDKs removes $JABBA_HOME/jdk/* entries from pth (entries added through symlinked $JABBA_HOME included).
+// JDKs of other named homes (see cfg.Homes) (e.g. activated with `jabba --jabba-home work use 17`) are removed too.
+func stripJDKs(pth string) string {
+	jdkDirs := []string{cfg.JDKDir()}
+	for _, home := range cfg.Homes() {
+		jdkDirs = append(jdkDirs, filepath.Join(home, "jdk"))
+	}
+	var prefixes []string
+	for _, jdkDir := range jdkDirs {
+		prefixes = append(prefixes, jdkDir+string(os.PathSeparator))
+		if resolved, err := filepath.EvalSymlinks(jdkDir); err == nil && resolved != jdkDir {
+			prefixes = append(prefixes, resolved+string(os.PathSeparator))
+		}
+	}
+	var dirs []string
+	for _, dir := range filepath.SplitList(pth) {
+		keep := true
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(dir, prefix) {
+				keep = false
+			}
+		}
+		if keep {
+			dirs = append(dirs, dir)
+		}
+	}
+	return strings.Join(dirs, string(os.PathListSeparator))
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// "exotic" but perfectly valid home
+const exoticPath = `/home/o'brien/My "JDKs"/$HOME/ünïcødé\ ` + "`pwd`"
+
+func TestEnvChangeScript(t *testing.T) {
+	change := &EnvChange{Set: []string{"JAVA_HOME=" + exoticPath, "PATH=" + exoticPath + "/bin:/usr/bin"},
+		Unset: []string{"JAVA_HOME_BEFORE_JABBA"}}
+	for shell, expected := range map[string]string{
+		"sh": `export JAVA_HOME='/home/o'\''brien/My "JDKs"/$HOME/ünïcødé\ ` + "`pwd`'\n" +
+			`export PATH='/home/o'\''brien/My "JDKs"/$HOME/ünïcødé\ ` + "`pwd`/bin:/usr/bin'\n" +
+			`unset JAVA_HOME_BEFORE_JABBA`,
+		"fish": `set -gx JAVA_HOME '/home/o\'brien/My "JDKs"/$HOME/ünïcødé\\ ` + "`pwd`'\n" +
+			`set -gx PATH '/home/o\'brien/My "JDKs"/$HOME/ünïcødé\\ ` + "`pwd`/bin' '/usr/bin'\n" +
+			`set -e JAVA_HOME_BEFORE_JABBA`,
+		"pwsh": `$env:JAVA_HOME = '/home/o''brien/My "JDKs"/$HOME/ünïcødé\ ` + "`pwd`'\n" +
+			`$env:PATH = '/home/o''brien/My "JDKs"/$HOME/ünïcødé\ ` + "`pwd`/bin:/usr/bin'\n" +
+			`Remove-Item -ErrorAction SilentlyContinue env:JAVA_HOME_BEFORE_JABBA`,
+	} {
+		if runtime.GOOS == "windows" && shell != "pwsh" {
+			continue // PATH is split on ';'
+		}
+		if actual := change.Script(shell); actual != expected {
+			t.Fatalf("%s: actual:\n%v\n!= expected:\n%v", shell, actual, expected)
+		}
+	}
+	var nushell struct {
+		Set   map[string]interface{} `json:"set"`
+		Unset []string               `json:"unset"`
+	}
+	if err := json.Unmarshal([]byte(change.Script("nu")), &nushell); err != nil {
+		t.Fatal(err)
+	}
+	if nushell.Set["JAVA_HOME"] != exoticPath || !reflect.DeepEqual(nushell.Unset, change.Unset) {
+		t.Fatalf("unexpected nushell output: %+v", nushell)
+	}
+}
+
+func TestEnvChangeScriptIsEvaluatedLiterally(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil || runtime.GOOS == "windows" {
+		t.Skip("sh is not available")
+	}
+	change := &EnvChange{Set: []string{"JAVA_HOME=" + exoticPath}}
+	out, err := exec.Command(sh, "-c", change.Script("sh")+"\nprintf %s \"$JAVA_HOME\"").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != exoticPath {
+		t.Fatalf("actual: %v != expected: %v", string(out), exoticPath)
+	}
+}
+
+func TestStripJDKsOfSymlinkedHome(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges")
+	}
+	dir, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "real", "jdk"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "real"), filepath.Join(dir, "home")); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("JABBA_HOME", filepath.Join(dir, "home"))
+	defer os.Unsetenv("JABBA_HOME")
+	pth := strings.Join([]string{
+		filepath.Join(dir, "home", "jdk", "1.8.0", "bin"), "/usr/bin", filepath.Join(dir, "real", "jdk", "1.11.0", "bin"),
+	}, string(os.PathListSeparator))
+	if actual := stripJDKs(pth); actual != "/usr/bin" {
+		t.Fatalf("actual: %v != expected: %v", actual, "/usr/bin")
+	}
+}
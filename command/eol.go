@@ -0,0 +1,273 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// EOLData maps vendor (qualifier, "*" for any) -> Java major ("*" for any) -> date vendor stops shipping updates of the
+// line ("2006-01-02") or "next-release" (line is supported until the next major release of Java is out).
+// Lookup order: vendor[major], vendor["*"], "*"[major], "*"["*"].
+type EOLData map[string]map[string]string
+
+const eolNextRelease = "next-release"
+
+// builtinEOL is the best knowledge of vendor support roadmaps at the time of the release of jabba ("at least until")
+// (see "eol_data" in config.yaml for a way to keep them up to date without upgrading jabba).
+var builtinEOL = EOLData{
+	"*": {
+		"6": "2018-12-31", "7": "2022-07-31", "8": "2030-12-31", "11": "2027-10-31", "17": "2027-10-31",
+		"21": "2029-12-31", "25": "2031-09-30",
+		// non-LTS lines
+		"*": eolNextRelease,
+	},
+	"temurin": {"8": "2030-12-31", "11": "2027-10-31", "17": "2027-10-31", "21": "2029-12-31", "25": "2031-09-30"},
+	// AdoptOpenJDK moved to Eclipse Adoptium (temurin) in 2021
+	"adopt":        {"*": "2021-08-01"},
+	"adopt-openj9": {"*": "2021-08-01"},
+	"zulu":         {"7": "2027-12-31", "8": "2030-12-31", "11": "2032-01-31", "17": "2029-09-30", "21": "2031-09-30"},
+	"amazon-corretto": {"8": "2030-12-31", "11": "2032-01-31", "17": "2029-10-31", "21": "2030-10-31",
+		"25": "2032-10-31"},
+	"corretto": {"8": "2030-12-31", "11": "2032-01-31", "17": "2029-10-31", "21": "2030-10-31", "25": "2032-10-31"},
+	// builds from jdk.java.net are never updated after the next release is out (LTS or not)
+	"openjdk":    {"*": eolNextRelease},
+	"openjdk-ri": {"*": eolNextRelease},
+}
+
+// GA dates of Java releases (planned ones included)
+var javaGA = map[int]string{
+	9: "2017-09-21", 10: "2018-03-20", 11: "2018-09-25", 12: "2019-03-19", 13: "2019-09-17", 14: "2020-03-17",
+	15: "2020-09-15", 16: "2021-03-16", 17: "2021-09-14", 18: "2022-03-22", 19: "2022-09-20", 20: "2023-03-21",
+	21: "2023-09-19", 22: "2024-03-19", 23: "2024-09-17", 24: "2025-03-18", 25: "2025-09-16", 26: "2026-03-17",
+	27: "2026-09-15", 28: "2027-03-16",
+}
+
+// data is checked for updates (see cfg.EOLData) at most this often
+const eolDataRefreshInterval = 7 * 24 * time.Hour
+
+func eolDataFile() string {
+	return filepath.Join(cfg.StateDir(), "eol.json")
+}
+
+// loadEOLData returns built-in EOL data with the one fetched from cfg.EOLData() (see RefreshEOLData) on top.
+func loadEOLData() EOLData {
+	data := make(EOLData)
+	merge := func(src EOLData) {
+		for vendor, majors := range src {
+			if data[vendor] == nil {
+				data[vendor] = make(map[string]string)
+			}
+			for major, date := range majors {
+				data[vendor][major] = date
+			}
+		}
+	}
+	merge(builtinEOL)
+	if cfg.EOLData() != "" {
+		var fetched EOLData
+		if b, err := ioutil.ReadFile(eolDataFile()); err == nil && json.Unmarshal(b, &fetched) == nil {
+			merge(fetched)
+		}
+	}
+	return data
+}
+
+// RefreshEOLData fetches EOL data from cfg.EOLData() (unless it was fetched less than a week ago or force is true).
+func RefreshEOLData(force bool) error {
+	url := cfg.EOLData()
+	if url == "" || cfg.Offline() {
+		return nil
+	}
+	if stat, err := os.Stat(eolDataFile()); err == nil && !force &&
+		time.Since(stat.ModTime()) < eolDataRefreshInterval {
+		return nil
+	}
+	if !strings.Contains(url, "://") {
+		url = "file://" + filepath.ToSlash(url)
+	}
+	var data EOLData
+	if err := fetchJSON(url, &data); err != nil {
+		return fmt.Errorf("Failed to fetch EOL data from %s (%v)", url, err)
+	}
+	for vendor, majors := range data {
+		for major, date := range majors {
+			if _, err := time.Parse("2006-01-02", date); err != nil && date != eolNextRelease {
+				return fmt.Errorf("%s: %s/%s: \"%s\" is neither a date (YYYY-MM-DD) nor %s", url, vendor, major,
+					date, eolNextRelease)
+			}
+		}
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(eolDataFile(), b, 0644)
+}
+
+// eolOf returns date vendor stops shipping updates of the line JDK belongs to (ok is false if it's not known
+// (e.g. graalvm@22.3.0)).
+func eolOf(data EOLData, v *semver.Version) (eol time.Time, ok bool) {
+	major := javaMajor(v)
+	if major == 0 {
+		return time.Time{}, false
+	}
+	vendor, key := v.Qualifier(), strconv.Itoa(major)
+	var value string
+	for _, candidate := range []struct{ vendor, major string }{{vendor, key}, {vendor, "*"}, {"*", key}, {"*", "*"}} {
+		if date, found := data[candidate.vendor][candidate.major]; found {
+			value = date
+			break
+		}
+	}
+	if value == eolNextRelease {
+		value = javaGA[major+1]
+	}
+	if value == "" {
+		return time.Time{}, false
+	}
+	eol, err := time.Parse("2006-01-02", value)
+	return eol, err == nil
+}
+
+// OutdatedJDK is what `jabba outdated` lists.
+type OutdatedJDK struct {
+	Version string `json:"version"`
+	// the latest release of the line (same as Version if JDK is up to date, "" if it couldn't be determined)
+	Latest string `json:"latest,omitempty"`
+	// date vendor stops shipping updates of the line ("2006-01-02") ("" if unknown)
+	EOL     string `json:"eol,omitempty"`
+	PastEOL bool   `json:"pastEOL"`
+	// why Latest is unknown
+	Problem string `json:"problem,omitempty"`
+}
+
+// Superseded tells whether there is a newer release of the line JDK belongs to (updates of Java are security
+// (Critical Patch Update) releases).
+func (o OutdatedJDK) Superseded() bool {
+	return o.Latest != "" && o.Latest != o.Version
+}
+
+// outdatedState is what `jabba outdated` knows about installed JDKs (so that `jabba use` / `jabba current` could
+// warn without talking to the index).
+type outdatedState struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	// version -> the latest release of its line
+	Latest map[string]string `json:"latest"`
+	// version -> when `jabba use` / `jabba current` warned about it last
+	WarnedAt map[string]time.Time `json:"warnedAt,omitempty"`
+}
+
+func outdatedStateFile() string {
+	return filepath.Join(cfg.StateDir(), "outdated.json")
+}
+
+func readOutdatedState() *outdatedState {
+	state := &outdatedState{}
+	if b, err := ioutil.ReadFile(outdatedStateFile()); err == nil {
+		json.Unmarshal(b, state)
+	}
+	if state.Latest == nil {
+		state.Latest = make(map[string]string)
+	}
+	if state.WarnedAt == nil {
+		state.WarnedAt = make(map[string]time.Time)
+	}
+	return state
+}
+
+func writeOutdatedState(state *outdatedState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outdatedStateFile(), b, 0644)
+}
+
+// Outdated lists installed JDKs along with the latest releases of their lines (as per index) & end-of-support dates
+// (system@... JDKs are left out (they are updated by the OS package manager)).
+func Outdated() ([]OutdatedJDK, error) {
+	if err := RefreshEOLData(false); err != nil {
+		log.Warn(err)
+	}
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	data := loadEOLData()
+	state := readOutdatedState()
+	state.CheckedAt, state.Latest = time.Now(), make(map[string]string)
+	r := []OutdatedJDK{}
+	for _, v := range vs {
+		if v.Qualifier() == "system" {
+			continue
+		}
+		o := OutdatedJDK{Version: v.String()}
+		if eol, ok := eolOf(data, v); ok {
+			o.EOL, o.PastEOL = eol.Format("2006-01-02"), time.Now().After(eol)
+		}
+		if latest, _, err := resolveRelease(upgradeRange(v), InstallOptions{}); err != nil {
+			o.Problem = err.Error()
+		} else {
+			o.Latest = v.String()
+			if v.LessThan(latest) {
+				o.Latest = latest.String()
+			}
+			state.Latest[o.Version] = o.Latest
+		}
+		r = append(r, o)
+	}
+	if err := writeOutdatedState(state); err != nil {
+		log.Debug("Failed to write ", outdatedStateFile(), " (", err, ")")
+	}
+	return r, nil
+}
+
+// eolWarningInterval is how often `jabba use` / `jabba current` warn about the same JDK
+const eolWarningInterval = 24 * time.Hour
+
+// WarnIfOutdated logs a one-line warning if JDK is past end of support or superseded by a newer release of the line
+// (as of the last `jabba outdated`) (at most once a day per JDK, unless disabled (see cfg.EOLWarnings)).
+func WarnIfOutdated(ver string) {
+	if !cfg.EOLWarnings() {
+		return
+	}
+	state := readOutdatedState()
+	warning := outdatedWarning(ver, loadEOLData(), state.Latest, time.Now())
+	if warning == "" || time.Since(state.WarnedAt[ver]) < eolWarningInterval {
+		return
+	}
+	state.WarnedAt[ver] = time.Now()
+	if err := writeOutdatedState(state); err != nil {
+		log.Debug("Failed to write ", outdatedStateFile(), " (", err, ")")
+	}
+	log.Warn(warning)
+}
+
+// outdatedWarning returns what WarnIfOutdated says about ver ("" if there is nothing to warn about).
+func outdatedWarning(ver string, data EOLData, latest map[string]string, now time.Time) string {
+	v, err := semver.ParseVersion(ver)
+	if err != nil || v.Qualifier() == "system" {
+		return ""
+	}
+	var problems []string
+	if eol, ok := eolOf(data, v); ok && now.After(eol) {
+		problems = append(problems, "reached end of support on "+eol.Format("2006-01-02"))
+	}
+	if l := latest[ver]; l != "" && l != ver {
+		problems = append(problems, "is superseded by "+l+" (`jabba upgrade "+ver+"`)")
+	}
+	if len(problems) == 0 {
+		return ""
+	}
+	return ver + " " + strings.Join(problems, " and ") + " (see `jabba outdated`, JABBA_EOL_WARNINGS=false to silence)"
+}
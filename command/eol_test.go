@@ -0,0 +1,120 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+func TestEOLOf(t *testing.T) {
+	data := EOLData{
+		"*":       {"17": "2027-10-31", "*": eolNextRelease},
+		"zulu":    {"17": "2029-09-30"},
+		"openjdk": {"*": eolNextRelease},
+	}
+	for ver, expected := range map[string]string{
+		"zulu@1.17.0-9":     "2029-09-30",
+		"temurin@1.17.0-9":  "2027-10-31",
+		"1.17.0":            "2027-10-31",
+		"temurin@1.22.0-36": "2024-09-17",
+		"openjdk@1.17.0-2":  "2022-03-22",
+		"graalvm@22.3.0":    "",
+	} {
+		v, err := semver.ParseVersion(ver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual string
+		if eol, ok := eolOf(data, v); ok {
+			actual = eol.Format("2006-01-02")
+		}
+		if actual != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", ver, actual, expected)
+		}
+	}
+	// every LTS line is covered by the built-in data
+	for major := 8; major <= 25; major++ {
+		if _, ok := builtinEOL["*"][strconv.Itoa(major)]; IsLTS(major) && !ok {
+			t.Fatalf("there is no end of support date for %d", major)
+		}
+	}
+}
+
+func TestOutdatedWarning(t *testing.T) {
+	now, _ := time.Parse("2006-01-02", "2026-10-17")
+	latest := map[string]string{"temurin@1.17.0-9": "temurin@1.17.0-16", "temurin@1.21.0-8": "temurin@1.21.0-8"}
+	if actual := outdatedWarning("temurin@1.21.0-8", builtinEOL, latest, now); actual != "" {
+		t.Fatalf("actual: %v != expected: no warning", actual)
+	}
+	actual := outdatedWarning("temurin@1.17.0-9", builtinEOL, latest, now)
+	if !strings.HasPrefix(actual, "temurin@1.17.0-9 is superseded by temurin@1.17.0-16") {
+		t.Fatalf("actual: %v", actual)
+	}
+	actual = outdatedWarning("adopt@1.11.0-10", builtinEOL, latest, now)
+	if !strings.HasPrefix(actual, "adopt@1.11.0-10 reached end of support on 2021-08-01") {
+		t.Fatalf("actual: %v", actual)
+	}
+	if actual := outdatedWarning("system@1.6.0", builtinEOL, latest, now); actual != "" {
+		t.Fatalf("actual: %v != expected: system JDKs to be left alone", actual)
+	}
+}
+
+func TestOutdated(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	index := filepath.Join(home, "index.json")
+	err = ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"`+HostArch()+`": {"jdk@temurin": {
+		"1.17.0-9": "tgz+https://example.com/17.0.9.tar.gz", "1.17.0-16": "tgz+https://example.com/17.0.16.tar.gz",
+		"1.21.0-8": "tgz+https://example.com/21.0.8.tar.gz"
+	}}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	installFakeJDKs(t, home, "temurin@1.17.0-9", "temurin@1.21.0-8", "custom@1.8.0")
+	jdks, err := Outdated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, jdk := range jdks {
+		actual = append(actual, jdk.Version+" -> "+jdk.Latest)
+	}
+	expected := []string{"custom@1.8.0 -> ", "temurin@1.21.0-8 -> temurin@1.21.0-8",
+		"temurin@1.17.0-9 -> temurin@1.17.0-16"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if !jdks[2].Superseded() || jdks[1].Superseded() || jdks[0].Problem == "" {
+		t.Fatalf("actual: %+v", jdks)
+	}
+	// `jabba use` / `jabba current` warn at most once a day
+	state := readOutdatedState()
+	if state.Latest["temurin@1.17.0-9"] != "temurin@1.17.0-16" {
+		t.Fatalf("actual: %v", state.Latest)
+	}
+	WarnIfOutdated("temurin@1.17.0-9")
+	warnedAt := readOutdatedState().WarnedAt["temurin@1.17.0-9"]
+	if warnedAt.IsZero() {
+		t.Fatal("expected warning to be recorded")
+	}
+	WarnIfOutdated("temurin@1.17.0-9")
+	if !readOutdatedState().WarnedAt["temurin@1.17.0-9"].Equal(warnedAt) {
+		t.Fatal("expected warning not to be repeated")
+	}
+}
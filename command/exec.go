@@ -0,0 +1,180 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// Java-related environment variables `jabba exec --isolated` removes
+// (so that command runs against a clean runtime)
+var isolatedEnv = []string{"JAVA_OPTS", "JAVA_TOOL_OPTIONS", "_JAVA_OPTIONS", "JDK_JAVA_OPTIONS", "CLASSPATH"}
+
+type ExecOptions struct {
+	// install JDK if there is none matching the selector
+	Install bool
+	// remove isolatedEnv (except for the variables listed in Keep) from command's environment
+	Isolated bool
+	Keep     []string
+	// "auto", "always" or "never" ("" is the same as "never") (see splitEnvFile)
+	EnvFile string
+	// variables (glob patterns (e.g. "MATRIX_*")) to move into env file along with the ones attached to the JDK
+	EnvFileVars []string
+}
+
+// Exec runs command with PATH & JAVA_HOME pointing to the JDK matching the selector
+// (installing it first if opts.Install is true and there is no such JDK), returning command's exit code.
+func Exec(selector string, opts ExecOptions, args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, errors.New("Command to execute is missing")
+	}
+	resolved, err := ResolveAlias(selector)
+	if err != nil {
+		return 0, err
+	}
+	ver, err := LsBestMatch(resolved)
+	if err != nil {
+		if !opts.Install {
+			return 0, err
+		}
+		if ver, err = installMissing(resolved); err != nil {
+			return 0, err
+		}
+	}
+	recordHistory("exec", selector, ver)
+	return run(filepath.Join(cfg.JDKDir(), ver), selector, args, opts)
+}
+
+// installMissing installs the latest release matching the selector (for `jabba exec --install`, `jabba use --install`),
+// returning its version.
+func installMissing(selector string) (string, error) {
+	lock, err := LockHome()
+	if err != nil {
+		return "", err
+	}
+	defer lock.Unlock()
+	log.Info(selector, " isn't installed. Installing")
+	result, err := Install(selector, InstallOptions{})
+	if err == nil {
+		err = LinkLatest()
+	}
+	if err != nil {
+		return "", err
+	}
+	return result.Version, nil
+}
+
+// scrubbedEnv returns isolatedEnv minus the variables to keep.
+func scrubbedEnv(keep []string) []string {
+	var r []string
+	for _, key := range isolatedEnv {
+		kept := false
+		for _, k := range keep {
+			if strings.EqualFold(k, key) {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			r = append(r, key)
+		}
+	}
+	return r
+}
+
+// Try installs JDK matching the selector into a temporary directory, runs command against it and removes the JDK
+// (downloaded archive stays in the cache (if any)) once command exits, returning command's exit code.
+// $JABBA_HOME (jdk/, aliases, etc.) is left untouched.
+func Try(selector string, opts InstallOptions, args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, errors.New("Command to execute is missing")
+	}
+	tmp, release, err := mkTempDir("jabba-try-")
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	opts.Dst = filepath.Join(tmp, "jdk")
+	result, err := Install(selector, opts)
+	if err != nil {
+		return 0, err
+	}
+	log.Info("Running ", strings.Join(args, " "), " with ", result.Version, " (it's going to be removed afterwards)")
+	return run(result.Path, "", args, ExecOptions{})
+}
+
+// run runs command with PATH & JAVA_HOME pointing to the JDK selector resolved to (variables attached to it (see
+// SetJDKEnv) set and, if opts.Isolated is true, isolatedEnv removed).
+func run(jdk string, selector string, args []string, opts ExecOptions) (int, error) {
+	if err := ValidateEnvFileMode(opts.EnvFile); err != nil {
+		return 0, err
+	}
+	var unset []string
+	if opts.Isolated {
+		unset = scrubbedEnv(opts.Keep)
+	}
+	env, err := useEnv(jdk)
+	if err != nil {
+		return 0, err
+	}
+	base := len(env)
+	env, jdkUnset := withJDKEnv(env, selector, filepath.Base(jdk))
+	unset = append(unset, jdkUnset...)
+	var jdkKeys []string
+	for _, kv := range env[base:] {
+		key, _ := splitEnv(kv)
+		jdkKeys = append(jdkKeys, key)
+	}
+	for _, kv := range env {
+		split := strings.SplitN(kv, "=", 2)
+		// so that command is looked up in the (updated) PATH
+		if err := os.Setenv(split[0], split[1]); err != nil {
+			return 0, err
+		}
+	}
+	for _, key := range unset {
+		if _, ok := os.LookupEnv(key); ok {
+			log.Debug("Unsetting ", key)
+		}
+		if err := os.Unsetenv(key); err != nil {
+			return 0, err
+		}
+	}
+	// after isolatedEnv is removed (so that --isolated keeps truststore)
+	truststoreSet, truststoreUnset := withTruststore(nil, jdk)
+	for _, kv := range truststoreSet {
+		key, value := splitEnv(kv)
+		if err := os.Setenv(key, value); err != nil {
+			return 0, err
+		}
+	}
+	for _, key := range truststoreUnset {
+		if err := os.Unsetenv(key); err != nil {
+			return 0, err
+		}
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	var removeEnvFile func()
+	if cmd.Env, removeEnvFile, err = withEnvFile(os.Environ(), args, opts, jdkKeys); err != nil {
+		return 0, err
+	}
+	defer removeEnvFile()
+	// Ctrl+C is delivered to the whole process group, it's up to the command to decide what to do with it
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+	return 0, nil
+}
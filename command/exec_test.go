@@ -0,0 +1,126 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available")
+	}
+	for _, key := range []string{"PATH", "JAVA_HOME", "JAVA_HOME_BEFORE_JABBA"} {
+		prev, ok := os.LookupEnv(key)
+		defer func(key string) {
+			if ok {
+				os.Setenv(key, prev)
+			} else {
+				os.Unsetenv(key)
+			}
+		}(key)
+	}
+	var prevReadDir = readDir
+	defer func() { readDir = prevReadDir }()
+	readDir = func(dirname string) ([]os.FileInfo, error) {
+		return []os.FileInfo{FileInfoMock("1.7.2"), FileInfoMock("1.8.0")}, nil
+	}
+	os.Setenv("JAVA_HOME", "/system-jdk")
+	javaHome := filepath.Join(cfg.JDKDir(), "1.7.2")
+	if runtime.GOOS == "darwin" {
+		javaHome = filepath.Join(javaHome, "Contents", "Home")
+	}
+	code, err := Exec("1.7", ExecOptions{}, []string{"sh", "-c",
+		`test "$JAVA_HOME" = "` + javaHome + `" && test "$JAVA_HOME_BEFORE_JABBA" = /system-jdk && exit 7`})
+	if err != nil || code != 7 {
+		t.Fatalf("actual: %v (%v) != expected: 7", code, err)
+	}
+	if _, err := Exec("1.9", ExecOptions{}, []string{"sh"}); err == nil {
+		t.Fatal("expected Exec to fail (1.9 isn't installed)")
+	}
+}
+
+func TestExecIsolated(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available")
+	}
+	for _, key := range []string{"PATH", "JAVA_HOME", "JAVA_HOME_BEFORE_JABBA", "JAVA_TOOL_OPTIONS", "CLASSPATH"} {
+		prev, ok := os.LookupEnv(key)
+		defer func(key string) {
+			if ok {
+				os.Setenv(key, prev)
+			} else {
+				os.Unsetenv(key)
+			}
+		}(key)
+	}
+	var prevReadDir = readDir
+	defer func() { readDir = prevReadDir }()
+	readDir = func(dirname string) ([]os.FileInfo, error) {
+		return []os.FileInfo{FileInfoMock("1.8.0")}, nil
+	}
+	os.Setenv("JAVA_TOOL_OPTIONS", "-Xmx1m")
+	os.Setenv("CLASSPATH", "/tmp/classes")
+	code, err := Exec("1.8", ExecOptions{Isolated: true, Keep: []string{"CLASSPATH"}}, []string{"sh", "-c",
+		`test -z "${JAVA_TOOL_OPTIONS+x}" && test "$CLASSPATH" = /tmp/classes && exit 5`})
+	if err != nil || code != 5 {
+		t.Fatalf("actual: %v (%v) != expected: 5", code, err)
+	}
+}
+
+func TestTry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is not available")
+	}
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"PATH", "JAVA_HOME", "JAVA_HOME_BEFORE_JABBA"} {
+		prev, ok := os.LookupEnv(key)
+		defer func(key string) {
+			if ok {
+				os.Setenv(key, prev)
+			} else {
+				os.Unsetenv(key)
+			}
+		}(key)
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	archive := filepath.Join(home, "jdk.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, file := range []string{"jdk/bin/java", "jdk/release"} {
+		ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755}))
+	}
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	out := filepath.Join(home, "java_home")
+	code, err := Try("1.22.0-ea="+"tgz+file://"+filepath.ToSlash(archive), InstallOptions{},
+		[]string{"sh", "-c", `printf %s "$JAVA_HOME" > "` + out + `" && test -f "$JAVA_HOME/release" && exit 3`})
+	if err != nil || code != 3 {
+		t.Fatalf("actual: %v (%v) != expected: 3", code, err)
+	}
+	javaHome, err := ioutil.ReadFile(out)
+	ok(err)
+	if _, err := os.Stat(string(javaHome)); !os.IsNotExist(err) {
+		t.Fatalf("%s wasn't removed (%v)", javaHome, err)
+	}
+	if vs := installed(t); len(vs) != 0 {
+		t.Fatalf("actual: %v != expected: []", vs)
+	}
+}
@@ -0,0 +1,65 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// Facts describe jabba installation of the host (`jabba facts`) (what `jabba report` aggregates across the fleet).
+type Facts struct {
+	Host string `json:"host"`
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// version of jabba
+	Jabba string `json:"jabba,omitempty"`
+	Home  string `json:"home"`
+	// installed version "default" alias resolves to ("" if there is no such alias (or JDK it points to is missing))
+	Default string `json:"default,omitempty"`
+	// alias -> installed version it resolves to (selector alias resolves to if there is no matching JDK)
+	Aliases map[string]string `json:"aliases,omitempty"`
+	JDKs    []JDK             `json:"jdks"`
+}
+
+// CollectFacts describes jabba installation (jabbaVersion is the version of jabba itself).
+func CollectFacts(jabbaVersion string) (*Facts, error) {
+	host, _ := os.Hostname()
+	facts := &Facts{Host: host, OS: runtime.GOOS, Arch: HostArch(), Jabba: jabbaVersion, Home: cfg.Dir(),
+		JDKs: []JDK{}}
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range vs {
+		jdk := JDK{Version: v.String(), Vendor: v.Qualifier(), Archs: InstalledArchs(v.String()),
+			Path: filepath.Join(cfg.JDKDir(), v.String()), Pinned: IsPinned(v.String())}
+		// walking every JDK (see DescribeInstalled) would make `jabba report --remote` crawl
+		if meta, err := readInstallMeta(v.String()); err == nil {
+			jdk.Size = meta.Size
+		}
+		facts.JDKs = append(facts.JDKs, jdk)
+	}
+	names, err := Aliases()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		selector, err := ResolveAlias(name)
+		if err != nil {
+			continue
+		}
+		if facts.Aliases == nil {
+			facts.Aliases = make(map[string]string)
+		}
+		facts.Aliases[name] = selector
+		if ver, err := LsBestMatchWithVersionSlice(vs, selector); err == nil {
+			facts.Aliases[name] = ver
+			if name == "default" {
+				facts.Default = ver
+			}
+		}
+	}
+	return facts, nil
+}
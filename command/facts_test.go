@@ -0,0 +1,52 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCollectFacts(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "zulu@1.8.0", "zulu@1.17.0")
+	for name, value := range map[string]string{"default": "zulu@1.17", "legacy": "zulu@1.7"} {
+		if err := SetAlias(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	facts, err := CollectFacts("0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if facts.OS != runtime.GOOS || facts.Jabba != "0.0.0" || facts.Home != home {
+		t.Fatalf("actual: %+v", facts)
+	}
+	if facts.Default != "zulu@1.17.0" {
+		t.Fatalf("actual: %v != expected: %v", facts.Default, "zulu@1.17.0")
+	}
+	// alias pointing to a JDK that is not installed is reported as is
+	expectedAliases := map[string]string{"default": "zulu@1.17.0", "legacy": "zulu@1.7"}
+	if !reflect.DeepEqual(facts.Aliases, expectedAliases) {
+		t.Fatalf("actual: %v != expected: %v", facts.Aliases, expectedAliases)
+	}
+	var vs []string
+	for _, jdk := range facts.JDKs {
+		vs = append(vs, jdk.Version)
+	}
+	expected := []string{"zulu@1.17.0", "zulu@1.8.0"}
+	if !reflect.DeepEqual(vs, expected) {
+		t.Fatalf("actual: %v != expected: %v", vs, expected)
+	}
+	if facts.JDKs[0].Path != filepath.Join(home, "jdk", "zulu@1.17.0") || facts.JDKs[0].Vendor != "zulu" {
+		t.Fatalf("actual: %+v", facts.JDKs[0])
+	}
+}
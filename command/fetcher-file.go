@@ -0,0 +1,33 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolveLocalArchive turns file:// URL into a path. URL can be a glob pattern (e.g.
+// file:///mnt/jdk/zulu17.*-linux_x64.tar.gz (archives mirrored to a network share under their original names)),
+// in which case it has to match exactly one file.
+func resolveLocalArchive(url string) (string, error) {
+	file := localPath(url)
+	if !strings.ContainsAny(file, "*?[") {
+		return file, nil
+	}
+	if _, err := os.Stat(file); err == nil {
+		// file name that just happens to contain glob metacharacters
+		return file, nil
+	}
+	matches, err := filepath.Glob(file)
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", url, err)
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%s doesn't match any file", url)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%s matches more than one file (%s)", url, strings.Join(matches, ", "))
+}
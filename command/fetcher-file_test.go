@@ -0,0 +1,44 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveLocalArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-archives")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"zulu17.44.53-ca-jdk17.0.8.1-linux_x64.tar.gz",
+		"zulu21.28.85-ca-jdk21.0.0-linux_x64.tar.gz", "zulu21.30.15-ca-jdk21.0.1-linux_x64.tar.gz"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	url := func(name string) string {
+		return "file://" + filepath.ToSlash(filepath.Join(dir, name))
+	}
+	file, err := resolveLocalArchive(url("zulu17.*-linux_x64.tar.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := filepath.Join(dir, "zulu17.44.53-ca-jdk17.0.8.1-linux_x64.tar.gz"); file != expected {
+		t.Fatalf("actual: %v != expected: %v", file, expected)
+	}
+	if _, err := resolveLocalArchive(url("zulu21.*-linux_x64.tar.gz")); err == nil ||
+		!strings.Contains(err.Error(), "matches more than one file") {
+		t.Fatalf("actual: %v != expected: ambiguity to be reported", err)
+	}
+	if _, err := resolveLocalArchive(url("zulu11.*.tar.gz")); err == nil {
+		t.Fatal("expected error (no match)")
+	}
+	// no glob, no check (file is opened later on)
+	if file, err := resolveLocalArchive(url("missing.tar.gz")); err != nil || file != filepath.Join(dir, "missing.tar.gz") {
+		t.Fatalf("actual: %v, %v", file, err)
+	}
+}
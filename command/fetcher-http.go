@@ -0,0 +1,162 @@
+package command
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// authTransport adds credentials to the requests to authenticated repositories (e.g. Artifactory / Nexus mirrors of
+// JDK archives): "auth" entries of config.yaml (see cfg.Auth) or, if there is no entry matching the URL, ~/.netrc
+// ($NETRC) (https:// only). Credentials are added per request, i.e. they are not carried over to the host request is
+// redirected to (e.g. pre-signed S3 URL).
+type authTransport struct {
+	transport http.RoundTripper
+	auth      []cfg.AuthEntry
+	netrc     []netrcEntry
+}
+
+func (t authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") == "" {
+		if name, value, ok := t.credentials(req.URL); ok {
+			req = req.Clone(req.Context())
+			req.Header.Set(name, value)
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// credentials returns header to authenticate request to u with (ok is false if there are no credentials for u).
+func (t authTransport) credentials(u *url.URL) (name string, value string, ok bool) {
+	if entry, found := matchAuthEntry(t.auth, u); found {
+		switch {
+		case entry.Token != "":
+			return "Authorization", "Bearer " + entry.Token, true
+		case entry.Header != "":
+			if i := strings.Index(entry.Header, ":"); i != -1 {
+				return strings.TrimSpace(entry.Header[:i]), strings.TrimSpace(entry.Header[i+1:]), true
+			}
+			log.Warn("auth: header of ", entry.URL, " must be in \"Name: value\" format")
+			return "", "", false
+		case entry.Username != "":
+			req := &http.Request{Header: make(http.Header)}
+			req.SetBasicAuth(entry.Username, entry.Password)
+			return "Authorization", req.Header.Get("Authorization"), true
+		}
+		return "", "", false
+	}
+	if u.Scheme != "https" {
+		return "", "", false
+	}
+	for _, entry := range t.netrc {
+		if entry.machine == "" || strings.EqualFold(entry.machine, u.Hostname()) {
+			req := &http.Request{Header: make(http.Header)}
+			req.SetBasicAuth(entry.login, entry.password)
+			return "Authorization", req.Header.Get("Authorization"), true
+		}
+	}
+	return "", "", false
+}
+
+// matchAuthEntry returns entry with the longest URL prefix u is under.
+func matchAuthEntry(entries []cfg.AuthEntry, u *url.URL) (match cfg.AuthEntry, ok bool) {
+	longest := -1
+	for _, entry := range entries {
+		eu, err := url.Parse(entry.URL)
+		if err != nil || !strings.EqualFold(eu.Scheme, u.Scheme) || !strings.EqualFold(eu.Host, u.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(eu.Path, "/")
+		if u.Path != prefix && !strings.HasPrefix(u.Path, prefix+"/") {
+			continue
+		}
+		if len(prefix) > longest {
+			longest, match, ok = len(prefix), entry, true
+		}
+	}
+	return
+}
+
+type netrcEntry struct {
+	// "" for "default"
+	machine  string
+	login    string
+	password string
+}
+
+// loadNetrc reads $NETRC (~/.netrc (~/_netrc on Windows) by default), nil if there is no such file.
+func loadNetrc() []netrcEntry {
+	file := os.Getenv("NETRC")
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		file = filepath.Join(home, ".netrc")
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			file = filepath.Join(home, "_netrc")
+		}
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debug("Failed to read ", file, " (", err, ")")
+		}
+		return nil
+	}
+	return parseNetrc(string(b))
+}
+
+// parseNetrc parses .netrc ("default" entry, if any, goes last (it applies only if no machine matches)).
+func parseNetrc(content string) []netrcEntry {
+	var r []netrcEntry
+	var def *netrcEntry
+	var current *netrcEntry
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			value := func() string {
+				if j+1 < len(fields) {
+					j++
+					return fields[j]
+				}
+				return ""
+			}
+			switch fields[j] {
+			case "machine":
+				r = append(r, netrcEntry{machine: value()})
+				current = &r[len(r)-1]
+			case "default":
+				def = &netrcEntry{}
+				current = def
+			case "login":
+				if current != nil {
+					current.login = value()
+				}
+			case "password":
+				if current != nil {
+					current.password = value()
+				}
+			case "account":
+				value()
+			case "macdef":
+				// macro definition runs until the next blank line
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+	if def != nil {
+		r = append(r, *def)
+	}
+	return r
+}
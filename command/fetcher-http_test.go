@@ -0,0 +1,54 @@
+package command
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestParseNetrc(t *testing.T) {
+	actual := parseNetrc("default login anonymous password x\n" +
+		"machine artifactory.example.com\n  login ci\n  password s3cr3t\n" +
+		"macdef init\ncd /pub\nmachine ignored.example.com login x\n\n" +
+		"machine nexus.example.com login deployer password p account a\n")
+	expected := []netrcEntry{
+		{machine: "artifactory.example.com", login: "ci", password: "s3cr3t"},
+		{machine: "nexus.example.com", login: "deployer", password: "p"},
+		{login: "anonymous", password: "x"},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestAuthTransportCredentials(t *testing.T) {
+	transport := authTransport{
+		auth: []cfg.AuthEntry{
+			{URL: "https://artifactory.example.com/artifactory/", Header: "X-JFrog-Art-Api: key"},
+			{URL: "https://artifactory.example.com/artifactory/jdk", Token: "token"},
+			{URL: "http://nexus.example.com/repository/jdk/", Username: "ci", Password: "p"},
+		},
+		netrc: []netrcEntry{{machine: "nexus.example.com", login: "deployer", password: "p"}},
+	}
+	for rawurl, expected := range map[string]string{
+		"https://artifactory.example.com/artifactory/jdk/zulu.tar.gz":   "Authorization: Bearer token",
+		"https://artifactory.example.com/artifactory/jdk":               "Authorization: Bearer token",
+		"https://artifactory.example.com/artifactory/jdkx/zulu.tar.gz":  "X-JFrog-Art-Api: key",
+		"https://artifactory.example.com/other/zulu.tar.gz":             "",
+		"http://nexus.example.com/repository/jdk/zulu.tar.gz":           "Authorization: Basic Y2k6cA==",
+		"https://nexus.example.com/repository/other/zulu.tar.gz":        "Authorization: Basic ZGVwbG95ZXI6cA==",
+		"http://nexus.example.com/repository/other/zulu.tar.gz":         "",
+		"https://artifactory.example.com.evil.com/artifactory/jdk/x.gz": "",
+	} {
+		u, _ := url.Parse(rawurl)
+		var actual string
+		if name, value, ok := transport.credentials(u); ok {
+			actual = name + ": " + value
+		}
+		if actual != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", rawurl, actual, expected)
+		}
+	}
+}
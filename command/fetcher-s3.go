@@ -0,0 +1,459 @@
+package command
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// s3Fetcher fetches s3://<bucket>/<key> URLs (objects of private buckets included) with requests signed by AWS
+// Signature Version 4. Credentials are looked up the same way AWS CLI / SDKs do (minus SSO, assume-role &
+// credential_process): AWS_ACCESS_KEY_ID & AWS_SECRET_ACCESS_KEY (& AWS_SESSION_TOKEN), shared credentials file
+// (~/.aws/credentials, $AWS_SHARED_CREDENTIALS_FILE) / config file (~/.aws/config, $AWS_CONFIG_FILE) profile
+// ($AWS_PROFILE, "default" by default), ECS container credentials, EC2 instance metadata (IMDSv2). Requests are sent
+// unsigned (public buckets) if there are none. AWS_ENDPOINT_URL_S3 / AWS_ENDPOINT_URL point it at S3-compatible
+// storage (e.g. MinIO) (path-style addressing).
+type s3Fetcher struct {
+	base http.RoundTripper
+	// endpoints of AWS (metadata endpoints (IMDS / ECS) are reached without going through proxy)
+	metadataClient *http.Client
+	mutex          sync.Mutex
+	creds          *awsCredentials
+	// true once credentials were looked up (creds being nil if there are none)
+	credsLoaded bool
+	// bucket -> region (learned from S3 redirects (x-amz-bucket-region))
+	regions map[string]string
+}
+
+func newS3Fetcher(base http.RoundTripper) *s3Fetcher {
+	return &s3Fetcher{base: base, regions: make(map[string]string),
+		metadataClient: &http.Client{Transport: &http.Transport{}, Timeout: 2 * time.Second}}
+}
+
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// zero if credentials don't expire
+	Expiration time.Time
+	// where credentials came from (for debugging)
+	source string
+}
+
+func (f *s3Fetcher) RoundTrip(req *http.Request) (*http.Response, error) {
+	bucket := req.URL.Host
+	if bucket == "" {
+		return nil, fmt.Errorf("%s: bucket is missing (expected s3://<bucket>/<key>)", req.URL)
+	}
+	creds, err := f.credentials()
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		region := f.region(bucket)
+		target, err := url.Parse(s3ObjectURL(bucket, req.URL.Path, region))
+		if err != nil {
+			return nil, err
+		}
+		r, err := http.NewRequestWithContext(req.Context(), req.Method, target.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range req.Header {
+			r.Header[name] = values
+		}
+		if creds != nil {
+			signS3(r, creds, region, time.Now())
+		}
+		log.Debug(req.Method, " ", req.URL, " -> ", target, " (credentials: ", creds.describe(), ")")
+		res, err := f.base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		// bucket is in a different region than the one requests were signed for
+		if actual := res.Header.Get("X-Amz-Bucket-Region"); res.StatusCode >= 300 && actual != "" &&
+			actual != region && attempt == 1 && s3Endpoint() == "" {
+			res.Body.Close()
+			f.mutex.Lock()
+			f.regions[bucket] = actual
+			f.mutex.Unlock()
+			continue
+		}
+		if res.StatusCode >= 400 && res.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+			logS3Error(req, res, creds)
+		}
+		res.Request = req
+		return res, nil
+	}
+}
+
+func (c *awsCredentials) describe() string {
+	if c == nil {
+		return "none (anonymous request)"
+	}
+	return c.source
+}
+
+var s3ErrorRegexp = regexp.MustCompile(`<Code>([^<]*)</Code>(?:\s*<Message>([^<]*)</Message>)?`)
+
+// logS3Error logs S3 error code & message (e.g. "AccessDenied: Access Denied") of the (failed) response (body is
+// replaced with a copy).
+func logS3Error(req *http.Request, res *http.Response, creds *awsCredentials) {
+	b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+	if m := s3ErrorRegexp.FindStringSubmatch(string(b)); m != nil {
+		log.Debug(req.URL, ": ", m[1], ": ", m[2])
+	}
+	if creds == nil && (res.StatusCode == http.StatusForbidden || res.StatusCode == http.StatusNotFound) {
+		log.Warn("No AWS credentials found (request to ", req.URL, " was sent anonymously). "+
+			"Set AWS_PROFILE or AWS_ACCESS_KEY_ID & AWS_SECRET_ACCESS_KEY")
+	}
+}
+
+// s3Endpoint returns custom S3 endpoint ("" if there is none).
+func s3Endpoint() string {
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL_S3"); endpoint != "" {
+		return endpoint
+	}
+	return os.Getenv("AWS_ENDPOINT_URL")
+}
+
+// s3ObjectURL returns https:// URL of the object (path-style if custom endpoint is used or bucket name contains dots
+// (which wildcard certificate of *.s3.<region>.amazonaws.com doesn't cover)).
+func s3ObjectURL(bucket string, key string, region string) string {
+	path := awsURIEncode(strings.TrimPrefix(key, "/"), false)
+	if endpoint := s3Endpoint(); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + path
+	}
+	if strings.Contains(bucket, ".") {
+		return "https://s3." + region + ".amazonaws.com/" + bucket + "/" + path
+	}
+	return "https://" + bucket + ".s3." + region + ".amazonaws.com/" + path
+}
+
+// region returns region of the bucket (AWS_REGION, AWS_DEFAULT_REGION, region of the profile, us-east-1, unless
+// S3 said otherwise).
+func (f *s3Fetcher) region(bucket string) string {
+	f.mutex.Lock()
+	region := f.regions[bucket]
+	f.mutex.Unlock()
+	if region != "" {
+		return region
+	}
+	for _, key := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(key); region != "" {
+			return region
+		}
+	}
+	if region := awsConfigProfile()["region"]; region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+// credentials returns AWS credentials (nil if there are none), refreshing them if they are about to expire.
+func (f *s3Fetcher) credentials() (*awsCredentials, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.credsLoaded && (f.creds == nil || f.creds.Expiration.IsZero() ||
+		time.Until(f.creds.Expiration) > 5*time.Minute) {
+		return f.creds, nil
+	}
+	creds, err := f.loadCredentials()
+	if err != nil {
+		return nil, err
+	}
+	f.creds, f.credsLoaded = creds, true
+	return creds, nil
+}
+
+func (f *s3Fetcher) loadCredentials() (*awsCredentials, error) {
+	if key, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); key != "" && secret != "" {
+		return &awsCredentials{AccessKeyID: key, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			source: "environment"}, nil
+	}
+	profile := awsProfileName()
+	for _, file := range []string{awsSharedCredentialsFile(), awsConfigFile()} {
+		section := profile
+		if file == awsConfigFile() && profile != "default" {
+			section = "profile " + profile
+		}
+		values := readINI(file)[section]
+		if values["aws_access_key_id"] != "" && values["aws_secret_access_key"] != "" {
+			return &awsCredentials{AccessKeyID: values["aws_access_key_id"],
+				SecretAccessKey: values["aws_secret_access_key"], SessionToken: values["aws_session_token"],
+				source: file + " [" + section + "]"}, nil
+		}
+	}
+	if creds, err := f.containerCredentials(); creds != nil || err != nil {
+		return creds, err
+	}
+	if !strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		creds, err := f.instanceCredentials()
+		if err == nil {
+			return creds, nil
+		}
+		log.Debug("EC2 instance metadata is not available (", err, ")")
+	}
+	return nil, nil
+}
+
+// containerCredentials returns credentials of ECS task role (nil if not running in ECS (or other container
+// environment that exposes credentials the same way (e.g. EKS Pod Identity))).
+func (f *s3Fetcher) containerCredentials() (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		endpoint = "http://169.254.170.2" + uri
+	}
+	if endpoint == "" {
+		return nil, nil
+	}
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if file := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	creds, err := f.fetchMetadataCredentials(req)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get container credentials (%v)", err)
+	}
+	creds.source = "container credentials"
+	return creds, nil
+}
+
+// instanceCredentials returns credentials of EC2 instance profile (IMDSv2).
+func (f *s3Fetcher) instanceCredentials() (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	req, err := http.NewRequest("PUT", endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	token, err := f.metadata(req)
+	if err != nil {
+		return nil, err
+	}
+	get := func(path string) (*http.Request, error) {
+		req, err := http.NewRequest("GET", endpoint+path, nil)
+		if err == nil {
+			req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+		}
+		return req, err
+	}
+	req, err = get("/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return nil, err
+	}
+	role, err := f.metadata(req)
+	if err != nil {
+		return nil, err
+	}
+	role = strings.TrimSpace(strings.SplitN(role, "\n", 2)[0])
+	if role == "" {
+		return nil, errors.New("instance has no IAM role")
+	}
+	if req, err = get("/latest/meta-data/iam/security-credentials/" + role); err != nil {
+		return nil, err
+	}
+	creds, err := f.fetchMetadataCredentials(req)
+	if err != nil {
+		return nil, err
+	}
+	creds.source = "EC2 instance profile (" + role + ")"
+	return creds, nil
+}
+
+func (f *s3Fetcher) metadata(req *http.Request) (string, error) {
+	res, err := f.metadataClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return "", fmt.Errorf("%s %s returned %d", req.Method, req.URL, res.StatusCode)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	return string(b), err
+}
+
+func (f *s3Fetcher) fetchMetadataCredentials(req *http.Request) (*awsCredentials, error) {
+	body, err := f.metadata(req)
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return nil, err
+	}
+	if v.AccessKeyID == "" || v.SecretAccessKey == "" {
+		return nil, fmt.Errorf("%s returned no credentials", req.URL)
+	}
+	return &awsCredentials{AccessKeyID: v.AccessKeyID, SecretAccessKey: v.SecretAccessKey, SessionToken: v.Token,
+		Expiration: v.Expiration}, nil
+}
+
+func awsProfileName() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+func awsSharedCredentialsFile() string {
+	if file := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); file != "" {
+		return file
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", "credentials")
+}
+
+func awsConfigFile() string {
+	if file := os.Getenv("AWS_CONFIG_FILE"); file != "" {
+		return file
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".aws", "config")
+}
+
+// awsConfigProfile returns settings of the profile in ~/.aws/config.
+func awsConfigProfile() map[string]string {
+	section := "default"
+	if profile := awsProfileName(); profile != "default" {
+		section = "profile " + profile
+	}
+	return readINI(awsConfigFile())[section]
+}
+
+// readINI returns section -> key -> value of the INI file (empty if it cannot be read).
+func readINI(file string) map[string]map[string]string {
+	r := make(map[string]map[string]string)
+	f, err := os.Open(file)
+	if err != nil {
+		return r
+	}
+	defer f.Close()
+	var section map[string]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[' && line[len(line)-1] == ']':
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if r[name] == nil {
+				r[name] = make(map[string]string)
+			}
+			section = r[name]
+		case section != nil:
+			if i := strings.Index(line, "="); i != -1 {
+				section[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+			}
+		}
+	}
+	return r
+}
+
+// sha256 of the empty payload (GET / HEAD)
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// signS3 signs request (with empty payload) to S3 in the region with AWS Signature Version 4
+// (https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html).
+func signS3(req *http.Request, creds *awsCredentials, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{req.Method, req.URL.EscapedPath(), req.URL.RawQuery,
+		canonicalHeaders.String(), signedHeaders, emptySHA256}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters (RFC 3986) ("/" too, unless encodeSlash is
+// false).
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
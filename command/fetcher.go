@@ -0,0 +1,29 @@
+package command
+
+import (
+	"net/http"
+)
+
+// fetcher is a download backend for the URLs of a scheme other than http(s) (selected by the scheme of the URL in the
+// index or the selector, e.g. "tgz+s3://bucket/zulu17.tar.gz"). Backends speak HTTP (status codes, Range /
+// Content-Range, Content-Length), so that download resume & retries, streaming extraction, HEAD requests
+// (`jabba install --show-plan`, `jabba index lint --probe`) and index fetches work the same way for all of them.
+// file:// URLs are not fetched (archive is read in place (see resolveLocalArchive)).
+type fetcher interface {
+	// RoundTrip sends GET / HEAD request to req.URL (URL of the scheme fetcher is registered for).
+	RoundTrip(req *http.Request) (*http.Response, error)
+}
+
+// fetchers by URL scheme (base is the transport to send HTTP(S) requests through (proxy, --cacert, timeouts
+// included)).
+var fetchers = map[string]func(base http.RoundTripper) fetcher{
+	"s3": func(base http.RoundTripper) fetcher { return newS3Fetcher(base) },
+}
+
+// registerFetchers makes t hand requests to URLs of the schemes in fetchers to the respective backends.
+func registerFetchers(t *http.Transport) {
+	base := t.Clone()
+	for scheme, newFetcher := range fetchers {
+		t.RegisterProtocol(scheme, newFetcher(base))
+	}
+}
@@ -0,0 +1,135 @@
+package flock
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"time"
+)
+
+// ErrTimeout is returned by LockWithTimeout if lock wasn't acquired in time.
+var ErrTimeout = errors.New("timed out waiting for the lock")
+
+// how often LockWithTimeout checks whether lock has been released
+var pollInterval = 100 * time.Millisecond
+
+// Lock is an advisory lock backed by a file (flock(2) on Unix, LockFileEx on Windows),
+// i.e. it guards against other processes (including those run by other users), not goroutines.
+type Lock struct {
+	path string
+	f    *os.File
+}
+
+func New(path string) *Lock {
+	return &Lock{path: path}
+}
+
+func (l *Lock) Path() string {
+	return l.path
+}
+
+// Lock blocks until lock is acquired.
+func (l *Lock) Lock() error {
+	for {
+		f, err := l.open()
+		if err != nil {
+			return err
+		}
+		if err := lock(f); err != nil {
+			f.Close()
+			return err
+		}
+		if l.isCurrent(f) {
+			l.f = f
+			return nil
+		}
+		unlock(f)
+		f.Close()
+	}
+}
+
+// TryLock acquires lock without blocking (false is returned if lock is held by someone else).
+func (l *Lock) TryLock() (bool, error) {
+	for {
+		f, err := l.open()
+		if err != nil {
+			return false, err
+		}
+		ok, err := tryLock(f)
+		if err != nil || !ok {
+			f.Close()
+			return false, err
+		}
+		if l.isCurrent(f) {
+			l.f = f
+			return true, nil
+		}
+		unlock(f)
+		f.Close()
+	}
+}
+
+// isCurrent returns false if lock file f (just locked) was removed (see UnlockAndRemove) (or replaced) by the
+// previous holder of the lock while we were waiting for it (locking it would then exclude no one).
+func (l *Lock) isCurrent(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	current, err := os.Stat(l.path)
+	return err == nil && os.SameFile(stat, current)
+}
+
+// LockWithTimeout blocks until lock is acquired or timeout expires (in which case ErrTimeout is returned).
+func (l *Lock) LockWithTimeout(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := l.TryLock()
+		if err != nil || ok {
+			return err
+		}
+		if !time.Now().Before(deadline) {
+			return ErrTimeout
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+func (l *Lock) open() (*os.File, error) {
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0664)
+	if err != nil {
+		return nil, err
+	}
+	// lock file might be shared between users (umask is likely to strip group write permission)
+	f.Chmod(0664)
+	return f, nil
+}
+
+// UnlockAndRemove releases the lock, removing lock file.
+func (l *Lock) UnlockAndRemove() error {
+	if l.f == nil {
+		return nil
+	}
+	if runtime.GOOS == "windows" {
+		// open files can't be removed on Windows (i.e. file can't be removed from under the process that is about
+		// to lock it)
+		err := l.Unlock()
+		os.Remove(l.path)
+		return err
+	}
+	// while still holding the lock (processes waiting for it start over (see isCurrent))
+	os.Remove(l.path)
+	return l.Unlock()
+}
+
+func (l *Lock) Unlock() error {
+	if l.f == nil {
+		return nil
+	}
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
@@ -0,0 +1,73 @@
+package flock
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockWithTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flock_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "lock")
+	// locks are held by open files (not processes), which makes it possible to test contention within a single process
+	held := New(file)
+	if err := held.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	lock := New(file)
+	if ok, err := lock.TryLock(); ok || err != nil {
+		t.Fatalf("actual: %v (%v) != expected: false", ok, err)
+	}
+	if err := lock.LockWithTimeout(200 * time.Millisecond); err != ErrTimeout {
+		t.Fatalf("actual: %v != expected: %v", err, ErrTimeout)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		held.Unlock()
+	}()
+	if err := lock.LockWithTimeout(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnlockAndRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flock_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "lock")
+	held := New(file)
+	if err := held.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	waiting := New(file)
+	acquired := make(chan error)
+	go func() { acquired <- waiting.Lock() }()
+	time.Sleep(200 * time.Millisecond)
+	if err := held.UnlockAndRemove(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-acquired; err != nil {
+		t.Fatal(err)
+	}
+	// lock acquired by the waiting process has to exclude the ones that come after the file was removed
+	if ok, err := New(file).TryLock(); ok || err != nil {
+		t.Fatalf("actual: %v (%v) != expected: false", ok, err)
+	}
+	if err := waiting.UnlockAndRemove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed (%v)", file, err)
+	}
+}
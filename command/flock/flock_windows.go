@@ -0,0 +1,27 @@
+package flock
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/shyiko/jabba/w32"
+)
+
+func lock(f *os.File) error {
+	return w32.LockFileEx(w32.HANDLE(f.Fd()), w32.LOCKFILE_EXCLUSIVE_LOCK)
+}
+
+// ERROR_LOCK_VIOLATION
+const errLockViolation = syscall.Errno(33)
+
+func tryLock(f *os.File) (bool, error) {
+	err := w32.LockFileEx(w32.HANDLE(f.Fd()), w32.LOCKFILE_EXCLUSIVE_LOCK|w32.LOCKFILE_FAIL_IMMEDIATELY)
+	if serr, ok := err.(*os.SyscallError); ok && serr.Err == errLockViolation {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlock(f *os.File) error {
+	return w32.UnlockFileEx(w32.HANDLE(f.Fd()))
+}
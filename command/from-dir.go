@@ -0,0 +1,112 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+// Archives in the artifact directory (`jabba install --from-dir`) are expected to be named
+// <version>_<os>_<arch>.<ext> (e.g. zulu@1.17.0-35_linux_amd64.tar.gz, 1.8.0_windows_x64.zip) and/or be accompanied by
+// <archive>.json (see artifactMeta) (fields of which take precedence over those implied by the name).
+
+// artifactMeta is the content of <archive>.json.
+type artifactMeta struct {
+	Version string `json:"version"`
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	// archive type ("" means the one implied by the extension (see fileTypeOf))
+	Type   string `json:"type"`
+	SHA256 string `json:"sha256"`
+}
+
+// parseArtifactName extracts version, os & arch from <version>_<os>_<arch>.<ext> (ok is false if name doesn't
+// follow the convention).
+func parseArtifactName(name string) (meta artifactMeta, ok bool) {
+	meta.Type = fileTypeOf(name)
+	if meta.Type == "" {
+		return meta, false
+	}
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar.xz", ".tar.zst", ".zip", ".dmg"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	split := strings.Split(name, "_")
+	if len(split) < 3 {
+		return meta, false
+	}
+	n := len(split)
+	meta.Version, meta.OS, meta.Arch = strings.Join(split[:n-2], "_"), split[n-2], split[n-1]
+	switch meta.OS {
+	case "macos", "osx", "mac":
+		meta.OS = "darwin"
+	case "alpine":
+		meta.OS = "linux-musl"
+	}
+	return meta, true
+}
+
+// LsDir lists archives (for goos/arch) in the artifact directory (subdirectories included), the same way LsRemote
+// lists index entries (URLs are file://). Nothing is downloaded.
+func LsDir(dir string, goos, arch string) (map[*semver.Version]Release, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() && !strings.HasSuffix(path, ".json") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	releaseMap := make(map[*semver.Version]Release)
+	seen := make(map[string]string)
+	for _, file := range files {
+		meta, ok := parseArtifactName(filepath.Base(file))
+		if b, err := ioutil.ReadFile(file + ".json"); err == nil {
+			if err := json.Unmarshal(b, &meta); err != nil {
+				return nil, fmt.Errorf("%s.json is not valid (%v)", file, err)
+			}
+			ok = meta.Version != "" && meta.OS != "" && meta.Arch != "" && meta.Type != ""
+			if !ok {
+				return nil, fmt.Errorf("%s.json must specify version, os, arch (and type, unless implied by the "+
+					"extension)", file)
+			}
+		}
+		if !ok {
+			log.Debug("Ignoring ", file, " (neither named <version>_<os>_<arch>.<ext> nor accompanied by .json)")
+			continue
+		}
+		if meta.OS != goos || NormalizeArch(meta.Arch) != arch {
+			continue
+		}
+		v, err := semver.ParseVersion(meta.Version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if other, ok := seen[v.String()]; ok {
+			return nil, fmt.Errorf("%s (%s/%s) is provided by both %s and %s", v, goos, arch, other, file)
+		}
+		seen[v.String()] = file
+		url := meta.Type + "+file://" + filepath.ToSlash(file)
+		if meta.SHA256 != "" {
+			url += "#sha256=" + strings.ToLower(meta.SHA256)
+		}
+		releaseMap[v] = Release{URL: url}
+	}
+	return releaseMap, nil
+}
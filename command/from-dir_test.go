@@ -0,0 +1,103 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestParseArtifactName(t *testing.T) {
+	for name, expected := range map[string]artifactMeta{
+		"zulu@1.17.0-35_linux_amd64.tar.gz": {Version: "zulu@1.17.0-35", OS: "linux", Arch: "amd64", Type: "tgz"},
+		"1.8.0_macos_aarch64.zip":           {Version: "1.8.0", OS: "darwin", Arch: "aarch64", Type: "zip"},
+		"temurin@1.21.0_alpine_x64.tgz":     {Version: "temurin@1.21.0", OS: "linux-musl", Arch: "x64", Type: "tgz"},
+	} {
+		actual, ok := parseArtifactName(name)
+		if !ok || actual != expected {
+			t.Fatalf("actual: %+v (%v) != expected: %+v", actual, ok, expected)
+		}
+	}
+	for _, name := range []string{"jdk.tar.gz", "zulu@1.17.0_linux_amd64.rpm", "README"} {
+		if _, ok := parseArtifactName(name); ok {
+			t.Fatalf("%s is not expected to follow the convention", name)
+		}
+	}
+}
+
+func TestInstallFromDir(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	// index must not be touched
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(filepath.Join(home, "missing.json"))})
+	defer cfg.SetRegistry(nil)
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	dir := filepath.Join(home, "artifacts")
+	ok(os.MkdirAll(filepath.Join(dir, "zulu"), 0755))
+	writeArchive := func(file string) {
+		f, err := os.Create(file)
+		ok(err)
+		gw := gzip.NewWriter(f)
+		tw := tar.NewWriter(gw)
+		for _, name := range []string{java, "jdk/release"} {
+			ok(tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0755, Size: 1}))
+			_, err = tw.Write([]byte("x"))
+			ok(err)
+		}
+		ok(tw.Close())
+		ok(gw.Close())
+		ok(f.Close())
+	}
+	platform := "_" + runtime.GOOS + "_" + HostArch()
+	writeArchive(filepath.Join(dir, "zulu@1.17.0"+platform+".tar.gz"))
+	writeArchive(filepath.Join(dir, "zulu@1.17.2_other_amd64.tar.gz"))
+	sidecar := filepath.Join(dir, "zulu", "jdk-17.0.1.tgz")
+	writeArchive(sidecar)
+	sum, err := sha256OfFile(sidecar)
+	ok(err)
+	ok(ioutil.WriteFile(sidecar+".json", []byte(`{"version": "zulu@1.17.1", "os": "`+runtime.GOOS+`", "arch": "`+
+		HostArch()+`", "sha256": "`+sum+`"}`), 0644))
+	opts := InstallOptions{FromDir: dir}
+	r, err := ResolveRemote("zulu@1.17", opts)
+	ok(err)
+	if r.Version != "zulu@1.17.1" || r.SHA256 != sum || r.URL != "file://"+filepath.ToSlash(sidecar) {
+		t.Fatalf("unexpected resolution: %+v", r)
+	}
+	result, err := Install("zulu@1.17", opts)
+	ok(err)
+	if result.Version != "zulu@1.17.1" || !result.ChecksumVerified {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if _, err := Install("zulu@1.21", opts); err == nil {
+		t.Fatal("expected Install to fail (no such archive)")
+	}
+	// archive doesn't match its metadata
+	ok(ioutil.WriteFile(sidecar+".json", []byte(`{"version": "zulu@1.17.1", "os": "`+runtime.GOOS+`", "arch": "`+
+		HostArch()+`", "sha256": "`+strings.Repeat("0", 64)+`"}`), 0644))
+	ok(os.RemoveAll(filepath.Join(home, "jdk", "zulu@1.17.1")))
+	if _, err := Install("zulu@1.17.1", opts); err == nil {
+		t.Fatal("expected Install to fail (checksum mismatch)")
+	}
+	// two archives claiming the same version
+	writeArchive(filepath.Join(dir, "zulu@1.17.1"+platform+".tar.gz"))
+	if _, err := ResolveRemote("zulu@1.17", opts); err == nil || !strings.Contains(err.Error(), "provided by both") {
+		t.Fatalf("expected ResolveRemote to fail (ambiguous version) (%v)", err)
+	}
+}
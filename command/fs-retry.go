@@ -0,0 +1,82 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// on NFS/SMB rename, chmod & co. occasionally fail with errors that go away on their own
+// (stale file handles, files held open by the server (.nfsXXXX), etc.)
+var fsAttempts = 5
+var fsBackoff = 100 * time.Millisecond
+
+// NetworkFSError is returned when file system operation kept failing with an error typical of network file
+// systems (e.g. ESTALE) after all retries.
+type NetworkFSError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *NetworkFSError) Error() string {
+	return fmt.Sprintf("%v (%s of %s failed %d times in a row, which usually means that it's on a network "+
+		"file system (NFS/SMB) that is misbehaving; retry later or point JABBA_HOME to a local disk)",
+		e.Err, e.Op, e.Path, fsAttempts)
+}
+
+func (e *NetworkFSError) Unwrap() error {
+	return e.Err
+}
+
+func isTransientFSError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.ESTALE, syscall.EBUSY, syscall.ETXTBSY, syscall.EAGAIN} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	if runtime.GOOS == "windows" {
+		// ERROR_SHARING_VIOLATION, ERROR_LOCK_VIOLATION
+		// (reported by SMB shares (and antivirus software) holding on to just created files)
+		return errors.Is(err, syscall.Errno(32)) || errors.Is(err, syscall.Errno(33))
+	}
+	return false
+}
+
+// retryFS calls fn (op on path) until it succeeds, fails with non-transient error or fsAttempts is exhausted.
+func retryFS(op string, path string, fn func() error) error {
+	backoff := fsBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isTransientFSError(err) {
+			return err
+		}
+		if attempt >= fsAttempts {
+			return &NetworkFSError{Op: op, Path: path, Err: err}
+		}
+		log.Debug(op, " of ", path, " failed (", err, "). Retrying in ", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func rename(src string, dst string) error {
+	return retryFS("rename", src, func() error { return os.Rename(src, dst) })
+}
+
+func chmod(path string, mode os.FileMode) error {
+	return retryFS("chmod", path, func() error { return os.Chmod(path, mode) })
+}
+
+func mkdirAll(path string, perm os.FileMode) error {
+	return retryFS("mkdir", path, func() error { return os.MkdirAll(path, perm) })
+}
+
+func removeAll(path string) error {
+	return retryFS("removal", path, func() error { return os.RemoveAll(path) })
+}
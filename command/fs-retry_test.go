@@ -0,0 +1,43 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryFS(t *testing.T) {
+	prevBackoff := fsBackoff
+	defer func() { fsBackoff = prevBackoff }()
+	fsBackoff = time.Millisecond
+	calls := 0
+	err := retryFS("rename", "/mnt/nfs/jdk", func() error {
+		if calls++; calls < 3 {
+			return &os.LinkError{Op: "rename", Old: "/mnt/nfs/jdk", New: "/mnt/nfs/jdk~", Err: syscall.EBUSY}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("actual: %v (%d calls)", err, calls)
+	}
+	calls = 0
+	err = retryFS("chmod", "/mnt/nfs/jdk", func() error {
+		calls++
+		return &os.PathError{Op: "chmod", Path: "/mnt/nfs/jdk", Err: syscall.ESTALE}
+	})
+	if _, ok := err.(*NetworkFSError); !ok || calls != fsAttempts || !errors.Is(err, syscall.ESTALE) ||
+		!strings.Contains(err.Error(), "network file system") {
+		t.Fatalf("actual: %v (%d calls)", err, calls)
+	}
+	calls = 0
+	err = retryFS("chmod", "/mnt/nfs/jdk", func() error {
+		calls++
+		return &os.PathError{Op: "chmod", Path: "/mnt/nfs/jdk", Err: syscall.EPERM}
+	})
+	if _, ok := err.(*NetworkFSError); ok || calls != 1 {
+		t.Fatalf("actual: %v (%d calls)", err, calls)
+	}
+}
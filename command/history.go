@@ -0,0 +1,163 @@
+package command
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/command/flock"
+)
+
+// HistoryEvent is an entry of $JABBA_HOME/history.log (one JSON object per line).
+type HistoryEvent struct {
+	Time time.Time `json:"time"`
+	// "install", "use" or "exec"
+	Event    string `json:"event"`
+	Selector string `json:"selector"`
+	Version  string `json:"version"`
+	User     string `json:"user"`
+}
+
+func historyFile() string {
+	return filepath.Join(cfg.StateDir(), "history.log")
+}
+
+// recordHistory appends event to the history (if enabled (see cfg.History)).
+// Failure to do so is logged but otherwise ignored (it shouldn't prevent JDK from being used).
+func recordHistory(event string, selector string, ver string) {
+	if !cfg.History() {
+		return
+	}
+	e := HistoryEvent{Time: time.Now().UTC(), Event: event, Selector: selector, Version: ver, User: currentUser()}
+	if err := appendHistory(historyFile(), e, cfg.HistoryRetention()); err != nil {
+		log.Warn("Failed to record ", event, " of ", ver, " in ", historyFile(), " (", err, ")")
+	}
+}
+
+func appendHistory(file string, e HistoryEvent, retention time.Duration) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// history.log is written to by `jabba use`, which doesn't lock jabba home
+	lock := flock.New(file + ".lock")
+	if err := lock.LockWithTimeout(cfg.LockTimeout()); err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	if retention > 0 {
+		if err := pruneHistory(file, e.Time.Add(-retention)); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// pruneHistory removes entries recorded before the cutoff (file is rewritten only if the oldest entry is older).
+func pruneHistory(file string, cutoff time.Time) error {
+	events, err := readHistory(file)
+	if err != nil || len(events) == 0 || !events[0].Time.Before(cutoff) {
+		return err
+	}
+	var b []byte
+	for _, e := range events {
+		if e.Time.Before(cutoff) {
+			continue
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		b = append(append(b, line...), '\n')
+	}
+	if err := ioutil.WriteFile(file+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
+
+func readHistory(file string) ([]HistoryEvent, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var events []HistoryEvent
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e HistoryEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", file, n, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// History returns events recorded since the specified time (oldest first).
+func History(since time.Time) ([]HistoryEvent, error) {
+	events, err := readHistory(historyFile())
+	if err != nil {
+		return nil, err
+	}
+	var r []HistoryEvent
+	for _, e := range events {
+		if !e.Time.Before(since) {
+			r = append(r, e)
+		}
+	}
+	return r, nil
+}
+
+// ParseSince parses either a date (2006-01-02 or RFC 3339 timestamp) or age (e.g. "24h", "7d").
+func ParseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil {
+			return time.Now().Add(-time.Duration(days) * 24 * time.Hour), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("\"%s\" is neither a date (e.g. 2006-01-02) nor age (e.g. 24h or 7d)", value)
+	}
+	return time.Now().Add(-d), nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
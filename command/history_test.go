@@ -0,0 +1,88 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	recordHistory("use", "zulu@1.17", "zulu@1.17.0")
+	if _, err := os.Stat(historyFile()); !os.IsNotExist(err) {
+		t.Fatalf("history is not supposed to be recorded unless enabled (%v)", err)
+	}
+	os.Setenv("JABBA_HISTORY", "1")
+	defer os.Unsetenv("JABBA_HISTORY")
+	os.MkdirAll(filepath.Join(home, "jdk", "zulu@1.17.0", "bin"), 0755)
+	if _, err := Use("zulu@1.17"); err != nil {
+		t.Fatal(err)
+	}
+	events, err := History(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Event != "use" || events[0].Selector != "zulu@1.17" ||
+		events[0].Version != "zulu@1.17.0" || events[0].User == "" {
+		t.Fatalf("unexpected history: %+v", events)
+	}
+	if events, _ := History(time.Now().Add(time.Hour)); len(events) != 0 {
+		t.Fatalf("unexpected history: %+v", events)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "history.log")
+	now := time.Now().UTC().Truncate(time.Second)
+	old := HistoryEvent{Time: now.Add(-48 * time.Hour), Event: "install", Selector: "1.8", Version: "1.8.0", User: "ci"}
+	recent := HistoryEvent{Time: now.Add(-time.Hour), Event: "use", Selector: "1.8", Version: "1.8.0", User: "ci"}
+	latest := HistoryEvent{Time: now, Event: "exec", Selector: "1.8", Version: "1.8.0", User: "ci"}
+	for _, e := range []HistoryEvent{old, recent} {
+		if err := appendHistory(file, e, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := appendHistory(file, latest, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	events, err := readHistory(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []HistoryEvent{recent, latest}; !reflect.DeepEqual(events, expected) {
+		t.Fatalf("actual: %v != expected: %v", events, expected)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	for value, expected := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "36h": 36 * time.Hour} {
+		since, err := ParseSince(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if age := time.Since(since); age < expected || age > expected+time.Minute {
+			t.Fatalf("%s: actual: %v != expected: %v", value, age, expected)
+		}
+	}
+	since, err := ParseSince("2024-01-31")
+	if err != nil || since.Format("2006-01-02 15:04") != "2024-01-31 00:00" {
+		t.Fatalf("actual: %v (%v)", since, err)
+	}
+	if _, err := ParseSince("last week"); err == nil {
+		t.Fatal("expected \"last week\" to be rejected")
+	}
+}
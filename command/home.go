@@ -0,0 +1,68 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/command/flock"
+)
+
+// ensureWritableDir creates dir (if needed) and checks that it can be written to
+// (returning an error explaining what to do if it can't).
+func ensureWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return notWritableError(dir, err)
+	}
+	f, err := ioutil.TempFile(dir, ".jabba-w-")
+	if err != nil {
+		return notWritableError(dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func notWritableError(dir string, err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		err = pathErr.Err
+	}
+	if cfg.StateDir() != cfg.Dir() {
+		return fmt.Errorf("%s is not writable (%v).\n"+
+			"jabba home is read-only (shared through JABBA_OVERLAY), JDKs have to be installed / removed on the host",
+			dir, err)
+	}
+	return fmt.Errorf("%s is not writable (%v).\n"+
+		"Set JABBA_HOME to a writable directory (e.g. \"export JABBA_HOME=/opt/jabba\")", dir, err)
+}
+
+// LockHome acquires exclusive lock of jabba home, so that concurrent jabba processes (e.g. parallel CI jobs)
+// wouldn't step on each other's toes while installing / uninstalling JDKs, updating aliases, etc.
+// If lock is held by another process, LockHome waits for it to be released for up to cfg.LockTimeout().
+// Lock file is kept in cfg.StateDir() (jabba home that's shared read-only can't be changed anyway).
+func LockHome() (*flock.Lock, error) {
+	dir := cfg.StateDir()
+	if err := ensureWritableDir(dir); err != nil {
+		return nil, err
+	}
+	lock := flock.New(filepath.Join(dir, ".lock"))
+	ok, err := lock.TryLock()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		timeout := cfg.LockTimeout()
+		log.Info("Waiting for another jabba process to finish (", lock.Path(), " is locked)")
+		if err := lock.LockWithTimeout(timeout); err != nil {
+			if err == flock.ErrTimeout {
+				err = fmt.Errorf("Another jabba process is running (%s is still locked after %s).\n"+
+					"Try again later or increase the timeout (JABBA_LOCK_TIMEOUT or \"lock_timeout\" in config.yaml)",
+					lock.Path(), timeout)
+			}
+			return nil, err
+		}
+	}
+	return lock, nil
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLockHome(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	os.Setenv("JABBA_LOCK_TIMEOUT", "100ms")
+	defer os.Unsetenv("JABBA_LOCK_TIMEOUT")
+	lock, err := LockHome()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// e.g. `jabba install` running in another terminal
+	if _, err := LockHome(); err == nil || !strings.Contains(err.Error(), "Another jabba process is running") {
+		t.Fatalf("expected \"Another jabba process is running\" error, got %v", err)
+	}
+	lock.Unlock()
+	lock, err = LockHome()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+}
+
+func TestReadOnlyHomeOverlay(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("read-only directories are writable by root (and can't be made read-only with chmod on Windows)")
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	overlay, err := ioutil.TempDir("", "jabba-overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(overlay)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	for name, ver := range map[string]string{"default": "zulu@1.17.0", "legacy": "zulu@1.8.0"} {
+		if err := SetAlias(name, ver); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// home bind-mounted into a container with :ro
+	os.Chmod(home, 0555)
+	defer os.Chmod(home, 0755)
+	os.Setenv("JABBA_OVERLAY", overlay)
+	defer os.Unsetenv("JABBA_OVERLAY")
+	if err := SetAlias("default", "zulu@1.21.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAlias("legacy", ""); err != nil {
+		t.Fatal(err)
+	}
+	if actual := GetAlias("default"); actual != "zulu@1.21.0" {
+		t.Fatalf("actual: %v != expected: zulu@1.21.0", actual)
+	}
+	if aliases, err := Aliases(); err != nil || !reflect.DeepEqual(aliases, []string{"default"}) {
+		t.Fatalf("actual: %v (%v) != expected: [default]", aliases, err)
+	}
+	lock, err := LockHome()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Unlock()
+	if _, err := os.Stat(filepath.Join(overlay, ".lock")); err != nil {
+		t.Fatal(err)
+	}
+}
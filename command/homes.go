@@ -0,0 +1,126 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mitchellh/go-homedir"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// Named homes (see cfg.Homes) (e.g. "work" & "personal") keep JDKs, aliases (the "default" one included) & config
+// apart. `jabba home use <name>` moves the shell from one to another: JDK of the home being left is deactivated,
+// JABBA_HOME is updated and "default" alias of the new home (if there is one) is activated.
+
+type Home struct {
+	// "" if home is not named (e.g. $JABBA_HOME set by hand)
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Active bool   `json:"active,omitempty"`
+}
+
+// Homes returns named homes (sorted by name), followed by the active one if it's not named.
+func Homes() []Home {
+	var r []Home
+	active := false
+	for name, path := range cfg.Homes() {
+		home := Home{Name: name, Path: path, Active: path == cfg.Dir()}
+		active = active || home.Active
+		r = append(r, home)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	if !active {
+		r = append(r, Home{Path: cfg.Dir(), Active: true})
+	}
+	return r
+}
+
+// ResolveHome returns path of the named home (value itself (made absolute) if it's a path (e.g. ~/work/.jabba)).
+func ResolveHome(value string) (string, error) {
+	homes := cfg.Homes()
+	if path, ok := homes[value]; ok {
+		return path, nil
+	}
+	if !strings.ContainsAny(value, `/\`) && value != "~" && value != "." && value != ".." {
+		var names []string
+		for name := range homes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return "", fmt.Errorf("\"%s\" home is not defined (there are no \"homes\" in config.yaml)", value)
+		}
+		return "", fmt.Errorf("\"%s\" home is not defined (must be one of %s or a path)", value,
+			strings.Join(names, ", "))
+	}
+	path, err := homedir.Expand(value)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(path)
+}
+
+// UseHome returns change of the environment that moves the shell to the specified home (name or path).
+func UseHome(value string) (*EnvChange, error) {
+	path, err := ResolveHome(value)
+	if err != nil {
+		return nil, err
+	}
+	if fi, err := os.Stat(path); err != nil || !fi.IsDir() {
+		log.Info(path, " doesn't exist yet (it will be created by the first `jabba install`)")
+	}
+	deactivation, err := Deactivate()
+	if err != nil {
+		return nil, err
+	}
+	// changes are applied to the environment of jabba itself, so that "default" of the new home would be activated
+	// on top of deactivation (and net change could be computed)
+	var keys []string
+	apply := func(change *EnvChange) {
+		for _, kv := range change.Set {
+			key, value := splitEnv(kv)
+			os.Setenv(key, value)
+			keys = append(keys, key)
+		}
+		for _, key := range change.Unset {
+			os.Unsetenv(key)
+			keys = append(keys, key)
+		}
+	}
+	apply(deactivation)
+	apply(&EnvChange{Set: []string{"JABBA_HOME=" + path}})
+	cfg.Use(nil)
+	// shell integration runs $JABBA_HOME/bin/jabba unless JABBA_BIN is set (see binExpr), new home might not have one
+	if bin, err := os.Executable(); err == nil {
+		if !isRegularFile(filepath.Join(path, "bin", filepath.Base(bin))) {
+			apply(&EnvChange{Set: []string{"JABBA_BIN=" + bin}})
+		} else if _, ok := os.LookupEnv("JABBA_BIN"); ok {
+			apply(&EnvChange{Unset: []string{"JABBA_BIN"}})
+		}
+	}
+	if GetAlias("default") != "" {
+		if change, err := Use("default"); err != nil {
+			log.Warn("Failed to activate \"default\" alias of ", path, " (", err, ")")
+		} else {
+			apply(change)
+		}
+	}
+	change := &EnvChange{}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if value, ok := os.LookupEnv(key); ok {
+			change.Set = append(change.Set, key+"="+value)
+		} else {
+			change.Unset = append(change.Unset, key)
+		}
+	}
+	return change, nil
+}
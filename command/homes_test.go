@@ -0,0 +1,92 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestUseHome(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	environ, prevDisableCache := os.Environ(), homedir.DisableCache
+	defer func() {
+		os.Clearenv()
+		for _, kv := range environ {
+			key, value := splitEnv(kv)
+			os.Setenv(key, value)
+		}
+		// homedir caches whatever it resolves (even with DisableCache on)
+		homedir.Dir()
+		homedir.DisableCache = prevDisableCache
+		cfg.Use(nil)
+	}()
+	os.Setenv("HOME", dir)
+	homedir.DisableCache = true
+	personal, work := filepath.Join(dir, ".jabba"), filepath.Join(dir, "work")
+	installFakeJDKs(t, personal, "zulu@1.8.0")
+	installFakeJDKs(t, work, "zulu@1.17.0")
+	if err := ioutil.WriteFile(filepath.Join(personal, "config.yaml"),
+		[]byte("homes:\n  personal: ~/.jabba\n  work: ~/work\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("JABBA_HOME", work)
+	cfg.Use(nil)
+	if err := SetAlias("default", "zulu@1.17"); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("JABBA_HOME", personal)
+	cfg.Use(nil)
+	if path, err := ResolveHome("work"); err != nil || path != work {
+		t.Fatalf("actual: %v (%v) != expected: %v", path, err, work)
+	}
+	if _, err := ResolveHome("play"); err == nil {
+		t.Fatal("expected undefined home to be rejected")
+	}
+	os.Setenv("PATH", "/usr/bin"+string(os.PathListSeparator)+filepath.Join(personal, "jdk", "zulu@1.8.0", "bin"))
+	os.Setenv("JAVA_HOME", filepath.Join(personal, "jdk", "zulu@1.8.0"))
+	os.Setenv("JAVA_HOME_BEFORE_JABBA", "/system-jdk")
+	change, err := UseHome("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := make(map[string]string)
+	for _, kv := range change.Set {
+		key, value := splitEnv(kv)
+		set[key] = value
+	}
+	bin, _ := os.Executable()
+	javaHome := filepath.Join(work, "jdk", "zulu@1.17.0")
+	for key, expected := range map[string]string{
+		"JABBA_HOME": work,
+		// there is no jabba in work/bin
+		"JABBA_BIN":              bin,
+		"JAVA_HOME":              javaHome,
+		"JAVA_HOME_BEFORE_JABBA": "/system-jdk",
+		// JDK of the personal home is gone
+		"PATH": filepath.Join(javaHome, "bin") + string(os.PathListSeparator) + "/usr/bin",
+	} {
+		if set[key] != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", key, set[key], expected)
+		}
+	}
+	if actual := cfg.Dir(); actual != work {
+		t.Fatalf("actual: %v != expected: %v", actual, work)
+	}
+	var names []string
+	for _, home := range Homes() {
+		if home.Active {
+			names = append(names, home.Name)
+		}
+	}
+	if len(names) != 1 || names[0] != "work" {
+		t.Fatalf("actual: %v != expected: [work]", names)
+	}
+}
@@ -0,0 +1,78 @@
+package command
+
+import (
+	"fmt"
+)
+
+// bash & zsh (same function, different ways to trigger it)
+const posixHook = `__jabba_hook() {
+    local ver
+    # (JDK is left as is if project version file can't be read (e.g. malformed .jabbarc))
+    ver="$(%[1]s hook --resolve 2>/dev/null)" || return
+    [ "$ver" = "${__JABBA_HOOK_VERSION-}" ] && return
+    if [ -n "$ver" ]; then
+        # remember JDK that was in use before entering the project (to restore it later)
+        [ -z "${__JABBA_HOOK_VERSION-}" ] && __JABBA_HOOK_PREVIOUS="$(%[1]s current)"
+        __JABBA_HOOK_VERSION="$ver"
+        jabba use "$ver"
+    else
+        if [ -n "${__JABBA_HOOK_PREVIOUS-}" ]; then
+            jabba use "$__JABBA_HOOK_PREVIOUS"
+        else
+            jabba deactivate
+        fi
+        unset __JABBA_HOOK_VERSION __JABBA_HOOK_PREVIOUS
+    fi
+}
+`
+
+var hooks = map[string]string{
+	"bash": posixHook + `
+__jabba_hook_prompt() {
+    [ "$PWD" = "${__JABBA_HOOK_PWD-}" ] && return
+    __JABBA_HOOK_PWD="$PWD"
+    __jabba_hook
+}
+case ";${PROMPT_COMMAND-};" in
+    *";__jabba_hook_prompt;"*) ;;
+    *) PROMPT_COMMAND="__jabba_hook_prompt${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`,
+	"zsh": posixHook + `
+autoload -U add-zsh-hook
+add-zsh-hook chpwd __jabba_hook
+__jabba_hook
+`,
+	"fish": `function __jabba_hook --on-variable PWD
+    # (JDK is left as is if project version file can't be read (e.g. malformed .jabbarc))
+    set -l ver (%[1]s hook --resolve 2>/dev/null); or return
+    test "$ver" = "$__JABBA_HOOK_VERSION"; and return
+    if test -n "$ver"
+        # remember JDK that was in use before entering the project (to restore it later)
+        test -z "$__JABBA_HOOK_VERSION"; and set -g __JABBA_HOOK_PREVIOUS (%[1]s current)
+        set -g __JABBA_HOOK_VERSION $ver
+        jabba use $ver
+    else
+        if test -n "$__JABBA_HOOK_PREVIOUS"
+            jabba use $__JABBA_HOOK_PREVIOUS
+        else
+            jabba deactivate
+        end
+        set -e __JABBA_HOOK_VERSION __JABBA_HOOK_PREVIOUS
+    end
+end
+__jabba_hook
+`,
+}
+
+// Hook returns a script that (once eval'ed) runs `jabba use` whenever current directory (or any of its parents)
+// contains .jabbarc, .java-version or .tool-versions (see ProjectVersion) and restores JDK that was in use before
+// upon leaving it.
+// bin is the path to jabba executable (`jabba` itself is expected to be a shell function (see ShellIntegration)).
+func Hook(shell string, bin string) (string, error) {
+	hook, ok := hooks[shell]
+	if !ok {
+		return "", fmt.Errorf("Unsupported shell \"%s\" (must be one of bash, zsh, fish)", shell)
+	}
+	return fmt.Sprintf(hook, quoteShell(shell, bin)), nil
+}
@@ -0,0 +1,60 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHook(t *testing.T) {
+	for shell, expected := range map[string]string{
+		"bash": `'/opt/it'\''s/jabba' hook --resolve`,
+		"zsh":  `'/opt/it'\''s/jabba' hook --resolve`,
+		"fish": `'/opt/it\'s/jabba' hook --resolve`,
+	} {
+		hook, err := Hook(shell, "/opt/it's/jabba")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(hook, expected) {
+			t.Fatalf("%s hook does not contain %s:\n%s", shell, expected, hook)
+		}
+	}
+	if _, err := Hook("tcsh", "jabba"); err == nil {
+		t.Fatal("expected tcsh to be rejected")
+	}
+}
+
+func TestHookLeavesJDKAloneIfVersionCannotBeResolved(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil || runtime.GOOS == "windows" {
+		t.Skip("bash is not available")
+	}
+	dir, err := ioutil.TempDir("", "jabba-hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// `jabba hook --resolve` failing (e.g. malformed .jabbarc)
+	bin := filepath.Join(dir, "jabba")
+	if err := ioutil.WriteFile(bin, []byte("#!/bin/sh\necho 'malformed .jabbarc' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	hook, err := Hook("bash", bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := "jabba() { echo \"jabba $*\"; }\n" + hook +
+		"__JABBA_HOOK_VERSION=1.17 __JABBA_HOOK_PREVIOUS=1.8\n__jabba_hook\necho \"$__JABBA_HOOK_VERSION\"\n"
+	out, err := exec.Command(bash, "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if actual := strings.TrimSpace(string(out)); actual != "1.17" {
+		t.Fatalf("actual: %q != expected: %q", actual, "1.17")
+	}
+}
@@ -0,0 +1,208 @@
+package command
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+var transportOnce sync.Once
+var transport http.RoundTripper
+var transportErr error
+
+// httpTransport returns transport used for both index fetches & downloads
+// (http.DefaultTransport + "proxy" from config.yaml + --cacert + --insecure + timeouts + Maven mirror credentials +
+// "auth" / .netrc credentials + fetchers of non-HTTP URLs (e.g. s3://)).
+func httpTransport() (http.RoundTripper, error) {
+	transportOnce.Do(func() {
+		var t *http.Transport
+		if t, transportErr = newTransport(); transportErr == nil {
+			transport = readTimeoutTransport{t, cfg.ReadTimeout()}
+			if settings := loadMavenSettings(); settings != nil {
+				transport = mavenAuthTransport{transport, settings}
+			}
+			if auth, netrc := cfg.Auth(), loadNetrc(); len(auth) != 0 || len(netrc) != 0 {
+				transport = authTransport{transport, auth, netrc}
+			}
+		}
+	})
+	return transport, transportErr
+}
+
+// newHTTPClient returns client whose requests (response body included) are bound by cfg.Timeout().
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: RedirectTracer{}, Timeout: cfg.Timeout()}
+}
+
+func newTransport() (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	proxy, noProxy := cfg.Proxy(), ""
+	if settings := loadMavenSettings(); proxy == "" && settings != nil {
+		proxy, noProxy = settings.proxy()
+	}
+	t.Proxy = proxyFunc(proxy, noProxy)
+	dialer := &net.Dialer{Timeout: cfg.ConnectTimeout(), KeepAlive: 30 * time.Second}
+	t.DialContext = dialer.DialContext
+	t.TLSHandshakeTimeout = cfg.ConnectTimeout()
+	t.ResponseHeaderTimeout = cfg.ReadTimeout()
+	tlsConfig := &tls.Config{}
+	if t.TLSClientConfig != nil {
+		tlsConfig = t.TLSClientConfig.Clone()
+	}
+	if files := cfg.CACerts(); len(files) != 0 {
+		// $JABBA_CAFILE/$JABBA_CAPATH replace system roots, --cacert adds to whatever is there
+		pool := tlsConfig.RootCAs
+		if pool == nil {
+			var err error
+			if pool, err = x509.SystemCertPool(); err != nil {
+				log.Debug("Failed to load system root certificates (", err, ")")
+				pool = x509.NewCertPool()
+			}
+		}
+		for _, file := range files {
+			pem, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("%s does not contain any PEM-encoded certificates", file)
+			}
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.Insecure() {
+		log.Warn("TLS certificate verification is disabled (--insecure). " +
+			"Consider using --cacert instead")
+		tlsConfig.InsecureSkipVerify = true
+	}
+	t.TLSClientConfig = tlsConfig
+	registerFetchers(t)
+	return t, nil
+}
+
+// readTimeoutTransport aborts requests that haven't received any data (response body included) for longer than
+// timeout (0 means no timeout).
+type readTimeoutTransport struct {
+	transport http.RoundTripper
+	timeout   time.Duration
+}
+
+func (t readTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.timeout == 0 {
+		return t.transport.RoundTrip(req)
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := t.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	body := &readTimeoutBody{ReadCloser: resp.Body, timeout: t.timeout, cancel: cancel}
+	body.timer = time.AfterFunc(t.timeout, body.expire)
+	resp.Body = body
+	return resp, nil
+}
+
+type readTimeoutBody struct {
+	io.ReadCloser
+	timeout time.Duration
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	mutex   sync.Mutex
+	expired bool
+}
+
+func (b *readTimeoutBody) expire() {
+	b.mutex.Lock()
+	b.expired = true
+	b.mutex.Unlock()
+	b.cancel()
+}
+
+func (b *readTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mutex.Lock()
+	expired := b.expired
+	b.mutex.Unlock()
+	if expired {
+		return n, fmt.Errorf("no data received for %v (read timeout)", b.timeout)
+	}
+	b.timer.Reset(b.timeout)
+	return n, err
+}
+
+func (b *readTimeoutBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// proxyFunc returns http.ProxyFromEnvironment if HTTP(S)_PROXY is set and fallback otherwise
+// (unless request host is listed in NO_PROXY or noProxyHosts (same format)).
+func proxyFunc(fallback string, noProxyHosts string) func(*http.Request) (*url.URL, error) {
+	if fallback == "" || getenv("HTTPS_PROXY") != "" || getenv("HTTP_PROXY") != "" {
+		return http.ProxyFromEnvironment
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxy(req.URL.Hostname(), getenv("NO_PROXY")) || noProxy(req.URL.Hostname(), noProxyHosts) {
+			return nil, nil
+		}
+		u, err := url.Parse(fallback)
+		if err != nil || u.Host == "" {
+			// "proxy.example.com:3128"
+			if u, err = url.Parse("http://" + fallback); err != nil {
+				return nil, fmt.Errorf("invalid proxy address %q (%v)", fallback, err)
+			}
+		}
+		return u, nil
+	}
+}
+
+func getenv(key string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToLower(key))
+}
+
+// noProxy checks host against comma-separated list of hosts/domains/IPs (NO_PROXY format).
+func noProxy(host string, list string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip := net.ParseIP(host); ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(entry, "*")
+		if host == strings.TrimPrefix(entry, ".") || (strings.HasPrefix(entry, ".") && strings.HasSuffix(host, entry)) ||
+			strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package command
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestTransportTrustsCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "jabba-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("JABBA_HOME", dir)
+	defer os.Unsetenv("JABBA_HOME")
+	get := func() error {
+		transport, err := newTransport()
+		if err != nil {
+			return err
+		}
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+	if err := get(); err == nil {
+		t.Fatalf("self-signed certificate was expected to be rejected")
+	}
+	cacert := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(cacert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644)
+	cfg.SetCACerts([]string{cacert})
+	defer cfg.SetCACerts(nil)
+	if err := get(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ioutil.WriteFile(cacert, []byte("not a certificate"), 0644)
+	if err := get(); err == nil {
+		t.Fatalf("invalid --cacert was expected to be reported")
+	}
+	cfg.SetCACerts(nil)
+	cfg.SetInsecure(true)
+	defer cfg.SetInsecure(false)
+	if err := get(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	for _, key := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"} {
+		if value, ok := os.LookupEnv(key); ok {
+			defer os.Setenv(key, value)
+			os.Unsetenv(key)
+		}
+	}
+	os.Setenv("NO_PROXY", "localhost,.internal.example.com,10.0.0.0/8")
+	defer os.Unsetenv("NO_PROXY")
+	proxy := proxyFunc("proxy.example.com:3128", "")
+	for url, expected := range map[string]string{
+		"https://github.com/shyiko/jabba/raw/master/index.json": "http://proxy.example.com:3128",
+		"http://localhost:8080/index.json":                      "",
+		"https://artifactory.internal.example.com/jdk.tar.gz":   "",
+		"http://10.1.2.3/jdk.tar.gz":                            "",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		u, err := proxy(req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		actual := ""
+		if u != nil {
+			actual = u.String()
+		}
+		if actual != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", url, actual, expected)
+		}
+	}
+}
+
+func TestReadTimeout(t *testing.T) {
+	stalled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-stalled
+	}))
+	defer server.Close()
+	defer close(stalled)
+	transport, err := newTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: readTimeoutTransport{transport, 100 * time.Millisecond}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	start := time.Now()
+	_, err = ioutil.ReadAll(resp.Body)
+	if err == nil || !strings.Contains(err.Error(), "read timeout") {
+		t.Fatalf("expected read timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("read timeout took %v", elapsed)
+	}
+}
@@ -0,0 +1,407 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// IDETargets lists IDEs `jabba ide sync` can register JDKs with.
+var IDETargets = []string{"idea", "vscode"}
+
+// IdeaJdkTableFiles returns options/jdk.table.xml of every IntelliJ IDEA (Ultimate & Community) configuration
+// directory (e.g. ~/.config/JetBrains/IntelliJIdea2023.3 on Linux).
+func IdeaJdkTableFiles() []string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	var r []string
+	for _, pattern := range []string{"IntelliJIdea*", "IdeaIC*"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, "JetBrains", pattern))
+		for _, match := range matches {
+			if stat, err := os.Stat(match); err == nil && stat.IsDir() {
+				r = append(r, filepath.Join(match, "options", "jdk.table.xml"))
+			}
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+// VSCodeSettingsFile returns user settings.json of VS Code (e.g. ~/.config/Code/User/settings.json on Linux).
+func VSCodeSettingsFile() string {
+	dir, _ := os.UserConfigDir()
+	return filepath.Join(dir, "Code", "User", "settings.json")
+}
+
+// isManagedHome returns true if home is a JDK in cfg.JDKDir() (IntelliJ's $USER_HOME$ macro is expanded).
+func isManagedHome(home string) bool {
+	if strings.HasPrefix(home, "$USER_HOME$") {
+		userHome, _ := os.UserHomeDir()
+		home = userHome + strings.TrimPrefix(home, "$USER_HOME$")
+	}
+	return strings.HasPrefix(filepath.Clean(home)+string(os.PathSeparator), cfg.JDKDir()+string(os.PathSeparator))
+}
+
+var (
+	ideaJdkRegexp      = regexp.MustCompile(`(?s)[ \t]*<jdk\b[^>]*>.*?</jdk>[ \t]*\r?\n?`)
+	ideaHomePathRegexp = regexp.MustCompile(`<homePath value="([^"]*)"`)
+	ideaJdkTableRegexp = regexp.MustCompile(`<component name="ProjectJdkTable"\s*(/>|>)`)
+)
+
+// IdeaJdkTable returns content of IntelliJ's jdk.table.xml (file) with JDKs from jabba's JDK directory replaced by
+// toolchains (named after jabba versions (e.g. "temurin@1.17.0"), so that project settings referencing them keep
+// working across syncs). JDKs registered by other means are left as is. File doesn't have to exist.
+func IdeaJdkTable(file string, toolchains []Toolchain) ([]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		b = []byte("<application>\n</application>\n")
+	}
+	b = ideaJdkRegexp.ReplaceAllFunc(b, func(entry []byte) []byte {
+		if m := ideaHomePathRegexp.FindSubmatch(entry); m != nil && isManagedHome(xmlUnescape(string(m[1]))) {
+			return nil
+		}
+		return entry
+	})
+	var buf bytes.Buffer
+	for _, tc := range toolchains {
+		version := tc.FullVersion
+		if version == "" {
+			version = tc.JavaVersion
+		}
+		fmt.Fprintf(&buf, "    <jdk version=\"2\">\n"+
+			"      <name value=\"%s\" />\n"+
+			"      <type value=\"JavaSDK\" />\n"+
+			"      <version value=\"%s\" />\n"+
+			"      <homePath value=\"%s\" />\n"+
+			"      <roots>\n"+
+			"        <annotationsPath>\n"+
+			"          <root type=\"composite\" />\n"+
+			"        </annotationsPath>\n"+
+			"        <classPath>\n"+
+			"          <root type=\"composite\" />\n"+
+			"        </classPath>\n"+
+			"        <javadocPath>\n"+
+			"          <root type=\"composite\" />\n"+
+			"        </javadocPath>\n"+
+			"        <sourcePath>\n"+
+			"          <root type=\"composite\" />\n"+
+			"        </sourcePath>\n"+
+			"      </roots>\n"+
+			"      <additional />\n"+
+			"    </jdk>\n",
+			xmlEscape(tc.Version), xmlEscape("java version \""+version+"\""), xmlEscape(filepath.ToSlash(tc.Home)))
+	}
+	loc := ideaJdkTableRegexp.FindSubmatchIndex(b)
+	if loc == nil {
+		end := bytes.LastIndex(b, []byte("</application>"))
+		if end == -1 {
+			return nil, fmt.Errorf("%s is not valid (</application> not found)", file)
+		}
+		return concat(b[:end], []byte("  <component name=\"ProjectJdkTable\">\n"), buf.Bytes(),
+			[]byte("  </component>\n"), b[end:]), nil
+	}
+	if string(b[loc[2]:loc[3]]) == "/>" {
+		return concat(b[:loc[2]], []byte(">\n"), buf.Bytes(), []byte("  </component>"), b[loc[3]:]), nil
+	}
+	end := bytes.Index(b[loc[1]:], []byte("</component>"))
+	if end == -1 {
+		return nil, fmt.Errorf("%s is not valid (ProjectJdkTable is not closed)", file)
+	}
+	end += loc[1]
+	// keep indentation of </component>
+	lineStart := bytes.LastIndexByte(b[:end], '\n') + 1
+	if len(bytes.TrimSpace(b[lineStart:end])) == 0 {
+		end = lineStart
+	} else {
+		buf.WriteString("  ")
+	}
+	if end > 0 && b[end-1] != '\n' {
+		return concat(b[:end], []byte("\n"), buf.Bytes(), b[end:]), nil
+	}
+	return concat(b[:end], buf.Bytes(), b[end:]), nil
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func xmlEscape(value string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
+
+func xmlUnescape(value string) string {
+	var s string
+	if err := xml.Unmarshal([]byte("<v>"+value+"</v>"), &s); err != nil {
+		return value
+	}
+	return s
+}
+
+const vscodeRuntimesKey = "java.configuration.runtimes"
+
+type vscodeRuntime struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Default bool   `json:"default,omitempty"`
+}
+
+// VSCodeSettings returns content of VS Code's settings.json (file) with "java.configuration.runtimes" entries
+// pointing to jabba's JDK directory replaced by toolchains (one per execution environment (e.g. "JavaSE-17"),
+// the first one (see Toolchains) wins). Entries added by other means take precedence and are kept (comments inside
+// "java.configuration.runtimes" are not). The rest of the file is left as is. File doesn't have to exist.
+func VSCodeSettings(file string, toolchains []Toolchain) ([]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		b = []byte("{\n}\n")
+	}
+	obj, err := scanJSONCObject(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not valid (%v)", file, err)
+	}
+	var runtimes []json.RawMessage
+	names := make(map[string]bool)
+	member, ok := obj.members[vscodeRuntimesKey]
+	if ok {
+		if err := json.Unmarshal(stripJSONC(b[member[0]:member[1]]), &runtimes); err != nil {
+			return nil, fmt.Errorf("%s is not valid (%s: %v)", file, vscodeRuntimesKey, err)
+		}
+		kept := runtimes[:0]
+		for _, raw := range runtimes {
+			var r vscodeRuntime
+			if json.Unmarshal(raw, &r) == nil && isManagedHome(r.Path) {
+				continue
+			}
+			names[r.Name] = true
+			kept = append(kept, raw)
+		}
+		runtimes = kept
+	}
+	for _, tc := range toolchains {
+		name := "JavaSE-" + tc.JavaVersion
+		if names[name] {
+			continue
+		}
+		names[name] = true
+		raw, _ := json.Marshal(vscodeRuntime{Name: name, Path: tc.Home})
+		runtimes = append(runtimes, raw)
+	}
+	var value bytes.Buffer
+	if len(runtimes) == 0 {
+		value.WriteString("[]")
+	} else {
+		value.WriteString("[\n")
+		for i, raw := range runtimes {
+			var indented bytes.Buffer
+			json.Indent(&indented, raw, "    ", "  ")
+			value.WriteString("    ")
+			value.Write(indented.Bytes())
+			if i != len(runtimes)-1 {
+				value.WriteString(",")
+			}
+			value.WriteString("\n")
+		}
+		value.WriteString("  ]")
+	}
+	if ok {
+		return concat(b[:member[0]], value.Bytes(), b[member[1]:]), nil
+	}
+	key, _ := json.Marshal(vscodeRuntimesKey)
+	insert := "  " + string(key) + ": " + value.String()
+	if obj.last == -1 {
+		// {} -> {\n  "key": value\n}
+		if !bytes.Contains(b[obj.start:obj.end], []byte("\n")) {
+			insert += "\n"
+		}
+		return concat(b[:obj.start], []byte("\n"+insert), b[obj.start:]), nil
+	}
+	return concat(b[:obj.last], []byte(",\n"+insert), b[obj.last:]), nil
+}
+
+// jsoncObject is the result of scanJSONCObject.
+type jsoncObject struct {
+	// key -> [start, end) of the value
+	members map[string][2]int
+	// position right after the opening brace
+	start int
+	// position right after the last member's value (-1 if there are none)
+	last int
+	// position of the closing brace
+	end int
+}
+
+// scanJSONCObject locates members of the top-level JSON object (JSON with comments & trailing commas, which is what
+// VS Code settings are written in).
+func scanJSONCObject(b []byte) (*jsoncObject, error) {
+	s := &jsoncScanner{b: b}
+	s.skip()
+	if s.i >= len(b) || b[s.i] != '{' {
+		return nil, fmt.Errorf("expected '{'")
+	}
+	s.i++
+	obj := &jsoncObject{members: make(map[string][2]int), start: s.i, last: -1}
+	for {
+		s.skip()
+		if s.i >= len(b) {
+			return nil, fmt.Errorf("unexpected end of file")
+		}
+		if b[s.i] == '}' {
+			obj.end = s.i
+			return obj, nil
+		}
+		if b[s.i] == ',' {
+			s.i++
+			continue
+		}
+		start := s.i
+		if err := s.value(); err != nil {
+			return nil, err
+		}
+		var key string
+		if err := json.Unmarshal(b[start:s.i], &key); err != nil {
+			return nil, fmt.Errorf("expected key at offset %d", start)
+		}
+		s.skip()
+		if s.i >= len(b) || b[s.i] != ':' {
+			return nil, fmt.Errorf("expected ':' at offset %d", s.i)
+		}
+		s.i++
+		s.skip()
+		start = s.i
+		if err := s.value(); err != nil {
+			return nil, err
+		}
+		obj.members[key] = [2]int{start, s.i}
+		obj.last = s.i
+	}
+}
+
+type jsoncScanner struct {
+	b []byte
+	i int
+}
+
+// skip moves past whitespace & comments.
+func (s *jsoncScanner) skip() {
+	for s.i < len(s.b) {
+		switch {
+		case s.b[s.i] == ' ' || s.b[s.i] == '\t' || s.b[s.i] == '\r' || s.b[s.i] == '\n':
+			s.i++
+		case bytes.HasPrefix(s.b[s.i:], []byte("//")):
+			if end := bytes.IndexByte(s.b[s.i:], '\n'); end != -1 {
+				s.i += end
+			} else {
+				s.i = len(s.b)
+			}
+		case bytes.HasPrefix(s.b[s.i:], []byte("/*")):
+			if end := bytes.Index(s.b[s.i+2:], []byte("*/")); end != -1 {
+				s.i += end + 4
+			} else {
+				s.i = len(s.b)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// value moves past a value (string, number, literal, array or object).
+func (s *jsoncScanner) value() error {
+	if s.i >= len(s.b) {
+		return fmt.Errorf("unexpected end of file")
+	}
+	switch s.b[s.i] {
+	case '"':
+		for s.i++; s.i < len(s.b); s.i++ {
+			switch s.b[s.i] {
+			case '\\':
+				s.i++
+			case '"':
+				s.i++
+				return nil
+			}
+		}
+		return fmt.Errorf("unterminated string")
+	case '[', '{':
+		depth := 0
+		for s.i < len(s.b) {
+			s.skip()
+			if s.i >= len(s.b) {
+				break
+			}
+			switch s.b[s.i] {
+			case '"':
+				if err := s.value(); err != nil {
+					return err
+				}
+				continue
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+				if depth == 0 {
+					s.i++
+					return nil
+				}
+			}
+			s.i++
+		}
+		return fmt.Errorf("unexpected end of file")
+	}
+	start := s.i
+	for s.i < len(s.b) && !strings.ContainsRune(",:]} \t\r\n/", rune(s.b[s.i])) {
+		s.i++
+	}
+	if s.i == start {
+		return fmt.Errorf("unexpected '%c' at offset %d", s.b[s.i], s.i)
+	}
+	return nil
+}
+
+// stripJSONC turns JSON with comments & trailing commas into plain JSON.
+func stripJSONC(b []byte) []byte {
+	s := &jsoncScanner{b: b}
+	var out bytes.Buffer
+	for s.i < len(b) {
+		start := s.i
+		s.skip()
+		if s.i != start {
+			out.WriteByte(' ')
+			continue
+		}
+		switch b[s.i] {
+		case '"':
+			s.value()
+			out.Write(b[start:s.i])
+		case ',':
+			s.i++
+			s.skip()
+			if s.i < len(b) && (b[s.i] == ']' || b[s.i] == '}') {
+				continue
+			}
+			out.WriteByte(',')
+		default:
+			out.WriteByte(b[s.i])
+			s.i++
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,127 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdeaJdkTable(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	jdkHome := filepath.ToSlash(filepath.Join(home, "jdk", "temurin@1.17.0"))
+	file := filepath.Join(home, "jdk.table.xml")
+	ioutil.WriteFile(file, []byte(`<application>
+  <component name="ProjectJdkTable">
+    <jdk version="2">
+      <name value="corretto-11" />
+      <homePath value="/opt/corretto-11" />
+    </jdk>
+    <jdk version="2">
+      <name value="zulu@1.8.0" />
+      <homePath value="`+filepath.ToSlash(filepath.Join(home, "jdk", "zulu@1.8.0"))+`" />
+    </jdk>
+  </component>
+</application>
+`), 0644)
+	toolchains := []Toolchain{{Version: "temurin@1.17.0", JavaVersion: "17", FullVersion: "17.0.9", Home: jdkHome}}
+	content, err := IdeaJdkTable(file, toolchains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<application>
+  <component name="ProjectJdkTable">
+    <jdk version="2">
+      <name value="corretto-11" />
+      <homePath value="/opt/corretto-11" />
+    </jdk>
+    <jdk version="2">
+      <name value="temurin@1.17.0" />
+      <type value="JavaSDK" />
+      <version value="java version &#34;17.0.9&#34;" />
+      <homePath value="` + jdkHome + `" />
+      <roots>
+        <annotationsPath>
+          <root type="composite" />
+        </annotationsPath>
+        <classPath>
+          <root type="composite" />
+        </classPath>
+        <javadocPath>
+          <root type="composite" />
+        </javadocPath>
+        <sourcePath>
+          <root type="composite" />
+        </sourcePath>
+      </roots>
+      <additional />
+    </jdk>
+  </component>
+</application>
+`
+	if actual := string(content); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	// syncing again changes nothing
+	ioutil.WriteFile(file, content, 0644)
+	if again, _ := IdeaJdkTable(file, toolchains); string(again) != expected {
+		t.Fatalf("actual: %v != expected: %v", string(again), expected)
+	}
+}
+
+func TestVSCodeSettings(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	jdk := filepath.Join(home, "jdk")
+	toolchains := []Toolchain{
+		{Version: "temurin@1.17.0", JavaVersion: "17", Home: filepath.Join(jdk, "temurin@1.17.0")},
+		{Version: "zulu@1.17.0", JavaVersion: "17", Home: filepath.Join(jdk, "zulu@1.17.0")},
+		{Version: "1.8.0", JavaVersion: "1.8", Home: filepath.Join(jdk, "1.8.0")},
+	}
+	file := filepath.Join(home, "settings.json")
+	for _, c := range []struct{ content, expected string }{
+		{"", "{\n  \"java.configuration.runtimes\": [\n" +
+			"    {\n      \"name\": \"JavaSE-17\",\n      \"path\": " + quoteJSON(toolchains[0].Home) + "\n    },\n" +
+			"    {\n      \"name\": \"JavaSE-1.8\",\n      \"path\": " + quoteJSON(toolchains[2].Home) + "\n    }\n" +
+			"  ]\n}\n"},
+		{"{\n  // comment\n  \"editor.tabSize\": 2, /* { */\n}\n",
+			"{\n  // comment\n  \"editor.tabSize\": 2,\n  \"java.configuration.runtimes\": [\n" +
+				"    {\n      \"name\": \"JavaSE-17\",\n      \"path\": " + quoteJSON(toolchains[0].Home) + "\n    },\n" +
+				"    {\n      \"name\": \"JavaSE-1.8\",\n      \"path\": " + quoteJSON(toolchains[2].Home) + "\n    }\n" +
+				"  ], /* { */\n}\n"},
+		// user-defined JavaSE-1.8 is kept (and wins), jabba's JavaSE-11 (which is no longer installed) is removed
+		{"{\"java.configuration.runtimes\": [{\"name\": \"JavaSE-1.8\", \"path\": \"/opt/jdk8\", \"default\": true}, " +
+			"// jabba\n{\"name\": \"JavaSE-11\", \"path\": " + quoteJSON(filepath.Join(jdk, "1.11.0")) + "},]," +
+			"\"x\": 1}",
+			"{\"java.configuration.runtimes\": [\n" +
+				"    {\n      \"name\": \"JavaSE-1.8\",\n      \"path\": \"/opt/jdk8\",\n      \"default\": true\n    },\n" +
+				"    {\n      \"name\": \"JavaSE-17\",\n      \"path\": " + quoteJSON(toolchains[0].Home) + "\n    }\n" +
+				"  ],\"x\": 1}"},
+	} {
+		ioutil.WriteFile(file, []byte(c.content), 0644)
+		content, err := VSCodeSettings(file, toolchains)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := string(content); actual != c.expected {
+			t.Fatalf("actual: %v != expected: %v", actual, c.expected)
+		}
+	}
+}
+
+func quoteJSON(value string) string {
+	b, _ := json.Marshal(value)
+	return string(b)
+}
@@ -0,0 +1,182 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// ImportedJDK is an outcome of importing a single JDK managed by another tool (e.g. SDKMAN!).
+type ImportedJDK struct {
+	// identifier used by the other tool (e.g. 17.0.9-tem)
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+	Path    string `json:"path"`
+	// reason JDK wasn't imported (if it wasn't)
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// SDKMAN! vendor -> jabba qualifier
+var sdkmanVendors = map[string]string{
+	"adpt":    "adopt",
+	"albba":   "dragonwell",
+	"amzn":    "amazon-corretto",
+	"bisheng": "bisheng",
+	"gln":     "gluon",
+	"graal":   "graalvm",
+	"graalce": "graalvm-ce",
+	"jbr":     "jetbrains",
+	"kona":    "kona",
+	"librca":  "liberica",
+	"mandrel": "mandrel",
+	"ms":      "microsoft",
+	"nik":     "liberica-nik",
+	"open":    "openjdk",
+	"oracle":  "",
+	"sapmchn": "sapmachine",
+	"sem":     "semeru",
+	"tem":     "temurin",
+	"trava":   "trava",
+	"zulu":    "zulu",
+}
+
+var sdkmanIDRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)*)(?:\.(fx|crac))?-([a-z]+)$`)
+
+// sdkmanVersion converts SDKMAN! identifier (e.g. 17.0.9-tem, 8.0.392-zulu, 17.0.9.fx-librca) to jabba's version
+// (temurin@1.17.0-9, zulu@1.8.0-392, liberica-fx@1.17.0-9).
+func sdkmanVersion(id string) (*semver.Version, error) {
+	m := sdkmanIDRegexp.FindStringSubmatch(id)
+	if m == nil {
+		return nil, fmt.Errorf("unsupported identifier \"%s\"", id)
+	}
+	parts, variant, vendor := strings.Split(m[1], "."), m[2], m[3]
+	if q, ok := sdkmanVendors[vendor]; ok {
+		vendor = q
+	}
+	if variant != "" {
+		if vendor == "" {
+			vendor = "oracle"
+		}
+		vendor += "-" + variant
+	}
+	ver := "1." + parts[0]
+	if len(parts) > 1 {
+		ver += "." + parts[1]
+	} else {
+		ver += ".0"
+	}
+	if len(parts) > 2 {
+		ver += "-" + strings.Join(parts[2:], ".")
+	}
+	if vendor != "" {
+		ver = vendor + "@" + ver
+	}
+	return semver.ParseVersion(ver)
+}
+
+// SdkmanDir returns $SDKMAN_DIR (~/.sdkman if not set).
+func SdkmanDir() string {
+	if dir := os.Getenv("SDKMAN_DIR"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".sdkman")
+}
+
+// ImportSdkman imports JDKs from <sdkmanDir>/candidates/java.
+// JDKs are linked into $JABBA_HOME/jdk (and so remain managed by SDKMAN!) unless move is true, in which case they are
+// moved into $JABBA_HOME/jdk (leaving links behind, so that SDKMAN! keeps working).
+// JDKs that are already installed (or which identifiers can't be translated) are skipped.
+func ImportSdkman(sdkmanDir string, move bool) ([]ImportedJDK, error) {
+	sdkmanDir, err := filepath.Abs(sdkmanDir)
+	if err != nil {
+		return nil, err
+	}
+	candidatesDir := filepath.Join(sdkmanDir, "candidates", "java")
+	files, err := ioutil.ReadDir(candidatesDir)
+	if err != nil {
+		return nil, err
+	}
+	local, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	installed := make(map[string]bool)
+	for _, v := range local {
+		installed[v.String()] = true
+	}
+	jdkDir := cfg.JDKDir()
+	if err := ensureWritableDir(jdkDir); err != nil {
+		return nil, err
+	}
+	var r []ImportedJDK
+	for _, f := range files {
+		if f.Name() == "current" {
+			continue
+		}
+		jdk := ImportedJDK{ID: f.Name(), Path: filepath.Join(candidatesDir, f.Name())}
+		ver, err := sdkmanVersion(f.Name())
+		switch {
+		case err != nil:
+			jdk.Skipped = err.Error() + " (use `jabba link system@<version> " + jdk.Path + "` instead)"
+		case installed[ver.String()]:
+			jdk.Version = ver.String()
+			jdk.Skipped = ver.String() + " is already installed"
+		default:
+			jdk.Version = ver.String()
+			if err := importJDK(jdk.Path, filepath.Join(jdkDir, ver.String()), move); err != nil {
+				jdk.Skipped = err.Error()
+			} else {
+				installed[ver.String()] = true
+			}
+		}
+		if jdk.Skipped != "" {
+			log.Debug("Skipping ", jdk.Path, " (", jdk.Skipped, ")")
+		}
+		r = append(r, jdk)
+	}
+	return r, nil
+}
+
+func importJDK(src string, dst string, move bool) error {
+	invalidateState()
+	target := dst
+	if err := assertJavaDistribution(src, runtime.GOOS); err != nil {
+		// SDKMAN! keeps macOS JDKs flattened (<candidate>/bin/java instead of <candidate>/Contents/Home/bin/java)
+		if runtime.GOOS != "darwin" || assertJavaDistribution(src, "linux") != nil {
+			return err
+		}
+		target = filepath.Join(dst, "Contents", "Home")
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+	}
+	stat, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	// local installs (`sdk install java <id> <path>`) are links themselves
+	if !move || stat.Mode()&os.ModeSymlink == os.ModeSymlink {
+		resolved, err := filepath.EvalSymlinks(src)
+		if err == nil {
+			err = os.Symlink(resolved, target)
+		}
+		if err != nil {
+			os.RemoveAll(dst)
+		}
+		return err
+	}
+	if err := os.Rename(src, target); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.Symlink(target, src)
+}
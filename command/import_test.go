@@ -0,0 +1,81 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSdkmanVersion(t *testing.T) {
+	for id, expected := range map[string]string{
+		"17.0.9-tem":       "temurin@1.17.0-9",
+		"8.0.392-zulu":     "zulu@1.8.0-392",
+		"11.0.21-amzn":     "amazon-corretto@1.11.0-21",
+		"11.0.14.1-ms":     "microsoft@1.11.0-14.1",
+		"21-graalce":       "graalvm-ce@1.21.0",
+		"17.0.9.fx-librca": "liberica-fx@1.17.0-9",
+		"17.0.9-oracle":    "1.17.0-9",
+		"17.0.9-foo":       "foo@1.17.0-9",
+	} {
+		actual, err := sdkmanVersion(id)
+		if err != nil || actual.String() != expected {
+			t.Fatalf("%s: actual: %v (%v) != expected: %v", id, actual, err, expected)
+		}
+	}
+	if _, err := sdkmanVersion("22.3.r17-grl"); err == nil {
+		t.Fatal("expected 22.3.r17-grl to be rejected")
+	}
+}
+
+func TestImportSdkman(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges")
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	sdkman := filepath.Join(home, "sdkman")
+	candidates := filepath.Join(sdkman, "candidates", "java")
+	for _, id := range []string{"17.0.9-tem", "8.0.392-zulu", "22.3.r17-grl"} {
+		if err := touch(expectedJavaPath(filepath.Join(candidates, id), runtime.GOOS)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("17.0.9-tem", filepath.Join(candidates, "current")); err != nil {
+		t.Fatal(err)
+	}
+	jdks, err := ImportSdkman(sdkman, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jdks) != 3 || jdks[0].ID != "17.0.9-tem" || jdks[0].Version != "temurin@1.17.0-9" || jdks[0].Skipped != "" ||
+		jdks[1].Skipped == "" || jdks[2].Version != "zulu@1.8.0-392" || jdks[2].Skipped != "" {
+		t.Fatalf("unexpected result: %+v", jdks)
+	}
+	if actual, _ := LsBestMatch("temurin@1.17.0-9"); actual != "temurin@1.17.0-9" {
+		t.Fatalf("actual: %v != expected: %v", actual, "temurin@1.17.0-9")
+	}
+	if _, err := Uninstall("temurin@1.17.0-9"); err != nil {
+		t.Fatal(err)
+	}
+	// uninstalling linked JDK must leave SDKMAN!'s copy intact
+	if err := file(expectedJavaPath(filepath.Join(candidates, "17.0.9-tem"), runtime.GOOS)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImportSdkman(sdkman, true); err != nil {
+		t.Fatal(err)
+	}
+	if stat, err := os.Lstat(filepath.Join(home, "jdk", "temurin@1.17.0-9")); err != nil || !stat.IsDir() {
+		t.Fatalf("temurin@1.17.0-9 wasn't moved (%v)", err)
+	}
+	// SDKMAN! keeps working
+	if err := file(expectedJavaPath(filepath.Join(candidates, "17.0.9-tem"), runtime.GOOS)); err != nil {
+		t.Fatal(err)
+	}
+}
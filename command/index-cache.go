@@ -0,0 +1,143 @@
+package command
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// $JABBA_HOME/cache/index/<sha1(url)>.meta
+type indexCacheMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+}
+
+func indexCacheFile(url string) string {
+	h := sha1.Sum([]byte(url))
+	return filepath.Join(cfg.StateDir(), "cache", "index", hex.EncodeToString(h[:]))
+}
+
+func readIndexCache(url string) ([]byte, *indexCacheMeta, error) {
+	file := indexCacheFile(url)
+	b, err := ioutil.ReadFile(file + ".meta")
+	if err != nil {
+		return nil, nil, err
+	}
+	var meta indexCacheMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, nil, err
+	}
+	cnt, err := ioutil.ReadFile(file + ".json")
+	if err != nil {
+		return nil, nil, err
+	}
+	return cnt, &meta, nil
+}
+
+func writeIndexCache(url string, cnt []byte, meta *indexCacheMeta) error {
+	file := indexCacheFile(url)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	// content first (so that .meta never refers to a stale / partially written index)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{file + ".json", cnt}, {file + ".meta", b}} {
+		if err := ioutil.WriteFile(f.name+".tmp", f.data, 0644); err != nil {
+			return err
+		}
+		if err := os.Rename(f.name+".tmp", f.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchIndex fetches index (using conditional GET if a cached copy is available).
+// Cached copy is served as is if offline mode is on (see cfg.Offline) or if url cannot be reached.
+// force skips conditional GET (see `jabba refresh`).
+func fetchIndex(url string, force bool) ([]byte, error) {
+	if strings.HasPrefix(url, "file://") {
+		return fetch(url)
+	}
+	cached, meta, cacheErr := readIndexCache(url)
+	if cfg.Offline() {
+		if cacheErr != nil {
+			return nil, fmt.Errorf("%s is not cached (run `jabba refresh` while online)", url)
+		}
+		log.Debug("Using cached ", url, " (fetched at ", meta.FetchedAt.Format(time.RFC3339), ")")
+		return cached, nil
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cacheErr == nil && !force {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	client := newHTTPClient()
+	res, err := client.Do(req)
+	if err == nil && res.StatusCode == http.StatusNotModified {
+		res.Body.Close()
+		if cacheErr != nil { // should never happen (no conditional headers were sent)
+			return nil, errors.New("GET " + url + " returned 304 while index is not cached")
+		}
+		meta.FetchedAt = time.Now()
+		if err := writeIndexCache(url, cached, meta); err != nil {
+			log.Debug("Failed to update index cache: ", err)
+		}
+		return cached, nil
+	}
+	var cnt []byte
+	if err == nil {
+		defer res.Body.Close()
+		if res.StatusCode >= 400 {
+			err = errors.New("GET " + url + " returned " + strconv.Itoa(res.StatusCode))
+		} else {
+			cnt, err = ioutil.ReadAll(res.Body)
+		}
+	}
+	if err != nil {
+		if cacheErr == nil && !force {
+			log.Warn("Failed to fetch ", url, " (", err, "). Using index cached at ",
+				meta.FetchedAt.Format(time.RFC3339))
+			return cached, nil
+		}
+		return nil, err
+	}
+	err = writeIndexCache(url, cnt, &indexCacheMeta{
+		URL:          url,
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	})
+	if err != nil {
+		log.Debug("Failed to update index cache: ", err)
+	}
+	invalidateCompletionCache()
+	return cnt, nil
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestFetchIndexIsCached(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("{}"))
+	}))
+	url := server.URL + "/index.json"
+	for i := 0; i < 2; i++ {
+		cnt, err := fetchIndex(url, false)
+		if err != nil || string(cnt) != "{}" {
+			t.Fatalf("actual: %s (%v) != expected: {}", cnt, err)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Fatalf("actual: %d/%d != expected: 2/1", requests, notModified)
+	}
+	if _, err := fetchIndex(url, true); err != nil || notModified != 1 {
+		t.Fatalf("refresh was expected to skip conditional GET (%v)", err)
+	}
+	server.Close()
+	// unreachable
+	if cnt, err := fetchIndex(url, false); err != nil || string(cnt) != "{}" {
+		t.Fatalf("actual: %s (%v) != expected: {}", cnt, err)
+	}
+	cfg.SetOffline(true)
+	defer cfg.SetOffline(false)
+	if cnt, err := fetchIndex(url, false); err != nil || string(cnt) != "{}" {
+		t.Fatalf("actual: %s (%v) != expected: {}", cnt, err)
+	}
+	if _, err := fetchIndex(server.URL+"/other.json", false); err == nil {
+		t.Fatal("offline fetch of an index that is not cached was expected to fail")
+	}
+}
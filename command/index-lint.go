@@ -0,0 +1,374 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+// IndexFinding is a problem `jabba index lint` found in the index.
+type IndexFinding struct {
+	// "error" or "warning"
+	Severity string `json:"severity"`
+	// "json", "schema", "duplicate", "semver", "url", "checksum", "notice" or "reachability"
+	Check string `json:"check"`
+	// "<os>/<arch>/<distribution>/<version>" (as far as it goes) ("" if finding is about the index as a whole)
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+type IndexLintOptions struct {
+	// true to HEAD every URL (reporting the ones that are not reachable)
+	Probe bool
+	// how many URLs to probe concurrently
+	Jobs int
+}
+
+// architectures index keys are expected to be in (runtime.GOARCH format)
+var indexArchs = map[string]bool{"amd64": true, "386": true, "arm64": true, "arm": true, "ppc64le": true,
+	"s390x": true, "riscv64": true}
+
+var sha256Pattern = regexp.MustCompile("^sha256=[0-9a-f]{64}$")
+var dottedVersionPattern = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+// notices are meant to fit a line or two of the terminal (see Release.Notice)
+const maxNoticeLength = 200
+
+type indexLinter struct {
+	findings []IndexFinding
+	// URL -> path of the first entry referencing it
+	urls map[string]string
+}
+
+func (l *indexLinter) report(severity, check, path, format string, args ...interface{}) {
+	l.findings = append(l.findings, IndexFinding{Severity: severity, Check: check, Path: path,
+		Message: fmt.Sprintf(format, args...)})
+}
+
+// LintIndexFile validates index (see LintIndex) read from file ("-" means stdin).
+func LintIndexFile(file string, opts IndexLintOptions) ([]IndexFinding, error) {
+	var b []byte
+	var err error
+	if file == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return LintIndex(b, opts), nil
+}
+
+// LintIndex validates index (index.json format (see byOS)): structure, keys (OS, architecture, "jdk" /
+// "jdk@<vendor>", versions (semver, duplicates)), entries (URL qualifier & fragment, sha256, requires, channel) &,
+// if opts.Probe is true, reachability of the URLs. Findings are sorted by path.
+func LintIndex(b []byte, opts IndexLintOptions) []IndexFinding {
+	l := &indexLinter{findings: []IndexFinding{}, urls: make(map[string]string)}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if err := findDuplicateKeys(dec, "", func(path string) {
+		l.report("error", "duplicate", path, "key is specified more than once (all but the last one are ignored)")
+	}); err != nil {
+		l.report("error", "json", "", "not a valid JSON (%v)", err)
+		return l.findings
+	}
+	var index map[string]json.RawMessage
+	if err := json.Unmarshal(b, &index); err != nil {
+		l.report("error", "schema", "", "index must be an object ({\"<os>\": {\"<arch>\": {...}}})")
+		return l.findings
+	}
+	for _, goos := range sortedRawKeys(index) {
+		l.lintOS(goos, index[goos])
+	}
+	if opts.Probe {
+		l.probe(opts.Jobs)
+	}
+	sort.SliceStable(l.findings, func(i, j int) bool { return l.findings[i].Path < l.findings[j].Path })
+	return l.findings
+}
+
+func (l *indexLinter) lintOS(goos string, raw json.RawMessage) {
+	if _, ok := platforms[strings.TrimSuffix(goos, "-musl")]; !ok {
+		l.report("warning", "schema", goos, "unknown OS (expected one of %s or linux-musl)",
+			strings.Join(SupportedOSs(), ", "))
+	}
+	var byArch map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &byArch); err != nil {
+		l.report("error", "schema", goos, "must be an object ({\"<arch>\": {...}})")
+		return
+	}
+	for _, arch := range sortedRawKeys(byArch) {
+		path := goos + "/" + arch
+		if !indexArchs[arch] {
+			hint := ""
+			if arch == "aarch64" {
+				hint = " (did you mean arm64?)"
+			} else if arch == "x86_64" || arch == "x64" {
+				hint = " (did you mean amd64?)"
+			}
+			l.report("warning", "schema", path, "unknown architecture%s (entries won't be matched)", hint)
+		}
+		var byDistribution map[string]json.RawMessage
+		if err := json.Unmarshal(byArch[arch], &byDistribution); err != nil {
+			l.report("error", "schema", path, "must be an object ({\"jdk@<vendor>\": {...}})")
+			continue
+		}
+		for _, distribution := range sortedRawKeys(byDistribution) {
+			l.lintDistribution(goos, path+"/"+distribution, distribution, byDistribution[distribution])
+		}
+	}
+}
+
+func (l *indexLinter) lintDistribution(goos, path, distribution string, raw json.RawMessage) {
+	var prefix string
+	if distribution != "jdk" {
+		if !strings.HasPrefix(distribution, "jdk@") || len(distribution) == len("jdk@") {
+			l.report("error", "schema", path, "key must be either \"jdk\" or \"jdk@<vendor>\" (entries are ignored)")
+			return
+		}
+		prefix = strings.TrimPrefix(distribution, "jdk@") + "@"
+	}
+	var byVersion map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &byVersion); err != nil {
+		l.report("error", "schema", path, "must be an object ({\"<version>\": \"<type>+<url>\"})")
+		return
+	}
+	var versions []*semver.Version
+	recommended := 0
+	for _, ver := range sortedRawKeys(byVersion) {
+		entryPath := path + "/" + ver
+		v, err := semver.ParseVersion(prefix + ver)
+		if err != nil {
+			l.report("error", "semver", entryPath, "version is not a valid semver (%v)", err)
+		} else {
+			for _, other := range versions {
+				// e.g. "1.8" & "1.8.0"
+				if !v.LessThan(other) && !other.LessThan(v) {
+					l.report("error", "duplicate", entryPath, "same version as %s", other)
+				}
+			}
+			versions = append(versions, v)
+		}
+		release, ok := l.lintRelease(entryPath, byVersion[ver])
+		if !ok {
+			continue
+		}
+		if release.Recommended {
+			recommended++
+		}
+		l.lintURL(goos, entryPath, release)
+	}
+	if recommended > 1 {
+		l.report("warning", "schema", path, "%d releases are marked as recommended (the latest one wins)",
+			recommended)
+	}
+}
+
+func (l *indexLinter) lintRelease(path string, raw json.RawMessage) (Release, bool) {
+	var release Release
+	var url string
+	if err := json.Unmarshal(raw, &url); err == nil {
+		return Release{URL: url}, true
+	}
+	type strict Release // no UnmarshalJSON
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode((*strict)(&release)); err != nil {
+		if err := json.Unmarshal(raw, (*strict)(&release)); err != nil {
+			l.report("error", "schema", path, "entry must be either \"<type>+<url>\" or {\"url\": \"<type>+<url>\", "+
+				"...} (%v)", err)
+			return release, false
+		}
+		l.report("warning", "schema", path, "%v (ignored)", err)
+	}
+	for _, key := range sortedStringKeys(release.Requires) {
+		if key != "glibc" && key != "macos" {
+			l.report("warning", "schema", path, "unknown requirement \"%s\" (expected \"glibc\" or \"macos\")", key)
+		}
+		if !dottedVersionPattern.MatchString(release.Requires[key]) {
+			l.report("error", "schema", path, "%s requirement must be a dotted version (e.g. 2.17)", key)
+		}
+	}
+	if release.Channel != "" && release.Channel != ChannelGA && release.Channel != ChannelEA {
+		l.report("error", "schema", path, "channel must be either \"ga\" or \"ea\"")
+	}
+	if (release.Sig == "") != (release.Key == "") {
+		l.report("error", "schema", path, "both sig and key have to be specified")
+	}
+	if notice := noticeOf(release); notice != release.Notice {
+		l.report("warning", "notice", path, "notice is shown on a single line (whitespace is collapsed, "+
+			"control characters are dropped)")
+	} else if len(notice) > maxNoticeLength {
+		l.report("warning", "notice", path, "notice is longer than %d characters (link to the details instead)",
+			maxNoticeLength)
+	}
+	return release, true
+}
+
+func (l *indexLinter) lintURL(goos, path string, release Release) {
+	url := release.URL
+	if matched, _ := regexp.MatchString("^\\w+[+]\\w+://", url); !matched {
+		l.report("error", "url", path, "URL must contain qualifier (e.g. tgz+https://...)")
+		return
+	}
+	fileType := url[:strings.Index(url, "+")]
+	if _, ok := platforms[strings.TrimSuffix(goos, "-musl")]; ok {
+		if _, err := installerFor(strings.TrimSuffix(goos, "-musl"), fileType); err != nil {
+			l.report("error", "url", path, "%v", err)
+		}
+	}
+	url, f, err := splitFragment(url[strings.Index(url, "+")+1:])
+	if err != nil {
+		l.report("error", "url", path, "%v", err)
+		return
+	}
+	switch {
+	case f.checksum != "" && !sha256Pattern.MatchString(f.checksum):
+		l.report("error", "checksum", path, "#%s is not a valid sha256 (64 hex digits)", f.checksum)
+	case f.checksum == "" && f.sig == "" && release.Sig == "":
+		l.report("warning", "checksum", path, "neither sha256 (#sha256=<hex>) nor signature (sig & key) is "+
+			"specified (archive can't be verified)")
+	}
+	if first, ok := l.urls[url]; ok {
+		l.report("warning", "duplicate", path, "same URL as %s", first)
+	} else {
+		l.urls[url] = path
+	}
+}
+
+// probe HEADs every URL (GET of the first byte if server doesn't implement HEAD).
+func (l *indexLinter) probe(jobs int) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	urls := make([]string, 0, len(l.urls))
+	for url := range l.urls {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	problems := make([]string, len(urls))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, url string) {
+			defer func() { <-sem; wg.Done() }()
+			problems[i] = probeURL(url)
+		}(i, url)
+	}
+	wg.Wait()
+	for i, url := range urls {
+		if problems[i] != "" {
+			l.report("error", "reachability", l.urls[url], "%s", problems[i])
+		}
+	}
+}
+
+// probeURL returns why URL is not reachable ("" if it is).
+func probeURL(url string) string {
+	if strings.HasPrefix(url, "file://") {
+		file, err := resolveLocalArchive(url)
+		if err == nil {
+			_, err = os.Stat(file)
+		}
+		if err != nil {
+			return err.Error()
+		}
+		return ""
+	}
+	client := newDownloadClient()
+	status := 0
+	for _, method := range []string{"HEAD", "GET"} {
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return err.Error()
+		}
+		req.Header.Set("Cookie", "oraclelicense=accept-securebackup-cookie")
+		if method == "GET" {
+			req.Header.Set("Range", "bytes=0-0")
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err.Error()
+		}
+		res.Body.Close()
+		status = res.StatusCode
+		if status < 400 {
+			return ""
+		}
+		// some servers (S3 pre-signed URLs, CDNs) don't implement HEAD
+		if status != http.StatusMethodNotAllowed && status != http.StatusForbidden &&
+			status != http.StatusNotImplemented {
+			break
+		}
+	}
+	return fmt.Sprintf("%s returned %d", url, status)
+}
+
+// findDuplicateKeys walks JSON value dec is positioned at, calling onDuplicate with "/"-separated path of every key
+// object has more than once (which encoding/json silently ignores).
+func findDuplicateKeys(dec *json.Decoder, path string, onDuplicate func(path string)) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := t.(string)
+			keyPath := key
+			if path != "" {
+				keyPath = path + "/" + key
+			}
+			if seen[key] {
+				onDuplicate(keyPath)
+			}
+			seen[key] = true
+			if err := findDuplicateKeys(dec, keyPath, onDuplicate); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	case json.Delim('['):
+		for dec.More() {
+			if err := findDuplicateKeys(dec, path, onDuplicate); err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+	}
+	return err
+}
+
+func sortedRawKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
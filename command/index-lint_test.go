@@ -0,0 +1,96 @@
+package command
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintIndex(t *testing.T) {
+	sum := "sha256=" + strings.Repeat("0", 64)
+	findings := LintIndex([]byte(`{
+  "linux": {
+    "amd64": {
+      "jdk@zulu": {
+        "1.17.0": "tgz+https://example.com/zulu-17.tar.gz#`+sum+`",
+        "1.17": "tgz+https://example.com/zulu-17.0.0.tar.gz#`+sum+`",
+        "x.y": "tgz+https://example.com/zulu-x.tar.gz#`+sum+`",
+        "1.11.0": "dmg+https://example.com/zulu-11.dmg#`+sum+`",
+        "1.8.0": "tgz+https://example.com/zulu-8.tar.gz"
+      },
+      "zulu": {
+        "1.8.0": "tgz+https://example.com/zulu-8.tar.gz"
+      }
+    },
+    "aarch64": {
+      "jdk": {
+        "1.9.0": {"url": "https://example.com/9.tar.gz#sha256=abc", "requires": {"glibc": "two"}, "urgent": true},
+        "1.9.0": "tgz+https://example.com/9.tar.gz"
+      }
+    }
+  }
+}`), IndexLintOptions{})
+	var actual []string
+	for _, f := range findings {
+		actual = append(actual, f.Severity+" "+f.Check+" "+f.Path)
+	}
+	expected := []string{
+		"warning schema linux/aarch64",
+		"error duplicate linux/aarch64/jdk/1.9.0",
+		// the last 1.9.0 wins
+		"warning checksum linux/aarch64/jdk/1.9.0",
+		"error url linux/amd64/jdk@zulu/1.11.0",
+		// "1.17" == "1.17.0"
+		"error duplicate linux/amd64/jdk@zulu/1.17.0",
+		"warning checksum linux/amd64/jdk@zulu/1.8.0",
+		"error semver linux/amd64/jdk@zulu/x.y",
+		"error schema linux/amd64/zulu",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if findings := LintIndex([]byte(`{"linux": [`), IndexLintOptions{}); len(findings) != 1 ||
+		findings[0].Check != "json" {
+		t.Fatalf("actual: %v != expected: invalid JSON to be reported", findings)
+	}
+}
+
+func TestLintIndexProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/head-not-allowed.tar.gz" && r.Method == "HEAD":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/missing.tar.gz":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	sum := "#sha256=" + strings.Repeat("0", 64)
+	findings := LintIndex([]byte(`{"linux": {"amd64": {"jdk": {
+  "1.8.0": "tgz+`+server.URL+`/ok.tar.gz`+sum+`",
+  "1.9.0": "tgz+`+server.URL+`/head-not-allowed.tar.gz`+sum+`",
+  "1.10.0": "tgz+`+server.URL+`/missing.tar.gz`+sum+`"
+}}}}`), IndexLintOptions{Probe: true, Jobs: 2})
+	if len(findings) != 1 || findings[0].Check != "reachability" || findings[0].Path != "linux/amd64/jdk/1.10.0" {
+		t.Fatalf("actual: %v != expected: linux/amd64/jdk/1.10.0 to be unreachable", findings)
+	}
+}
+
+func TestLintIndexNotice(t *testing.T) {
+	sum := "#sha256=" + strings.Repeat("0", 64)
+	findings := LintIndex([]byte(`{"linux": {"amd64": {"jdk@zulu": {
+  "1.17.0": {"url": "tgz+https://example.com/17.0.0.tar.gz`+sum+`", "notice": "known TLS bug, prefer 17.0.1"},
+  "1.17.1": {"url": "tgz+https://example.com/17.0.1.tar.gz`+sum+`", "notice": "requires\n--enable-preview"},
+  "1.17.2": {"url": "tgz+https://example.com/17.0.2.tar.gz`+sum+`", "notice": "`+strings.Repeat("x", 201)+`"}
+}}}}`), IndexLintOptions{})
+	var actual []string
+	for _, f := range findings {
+		actual = append(actual, f.Severity+" "+f.Check+" "+f.Path)
+	}
+	expected := []string{"warning notice linux/amd64/jdk@zulu/1.17.1", "warning notice linux/amd64/jdk@zulu/1.17.2"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
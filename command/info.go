@@ -0,0 +1,91 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// InstallInfo is what `jabba info` (and `jabba ls --verbose`) outputs.
+// Only Version, Path, Vendor, JavaVersion & Size are known for JDKs that were `jabba link`ed or
+// installed by an older version of jabba (see installMeta).
+type InstallInfo struct {
+	Version     string `json:"version"`
+	Path        string `json:"path"`
+	Vendor      string `json:"vendor,omitempty"`
+	JavaVersion string `json:"javaVersion,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Type        string `json:"type,omitempty"`
+	SHA256      string `json:"sha256,omitempty"`
+	Signer      string `json:"signer,omitempty"`
+	OS          string `json:"os,omitempty"`
+	Arch        string `json:"arch,omitempty"`
+	// see InstalledArchs
+	Archs       []string   `json:"archs,omitempty"`
+	Size        int64      `json:"size"`
+	InstalledAt *time.Time `json:"installedAt,omitempty"`
+	// see Pin
+	Pinned bool `json:"pinned,omitempty"`
+	// see SetJDKEnv
+	Env map[string]string `json:"env,omitempty"`
+	// see AddComponents
+	Components []string `json:"components,omitempty"`
+	// see Release.Notice
+	Notice string `json:"notice,omitempty"`
+}
+
+// Info describes installed JDK matching the selector (which can be an alias).
+func Info(selector string) (*InstallInfo, error) {
+	ver, err := Resolve(selector)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(cfg.JDKDir(), ver)
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		info := &InstallInfo{Version: ver, Path: path, Archs: InstalledArchs(ver), Size: diskUsage(path),
+			Pinned: IsPinned(ver)}
+		// system@... JDKs are symlinks
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			release := readReleaseFile(resolved)
+			info.Vendor, info.JavaVersion = release["IMPLEMENTOR"], release["JAVA_RUNTIME_VERSION"]
+			if info.JavaVersion == "" {
+				info.JavaVersion = release["JAVA_VERSION"]
+			}
+		}
+		if v, err := semver.ParseVersion(ver); err == nil && info.Vendor == "" {
+			info.Vendor = v.Qualifier()
+		}
+		return info, nil
+	}
+	size := meta.Size
+	if size == 0 {
+		size = diskUsage(path)
+	}
+	installedAt := meta.InstalledAt
+	return &InstallInfo{
+		Version:     ver,
+		Path:        path,
+		Vendor:      meta.Vendor,
+		JavaVersion: meta.JavaVersion,
+		URL:         meta.URL,
+		Type:        meta.Type,
+		SHA256:      meta.SHA256,
+		Signer:      meta.Signer,
+		OS:          meta.OS,
+		Arch:        meta.Arch,
+		Archs:       InstalledArchs(ver),
+		Size:        size,
+		InstalledAt: &installedAt,
+		Pinned:      IsPinned(ver),
+		Env:         meta.Env,
+		Components:  meta.Components,
+		Notice:      meta.Notice,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestInfo(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	release := "IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_VERSION=\"17.0.9\"\nJAVA_RUNTIME_VERSION=\"17.0.9+9\"\n"
+	archive := filepath.Join(home, "jdk.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	ok(tw.WriteHeader(&tar.Header{Name: java, Typeflag: tar.TypeReg, Mode: 0755}))
+	ok(tw.WriteHeader(&tar.Header{Name: "jdk/release", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(release))}))
+	_, err = tw.Write([]byte(release))
+	ok(err)
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	_, err = Install("temurin@1.17.0-9=tgz+file://"+filepath.ToSlash(archive), InstallOptions{Arch: "arm64"})
+	ok(err)
+	info, err := Info("temurin@1.17.0-9")
+	ok(err)
+	if info.Vendor != "Eclipse Adoptium" || info.JavaVersion != "17.0.9+9" || info.Type != "tgz" ||
+		info.OS != runtime.GOOS || info.Arch != "arm64" || info.Size != int64(len(release)) ||
+		info.SHA256 == "" || info.InstalledAt == nil || info.URL != "file://"+filepath.ToSlash(archive) {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	// installed by an older version of jabba
+	installFakeJDKs(t, home, "zulu@1.8.0")
+	info, err = Info("zulu@1.8")
+	ok(err)
+	if info.Version != "zulu@1.8.0" || info.Vendor != "zulu" || info.InstalledAt != nil || info.URL != "" {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+}
+
+func TestInfoNotice(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	archive := filepath.Join(home, "jdk.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, file := range []string{java, "jdk/release"} {
+		ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755}))
+	}
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	index := filepath.Join(home, "index.json")
+	// escape sequences must not reach the terminal
+	ok(ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"`+HostArch()+`": {"jdk@zulu": {"1.17.0": {
+  "url": "tgz+file://`+filepath.ToSlash(archive)+`",
+  "notice": "known TLS bug,\n  prefer \u001b[1m17.0.1\u001b[0m"
+}}}}}`), 0644))
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	expected := "known TLS bug, prefer 17.0.1"
+	result, err := Install("zulu@1.17", InstallOptions{})
+	ok(err)
+	if result.Notice != expected {
+		t.Fatalf("actual: %q != expected: %q", result.Notice, expected)
+	}
+	info, err := Info("zulu@1.17.0")
+	ok(err)
+	if info.Notice != expected {
+		t.Fatalf("actual: %q != expected: %q", info.Notice, expected)
+	}
+}
@@ -0,0 +1,121 @@
+package command
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// InstallOutcome is the result of installing a single JDK out of many (see InstallAll).
+type InstallOutcome struct {
+	Selector string         `json:"selector"`
+	Result   *InstallResult `json:"result,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// InstallAll installs JDKs matching selectors, downloading up to jobs archives at a time (JDKs are extracted
+// one by one). Failure to install one JDK doesn't affect the others. Outcomes are in the order of selectors.
+func InstallAll(selectors []string, opts InstallOptions, jobs int) []InstallOutcome {
+	if jobs < 1 {
+		jobs = 1
+	}
+	// archives are extracted one by one (as downloads complete)
+	opts.NoStream = true
+	outcomes := make([]InstallOutcome, len(selectors))
+	plans := make([]*InstallPlan, len(selectors))
+	// version -> index of the selector it's being installed for
+	planned := make(map[string]int)
+	duplicates := make(map[int]int)
+	var queue []int
+	for i, selector := range selectors {
+		outcomes[i].Selector = selector
+		plan, err := planInstall(selector, opts)
+		if err != nil {
+			outcomes[i].Error = err.Error()
+			continue
+		}
+		if plan.AlreadyInstalled {
+			outcomes[i].Result = &InstallResult{Version: plan.Version, Path: plan.Target, AlreadyInstalled: true}
+			continue
+		}
+		// e.g. "zulu@1.17" & "zulu@1.17.0-1"
+		if j, ok := planned[plan.Version]; ok {
+			duplicates[i] = j
+			continue
+		}
+		planned[plan.Version] = i
+		plans[i] = plan
+		queue = append(queue, i)
+	}
+	type fetched struct {
+		i       int
+		archive *fetchedArchive
+		err     error
+	}
+	work := make(chan int)
+	results := make(chan fetched)
+	for w := 0; w < jobs && w < len(queue); w++ {
+		go func() {
+			for i := range work {
+				o := opts
+				// progress bars of concurrent downloads would overwrite each other
+				if o.Progress == nil && len(queue) > 1 && resolveProgressMode() == "tty" {
+					draw := plainProgress(os.Stderr, plans[i].URL, 0)
+					o.Progress = func(downloaded int64, total int64) { draw(downloaded, total) }
+				}
+				archive, err := fetchArchive(plans[i], o)
+				results <- fetched{i, archive, err}
+			}
+		}()
+	}
+	go func() {
+		for _, i := range queue {
+			work <- i
+		}
+		close(work)
+	}()
+	for range queue {
+		f := <-results
+		if f.err == nil {
+			f.err = extract(plans[f.i], f.archive, opts)
+		}
+		if f.err != nil {
+			outcomes[f.i].Error = f.err.Error()
+			continue
+		}
+		outcomes[f.i].Result = f.archive.result
+		recordHistory("install", selectors[f.i], plans[f.i].Version)
+	}
+	for i, j := range duplicates {
+		outcomes[i].Result, outcomes[i].Error = outcomes[j].Result, outcomes[j].Error
+	}
+	return outcomes
+}
+
+// ReadSelectors reads selectors (one per line, # at the beginning of the line or after a whitespace starts
+// a comment (#sha256=... of <version>=<url> is not one)) from the file ("-" means stdin).
+func ReadSelectors(file string) ([]string, error) {
+	f := os.Stdin
+	if file != "-" {
+		var err error
+		if f, err = os.Open(file); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+	}
+	var selectors []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for i := 0; i < len(line); i++ {
+			if line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+				line = line[:i]
+				break
+			}
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			selectors = append(selectors, line)
+		}
+	}
+	return selectors, scanner.Err()
+}
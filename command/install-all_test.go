@@ -0,0 +1,104 @@
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestInstallAll(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	var archive bytes.Buffer
+	gw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gw)
+	for _, file := range []string{java, "jdk/release"} {
+		ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755}))
+	}
+	ok(tw.Close())
+	ok(gw.Close())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive.Bytes())
+	}))
+	defer server.Close()
+	prevProgressMode := progressMode
+	defer func() { progressMode = prevProgressMode }()
+	progressMode = "none"
+	outcomes := InstallAll([]string{
+		"1.17.0-a=tgz+" + server.URL + "/a.tar.gz#sha256=" + sha256Hex(archive.Bytes()),
+		"1.17.0-b=tgz+" + server.URL + "/b.tar.gz#sha256=0000000000000000000000000000000000000000000000000000000000000000",
+		"1.17.0-c=tgz+" + server.URL + "/c.tar.gz#sha256=" + sha256Hex(archive.Bytes()),
+		"1.17.0-a=tgz+" + server.URL + "/a.tar.gz#sha256=" + sha256Hex(archive.Bytes()),
+	}, InstallOptions{}, 2)
+	if len(outcomes) != 4 {
+		t.Fatalf("unexpected outcomes: %+v", outcomes)
+	}
+	for _, i := range []int{0, 2, 3} {
+		if outcomes[i].Error != "" || outcomes[i].Result == nil || outcomes[i].Result.AlreadyInstalled {
+			t.Fatalf("unexpected outcome: %+v", outcomes[i])
+		}
+	}
+	if outcomes[1].Error == "" || outcomes[1].Result != nil {
+		t.Fatalf("unexpected outcome: %+v", outcomes[1])
+	}
+	vs, err := Ls()
+	ok(err)
+	var installed []string
+	for _, v := range vs {
+		installed = append(installed, v.String())
+	}
+	if expected := []string{"1.17.0-c", "1.17.0-a"}; !reflect.DeepEqual(installed, expected) {
+		t.Fatalf("actual: %v != expected: %v", installed, expected)
+	}
+}
+
+func sha256Hex(b []byte) string {
+	sum, _ := sha256Of(bytes.NewReader(b))
+	return sum
+}
+
+func TestReadSelectors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "versions.txt")
+	ioutil.WriteFile(file, []byte(strings.Join([]string{
+		"# JDKs used by CI",
+		"zulu@1.17",
+		"",
+		"  temurin@>=1.21.0-0 <1.22.0 # latest 21",
+		"1.8.0-custom=tgz+https://example.com/jdk.tar.gz#sha256=abc",
+	}, "\n")), 0644)
+	selectors, err := ReadSelectors(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"zulu@1.17", "temurin@>=1.21.0-0 <1.22.0",
+		"1.8.0-custom=tgz+https://example.com/jdk.tar.gz#sha256=abc"}
+	if !reflect.DeepEqual(selectors, expected) {
+		t.Fatalf("actual: %v != expected: %v", selectors, expected)
+	}
+}
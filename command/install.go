@@ -3,250 +3,490 @@ package command
 import (
 	"archive/tar"
 	"archive/zip"
+	"bufio"
 	"compress/gzip"
 	"errors"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
-	"github.com/mitchellh/ioprogress"
+	"github.com/klauspost/compress/zstd"
 	"github.com/shyiko/jabba/cfg"
 	"github.com/shyiko/jabba/command/fileiter"
+	"github.com/shyiko/jabba/command/flock"
+	"github.com/shyiko/jabba/command/trace"
 	"github.com/shyiko/jabba/semver"
 	"github.com/shyiko/jabba/w32"
 	"github.com/xi2/xz"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 )
 
-func Install(selector string, dst string) (string, error) {
-	var releaseMap map[*semver.Version]string
-	var ver *semver.Version
-	var err error
+// InstallResult describes installed JDK (see `jabba install --json`).
+type InstallResult struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	URL     string `json:"url,omitempty"`
+	// archive type (e.g. "tgz")
+	Type string `json:"type,omitempty"`
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+	// sha256 of the archive (measured (regardless of whether index specified one or not))
+	SHA256 string `json:"sha256,omitempty"`
+	// true if sha256 was checked against the one specified in the index
+	ChecksumVerified bool `json:"checksumVerified"`
+	// fingerprint of the key archive was signed with (if signature was verified)
+	Signer           string `json:"signer,omitempty"`
+	AlreadyInstalled bool   `json:"alreadyInstalled"`
+	// see Release.Notice
+	Notice string `json:"notice,omitempty"`
+	// when download started (see installMeta.StartedAt)
+	startedAt time.Time
+}
+
+type InstallOptions struct {
+	// custom destination ("" means $JABBA_HOME/jdk/<version>)
+	Dst string
+	// OS to install JDK for ("" means runtime.GOOS). JDKs for other OSs can only be installed into custom Dst
+	// (e.g. to pre-stage them for Docker images / build agents)
+	OS string
+	// architecture to install JDK for ("" means HostArch() (with Rosetta 2 fallback on darwin/arm64))
+	Arch string
+	// true to fall back to amd64 JDK on windows/arm64 (x64 emulation) when there is no native build
+	AllowEmulation bool
+	// "glibc" or "musl" ("" means auto-detect (with fallback to glibc builds if there are no musl ones))
+	Libc string
+	// true to pick the latest matching release even if there is a recommended one (see Release.Recommended)
+	Any bool
+	// release channel to pick from ("ga" (default), "ea" or "all")
+	Channel string
+	// download progress listener (nil means progress is drawn to stderr)
+	Progress ProgressFunc
+	// true to always save archive to a file before extracting it (see InstallPlan.Streamed)
+	NoStream bool
+	// true to generate CDS archive (`java -Xshare:dump`) once JDK is installed ("cds" in config.yaml is used if
+	// false) (JDKs installed into custom Dst are left as is)
+	CDS bool
+	// true to skip post_install hooks (see cfg.PostInstall)
+	NoPostInstall bool
+	// artifact directory to pick archives from instead of the index / vendor APIs (see LsDir) ("" means none)
+	FromDir string
+	// true to install JDK even if it's known not to work on this host (see compatIssues)
+	IgnoreCompat bool
+	// GraalVM components (e.g. "native-image") to add once JDK is installed (see AddComponents) (JDKs installed into
+	// custom Dst are left as is)
+	Components []string
+}
+
+func Install(selector string, opts InstallOptions) (*InstallResult, error) {
+	span := trace.Start("install", "selector", selector)
+	result, err := install(selector, opts)
+	span.End(err)
+	if err == nil && !result.AlreadyInstalled {
+		recordHistory("install", selector, result.Version)
+		if result.Notice != "" {
+			log.Warn(result.Version, ": ", result.Notice)
+		}
+	}
+	return result, err
+}
+
+// resolveRelease finds the latest release matching the selector (unless selector is in form of <version>=<url>).
+// If arch is not specified (neither in opts nor with "arch" in config.yaml), HostArch() is assumed, falling back to
+// amd64 (Rosetta 2) on darwin/arm64 when there is no native build (on windows/arm64 only if opts.AllowEmulation is
+// true). Same goes for libc (musl-based distributions fall back to glibc builds).
+func resolveRelease(selector string, opts InstallOptions) (*semver.Version, Release, error) {
+	if opts.Arch == "" {
+		opts.Arch = cfg.Arch()
+	}
 	// selector can be in form of <version>=<url>
-	if strings.Contains(selector, "=") && strings.Contains(selector, "://") {
+	if IsURLSelector(selector) {
 		split := strings.SplitN(selector, "=", 2)
-		selector = split[0]
 		// <version> has to be valid per semver
-		ver, err = semver.ParseVersion(selector)
+		ver, err := semver.ParseVersion(split[0])
 		if err != nil {
-			return "", err
+			return nil, Release{}, err
+		}
+		arch := HostArch()
+		if opts.Arch != "" {
+			arch = NormalizeArch(opts.Arch)
 		}
-		releaseMap = map[*semver.Version]string{ver: split[1]}
-	} else {
-		// ... or a version (range will be tried over remote targets)
-		ver, _ = semver.ParseVersion(selector)
+		return ver, Release{URL: split[1], os: opts.targetOS(), arch: arch}, nil
+	}
+	// ... or a digest (see IsDigestSelector) or a version (range will be tried over remote targets)
+	resolveFor := func(os, arch string) (*semver.Version, Release, error) {
+		return resolveDigestFor(selector, os, arch, opts)
 	}
-	// ... apparently it's not
-	if releaseMap == nil {
-		ver = nil
+	if !IsDigestSelector(selector) {
 		rng, err := semver.ParseRange(selector)
 		if err != nil {
-			return "", err
-		}
-		releaseMap, err = LsRemote(runtime.GOOS, runtime.GOARCH)
-		if err != nil {
-			return "", err
+			return nil, Release{}, err
 		}
-		var vs = make([]*semver.Version, len(releaseMap))
-		var i = 0
-		for k := range releaseMap {
-			vs[i] = k
-			i++
+		resolveFor = func(os, arch string) (*semver.Version, Release, error) {
+			return resolveReleaseFor(rng, selector, os, arch, opts)
 		}
-		sort.Sort(sort.Reverse(semver.VersionSlice(vs)))
-		for _, v := range vs {
-			if rng.Contains(v) {
-				ver = v
-				break
+	}
+	goos, err := TargetOS(opts.targetOS(), opts.Libc)
+	if err != nil {
+		return nil, Release{}, err
+	}
+	type target struct{ os, arch, fallbackWarning string }
+	targets := []target{{goos, HostArch(), ""}}
+	windowsOnARM := opts.Arch == "" && opts.targetOS() == "windows" && HostArch() == "arm64"
+	if opts.Arch != "" {
+		targets[0].arch = NormalizeArch(opts.Arch)
+	} else if opts.targetOS() == "darwin" && runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+		targets = append(targets, target{goos, "amd64", "There is no native (arm64) build of %s, " +
+			"falling back to amd64 (Rosetta 2)"})
+	} else if windowsOnARM && opts.AllowEmulation {
+		targets = append(targets, target{goos, "amd64", "There is no native (arm64) build of %s, " +
+			"falling back to amd64 (x64 emulation (expect JDK to be noticeably slower))"})
+	}
+	if opts.Libc == "" && goos == "linux-musl" {
+		targets = append(targets, target{"linux", targets[0].arch, "There is no musl build of %s, " +
+			"falling back to glibc one (which requires glibc compatibility layer (e.g. gcompat))"})
+	}
+	var firstErr error
+	for _, t := range targets {
+		ver, release, err := resolveFor(t.os, t.arch)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
 			}
+			continue
+		}
+		if t.fallbackWarning != "" {
+			log.Warnf(t.fallbackWarning, ver)
 		}
-		if ver == nil {
-			tt := make([]string, len(vs))
-			for i, v := range vs {
-				tt[i] = v.String()
+		release.os, release.arch = t.os, t.arch
+		if t.arch == "amd64" && opts.targetOS() == "darwin" && runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+			if _, err := os.Stat("/Library/Apple/usr/share/rosetta/rosetta"); err != nil {
+				log.Warn("Rosetta 2 doesn't seem to be installed (see `softwareupdate --install-rosetta`)")
 			}
-			return "", errors.New("No compatible version found for " + selector +
-				"\nValid install targets: " + strings.Join(tt, ", "))
 		}
+		return ver, release, nil
 	}
-	// check whether requested version is already installed
-	if ver != nil && dst == "" {
-		local, err := Ls()
-		if err != nil {
-			return "", err
-		}
-		for _, v := range local {
-			if ver.Equals(v) {
-				return ver.String(), nil
-			}
+	if windowsOnARM && !opts.AllowEmulation {
+		if ver, _, err := resolveFor(goos, "amd64"); err == nil {
+			return nil, Release{}, fmt.Errorf("There is no native (arm64) build of %s (amd64 one (%s) can be run "+
+				"under x64 emulation, use --allow-emulation to install it)", selector, ver)
 		}
 	}
-	url := releaseMap[ver]
-	if matched, _ := regexp.MatchString("^\\w+[+]\\w+://", url); !matched {
-		return "", errors.New("URL must contain qualifier, e.g. tgz+http://...")
+	return nil, Release{}, firstErr
+}
+
+// resolveReleaseFor picks the latest recommended release in the channel matching the range
+// (or the latest matching one if there are no recommended releases in range or ignoreRecommended is true).
+func resolveReleaseFor(rng *semver.Range, selector string, os, arch string,
+	opts InstallOptions) (*semver.Version, Release, error) {
+	channel, ignoreRecommended := opts.Channel, opts.Any
+	releaseMap, err := opts.lsRemote(os, arch)
+	if err != nil {
+		return nil, Release{}, err
+	}
+	var vs = make([]*semver.Version, len(releaseMap))
+	var i = 0
+	for k := range releaseMap {
+		vs[i] = k
+		i++
 	}
-	if dst == "" {
-		dst = filepath.Join(cfg.Dir(), "jdk", ver.String())
-	} else {
-		if _, err := os.Stat(dst); !os.IsNotExist(err) {
-			if err == nil { // dst exists
-				if empty, _ := isEmptyDir(dst); !empty {
-					err = fmt.Errorf("\"%s\" is not empty", dst)
-				}
-			} // or is inaccessible
-			if err != nil {
-				return "", err
+	sort.Sort(sort.Reverse(semver.VersionSlice(vs)))
+	var latest, ea *semver.Version
+	for _, v := range vs {
+		if !inChannel(rng, v, releaseMap[v], channel) {
+			if ea == nil && (channel == "" || channel == ChannelGA) && inChannel(rng, v, releaseMap[v], ChannelEA) {
+				ea = v
 			}
+			continue
 		}
-	}
-	var fileType = url[0:strings.Index(url, "+")]
-	url = url[strings.Index(url, "+")+1:]
-	var file string
-	var deleteFileWhenFinnished bool
-	if strings.HasPrefix(url, "file://") {
-		file = strings.TrimPrefix(url, "file://")
-		if runtime.GOOS == "windows" {
-			// file:///C:/path/...
-			file = strings.Replace(strings.TrimPrefix(file, "/"), "/", "\\", -1)
+		if latest == nil {
+			latest = v
+			if ignoreRecommended {
+				break
+			}
 		}
-	} else {
-		log.Info("Downloading ", ver, " (", url, ")")
-		file, err = download(url, fileType)
-		if err != nil {
-			return "", err
+		if releaseMap[v].Recommended {
+			if v != latest {
+				log.Info("Picking ", v, " (recommended) over ", latest, " (use --any to install the latest one)")
+			}
+			return v, releaseMap[v], nil
 		}
-		deleteFileWhenFinnished = true
 	}
-	switch runtime.GOOS {
-	case "darwin":
-		err = installOnDarwin(file, fileType, dst)
-	case "linux":
-		err = installOnLinux(file, fileType, dst)
-	case "windows":
-		err = installOnWindows(file, fileType, dst)
-	default:
-		err = errors.New(runtime.GOOS + " OS is not supported")
+	if latest != nil {
+		return latest, releaseMap[latest], nil
 	}
-	if err == nil && deleteFileWhenFinnished {
-		os.Remove(file)
+	if ea != nil {
+		return nil, Release{}, fmt.Errorf("There is no GA release matching %s (%s/%s), only early-access ones "+
+			"(e.g. %s) (use --channel=ea or --include-ea to install them)", selector, os, arch, ea)
 	}
-	return ver.String(), err
+	tt := make([]string, len(vs))
+	for i, v := range vs {
+		tt[i] = v.String()
+	}
+	return nil, Release{}, errors.New("No compatible version found for " + selector + " (" + os + "/" + arch + ")" +
+		"\nValid install targets: " + strings.Join(tt, ", "))
 }
 
-func isEmptyDir(name string) (bool, error) {
-	entries, err := ioutil.ReadDir(name)
-	if err != nil {
-		return false, err
+// lsRemote lists releases either in opts.FromDir or in the index (+ vendor APIs).
+func (opts InstallOptions) lsRemote(os, arch string) (map[*semver.Version]Release, error) {
+	if opts.FromDir != "" {
+		return LsDir(opts.FromDir, os, arch)
 	}
-	return len(entries) == 0, nil
+	return LsRemote(os, arch)
 }
 
-type RedirectTracer struct {
-	Transport http.RoundTripper
+func (opts InstallOptions) targetOS() string {
+	if opts.OS != "" {
+		return opts.OS
+	}
+	return runtime.GOOS
+}
+
+// crossOS returns true if JDK is installed for OS other than the one jabba is running on.
+func (opts InstallOptions) crossOS() bool {
+	return opts.targetOS() != runtime.GOOS
 }
 
-func (self RedirectTracer) RoundTrip(req *http.Request) (resp *http.Response, err error) {
-	transport := self.Transport
-	if transport == nil {
-		transport = http.DefaultTransport
+func install(selector string, opts InstallOptions) (*InstallResult, error) {
+	plan, err := planInstall(selector, opts)
+	if err != nil {
+		return nil, err
 	}
-	resp, err = transport.RoundTrip(req)
+	if plan.AlreadyInstalled {
+		return &InstallResult{Version: plan.Version, Path: plan.Target, AlreadyInstalled: true}, nil
+	}
+	if plan.Streamed {
+		return streamInstall(plan, opts)
+	}
+	archive, err := fetchArchive(plan, opts)
 	if err != nil {
-		return
+		return nil, err
 	}
-	switch resp.StatusCode {
-	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
-		log.Debug("Following ", resp.StatusCode, " redirect to ", resp.Header.Get("Location"))
+	if err := extract(plan, archive, opts); err != nil {
+		return nil, err
 	}
-	return
+	return archive.result, nil
 }
 
-func download(url string, fileType string) (file string, err error) {
-	tmp, err := ioutil.TempFile("", "jabba-d-")
-	if err != nil {
-		return
+// fetchedArchive is a downloaded (and verified) archive that is ready to be extracted.
+type fetchedArchive struct {
+	file string
+	// true if file has to be removed once JDK is installed (i.e. it's not a local / cached file)
+	temporary bool
+	result    *InstallResult
+}
+
+// fetchArchive downloads archive (unless it's a file:// URL), verifying its checksum / signature (if specified).
+func fetchArchive(plan *InstallPlan, opts InstallOptions) (*fetchedArchive, error) {
+	if opts.Dst == "" {
+		if err := ensureWritableDir(cfg.JDKDir()); err != nil {
+			return nil, err
+		}
+	}
+	ver, url, fileType, sig, key := plan.Version, plan.URL, plan.Type, plan.Sig, plan.Key
+	var checksum string
+	if plan.SHA256 != "" {
+		checksum = "sha256=" + plan.SHA256
 	}
-	if fileType == "exe" {
-		err = tmp.Close()
+	result := &InstallResult{Version: ver, Path: plan.Target, URL: url, Type: fileType, OS: plan.OS, Arch: plan.Arch,
+		Notice: plan.Notice, startedAt: time.Now().UTC()}
+	archive := &fetchedArchive{file: plan.Archive, result: result}
+	var err error
+	if !strings.HasPrefix(url, "file://") {
+		log.Info("Downloading ", ver, " (", url, ")")
+		var cached bool
+		downloadSpan := trace.Start("download", "url", url)
+		archive.file, result.SHA256, cached, err = download(url, fileType, checksum, opts.Progress)
+		downloadSpan.End(err)
 		if err != nil {
-			return
+			return nil, err
 		}
-		err = os.Rename(tmp.Name(), tmp.Name()+".exe")
-		if err != nil {
-			return
+		archive.temporary = !cached
+	}
+	file := archive.file
+	// sha256 is calculated by download, file has to be read only if it's local
+	if result.SHA256 == "" {
+		if result.SHA256, err = sha256OfFile(file); err != nil {
+			return nil, err
 		}
-		tmp, err = os.OpenFile(tmp.Name()+".exe", os.O_RDWR, 0600)
+	}
+	if checksum != "" {
+		validateSpan := trace.Start("validate", "checksum", checksum)
+		err := verifyChecksum(result.SHA256, checksum)
+		validateSpan.End(err)
 		if err != nil {
-			return
-		}
-	}
-	defer tmp.Close()
-	file = tmp.Name()
-	log.Debug("Saving ", url, " to ", file)
-	// todo: timeout
-	client := http.Client{Transport: RedirectTracer{}}
-	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		if len(via) >= 10 {
-			return fmt.Errorf("too many redirects")
-		}
-		if len(via) != 0 {
-			// https://github.com/golang/go/issues/4800
-			for attr, val := range via[0].Header {
-				if _, ok := req.Header[attr]; !ok {
-					req.Header[attr] = val
-				}
+			if !strings.HasPrefix(url, "file://") {
+				// so that the next attempt wouldn't pick it up
+				removeDownload(file)
 			}
+			return nil, fmt.Errorf("%s (%s)", err, url)
 		}
-		return nil
+		result.ChecksumVerified = true
 	}
-	req, err := http.NewRequest("GET", url, nil)
-	if strings.Contains(url, "zulu") {
-		req.Header.Set("Referer", "http://www.azul.com/downloads/zulu/")
+	if sig != "" {
+		validateSpan := trace.Start("validate", "signature", sig)
+		result.Signer, err = verifySignature(file, url, sig, key)
+		validateSpan.End(err)
+		if err != nil {
+			if !strings.HasPrefix(url, "file://") {
+				removeDownload(file)
+			}
+			return nil, fmt.Errorf("%s (%s)", err, url)
+		}
+		log.Info("Signature verified (", result.Signer, ")")
 	}
-	req.Header.Set("Cookie", "oraclelicense=accept-securebackup-cookie")
-	res, err := client.Do(req)
+	return archive, nil
+}
+
+// extract installs JDK from the fetched archive into plan.Target.
+func extract(plan *InstallPlan, archive *fetchedArchive, opts InstallOptions) error {
+	file, fileType := archive.file, plan.Type
+	err := stage(plan, opts, func(target string) error {
+		return installOn(opts.targetOS(), file, fileType, target)
+	})
 	if err != nil {
+		if _, corrupt := err.(*CorruptArchiveError); corrupt && !strings.HasPrefix(plan.URL, "file://") {
+			// so that the next attempt would download it again (instead of resuming / reusing cached copy)
+			removeDownload(file)
+		}
+		return err
+	}
+	if archive.temporary {
+		os.Remove(file)
+	}
+	recordManagedInstall(archive.result, opts)
+	return nil
+}
+
+// stage calls unpack with the directory JDK should be put into & moves it to plan.Target once unpack succeeds.
+// JDK is extracted into $JABBA_HOME/jdk/.staging/<version> and moved into place only after it has been validated
+// (so that interrupted install wouldn't leave behind half-populated directory that looks like installed JDK).
+func stage(plan *InstallPlan, opts InstallOptions, unpack func(target string) error) (err error) {
+	dst := plan.Target
+	target := dst
+	if opts.Dst == "" {
+		var lock *flock.Lock
+		if target, lock, err = stagingDir(plan.Version); err != nil {
+			return err
+		}
+		defer lock.UnlockAndRemove()
+	}
+	if err := preflight(filepath.Dir(target), plan.Type, plan.Archive); err != nil {
+		return err
+	}
+	extractSpan := trace.Start("extract", "type", plan.Type, "destination", target)
+	err = unpack(target)
+	if err == nil && !opts.NoPostInstall {
+		if err = postInstall(cfg.PostInstall(), target, plan.Version, opts.targetOS()); err != nil {
+			os.RemoveAll(target)
+		}
+	}
+	if err == nil && target != dst {
+		log.Debugf("Moving %s to %s", target, dst)
+		invalidateState()
+		if err = rename(target, dst); err != nil {
+			os.RemoveAll(target)
+		}
+	}
+	extractSpan.End(err)
+	return err
+}
+
+func recordManagedInstall(result *InstallResult, opts InstallOptions) {
+	if opts.Dst != "" {
+		return
+	}
+	// before metadata is recorded (so that archive is accounted for by `jabba verify`)
+	cds := generateCDSOnInstall(result, opts)
+	if err := recordInstall(result, cds); err != nil {
+		log.Warn("Failed to record metadata of ", result.Version, " (", err, "). `jabba verify` won't be available")
 		return
 	}
-	defer res.Body.Close()
-	progressTracker := &ioprogress.Reader{
-		Reader: res.Body,
-		Size:   res.ContentLength,
+	addComponentsOnInstall(result, opts)
+	dedupeOnInstall(result.Version)
+}
+
+// stagingDir returns (empty) $JABBA_HOME/jdk/.staging/<version>, locked (see CleanStaging) until lock is released.
+func stagingDir(ver string) (string, *flock.Lock, error) {
+	dir := filepath.Join(cfg.JDKDir(), ".staging", ver)
+	if err := mkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return "", nil, err
 	}
-	_, err = io.Copy(tmp, progressTracker)
+	lock := flock.New(dir + ".lock")
+	if err := lock.Lock(); err != nil {
+		return "", nil, err
+	}
+	// leftovers of the previous (interrupted) attempt
+	for _, path := range []string{dir, dir + "~"} {
+		if err := removeAll(path); err != nil {
+			lock.UnlockAndRemove()
+			return "", nil, err
+		}
+	}
+	return dir, lock, nil
+}
+
+// staging directories older than this are considered to be abandoned (jabba got killed mid-install)
+const staleStagingAge = time.Hour
+
+// CleanStaging removes stale entries from $JABBA_HOME/jdk/.staging (entries of installs that are still in progress
+// (JDK dir might be shared by multiple jabba homes) are locked (see stagingDir)).
+func CleanStaging() error {
+	dir := filepath.Join(cfg.JDKDir(), ".staging")
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
 	}
-	return
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".lock") || time.Since(f.ModTime()) < staleStagingAge {
+			continue
+		}
+		path := filepath.Join(dir, f.Name())
+		lock := flock.New(strings.TrimSuffix(path, "~") + ".lock")
+		locked, err := lock.TryLock()
+		if err != nil {
+			return err
+		}
+		if !locked {
+			log.Debug("Keeping ", path, " (install is in progress)")
+			continue
+		}
+		log.Debug("Removing stale ", path)
+		err = os.RemoveAll(path)
+		lock.UnlockAndRemove()
+		if err != nil {
+			return err
+		}
+	}
+	// fails unless empty
+	os.Remove(dir)
+	return nil
 }
 
-func installOnDarwin(file string, fileType string, dst string) (err error) {
+// isInstaller returns true if fileType requires running vendor's installer (as opposed to extracting an archive).
+func isInstaller(fileType string) bool {
 	switch fileType {
-	case "dmg":
-		err = installFromDmg(file, dst)
-	case "tgz":
-		err = installFromTgz(file, dst)
-	case "tgx":
-		err = installFromTgx(file, dst)
-	case "zip":
-		err = installFromZip(file, dst)
-	default:
-		return errors.New(fileType + " is not supported")
-	}
-	if err == nil {
-		err = normalizePathToBinJava(dst, runtime.GOOS)
+	case "dmg", "exe", "bin", "ia":
+		return true
 	}
+	return false
+}
+
+func isEmptyDir(name string) (bool, error) {
+	entries, err := ioutil.ReadDir(name)
 	if err != nil {
-		os.RemoveAll(dst)
+		return false, err
 	}
-	return
+	return len(entries) == 0, nil
 }
 
 // **/{Contents/Home,Home,}bin/java -> <dir>/Contents/Home/bin/java
@@ -272,7 +512,7 @@ func normalizePathToBinJava(dir string, goos string) error {
 			tmp := dir + "~"
 			javaPath = strings.Replace(javaPath, dir, tmp, 1)
 			log.Debugf("Moving %s to %s", dir, tmp)
-			if err := os.Rename(dir, tmp); err != nil {
+			if err := rename(dir, tmp); err != nil {
 				return err
 			}
 			defer func() {
@@ -294,10 +534,10 @@ func normalizePathToBinJava(dir string, goos string) error {
 				dst = dir
 			}
 			log.Debugf("Moving %s to %s", src, dst)
-			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			if err := mkdirAll(filepath.Dir(dst), 0755); err != nil {
 				return err
 			}
-			if err = os.Rename(src, dst); err != nil {
+			if err = rename(src, dst); err != nil {
 				return err
 			}
 		}
@@ -330,123 +570,123 @@ func assertJavaDistribution(dir string, goos string) error {
 }
 
 func installFromDmg(src string, dst string) error {
-	tmp, err := ioutil.TempDir("", "jabba-i-")
+	tmp, release, err := mkTempDir("jabba-i-")
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(tmp)
-	srcName := filepath.Base(src)
-	pkgdir := tmp + "/" + srcName + "-pkg"
-	mountpoint := tmp + "/" + srcName
+	defer release()
+	mountpoint := filepath.Join(tmp, filepath.Base(src))
 	log.Info("Mounting " + src)
-	err = sh(fmt.Sprintf(`hdiutil mount -mountpoint "%s" "%s"`, mountpoint, src))
+	err = runCmd(exec.Command("hdiutil", "attach", "-nobrowse", "-readonly", "-mountpoint", mountpoint, src))
 	if err != nil {
 		return err
 	}
 	defer func() {
 		log.Info("Unmounting " + mountpoint)
-		sh(fmt.Sprintf(`hdiutil unmount "%s"`, mountpoint))
+		runCmd(exec.Command("hdiutil", "detach", mountpoint))
 	}()
-	log.Info("Extracting " + mountpoint + "/*.pkg")
-	err = sh(fmt.Sprintf(`pkgutil --expand "%s"/*.pkg "%s"`, mountpoint, pkgdir))
-	if err == nil {
-		pathToPayload := ""
-		// fixme: find a better way
-		var payloadSize int64
-		for it := fileiter.New(pkgdir, fileiter.BreadthFirst()); it.Next(); {
-			if !it.IsDir() && it.Name() == "Payload" {
-				path := filepath.Join(it.Dir(), it.Name())
-				stat, err := os.Stat(path)
-				if err != nil {
-					return err
-				}
-				if payloadSize < stat.Size() { // pick largest (e.g. among javaappletplugin.pkg & jdk180191.pkg)
-					pathToPayload = path
-					payloadSize = stat.Size()
-				}
-			}
+	pkg, err := findPkg(mountpoint)
+	if err != nil {
+		return err
+	}
+	log.Info("Extracting " + pkg + " to " + dst)
+	if stat, err := os.Stat(pkg); err == nil && stat.IsDir() {
+		return unpkgBundle(pkg, dst)
+	}
+	return unpkg(pkg, dst)
+}
+
+// findPkg returns the largest .pkg on the (mounted) dmg (e.g. jdk180191.pkg rather than javaappletplugin.pkg).
+// Both flat packages (files) and bundles (directories) are considered.
+func findPkg(dir string) (string, error) {
+	var pkg string
+	var pkgSize int64
+	for it := fileiter.New(dir, fileiter.BreadthFirst()); it.Next(); {
+		if !strings.HasSuffix(it.Name(), ".pkg") {
+			continue
+		}
+		path := filepath.Join(it.Dir(), it.Name())
+		var size int64
+		if it.IsDir() {
+			it.SkipDir()
+			size = diskUsage(path)
+		} else if stat, err := os.Stat(path); err == nil {
+			size = stat.Size()
 		}
-		if pathToPayload != "" {
-			log.Info("Extracting " + pathToPayload)
-			err = sh(fmt.Sprintf(`mkdir -p "%s" && cd "%s" && gzip -dc "%s" | cpio -i`, dst, dst, pathToPayload))
+		if pkg == "" || pkgSize < size {
+			pkg, pkgSize = path, size
 		}
 	}
-	return err
+	if pkg == "" {
+		return "", fmt.Errorf("no .pkg found in %s", dir)
+	}
+	return pkg, nil
 }
 
-func installOnLinux(file string, fileType string, dst string) (err error) {
-	switch fileType {
-	case "bin":
-		err = installFromBin(file, dst)
-	case "ia":
-		err = installFromIa(file, dst)
-	case "tgz":
-		err = installFromTgz(file, dst)
-	case "tgx":
-		err = installFromTgx(file, dst)
-	case "zip":
-		err = installFromZip(file, dst)
-	default:
-		return errors.New(fileType + " is not supported")
-	}
-	if err == nil {
-		err = normalizePathToBinJava(dst, runtime.GOOS)
+// unpkgBundle extracts bundle-style package (<name>.pkg/Contents/Archive.pax.gz (gzip-compressed cpio)).
+func unpkgBundle(pkg string, dst string) error {
+	src := filepath.Join(pkg, "Contents", "Archive.pax.gz")
+	file, err := os.Open(src)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
+	cr, err := decompressPayload(bufio.NewReader(file))
 	if err != nil {
-		os.RemoveAll(dst)
+		return &CorruptArchiveError{src, err}
 	}
-	return
+	return uncpio(src, corruptOnError{cr, src}, dst)
 }
 
-func installOnWindows(file string, fileType string, dst string) (err error) {
-	switch fileType {
-	case "exe":
-		err = installFromExe(file, dst)
-	case "tgz":
-		err = installFromTgz(file, dst)
-	case "tgx":
-		err = installFromTgx(file, dst)
-	case "zip":
-		err = installFromZip(file, dst)
-	default:
-		return errors.New(fileType + " is not supported")
+func installFromBin(src string, dst string) error {
+	tmp, release, err := mkTempDir("jabba-i-")
+	if err != nil {
+		return err
 	}
-	if err == nil {
-		err = normalizePathToBinJava(dst, runtime.GOOS)
+	defer release()
+	if src, err = filepath.Abs(src); err != nil {
+		return err
 	}
-	if err != nil {
-		os.RemoveAll(dst)
+	log.Info("Extracting " + src + " to " + dst)
+	// self-extracting archive unpacks itself into the working directory (after waiting for the "Enter")
+	cmd := exec.Command("sh", src)
+	cmd.Dir = tmp
+	cmd.Stdin = strings.NewReader("\n")
+	if err := runCmd(cmd); err != nil {
+		return err
 	}
-	return
-}
-
-func installFromBin(src string, dst string) (err error) {
-	tmp, err := ioutil.TempDir("", "jabba-i-")
+	entries, err := ioutil.ReadDir(tmp)
 	if err != nil {
-		return
+		return err
 	}
-	defer os.RemoveAll(tmp)
-	err = sh("cp " + src + " " + tmp)
-	if err == nil {
-		log.Info("Extracting " + filepath.Join(tmp, filepath.Base(src)) + " to " + dst)
-		err = sh("cd " + tmp + " && echo | sh " + filepath.Base(src) + " && mv jdk*/ " + dst)
+	var jdk string
+	for _, e := range entries {
+		if e.IsDir() && (jdk == "" || strings.HasPrefix(e.Name(), "jdk")) {
+			jdk = e.Name()
+		}
+	}
+	if jdk == "" {
+		return fmt.Errorf("%s didn't extract anything", src)
 	}
-	return
+	// custom destination (--output) is allowed to exist as long as it's empty
+	os.Remove(dst)
+	return rename(filepath.Join(tmp, jdk), dst)
 }
 
 func installFromIa(src string, dst string) error {
-	tmp, err := ioutil.TempDir("", "jabba-i-")
+	tmp, release, err := mkTempDir("jabba-i-")
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(tmp)
-	err = sh("printf 'LICENSE_ACCEPTED=TRUE\\nUSER_INSTALL_DIR=" + dst + "' > " +
-		filepath.Join(tmp, "installer.properties"))
-	if err == nil {
-		log.Info("Extracting " + src + " to " + dst)
-		err = sh("echo | sh " + src + " -i silent -f " + filepath.Join(tmp, "installer.properties"))
+	defer release()
+	properties := filepath.Join(tmp, "installer.properties")
+	if err := ioutil.WriteFile(properties, []byte("LICENSE_ACCEPTED=TRUE\nUSER_INSTALL_DIR="+dst), 0644); err != nil {
+		return err
 	}
-	return err
+	log.Info("Extracting " + src + " to " + dst)
+	cmd := exec.Command("sh", src, "-i", "silent", "-f", properties)
+	cmd.Stdin = strings.NewReader("\n")
+	return runCmd(cmd)
 }
 
 func installFromExe(src string, dst string) error {
@@ -456,189 +696,145 @@ func installFromExe(src string, dst string) error {
 		"\" STATIC=1 AUTO_UPDATE=0 WEB_JAVA=0 WEB_ANALYTICS=0 REBOOT=0", "", 3)
 }
 
+// tarDecompressors maps types of tar archives to the respective decompressors.
+// Decompressor has to be closed once it's no longer needed (zstd decoder reads ahead in the background).
+var tarDecompressors = map[string]func(io.Reader) (io.ReadCloser, error){
+	"tgz": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"tgx": func(r io.Reader) (io.ReadCloser, error) {
+		xr, err := xz.NewReader(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(xr), nil
+	},
+	"txz": func(r io.Reader) (io.ReadCloser, error) {
+		xr, err := xz.NewReader(r, 0)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(xr), nil
+	},
+	"tzst": func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
 func installFromTgz(src string, dst string) error {
 	log.Info("Extracting " + src + " to " + dst)
 	return untgz(src, dst, true)
 }
 
 func untgz(src string, dst string, strip bool) error {
-	gzFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer gzFile.Close()
+	return untar(src, dst, strip, tarDecompressors["tgz"])
+}
+
+func installFromTgx(src string, dst string) error {
+	log.Info("Extracting " + src + " to " + dst)
+	return untgx(src, dst, true)
+}
+
+func untgx(src string, dst string, strip bool) error {
+	return untar(src, dst, strip, tarDecompressors["tgx"])
+}
+
+// untar extracts (compressed) tar archive into dst, preserving file modes and symlinks.
+// If strip is true, the longest directory prefix common to all the files is removed
+// (same as "tar --strip-components=N" but without having to know N in advance).
+func untar(src string, dst string, strip bool, decompress func(io.Reader) (io.ReadCloser, error)) error {
 	var prefixToStrip string
 	if strip {
-		gzr, err := gzip.NewReader(gzFile)
-		if err != nil {
+		var err error
+		if prefixToStrip, err = tarPrefix(src, decompress); err != nil {
 			return err
 		}
-		defer gzr.Close()
-		r := tar.NewReader(gzr)
-		var prefix []string
-		for {
-			header, err := r.Next()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return err
-			}
-			var dir string
-			if header.Typeflag != tar.TypeDir {
-				dir = filepath.Dir(header.Name)
-			} else {
-				continue
-			}
-			if prefix != nil {
-				dirSplit := strings.Split(dir, string(filepath.Separator))
-				i, e, dse := 0, len(prefix), len(dirSplit)
-				if dse < e {
-					e = dse
-				}
-				for i < e {
-					if prefix[i] != dirSplit[i] {
-						prefix = prefix[0:i]
-						break
-					}
-					i++
-				}
-			} else {
-				prefix = strings.Split(dir, string(filepath.Separator))
-			}
-		}
-		prefixToStrip = strings.Join(prefix, string(filepath.Separator))
 	}
-	gzFile.Seek(0, 0)
-	gzr, err := gzip.NewReader(gzFile)
+	file, err := os.Open(src)
 	if err != nil {
 		return err
 	}
-	defer gzr.Close()
-	r := tar.NewReader(gzr)
-	dirCache := make(map[string]bool) // todo: radix tree would perform better here
-	if err := os.MkdirAll(dst, 0755); err != nil {
+	defer file.Close()
+	cr, err := decompress(file)
+	if err != nil {
 		return err
 	}
+	defer cr.Close()
+	return extractTar(src, cr, dst, prefixToStrip)
+}
+
+// tarPrefix returns the longest directory prefix common to all the files of (compressed) tar archive.
+func tarPrefix(src string, decompress func(io.Reader) (io.ReadCloser, error)) (string, error) {
+	// (opened separately from the file that is then extracted (decompressor might still be reading ahead))
+	file, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	cr, err := decompress(file)
+	if err != nil {
+		return "", err
+	}
+	defer cr.Close()
+	r := tar.NewReader(cr)
+	var prefix []string
 	for {
 		header, err := r.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return err
-		}
-		var dir string
-		if header.Typeflag != tar.TypeDir {
-			dir = filepath.Dir(header.Name)
-		} else {
-			dir = filepath.Clean(header.Name)
-			if !strings.HasPrefix(dir, prefixToStrip) {
-				continue
-			}
+			return "", err
 		}
-		dir = strings.TrimPrefix(dir, prefixToStrip)
-		if dir != "" && dir != "." {
-			cached := dirCache[dir]
-			if !cached {
-				if err := os.MkdirAll(filepath.Join(dst, dir), 0755); err != nil {
-					return err
-				}
-				dirCache[dir] = true
-			}
+		if header.Typeflag == tar.TypeDir || !isExtractedTarEntry(header.Typeflag) {
+			continue
 		}
-		target := filepath.Join(dst, dir, filepath.Base(header.Name))
-		switch header.Typeflag {
-		case tar.TypeReg:
-			d, err := os.OpenFile(target,
-				os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode|0600)&0777)
-			if err != nil {
-				return err
-			}
-			_, err = io.Copy(d, r)
-			d.Close()
-			if err != nil {
-				return err
+		dir := filepath.Dir(header.Name)
+		if prefix != nil {
+			dirSplit := strings.Split(dir, string(filepath.Separator))
+			i, e, dse := 0, len(prefix), len(dirSplit)
+			if dse < e {
+				e = dse
 			}
-		case tar.TypeSymlink:
-			if err = os.Symlink(header.Linkname, target); err != nil {
-				return err
+			for i < e && prefix[i] == dirSplit[i] {
+				i++
 			}
+			prefix = prefix[0:i]
+		} else {
+			prefix = strings.Split(dir, string(filepath.Separator))
 		}
 	}
-	return nil
+	return strings.Join(prefix, string(filepath.Separator)), nil
 }
 
-func installFromTgx(src string, dst string) error {
-	log.Info("Extracting " + src + " to " + dst)
-	return untgx(src, dst, true)
-}
-
-func untgx(src string, dst string, strip bool) error {
-	xzFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer xzFile.Close()
-	var prefixToStrip string
-	if strip {
-		xzr, err := xz.NewReader(xzFile, 0)
-		if err != nil {
-			return err
-		}
-		r := tar.NewReader(xzr)
-		var prefix []string
-		for {
-			header, err := r.Next()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				return err
-			}
-			var dir string
-			if header.Typeflag != tar.TypeDir {
-				dir = filepath.Dir(header.Name)
-			} else {
-				continue
-			}
-			if prefix != nil {
-				dirSplit := strings.Split(dir, string(filepath.Separator))
-				i, e, dse := 0, len(prefix), len(dirSplit)
-				if dse < e {
-					e = dse
-				}
-				for i < e {
-					if prefix[i] != dirSplit[i] {
-						prefix = prefix[0:i]
-						break
-					}
-					i++
-				}
-			} else {
-				prefix = strings.Split(dir, string(filepath.Separator))
-			}
-		}
-		prefixToStrip = strings.Join(prefix, string(filepath.Separator))
-	}
-	xzFile.Seek(0, 0)
-	xzr, err := xz.NewReader(xzFile, 0)
-	if err != nil {
-		return err
-	}
-	r := tar.NewReader(xzr)
+// extractTar extracts tar stream r (of the archive src) into dst, removing prefixToStrip from the paths.
+// Entries that would end up outside of dst (e.g. "../x" or "x" after "x -> /etc" symlink) are rejected.
+func extractTar(src string, r io.Reader, dst string, prefixToStrip string) error {
+	tr := tar.NewReader(r)
 	dirCache := make(map[string]bool) // todo: radix tree would perform better here
-	if err := os.MkdirAll(dst, 0755); err != nil {
+	// symlinks created so far (relative to dst)
+	symlinks := make(map[string]bool)
+	// directory modes are applied once extraction is complete (otherwise read-only dirs couldn't be populated)
+	dirModes := make(map[string]os.FileMode)
+	if err := mkdirAll(dst, 0755); err != nil {
 		return err
 	}
 	for {
-		header, err := r.Next()
+		header, err := tr.Next()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return err
 		}
+		if header.Typeflag != tar.TypeDir && !isExtractedTarEntry(header.Typeflag) {
+			log.Debug("Skipping ", header.Name, " (type ", string(header.Typeflag), ")")
+			continue
+		}
 		var dir string
 		if header.Typeflag != tar.TypeDir {
 			dir = filepath.Dir(header.Name)
@@ -649,10 +845,14 @@ func untgx(src string, dst string, strip bool) error {
 			}
 		}
 		dir = strings.TrimPrefix(dir, prefixToStrip)
+		relDir, err := entryDir(src, header.Name, dir, symlinks)
+		if err != nil {
+			return err
+		}
 		if dir != "" && dir != "." {
 			cached := dirCache[dir]
 			if !cached {
-				if err := os.MkdirAll(filepath.Join(dst, dir), 0755); err != nil {
+				if err := mkdirAll(filepath.Join(dst, dir), 0755); err != nil {
 					return err
 				}
 				dirCache[dir] = true
@@ -660,37 +860,199 @@ func untgx(src string, dst string, strip bool) error {
 		}
 		target := filepath.Join(dst, dir, filepath.Base(header.Name))
 		switch header.Typeflag {
-		case tar.TypeReg:
-			d, err := os.OpenFile(target,
-				os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(header.Mode|0600)&0777)
+		case tar.TypeDir:
+			if dir != "" && dir != "." {
+				dirModes[filepath.Join(dst, dir)] = os.FileMode(header.Mode|0700) & 0777
+			}
+		case tar.TypeReg, tar.TypeRegA, tar.TypeGNUSparse:
+			d, err := createFile(target, os.FileMode(header.Mode|0600)&0777)
 			if err != nil {
 				return err
 			}
-			_, err = io.Copy(d, r)
+			_, err = io.Copy(d, tr)
 			d.Close()
 			if err != nil {
 				return err
 			}
+			// OpenFile is subject to umask
+			if err := chmod(target, os.FileMode(header.Mode|0600)&0777); err != nil {
+				return err
+			}
 		case tar.TypeSymlink:
 			if err = os.Symlink(header.Linkname, target); err != nil {
 				return err
 			}
+			symlinks[filepath.Join(relDir, filepath.Base(header.Name))] = true
+		case tar.TypeLink:
+			// Linkname is a path of the file (extracted before) within the archive
+			linkname := filepath.Clean(header.Linkname)
+			if !strings.HasPrefix(linkname, prefixToStrip) || filepath.IsAbs(linkname) {
+				return &CorruptArchiveError{File: src,
+					Err: fmt.Errorf("%s links to %s, which is outside of the archive", header.Name, header.Linkname)}
+			}
+			// (neither linked file nor any of its parents can be a symlink)
+			rel, err := entryDir(src, header.Linkname, strings.TrimPrefix(linkname, prefixToStrip), symlinks)
+			if err != nil {
+				return err
+			}
+			source := filepath.Join(dst, rel)
+			if err := hardlink(source, target); err != nil {
+				return err
+			}
+		}
+	}
+	for dir, mode := range dirModes {
+		if err := chmod(dir, mode); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// isExtractedTarEntry returns true if (non-directory) entry of that type ends up on disk.
+// PAX global headers (e.g. pax_global_header added by `git archive`) are returned by tar.Reader as entries too (PAX
+// extended headers & GNU long names/links are not (they are merged into the entry they precede)), same as device
+// files & FIFOs, neither of which have any place in JDK archive.
+func isExtractedTarEntry(typeflag byte) bool {
+	switch typeflag {
+	case tar.TypeReg, tar.TypeRegA, tar.TypeGNUSparse, tar.TypeSymlink, tar.TypeLink:
+		return true
+	}
+	return false
+}
+
+// entryDir returns dir (of the archive entry, with prefix stripped) relative to the extraction root.
+// Entries that would end up outside of it ("../x" or "x/y" after "x -> /etc" symlink) make archive corrupt.
+func entryDir(src string, name string, dir string, symlinks map[string]bool) (string, error) {
+	// e.g. "/bin" (after "jdk" is stripped from "jdk/bin") -> "bin"
+	relDir := filepath.Clean(strings.TrimPrefix(dir, string(filepath.Separator)))
+	if relDir == ".." || strings.HasPrefix(relDir, ".."+string(filepath.Separator)) || filepath.IsAbs(relDir) {
+		return "", &CorruptArchiveError{File: src, Err: fmt.Errorf("%s points outside of the archive", name)}
+	}
+	for d := relDir; d != "."; d = filepath.Dir(d) {
+		if symlinks[d] {
+			return "", &CorruptArchiveError{File: src, Err: fmt.Errorf("%s is inside of %s, which is a symlink", name, d)}
+		}
+	}
+	return relDir, nil
+}
+
+// createFile creates target (of the archive entry) for writing. Whatever is already there (e.g. "x -> /etc/passwd"
+// symlink of the earlier entry with the same name) is replaced rather than written through.
+func createFile(target string, perm os.FileMode) (*os.File, error) {
+	if stat, err := os.Lstat(target); err == nil && !stat.IsDir() {
+		if err := os.Remove(target); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+}
+
+// hardlink links target to source (copying source if file system does not support hard links).
+// Source must be a regular file.
+func hardlink(source string, target string) error {
+	stat, err := os.Lstat(source)
+	if err != nil {
+		return err
+	}
+	if !stat.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", source)
+	}
+	os.Remove(target)
+	err = os.Link(source, target)
+	if err == nil {
+		return nil
+	}
+	log.Debug("Unable to create hard link (", err, "), copying ", source, " to ", target)
+	s, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	d, err := createFile(target, stat.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(d, s); err != nil {
+		d.Close()
+		return err
+	}
+	if err := d.Close(); err != nil {
+		return err
+	}
+	return chmod(target, stat.Mode())
+}
+
+func installFromTzst(src string, dst string) error {
+	log.Info("Extracting " + src + " to " + dst)
+	return untzst(src, dst, true)
+}
+
+func untzst(src string, dst string, strip bool) error {
+	return untar(src, dst, strip, tarDecompressors["tzst"])
+}
+
 func installFromZip(src string, dst string) error {
 	log.Info("Extracting " + src + " to " + dst)
 	return unzip(src, dst, true)
 }
 
+// CorruptArchiveError means that archive (as opposed to, e.g., the disk it's being extracted to) is broken.
+type CorruptArchiveError struct {
+	File string
+	Err  error
+}
+
+func (e *CorruptArchiveError) Error() string {
+	return fmt.Sprintf("%s is corrupt (%v)", e.File, e.Err)
+}
+
+// corruptOnError wraps read errors (other than io.EOF) into CorruptArchiveError.
+type corruptOnError struct {
+	r    io.Reader
+	file string
+}
+
+func (c corruptOnError) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = &CorruptArchiveError{c.file, err}
+	}
+	return n, err
+}
+
+// validateZip checks that every entry listed in the central directory (zip64 included) has a valid local
+// header and fits within the archive (so that extraction wouldn't fail half way through).
+func validateZip(src string, r *zip.Reader, size int64) error {
+	for _, f := range r.File {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return &CorruptArchiveError{src, fmt.Errorf("%s: %v", f.Name, err)}
+		}
+		if offset < 0 || f.CompressedSize64 > uint64(size) || uint64(offset) > uint64(size)-f.CompressedSize64 {
+			return &CorruptArchiveError{src, fmt.Errorf("%s: entry extends beyond the end of the archive", f.Name)}
+		}
+	}
+	return nil
+}
+
 func unzip(src string, dst string, strip bool) error {
-	r, err := zip.OpenReader(src)
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	r, err := zip.NewReader(file, stat.Size())
 	if err != nil {
+		return &CorruptArchiveError{src, err}
+	}
+	if err := validateZip(src, r, stat.Size()); err != nil {
 		return err
 	}
-	defer r.Close()
 	var prefixToStrip string
 	if strip {
 		var prefix []string
@@ -707,13 +1069,10 @@ func unzip(src string, dst string, strip bool) error {
 				if dse < e {
 					e = dse
 				}
-				for i < e {
-					if prefix[i] != dirSplit[i] {
-						prefix = prefix[0:i]
-						break
-					}
+				for i < e && prefix[i] == dirSplit[i] {
 					i++
 				}
+				prefix = prefix[0:i]
 			} else {
 				prefix = strings.Split(dir, string(filepath.Separator))
 			}
@@ -721,12 +1080,17 @@ func unzip(src string, dst string, strip bool) error {
 		prefixToStrip = strings.Join(prefix, string(filepath.Separator))
 	}
 	dirCache := make(map[string]bool) // todo: radix tree would perform better here
-	if err := os.MkdirAll(dst, 0755); err != nil {
+	// symlinks created so far (relative to dst)
+	symlinks := make(map[string]bool)
+	// directory modes are applied once extraction is complete (otherwise read-only dirs couldn't be populated)
+	dirModes := make(map[string]os.FileMode)
+	if err := mkdirAll(dst, 0755); err != nil {
 		return err
 	}
 	for _, f := range r.File {
+		mode, unix := zipMode(f)
 		var dir string
-		if !f.Mode().IsDir() {
+		if !mode.IsDir() {
 			dir = filepath.Dir(f.Name)
 		} else {
 			dir = filepath.Clean(f.Name)
@@ -735,47 +1099,113 @@ func unzip(src string, dst string, strip bool) error {
 			}
 		}
 		dir = strings.TrimPrefix(dir, prefixToStrip)
+		relDir, err := entryDir(src, f.Name, dir, symlinks)
+		if err != nil {
+			return err
+		}
 		if dir != "" && dir != "." {
 			cached := dirCache[dir]
 			if !cached {
-				if err := os.MkdirAll(filepath.Join(dst, dir), 0755); err != nil {
+				if err := mkdirAll(filepath.Join(dst, dir), 0755); err != nil {
 					return err
 				}
 				dirCache[dir] = true
 			}
 		}
-		if !f.Mode().IsDir() {
-			name := filepath.Base(f.Name)
-			fr, err := f.Open()
+		target := filepath.Join(dst, dir, filepath.Base(f.Name))
+		switch {
+		case mode.IsDir():
+			if unix && dir != "" && dir != "." {
+				dirModes[filepath.Join(dst, dir)] = (mode | 0700) & 0777
+			}
+		case mode&os.ModeSymlink != 0:
+			// link target is the content of the entry
+			linkname, err := readZipEntry(src, f)
 			if err != nil {
 				return err
 			}
-			d, err := os.OpenFile(filepath.Join(dst, dir, name),
-				os.O_WRONLY|os.O_CREATE|os.O_TRUNC, (f.Mode()|0600)&0777)
+			if err := os.Symlink(string(linkname), target); err != nil {
+				return err
+			}
+			symlinks[filepath.Join(relDir, filepath.Base(f.Name))] = true
+		default:
+			if !unix {
+				// archive doesn't say whether file is executable (e.g. zip was created on Windows)
+				mode = 0644
+				if isJDKExecutable(filepath.Join(relDir, filepath.Base(f.Name))) {
+					mode = 0755
+				}
+			}
+			fr, err := f.Open()
+			if err != nil {
+				return &CorruptArchiveError{src, fmt.Errorf("%s: %v", f.Name, err)}
+			}
+			d, err := createFile(target, (mode|0600)&0777)
 			if err != nil {
+				fr.Close()
 				return err
 			}
-			_, err = io.Copy(d, fr)
+			// crc32 is checked once entry is read in full
+			_, err = io.Copy(d, corruptOnError{fr, src})
+			fr.Close()
 			d.Close()
 			if err != nil {
 				return err
 			}
+			// OpenFile is subject to umask
+			if err := chmod(target, (mode|0600)&0777); err != nil {
+				return err
+			}
+		}
+	}
+	for dir, mode := range dirModes {
+		if err := chmod(dir, mode); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
-func sh(cmd string) error {
-	var execArg []string
-	if runtime.GOOS == "windows" {
-		execArg = []string{"cmd", "/C"}
-	} else {
-		execArg = []string{"sh", "-c"}
+// zipMode returns mode of the zip entry (unix is false if archive doesn't carry unix permissions).
+// Unlike zip.File.Mode, unix mode (upper 16 bits of external attributes) is honored even if archive claims to be
+// created on a system without one (which is what some of the tools used to package JDKs do).
+func zipMode(f *zip.File) (mode os.FileMode, unix bool) {
+	const creatorUnix, creatorMacOSX = 3, 19
+	mode = f.Mode()
+	attrs := f.ExternalAttrs >> 16
+	switch creator := f.CreatorVersion >> 8; {
+	case creator == creatorUnix || creator == creatorMacOSX:
+		return mode, attrs != 0
+	case attrs == 0:
+		return mode, false
+	}
+	const sIFMT, sIFDIR, sIFLNK = 0xf000, 0x4000, 0xa000
+	mode = os.FileMode(attrs & 0777)
+	switch {
+	case attrs&sIFMT == sIFLNK:
+		mode |= os.ModeSymlink
+	case attrs&sIFMT == sIFDIR || strings.HasSuffix(f.Name, "/"):
+		mode |= os.ModeDir
+	}
+	return mode, true
+}
+
+// isJDKExecutable tells whether file (relative to JDK root) is expected to be executable
+// (e.g. bin/java, lib/jspawnhelper).
+func isJDKExecutable(file string) bool {
+	file = filepath.ToSlash(file)
+	switch path.Base(file) {
+	case "jspawnhelper", "jexec":
+		return true
 	}
-	out, err := exec.Command(execArg[0], execArg[1], cmd).CombinedOutput()
+	return path.Base(path.Dir(file)) == "bin" && !strings.HasSuffix(file, ".dll")
+}
+
+func readZipEntry(src string, f *zip.File) ([]byte, error) {
+	fr, err := f.Open()
 	if err != nil {
-		log.Error(string(out))
-		return errors.New("'" + cmd + "' failed: " + err.Error())
+		return nil, &CorruptArchiveError{src, fmt.Errorf("%s: %v", f.Name, err)}
 	}
-	return nil
+	defer fr.Close()
+	return ioutil.ReadAll(corruptOnError{fr, src})
 }
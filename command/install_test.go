@@ -1,10 +1,22 @@
 package command
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/command/flock"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestBinJavaRelocation(t *testing.T) {
@@ -79,6 +91,256 @@ func TestBinJavaRelocation(t *testing.T) {
 	}
 }
 
+func TestUntar(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "install_test")
+	ok(err)
+	defer os.RemoveAll(dir)
+	for _, scenario := range []struct {
+		ext      string
+		compress func(io.Writer) (io.WriteCloser, error)
+		extract  func(src string, dst string, strip bool) error
+	}{
+		{
+			ext:      "tar.gz",
+			compress: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+			extract:  untgz,
+		},
+		{
+			ext:      "tar.zst",
+			compress: func(w io.Writer) (io.WriteCloser, error) { return zstd.NewWriter(w) },
+			extract:  untzst,
+		},
+	} {
+		src := filepath.Join(dir, "jdk."+scenario.ext)
+		f, err := os.Create(src)
+		ok(err)
+		cw, err := scenario.compress(f)
+		ok(err)
+		tw := tar.NewWriter(cw)
+		for _, header := range []*tar.Header{
+			{Name: "jdk1.8.0/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "jdk1.8.0/bin/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "jdk1.8.0/bin/java", Typeflag: tar.TypeReg, Mode: 0755},
+			{Name: "jdk1.8.0/bin/jjs", Typeflag: tar.TypeSymlink, Linkname: "java", Mode: 0777},
+			{Name: "jdk1.8.0/release", Typeflag: tar.TypeReg, Mode: 0644},
+			// e.g. Temurin/Zulu ship libjsig.so as a hard link
+			{Name: "jdk1.8.0/lib/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "jdk1.8.0/lib/libjsig.so", Typeflag: tar.TypeReg, Mode: 0755},
+			{Name: "jdk1.8.0/lib/server/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "jdk1.8.0/lib/server/libjsig.so", Typeflag: tar.TypeLink, Linkname: "jdk1.8.0/lib/libjsig.so"},
+		} {
+			ok(tw.WriteHeader(header))
+		}
+		ok(tw.Close())
+		ok(cw.Close())
+		ok(f.Close())
+		dst := filepath.Join(dir, scenario.ext)
+		ok(scenario.extract(src, dst, true))
+		for path, mode := range map[string]os.FileMode{
+			filepath.Join("bin", "java"): 0755,
+			"release":                    0644,
+		} {
+			stat, err := os.Stat(filepath.Join(dst, path))
+			ok(err)
+			if stat.Mode().Perm() != mode {
+				t.Fatalf("%s: actual: %v != expected: %v", path, stat.Mode().Perm(), mode)
+			}
+		}
+		link, err := os.Readlink(filepath.Join(dst, "bin", "jjs"))
+		ok(err)
+		if link != "java" {
+			t.Fatalf("actual: %v != expected: %v", link, "java")
+		}
+		lib, err := os.Stat(filepath.Join(dst, "lib", "libjsig.so"))
+		ok(err)
+		hardlink, err := os.Stat(filepath.Join(dst, "lib", "server", "libjsig.so"))
+		ok(err)
+		if !os.SameFile(lib, hardlink) {
+			t.Fatalf("lib/server/libjsig.so is not a hard link to lib/libjsig.so")
+		}
+	}
+}
+
+func TestUntarRejectsHardLinkOutsideOfArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "install_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "jdk.tar.gz")
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, header := range []*tar.Header{
+		{Name: "bin/java", Typeflag: tar.TypeReg, Mode: 0755},
+		{Name: "bin/passwd", Typeflag: tar.TypeLink, Linkname: "../../etc/passwd"},
+	} {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gw.Close()
+	f.Close()
+	err = untgz(src, filepath.Join(dir, "jdk"), false)
+	if _, ok := err.(*CorruptArchiveError); !ok {
+		t.Fatalf("expected CorruptArchiveError, got %v", err)
+	}
+}
+
+func TestUntarRejectsEntriesOutsideOfArchive(t *testing.T) {
+	for name, headers := range map[string][]*tar.Header{
+		"dot-dot": {{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0644}},
+		"symlink": {
+			{Name: "jdk/lib", Typeflag: tar.TypeSymlink, Linkname: "/tmp"},
+			{Name: "jdk/lib/evil", Typeflag: tar.TypeReg, Mode: 0644},
+		},
+	} {
+		var b bytes.Buffer
+		tw := tar.NewWriter(&b)
+		for _, header := range headers {
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tw.Close()
+		dir, err := ioutil.TempDir("", "install_test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = extractTar("jdk.tar", &b, filepath.Join(dir, "jdk"), "")
+		os.RemoveAll(dir)
+		if _, ok := err.(*CorruptArchiveError); !ok {
+			t.Fatalf("%s: expected CorruptArchiveError, got %v", name, err)
+		}
+	}
+}
+
+func TestUntarDoesNotWriteThroughSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+	dir, err := ioutil.TempDir("", "install_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	victim := filepath.Join(dir, "victim.txt")
+	if err := ioutil.WriteFile(victim, []byte("intact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	extract := func(headers []*tar.Header, contents []string) error {
+		var b bytes.Buffer
+		tw := tar.NewWriter(&b)
+		for i, header := range headers {
+			header.Size = int64(len(contents[i]))
+			if err := tw.WriteHeader(header); err != nil {
+				t.Fatal(err)
+			}
+			tw.Write([]byte(contents[i]))
+		}
+		tw.Close()
+		dst, err := ioutil.TempDir(dir, "jdk")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return extractTar("jdk.tar", &b, dst, "jdk")
+	}
+	// symlink followed by the file with the same name
+	err = extract([]*tar.Header{
+		{Name: "jdk/release", Typeflag: tar.TypeSymlink, Linkname: victim, Mode: 0777},
+		{Name: "jdk/release", Typeflag: tar.TypeReg, Mode: 0644},
+	}, []string{"", "JAVA_VERSION=1.8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// hard link to the file behind symlink
+	err = extract([]*tar.Header{
+		{Name: "jdk/lib", Typeflag: tar.TypeSymlink, Linkname: dir, Mode: 0777},
+		{Name: "jdk/release", Typeflag: tar.TypeLink, Linkname: "jdk/lib/victim.txt"},
+	}, []string{"", ""})
+	if _, ok := err.(*CorruptArchiveError); !ok {
+		t.Fatalf("expected CorruptArchiveError, got %v", err)
+	}
+	if b, _ := ioutil.ReadFile(victim); string(b) != "intact" {
+		t.Fatalf("file outside of the archive was overwritten (%q)", b)
+	}
+}
+
+func TestUntarLongNames(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "install_test")
+	ok(err)
+	defer os.RemoveAll(dir)
+	// same layout as Corretto/Temurin tarballs (paths well over 100 characters (the limit of ustar name field))
+	root := "amazon-corretto-17.0.8.8.1-linux-x64/"
+	legal := "legal/jdk.internal.vm.compiler.management/" + strings.Repeat("long-directory-name/", 4)
+	for _, format := range []tar.Format{tar.FormatPAX, tar.FormatGNU} {
+		src := filepath.Join(dir, format.String()+".tar.gz")
+		f, err := os.Create(src)
+		ok(err)
+		gw := gzip.NewWriter(f)
+		tw := tar.NewWriter(gw)
+		var headers []*tar.Header
+		if format == tar.FormatPAX {
+			// `git archive` (GitHub source tarballs) & some vendor build pipelines start with one
+			headers = append(headers, &tar.Header{Name: "pax_global_header", Typeflag: tar.TypeXGlobalHeader,
+				PAXRecords: map[string]string{"comment": strings.Repeat("0", 40)}})
+		}
+		headers = append(headers,
+			&tar.Header{Name: root, Typeflag: tar.TypeDir, Mode: 0755},
+			&tar.Header{Name: root + "bin/java", Typeflag: tar.TypeReg, Mode: 0755},
+			&tar.Header{Name: root + legal + "ASSEMBLY_EXCEPTION", Typeflag: tar.TypeReg, Mode: 0644},
+			&tar.Header{Name: root + legal + "LICENSE", Typeflag: tar.TypeLink,
+				Linkname: root + legal + "ASSEMBLY_EXCEPTION"},
+			&tar.Header{Name: root + legal + "ADDITIONAL_LICENSE_INFO", Typeflag: tar.TypeSymlink, Mode: 0777,
+				Linkname: strings.Repeat("../", 6) + "java.base/ADDITIONAL_LICENSE_INFO"},
+		)
+		for _, header := range headers {
+			if header.Typeflag != tar.TypeXGlobalHeader {
+				header.Format = format
+			}
+			ok(tw.WriteHeader(header))
+		}
+		ok(tw.Close())
+		ok(gw.Close())
+		ok(f.Close())
+		dst := filepath.Join(dir, format.String())
+		ok(untgz(src, dst, true))
+		if _, err := os.Stat(filepath.Join(dst, "bin", "java")); err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		legalDir := filepath.Join(dst, filepath.FromSlash(legal))
+		exception, err := os.Stat(filepath.Join(legalDir, "ASSEMBLY_EXCEPTION"))
+		ok(err)
+		license, err := os.Stat(filepath.Join(legalDir, "LICENSE"))
+		ok(err)
+		if !os.SameFile(exception, license) {
+			t.Fatalf("%v: LICENSE is not a hard link to ASSEMBLY_EXCEPTION", format)
+		}
+		link, err := os.Readlink(filepath.Join(legalDir, "ADDITIONAL_LICENSE_INFO"))
+		ok(err)
+		if link != headers[len(headers)-1].Linkname {
+			t.Fatalf("%v: actual: %v != expected: %v", format, link, headers[len(headers)-1].Linkname)
+		}
+		if _, err := os.Stat(filepath.Join(dst, "pax_global_header")); !os.IsNotExist(err) {
+			t.Fatalf("%v: pax_global_header is not expected to be extracted", format)
+		}
+	}
+}
+
 func touch(path ...string) error {
 	filename := filepath.Join(path...)
 	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
@@ -96,3 +358,544 @@ func file(path ...string) error {
 	}
 	return nil
 }
+
+func TestResolveReleaseForArch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	index := filepath.Join(dir, "index.json")
+	err = ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {
+		"amd64": {"jdk@zulu": {"1.17.0": "tgz+https://example.com/zulu-x64.tar.gz"}},
+		"arm64": {"jdk@zulu": {"1.17.0": "tgz+https://example.com/zulu-aarch64.tar.gz", "1.18.0": "tgz+https://example.com/18.tar.gz"}}
+	}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	for _, arch := range []string{"x86_64", "amd64"} {
+		ver, release, err := resolveRelease("zulu@1.17", InstallOptions{Arch: arch})
+		if err != nil || ver.String() != "zulu@1.17.0" || release.URL != "tgz+https://example.com/zulu-x64.tar.gz" {
+			t.Fatalf("%s: actual: %v %v (%v)", arch, ver, release.URL, err)
+		}
+	}
+	ver, release, err := resolveRelease("zulu@1.17", InstallOptions{Arch: "aarch64"})
+	if err != nil || ver.String() != "zulu@1.17.0" || release.URL != "tgz+https://example.com/zulu-aarch64.tar.gz" {
+		t.Fatalf("actual: %v %v (%v)", ver, release.URL, err)
+	}
+	// explicit --arch disables fallback
+	if _, _, err := resolveRelease("zulu@1.18", InstallOptions{Arch: "amd64"}); err == nil {
+		t.Fatal("expected zulu@1.18 (amd64) not to be found")
+	}
+}
+
+func TestUnzipReportsCorruptArchive(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "install_test")
+	ok(err)
+	defer os.RemoveAll(dir)
+	content := []byte("java binary")
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for _, name := range []string{"jdk1.8.0/bin/java", "jdk1.8.0/release"} {
+		// stored (uncompressed) so that data can be tampered with below
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		ok(err)
+		_, err = w.Write(content)
+		ok(err)
+	}
+	ok(zw.Close())
+	archive := b.Bytes()
+	src := filepath.Join(dir, "jdk.zip")
+	ok(ioutil.WriteFile(src, archive, 0644))
+	ok(unzip(src, filepath.Join(dir, "ok"), true))
+	for name, corrupt := range map[string][]byte{
+		"truncated": archive[:len(archive)/2],
+		// crc32 mismatch
+		"tampered": bytes.Replace(archive, content, []byte("JAVA BINARY"), 1),
+		// central directory pointing at something that isn't a local file header
+		"misaligned": bytes.Replace(archive, []byte("PK\x03\x04"), []byte("PK\x00\x00"), 1),
+	} {
+		src := filepath.Join(dir, name+".zip")
+		ok(ioutil.WriteFile(src, corrupt, 0644))
+		err := unzip(src, filepath.Join(dir, name), true)
+		if _, isCorrupt := err.(*CorruptArchiveError); !isCorrupt {
+			t.Fatalf("%s: expected CorruptArchiveError (got %v)", name, err)
+		}
+	}
+}
+
+// zipArchive writes zip with the specified entries (content of symlink entries is the link target) to dir/name.
+func zipArchive(dir string, name string, headers []*zip.FileHeader, contents []string) (string, error) {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for i, header := range headers {
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := w.Write([]byte(contents[i])); err != nil {
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	src := filepath.Join(dir, name)
+	return src, ioutil.WriteFile(src, b.Bytes(), 0644)
+}
+
+func TestUnzipRestoresModesAndSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes & symlinks are not preserved on Windows")
+	}
+	dir, err := ioutil.TempDir("", "install_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	unix := func(name string, mode os.FileMode) *zip.FileHeader {
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		header.SetMode(mode)
+		return header
+	}
+	// zip created on Windows (FAT) that still carries unix mode in external attributes
+	fat := &zip.FileHeader{Name: "jdk/bin/javac", Method: zip.Deflate, CreatorVersion: 20,
+		ExternalAttrs: (0100755 << 16) | 0x20}
+	// no unix mode at all
+	dos := &zip.FileHeader{Name: "jdk/bin/jar", Method: zip.Deflate, CreatorVersion: 20, ExternalAttrs: 0x20}
+	src, err := zipArchive(dir, "jdk.zip", []*zip.FileHeader{
+		unix("jdk/bin/java", 0755), fat, dos, unix("jdk/release", 0644), unix("jdk/lib/java", os.ModeSymlink|0777),
+	}, []string{"java", "javac", "jar", "JAVA_VERSION=1.8", "../bin/java"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "jdk")
+	if err := unzip(src, dst, true); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]os.FileMode{
+		"bin/java": 0755, "bin/javac": 0755, "bin/jar": 0755, "release": 0644,
+	} {
+		stat, err := os.Stat(filepath.Join(dst, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := stat.Mode().Perm(); actual != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", name, actual, expected)
+		}
+	}
+	if link, err := os.Readlink(filepath.Join(dst, "lib", "java")); err != nil || link != "../bin/java" {
+		t.Fatalf("actual: %v (%v) != expected: ../bin/java", link, err)
+	}
+}
+
+func TestUnzipRejectsEntriesOutsideOfArchive(t *testing.T) {
+	dir := t.TempDir()
+	symlink := &zip.FileHeader{Name: "jdk/lib"}
+	symlink.SetMode(os.ModeSymlink | 0777)
+	for name, entries := range map[string][]*zip.FileHeader{
+		"dot-dot": {{Name: "jdk/bin/java"}, {Name: "../../evil"}},
+		"symlink": {symlink, {Name: "jdk/lib/evil"}},
+	} {
+		// either of the entries would end up in dir/evil
+		contents := make([]string, len(entries))
+		for i := range contents {
+			contents[i] = dir
+		}
+		src, err := zipArchive(dir, name+".zip", entries, contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = unzip(src, filepath.Join(dir, name, "jdk"), true)
+		if _, ok := err.(*CorruptArchiveError); !ok {
+			t.Fatalf("%s: expected CorruptArchiveError, got %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+		t.Fatalf("entry was extracted outside of the target directory (%v)", err)
+	}
+}
+
+func TestUnzipDoesNotWriteThroughSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+	dir, err := ioutil.TempDir("", "install_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	victim := filepath.Join(dir, "victim.txt")
+	if err := ioutil.WriteFile(victim, []byte("intact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	symlink := &zip.FileHeader{Name: "jdk/release"}
+	symlink.SetMode(os.ModeSymlink | 0777)
+	file := &zip.FileHeader{Name: "jdk/release"}
+	file.SetMode(0644)
+	src, err := zipArchive(dir, "jdk.zip", []*zip.FileHeader{symlink, file}, []string{victim, "JAVA_VERSION=1.8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "jdk")
+	if err := unzip(src, dst, true); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(victim); string(b) != "intact" {
+		t.Fatalf("file outside of the archive was overwritten (%q)", b)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dst, "release")); err != nil || string(b) != "JAVA_VERSION=1.8" {
+		t.Fatalf("actual: %q (%v) != expected: %q", b, err, "JAVA_VERSION=1.8")
+	}
+}
+
+func TestInstallFromBin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip(".bin installers are Linux-only")
+	}
+	dir, err := ioutil.TempDir("", "install test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("JABBA_HOME", dir)
+	defer os.Unsetenv("JABBA_HOME")
+	// self-extracting archive waits for "Enter" & unpacks JDK into the working directory
+	src := filepath.Join(dir, "jdk-6u45 linux-x64.bin")
+	script := "read answer\nmkdir -p jdk1.6.0_45/bin && echo java > jdk1.6.0_45/bin/java\n"
+	if err := ioutil.WriteFile(src, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "jdk dst")
+	if err := installFromBin(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "bin", "java")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateZip64(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	// more than 65535 entries require zip64 end of central directory record
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for i := 0; i < 0xffff+1; i++ {
+		if _, err := zw.Create(fmt.Sprintf("jdk/%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 0xffff+1 {
+		t.Fatalf("actual: %v != expected: %v", len(r.File), 0xffff+1)
+	}
+	if err := validateZip("jdk.zip", r, int64(b.Len())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveReleaseForMusl(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("linux-only")
+	}
+	prevHostIsMusl := hostIsMusl
+	defer func() { hostIsMusl = prevHostIsMusl }()
+	hostIsMusl = func() bool { return true }
+	dir, err := ioutil.TempDir("", "jabba-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	index := filepath.Join(dir, "index.json")
+	err = ioutil.WriteFile(index, []byte(`{
+		"linux": {"amd64": {"jdk@zulu": {"1.17.0": "tgz+https://example.com/17-glibc.tar.gz", "1.11.0": "tgz+https://example.com/11-glibc.tar.gz"}}},
+		"linux-musl": {"amd64": {"jdk@zulu": {"1.17.0": "tgz+https://example.com/17-musl.tar.gz"}}}
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + index})
+	defer cfg.SetRegistry(nil)
+	for _, scenario := range []struct{ selector, libc, expected string }{
+		{"zulu@1.17", "", "tgz+https://example.com/17-musl.tar.gz"},
+		{"zulu@1.17", "glibc", "tgz+https://example.com/17-glibc.tar.gz"},
+		// no musl build
+		{"zulu@1.11", "", "tgz+https://example.com/11-glibc.tar.gz"},
+		{"zulu@1.11", "musl", ""},
+	} {
+		_, release, err := resolveRelease(scenario.selector, InstallOptions{Arch: "amd64", Libc: scenario.libc})
+		if release.URL != scenario.expected || (err != nil) != (scenario.expected == "") {
+			t.Fatalf("%v: actual: %v (%v) != expected: %v", scenario, release.URL, err, scenario.expected)
+		}
+	}
+}
+
+func TestResolveRecommendedRelease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	index := filepath.Join(dir, "index.json")
+	err = ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"amd64": {"jdk@zulu": {
+		"1.21.2": "tgz+https://example.com/21.2.tar.gz",
+		"1.21.1": {"url": "tgz+https://example.com/21.1.tar.gz", "recommended": true},
+		"1.21.0": {"url": "tgz+https://example.com/21.0.tar.gz", "recommended": true},
+		"1.17.0": "tgz+https://example.com/17.0.tar.gz"
+	}}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	for _, scenario := range []struct {
+		selector string
+		any      bool
+		expected string
+	}{
+		{"zulu@1.21", false, "zulu@1.21.1"},
+		{"zulu@1.21", true, "zulu@1.21.2"},
+		{"zulu@1.21.2", false, "zulu@1.21.2"},
+		{"zulu@~1.21.0 <1.21.1", false, "zulu@1.21.0"},
+		// no recommended releases in range
+		{"zulu@1.17", false, "zulu@1.17.0"},
+	} {
+		ver, _, err := resolveRelease(scenario.selector, InstallOptions{Arch: "amd64", Any: scenario.any})
+		if err != nil || ver.String() != scenario.expected {
+			t.Fatalf("%v: actual: %v (%v) != expected: %v", scenario, ver, err, scenario.expected)
+		}
+	}
+}
+
+func TestResolveReleaseChannel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	index := filepath.Join(dir, "index.json")
+	err = ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"amd64": {"jdk@openjdk": {
+		"1.23.0-0": {"url": "tgz+https://example.com/23-ea+1.tar.gz", "channel": "ea"},
+		"1.22.0-ea.7": "tgz+https://example.com/22-ea+7.tar.gz",
+		"1.21.0": "tgz+https://example.com/21.tar.gz"
+	}}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	for _, scenario := range []struct {
+		selector string
+		channel  string
+		expected string
+	}{
+		{"openjdk@>=1.21", "", "openjdk@1.21.0"},
+		{"openjdk@>=1.21-0", ChannelGA, "openjdk@1.21.0"},
+		{"openjdk@1.22", ChannelEA, "openjdk@1.22.0-ea.7"},
+		{"openjdk@>=1.21", ChannelEA, "openjdk@1.23.0-0"},
+		{"openjdk@>=1.21", ChannelAll, "openjdk@1.23.0-0"},
+		{"openjdk@1.21", ChannelEA, ""},
+		// there are only early-access builds of 22
+		{"openjdk@1.22", "", ""},
+	} {
+		ver, _, err := resolveRelease(scenario.selector, InstallOptions{Arch: "amd64", Channel: scenario.channel})
+		if scenario.expected == "" {
+			if err == nil {
+				t.Fatalf("%v: actual: %v != expected: error", scenario, ver)
+			}
+			continue
+		}
+		if err != nil || ver.String() != scenario.expected {
+			t.Fatalf("%v: actual: %v (%v) != expected: %v", scenario, ver, err, scenario.expected)
+		}
+	}
+}
+
+func TestInstallIsAtomic(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	archive := func(name string, files ...string) string {
+		path := filepath.Join(home, name)
+		f, err := os.Create(path)
+		ok(err)
+		gw := gzip.NewWriter(f)
+		tw := tar.NewWriter(gw)
+		for _, file := range files {
+			ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755}))
+		}
+		ok(tw.Close())
+		ok(gw.Close())
+		ok(f.Close())
+		return "tgz+file://" + filepath.ToSlash(path)
+	}
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	if _, err := Install("1.8.0-custom="+archive("broken.tar.gz", "jdk/release"), InstallOptions{}); err == nil {
+		t.Fatal("expected install to fail (bin/java is missing)")
+	}
+	if _, err := os.Stat(filepath.Join(home, "jdk", "1.8.0-custom")); !os.IsNotExist(err) {
+		t.Fatalf("half-populated jdk/1.8.0-custom was left behind (%v)", err)
+	}
+	vs, err := Ls()
+	if err != nil || len(vs) != 0 {
+		t.Fatalf("actual: %v (%v) != expected: []", vs, err)
+	}
+	result, err := Install("1.8.0-custom="+archive("jdk.tar.gz", java, "jdk/release"), InstallOptions{})
+	ok(err)
+	if expected := filepath.Join(home, "jdk", "1.8.0-custom"); result.Path != expected {
+		t.Fatalf("actual: %v != expected: %v", result.Path, expected)
+	}
+	ok(file(expectedJavaPath(result.Path, runtime.GOOS)))
+	if _, err := os.Stat(filepath.Join(home, "jdk", ".staging", "1.8.0-custom")); !os.IsNotExist(err) {
+		t.Fatalf("staging directory was left behind (%v)", err)
+	}
+}
+
+func TestCleanStaging(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	staging := filepath.Join(home, "jdk", ".staging")
+	for _, ver := range []string{"1.8.0", "1.11.0", "1.17.0"} {
+		if err := touch(staging, ver, "bin", "java"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 1.8.0 is a leftover of an install that got killed, 1.11.0 is being installed right now, so is 1.17.0 (which
+	// has been downloading for a while)
+	stale := time.Now().Add(-2 * staleStagingAge)
+	for _, ver := range []string{"1.8.0", "1.17.0"} {
+		if err := os.Chtimes(filepath.Join(staging, ver), stale, stale); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lock := flock.New(filepath.Join(staging, "1.17.0.lock"))
+	if err := lock.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Unlock()
+	if err := CleanStaging(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(staging, "1.8.0")); !os.IsNotExist(err) {
+		t.Fatalf("stale 1.8.0 wasn't removed (%v)", err)
+	}
+	for _, ver := range []string{"1.11.0", "1.17.0"} {
+		if err := file(staging, ver, "bin", "java"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestInstallForAnotherOS(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	goos := "windows"
+	if runtime.GOOS == "windows" {
+		goos = "linux"
+	}
+	archive := filepath.Join(home, "jdk.zip")
+	f, err := os.Create(archive)
+	ok(err)
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"jdk-17/bin/java.exe", "jdk-17/bin/java", "jdk-17/release"} {
+		_, err := zw.Create(name)
+		ok(err)
+	}
+	ok(zw.Close())
+	ok(f.Close())
+	url := "zip+file://" + filepath.ToSlash(archive)
+	index := filepath.Join(home, "index.json")
+	ok(ioutil.WriteFile(index, []byte(`{"`+goos+`": {"arm64": {"jdk@zulu": {
+		"1.17.0": "`+url+`", "1.17.1": "exe+https://example.com/zulu-17.0.1.exe"
+	}}}}`), 0644))
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	if _, err := Install("zulu@1.17.0", InstallOptions{OS: goos, Arch: "arm64"}); err == nil {
+		t.Fatal("expected install into $JABBA_HOME/jdk to fail")
+	}
+	dst := filepath.Join(home, "image", "jdk")
+	result, err := Install("zulu@1.17.0", InstallOptions{OS: goos, Arch: "arm64", Dst: dst})
+	ok(err)
+	if result.Version != "zulu@1.17.0" {
+		t.Fatalf("actual: %v != expected: %v", result.Version, "zulu@1.17.0")
+	}
+	ok(file(expectedJavaPath(dst, goos)))
+	if _, err := Install("zulu@1.17.1", InstallOptions{OS: goos, Arch: "arm64", Dst: filepath.Join(home, "exe")}); err == nil ||
+		!strings.Contains(err.Error(), "installer") {
+		t.Fatalf("expected installer to be rejected (got %v)", err)
+	}
+	if vs := installed(t); len(vs) != 0 {
+		t.Fatalf("actual: %v != expected: []", vs)
+	}
+}
+
+func TestResolveReleaseOnWindowsARM(t *testing.T) {
+	prevNativeArch := nativeArch
+	defer func() { nativeArch = prevNativeArch }()
+	nativeArch = func() string { return "arm64" }
+	dir, err := ioutil.TempDir("", "jabba-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	index := filepath.Join(dir, "index.json")
+	err = ioutil.WriteFile(index, []byte(`{"windows": {
+		"amd64": {"jdk@zulu": {"1.17.0": "zip+https://example.com/zulu-x64.zip", "1.18.0": "zip+https://example.com/18-x64.zip"}},
+		"arm64": {"jdk@zulu": {"1.18.0": "zip+https://example.com/18-aarch64.zip"}}
+	}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	_, release, err := resolveRelease("zulu@1.18", InstallOptions{OS: "windows"})
+	if err != nil || release.URL != "zip+https://example.com/18-aarch64.zip" {
+		t.Fatalf("actual: %v (%v)", release.URL, err)
+	}
+	if _, _, err := resolveRelease("zulu@1.17", InstallOptions{OS: "windows"}); err == nil ||
+		!strings.Contains(err.Error(), "--allow-emulation") {
+		t.Fatalf("expected emulation to require opt-in, got %v", err)
+	}
+	_, release, err = resolveRelease("zulu@1.17", InstallOptions{OS: "windows", AllowEmulation: true})
+	if err != nil || release.URL != "zip+https://example.com/zulu-x64.zip" || release.arch != "amd64" {
+		t.Fatalf("actual: %v %v (%v)", release.URL, release.arch, err)
+	}
+}
@@ -0,0 +1,263 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// Environment variables (e.g. JAVA_TOOL_OPTIONS, GRAALVM_HOME) can be attached to installed JDK (kept in its
+// metadata (see installMeta)) or to an alias (kept in alias-env.json next to aliases.json). `jabba use`, `jabba exec`
+// & `jabba env` export them alongside JAVA_HOME (variables of the alias override the ones of the JDK, profiles
+// override both). Values can reference other variables (e.g. $JAVA_HOME of the JDK being activated).
+
+const (
+	aliasEnvFileName = "alias-env.json"
+	// names of the variables set by the last `jabba use` (so that the next one could unset the ones it doesn't set)
+	jdkEnvVar = "JABBA_JDK_ENV"
+)
+
+// JDKEnv is what `jabba setenv` changes / lists.
+type JDKEnv struct {
+	// alias name or JDK version variables are attached to
+	Target string `json:"target"`
+	Alias  bool   `json:"alias,omitempty"`
+	// name -> value (as is (unexpanded))
+	Env map[string]string `json:"env"`
+}
+
+// SetJDKEnv attaches variables (name -> value, "" value to remove the variable) to the alias (if selector is one) or
+// to installed JDK matching the selector, returning variables attached to it afterwards.
+func SetJDKEnv(selector string, vars map[string]string) (*JDKEnv, error) {
+	for key := range vars {
+		if err := validateJDKEnvKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if GetAlias(selector) != "" {
+		dir := cfg.StateDir()
+		if err := ensureWritableDir(dir); err != nil {
+			return nil, err
+		}
+		all, err := readAliasEnvFile(dir)
+		if err != nil {
+			return nil, err
+		}
+		env := mergeEnv(aliasEnv(selector), vars)
+		if len(env) == 0 {
+			delete(all, selector)
+		} else {
+			all[selector] = env
+		}
+		if err := writeAliasEnvFile(dir, all); err != nil {
+			return nil, err
+		}
+		return &JDKEnv{Target: selector, Alias: true, Env: env}, nil
+	}
+	ver, err := Resolve(selector)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("There is no metadata recorded for %s (it was either installed by an older "+
+				"version of jabba or `jabba link`ed). Attach variables to an alias (e.g. `jabba alias x %s`) instead",
+				ver, ver)
+		}
+		return nil, err
+	}
+	meta.Env = mergeEnv(meta.Env, vars)
+	if err := writeInstallMeta(meta); err != nil {
+		return nil, err
+	}
+	return &JDKEnv{Target: ver, Env: meta.Env}, nil
+}
+
+// GetJDKEnv returns variables attached to the alias (if selector is one) or to installed JDK matching the selector.
+func GetJDKEnv(selector string) (*JDKEnv, error) {
+	if GetAlias(selector) != "" {
+		return &JDKEnv{Target: selector, Alias: true, Env: aliasEnv(selector)}, nil
+	}
+	ver, err := Resolve(selector)
+	if err != nil {
+		return nil, err
+	}
+	return &JDKEnv{Target: ver, Env: versionEnv(ver)}, nil
+}
+
+func validateJDKEnvKey(key string) error {
+	if err := validateEnvKey(key); err != nil {
+		return err
+	}
+	if key == "PATH" || key == "JAVA_HOME" || strings.HasPrefix(key, "JABBA_") {
+		return fmt.Errorf("%s cannot be set (it's managed by jabba)", key)
+	}
+	return nil
+}
+
+// mergeEnv returns copy of env with vars applied on top ("" value removes the variable).
+func mergeEnv(env map[string]string, vars map[string]string) map[string]string {
+	r := make(map[string]string)
+	for key, value := range env {
+		r[key] = value
+	}
+	for key, value := range vars {
+		if value == "" {
+			delete(r, key)
+		} else {
+			r[key] = value
+		}
+	}
+	if len(r) == 0 {
+		return nil
+	}
+	return r
+}
+
+func versionEnv(ver string) map[string]string {
+	meta, err := readInstallMeta(ver)
+	if err != nil {
+		return nil
+	}
+	return meta.Env
+}
+
+// aliasEnv returns variables attached to the alias (when jabba home is overlaid, the ones attached in jabba home
+// are visible unless alias has variables of its own in the overlay).
+func aliasEnv(name string) map[string]string {
+	all, _ := readAliasEnvFile(cfg.StateDir())
+	env, ok := all[name]
+	if !ok && cfg.StateDir() != cfg.Dir() {
+		all, _ = readAliasEnvFile(cfg.Dir())
+		env = all[name]
+	}
+	return env
+}
+
+// withJDKEnv extends env (in "key=value" format, PATH & JAVA_HOME included) with variables attached to JDK ver & the
+// aliases selector goes through (see resolveAlias) (outermost alias wins), recording their names in jdkEnvVar.
+// unset lists variables set by the previous `jabba use` that are not set anymore.
+func withJDKEnv(env []string, selector string, ver string) (set []string, unset []string) {
+	layers := []map[string]string{versionEnv(ver)}
+	if selector != "" {
+		if _, chain, err := resolveAlias(selector, GetAlias); err == nil {
+			for i := len(chain) - 1; i >= 0; i-- {
+				layers = append(layers, aliasEnv(chain[i]))
+			}
+		}
+	}
+	values := make(map[string]string)
+	for _, kv := range env {
+		key, value := splitEnv(kv)
+		values[key] = value
+	}
+	lookup := func(key string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}
+	var keys []string
+	added := make(map[string]bool)
+	for _, layer := range layers {
+		var layerKeys []string
+		for key := range layer {
+			layerKeys = append(layerKeys, key)
+		}
+		sort.Strings(layerKeys)
+		for _, key := range layerKeys {
+			if validateJDKEnvKey(key) != nil {
+				continue
+			}
+			values[key] = os.Expand(layer[key], lookup)
+			if !added[key] {
+				keys = append(keys, key)
+				added[key] = true
+			}
+		}
+	}
+	set = env
+	for _, key := range keys {
+		set = append(set, key+"="+values[key])
+	}
+	if len(keys) != 0 {
+		set = append(set, jdkEnvVar+"="+strings.Join(keys, ","))
+	} else if _, ok := os.LookupEnv(jdkEnvVar); ok {
+		unset = append(unset, jdkEnvVar)
+	}
+	for _, key := range previousJDKEnv() {
+		if !added[key] {
+			unset = append(unset, key)
+		}
+	}
+	return set, unset
+}
+
+// deactivatedJDKEnv returns variables `jabba deactivate` has to unset (bookkeeping variable included).
+func deactivatedJDKEnv() []string {
+	r := previousJDKEnv()
+	if _, ok := os.LookupEnv(jdkEnvVar); ok {
+		r = append(r, jdkEnvVar)
+	}
+	return r
+}
+
+// previousJDKEnv returns names of the variables set by the previous `jabba use` (see jdkEnvVar).
+func previousJDKEnv() []string {
+	var r []string
+	for _, key := range strings.Split(os.Getenv(jdkEnvVar), ",") {
+		if key != "" {
+			r = append(r, key)
+		}
+	}
+	return r
+}
+
+// removeAliasEnv drops variables attached to the alias (that is being removed).
+func removeAliasEnv(dir string, name string) error {
+	all, err := readAliasEnvFile(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := all[name]; !ok {
+		return nil
+	}
+	delete(all, name)
+	return writeAliasEnvFile(dir, all)
+}
+
+func readAliasEnvFile(dir string) (map[string]map[string]string, error) {
+	file := filepath.Join(dir, aliasEnvFileName)
+	r := make(map[string]map[string]string)
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("%s is not valid (%v)", file, err)
+	}
+	return r, nil
+}
+
+func writeAliasEnvFile(dir string, env map[string]map[string]string) error {
+	b, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, aliasEnvFileName)
+	tmp := file + ".jabba-tmp"
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestJDKEnv(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "1.8.0", "graalvm@21.0.1")
+	ok(ioutil.WriteFile(filepath.Join(home, "jdk", "graalvm@21.0.1", "release"),
+		[]byte("JAVA_VERSION=\"21.0.1\"\nGRAALVM_VERSION=\"23.1.1\"\n"), 0644))
+	for _, ver := range []string{"1.8.0", "graalvm@21.0.1"} {
+		ok(recordInstall(&InstallResult{Version: ver, Path: filepath.Join(home, "jdk", ver),
+			URL: "https://example.com/jdk.tar.gz"}, nil))
+	}
+	env, err := GetJDKEnv("graalvm@21")
+	ok(err)
+	if expected := map[string]string{"GRAALVM_HOME": "$JAVA_HOME"}; !reflect.DeepEqual(env.Env, expected) {
+		t.Fatalf("actual: %v != expected: %v", env.Env, expected)
+	}
+	if _, err := SetJDKEnv("1.8", map[string]string{"JAVA_HOME": "/tmp"}); err == nil {
+		t.Fatal("JAVA_HOME should have been rejected")
+	}
+	_, err = SetJDKEnv("1.8", map[string]string{"JAVA_TOOL_OPTIONS": "-Xss4m", "JDK_HOME": "$JAVA_HOME"})
+	ok(err)
+	ok(SetAlias("legacy", "1.8"))
+	ok(SetAlias("work/legacy", "legacy"))
+	_, err = SetJDKEnv("legacy", map[string]string{"JAVA_TOOL_OPTIONS": "-Xss8m", "APP_ENV": "legacy"})
+	ok(err)
+	_, err = SetJDKEnv("work/legacy", map[string]string{"APP_ENV": "work"})
+	ok(err)
+	change, err := UseWithOptions("work/legacy", UseOptions{})
+	ok(err)
+	set := envMap(change.Set)
+	// outermost alias wins
+	for key, expected := range map[string]string{
+		"JAVA_TOOL_OPTIONS": "-Xss8m", "APP_ENV": "work", "JDK_HOME": set["JAVA_HOME"],
+		jdkEnvVar: "JAVA_TOOL_OPTIONS,JDK_HOME,APP_ENV",
+	} {
+		if set[key] != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", key, set[key], expected)
+		}
+	}
+	// variables set by the previous `jabba use` are unset unless they are set again
+	os.Setenv(jdkEnvVar, set[jdkEnvVar])
+	defer os.Unsetenv(jdkEnvVar)
+	change, err = UseWithOptions("graalvm@21.0.1", UseOptions{})
+	ok(err)
+	set = envMap(change.Set)
+	if set["GRAALVM_HOME"] != set["JAVA_HOME"] || set["GRAALVM_HOME"] == "" {
+		t.Fatalf("actual: %v != expected: %v", set["GRAALVM_HOME"], set["JAVA_HOME"])
+	}
+	unset := change.Unset
+	sort.Strings(unset)
+	if expected := []string{"APP_ENV", "JAVA_TOOL_OPTIONS", "JDK_HOME"}; !reflect.DeepEqual(unset, expected) {
+		t.Fatalf("actual: %v != expected: %v", unset, expected)
+	}
+	// `jabba env` exports them too (without bookkeeping)
+	change, err = Env("legacy")
+	ok(err)
+	set = envMap(change.Set)
+	if _, ok := set[jdkEnvVar]; ok || set["APP_ENV"] != "legacy" {
+		t.Fatalf("unexpected env: %v", change.Set)
+	}
+	out, err := change.Export("azure")
+	ok(err)
+	if expected := "##vso[task.setvariable variable=APP_ENV]legacy"; !strings.Contains(out, expected) {
+		t.Fatalf("%s was expected to contain %s", out, expected)
+	}
+	change, err = Deactivate()
+	ok(err)
+	unset = change.Unset
+	sort.Strings(unset)
+	if expected := []string{"APP_ENV", "JABBA_JDK_ENV", "JAVA_HOME_BEFORE_JABBA", "JAVA_TOOL_OPTIONS",
+		"JDK_HOME"}; !reflect.DeepEqual(unset, expected) {
+		t.Fatalf("actual: %v != expected: %v", unset, expected)
+	}
+	// "" removes variable
+	env, err = SetJDKEnv("legacy", map[string]string{"JAVA_TOOL_OPTIONS": "", "APP_ENV": ""})
+	ok(err)
+	if env.Env != nil {
+		t.Fatalf("actual: %v != expected: nil", env.Env)
+	}
+	// variables go along with the alias
+	ok(SetAlias("work/legacy", ""))
+	ok(SetAlias("work/legacy", "1.8"))
+	if env, err := GetJDKEnv("work/legacy"); err != nil || env.Env != nil {
+		t.Fatalf("actual: %v (%v) != expected: nil", env, err)
+	}
+}
+
+func envMap(env []string) map[string]string {
+	r := make(map[string]string)
+	for _, kv := range env {
+		key, value := splitEnv(kv)
+		r[key] = value
+	}
+	return r
+}
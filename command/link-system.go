@@ -0,0 +1,180 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// directory macOS (/usr/libexec/java_home, GUI apps) looks for JDKs in
+var javaVirtualMachinesDir = "/Library/Java/JavaVirtualMachines"
+
+const systemLinkBundleName = "jabba-default.jdk"
+
+// higher than the priority of any JDK package (e.g. 1711 of openjdk-17 on Debian)
+const systemLinkAlternativesPriority = "100000"
+
+// LinkSystem makes JDK selector resolves to the system-wide default (for the processes that don't read shell profiles
+// (e.g. GUI applications)) by pointing cfg.SystemLink() (e.g. /usr/local/opt/jabba-default) at its JAVA_HOME.
+// On macOS JDK is also linked into /Library/Java/JavaVirtualMachines, on Linux java & javac under cfg.SystemLink()
+// are registered with alternatives (update-alternatives) (if available).
+// Writing into the system directories usually requires root privileges.
+func LinkSystem(selector string) (string, error) {
+	if runtime.GOOS == "windows" {
+		return "", errors.New("`jabba link system` is not supported on Windows")
+	}
+	ver, err := LsBestMatch(selector)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cfg.JDKDir(), ver)
+	home := filepath.Dir(filepath.Dir(expectedJavaPath(dir, runtime.GOOS)))
+	link := cfg.SystemLink()
+	if err := replaceSystemLink(home, link); err != nil {
+		return "", err
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := os.Stat(filepath.Join(dir, "Contents", "Info.plist")); err != nil {
+			log.Warn(ver + " has no Contents/Info.plist (/usr/libexec/java_home won't list it)")
+		}
+		if err := replaceSystemLink(dir, filepath.Join(javaVirtualMachinesDir, systemLinkBundleName)); err != nil {
+			return "", err
+		}
+	case "linux":
+		if err := registerAlternatives(link); err != nil {
+			return "", err
+		}
+	}
+	return ver, nil
+}
+
+// UnlinkSystem undoes LinkSystem.
+func UnlinkSystem() error {
+	if runtime.GOOS == "windows" {
+		return errors.New("`jabba unlink system` is not supported on Windows")
+	}
+	link := cfg.SystemLink()
+	switch runtime.GOOS {
+	case "darwin":
+		if err := removeSystemLink(filepath.Join(javaVirtualMachinesDir, systemLinkBundleName)); err != nil {
+			return err
+		}
+	case "linux":
+		if err := unregisterAlternatives(link); err != nil {
+			return err
+		}
+	}
+	return removeSystemLink(link)
+}
+
+// GetSystemLink returns JAVA_HOME system-wide link points at ("" if there is none).
+func GetSystemLink() string {
+	target, err := os.Readlink(cfg.SystemLink())
+	if err != nil {
+		return ""
+	}
+	return target
+}
+
+// replaceSystemLink atomically (re)points link at target (refusing to replace anything but a link).
+func replaceSystemLink(target string, link string) error {
+	if stat, err := os.Lstat(link); err == nil && stat.Mode()&os.ModeSymlink != os.ModeSymlink {
+		return fmt.Errorf("%s already exists (and is not a link)", link)
+	}
+	if err := os.MkdirAll(filepath.Dir(link), 0755); err != nil {
+		return privilegesHint(err)
+	}
+	tmp := link + ".jabba-tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return privilegesHint(err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return privilegesHint(err)
+	}
+	log.Info(link + " -> " + target)
+	return nil
+}
+
+func removeSystemLink(link string) error {
+	stat, err := os.Lstat(link)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stat.Mode()&os.ModeSymlink != os.ModeSymlink {
+		return fmt.Errorf("%s is not a link (not removing)", link)
+	}
+	target, _ := os.Readlink(link)
+	if err := os.Remove(link); err != nil {
+		return privilegesHint(err)
+	}
+	log.Info(link + " -/> " + target)
+	return nil
+}
+
+func privilegesHint(err error) error {
+	if os.IsPermission(err) {
+		return fmt.Errorf("%v (re-run with sudo)", err)
+	}
+	return err
+}
+
+// alternativesCommand returns update-alternatives (alternatives on RHEL & co) ("" if neither is available).
+func alternativesCommand() string {
+	for _, name := range []string{"update-alternatives", "alternatives"} {
+		if path, err := lookPath(name); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func registerAlternatives(link string) error {
+	command := alternativesCommand()
+	if command == "" {
+		log.Debug("update-alternatives not found (skipping registration)")
+		return nil
+	}
+	for _, name := range []string{"java", "javac"} {
+		path := filepath.Join(link, "bin", name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		err := runCmd(exec.Command(command, "--install", filepath.Join("/usr/bin", name), name, path,
+			systemLinkAlternativesPriority))
+		if err == nil {
+			err = runCmd(exec.Command(command, "--set", name, path))
+		}
+		if err != nil {
+			return err
+		}
+		log.Info("Registered " + path + " with " + filepath.Base(command))
+	}
+	return nil
+}
+
+func unregisterAlternatives(link string) error {
+	command := alternativesCommand()
+	if command == "" {
+		return nil
+	}
+	for _, name := range []string{"java", "javac"} {
+		path := filepath.Join(link, "bin", name)
+		// nothing to remove is not an error
+		if exec.Command(command, "--remove", name, path).Run() == nil {
+			log.Debug("Unregistered " + path + " from " + filepath.Base(command))
+		}
+	}
+	return nil
+}
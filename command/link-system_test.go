@@ -0,0 +1,70 @@
+package command
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLinkSystem(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("not supported on windows")
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	link := filepath.Join(home, "opt", "jabba-default")
+	os.Setenv("JABBA_SYSTEM_LINK", link)
+	defer os.Unsetenv("JABBA_SYSTEM_LINK")
+	prevJavaVirtualMachinesDir, prevLookPath := javaVirtualMachinesDir, lookPath
+	defer func() { javaVirtualMachinesDir, lookPath = prevJavaVirtualMachinesDir, prevLookPath }()
+	javaVirtualMachinesDir = filepath.Join(home, "JavaVirtualMachines")
+	lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	for _, ver := range []string{"1.8.0", "zulu@1.17.0"} {
+		if err := os.MkdirAll(filepath.Dir(expectedJavaPath(filepath.Join(home, "jdk", ver), runtime.GOOS)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if actual := GetSystemLink(); actual != "" {
+		t.Fatalf("actual: %v != expected: %v", actual, "")
+	}
+	for _, selector := range []string{"1.8", "zulu@1.17"} {
+		ver, err := LinkSystem(selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := filepath.Dir(filepath.Dir(expectedJavaPath(filepath.Join(home, "jdk", ver), runtime.GOOS)))
+		if actual := GetSystemLink(); actual != expected {
+			t.Fatalf("actual: %v != expected: %v", actual, expected)
+		}
+	}
+	if runtime.GOOS == "darwin" {
+		actual, _ := os.Readlink(filepath.Join(javaVirtualMachinesDir, systemLinkBundleName))
+		if expected := filepath.Join(home, "jdk", "zulu@1.17.0"); actual != expected {
+			t.Fatalf("actual: %v != expected: %v", actual, expected)
+		}
+	}
+	if err := UnlinkSystem(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Fatalf("%s wasn't removed (%v)", link, err)
+	}
+	// anything but a link is left intact
+	if err := os.MkdirAll(link, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LinkSystem("1.8"); err == nil {
+		t.Fatal("expected LinkSystem to refuse to replace a directory")
+	}
+	if err := UnlinkSystem(); err == nil {
+		t.Fatal("expected UnlinkSystem to refuse to remove a directory")
+	}
+}
@@ -19,25 +19,29 @@ func Link(selector string, dir string) error {
 	if _, err := semver.ParseVersion(selector); err != nil {
 		return err
 	}
+	invalidateState()
 	if dir == "" {
 		ver, err := LsBestMatch(selector)
 		if err != nil {
 			return err
 		}
-		return os.Remove(filepath.Join(cfg.Dir(), "jdk", ver))
+		return os.Remove(filepath.Join(cfg.JDKDir(), ver))
 	} else {
 		if err := assertJavaDistribution(dir, runtime.GOOS); err != nil {
 			return err
 		}
-		if err := os.MkdirAll(filepath.Join(cfg.Dir(), "jdk"), 0755); err != nil {
+		if err := ensureWritableDir(cfg.JDKDir()); err != nil {
 			return err
 		}
-		return os.Symlink(dir, filepath.Join(cfg.Dir(), "jdk", selector))
+		return os.Symlink(dir, filepath.Join(cfg.JDKDir(), selector))
 	}
 }
 
 func LinkLatest() error {
-	files, _ := readDir(filepath.Join(cfg.Dir(), "jdk"))
+	if err := migrateLinks(); err != nil {
+		return err
+	}
+	files, _ := readDir(cfg.JDKDir())
 	var vs, err = Ls()
 	if err != nil {
 		return err
@@ -46,11 +50,12 @@ func LinkLatest() error {
 	for _, f := range files {
 		if f.IsDir() || f.Mode()&os.ModeSymlink == os.ModeSymlink {
 			sourceVersion := f.Name()
-			if strings.Count(sourceVersion, ".") == 1 && !strings.HasPrefix(sourceVersion, "system@") {
+			if strings.Count(sourceVersion, ".") == 1 && !strings.HasPrefix(sourceVersion, "system@") &&
+				!strings.HasPrefix(sourceVersion, ".") {
 				target := GetLink(sourceVersion)
 				_, err := LsBestMatchWithVersionSlice(vs, sourceVersion)
 				if err != nil {
-					err := os.Remove(filepath.Join(cfg.Dir(), "jdk", sourceVersion))
+					err := os.Remove(filepath.Join(cfg.JDKDir(), sourceVersion))
 					if err == nil {
 						log.Info(sourceVersion + " -/> " + target)
 					}
@@ -58,19 +63,20 @@ func LinkLatest() error {
 						return err
 					}
 				} else {
-					cache[sourceVersion] = target
+					// link value (not the resolved path) as JDK itself might be a link (e.g. `jabba import`ed one)
+					cache[sourceVersion], _ = os.Readlink(filepath.Join(cfg.JDKDir(), sourceVersion))
 				}
 			}
 		}
 	}
 	for _, v := range semver.VersionSlice(vs).TrimTo(semver.VPMinor) {
 		sourceVersion := v.TrimTo(semver.VPMinor)
-		target := filepath.Join(cfg.Dir(), "jdk", v.String())
-		if v.Prerelease() == "" && cache[sourceVersion] != target && !strings.HasPrefix(sourceVersion, "system@") {
-			source := filepath.Join(cfg.Dir(), "jdk", sourceVersion)
+		target := filepath.Join(cfg.JDKDir(), v.String())
+		if v.Prerelease() == "" && cache[sourceVersion] != v.String() && !strings.HasPrefix(sourceVersion, "system@") {
+			source := filepath.Join(cfg.JDKDir(), sourceVersion)
 			log.Info(sourceVersion + " -> " + target)
 			os.Remove(source)
-			if err := os.Symlink(target, source); err != nil {
+			if err := os.Symlink(v.String(), source); err != nil {
 				return err
 			}
 		}
@@ -79,27 +85,59 @@ func LinkLatest() error {
 }
 
 func LinkAlias(name string) error {
+	if err := migrateLinks(); err != nil {
+		return err
+	}
 	var vs, err = Ls()
 	if err != nil {
 		return err
 	}
-	return linkAlias(name, vs)
+	if err := linkAlias(name, vs); err != nil {
+		return err
+	}
+	// aliases referencing this one (e.g. lts -> work/backend) have to follow
+	names, err := Aliases()
+	if err != nil {
+		return err
+	}
+	for _, other := range names {
+		_, chain, err := resolveAlias(other, GetAlias)
+		if err != nil || other == name {
+			continue
+		}
+		for _, via := range chain {
+			if via == name {
+				if err := linkAlias(other, vs); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
 }
 
 func linkAlias(name string, vs []*semver.Version) error {
+	// namespaced aliases (e.g. work/backend) are not linked
+	if strings.Contains(name, "/") {
+		return nil
+	}
 	defaultAlias := GetAlias(name)
+	if defaultAlias != "" {
+		defaultAlias, _ = ResolveAlias(name)
+	}
 	if defaultAlias != "" {
 		defaultAlias, _ = LsBestMatchWithVersionSlice(vs, defaultAlias)
 	}
 	sourceRef := /*"alias@" + */ name
-	source := filepath.Join(cfg.Dir(), "jdk", sourceRef)
+	source := filepath.Join(cfg.JDKDir(), sourceRef)
 	sourceTarget := GetLink(sourceRef)
 	if defaultAlias != "" {
-		target := filepath.Join(cfg.Dir(), "jdk", defaultAlias)
+		target := filepath.Join(cfg.JDKDir(), defaultAlias)
 		if sourceTarget != target {
 			log.Info(sourceRef + " -> " + target)
 			os.Remove(source)
-			if err := os.Symlink(target, source); err != nil {
+			if err := os.Symlink(defaultAlias, source); err != nil {
 				return err
 			}
 		}
@@ -116,9 +154,41 @@ func linkAlias(name string, vs []*semver.Version) error {
 }
 
 func GetLink(name string) string {
-	res, err := filepath.EvalSymlinks(filepath.Join(cfg.Dir(), "jdk", name))
+	res, err := filepath.EvalSymlinks(filepath.Join(cfg.JDKDir(), name))
 	if err != nil {
 		return ""
 	}
 	return res
 }
+
+// migrateLinks rewrites absolute links (created by older versions of jabba) to JDKs in $JABBA_HOME/jdk
+// (e.g. 1.8 -> /home/user/.jabba/jdk/1.8.0) into relative ones (1.8 -> 1.8.0), so that links keep working
+// when $JABBA_HOME is moved (or mounted at a different path).
+// Links to JDKs outside of $JABBA_HOME/jdk (i.e. `jabba link`ed system@... ones) are left as is.
+func migrateLinks() error {
+	dir := cfg.JDKDir()
+	files, _ := readDir(dir)
+	for _, f := range files {
+		if f.Mode()&os.ModeSymlink != os.ModeSymlink || strings.HasPrefix(f.Name(), "system@") {
+			continue
+		}
+		link := filepath.Join(dir, f.Name())
+		target, err := os.Readlink(link)
+		if err != nil || !filepath.IsAbs(target) || filepath.Base(filepath.Dir(target)) != "jdk" {
+			continue
+		}
+		// $JABBA_HOME might have been moved, which is why target's name is checked against the current one
+		name := filepath.Base(target)
+		if stat, err := os.Lstat(filepath.Join(dir, name)); err != nil || !stat.IsDir() {
+			continue
+		}
+		log.Debug("Replacing ", link, " -> ", target, " with a relative link")
+		if err := os.Remove(link); err != nil {
+			return err
+		}
+		if err := os.Symlink(name, link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLinksAreRelative(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges")
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	jdk := filepath.Join(home, "jdk")
+	for _, dir := range []string{"1.8.0", "1.7.2"} {
+		if err := os.MkdirAll(filepath.Join(jdk, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// links created before JABBA_HOME was moved
+	for link, target := range map[string]string{
+		"1.8": "/old/.jabba/jdk/1.8.0", "1.7": "/old/.jabba/jdk/1.7.2", "lts": "/old/.jabba/jdk/1.8.0",
+	} {
+		if err := os.Symlink(target, filepath.Join(jdk, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SetAlias("default", "1.7"); err != nil {
+		t.Fatal(err)
+	}
+	if err := LinkLatest(); err != nil {
+		t.Fatal(err)
+	}
+	for link, expected := range map[string]string{
+		"1.8": "1.8.0", "1.7": "1.7.2", "default": "1.7.2", "lts": "1.8.0",
+	} {
+		actual, err := os.Readlink(filepath.Join(jdk, link))
+		if err != nil || actual != expected {
+			t.Fatalf("%s: actual: %v (%v) != expected: %v", link, actual, err, expected)
+		}
+	}
+	// JABBA_HOME relocation
+	moved := home + "-moved"
+	if err := os.Rename(home, moved); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(moved)
+	if _, err := os.Stat(filepath.Join(moved, "jdk", "default")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,231 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+// Lockfiles are meant to be committed, so `jabba lock` keeps the diff down to the entries that actually changed:
+// entries stay in the order they were in (new ones are appended), fields are always written in the same order (and
+// aliases sorted by name) (see WriteLockfile) and the file isn't touched at all if nothing changed.
+
+// LockChange is a single difference between two revisions of the lockfile (see DiffLockfiles).
+type LockChange struct {
+	// alias name ("" if change is about JDK)
+	Alias string `json:"alias,omitempty"`
+	// "" if entry was added
+	From string `json:"from,omitempty"`
+	// "" if entry was removed
+	To string `json:"to,omitempty"`
+}
+
+func (c LockChange) String() string {
+	var s string
+	switch {
+	case c.From == "":
+		s = c.To + " (added)"
+	case c.To == "":
+		s = c.From + " (removed)"
+	case c.From == c.To:
+		s = c.From + " (archive changed)"
+	default:
+		s = c.From + " -> " + c.To
+	}
+	if c.Alias != "" {
+		s = "alias " + c.Alias + ": " + s
+	}
+	return s
+}
+
+// LockInstalled returns lock with entries replaced by the JDKs currently installed (see Export), keeping the order of
+// the ones that were already there.
+func LockInstalled(lock *Lockfile) (*Lockfile, error) {
+	installed, err := Export()
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[string]LockedJDK)
+	for _, jdk := range installed.JDKs {
+		byVersion[jdk.Version] = jdk
+	}
+	r := &Lockfile{JDKs: []LockedJDK{}, Aliases: installed.Aliases}
+	for _, jdk := range lock.JDKs {
+		if current, ok := byVersion[jdk.Version]; ok {
+			r.JDKs = append(r.JDKs, current)
+			delete(byVersion, jdk.Version)
+		}
+	}
+	for _, jdk := range installed.JDKs {
+		if _, ok := byVersion[jdk.Version]; ok {
+			r.JDKs = append(r.JDKs, jdk)
+		}
+	}
+	return r, nil
+}
+
+// UpdateLockfile returns lock with every entry (only those matching selectors, if any are given) bumped to the
+// latest release of its line (e.g. zulu@1.17.0 -> zulu@1.17.8 (see `jabba upgrade`)) (for the platform entry was
+// locked on). Aliases follow. Nothing is installed (archive is fetched only if index doesn't specify its sha256).
+func UpdateLockfile(lock *Lockfile, selectors []string, ignoreRecommended bool) (*Lockfile, error) {
+	var ranges []*semver.Range
+	for _, selector := range selectors {
+		rng, err := semver.ParseRange(selector)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, rng)
+	}
+	r := &Lockfile{JDKs: []LockedJDK{}}
+	if lock.Aliases != nil {
+		r.Aliases = make(map[string]string)
+		for name, value := range lock.Aliases {
+			r.Aliases[name] = value
+		}
+	}
+	locked := make(map[string]bool)
+	for _, jdk := range lock.JDKs {
+		locked[jdk.Version] = true
+	}
+	for _, jdk := range lock.JDKs {
+		ver, err := semver.ParseVersion(jdk.Version)
+		if err != nil {
+			return nil, err
+		}
+		matches := len(ranges) == 0
+		for _, rng := range ranges {
+			matches = matches || rng.Contains(ver)
+		}
+		if !matches {
+			r.JDKs = append(r.JDKs, jdk)
+			continue
+		}
+		updated, err := latestLockedJDK(jdk, ver, ignoreRecommended)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", jdk.Version, err)
+		}
+		if updated.Version != jdk.Version && locked[updated.Version] {
+			// the latest release is locked already (as a separate entry)
+			log.Info("Dropping ", jdk.Version, " (", updated.Version, " is locked already)")
+		} else {
+			r.JDKs = append(r.JDKs, *updated)
+		}
+		for name, value := range r.Aliases {
+			if value == jdk.Version {
+				r.Aliases[name] = updated.Version
+			}
+		}
+	}
+	return r, nil
+}
+
+func latestLockedJDK(jdk LockedJDK, ver *semver.Version, ignoreRecommended bool) (*LockedJDK, error) {
+	opts := InstallOptions{OS: jdk.OS, Arch: jdk.Arch, Any: ignoreRecommended}
+	switch jdk.OS {
+	case "linux-musl":
+		opts.OS, opts.Libc = "linux", "musl"
+	case "linux":
+		// otherwise it's auto-detected (for the host)
+		opts.Libc = "glibc"
+	}
+	latest, release, err := resolveRelease(upgradeRange(ver), opts)
+	if err != nil {
+		return nil, err
+	}
+	if !ver.LessThan(latest) {
+		return &jdk, nil
+	}
+	url := release.URL
+	if matched, _ := regexp.MatchString("^\\w+[+]\\w+://", url); !matched {
+		return nil, errors.New("URL must contain qualifier, e.g. tgz+http://...")
+	}
+	fileType := url[:strings.Index(url, "+")]
+	url, f, err := splitFragment(url[strings.Index(url, "+")+1:])
+	if err != nil {
+		return nil, err
+	}
+	checksum := f.checksum
+	if checksum == "" {
+		log.Info("Fetching ", url, " to calculate sha256")
+		if checksum, err = Checksum(url); err != nil {
+			return nil, err
+		}
+	}
+	return &LockedJDK{Version: latest.String(), URL: fileType + "+" + url,
+		SHA256: strings.TrimPrefix(checksum, "sha256="), OS: release.os, Arch: release.arch,
+		Components: jdk.Components}, nil
+}
+
+// DiffLockfiles lists what changed between from and to. JDK removed & added within the same line (e.g. zulu@1.17.0
+// & zulu@1.17.8) is reported as a version bump.
+func DiffLockfiles(from, to *Lockfile) []LockChange {
+	before := make(map[string]LockedJDK)
+	for _, jdk := range from.JDKs {
+		before[jdk.Version] = jdk
+	}
+	after := make(map[string]LockedJDK)
+	for _, jdk := range to.JDKs {
+		after[jdk.Version] = jdk
+	}
+	line := func(version string) string {
+		if ver, err := semver.ParseVersion(version); err == nil {
+			return upgradeRange(ver)
+		}
+		return version
+	}
+	var changes []LockChange
+	var removed []string
+	for _, jdk := range from.JDKs {
+		if _, ok := after[jdk.Version]; !ok {
+			removed = append(removed, jdk.Version)
+		}
+	}
+	for _, jdk := range to.JDKs {
+		prev, ok := before[jdk.Version]
+		if ok {
+			if !reflect.DeepEqual(prev, jdk) {
+				changes = append(changes, LockChange{From: jdk.Version, To: jdk.Version})
+			}
+			continue
+		}
+		change := LockChange{To: jdk.Version}
+		for i, version := range removed {
+			if line(version) == line(jdk.Version) {
+				change.From = version
+				removed = append(removed[:i], removed[i+1:]...)
+				break
+			}
+		}
+		changes = append(changes, change)
+	}
+	for _, version := range removed {
+		changes = append(changes, LockChange{From: version})
+	}
+	for _, name := range sortedKeys(from.Aliases, to.Aliases) {
+		if from.Aliases[name] != to.Aliases[name] {
+			changes = append(changes, LockChange{Alias: name, From: from.Aliases[name], To: to.Aliases[name]})
+		}
+	}
+	return changes
+}
+
+func sortedKeys(maps ...map[string]string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestUpdateLockfile(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	sum := strings.Repeat("b", 64)
+	index := filepath.Join(home, "index.json")
+	if err := ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"`+runtime.GOARCH+`": {"jdk@zulu": {
+		"1.17.0": "tgz+https://example.com/zulu-1.17.0.tar.gz#sha256=`+strings.Repeat("a", 64)+`",
+		"1.17.8": "tgz+https://example.com/zulu-1.17.8.tar.gz#sha256=`+sum+`",
+		"1.21.0": "tgz+https://example.com/zulu-1.21.0.tar.gz#sha256=`+strings.Repeat("c", 64)+`"
+	}}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	lock := &Lockfile{JDKs: []LockedJDK{
+		{Version: "zulu@1.21.0", URL: "tgz+https://example.com/zulu-1.21.0.tar.gz", SHA256: strings.Repeat("c", 64)},
+		{Version: "zulu@1.17.0", URL: "tgz+https://example.com/zulu-1.17.0.tar.gz", SHA256: strings.Repeat("a", 64)},
+	}, Aliases: map[string]string{"default": "zulu@1.17.0", "latest": "zulu@1.21.0"}}
+	updated, err := UpdateLockfile(lock, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Lockfile{JDKs: []LockedJDK{
+		lock.JDKs[0],
+		{Version: "zulu@1.17.8", URL: "tgz+https://example.com/zulu-1.17.8.tar.gz", SHA256: sum,
+			OS: runtime.GOOS, Arch: HostArch()},
+	}, Aliases: map[string]string{"default": "zulu@1.17.8", "latest": "zulu@1.21.0"}}
+	if !reflect.DeepEqual(updated, expected) {
+		t.Fatalf("actual: %+v != expected: %+v", updated, expected)
+	}
+	if lock.Aliases["default"] != "zulu@1.17.0" {
+		t.Fatal("lockfile passed to UpdateLockfile is not expected to be modified")
+	}
+	changes := DiffLockfiles(lock, updated)
+	expectedChanges := []LockChange{{From: "zulu@1.17.0", To: "zulu@1.17.8"},
+		{Alias: "default", From: "zulu@1.17.0", To: "zulu@1.17.8"}}
+	if !reflect.DeepEqual(changes, expectedChanges) {
+		t.Fatalf("actual: %v != expected: %v", changes, expectedChanges)
+	}
+	// only entries matching selectors are updated
+	if updated, err := UpdateLockfile(lock, []string{"zulu@1.21"}, false); err != nil ||
+		len(DiffLockfiles(lock, updated)) != 0 {
+		t.Fatalf("expected nothing to be updated (%v)", err)
+	}
+	// only the line that changed is rewritten
+	file := filepath.Join(home, "jabba.lock")
+	if err := WriteLockfile(file, lock); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := ioutil.ReadFile(file)
+	if err := WriteLockfile(file, updated); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := ioutil.ReadFile(file)
+	remaining := make(map[string]int)
+	for _, line := range strings.Split(string(after), "\n") {
+		remaining[line]++
+	}
+	var removed []string
+	for _, line := range strings.Split(string(before), "\n") {
+		if remaining[line] == 0 {
+			removed = append(removed, strings.TrimSpace(line))
+			continue
+		}
+		remaining[line]--
+	}
+	// zulu@1.17.0 entry (version, url & sha256) & default alias
+	if len(removed) != 4 || !strings.Contains(removed[0], "zulu@1.17.0") ||
+		!strings.Contains(removed[3], "default") {
+		t.Fatalf("unexpected diff (removed: %v):\n%s", removed, after)
+	}
+}
+
+func TestDiffLockfiles(t *testing.T) {
+	from := &Lockfile{JDKs: []LockedJDK{{Version: "1.8.0", SHA256: "a"}, {Version: "zulu@1.17.0", SHA256: "b"},
+		{Version: "zulu@1.11.0", SHA256: "c"}}}
+	to := &Lockfile{JDKs: []LockedJDK{{Version: "1.8.0", SHA256: "x"}, {Version: "zulu@1.17.1", SHA256: "b"},
+		{Version: "temurin@1.21.0", SHA256: "d"}}, Aliases: map[string]string{"default": "1.8.0"}}
+	var actual []string
+	for _, change := range DiffLockfiles(from, to) {
+		actual = append(actual, change.String())
+	}
+	expected := []string{"1.8.0 (archive changed)", "zulu@1.17.0 -> zulu@1.17.1", "temurin@1.21.0 (added)",
+		"zulu@1.11.0 (removed)", "alias default: 1.8.0 (added)"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
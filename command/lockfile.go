@@ -0,0 +1,202 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+// Lockfile lists installed JDKs (pinned to the exact archive) & aliases (see `jabba export` / `jabba import`).
+type Lockfile struct {
+	JDKs    []LockedJDK       `json:"jdks"`
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+type LockedJDK struct {
+	Version string `json:"version"`
+	// "<qualifier>+<url>"
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+	// GraalVM components to add once JDK is installed (see AddComponents)
+	Components []string `json:"components,omitempty"`
+}
+
+// Export pins every JDK installed into $JABBA_HOME/jdk to the archive it was installed from.
+// JDKs that were `jabba link`ed or installed by an older version of jabba (i.e. without metadata) are skipped.
+func Export() (*Lockfile, error) {
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	lock := &Lockfile{JDKs: []LockedJDK{}}
+	for _, v := range vs {
+		ver := v.String()
+		if strings.HasPrefix(ver, "system@") {
+			log.Warn("Skipping ", ver, " (link to system JDK)")
+			continue
+		}
+		meta, err := readInstallMeta(ver)
+		if err != nil {
+			log.Warn("Skipping ", ver, " (there is no metadata recorded for it (reinstall it to have it exported))")
+			continue
+		}
+		fileType := meta.Type
+		if fileType == "" {
+			fileType = fileTypeOf(meta.URL)
+		}
+		if fileType == "" || meta.SHA256 == "" {
+			log.Warn("Skipping ", ver, " (archive type / sha256 is unknown (reinstall it to have it exported))")
+			continue
+		}
+		lock.JDKs = append(lock.JDKs, LockedJDK{Version: ver, URL: fileType + "+" + meta.URL, SHA256: meta.SHA256,
+			OS: meta.OS, Arch: meta.Arch, Components: meta.Components})
+	}
+	names, err := Aliases()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if lock.Aliases == nil {
+			lock.Aliases = make(map[string]string)
+		}
+		lock.Aliases[name] = strings.TrimSpace(GetAlias(name))
+	}
+	return lock, nil
+}
+
+func ReadLockfile(file string) (*Lockfile, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var lock Lockfile
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return nil, fmt.Errorf("%s is not a valid lockfile (%v)", file, err)
+	}
+	return &lock, nil
+}
+
+// ImportLockfile installs every JDK listed in the lockfile (verifying sha256 of the archives) and sets aliases.
+func ImportLockfile(lock *Lockfile) ([]*InstallResult, error) {
+	hostOS, err := TargetOS(runtime.GOOS, "")
+	if err != nil {
+		return nil, err
+	}
+	// all-or-nothing as far as validation goes
+	for _, jdk := range lock.JDKs {
+		if jdk.SHA256 == "" {
+			return nil, fmt.Errorf("%s is not pinned (sha256 is missing)", jdk.Version)
+		}
+		// amd64 JDKs run on darwin/arm64 through Rosetta 2
+		rosetta := jdk.Arch == "amd64" && runtime.GOOS == "darwin" && runtime.GOARCH == "arm64"
+		if (jdk.OS != "" && jdk.OS != runtime.GOOS && jdk.OS != hostOS) ||
+			(jdk.Arch != "" && jdk.Arch != HostArch() && !rosetta) {
+			return nil, fmt.Errorf("%s was exported on %s/%s (while this is %s/%s)", jdk.Version, jdk.OS, jdk.Arch,
+				runtime.GOOS, HostArch())
+		}
+	}
+	var results []*InstallResult
+	for _, jdk := range lock.JDKs {
+		result, err := Install(jdk.Version+"="+jdk.URL+"#sha256="+jdk.SHA256,
+			InstallOptions{Components: jdk.Components})
+		if err != nil {
+			return results, err
+		}
+		if result.AlreadyInstalled {
+			if meta, err := readInstallMeta(jdk.Version); err == nil && meta.SHA256 != "" &&
+				!strings.EqualFold(meta.SHA256, jdk.SHA256) {
+				log.Warn(jdk.Version, " is already installed (from an archive with sha256=", meta.SHA256,
+					" (lockfile says ", jdk.SHA256, "))")
+			}
+			if missing := missingComponents(jdk.Version, jdk.Components); len(missing) != 0 {
+				if _, err := AddComponents(jdk.Version, missing); err != nil {
+					return results, err
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	names := make([]string, 0, len(lock.Aliases))
+	for name := range lock.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := SetAlias(name, lock.Aliases[name]); err != nil {
+			return results, err
+		}
+		if err := LinkAlias(name); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// WriteLockfile writes lock to the file (as `jabba export` would print it).
+func WriteLockfile(file string, lock *Lockfile) error {
+	b, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(b, '\n'), 0644)
+}
+
+// Pin adds jdk to the lockfile (replacing entry of the same version, if any).
+func (lock *Lockfile) Pin(jdk LockedJDK) {
+	for i := range lock.JDKs {
+		if lock.JDKs[i].Version == jdk.Version {
+			lock.JDKs[i] = jdk
+			return
+		}
+	}
+	lock.JDKs = append(lock.JDKs, jdk)
+}
+
+// IsURLSelector returns true if selector is in the (legacy) form of <version>=<qualifier>+<url>.
+func IsURLSelector(selector string) bool {
+	return strings.Contains(selector, "=") && strings.Contains(selector, "://")
+}
+
+// PinURL turns legacy <version>=<qualifier>+<url> into lockfile entry, fetching the archive to record its sha256
+// (sha256 specified in the URL (#sha256=...), if any, has to match).
+func PinURL(selector string) (*LockedJDK, error) {
+	if !IsURLSelector(selector) {
+		return nil, fmt.Errorf("%s is not in the form of <version>=<qualifier>+<url>", selector)
+	}
+	split := strings.SplitN(selector, "=", 2)
+	ver, err := semver.ParseVersion(split[0])
+	if err != nil {
+		return nil, err
+	}
+	if matched, _ := regexp.MatchString("^\\w+[+]\\w+://", split[1]); !matched {
+		return nil, errors.New("URL must contain qualifier, e.g. tgz+http://...")
+	}
+	fileType := split[1][0:strings.Index(split[1], "+")]
+	url, f, err := splitFragment(split[1][strings.Index(split[1], "+")+1:])
+	if err != nil {
+		return nil, err
+	}
+	if f.sig != "" {
+		return nil, fmt.Errorf("%s: signatures cannot be recorded in the lockfile (remove #sig=...&key=...)", selector)
+	}
+	log.Info("Fetching ", url, " to calculate sha256")
+	checksum, err := Checksum(url)
+	if err != nil {
+		return nil, err
+	}
+	if f.checksum != "" && !strings.EqualFold(f.checksum, checksum) {
+		return nil, fmt.Errorf("%s does not match %s (%s)", checksum, f.checksum, url)
+	}
+	return &LockedJDK{Version: ver.String(), URL: fileType + "+" + url,
+		SHA256: strings.TrimPrefix(checksum, "sha256=")}, nil
+}
@@ -0,0 +1,113 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestExportImportLockfile(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	archive := filepath.Join(home, "jdk.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, file := range []string{java, "jdk/release"} {
+		ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755}))
+	}
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	url := "tgz+file://" + filepath.ToSlash(archive)
+	_, err = Install("1.17.0-custom="+url, InstallOptions{})
+	ok(err)
+	installFakeJDKs(t, home, "zulu@1.8.0") // no metadata
+	ok(SetAlias("default", "1.17.0-custom"))
+	lock, err := Export()
+	ok(err)
+	sha256, err := sha256OfFile(archive)
+	ok(err)
+	expected := &Lockfile{
+		JDKs:    []LockedJDK{{Version: "1.17.0-custom", URL: url, SHA256: sha256, OS: runtime.GOOS, Arch: runtime.GOARCH}},
+		Aliases: map[string]string{"default": "1.17.0-custom"},
+	}
+	if !reflect.DeepEqual(lock, expected) {
+		t.Fatalf("actual: %+v != expected: %+v", lock, expected)
+	}
+	// "another machine"
+	ok(os.RemoveAll(filepath.Join(home, "jdk")))
+	ok(SetAlias("default", ""))
+	results, err := ImportLockfile(lock)
+	ok(err)
+	if len(results) != 1 || results[0].Version != "1.17.0-custom" || !results[0].ChecksumVerified {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if actual := GetAlias("default"); actual != "1.17.0-custom" {
+		t.Fatalf("actual: %v != expected: %v", actual, "1.17.0-custom")
+	}
+	ok(os.RemoveAll(filepath.Join(home, "jdk")))
+	tampered := *lock
+	tampered.JDKs = []LockedJDK{lock.JDKs[0]}
+	tampered.JDKs[0].SHA256 = "0000000000000000000000000000000000000000000000000000000000000000"
+	if _, err := ImportLockfile(&tampered); err == nil {
+		t.Fatal("expected checksum verification to fail")
+	}
+	tampered.JDKs[0].Arch = "s390x"
+	if _, err := ImportLockfile(&tampered); err == nil {
+		t.Fatal("expected JDK exported on another platform to be rejected")
+	}
+}
+
+func TestPinURL(t *testing.T) {
+	content := []byte("archive")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+	jdk, err := PinURL("1.8.0-custom=tgz+" + server.URL + "/jdk.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := fmt.Sprintf("%x", sha256.Sum256(content))
+	expected := LockedJDK{Version: "1.8.0-custom", URL: "tgz+" + server.URL + "/jdk.tar.gz", SHA256: sum}
+	if !reflect.DeepEqual(*jdk, expected) {
+		t.Fatalf("actual: %+v != expected: %+v", *jdk, expected)
+	}
+	if _, err := PinURL("1.8.0-custom=tgz+" + server.URL + "/jdk.tar.gz#sha256=" + sum); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PinURL("1.8.0-custom=tgz+" + server.URL + "/jdk.tar.gz#sha256=00"); err == nil {
+		t.Fatal("expected mismatching sha256 to be reported")
+	}
+	if _, err := PinURL("zulu@1.8"); err == nil {
+		t.Fatal("expected selector without URL to be rejected")
+	}
+	lock := &Lockfile{JDKs: []LockedJDK{{Version: "1.8.0-custom", URL: "tgz+https://example.com/old.tar.gz"}}}
+	lock.Pin(*jdk)
+	if !reflect.DeepEqual(lock.JDKs, []LockedJDK{expected}) {
+		t.Fatalf("actual: %+v != expected: %+v", lock.JDKs, []LockedJDK{expected})
+	}
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+// ReleaseQuery narrows down releases listed by `jabba ls-remote` (see QueryReleases).
+type ReleaseQuery struct {
+	// range (e.g. "zulu@~1.17.2", "zulu@17.*" (versions the way vendors spell them are understood too)) or, if it's
+	// not one, text to look for in versions (e.g. "openj9"), "" to match everything
+	Selector string
+	// vendor (qualifier) releases must come from ("" for any)
+	Vendor  string
+	Channel string
+	// true to keep releases of LTS lines only (see IsLTS)
+	LTSOnly bool
+}
+
+// QueryReleases returns releases matching the query. Range that doesn't match anything as is is retried with
+// versions rewritten into 1.<major> scheme ("zulu@17.*" -> "zulu@1.17.*").
+func QueryReleases(releaseMap map[*semver.Version]Release, q ReleaseQuery) map[*semver.Version]Release {
+	var r map[*semver.Version]Release
+	if rng, err := semver.ParseRange(q.Selector); q.Selector == "" || err != nil {
+		r = FilterReleases(releaseMap, nil, q.Channel)
+		if q.Selector != "" {
+			r = searchReleases(r, q.Selector)
+		}
+	} else {
+		r = FilterReleases(releaseMap, rng, q.Channel)
+		if foreign := fromForeignRange(q.Selector); len(r) == 0 && foreign != q.Selector {
+			if rng, err := semver.ParseRange(foreign); err == nil {
+				r = FilterReleases(releaseMap, rng, q.Channel)
+			}
+		}
+	}
+	for v := range r {
+		if q.Vendor != "" && v.Qualifier() != q.Vendor || q.LTSOnly && !IsLTS(javaMajor(v)) {
+			delete(r, v)
+		}
+	}
+	return r
+}
+
+// searchReleases returns releases version of which contains text (case-insensitive).
+func searchReleases(releaseMap map[*semver.Version]Release, text string) map[*semver.Version]Release {
+	text = strings.ToLower(text)
+	r := make(map[*semver.Version]Release)
+	for v, release := range releaseMap {
+		if strings.Contains(strings.ToLower(v.String()), text) {
+			r[v] = release
+		}
+	}
+	return r
+}
+
+// major version (other than 1) that starts (optionally qualified) version in the range (e.g. "zulu@>=17.0.1")
+var foreignRangeVersionRegexp = regexp.MustCompile(`^((?:[^@]*@)?[<>=~^!]*)([2-9]|[1-9][0-9]+)([.-]|$)`)
+
+// fromForeignRange rewrites versions the way vendors spell them (e.g. 17, 17.0.9) into 1.<major> scheme
+// ("zulu@17.*" -> "zulu@1.17.*", ">=17 <21" -> ">=1.17 <1.21").
+func fromForeignRange(rng string) string {
+	fields := strings.Split(rng, " ")
+	for i, field := range fields {
+		fields[i] = foreignRangeVersionRegexp.ReplaceAllString(field, "${1}1.${2}${3}")
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+func TestFromForeignRange(t *testing.T) {
+	for rng, expected := range map[string]string{
+		"zulu@17.*":          "zulu@1.17.*",
+		">=17 <21":           ">=1.17 <1.21",
+		"17 - 21 || 8":       "1.17 - 1.21 || 1.8",
+		"zulu@>=17.0.1":      "zulu@>=1.17.0.1",
+		"1.8":                "1.8",
+		"zulu@~1.17.0-8":     "zulu@~1.17.0-8",
+		"graalvm-ce-java11@": "graalvm-ce-java11@",
+	} {
+		if actual := fromForeignRange(rng); actual != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", rng, actual, expected)
+		}
+	}
+}
+
+func TestQueryReleases(t *testing.T) {
+	releaseMap := make(map[*semver.Version]Release)
+	for _, ver := range []string{"zulu@1.8.0", "zulu@1.17.0", "zulu@1.21.0", "zulu@1.22.0", "1.17.1",
+		"adopt-openj9@1.11.0", "adopt@1.11.0", "graalvm@19.3.0", "zulu@1.23.0-ea.1"} {
+		v, err := semver.ParseVersion(ver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		releaseMap[v] = Release{}
+	}
+	for _, test := range []struct {
+		query    ReleaseQuery
+		expected []string
+	}{
+		{ReleaseQuery{Selector: "zulu@17.*"}, []string{"zulu@1.17.0"}},
+		{ReleaseQuery{Selector: "graalvm@19"}, []string{"graalvm@19.3.0"}},
+		{ReleaseQuery{Selector: ">=17"}, []string{"1.17.1"}},
+		{ReleaseQuery{Selector: "OpenJ9"}, []string{"adopt-openj9@1.11.0"}},
+		{ReleaseQuery{Selector: "adopt", Vendor: "adopt"}, []string{"adopt@1.11.0"}},
+		{ReleaseQuery{Vendor: "zulu", LTSOnly: true}, []string{"zulu@1.17.0", "zulu@1.21.0", "zulu@1.8.0"}},
+		{ReleaseQuery{Vendor: "zulu", Channel: ChannelEA}, []string{"zulu@1.23.0-ea.1"}},
+	} {
+		var actual []string
+		for v := range QueryReleases(releaseMap, test.query) {
+			actual = append(actual, v.String())
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Fatalf("%+v: actual: %v != expected: %v", test.query, actual, test.expected)
+		}
+	}
+}
@@ -3,21 +3,177 @@ package command
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
-	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/shyiko/jabba/cfg"
 	"github.com/shyiko/jabba/semver"
 )
 
 type byOS map[string]byArch
 type byArch map[string]byDistribution
-type byDistribution map[string]map[string]string
+type byDistribution map[string]map[string]Release
 
-func LsRemote(os, arch string) (map[*semver.Version]string, error) {
-	cnt, err := fetch(cfg.Index())
+// index entry, either "<qualifier>+<url>" or
+// {"url": "<qualifier>+<url>", "requires": {"glibc": "2.17", "macos": "10.12"}, "sig": ".asc", "key": "<key>"}
+type Release struct {
+	URL string `json:"url"`
+	// minimum version of the runtime component (see checkRequirements) required by the JDK
+	Requires map[string]string `json:"requires,omitempty"`
+	// detached OpenPGP signature & key it was made with (see verifySignature)
+	Sig string `json:"sig,omitempty"`
+	Key string `json:"key,omitempty"`
+	// true if release is the one vendor recommends (e.g. latest TCK-certified GA build)
+	Recommended bool `json:"recommended,omitempty"`
+	// "ga" or "ea" (early-access / nightly builds) ("" means the one implied by the version (see channelOf))
+	Channel string `json:"channel,omitempty"`
+	// short message for the users of the release (e.g. "known TLS bug, prefer 17.0.10") (shown once JDK is installed
+	// and kept in its metadata (see `jabba info`))
+	Notice string `json:"notice,omitempty"`
+	// platform release was resolved for (see resolveRelease)
+	os, arch string
+}
+
+func (r *Release) UnmarshalJSON(b []byte) error {
+	var url string
+	if err := json.Unmarshal(b, &url); err == nil {
+		*r = Release{URL: url}
+		return nil
+	}
+	type release Release // prevents recursion
+	return json.Unmarshal(b, (*release)(r))
+}
+
+// Release channels (see Release.Channel). ChannelAll (`--include-ea`) selects releases of both.
+const (
+	ChannelGA  = "ga"
+	ChannelEA  = "ea"
+	ChannelAll = "all"
+)
+
+// ANSI escape sequence (e.g. "\x1b[31m")
+var ansiEscapeRegexp = regexp.MustCompile("\x1b\\[[0-9;?]*[ -/]*[@-~]")
+
+// noticeOf returns notice of the release fit for the terminal (single line, escape sequences & control characters
+// removed).
+func noticeOf(release Release) string {
+	notice := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, ansiEscapeRegexp.ReplaceAllString(release.Notice, ""))
+	return strings.Join(strings.Fields(notice), " ")
+}
+
+// channelOf returns channel of the release. Releases that don't specify one are early-access if pre-release part
+// of the version starts with a non-numeric identifier (e.g. openjdk@1.22.0-ea.5, graalvm@23.1.0-dev.20230801),
+// numeric one being a GA build number (e.g. zulu@1.17.0-8).
+func channelOf(v *semver.Version, release Release) string {
+	if release.Channel != "" {
+		return release.Channel
+	}
+	pre := strings.SplitN(v.Prerelease(), ".", 2)[0]
+	if strings.Trim(pre, "0123456789") != "" {
+		return ChannelEA
+	}
+	return ChannelGA
+}
+
+// ValidateChannel checks that channel is one of "ga", "ea" or "all".
+func ValidateChannel(channel string) error {
+	switch channel {
+	case ChannelGA, ChannelEA, ChannelAll:
+		return nil
+	}
+	return fmt.Errorf("Unknown release channel \"%s\" (expected \"ga\", \"ea\" or \"all\")", channel)
+}
+
+// inChannel tells whether release is in the channel ("" means "ga") and matches the range (nil matches
+// everything). Early-access releases are matched even if range doesn't opt into pre-releases
+// (e.g. "openjdk@1.22" matches openjdk@1.22.0-ea.5 with --channel=ea).
+func inChannel(rng *semver.Range, v *semver.Version, release Release, channel string) bool {
+	if channel == "" {
+		channel = ChannelGA
+	}
+	rc := channelOf(v, release)
+	if channel != ChannelAll && rc != channel {
+		return false
+	}
+	if rng == nil {
+		return true
+	}
+	if rc == ChannelEA {
+		return rng.ContainsPrerelease(v)
+	}
+	return rng.Contains(v)
+}
+
+// FilterReleases returns releases in the channel that match the range (see inChannel).
+func FilterReleases(releaseMap map[*semver.Version]Release, rng *semver.Range,
+	channel string) map[*semver.Version]Release {
+	r := make(map[*semver.Version]Release)
+	for v, release := range releaseMap {
+		if inChannel(rng, v, release, channel) {
+			r[v] = release
+		}
+	}
+	return r
+}
+
+// LsRemote lists releases available from the default providers (see cfg.Providers).
+func LsRemote(os, arch string) (map[*semver.Version]Release, error) {
+	return LsRemoteFrom(cfg.Providers(), os, arch)
+}
+
+// LsRemoteFrom merges releases from the specified providers.
+// In case of a conflict (same version) provider listed first wins.
+func LsRemoteFrom(providerNames []string, os, arch string) (map[*semver.Version]Release, error) {
+	releaseMap := make(map[*semver.Version]Release)
+	seen := make(map[string]bool)
+	for _, name := range providerNames {
+		provider, err := GetProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		rm, err := provider.ListReleases(os, arch)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", provider.Name(), err)
+		}
+		for v, release := range rm {
+			if !seen[v.String()] {
+				seen[v.String()] = true
+				releaseMap[v] = release
+			}
+		}
+	}
+	return releaseMap, nil
+}
+
+type indexProvider struct{}
+
+func (indexProvider) Name() string {
+	return "index"
+}
+
+func (indexProvider) ListReleases(os, arch string) (map[*semver.Version]Release, error) {
+	var cnt []byte
+	var err error
+	// registry URLs are mirrors of each other
+	for _, url := range cfg.Registry() {
+		if cnt, err = fetchIndex(url, false); err == nil {
+			break
+		}
+		log.Warn("Failed to fetch ", url, " (", err, ")")
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -26,7 +182,7 @@ func LsRemote(os, arch string) (map[*semver.Version]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	releaseMap := make(map[*semver.Version]string)
+	releaseMap := make(map[*semver.Version]Release)
 	for key, value := range index[os][arch] {
 		var prefix string
 		if key != "jdk" {
@@ -35,19 +191,22 @@ func LsRemote(os, arch string) (map[*semver.Version]string, error) {
 			}
 			prefix = key[strings.Index(key, "@")+1:] + "@"
 		}
-		for ver, url := range value {
+		for ver, release := range value {
 			v, err := semver.ParseVersion(prefix + ver)
 			if err != nil {
 				return nil, err
 			}
-			releaseMap[v] = url
+			releaseMap[v] = release
 		}
 	}
 	return releaseMap, nil
 }
 
 func fetch(url string) (content []byte, err error) {
-	client := http.Client{Transport: RedirectTracer{}}
+	if strings.HasPrefix(url, "file://") {
+		return ioutil.ReadFile(strings.TrimPrefix(url, "file://"))
+	}
+	client := newHTTPClient()
 	res, err := client.Get(url)
 	if err != nil {
 		return
@@ -62,3 +221,11 @@ func fetch(url string) (content []byte, err error) {
 	}
 	return
 }
+
+func fetchJSON(url string, v interface{}) error {
+	content, err := fetch(url)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
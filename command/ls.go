@@ -14,10 +14,14 @@ import (
 var readDir = ioutil.ReadDir
 
 func Ls() ([]*semver.Version, error) {
-	files, _ := readDir(filepath.Join(cfg.Dir(), "jdk"))
+	dir := cfg.JDKDir()
+	files, _ := readDir(dir)
 	var r []*semver.Version
 	for _, f := range files {
-		if f.IsDir() || (f.Mode()&os.ModeSymlink == os.ModeSymlink && strings.HasPrefix(f.Name(), "system@")) {
+		if strings.HasPrefix(f.Name(), ".") { // e.g. .staging
+			continue
+		}
+		if f.IsDir() || isExternalLink(dir, f) {
 			v, err := semver.ParseVersion(f.Name())
 			if err != nil {
 				return nil, err
@@ -29,6 +33,20 @@ func Ls() ([]*semver.Version, error) {
 	return r, nil
 }
 
+// isExternalLink tells whether f is a link to JDK outside of $JABBA_HOME/jdk (`jabba link`ed system@... or
+// `jabba import`ed one) (as opposed to links to other entries of $JABBA_HOME/jdk (e.g. 1.8 -> 1.8.0, default)).
+func isExternalLink(dir string, f os.FileInfo) bool {
+	if f.Mode()&os.ModeSymlink != os.ModeSymlink {
+		return false
+	}
+	if strings.HasPrefix(f.Name(), "system@") {
+		return true
+	}
+	target, err := os.Readlink(filepath.Join(dir, f.Name()))
+	// see migrateLinks for why links to */jdk/* are not considered to be external
+	return err == nil && filepath.IsAbs(target) && filepath.Base(filepath.Dir(target)) != "jdk"
+}
+
 func LsBestMatch(selector string) (ver string, err error) {
 	vs, err := Ls()
 	if err != nil {
@@ -0,0 +1,33 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+func TestLsBestMatchWithExclusions(t *testing.T) {
+	var vs []*semver.Version
+	// sorted in descending order (same as Ls())
+	for _, raw := range []string{"1.17.0-9", "1.17.0-8.1", "1.17.0-8", "1.17.0-7"} {
+		v, err := semver.ParseVersion(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vs = append(vs, v)
+	}
+	for selector, expected := range map[string]string{
+		">=1.17.0-0":                        "1.17.0-9",
+		">=1.17.0-0 <1.17.0-9":              "1.17.0-8.1",
+		">=1.17.0-0 <1.17.0-9 !1.17.0-8":    "1.17.0-7",
+		"!1.17.0-9 >=1.17.0-0 !=1.17.0-8.1": "1.17.0-8",
+	} {
+		actual, err := LsBestMatchWithVersionSlice(vs, selector)
+		if err != nil || actual != expected {
+			t.Fatalf("%s: actual: %v (%v) != expected: %v", selector, actual, err, expected)
+		}
+	}
+	if _, err := LsBestMatchWithVersionSlice(vs, ">=1.17.0-0 !1.17.0-9 !1.17.0-8 !1.17.0-7"); err == nil {
+		t.Fatal("expected no match")
+	}
+}
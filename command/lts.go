@@ -0,0 +1,210 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// index is checked for new LTS lines at most this often
+const ltsCheckInterval = 7 * 24 * time.Hour
+
+// LTSNotice says that there is an LTS line of Java newer than any JDK installed.
+type LTSNotice struct {
+	Major int `json:"major"`
+	// the latest release of the line (e.g. "temurin@1.25.0-36")
+	Version string `json:"version"`
+}
+
+// Notifier delivers LTS notices (see cfg.LTSNotify).
+type Notifier interface {
+	Notify(notice LTSNotice) error
+}
+
+var notifiers = map[string]Notifier{
+	"stderr": stderrNotifier{},
+}
+
+// RegisterNotifier makes notifier available under the specified name (to be referenced from "lts_notify").
+func RegisterNotifier(name string, notifier Notifier) {
+	notifiers[name] = notifier
+}
+
+func getNotifier(name string) (Notifier, error) {
+	if strings.HasPrefix(name, "cmd:") {
+		return commandNotifier{strings.TrimSpace(strings.TrimPrefix(name, "cmd:"))}, nil
+	}
+	notifier, ok := notifiers[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown notifier \"%s\" (expected \"stderr\" or \"cmd:<command>\")", name)
+	}
+	return notifier, nil
+}
+
+type stderrNotifier struct{}
+
+func (stderrNotifier) Notify(notice LTSNotice) error {
+	log.Info("Java ", notice.Major, " (LTS) is now available (`jabba install ", notice.Version, "`)")
+	return nil
+}
+
+// commandNotifier runs user-specified command (e.g. to post a message to the team chat).
+type commandNotifier struct {
+	command string
+}
+
+func (n commandNotifier) Notify(notice LTSNotice) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", n.command)
+	} else {
+		cmd = exec.Command("sh", "-c", n.command)
+	}
+	cmd.Env = append(os.Environ(), "JABBA_LTS_MAJOR="+strconv.Itoa(notice.Major), "JABBA_LTS_VERSION="+notice.Version)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	return cmd.Run()
+}
+
+// IsLTS tells whether Java major version is a long-term support one
+// (8, 11, 17 and every fourth release (two years) after that).
+func IsLTS(major int) bool {
+	return major == 8 || major == 11 || major >= 17 && (major-17)%4 == 0
+}
+
+// javaMajor returns Java major version of the JDK (0 if version doesn't follow 1.<major>.x scheme
+// (e.g. graalvm@19.3.0)).
+func javaMajor(v *semver.Version) int {
+	if v.Major() != 1 {
+		return 0
+	}
+	return int(v.Minor())
+}
+
+type ltsState struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	// LTS lines user has already been notified about
+	Notified []int `json:"notified"`
+}
+
+func ltsStateFile() string {
+	return filepath.Join(cfg.StateDir(), "lts-notify.json")
+}
+
+// NotifyLTS announces new LTS line (if there is one) through the notifiers configured in "lts_notify".
+// Index is checked at most once a week and every LTS line is announced only once.
+// Failures are logged at debug level only (check shouldn't get in the way of the command it's run after).
+func NotifyLTS() {
+	names := cfg.LTSNotify()
+	if len(names) == 0 || cfg.Offline() {
+		return
+	}
+	notice, err := checkLTS(time.Now())
+	if err != nil {
+		log.Debug("Failed to check for new LTS release (", err, ")")
+		return
+	}
+	if notice == nil {
+		return
+	}
+	for _, name := range names {
+		notifier, err := getNotifier(name)
+		if err == nil {
+			err = notifier.Notify(*notice)
+		}
+		if err != nil {
+			log.Warn("Failed to deliver LTS notice (", name, "): ", err)
+		}
+	}
+}
+
+// checkLTS returns new LTS line (nil if there is none or it's too early to check again).
+func checkLTS(now time.Time) (*LTSNotice, error) {
+	var state ltsState
+	if b, err := ioutil.ReadFile(ltsStateFile()); err == nil {
+		json.Unmarshal(b, &state)
+	}
+	if now.Sub(state.CheckedAt) < ltsCheckInterval {
+		return nil, nil
+	}
+	// whatever the outcome, don't try again for another week
+	state.CheckedAt = now
+	notice, err := findNewLTS(state.Notified)
+	if notice != nil {
+		state.Notified = append(state.Notified, notice.Major)
+	}
+	b, merr := json.Marshal(state)
+	if merr != nil {
+		return nil, merr
+	}
+	if werr := ioutil.WriteFile(ltsStateFile(), b, 0644); werr != nil && err == nil {
+		err = werr
+	}
+	return notice, err
+}
+
+func findNewLTS(notified []int) (*LTSNotice, error) {
+	releases, err := LsRemote(runtime.GOOS, HostArch())
+	if err != nil {
+		return nil, err
+	}
+	var remote []*semver.Version
+	for v := range FilterReleases(releases, nil, ChannelGA) {
+		remote = append(remote, v)
+	}
+	installed, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	ignored := make(map[int]bool)
+	for _, value := range cfg.LTSNotifyIgnore() {
+		major, err := strconv.Atoi(strings.TrimPrefix(value, "1."))
+		if err != nil {
+			log.Warn("\"", value, "\" in \"lts_notify_ignore\" is not a Java major version (e.g. 25)")
+			continue
+		}
+		ignored[major] = true
+	}
+	notice := newLTS(remote, installed, ignored)
+	for _, major := range notified {
+		if notice != nil && notice.Major == major {
+			return nil, nil
+		}
+	}
+	return notice, nil
+}
+
+// newLTS returns the latest (not ignored) LTS line among remote versions provided it's newer than any of the
+// installed JDKs (nil if there is no such line).
+func newLTS(remote []*semver.Version, installed []*semver.Version, ignored map[int]bool) *LTSNotice {
+	newestInstalled := 0
+	for _, v := range installed {
+		if major := javaMajor(v); major > newestInstalled {
+			newestInstalled = major
+		}
+	}
+	var latest *semver.Version
+	for _, v := range remote {
+		major := javaMajor(v)
+		if !IsLTS(major) || ignored[major] || strings.Contains(v.Prerelease(), "ea") {
+			continue
+		}
+		if latest == nil || major > javaMajor(latest) || major == javaMajor(latest) && latest.LessThan(v) {
+			latest = v
+		}
+	}
+	if latest == nil || javaMajor(latest) <= newestInstalled {
+		return nil
+	}
+	return &LTSNotice{Major: javaMajor(latest), Version: latest.String()}
+}
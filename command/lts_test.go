@@ -0,0 +1,85 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+func TestIsLTS(t *testing.T) {
+	for major, expected := range map[int]bool{8: true, 9: false, 11: true, 16: false, 17: true, 21: true, 23: false,
+		25: true, 29: true} {
+		if actual := IsLTS(major); actual != expected {
+			t.Fatalf("%d: actual: %v != expected: %v", major, actual, expected)
+		}
+	}
+}
+
+func TestNewLTS(t *testing.T) {
+	versions := func(raw ...string) []*semver.Version {
+		var r []*semver.Version
+		for _, v := range raw {
+			ver, err := semver.ParseVersion(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r = append(r, ver)
+		}
+		return r
+	}
+	remote := versions("temurin@1.21.0-35", "temurin@1.25.0-36", "temurin@1.25.0-35", "temurin@1.26.0-1",
+		"openjdk@1.29.0-ea", "graalvm@25.0.0")
+	notice := newLTS(remote, versions("temurin@1.21.0-35"), nil)
+	if notice == nil || notice.Major != 25 || notice.Version != "temurin@1.25.0-36" {
+		t.Fatalf("actual: %+v", notice)
+	}
+	if notice := newLTS(remote, versions("temurin@1.21.0-35"), map[int]bool{25: true}); notice != nil {
+		t.Fatalf("25 was supposed to be ignored (actual: %+v)", notice)
+	}
+	notice = newLTS(remote, versions("temurin@1.17.0-8"), map[int]bool{25: true})
+	if notice == nil || notice.Version != "temurin@1.21.0-35" {
+		t.Fatalf("actual: %+v", notice)
+	}
+	if notice := newLTS(remote, versions("temurin@1.26.0-1"), nil); notice != nil {
+		t.Fatalf("nothing newer than 26 was supposed to be found (actual: %+v)", notice)
+	}
+}
+
+func TestCheckLTS(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	index := filepath.Join(home, "index.json")
+	err = ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"`+HostArch()+`": {"jdk@temurin": {
+		"1.21.0-35": "tgz+https://example.com/21.tar.gz", "1.25.0-36": "tgz+https://example.com/25.tar.gz"
+	}}}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	os.MkdirAll(filepath.Join(home, "jdk", "temurin@1.21.0-35", "bin"), 0755)
+	now := time.Now()
+	notice, err := checkLTS(now)
+	if err != nil || notice == nil || notice.Version != "temurin@1.25.0-36" {
+		t.Fatalf("actual: %+v (%v)", notice, err)
+	}
+	// at most once a week
+	if notice, err := checkLTS(now.Add(24 * time.Hour)); notice != nil || err != nil {
+		t.Fatalf("actual: %+v (%v)", notice, err)
+	}
+	// and only once per LTS line
+	if notice, err := checkLTS(now.Add(8 * 24 * time.Hour)); notice != nil || err != nil {
+		t.Fatalf("actual: %+v (%v)", notice, err)
+	}
+}
@@ -0,0 +1,207 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// installMeta is recorded for every JDK installed into $JABBA_HOME/jdk (as $JABBA_HOME/meta/<version>.json),
+// so that installation could later be audited / reproduced (see `jabba info`) and checked for corruption / tampering
+// (see `jabba verify`).
+type installMeta struct {
+	Version string `json:"version"`
+	// IMPLEMENTOR & JAVA_RUNTIME_VERSION (e.g. "17.0.9+9") from the "release" file of the JDK (if any)
+	Vendor      string `json:"vendor,omitempty"`
+	JavaVersion string `json:"javaVersion,omitempty"`
+	URL         string `json:"url"`
+	// archive type (e.g. "tgz")
+	Type string `json:"type,omitempty"`
+	// sha256 of the archive
+	SHA256 string `json:"sha256,omitempty"`
+	// fingerprint of the key archive was signed with (if signature was verified)
+	Signer string `json:"signer,omitempty"`
+	OS     string `json:"os,omitempty"`
+	Arch   string `json:"arch,omitempty"`
+	// architectures binaries are built for (e.g. ["amd64", "arm64"] in case of macOS universal build)
+	Archs []string `json:"archs,omitempty"`
+	// bytes on disk
+	Size int64 `json:"size,omitempty"`
+	// when download started (nil if JDK was installed by an older version of jabba)
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	InstalledAt time.Time  `json:"installedAt"`
+	// what JDK was installed with (nil if JDK was installed by an older version of jabba)
+	Installer *installerMeta `json:"installer,omitempty"`
+	// path (relative to the JDK dir) -> sha256 (or "-> <target>" in case of a symlink)
+	Files map[string]string `json:"files"`
+	// CDS archive generated after install / with `jabba cds regenerate` (nil if it wasn't)
+	CDS *cdsMeta `json:"cds,omitempty"`
+	// keys of the (content-addressed) store blobs files were replaced with (see Dedupe)
+	Blobs []string `json:"blobs,omitempty"`
+	// environment variables to export alongside JAVA_HOME (see SetJDKEnv)
+	Env map[string]string `json:"env,omitempty"`
+	// GraalVM components added with `jabba component add` (see AddComponents)
+	Components []string `json:"components,omitempty"`
+	// notice of the index entry JDK was installed from (see Release.Notice)
+	Notice string `json:"notice,omitempty"`
+}
+
+// installerMeta describes jabba & tools used to install JDK (see `jabba attest`).
+type installerMeta struct {
+	Jabba string `json:"jabba"`
+	// Go version jabba was built with (archives are extracted by jabba itself (see archiveInstallers))
+	Go string `json:"go"`
+	// what extracted / ran archive / installer (e.g. "built-in (tar+gzip)", "hdiutil + built-in (xar+cpio)")
+	Extractor string `json:"extractor"`
+	// platform JDK was installed on (e.g. "linux/amd64")
+	Host string `json:"host"`
+}
+
+// JabbaVersion is recorded in the metadata of installed JDKs (see installerMeta) (set by main).
+var JabbaVersion = "dev"
+
+// extractors describes what installs JDK of the given type (see platforms)
+var extractors = map[string]string{
+	"tgz":  "built-in (tar+gzip)",
+	"tgx":  "built-in (tar+xz)",
+	"txz":  "built-in (tar+xz)",
+	"tzst": "built-in (tar+zstd)",
+	"zip":  "built-in (zip)",
+	"dmg":  "hdiutil + built-in (xar+cpio)",
+	"pkg":  "built-in (xar+cpio)",
+	"bin":  "sh (self-extracting archive)",
+	"ia":   "sh (InstallAnywhere, silent mode)",
+	"exe":  "installer (silent mode)",
+}
+
+func recordInstall(result *InstallResult, cds *cdsMeta) error {
+	files, err := digestTree(result.Path)
+	if err != nil {
+		return err
+	}
+	release := readReleaseFile(result.Path)
+	javaVersion := release["JAVA_RUNTIME_VERSION"]
+	if javaVersion == "" {
+		javaVersion = release["JAVA_VERSION"]
+	}
+	var env map[string]string
+	// native-image, Truffle languages, etc. look for GraalVM there
+	if release["GRAALVM_VERSION"] != "" || strings.HasPrefix(result.Version, "graalvm") {
+		env = map[string]string{"GRAALVM_HOME": "$JAVA_HOME"}
+	}
+	var startedAt *time.Time
+	if !result.startedAt.IsZero() {
+		startedAt = &result.startedAt
+	}
+	return writeInstallMeta(&installMeta{
+		Version:     result.Version,
+		Vendor:      release["IMPLEMENTOR"],
+		JavaVersion: javaVersion,
+		URL:         result.URL,
+		Type:        result.Type,
+		SHA256:      result.SHA256,
+		Signer:      result.Signer,
+		OS:          result.OS,
+		Arch:        result.Arch,
+		Archs:       jdkArchs(result.Path, result.OS),
+		Size:        diskUsage(result.Path),
+		StartedAt:   startedAt,
+		InstalledAt: time.Now().UTC(),
+		Installer: &installerMeta{Jabba: JabbaVersion, Go: runtime.Version(), Extractor: extractors[result.Type],
+			Host: runtime.GOOS + "/" + HostArch()},
+		Files:  files,
+		CDS:    cds,
+		Env:    env,
+		Notice: result.Notice,
+	})
+}
+
+// readReleaseFile parses KEY="value" lines of the "release" file JDKs come with (nil if there is none).
+func readReleaseFile(dir string) map[string]string {
+	home := filepath.Dir(filepath.Dir(expectedJavaPath(dir, runtime.GOOS)))
+	b, err := ioutil.ReadFile(filepath.Join(home, "release"))
+	if err != nil {
+		return nil
+	}
+	return parseReleaseFile(b)
+}
+
+func parseReleaseFile(b []byte) map[string]string {
+	r := make(map[string]string)
+	for _, line := range strings.Split(string(b), "\n") {
+		split := strings.SplitN(strings.TrimSpace(line), "=", 2)
+		if len(split) == 2 {
+			r[split[0]] = strings.Trim(split[1], `"`)
+		}
+	}
+	return r
+}
+
+func metaFile(ver string) string {
+	return filepath.Join(cfg.Dir(), "meta", ver+".json")
+}
+
+func readInstallMeta(ver string) (*installMeta, error) {
+	return readInstallMetaFile(metaFile(ver))
+}
+
+func readInstallMetaFile(file string) (*installMeta, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var meta installMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func writeInstallMeta(meta *installMeta) error {
+	file := metaFile(meta.Version)
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0644)
+}
+
+// digestTree returns sha256 of every file under dir (see installMeta.Files).
+func digestTree(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			files[rel] = "-> " + filepath.ToSlash(target)
+		case info.Mode().IsRegular():
+			sum, err := sha256OfFile(path)
+			if err != nil {
+				return err
+			}
+			files[rel] = sum
+		}
+		return nil
+	})
+	return files, err
+}
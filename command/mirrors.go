@@ -0,0 +1,107 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MirrorReport is the outcome of probing single registry URL (see CheckMirrors).
+type MirrorReport struct {
+	URL string `json:"url"`
+	// 1 for the fastest healthy mirror (0 if mirror is unhealthy)
+	Rank int  `json:"rank"`
+	OK   bool `json:"ok"`
+	// HTTP status of the HEAD request (0 for file:// URLs)
+	Status int `json:"status,omitempty"`
+	// time it took to get HEAD response
+	LatencyMillis int64 `json:"latency_ms"`
+	// time it took to fetch (the whole) index
+	FetchMillis int64  `json:"fetch_ms"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256,omitempty"`
+	// reasons mirror is considered unhealthy (empty if OK)
+	Problems []string `json:"problems,omitempty"`
+}
+
+// CheckMirrors probes every registry URL (index & its mirrors) - HEAD (latency), then GET (bypassing the index
+// cache) validating that index is a well-formed JSON, that its sha256 matches the one advertised by the server
+// (X-Checksum-Sha256 (Artifactory, Nexus), if any) and that all mirrors serve the same index (the first healthy URL
+// is taken as reference). Reports are ranked (healthy mirrors first, fastest to slowest).
+func CheckMirrors(urls []string) []MirrorReport {
+	var r []MirrorReport
+	for _, url := range urls {
+		r = append(r, checkMirror(url))
+	}
+	var reference *MirrorReport
+	for i := range r {
+		if !r[i].OK {
+			continue
+		}
+		if reference == nil {
+			reference = &r[i]
+		} else if r[i].SHA256 != reference.SHA256 {
+			r[i].OK = false
+			r[i].Problems = append(r[i].Problems, "index differs from the one served by "+reference.URL+
+				" (mirror out of sync?)")
+		}
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		if r[i].OK != r[j].OK {
+			return r[i].OK
+		}
+		return r[i].OK && r[i].LatencyMillis+r[i].FetchMillis < r[j].LatencyMillis+r[j].FetchMillis
+	})
+	for i := range r {
+		if r[i].OK {
+			r[i].Rank = i + 1
+		}
+	}
+	return r
+}
+
+func checkMirror(url string) MirrorReport {
+	r := MirrorReport{URL: url}
+	var advertised string
+	if !strings.HasPrefix(url, "file://") {
+		start := time.Now()
+		res, err := newHTTPClient().Head(url)
+		r.LatencyMillis = time.Since(start).Milliseconds()
+		if err != nil {
+			r.Problems = append(r.Problems, err.Error())
+			return r
+		}
+		res.Body.Close()
+		r.Status = res.StatusCode
+		// some servers don't implement HEAD, GET below is what matters
+		if res.StatusCode >= 400 && res.StatusCode != http.StatusMethodNotAllowed {
+			r.Problems = append(r.Problems, fmt.Sprintf("HEAD %s returned %d", url, res.StatusCode))
+			return r
+		}
+		advertised = strings.ToLower(res.Header.Get("X-Checksum-Sha256"))
+	}
+	start := time.Now()
+	cnt, err := fetch(url)
+	r.FetchMillis = time.Since(start).Milliseconds()
+	if err != nil {
+		r.Problems = append(r.Problems, err.Error())
+		return r
+	}
+	sum := sha256.Sum256(cnt)
+	r.Size, r.SHA256 = int64(len(cnt)), hex.EncodeToString(sum[:])
+	if advertised != "" && advertised != r.SHA256 {
+		r.Problems = append(r.Problems, "sha256 is "+r.SHA256+" while server advertises "+advertised+
+			" (X-Checksum-Sha256) (index got corrupted in transit?)")
+	}
+	var index byOS
+	if err := json.Unmarshal(cnt, &index); err != nil {
+		r.Problems = append(r.Problems, "not a valid index ("+err.Error()+")")
+	}
+	r.OK = len(r.Problems) == 0
+	return r
+}
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckMirrors(t *testing.T) {
+	index := `{"linux": {"amd64": {"jdk@zulu": {"1.17.0": "tgz+https://example.com/zulu.tar.gz"}}}}`
+	serve := func(content string, advertised string, delay time.Duration) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			if advertised != "" {
+				w.Header().Set("X-Checksum-Sha256", advertised)
+			}
+			if content == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(content))
+		}))
+	}
+	sum := sha256.Sum256([]byte(index))
+	slow := serve(index, hex.EncodeToString(sum[:]), 50*time.Millisecond)
+	defer slow.Close()
+	fast := serve(index, "", 0)
+	defer fast.Close()
+	stale := serve(strings.Replace(index, "1.17.0", "1.16.0", 1), "", 0)
+	defer stale.Close()
+	corrupt := serve(index, strings.Repeat("0", 64), 0)
+	defer corrupt.Close()
+	missing := serve("", "", 0)
+	defer missing.Close()
+	reports := CheckMirrors([]string{slow.URL, fast.URL, stale.URL, corrupt.URL, missing.URL})
+	var actual []string
+	for _, r := range reports {
+		actual = append(actual, r.URL)
+	}
+	expected := []string{fast.URL, slow.URL, stale.URL, corrupt.URL, missing.URL}
+	if strings.Join(actual, " ") != strings.Join(expected, " ") {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	for i, r := range reports {
+		healthy := i < 2
+		if r.OK != healthy || (r.Rank != 0) != healthy || (len(r.Problems) == 0) != healthy {
+			t.Fatalf("unexpected report: %+v", r)
+		}
+	}
+	if reports[0].SHA256 != hex.EncodeToString(sum[:]) || reports[0].Size != int64(len(index)) {
+		t.Fatalf("unexpected report: %+v", reports[0])
+	}
+	for i, problem := range []string{"differs", "X-Checksum-Sha256", "404"} {
+		if !strings.Contains(reports[i+2].Problems[0], problem) {
+			t.Fatalf("%s: expected problem mentioning %s, got %v", reports[i+2].URL, problem,
+				reports[i+2].Problems)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package command
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// PeekResult describes remote archive without it being installed (see Peek).
+type PeekResult struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	// archive type (e.g. "tgz")
+	Type string `json:"type"`
+	// size of the archive (-1 if unknown)
+	Size int64 `json:"size"`
+	// bytes actually fetched
+	Downloaded int64 `json:"downloaded"`
+	Entries    int   `json:"entries"`
+	// total size of the files in the archive
+	UnpackedSize int64 `json:"unpackedSize"`
+	// top-level entries (directories end with "/") followed by the entries of the top-level directory if there is
+	// only one (e.g. "jdk-17.0.9+9/", "jdk-17.0.9+9/bin/", "jdk-17.0.9+9/lib/", ...)
+	Layout []string `json:"layout"`
+	// KEY -> value of the "release" file (JAVA_VERSION, IMPLEMENTOR, ...) (nil if JDK has none)
+	Release map[string]string `json:"release,omitempty"`
+}
+
+// Peek lists the archive selector resolves to (the same way Install does) without installing it.
+// Only the central directory (& "release" file) of zip archives is fetched (using HTTP range requests).
+// tar archives are streamed (but never written to disk).
+func Peek(selector string, opts InstallOptions) (*PeekResult, error) {
+	ver, release, err := resolveRelease(selector, opts)
+	if err != nil {
+		return nil, err
+	}
+	url := release.URL
+	i := strings.Index(url, "+")
+	if i == -1 || !strings.Contains(url, "://") || i > strings.Index(url, "://") {
+		return nil, errors.New("URL must contain qualifier, e.g. tgz+http://...")
+	}
+	fileType := url[:i]
+	url, _, err = splitFragment(url[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	r := &PeekResult{Version: ver.String(), URL: url, Type: fileType, Size: -1}
+	l := &layout{entries: make(map[string]bool)}
+	if decompress, ok := tarDecompressors[fileType]; ok {
+		err = peekTar(r, l, decompress)
+	} else if fileType == "zip" {
+		err = peekZip(r, l)
+	} else {
+		err = fmt.Errorf("%s is distributed as %s (only tar & zip archives can be peeked into)", ver, fileType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.Layout = l.list()
+	return r, nil
+}
+
+func peekTar(r *PeekResult, l *layout, decompress func(io.Reader) (io.ReadCloser, error)) error {
+	var body io.ReadCloser
+	if strings.HasPrefix(r.URL, "file://") {
+		file, err := resolveLocalArchive(r.URL)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		if stat, err := f.Stat(); err == nil {
+			r.Size = stat.Size()
+		}
+		body = f
+	} else {
+		b, err := openResumableBody(r.URL)
+		if err != nil {
+			return err
+		}
+		r.Size = b.size
+		body = b
+	}
+	defer body.Close()
+	log.Info("Reading ", r.URL)
+	counter := &countingReader{r: body}
+	cr, err := decompress(counter)
+	if err != nil {
+		return &CorruptArchiveError{r.URL, err}
+	}
+	defer cr.Close()
+	tr := tar.NewReader(cr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &CorruptArchiveError{r.URL, err}
+		}
+		l.add(h.Name, h.Typeflag == tar.TypeDir)
+		if h.Typeflag == tar.TypeReg {
+			r.Entries++
+			r.UnpackedSize += h.Size
+			if isReleaseFile(h.Name) && r.Release == nil {
+				b, err := ioutil.ReadAll(tr)
+				if err != nil {
+					return &CorruptArchiveError{r.URL, err}
+				}
+				r.Release = parseReleaseFile(b)
+			}
+		} else if h.Typeflag != tar.TypeDir {
+			r.Entries++
+		}
+	}
+	// rest of the stream (padding)
+	io.Copy(ioutil.Discard, counter)
+	r.Downloaded = counter.n
+	return nil
+}
+
+func peekZip(r *PeekResult, l *layout) error {
+	var ra io.ReaderAt
+	var size int64
+	if strings.HasPrefix(r.URL, "file://") {
+		file, err := resolveLocalArchive(r.URL)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		ra, size = f, stat.Size()
+	} else {
+		hra, err := newHTTPReaderAt(r.URL)
+		if err != nil {
+			return err
+		}
+		defer func() { r.Downloaded = hra.downloaded }()
+		ra, size = hra, hra.size
+	}
+	r.Size = size
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return &CorruptArchiveError{r.URL, err}
+	}
+	for _, f := range zr.File {
+		dir := f.FileInfo().IsDir()
+		l.add(f.Name, dir)
+		if dir {
+			continue
+		}
+		r.Entries++
+		r.UnpackedSize += int64(f.UncompressedSize64)
+		if isReleaseFile(f.Name) && r.Release == nil {
+			b, err := readZipEntry(r.URL, f)
+			if err != nil {
+				return err
+			}
+			r.Release = parseReleaseFile(b)
+		}
+	}
+	return nil
+}
+
+// localPath turns file:// URL into a path.
+func localPath(url string) string {
+	file := strings.TrimPrefix(url, "file://")
+	if runtime.GOOS == "windows" {
+		// file:///C:/path/...
+		file = strings.Replace(strings.TrimPrefix(file, "/"), "/", "\\", -1)
+	}
+	return file
+}
+
+// isReleaseFile returns true if name is <jdk>/release (<jdk>/Contents/Home/release in case of macOS bundles).
+func isReleaseFile(name string) bool {
+	split := strings.Split(strings.TrimPrefix(path.Clean("/"+name), "/"), "/")
+	switch len(split) {
+	case 1, 2:
+		return split[len(split)-1] == "release"
+	case 4:
+		return split[1] == "Contents" && split[2] == "Home" && split[3] == "release"
+	}
+	return false
+}
+
+// layout collects the first two levels of archive entries.
+type layout struct {
+	// "a/" -> true, "a/b" -> false (directories end with "/")
+	entries map[string]bool
+}
+
+func (l *layout) add(name string, dir bool) {
+	split := strings.Split(strings.Trim(path.Clean("/"+name), "/"), "/")
+	if split[0] == "" {
+		return
+	}
+	for i := 0; i < len(split) && i < 2; i++ {
+		entry := strings.Join(split[:i+1], "/")
+		if dir || i < len(split)-1 {
+			entry += "/"
+		}
+		l.entries[entry] = true
+	}
+}
+
+func (l *layout) list() []string {
+	var top, nested []string
+	for entry := range l.entries {
+		if strings.Count(strings.TrimSuffix(entry, "/"), "/") == 0 {
+			top = append(top, entry)
+		} else {
+			nested = append(nested, entry)
+		}
+	}
+	sort.Strings(top)
+	sort.Strings(nested)
+	if len(top) == 1 && strings.HasSuffix(top[0], "/") {
+		return append(top, nested...)
+	}
+	return top
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// size of the chunks httpReaderAt fetches (& caches)
+const httpReaderAtBlockSize = 256 * 1024
+
+// httpReaderAt reads remote file with HTTP range requests (in httpReaderAtBlockSize blocks).
+type httpReaderAt struct {
+	url        string
+	size       int64
+	downloaded int64
+	blocks     map[int64][]byte
+}
+
+func newHTTPReaderAt(url string) (*httpReaderAt, error) {
+	r := &httpReaderAt{url: url, size: -1, blocks: make(map[int64][]byte)}
+	// the last block (where central directory of the zip archive is) is fetched first (which also tells the size)
+	res, err := r.get("bytes=-" + strconv.Itoa(httpReaderAtBlockSize))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("%s doesn't support range requests (use `jabba install` + `jabba info` instead)", url)
+	}
+	start, total := parseContentRange(res.Header.Get("Content-Range"))
+	if start < 0 || total < 0 {
+		return nil, fmt.Errorf("GET %s returned unexpected Content-Range (%s)",
+			url, res.Header.Get("Content-Range"))
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.size = total
+	r.downloaded += int64(len(b))
+	// blocks are aligned to httpReaderAtBlockSize (tail is split accordingly)
+	for offset := start; offset < total; {
+		index := offset / httpReaderAtBlockSize
+		end := (index + 1) * httpReaderAtBlockSize
+		if end > total {
+			end = total
+		}
+		if offset == index*httpReaderAtBlockSize {
+			r.blocks[index] = b[offset-start : end-start]
+		}
+		offset = end
+	}
+	return r, nil
+}
+
+func (r *httpReaderAt) get(rng string) (*http.Response, error) {
+	req, err := newDownloadRequest(r.url, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", rng)
+	log.Debug("GET ", r.url, " (", rng, ")")
+	res, err := newDownloadClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 400 {
+		res.Body.Close()
+		return nil, fmt.Errorf("GET %s returned %d", r.url, res.StatusCode)
+	}
+	return res, nil
+}
+
+func (r *httpReaderAt) block(index int64) ([]byte, error) {
+	if b, ok := r.blocks[index]; ok {
+		return b, nil
+	}
+	start := index * httpReaderAtBlockSize
+	end := start + httpReaderAtBlockSize
+	if end > r.size {
+		end = r.size
+	}
+	res, err := r.get(fmt.Sprintf("bytes=%d-%d", start, end-1))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("GET %s returned %d (expected 206)", r.url, res.StatusCode)
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) != end-start {
+		return nil, fmt.Errorf("GET %s (bytes=%d-%d) returned %d byte(s)", r.url, start, end-1, len(b))
+	}
+	r.downloaded += int64(len(b))
+	r.blocks[index] = b
+	return b, nil
+}
+
+func (r *httpReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	for n < len(p) && off < r.size {
+		index := off / httpReaderAtBlockSize
+		b, err := r.block(index)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], b[off-index*httpReaderAtBlockSize:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
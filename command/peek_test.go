@@ -0,0 +1,109 @@
+package command
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPeekZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	blob := make([]byte, 2*1024*1024)
+	rand.Read(blob)
+	for _, e := range []struct {
+		name    string
+		content []byte
+	}{
+		{"jdk-17.0.9+9/", nil},
+		{"jdk-17.0.9+9/bin/java", []byte("#!/bin/sh")},
+		{"jdk-17.0.9+9/lib/modules", blob},
+		{"jdk-17.0.9+9/release", []byte("JAVA_VERSION=\"17.0.9\"\nIMPLEMENTOR=\"Eclipse Adoptium\"\n")},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(e.content)
+	}
+	zw.Close()
+	content := buf.Bytes()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("Range"))
+		http.ServeContent(w, r, "jdk.zip", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	r, err := Peek("1.17.0-custom=zip+"+server.URL+"/jdk.zip", InstallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Size != int64(len(content)) || r.Entries != 3 || r.UnpackedSize != int64(len(blob)+9+53) ||
+		r.Release["JAVA_VERSION"] != "17.0.9" {
+		t.Fatalf("actual: %+v", r)
+	}
+	if expected := []string{"jdk-17.0.9+9/", "jdk-17.0.9+9/bin/", "jdk-17.0.9+9/lib/",
+		"jdk-17.0.9+9/release"}; !reflect.DeepEqual(r.Layout, expected) {
+		t.Fatalf("actual: %v != expected: %v", r.Layout, expected)
+	}
+	// lib/modules is never fetched
+	if r.Downloaded >= r.Size/2 {
+		t.Fatalf("%d out of %d byte(s) downloaded (%v)", r.Downloaded, r.Size, requests)
+	}
+	for _, rng := range requests {
+		if !strings.HasPrefix(rng, "bytes=") {
+			t.Fatalf("request without Range (%v)", requests)
+		}
+	}
+}
+
+func TestPeekTgz(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-peek")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range []struct {
+		name    string
+		content string
+	}{
+		{"./jdk/", ""},
+		{"./jdk/Contents/Home/bin/java", "#!/bin/sh"},
+		{"./jdk/Contents/Home/release", "JAVA_VERSION=\"1.8.0_392\"\n"},
+	} {
+		h := &tar.Header{Name: e.name, Mode: 0755, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(e.name, "/") {
+			h.Typeflag = tar.TypeDir
+		}
+		tw.WriteHeader(h)
+		tw.Write([]byte(e.content))
+	}
+	tw.Close()
+	gw.Close()
+	archive := filepath.Join(dir, "jdk.tar.gz")
+	ioutil.WriteFile(archive, buf.Bytes(), 0644)
+	r, err := Peek("1.8.392-custom=tgz+file://"+filepath.ToSlash(archive), InstallOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Entries != 2 || r.Downloaded != int64(buf.Len()) || r.Release["JAVA_VERSION"] != "1.8.0_392" {
+		t.Fatalf("actual: %+v", r)
+	}
+	if expected := []string{"jdk/", "jdk/Contents/"}; !reflect.DeepEqual(r.Layout, expected) {
+		t.Fatalf("actual: %v != expected: %v", r.Layout, expected)
+	}
+}
@@ -0,0 +1,396 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// `jabba install -i` & `jabba use -i` let user pick a version from a filterable list (drawn on stderr (stdout of
+// `jabba use` is reserved for the shell function), keys are read from stdin).
+
+// ErrNotATerminal is returned by Pick if either stdin or stderr is not a terminal.
+var ErrNotATerminal = errors.New("interactive mode requires a terminal")
+
+// PickerItem is an entry of the list Pick shows.
+type PickerItem struct {
+	// version to install / use (e.g. "zulu@1.17.0-35")
+	Selector string
+	Vendor   string
+	// Selector without vendor
+	Version string
+	Arch    string
+	LTS     bool
+	// size of the installed JDK or (cached) archive (0 if unknown)
+	Size      int64
+	Installed bool
+	Current   bool
+}
+
+func newPickerItem(v *semver.Version, arch string) PickerItem {
+	vendor := v.Qualifier()
+	return PickerItem{Selector: v.String(), Vendor: vendor, Version: strings.TrimPrefix(v.String(), vendor+"@"),
+		Arch: arch, LTS: IsLTS(javaMajor(v))}
+}
+
+// RemotePickerItems lists releases matching query (range or text to look for (see ReleaseQuery.Selector)) that
+// `jabba install` could install given opts (latest first).
+func RemotePickerItems(query string, opts InstallOptions) ([]PickerItem, error) {
+	goos, err := TargetOS(opts.targetOS(), opts.Libc)
+	if err != nil {
+		return nil, err
+	}
+	arch := opts.Arch
+	if arch == "" {
+		arch = cfg.Arch()
+	}
+	if arch == "" {
+		arch = HostArch()
+	}
+	arch = NormalizeArch(arch)
+	releaseMap, err := opts.lsRemote(goos, arch)
+	if err != nil {
+		return nil, err
+	}
+	releaseMap = QueryReleases(releaseMap, ReleaseQuery{Selector: query, Channel: opts.Channel})
+	vs := make([]*semver.Version, 0, len(releaseMap))
+	for v := range releaseMap {
+		vs = append(vs, v)
+	}
+	sort.Sort(sort.Reverse(semver.VersionSlice(vs)))
+	installed := make(map[string]bool)
+	if local, err := Ls(); err == nil {
+		for _, v := range local {
+			installed[v.String()] = true
+		}
+	}
+	items := make([]PickerItem, len(vs))
+	for i, v := range vs {
+		items[i] = newPickerItem(v, arch)
+		items[i].Installed = installed[v.String()]
+		items[i].Size = cachedArchiveSize(releaseMap[v])
+	}
+	return items, nil
+}
+
+// cachedArchiveSize returns size of the release archive if it's in the download cache (0 otherwise).
+func cachedArchiveSize(release Release) int64 {
+	sep := strings.Index(release.URL, "+")
+	if sep == -1 {
+		return 0
+	}
+	url, f, err := splitFragment(release.URL[sep+1:])
+	if err != nil {
+		return 0
+	}
+	file, _ := downloadPath(url, release.URL[:sep], f.checksum)
+	stat, err := os.Stat(file)
+	if err != nil {
+		return 0
+	}
+	return stat.Size()
+}
+
+// LocalPickerItems lists installed JDKs matching query (same as in RemotePickerItems) (latest first).
+func LocalPickerItems(query string) ([]PickerItem, error) {
+	local, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	releaseMap := make(map[*semver.Version]Release)
+	for _, v := range local {
+		releaseMap[v] = Release{}
+	}
+	releaseMap = QueryReleases(releaseMap, ReleaseQuery{Selector: query, Channel: ChannelAll})
+	var vs []*semver.Version
+	for _, v := range local {
+		if _, ok := releaseMap[v]; ok {
+			vs = append(vs, v)
+		}
+	}
+	current := Current()
+	items := make([]PickerItem, len(vs))
+	for i, v := range vs {
+		items[i] = newPickerItem(v, "")
+		items[i].Installed, items[i].Current = true, v.String() == current
+		// diskUsage is too slow to be called for every JDK
+		if meta, err := readInstallMeta(v.String()); err == nil {
+			items[i].Arch, items[i].Size = meta.Arch, meta.Size
+		} else {
+			items[i].Arch = strings.Join(InstalledArchs(v.String()), ",")
+		}
+	}
+	return items, nil
+}
+
+// FilterPickerItems returns items every (whitespace-separated) word of query is found in (case-insensitive), words
+// being matched against version, vendor & arch ("lts" matches LTS releases, "installed" - installed ones).
+func FilterPickerItems(items []PickerItem, query string) []PickerItem {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return items
+	}
+	var r []PickerItem
+	for _, item := range items {
+		text := strings.ToLower(item.Selector + " " + item.Arch)
+		if item.LTS {
+			text += " lts"
+		}
+		if item.Installed {
+			text += " installed"
+		}
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			r = append(r, item)
+		}
+	}
+	return r
+}
+
+// picker is the state of the list Pick shows.
+type picker struct {
+	title    string
+	items    []PickerItem
+	query    string
+	filtered []PickerItem
+	// index of the highlighted item (in filtered)
+	cursor int
+	// index of the first visible item (in filtered)
+	offset int
+	// number of items that fit on screen
+	height int
+}
+
+func newPicker(title string, items []PickerItem, height int) *picker {
+	p := &picker{title: title, items: items, filtered: items, height: height}
+	if p.height < 1 {
+		p.height = 1
+	}
+	return p
+}
+
+// handle applies key (see decodeKeys), returning true once picker is closed (selected is "" if it was cancelled).
+func (p *picker) handle(key string) (closed bool, selected string) {
+	switch key {
+	case "enter":
+		if len(p.filtered) == 0 {
+			return false, ""
+		}
+		return true, p.filtered[p.cursor].Selector
+	case "esc", "ctrl-c", "ctrl-d":
+		return true, ""
+	case "up":
+		p.move(-1)
+	case "down", "tab":
+		p.move(1)
+	case "pgup":
+		p.move(-p.height)
+	case "pgdn":
+		p.move(p.height)
+	case "home":
+		p.move(-len(p.filtered))
+	case "end":
+		p.move(len(p.filtered))
+	case "backspace":
+		if p.query != "" {
+			_, size := utf8.DecodeLastRuneInString(p.query)
+			p.setQuery(p.query[:len(p.query)-size])
+		}
+	case "ctrl-u":
+		p.setQuery("")
+	default:
+		if len(key) == 1 && key[0] < 0x20 || strings.HasPrefix(key, "\x1b") {
+			// unsupported control key / escape sequence
+			return false, ""
+		}
+		p.setQuery(p.query + key)
+	}
+	return false, ""
+}
+
+func (p *picker) setQuery(query string) {
+	p.query = query
+	p.filtered = FilterPickerItems(p.items, query)
+	p.cursor, p.offset = 0, 0
+}
+
+func (p *picker) move(delta int) {
+	p.cursor += delta
+	if p.cursor >= len(p.filtered) {
+		p.cursor = len(p.filtered) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+p.height {
+		p.offset = p.cursor - p.height + 1
+	}
+}
+
+// render draws the picker (from the top left corner of the screen (width columns wide)).
+func (p *picker) render(w io.Writer, width int) {
+	truncate := func(s string, width int) string {
+		if width > 0 && len(s) > width {
+			return s[:width]
+		}
+		return s
+	}
+	lines := []string{
+		truncate(fmt.Sprintf("%s (%d of %d) (up/down to move, enter to select, esc to cancel)", p.title,
+			len(p.filtered), len(p.items)), width),
+		truncate("> "+p.query, width),
+	}
+	for i := p.offset; i < len(p.filtered) && i < p.offset+p.height; i++ {
+		row := formatPickerItem(p.filtered[i])
+		if i == p.cursor {
+			lines = append(lines, "\x1b[7m"+truncate("> "+row, width)+"\x1b[0m")
+		} else {
+			lines = append(lines, truncate("  "+row, width))
+		}
+	}
+	if len(p.filtered) == 0 {
+		lines = append(lines, "  (no matches)")
+	}
+	// no trailing newline (screen would scroll otherwise)
+	io.WriteString(w, "\x1b[H\x1b[2J"+strings.Join(lines, "\r\n"))
+}
+
+func formatPickerItem(item PickerItem) string {
+	vendor, lts, size, marker := item.Vendor, "", "", ""
+	if vendor == "" {
+		vendor = "-"
+	}
+	if item.LTS {
+		lts = "LTS"
+	}
+	if item.Size != 0 {
+		size = FormatSize(item.Size)
+	}
+	switch {
+	case item.Current:
+		marker = "current"
+	case item.Installed:
+		marker = "installed"
+	}
+	return fmt.Sprintf("%-28s %-12s %-8s %-4s %9s  %s", item.Version, vendor, item.Arch, lts, size, marker)
+}
+
+// decodeKeys splits what was read from the terminal (in raw mode) into keys ("up", "enter", "a", ...).
+func decodeKeys(b []byte) []string {
+	sequences := map[string]string{"\x1b[A": "up", "\x1b[B": "down", "\x1bOA": "up", "\x1bOB": "down",
+		"\x1b[5~": "pgup", "\x1b[6~": "pgdn", "\x1b[H": "home", "\x1b[F": "end", "\x1b[1~": "home", "\x1b[4~": "end"}
+	var r []string
+	s := string(b)
+	for len(s) > 0 {
+		if s[0] == 0x1b {
+			if len(s) == 1 {
+				r = append(r, "esc")
+				break
+			}
+			matched := false
+			for seq, key := range sequences {
+				if strings.HasPrefix(s, seq) {
+					r, s, matched = append(r, key), s[len(seq):], true
+					break
+				}
+			}
+			if !matched {
+				// unknown sequence (rest of the read is dropped)
+				r = append(r, s)
+				break
+			}
+			continue
+		}
+		switch s[0] {
+		case '\r', '\n':
+			r = append(r, "enter")
+		case 0x7f, 0x08:
+			r = append(r, "backspace")
+		case 0x03:
+			r = append(r, "ctrl-c")
+		case 0x04:
+			r = append(r, "ctrl-d")
+		case 0x09:
+			r = append(r, "tab")
+		case 0x0e:
+			r = append(r, "down")
+		case 0x10:
+			r = append(r, "up")
+		case 0x15:
+			r = append(r, "ctrl-u")
+		default:
+			rs := []rune(s)
+			r = append(r, string(rs[0]))
+			s = s[len(string(rs[0])):]
+			continue
+		}
+		s = s[1:]
+	}
+	return r
+}
+
+// Pick shows a filterable list of items, returning Selector of the one user picked ("" if cancelled).
+// ErrNotATerminal is returned if there is no terminal to show the list on.
+func Pick(title string, items []PickerItem) (string, error) {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return "", ErrNotATerminal
+	}
+	width, height, err := terminal.GetSize(int(os.Stderr.Fd()))
+	if err != nil {
+		return "", ErrNotATerminal
+	}
+	state, err := terminal.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return "", ErrNotATerminal
+	}
+	defer terminal.Restore(int(os.Stdin.Fd()), state)
+	// alternate screen (so that terminal's scrollback is left as is), hidden cursor
+	fmt.Fprint(os.Stderr, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(os.Stderr, "\x1b[?25h\x1b[?1049l")
+	// title, query
+	p := newPicker(title, items, height-2)
+	buf := make([]byte, 64)
+	for {
+		p.render(os.Stderr, width)
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		for _, key := range decodeKeys(buf[:n]) {
+			if closed, selected := p.handle(key); closed {
+				return selected, nil
+			}
+		}
+		if w, h, err := terminal.GetSize(int(os.Stderr.Fd())); err == nil && (w != width || h != height) {
+			width, height = w, h
+			if p.height = h - 2; p.height < 1 {
+				p.height = 1
+			}
+			p.move(0)
+		}
+	}
+}
+
+// PrintPickerItems prints items (one per line), e.g. when Pick fails with ErrNotATerminal.
+func PrintPickerItems(w io.Writer, items []PickerItem) {
+	for _, item := range items {
+		fmt.Fprintln(w, strings.TrimRight(formatPickerItem(item), " "))
+	}
+}
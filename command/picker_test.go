@@ -0,0 +1,78 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	actual := decodeKeys([]byte("zu\x1b[B\x1b[A\x7f\r\x1b"))
+	expected := []string{"z", "u", "down", "up", "backspace", "enter", "esc"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestPicker(t *testing.T) {
+	items := []PickerItem{
+		{Selector: "zulu@1.21.0", Vendor: "zulu", Version: "1.21.0", Arch: "amd64", LTS: true},
+		{Selector: "zulu@1.20.0", Vendor: "zulu", Version: "1.20.0", Arch: "amd64"},
+		{Selector: "temurin@1.21.0", Vendor: "temurin", Version: "1.21.0", Arch: "amd64", LTS: true, Installed: true},
+		{Selector: "temurin@1.17.0", Vendor: "temurin", Version: "1.17.0", Arch: "amd64", LTS: true},
+	}
+	if actual := FilterPickerItems(items, "TEMURIN lts 1.21"); len(actual) != 1 || actual[0].Selector != "temurin@1.21.0" {
+		t.Fatalf("actual: %v != expected: temurin@1.21.0", actual)
+	}
+	if actual := FilterPickerItems(items, "installed"); len(actual) != 1 || actual[0].Selector != "temurin@1.21.0" {
+		t.Fatalf("actual: %v != expected: temurin@1.21.0", actual)
+	}
+	p := newPicker("Pick", items, 2)
+	for _, key := range []string{"down", "down", "down", "down"} {
+		p.handle(key)
+	}
+	if p.cursor != 3 || p.offset != 2 {
+		t.Fatalf("actual: %d/%d != expected: 3/2", p.cursor, p.offset)
+	}
+	for _, key := range []string{"l", "t", "s", "x", "backspace", "down"} {
+		if closed, _ := p.handle(key); closed {
+			t.Fatal("picker closed prematurely")
+		}
+	}
+	if closed, selected := p.handle("enter"); !closed || selected != "temurin@1.21.0" {
+		t.Fatalf("actual: %v != expected: temurin@1.21.0", selected)
+	}
+	p.setQuery("nothing matches that")
+	if closed, _ := p.handle("enter"); closed {
+		t.Fatal("enter should be ignored when there is nothing to pick")
+	}
+	if closed, selected := p.handle("esc"); !closed || selected != "" {
+		t.Fatalf("actual: %v != expected: picker to be cancelled", selected)
+	}
+}
+
+func TestLocalPickerItems(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "zulu@1.8.0", "zulu@1.17.0", "temurin@1.17.0")
+	items, err := LocalPickerItems("zulu@")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, item := range items {
+		if !item.Installed {
+			t.Fatalf("%s is not marked as installed", item.Selector)
+		}
+		actual = append(actual, item.Version)
+	}
+	if expected := []string{"1.17.0", "1.8.0"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
@@ -0,0 +1,130 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// Pinned JDKs (pinned.json in cfg.StateDir() (sorted list of versions)) are never removed by `jabba prune`,
+// `jabba uninstall` or `jabba upgrade --purge` (files they share with other JDKs (see Dedupe) are kept by `jabba gc`
+// as long as they are installed). As with aliases, pins recorded in (read-only) jabba home are visible in the overlay.
+
+const pinsFileName = "pinned.json"
+
+// Pin marks installed JDK matching the selector as pinned, returning its version.
+func Pin(selector string) (string, error) {
+	ver, err := Resolve(selector)
+	if err != nil {
+		return "", err
+	}
+	dir := cfg.StateDir()
+	if err := ensureWritableDir(dir); err != nil {
+		return "", err
+	}
+	pins, err := readPinsFile(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, pin := range pins {
+		if pin == ver {
+			return ver, nil
+		}
+	}
+	return ver, writePinsFile(dir, append(pins, ver))
+}
+
+// Unpin removes the pin from JDK matching the selector (which can be an exact version of JDK that is no longer
+// installed), returning its version.
+func Unpin(selector string) (string, error) {
+	ver := selector
+	if !IsPinned(ver) {
+		var err error
+		if ver, err = Resolve(selector); err != nil {
+			return "", err
+		}
+		if !IsPinned(ver) {
+			return "", fmt.Errorf("%s is not pinned", ver)
+		}
+	}
+	dir := cfg.StateDir()
+	pins, err := readPinsFile(dir)
+	if err != nil {
+		return "", err
+	}
+	var r []string
+	for _, pin := range pins {
+		if pin != ver {
+			r = append(r, pin)
+		}
+	}
+	if len(r) == len(pins) {
+		return "", fmt.Errorf("%s is pinned in %s (which is read-only)", ver, cfg.Dir())
+	}
+	return ver, writePinsFile(dir, r)
+}
+
+// Pinned returns versions of pinned JDKs (in alphabetical order).
+func Pinned() ([]string, error) {
+	pins, err := readPinsFile(cfg.StateDir())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.StateDir() != cfg.Dir() {
+		home, err := readPinsFile(cfg.Dir())
+		if err != nil {
+			return nil, err
+		}
+		pins = uniqueSorted(append(pins, home...))
+	}
+	return pins, nil
+}
+
+// IsPinned reports whether JDK is pinned (see Pin).
+func IsPinned(ver string) bool {
+	pins, _ := Pinned()
+	for _, pin := range pins {
+		if pin == ver {
+			return true
+		}
+	}
+	return false
+}
+
+func readPinsFile(dir string) ([]string, error) {
+	file := filepath.Join(dir, pinsFileName)
+	var pins []string
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &pins); err != nil {
+		return nil, fmt.Errorf("%s is not valid (%v)", file, err)
+	}
+	return pins, nil
+}
+
+func writePinsFile(dir string, pins []string) error {
+	if pins == nil {
+		pins = []string{}
+	}
+	sort.Strings(pins)
+	b, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, pinsFileName)
+	tmp := file + ".jabba-tmp"
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPin(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "1.8.0", "1.8.1", "1.11.0")
+	if ver, err := Pin("1.8.0"); err != nil || ver != "1.8.0" {
+		t.Fatalf("actual: %v (%v) != expected: %v", ver, err, "1.8.0")
+	}
+	if _, err := Pin("1.6"); err == nil {
+		t.Fatal("expected Pin to fail (not installed)")
+	}
+	if pins, _ := Pinned(); !reflect.DeepEqual(pins, []string{"1.8.0"}) {
+		t.Fatalf("actual: %v != expected: %v", pins, []string{"1.8.0"})
+	}
+	removed, err := Uninstall("1.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"1.8.1"}; !reflect.DeepEqual(removed, expected) {
+		t.Fatalf("actual: %v != expected: %v", removed, expected)
+	}
+	if _, err := Uninstall("1.8.0"); err == nil || !strings.Contains(err.Error(), "is pinned") {
+		t.Fatalf("expected Uninstall to fail (pinned) (%v)", err)
+	}
+	removed, err = Prune(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"1.11.0"}; !reflect.DeepEqual(removed, expected) {
+		t.Fatalf("actual: %v != expected: %v", removed, expected)
+	}
+	if ver, err := Unpin("1.8"); err != nil || ver != "1.8.0" {
+		t.Fatalf("actual: %v (%v) != expected: %v", ver, err, "1.8.0")
+	}
+	if _, err := Unpin("1.8.0"); err == nil {
+		t.Fatal("expected Unpin to fail (not pinned)")
+	}
+	if _, err := Uninstall("1.8.0"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,435 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/xi2/xz"
+)
+
+// .pkg (flat package) is a xar archive holding either a single component (Payload at the root) or
+// a product (Distribution + <component>.pkg/Payload for each of the choices).
+// Payload is a cpio archive (gzip or pbzx (chunked xz) compressed).
+// Everything is done in Go (no pkgutil / installer), so neither sudo nor /Library is ever involved.
+
+func installFromPkg(src string, dst string) error {
+	log.Info("Extracting " + src + " to " + dst)
+	return unpkg(src, dst)
+}
+
+func unpkg(src string, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	x, err := newXarReader(file)
+	if err != nil {
+		return &CorruptArchiveError{src, err}
+	}
+	payload := pkgPayload(x)
+	if payload == nil {
+		return &CorruptArchiveError{src, errors.New("no Payload found")}
+	}
+	log.Debug("Extracting ", payload.name)
+	r, err := payload.open()
+	if err != nil {
+		return &CorruptArchiveError{src, fmt.Errorf("%s: %v", payload.name, err)}
+	}
+	defer r.Close()
+	br := bufio.NewReader(r)
+	cr, err := decompressPayload(br)
+	if err != nil {
+		return &CorruptArchiveError{src, fmt.Errorf("%s: %v", payload.name, err)}
+	}
+	if err := uncpio(src, corruptOnError{cr, src}, dst); err != nil {
+		return err
+	}
+	// cpio trailer might be followed by padding (checksum is verified only once payload is read in full)
+	if _, err := io.Copy(ioutil.Discard, br); err != nil {
+		return &CorruptArchiveError{src, fmt.Errorf("%s: %v", payload.name, err)}
+	}
+	return nil
+}
+
+// pkgPayload picks Payload of the JDK choice (when there is more than one component (e.g. jdk & javaappletplugin),
+// the largest one wins).
+func pkgPayload(x *xarReader) *xarFile {
+	var payload *xarFile
+	for _, f := range x.files {
+		if path.Base(f.name) != "Payload" || f.typ != "file" || f.data == nil {
+			continue
+		}
+		if payload == nil || payload.data.Size < f.data.Size {
+			payload = f
+		}
+	}
+	return payload
+}
+
+func decompressPayload(r *bufio.Reader) (io.Reader, error) {
+	magic, err := r.Peek(6)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(r)
+	case string(magic[0:4]) == "pbzx":
+		return newPbzxReader(r)
+	case string(magic) == "070707":
+		return r, nil
+	}
+	return nil, fmt.Errorf("unsupported payload format (%x)", magic)
+}
+
+type xarReader struct {
+	r     io.ReaderAt
+	heap  int64
+	files []*xarFile
+}
+
+type xarFile struct {
+	name string // "/"-separated path within the archive
+	typ  string
+	data *xarData
+	x    *xarReader
+}
+
+type xarData struct {
+	Offset   int64 `xml:"offset"`
+	Length   int64 `xml:"length"`
+	Size     int64 `xml:"size"`
+	Encoding struct {
+		Style string `xml:"style,attr"`
+	} `xml:"encoding"`
+	ArchivedChecksum struct {
+		Style string `xml:"style,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"archived-checksum"`
+}
+
+type xarTOCFile struct {
+	Name  string       `xml:"name"`
+	Type  string       `xml:"type"`
+	Data  *xarData     `xml:"data"`
+	Files []xarTOCFile `xml:"file"`
+}
+
+type xarTOC struct {
+	Checksum struct {
+		Style  string `xml:"style,attr"`
+		Offset int64  `xml:"offset"`
+		Size   int64  `xml:"size"`
+	} `xml:"toc>checksum"`
+	Files []xarTOCFile `xml:"toc>file"`
+}
+
+const xarMagic = 0x78617221 // "xar!"
+
+func newXarReader(r io.ReaderAt) (*xarReader, error) {
+	var header struct {
+		Magic                uint32
+		Size                 uint16
+		Version              uint16
+		TOCLengthCompressed  uint64
+		TOCLengthUncompresed uint64
+		ChecksumAlg          uint32
+	}
+	if err := binary.Read(io.NewSectionReader(r, 0, 28), binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Magic != xarMagic {
+		return nil, errors.New("not a xar archive")
+	}
+	if header.Size < 28 || header.TOCLengthCompressed > 64<<20 {
+		return nil, errors.New("invalid xar header")
+	}
+	compressedTOC := make([]byte, header.TOCLengthCompressed)
+	if _, err := r.ReadAt(compressedTOC, int64(header.Size)); err != nil {
+		return nil, err
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(compressedTOC))
+	if err != nil {
+		return nil, err
+	}
+	var toc xarTOC
+	if err := xml.NewDecoder(zr).Decode(&toc); err != nil {
+		return nil, err
+	}
+	x := &xarReader{r: r, heap: int64(header.Size) + int64(header.TOCLengthCompressed)}
+	if h := newXarHash(toc.Checksum.Style); h != nil {
+		expected := make([]byte, toc.Checksum.Size)
+		if _, err := r.ReadAt(expected, x.heap+toc.Checksum.Offset); err != nil {
+			return nil, err
+		}
+		h.Write(compressedTOC)
+		if !bytes.Equal(h.Sum(nil), expected) {
+			return nil, errors.New("table of contents checksum mismatch")
+		}
+	}
+	var walk func(dir string, files []xarTOCFile)
+	walk = func(dir string, files []xarTOCFile) {
+		for _, f := range files {
+			name := path.Join(dir, f.Name)
+			x.files = append(x.files, &xarFile{name: name, typ: f.Type, data: f.Data, x: x})
+			walk(name, f.Files)
+		}
+	}
+	walk("", toc.Files)
+	return x, nil
+}
+
+func newXarHash(style string) hash.Hash {
+	switch strings.ToLower(style) {
+	case "sha1":
+		return sha1.New()
+	case "md5":
+		return md5.New()
+	case "sha256":
+		return sha256.New()
+	case "sha512":
+		return sha512.New()
+	}
+	return nil
+}
+
+// open returns decoded content of the file (archived checksum is verified once content is read in full).
+func (f *xarFile) open() (io.ReadCloser, error) {
+	var r io.Reader = io.NewSectionReader(f.x.r, f.x.heap+f.data.Offset, f.data.Length)
+	if h := newXarHash(f.data.ArchivedChecksum.Style); h != nil {
+		r = &checksumReader{r: io.TeeReader(r, h), h: h, expected: strings.TrimSpace(f.data.ArchivedChecksum.Value)}
+	}
+	switch f.data.Encoding.Style {
+	case "", "application/octet-stream":
+		return ioutil.NopCloser(r), nil
+	case "application/x-gzip": // zlib, actually
+		return zlib.NewReader(r)
+	case "application/x-bzip2":
+		return ioutil.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %s", f.data.Encoding.Style)
+}
+
+type checksumReader struct {
+	r        io.Reader
+	h        hash.Hash
+	expected string
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err == io.EOF {
+		if actual := hex.EncodeToString(c.h.Sum(nil)); !strings.EqualFold(actual, c.expected) {
+			return n, fmt.Errorf("checksum mismatch (%s != %s)", actual, c.expected)
+		}
+	}
+	return n, err
+}
+
+// pbzx is a sequence of chunks, each either xz-compressed or stored as is.
+type pbzxReader struct {
+	r     io.Reader
+	flags uint64
+	chunk io.Reader
+}
+
+func newPbzxReader(r io.Reader) (io.Reader, error) {
+	var header struct {
+		Magic [4]byte
+		Flags uint64
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	return &pbzxReader{r: r, flags: header.Flags}, nil
+}
+
+func (p *pbzxReader) Read(b []byte) (int, error) {
+	for {
+		if p.chunk != nil {
+			n, err := p.chunk.Read(b)
+			if err == io.EOF {
+				p.chunk = nil
+				if n == 0 {
+					continue
+				}
+				err = nil
+			}
+			return n, err
+		}
+		if p.flags&(1<<24) == 0 { // no more chunks
+			return 0, io.EOF
+		}
+		var header struct {
+			Flags  uint64
+			Length uint64
+		}
+		if err := binary.Read(p.r, binary.BigEndian, &header); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+		p.flags = header.Flags
+		chunk := io.LimitReader(p.r, int64(header.Length))
+		br := bufio.NewReader(chunk)
+		if magic, _ := br.Peek(6); bytes.Equal(magic, []byte{0xfd, '7', 'z', 'X', 'Z', 0}) {
+			xr, err := xz.NewReader(br, 0)
+			if err != nil {
+				return 0, err
+			}
+			// xz reader stops at the end of the stream, padding (if any) is skipped
+			p.chunk = io.MultiReader(xr, &drain{chunk})
+		} else {
+			p.chunk = br
+		}
+	}
+}
+
+// drain discards whatever is left of r.
+type drain struct {
+	r io.Reader
+}
+
+func (d *drain) Read(b []byte) (int, error) {
+	if _, err := io.Copy(ioutil.Discard, d.r); err != nil {
+		return 0, err
+	}
+	return 0, io.EOF
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+const (
+	cpioModeType    = 0170000
+	cpioModeDir     = 0040000
+	cpioModeRegular = 0100000
+	cpioModeSymlink = 0120000
+)
+
+// uncpio extracts "odc" (portable ASCII) cpio archive (the format of pkg Payload) into dst.
+// Entries that would end up outside of dst (either directly or through a symlink) are rejected.
+func uncpio(src string, r io.Reader, dst string) error {
+	if err := mkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	symlinks := make(map[string]bool)
+	// directory modes are applied once extraction is complete (otherwise read-only dirs couldn't be populated)
+	dirModes := make(map[string]os.FileMode)
+	header := make([]byte, 76)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return &CorruptArchiveError{src, unexpectedEOF(err)}
+		}
+		if string(header[0:6]) != "070707" {
+			return &CorruptArchiveError{src, fmt.Errorf("invalid cpio header (%q)", header[0:6])}
+		}
+		mode, err1 := strconv.ParseUint(string(header[18:24]), 8, 32)
+		nameSize, err2 := strconv.ParseUint(string(header[59:65]), 8, 32)
+		fileSize, err3 := strconv.ParseUint(string(header[65:76]), 8, 63)
+		if err1 != nil || err2 != nil || err3 != nil || nameSize == 0 || nameSize > 4096 {
+			return &CorruptArchiveError{src, fmt.Errorf("invalid cpio header (%q)", header)}
+		}
+		nameBytes := make([]byte, nameSize)
+		if _, err := io.ReadFull(r, nameBytes); err != nil {
+			return &CorruptArchiveError{src, unexpectedEOF(err)}
+		}
+		name := string(bytes.TrimRight(nameBytes, "\x00"))
+		if name == "TRAILER!!!" {
+			break
+		}
+		data := io.LimitReader(r, int64(fileSize))
+		rel := path.Clean(strings.TrimPrefix(name, "/"))
+		if rel == ".." || strings.HasPrefix(rel, "../") {
+			return &CorruptArchiveError{src, fmt.Errorf("%s is outside of the archive", name)}
+		}
+		// (directory entry itself can't be a symlink either (mkdirAll & chmod would follow it))
+		dir := path.Dir(rel)
+		if mode&cpioModeType == cpioModeDir {
+			dir = rel
+		}
+		for ; dir != "."; dir = path.Dir(dir) {
+			if symlinks[dir] {
+				return &CorruptArchiveError{src, fmt.Errorf("%s is outside of the archive (%s is a symlink)", name, dir)}
+			}
+		}
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if rel != "." {
+			if err := mkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+		}
+		perm := os.FileMode(mode) & 0777
+		switch mode & cpioModeType {
+		case cpioModeDir:
+			if err := mkdirAll(target, 0755); err != nil {
+				return err
+			}
+			if rel != "." {
+				dirModes[target] = perm | 0700
+			}
+		case cpioModeRegular:
+			d, err := createFile(target, perm|0600)
+			if err != nil {
+				return err
+			}
+			n, err := io.Copy(d, data)
+			d.Close()
+			if err != nil {
+				return err
+			}
+			if n != int64(fileSize) {
+				return &CorruptArchiveError{src, fmt.Errorf("%s: %v", name, io.ErrUnexpectedEOF)}
+			}
+			// OpenFile is subject to umask
+			if err := chmod(target, perm|0600); err != nil {
+				return err
+			}
+		case cpioModeSymlink:
+			linkname, err := ioutil.ReadAll(data)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			if err := os.Symlink(string(linkname), target); err != nil {
+				return err
+			}
+			symlinks[rel] = true
+		default:
+			log.Debug("Skipping ", name, " (mode ", strconv.FormatUint(mode, 8), ")")
+		}
+		if _, err := io.Copy(ioutil.Discard, data); err != nil {
+			return err
+		}
+	}
+	for dir, mode := range dirModes {
+		if err := chmod(dir, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package command
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+type cpioEntry struct {
+	name string
+	mode int64
+	data string
+}
+
+func cpioArchive(entries ...cpioEntry) []byte {
+	var b bytes.Buffer
+	for _, e := range append(entries, cpioEntry{name: "TRAILER!!!"}) {
+		fmt.Fprintf(&b, "070707%06o%06o%06o%06o%06o%06o%06o%011o%06o%011o%s\x00%s",
+			0, 0, e.mode, 0, 0, 1, 0, 0, len(e.name)+1, len(e.data), e.name, e.data)
+	}
+	return b.Bytes()
+}
+
+func gzipped(data []byte) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	return b.Bytes()
+}
+
+// pbzx with a single uncompressed chunk
+func pbzx(data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("pbzx")
+	binary.Write(&b, binary.BigEndian, []uint64{1 << 24, 0, uint64(len(data))})
+	b.Write(data)
+	return b.Bytes()
+}
+
+// xarArchive creates xar archive (with sha1 checksums) out of "dir/name" -> content.
+func xarArchive(files map[string][]byte, order ...string) []byte {
+	var heap bytes.Buffer
+	heap.Write(make([]byte, sha1.Size)) // toc checksum
+	toc := `<?xml version="1.0" encoding="UTF-8"?><xar><toc>` +
+		`<checksum style="sha1"><offset>0</offset><size>20</size></checksum>`
+	for i, name := range order {
+		dir, base := filepath.Split(name)
+		data := files[name]
+		h := sha1.Sum(data)
+		entry := fmt.Sprintf(`<file id="%d"><name>%s</name><type>file</type><data><offset>%d</offset>`+
+			`<length>%d</length><size>%d</size><encoding style="application/octet-stream"/>`+
+			`<archived-checksum style="sha1">%s</archived-checksum></data></file>`,
+			i+2, base, heap.Len(), len(data), len(data), hex.EncodeToString(h[:]))
+		if dir != "" {
+			entry = fmt.Sprintf(`<file id="%d"><name>%s</name><type>directory</type>%s</file>`,
+				100+i, filepath.Clean(dir), entry)
+		}
+		toc += entry
+		heap.Write(data)
+	}
+	toc += `</toc></xar>`
+	var compressedTOC bytes.Buffer
+	w := zlib.NewWriter(&compressedTOC)
+	w.Write([]byte(toc))
+	w.Close()
+	h := sha1.Sum(compressedTOC.Bytes())
+	r := heap.Bytes()
+	copy(r, h[:])
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(xarMagic))
+	binary.Write(&b, binary.BigEndian, []uint16{28, 1})
+	binary.Write(&b, binary.BigEndian, []uint64{uint64(compressedTOC.Len()), uint64(len(toc))})
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	b.Write(compressedTOC.Bytes())
+	b.Write(r)
+	return b.Bytes()
+}
+
+func TestUnpkg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pkg_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	payload := cpioArchive(
+		cpioEntry{name: ".", mode: 040755},
+		cpioEntry{name: "./temurin-21.jdk", mode: 040755},
+		cpioEntry{name: "./temurin-21.jdk/Contents/Home/bin/java", mode: 0100755, data: "java binary"},
+		cpioEntry{name: "./temurin-21.jdk/Contents/Home/release", mode: 0100644, data: "JAVA_VERSION=21"},
+		cpioEntry{name: "./temurin-21.jdk/Contents/MacOS/libjli.dylib", mode: 0120755, data: "../Home/lib/libjli.dylib"},
+	)
+	for name, compress := range map[string]func([]byte) []byte{"gzip": gzipped, "pbzx": pbzx} {
+		src := filepath.Join(dir, name+".pkg")
+		err := ioutil.WriteFile(src, xarArchive(map[string][]byte{
+			"Distribution":               []byte("<installer-gui-script/>"),
+			"plugin.pkg/Payload":         compress(cpioArchive(cpioEntry{name: "./plugin", mode: 0100644, data: "x"})),
+			"temurin-21.pkg/Payload":     compress(payload),
+			"temurin-21.pkg/PackageInfo": []byte("<pkg-info/>"),
+		}, "Distribution", "plugin.pkg/Payload", "temurin-21.pkg/Payload", "temurin-21.pkg/PackageInfo"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dst := filepath.Join(dir, name)
+		if err := unpkg(src, dst); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dst, "temurin-21.jdk", "Contents", "Home", "bin", "java"))
+		if err != nil || string(b) != "java binary" {
+			t.Fatalf("%s: actual: %q (%v) != expected: %q", name, b, err, "java binary")
+		}
+		if runtime.GOOS != "windows" {
+			stat, _ := os.Stat(filepath.Join(dst, "temurin-21.jdk", "Contents", "Home", "bin", "java"))
+			if stat.Mode()&0111 == 0 {
+				t.Fatalf("%s: bin/java is not executable (%v)", name, stat.Mode())
+			}
+			link, err := os.Readlink(filepath.Join(dst, "temurin-21.jdk", "Contents", "MacOS", "libjli.dylib"))
+			if err != nil || link != "../Home/lib/libjli.dylib" {
+				t.Fatalf("%s: actual: %v (%v) != expected: %v", name, link, err, "../Home/lib/libjli.dylib")
+			}
+		}
+		if err := normalizePathToBinJava(dst, "darwin"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestUnpkgRejectsCorruptArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pkg_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outside := filepath.Join(dir, "outside")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, payload := range map[string][]byte{
+		"escape":  cpioArchive(cpioEntry{name: "../evil", mode: 0100644, data: "x"}),
+		"symlink": cpioArchive(cpioEntry{name: "lib", mode: 0120755, data: "/tmp"}, cpioEntry{name: "lib/evil", mode: 0100644}),
+		"symlink-dir": cpioArchive(cpioEntry{name: "lib", mode: 0120755, data: outside},
+			cpioEntry{name: "lib", mode: 040777}),
+		"truncated": cpioArchive(cpioEntry{name: "release", mode: 0100644, data: "JAVA_VERSION=21"})[:90],
+	} {
+		archive := xarArchive(map[string][]byte{"Payload": gzipped(payload)}, "Payload")
+		src := filepath.Join(dir, name+".pkg")
+		if err := ioutil.WriteFile(src, archive, 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := unpkg(src, filepath.Join(dir, name))
+		if _, ok := err.(*CorruptArchiveError); !ok {
+			t.Fatalf("%s: expected CorruptArchiveError, got %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil")); !os.IsNotExist(err) {
+		t.Fatal("../evil was extracted")
+	}
+	if stat, err := os.Stat(outside); err != nil || stat.Mode().Perm() != 0755 {
+		t.Fatalf("mode of the directory outside of the archive was changed (%v, %v)", stat, err)
+	}
+	// payload tampered with
+	archive := xarArchive(map[string][]byte{"Payload": cpioArchive(cpioEntry{name: "release", mode: 0100644, data: "JAVA_VERSION=21"})}, "Payload")
+	i := bytes.Index(archive, []byte("JAVA_VERSION=21"))
+	archive[i] = 'X'
+	src := filepath.Join(dir, "tampered.pkg")
+	if err := ioutil.WriteFile(src, archive, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := unpkg(src, filepath.Join(dir, "tampered")); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+}
+
+func TestUnpkgDoesNotWriteThroughSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+	dir, err := ioutil.TempDir("", "pkg_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	victim := filepath.Join(dir, "victim.txt")
+	if err := ioutil.WriteFile(victim, []byte("intact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	payload := cpioArchive(
+		cpioEntry{name: "./release", mode: 0120755, data: victim},
+		cpioEntry{name: "./release", mode: 0100644, data: "JAVA_VERSION=21"},
+	)
+	src := filepath.Join(dir, "jdk.pkg")
+	if err := ioutil.WriteFile(src, xarArchive(map[string][]byte{"Payload": gzipped(payload)}, "Payload"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "jdk")
+	if err := unpkg(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(victim); string(b) != "intact" {
+		t.Fatalf("file outside of the archive was overwritten (%q)", b)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dst, "release")); err != nil || string(b) != "JAVA_VERSION=21" {
+		t.Fatalf("actual: %q (%v) != expected: %q", b, err, "JAVA_VERSION=21")
+	}
+}
+
+func TestFindPkgAndUnpkgBundle(t *testing.T) {
+	volume, err := ioutil.TempDir("", "pkg test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(volume)
+	bundle := filepath.Join(volume, "JDK 8 Update 191.pkg")
+	if err := os.MkdirAll(filepath.Join(bundle, "Contents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	payload := gzipped(cpioArchive(
+		cpioEntry{name: ".", mode: 040755},
+		cpioEntry{name: "./Contents/Home/bin/java", mode: 0100755, data: "java binary"},
+	))
+	if err := ioutil.WriteFile(filepath.Join(bundle, "Contents", "Archive.pax.gz"), payload, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// smaller one (e.g. javaappletplugin.pkg) is not supposed to be picked
+	if err := ioutil.WriteFile(filepath.Join(volume, "plugin.pkg"), []byte("xar!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := findPkg(volume)
+	if err != nil || pkg != bundle {
+		t.Fatalf("actual: %v (%v) != expected: %v", pkg, err, bundle)
+	}
+	dst := filepath.Join(volume, "jdk")
+	if err := unpkgBundle(pkg, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dst, "Contents", "Home", "bin", "java")); string(b) != "java binary" {
+		t.Fatalf("actual: %q (%v)", b, err)
+	}
+	if _, err := findPkg(filepath.Join(dst, "Contents")); err == nil {
+		t.Fatal("expected error when there is no .pkg")
+	}
+}
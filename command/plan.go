@@ -0,0 +1,237 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/command/trace"
+)
+
+// InstallPlan describes what `jabba install` is going to do to the file system (see `jabba install --show-plan`).
+type InstallPlan struct {
+	Version string `json:"version"`
+	// true if JDK is already installed (there is nothing to do)
+	AlreadyInstalled bool   `json:"alreadyInstalled"`
+	URL              string `json:"url,omitempty"`
+	// archive type (e.g. "tgz")
+	Type string `json:"type,omitempty"`
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+	// sha256 archive is verified against ("" if neither index nor URL specify one)
+	SHA256 string `json:"sha256,omitempty"`
+	// detached signature & key archive is verified with (see verifySignature)
+	Sig string `json:"sig,omitempty"`
+	Key string `json:"key,omitempty"`
+	// see Release.Notice
+	Notice string `json:"notice,omitempty"`
+	// size of the archive (-1 if unknown)
+	Size int64 `json:"size"`
+	// file archive is downloaded to (or read from in case of file:// URL)
+	Archive string `json:"archive,omitempty"`
+	// true if Archive is kept in the download cache (see cfg.CacheDir) after JDK is installed
+	Cached bool `json:"cached"`
+	// true if archive is extracted as it's being downloaded (Archive is not written to disk)
+	Streamed bool `json:"streamed"`
+	// temporary directory installer is run in (installers only (dmg, bin, ia))
+	TempDir string `json:"tempDir,omitempty"`
+	// directory JDK is extracted into before it's moved to Target ("" if JDK is extracted right into Target)
+	Staging string `json:"staging,omitempty"`
+	Target  string `json:"target"`
+	// what is going to happen (in order)
+	Steps []string `json:"steps"`
+}
+
+// PlanInstall resolves selector the same way Install does, without changing anything on disk.
+// Unlike the plan Install follows, Size is filled in (which takes a HEAD request unless archive is local / cached).
+func PlanInstall(selector string, opts InstallOptions) (*InstallPlan, error) {
+	plan, err := planInstall(selector, opts)
+	if err != nil || plan.AlreadyInstalled {
+		return plan, err
+	}
+	plan.Size = -1
+	if stat, err := os.Stat(plan.Archive); err == nil && (plan.Cached || strings.HasPrefix(plan.URL, "file://")) {
+		plan.Size = stat.Size()
+	} else if !strings.HasPrefix(plan.URL, "file://") {
+		req, err := http.NewRequest("HEAD", plan.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Cookie", "oraclelicense=accept-securebackup-cookie")
+		res, err := newDownloadClient().Do(req)
+		if err != nil {
+			log.Debug("HEAD ", plan.URL, " failed (", err, ")")
+		} else {
+			res.Body.Close()
+			if res.StatusCode < 400 {
+				plan.Size = res.ContentLength
+			}
+		}
+	}
+	return plan, nil
+}
+
+func planInstall(selector string, opts InstallOptions) (*InstallPlan, error) {
+	dst := opts.Dst
+	if opts.crossOS() && dst == "" {
+		return nil, fmt.Errorf("JDK for %s can only be installed into a custom destination (--output)", opts.OS)
+	}
+	resolveSpan := trace.Start("resolve")
+	ver, release, err := resolveRelease(selector, opts)
+	resolveSpan.End(err)
+	if err != nil {
+		return nil, err
+	}
+	// check whether requested version is already installed
+	if dst == "" {
+		local, err := Ls()
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range local {
+			if ver.Equals(v) && IsDigestSelector(selector) {
+				if err := checkInstalledDigest(ver.String(), selector); err != nil {
+					return nil, err
+				}
+			}
+			if ver.Equals(v) {
+				return &InstallPlan{Version: ver.String(), AlreadyInstalled: true,
+					Target: filepath.Join(cfg.JDKDir(), ver.String()), Steps: []string{}}, nil
+			}
+		}
+	}
+	// runtime constraints are those of the host JDK is going to run on
+	if !opts.crossOS() {
+		if err := checkRequirements(release.Requires); err != nil {
+			return nil, fmt.Errorf("%s cannot be installed: %s", ver, err)
+		}
+	}
+	url := release.URL
+	if matched, _ := regexp.MatchString("^\\w+[+]\\w+://", url); !matched {
+		return nil, errors.New("URL must contain qualifier, e.g. tgz+http://...")
+	}
+	fileType := url[0:strings.Index(url, "+")]
+	// before anything is downloaded
+	if _, err := installerFor(opts.targetOS(), fileType); err != nil {
+		return nil, err
+	}
+	if opts.crossOS() && isInstaller(fileType) {
+		return nil, fmt.Errorf("%s is distributed as an installer (%s), which can only be run on %s",
+			ver, fileType, opts.OS)
+	}
+	if !opts.crossOS() {
+		if err := checkCompat(ver, release.arch, fileType, opts.IgnoreCompat); err != nil {
+			return nil, err
+		}
+	}
+	if dst == "" {
+		dst = filepath.Join(cfg.JDKDir(), ver.String())
+	} else {
+		if _, err := os.Stat(dst); !os.IsNotExist(err) {
+			if err == nil { // dst exists
+				if empty, _ := isEmptyDir(dst); !empty {
+					err = fmt.Errorf("\"%s\" is not empty", dst)
+				}
+			} // or is inaccessible
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	url, f, err := splitFragment(url[strings.Index(url, "+")+1:])
+	if err != nil {
+		return nil, err
+	}
+	if IsURLSelector(selector) && f.checksum == "" && f.sig == "" {
+		log.Warn("Installing from unpinned URL (", selector, ") is deprecated. Use `jabba pin-url \"", selector,
+			"\" --lockfile jabba.lock` + `jabba import jabba.lock` instead")
+	}
+	plan := &InstallPlan{Version: ver.String(), URL: url, Type: fileType, OS: release.os, Arch: release.arch,
+		SHA256: strings.TrimPrefix(f.checksum, "sha256="), Notice: noticeOf(release), Target: dst}
+	// sig & key can be specified either in the index entry or in the URL (#sig=...&key=...)
+	plan.Sig, plan.Key = release.Sig, release.Key
+	if f.sig != "" {
+		plan.Sig, plan.Key = f.sig, f.key
+	}
+	if strings.HasPrefix(url, "file://") {
+		if plan.Archive, err = resolveLocalArchive(url); err != nil {
+			return nil, err
+		}
+	} else {
+		plan.Archive, plan.Cached = downloadPath(url, fileType, f.checksum)
+	}
+	if !opts.NoStream && canStream(plan) {
+		plan.Streamed, plan.Archive = true, ""
+	}
+	if fileType == "dmg" || fileType == "bin" || fileType == "ia" {
+		plan.TempDir = filepath.Join(cfg.TempDir(), "jabba-i-*")
+	}
+	if opts.Dst == "" {
+		plan.Staging = filepath.Join(cfg.JDKDir(), ".staging", ver.String())
+	}
+	var hooks cfg.PostInstallHooks
+	if !opts.NoPostInstall {
+		hooks = cfg.PostInstall()
+	}
+	plan.Steps = plan.steps(opts.targetOS(), opts.Dst == "", hooks)
+	return plan, nil
+}
+
+func (plan *InstallPlan) steps(goos string, managed bool, hooks cfg.PostInstallHooks) []string {
+	var steps []string
+	target := plan.Target
+	if plan.Staging != "" {
+		target = plan.Staging
+	}
+	switch {
+	case plan.Streamed:
+		steps = append(steps, "download "+plan.URL+" extracting "+plan.Type+" archive into "+target+
+			" as it's being downloaded")
+	case strings.HasPrefix(plan.URL, "file://"):
+		steps = append(steps, "read "+plan.Archive)
+	case plan.Cached:
+		steps = append(steps, "download "+plan.URL+" to "+plan.Archive+" (unless already cached)")
+	default:
+		steps = append(steps, "download "+plan.URL+" to "+plan.Archive)
+	}
+	if plan.SHA256 != "" {
+		steps = append(steps, "verify sha256 of the archive")
+	}
+	if plan.Sig != "" {
+		steps = append(steps, "verify signature of the archive ("+plan.Key+")")
+	}
+	switch {
+	case plan.Streamed:
+		// see the first step
+	case isInstaller(plan.Type):
+		steps = append(steps, "run "+plan.Type+" installer to install JDK into "+target)
+	default:
+		steps = append(steps, "extract "+plan.Type+" archive into "+target)
+	}
+	steps = append(steps, "make sure java is at "+expectedJavaPath(target, goos)+" (moving JDK files if necessary)")
+	for _, file := range hooks.CACerts {
+		steps = append(steps, "import "+file+" into cacerts (post_install)")
+	}
+	if hooks.JavaSecurity != "" {
+		steps = append(steps, "set properties of "+hooks.JavaSecurity+" in java.security (post_install)")
+	}
+	if hooks.Run != "" {
+		steps = append(steps, "run "+hooks.Run+" (post_install)")
+	}
+	if plan.Staging != "" {
+		steps = append(steps, "move "+plan.Staging+" to "+plan.Target)
+	}
+	if !plan.Cached && !plan.Streamed && !strings.HasPrefix(plan.URL, "file://") {
+		steps = append(steps, "remove "+plan.Archive)
+	}
+	if managed {
+		steps = append(steps, "record checksums of installed files in "+metaFile(plan.Version))
+	}
+	return steps
+}
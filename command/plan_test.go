@@ -0,0 +1,66 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPlanInstall(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	archive := filepath.Join(home, "jdk.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, file := range []string{java, "jdk/release"} {
+		ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755}))
+	}
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	stat, err := os.Stat(archive)
+	ok(err)
+	selector := "1.17.0-custom=tgz+file://" + filepath.ToSlash(archive)
+	plan, err := PlanInstall(selector, InstallOptions{})
+	ok(err)
+	if plan.Version != "1.17.0-custom" || plan.Type != "tgz" || plan.Size != stat.Size() || plan.Archive != archive ||
+		plan.Staging != filepath.Join(home, "jdk", ".staging", "1.17.0-custom") ||
+		plan.Target != filepath.Join(home, "jdk", "1.17.0-custom") || len(plan.Steps) == 0 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	// plan must not touch the file system
+	if _, err := os.Stat(filepath.Join(home, "jdk")); !os.IsNotExist(err) {
+		t.Fatalf("%s was not expected to exist (%v)", filepath.Join(home, "jdk"), err)
+	}
+	_, err = Install(selector, InstallOptions{})
+	ok(err)
+	plan, err = PlanInstall(selector, InstallOptions{})
+	ok(err)
+	if !plan.AlreadyInstalled {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	dst := filepath.Join(home, "custom")
+	plan, err = PlanInstall(selector, InstallOptions{Dst: dst})
+	ok(err)
+	if plan.AlreadyInstalled || plan.Staging != "" || plan.Target != dst {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// platform describes how JDKs are installed on the OS (see platforms).
+type platform struct {
+	// archive/installer type (as in tgz+https://...) -> function extracting it into directory
+	installers map[string]func(src string, dst string) error
+}
+
+// archives are extracted the same way everywhere (installers (dmg, bin, exe, ...) are OS-specific)
+var archiveInstallers = map[string]func(src string, dst string) error{
+	"tgz":  installFromTgz,
+	"tgx":  installFromTgx,
+	"txz":  installFromTgx,
+	"tzst": installFromTzst,
+	"zip":  installFromZip,
+}
+
+// platforms JDKs can be installed on (keyed by runtime.GOOS). Supporting another OS (at least for archives) is a
+// matter of adding an entry here (and to index).
+var platforms = map[string]platform{
+	"darwin":  {withArchives(map[string]func(string, string) error{"dmg": installFromDmg, "pkg": installFromPkg})},
+	"linux":   {withArchives(map[string]func(string, string) error{"bin": installFromBin, "ia": installFromIa})},
+	"windows": {withArchives(map[string]func(string, string) error{"exe": installFromExe})},
+	// OpenJDK builds for BSDs are distributed as (tar.gz / tar.xz / tar.zst) archives (pkg(8) packages included)
+	"freebsd":   {archiveInstallers},
+	"openbsd":   {archiveInstallers},
+	"netbsd":    {archiveInstallers},
+	"dragonfly": {archiveInstallers},
+}
+
+func withArchives(installers map[string]func(string, string) error) map[string]func(string, string) error {
+	for fileType, fn := range archiveInstallers {
+		installers[fileType] = fn
+	}
+	return installers
+}
+
+// SupportedOSs returns OSs JDKs can be installed on (sorted).
+func SupportedOSs() []string {
+	var r []string
+	for goos := range platforms {
+		r = append(r, goos)
+	}
+	sort.Strings(r)
+	return r
+}
+
+func installerFor(goos string, fileType string) (func(src string, dst string) error, error) {
+	p, ok := platforms[goos]
+	if !ok {
+		return nil, errors.New(goos + " OS is not supported")
+	}
+	installer, ok := p.installers[fileType]
+	if !ok {
+		return nil, fmt.Errorf("%s is not supported (on %s)", fileType, goos)
+	}
+	return installer, nil
+}
+
+// installOn extracts/installs file of the given type into dst (removed on failure) the way it's done on goos.
+func installOn(goos string, file string, fileType string, dst string) (err error) {
+	installer, err := installerFor(goos, fileType)
+	if err != nil {
+		return err
+	}
+	err = installer(file, dst)
+	if err == nil {
+		err = normalizePathToBinJava(dst, goos)
+	}
+	if err != nil {
+		os.RemoveAll(dst)
+	}
+	return
+}
@@ -0,0 +1,60 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestInstallForFreeBSD(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	archive := filepath.Join(home, "openjdk17.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, name := range []string{"usr/local/openjdk17/bin/java", "usr/local/openjdk17/release"} {
+		ok(tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0755, Size: 1}))
+		_, err = tw.Write([]byte("x"))
+		ok(err)
+	}
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	index := filepath.Join(home, "index.json")
+	ok(ioutil.WriteFile(index, []byte(`{"freebsd": {"amd64": {"jdk@openjdk": {
+		"1.17.0": "tgz+file://`+filepath.ToSlash(archive)+`",
+		"1.17.1": "dmg+https://example.com/openjdk-17.0.1.dmg"
+	}}}}`), 0644))
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	dst := filepath.Join(home, "image", "jdk")
+	opts := InstallOptions{OS: "freebsd", Arch: "amd64", Dst: dst}
+	if _, err := Install("openjdk@1.17.0", opts); err != nil {
+		t.Fatal(err)
+	}
+	ok(file(expectedJavaPath(dst, "freebsd")))
+	opts.Dst = filepath.Join(home, "dmg")
+	if _, err := Install("openjdk@1.17.1", opts); err == nil || !strings.Contains(err.Error(), "dmg is not supported") {
+		t.Fatalf("expected dmg to be rejected on freebsd (got %v)", err)
+	}
+	if err := installOn("plan9", archive, "tgz", filepath.Join(home, "plan9")); err == nil ||
+		!strings.Contains(err.Error(), "OS is not supported") {
+		t.Fatalf("expected plan9 to be rejected (got %v)", err)
+	}
+}
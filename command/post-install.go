@@ -0,0 +1,305 @@
+package command
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// post_install (see cfg.PostInstall) is applied to JDK in the staging directory, i.e. JDK either gets installed with
+// corporate CA imported (java.security patched, etc.) or (if any of the hooks fails) not at all.
+
+// default password of cacerts (which every vendor ships with)
+const cacertsPassword = "changeit"
+
+// where JDKs keep cacerts & java.security (relative to JAVA_HOME) (JDK 9+ first)
+var (
+	cacertsPaths      = []string{"lib/security/cacerts", "jre/lib/security/cacerts"}
+	javaSecurityPaths = []string{"conf/security/java.security", "jre/lib/security/java.security",
+		"lib/security/java.security"}
+)
+
+// postInstall applies hooks to the JDK (of version ver, for goos) unpacked into dir.
+func postInstall(hooks cfg.PostInstallHooks, dir string, ver string, goos string) error {
+	if hooks.IsEmpty() {
+		return nil
+	}
+	home := filepath.Dir(filepath.Dir(expectedJavaPath(dir, goos)))
+	if len(hooks.CACerts) != 0 {
+		if goos != runtime.GOOS {
+			log.Warn("Not importing post_install.cacerts into ", ver, " (keytool of ", goos, " JDK can't be run on ",
+				runtime.GOOS, ")")
+		} else if err := importCACerts(home, hooks.CACerts); err != nil {
+			return fmt.Errorf("post_install.cacerts: %v", err)
+		}
+	}
+	if hooks.JavaSecurity != "" {
+		if err := patchJavaSecurity(home, hooks.JavaSecurity); err != nil {
+			return fmt.Errorf("post_install.java_security: %v", err)
+		}
+	}
+	if hooks.Run != "" {
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", hooks.Run)
+		} else {
+			cmd = exec.Command("sh", "-c", hooks.Run)
+		}
+		cmd.Env = append(os.Environ(), "JAVA_HOME="+home, "JABBA_VERSION="+ver, "JABBA_OS="+goos)
+		log.Info("Running post_install.run (", hooks.Run, ") against ", ver)
+		if err := runCmd(cmd); err != nil {
+			return fmt.Errorf("post_install.run: %v", err)
+		}
+	}
+	return nil
+}
+
+// ApplyPostInstall applies post_install hooks (see cfg.PostInstall) to the installed JDK matching the selector (e.g.
+// JDKs installed before hooks were configured), returning its version. JDK's metadata is updated accordingly (so
+// that `jabba verify` keeps passing).
+func ApplyPostInstall(selector string) (string, error) {
+	hooks := cfg.PostInstall()
+	if hooks.IsEmpty() {
+		return "", errors.New("post_install is not configured (see config.yaml)")
+	}
+	ver, err := Resolve(selector)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cfg.JDKDir(), ver)
+	meta, merr := readInstallMeta(ver)
+	goos := runtime.GOOS
+	if merr == nil && meta.OS != "" {
+		goos = strings.TrimSuffix(meta.OS, "-musl")
+	}
+	if merr == nil && len(meta.Blobs) != 0 {
+		// keytool & patchJavaSecurity modify files in place (which, if hard-linked (see Dedupe), would modify them
+		// in every JDK sharing the blob)
+		home := filepath.Dir(filepath.Dir(expectedJavaPath(dir, goos)))
+		for _, paths := range [][]string{cacertsPaths, javaSecurityPaths} {
+			if file, err := findUnder(home, paths); err == nil {
+				if err := unshare(file); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	if err := postInstall(hooks, dir, ver, goos); err != nil {
+		return "", err
+	}
+	if merr != nil {
+		return ver, nil
+	}
+	if meta.Files, err = digestTree(dir); err != nil {
+		return "", err
+	}
+	pruneBlobs(meta, dir)
+	return ver, writeInstallMeta(meta)
+}
+
+func findUnder(home string, paths []string) (string, error) {
+	for _, path := range paths {
+		path = filepath.Join(home, filepath.FromSlash(path))
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found under %s", filepath.Base(paths[0]), home)
+}
+
+// importCACerts imports every certificate found in PEM files into cacerts of the JDK (replacing the ones imported
+// before) as "jabba-<file name>" ("jabba-<file name>-<n>" if file contains more than one).
+func importCACerts(home string, files []string) error {
+	cacerts, err := findUnder(home, cacertsPaths)
+	if err != nil {
+		return err
+	}
+	return importCACertsInto(keytoolOf(home), cacerts, files)
+}
+
+func keytoolOf(home string) string {
+	keytool := filepath.Join(home, "bin", "keytool")
+	if runtime.GOOS == "windows" {
+		keytool += ".exe"
+	}
+	return keytool
+}
+
+// importCACertsInto imports certificates of PEM files into keystore (protected by cacertsPassword) (see
+// importCACerts).
+func importCACertsInto(keytool string, cacerts string, files []string) error {
+	tmp, release, err := mkTempDir("jabba-cacerts-")
+	if err != nil {
+		return err
+	}
+	defer release()
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		certs := pemCertificates(b)
+		if len(certs) == 0 {
+			return fmt.Errorf("%s doesn't contain any (PEM-encoded) certificates", file)
+		}
+		log.Info("Importing ", file, " into ", cacerts)
+		for i, cert := range certs {
+			alias := caCertAlias(file)
+			if len(certs) > 1 {
+				alias += "-" + strconv.Itoa(i+1)
+			}
+			certFile := filepath.Join(tmp, alias+".pem")
+			if err := ioutil.WriteFile(certFile, cert, 0644); err != nil {
+				return err
+			}
+			// fails if there is no such entry (which is fine)
+			outputOf(exec.Command(keytool, "-delete", "-noprompt", "-alias", alias, "-keystore", cacerts,
+				"-storepass", cacertsPassword))
+			if err := runCmd(exec.Command(keytool, "-importcert", "-noprompt", "-trustcacerts", "-alias", alias,
+				"-file", certFile, "-keystore", cacerts, "-storepass", cacertsPassword)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var nonAliasCharRegexp = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// "/etc/pki/Corp Root CA.pem" -> "jabba-corp-root-ca"
+func caCertAlias(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return "jabba-" + strings.Trim(nonAliasCharRegexp.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// pemCertificates returns every CERTIFICATE block (PEM-encoded) found in b.
+func pemCertificates(b []byte) [][]byte {
+	var r [][]byte
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return r
+		}
+		if block.Type == "CERTIFICATE" {
+			r = append(r, pem.EncodeToMemory(block))
+		}
+	}
+}
+
+// patchJavaSecurity sets properties found in file in java.security of the JDK.
+func patchJavaSecurity(home string, file string) error {
+	overrides, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	target, err := findUnder(home, javaSecurityPaths)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(target)
+	if err != nil {
+		return err
+	}
+	log.Info("Setting properties of ", file, " in ", target)
+	return ioutil.WriteFile(target, mergeProperties(b, overrides, "added by jabba ("+file+")"), 0644)
+}
+
+// mergeProperties returns properties (java.util.Properties format) with the ones defined in overrides replaced (in
+// place) (properties that are not defined yet are appended (after the comment)).
+func mergeProperties(properties []byte, overrides []byte, comment string) []byte {
+	eol := "\n"
+	if bytes.Contains(properties, []byte("\r\n")) {
+		eol = "\r\n"
+	}
+	var order []string
+	replacements := make(map[string][]string)
+	for _, entry := range propertyEntries(overrides) {
+		if entry.key == "" {
+			continue
+		}
+		if _, ok := replacements[entry.key]; !ok {
+			order = append(order, entry.key)
+		}
+		replacements[entry.key] = entry.lines
+	}
+	var r []string
+	for _, entry := range propertyEntries(properties) {
+		lines, ok := replacements[entry.key]
+		if entry.key == "" || !ok {
+			r = append(r, entry.lines...)
+			continue
+		}
+		if lines != nil {
+			r = append(r, lines...)
+			// only the first definition is replaced (the rest are dropped)
+			replacements[entry.key] = nil
+		}
+	}
+	for len(r) != 0 && strings.TrimSpace(r[len(r)-1]) == "" {
+		r = r[:len(r)-1]
+	}
+	appended := false
+	for _, key := range order {
+		if lines := replacements[key]; lines != nil {
+			if !appended {
+				r = append(r, "", "# "+comment)
+				appended = true
+			}
+			r = append(r, lines...)
+		}
+	}
+	return []byte(strings.Join(r, eol) + eol)
+}
+
+type propertyEntry struct {
+	// "" for comments & blank lines
+	key   string
+	lines []string
+}
+
+// propertyEntries splits properties into logical lines (property value can span multiple lines (\ at the end of the
+// line)).
+func propertyEntries(b []byte) []propertyEntry {
+	var r []propertyEntry
+	var current *propertyEntry
+	for _, line := range strings.Split(strings.TrimRight(string(b), "\r\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if current != nil {
+			current.lines = append(current.lines, line)
+		} else {
+			trimmed := strings.TrimSpace(line)
+			entry := propertyEntry{lines: []string{line}}
+			if trimmed != "" && trimmed[0] != '#' && trimmed[0] != '!' {
+				entry.key = trimmed
+				if i := strings.IndexAny(trimmed, "=: \t"); i != -1 {
+					entry.key = trimmed[:i]
+				}
+			}
+			r = append(r, entry)
+			current = &r[len(r)-1]
+		}
+		if current.key == "" || !continues(line) {
+			current = nil
+		}
+	}
+	return r
+}
+
+// continues returns true if line ends with odd number of backslashes.
+func continues(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, "\\"))
+	return n%2 == 1
+}
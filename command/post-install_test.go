@@ -0,0 +1,144 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestMergeProperties(t *testing.T) {
+	properties := "# comment\n" +
+		"securerandom.source=file:/dev/random\n" +
+		"jdk.tls.disabledAlgorithms=SSLv3, TLSv1, \\\n" +
+		"    TLSv1.1, RC4\n" +
+		"\n" +
+		"#jdk.tls.ephemeralDHKeySize=2048\n" +
+		"networkaddress.cache.ttl = 30\n" +
+		"networkaddress.cache.ttl=60\n"
+	overrides := "# corporate policy\n" +
+		"jdk.tls.disabledAlgorithms=SSLv3, TLSv1, TLSv1.1, \\\n" +
+		"    RC4, 3DES_EDE_CBC\n" +
+		"networkaddress.cache.ttl=10\n" +
+		"jdk.tls.ephemeralDHKeySize=3072\n"
+	actual := string(mergeProperties([]byte(properties), []byte(overrides), "added by jabba"))
+	expected := "# comment\n" +
+		"securerandom.source=file:/dev/random\n" +
+		"jdk.tls.disabledAlgorithms=SSLv3, TLSv1, TLSv1.1, \\\n" +
+		"    RC4, 3DES_EDE_CBC\n" +
+		"\n" +
+		"#jdk.tls.ephemeralDHKeySize=2048\n" +
+		"networkaddress.cache.ttl=10\n" +
+		"\n" +
+		"# added by jabba\n" +
+		"jdk.tls.ephemeralDHKeySize=3072\n"
+	if actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestCACertAlias(t *testing.T) {
+	actual := caCertAlias(filepath.Join("etc", "pki", "Corp Root CA (2024).pem"))
+	expected := "jabba-corp-root-ca-2024"
+	if actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestPostInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("keytool stub is a shell script")
+	}
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	keytoolLog := filepath.Join(home, "keytool.log")
+	javaHomeLog := filepath.Join(home, "java-home.log")
+	archive := filepath.Join(home, "jdk.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range map[string]string{
+		"jdk/bin/java":                    "",
+		"jdk/bin/keytool":                 "#!/bin/sh\necho \"$@\" >> " + keytoolLog + "\n",
+		"jdk/lib/security/cacerts":        "",
+		"jdk/conf/security/java.security": "jdk.tls.disabledAlgorithms=SSLv3\n",
+	} {
+		ok(tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(content))}))
+		_, err = tw.Write([]byte(content))
+		ok(err)
+	}
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	block := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")}))
+	ok(ioutil.WriteFile(filepath.Join(home, "corp.pem"), []byte(block+block), 0644))
+	ok(ioutil.WriteFile(filepath.Join(home, "java.security"), []byte("jdk.tls.disabledAlgorithms=SSLv3, TLSv1\n"),
+		0644))
+	cfg.Use(&cfg.Config{PostInstall: cfg.PostInstallHooks{
+		// relative to jabba home
+		CACerts:      cfg.StringList{"corp.pem"},
+		JavaSecurity: "java.security",
+		Run:          "echo \"$JABBA_VERSION $JAVA_HOME\" >> " + javaHomeLog,
+	}})
+	defer cfg.Use(nil)
+	_, err = Install("1.17.0=tgz+file://"+filepath.ToSlash(archive), InstallOptions{})
+	ok(err)
+	dir := filepath.Join(cfg.JDKDir(), "1.17.0")
+	b, err := ioutil.ReadFile(keytoolLog)
+	ok(err)
+	// staging directory
+	cacerts := filepath.Join(cfg.JDKDir(), ".staging", "1.17.0", "lib", "security", "cacerts")
+	if imports := strings.Count(string(b), "-importcert"); imports != 2 ||
+		!strings.Contains(string(b), "-alias jabba-corp-2 -file") || !strings.Contains(string(b), cacerts) {
+		t.Fatalf("unexpected keytool invocations:\n%s", b)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "conf", "security", "java.security"))
+	ok(err)
+	if string(b) != "jdk.tls.disabledAlgorithms=SSLv3, TLSv1\n" {
+		t.Fatalf("unexpected java.security:\n%s", b)
+	}
+	b, err = ioutil.ReadFile(javaHomeLog)
+	ok(err)
+	if !strings.HasPrefix(string(b), "1.17.0 ") {
+		t.Fatalf("unexpected run output: %s", b)
+	}
+	// hooks are accounted for by `jabba verify`
+	_, err = Verify("1.17.0")
+	ok(err)
+	// re-applying hooks keeps `jabba verify` passing
+	cfg.Use(&cfg.Config{PostInstall: cfg.PostInstallHooks{Run: "echo x >> \"$JAVA_HOME/release\""}})
+	ver, err := ApplyPostInstall("1.17")
+	ok(err)
+	if ver != "1.17.0" {
+		t.Fatalf("actual: %v != expected: 1.17.0", ver)
+	}
+	_, err = Verify("1.17.0")
+	ok(err)
+	// JDK is not installed if any of the hooks fails
+	cfg.Use(&cfg.Config{PostInstall: cfg.PostInstallHooks{Run: "exit 3"}})
+	if _, err := Install("1.17.1=tgz+file://"+filepath.ToSlash(archive), InstallOptions{}); err == nil {
+		t.Fatal("expected Install to fail (post_install.run exited with 3)")
+	}
+	if actual := installed(t); len(actual) != 1 {
+		t.Fatalf("actual: %v != expected: [1.17.0]", actual)
+	}
+	// ... unless hooks are skipped
+	_, err = Install("1.17.1=tgz+file://"+filepath.ToSlash(archive), InstallOptions{NoPostInstall: true})
+	ok(err)
+}
@@ -0,0 +1,94 @@
+package command
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// fsStat is what is left on the file system (see statFSOf).
+type fsStat struct {
+	bytes  uint64
+	inodes uint64
+	// false if file system doesn't have a fixed number of inodes (or doesn't report it)
+	inodesKnown bool
+}
+
+var statFS = statFSOf
+
+const (
+	// number of files JDK is assumed to have when it cannot be told without reading the whole archive
+	// (i.e. anything but zip)
+	estimatedJDKFiles = 5000
+	// extracted JDK is assumed to be this many times larger than the archive it came from
+	estimatedCompressionRatio = 2
+)
+
+// InsufficientSpaceError is returned when file system JDK is about to be extracted to is (nearly) full.
+type InsufficientSpaceError struct {
+	Dir string
+	// "disk space" or "inodes"
+	Resource  string
+	Required  uint64
+	Available uint64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	if e.Resource == "inodes" {
+		return fmt.Sprintf("Not enough free inodes on the file system %s is on (~%d required, %d available). "+
+			"File system has run out of inodes (JDK consists of thousands of small files) even though there might be "+
+			"plenty of disk space left (see `df -i %s`)", e.Dir, e.Required, e.Available, e.Dir)
+	}
+	return fmt.Sprintf("Not enough disk space in %s (~%s required, %s available)", e.Dir,
+		FormatSize(int64(e.Required)), FormatSize(int64(e.Available)))
+}
+
+// preflight makes sure there is enough room (both bytes and inodes) in dir for JDK extracted from the archive
+// ("" if archive is extracted as it's being downloaded (in which case only inodes are checked)).
+func preflight(dir string, fileType string, archive string) error {
+	bytes, inodes := estimateJDKSize(fileType, archive)
+	// dir might not exist yet (e.g. --output)
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	st, err := statFS(dir)
+	if err != nil {
+		log.Debug("Unable to determine free space in ", dir, " (", err, ")")
+		return nil
+	}
+	log.Debugf("%s: %d bytes / %d inodes available (~%d / ~%d required)", dir, st.bytes, st.inodes, bytes, inodes)
+	if st.bytes < bytes {
+		return &InsufficientSpaceError{Dir: dir, Resource: "disk space", Required: bytes, Available: st.bytes}
+	}
+	if st.inodesKnown && st.inodes < inodes {
+		return &InsufficientSpaceError{Dir: dir, Resource: "inodes", Required: inodes, Available: st.inodes}
+	}
+	return nil
+}
+
+// estimateJDKSize returns (approximate) size & number of files of JDK in the archive.
+func estimateJDKSize(fileType string, archive string) (bytes uint64, files uint64) {
+	if archive == "" {
+		return 0, estimatedJDKFiles
+	}
+	if fileType == "zip" {
+		// central directory has everything we need
+		if r, err := zip.OpenReader(archive); err == nil {
+			defer r.Close()
+			for _, f := range r.File {
+				bytes += f.UncompressedSize64
+			}
+			return bytes, uint64(len(r.File))
+		}
+	}
+	if stat, err := os.Stat(archive); err == nil {
+		bytes = uint64(stat.Size()) * estimatedCompressionRatio
+	}
+	return bytes, estimatedJDKFiles
+}
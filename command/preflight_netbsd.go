@@ -0,0 +1,8 @@
+package command
+
+import "errors"
+
+func statFSOf(dir string) (fsStat, error) {
+	// statvfs(2) isn't exposed by syscall on NetBSD
+	return fsStat{}, errors.New("not supported on NetBSD")
+}
@@ -0,0 +1,11 @@
+package command
+
+import "syscall"
+
+func statFSOf(dir string) (fsStat, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return fsStat{}, err
+	}
+	return fsStat{bytes: uint64(st.F_bavail) * uint64(st.F_bsize), inodes: st.F_ffree, inodesKnown: st.F_files != 0}, nil
+}
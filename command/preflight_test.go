@@ -0,0 +1,53 @@
+package command
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preflight_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var headers []*zip.FileHeader
+	for _, name := range []string{"jdk/bin/java", "jdk/bin/javac", "jdk/release"} {
+		headers = append(headers, &zip.FileHeader{Name: name, Method: zip.Deflate})
+	}
+	archive, err := zipArchive(dir, "jdk.zip", headers, []string{strings.Repeat("j", 1024), "javac", "release"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(prev func(string) (fsStat, error)) { statFS = prev }(statFS)
+	for name, c := range map[string]struct {
+		st       fsStat
+		archive  string
+		resource string
+	}{
+		"enough":                  {fsStat{bytes: 1 << 20, inodes: 3, inodesKnown: true}, archive, ""},
+		"out of inodes":           {fsStat{bytes: 1 << 20, inodes: 2, inodesKnown: true}, archive, "inodes"},
+		"dynamic inodes":          {fsStat{bytes: 1 << 20}, archive, ""},
+		"out of disk space":       {fsStat{bytes: 1000, inodes: 1 << 20, inodesKnown: true}, archive, "disk space"},
+		"streamed":                {fsStat{bytes: 0, inodes: estimatedJDKFiles, inodesKnown: true}, "", ""},
+		"streamed, out of inodes": {fsStat{bytes: 1 << 20, inodes: 100, inodesKnown: true}, "", "inodes"},
+	} {
+		st := c.st
+		statFS = func(string) (fsStat, error) { return st, nil }
+		// target (its parent, to be precise) doesn't exist yet
+		err := preflight(filepath.Join(dir, "output", "jdk"), "zip", c.archive)
+		if c.resource == "" {
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			continue
+		}
+		if e, ok := err.(*InsufficientSpaceError); !ok || e.Resource != c.resource || e.Dir != dir {
+			t.Fatalf("%s: actual: %v != expected: InsufficientSpaceError (%s)", name, err, c.resource)
+		}
+	}
+}
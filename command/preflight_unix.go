@@ -0,0 +1,16 @@
+//go:build !windows && !openbsd && !netbsd
+// +build !windows,!openbsd,!netbsd
+
+package command
+
+import "syscall"
+
+func statFSOf(dir string) (fsStat, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return fsStat{}, err
+	}
+	// f_files is 0 on file systems that allocate inodes dynamically (e.g. btrfs)
+	return fsStat{bytes: uint64(st.Bavail) * uint64(st.Bsize), inodes: uint64(st.Ffree),
+		inodesKnown: st.Files != 0}, nil
+}
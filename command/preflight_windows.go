@@ -0,0 +1,9 @@
+package command
+
+import "github.com/shyiko/jabba/w32"
+
+func statFSOf(dir string) (fsStat, error) {
+	// NTFS has no fixed inode table
+	available, err := w32.GetDiskFreeSpaceEx(dir)
+	return fsStat{bytes: available}, err
+}
@@ -0,0 +1,112 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// applied profiles are tracked in the environment so that they could be undone by the next `jabba use` / `deactivate`
+const (
+	profileVar     = "JABBA_PROFILE"
+	profileEnvVar  = "JABBA_PROFILE_ENV"
+	profilePathVar = "JABBA_PROFILE_PATH"
+)
+
+var getProfile = cfg.GetProfile
+
+// applyProfiles extends env (in "key=value" format, PATH included) with variables & PATH entries of the profiles
+// (undoing the ones applied before). unset is a list of variables that are no longer needed.
+func applyProfiles(env []string, names []string) (set []string, unset []string, err error) {
+	var keys []string
+	values := make(map[string]string)
+	put := func(key, value string) {
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = value
+	}
+	for _, kv := range env {
+		split := strings.SplitN(kv, "=", 2)
+		put(split[0], split[1])
+	}
+	pth, previouslySet := undoProfiles(values["PATH"])
+	lookup := func(key string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}
+	var profileKeys, profilePath []string
+	for _, name := range names {
+		profile, ok := getProfile(name)
+		if !ok {
+			return nil, nil, fmt.Errorf("Profile \"%s\" is not defined (see \"profiles\" in config.yaml)", name)
+		}
+		var envKeys []string
+		for key := range profile.Env {
+			envKeys = append(envKeys, key)
+		}
+		sort.Strings(envKeys)
+		for _, key := range envKeys {
+			if key == "PATH" || strings.HasPrefix(key, profileVar) {
+				return nil, nil, fmt.Errorf("Profile \"%s\" cannot set %s", name, key)
+			}
+			if err := validateEnvKey(key); err != nil {
+				return nil, nil, fmt.Errorf("Profile \"%s\": %s", name, err)
+			}
+			put(key, os.Expand(profile.Env[key], lookup))
+			profileKeys = append(profileKeys, key)
+		}
+		for _, dir := range profile.Path {
+			profilePath = append(profilePath, os.Expand(dir, lookup))
+		}
+	}
+	if len(profilePath) != 0 {
+		pth = strings.Join(profilePath, string(os.PathListSeparator)) + string(os.PathListSeparator) + pth
+	}
+	put("PATH", pth)
+	if len(names) != 0 {
+		put(profileVar, strings.Join(names, ","))
+		put(profileEnvVar, strings.Join(profileKeys, ","))
+		put(profilePathVar, strings.Join(profilePath, string(os.PathListSeparator)))
+	}
+	for _, key := range keys {
+		set = append(set, key+"="+values[key])
+	}
+	for _, key := range previouslySet {
+		if _, ok := values[key]; !ok {
+			unset = append(unset, key)
+		}
+	}
+	return set, unset, nil
+}
+
+// undoProfiles strips PATH entries added by the profiles applied before, returning the names of variables
+// set by them (profile bookkeeping variables included).
+func undoProfiles(pth string) (string, []string) {
+	if _, ok := os.LookupEnv(profileVar); !ok {
+		return pth, nil
+	}
+	added := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv(profilePathVar)) {
+		added[dir] = true
+	}
+	var dirs []string
+	for _, dir := range filepath.SplitList(pth) {
+		if !added[dir] {
+			dirs = append(dirs, dir)
+		}
+	}
+	keys := []string{profileVar, profileEnvVar, profilePathVar}
+	for _, key := range strings.Split(os.Getenv(profileEnvVar), ",") {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return strings.Join(dirs, string(os.PathListSeparator)), keys
+}
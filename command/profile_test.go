@@ -0,0 +1,56 @@
+package command
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestApplyProfiles(t *testing.T) {
+	prevGetProfile := getProfile
+	defer func() { getProfile = prevGetProfile }()
+	getProfile = func(name string) (cfg.Profile, bool) {
+		switch name {
+		case "debug":
+			return cfg.Profile{Env: map[string]string{"JAVA_TOOL_OPTIONS": "-agentlib:jdwp=transport=dt_socket"}}, true
+		case "profiler":
+			return cfg.Profile{Env: map[string]string{"ASYNC_PROFILER_JDK": "$JAVA_HOME"}}, true
+		}
+		return cfg.Profile{}, false
+	}
+	env := []string{"PATH=/jdk/bin:/usr/bin", "JAVA_HOME=/jdk"}
+	actual, unset, err := applyProfiles(env, []string{"debug", "profiler"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"PATH=/jdk/bin:/usr/bin", "JAVA_HOME=/jdk",
+		"JAVA_TOOL_OPTIONS=-agentlib:jdwp=transport=dt_socket", "ASYNC_PROFILER_JDK=/jdk",
+		"JABBA_PROFILE=debug,profiler", "JABBA_PROFILE_ENV=JAVA_TOOL_OPTIONS,ASYNC_PROFILER_JDK", "JABBA_PROFILE_PATH=",
+	}
+	if !reflect.DeepEqual(actual, expected) || len(unset) != 0 {
+		t.Fatalf("actual: %v (unset: %v) != expected: %v", actual, unset, expected)
+	}
+	if _, _, err := applyProfiles(env, []string{"missing"}); err == nil {
+		t.Fatal("expected undefined profile to be rejected")
+	}
+	// switching to another JDK without profiles undoes the ones applied before
+	for _, kv := range [][2]string{
+		{"JABBA_PROFILE", "debug"}, {"JABBA_PROFILE_ENV", "JAVA_TOOL_OPTIONS"}, {"JABBA_PROFILE_PATH", "/opt/tools/bin"},
+	} {
+		os.Setenv(kv[0], kv[1])
+		defer os.Unsetenv(kv[0])
+	}
+	actual, unset, err = applyProfiles([]string{"PATH=/jdk/bin:/opt/tools/bin:/usr/bin"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"PATH=/jdk/bin:/usr/bin"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if expected := []string{"JABBA_PROFILE", "JABBA_PROFILE_ENV", "JABBA_PROFILE_PATH", "JAVA_TOOL_OPTIONS"}; !reflect.DeepEqual(unset, expected) {
+		t.Fatalf("actual: %v != expected: %v", unset, expected)
+	}
+}
@@ -0,0 +1,211 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/ioprogress"
+)
+
+// ProgressModes lists values accepted by SetProgress.
+// "auto" means "tty" if stderr is a terminal and "plain" otherwise (e.g. in CI).
+// "plain-verbose" is "plain" in complete sentences (for screen readers & dumb terminals).
+var ProgressModes = []string{"auto", "tty", "plain", "plain-verbose", "json", "none"}
+
+var progressMode = "auto"
+
+// how often "plain" progress is printed (unless another 10% has been downloaded in the meantime)
+var plainProgressInterval = 10 * time.Second
+
+// SetProgress changes how download progress is reported (see ProgressModes).
+func SetProgress(mode string) error {
+	for _, m := range ProgressModes {
+		if m == mode {
+			progressMode = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("Unsupported progress mode \"%s\" (expected one of %v)", mode, ProgressModes)
+}
+
+// newProgressDrawFunc returns function drawing progress of reading label (e.g. URL) to stderr.
+// offset is the number of bytes that were read before (e.g. by the interrupted download).
+func newProgressDrawFunc(label string, offset int64) ioprogress.DrawFunc {
+	switch resolveProgressMode() {
+	case "tty":
+		return ioprogress.DrawTerminalf(os.Stderr, ioprogress.DrawTextFormatBytes)
+	case "plain":
+		return plainProgress(os.Stderr, label, offset)
+	case "plain-verbose":
+		return verboseProgress(os.Stderr, label, offset)
+	case "json":
+		return jsonProgress(os.Stderr, label, offset)
+	}
+	return func(int64, int64) error { return nil }
+}
+
+// resolveProgressMode returns progress mode with "auto" resolved.
+func resolveProgressMode() string {
+	if progressMode != "auto" {
+		return progressMode
+	}
+	if isTerminal(os.Stderr) {
+		return "tty"
+	}
+	return "plain"
+}
+
+// plainProgress prints a line every plainProgressInterval or 10% (whichever comes first) instead of redrawing
+// the same line (\r garbles CI logs).
+func plainProgress(w io.Writer, label string, offset int64) ioprogress.DrawFunc {
+	var last time.Time
+	lastPercent := int64(-1)
+	return func(n int64, size int64) error {
+		// (-1, -1) marks the end of the progress
+		if n < 0 {
+			return nil
+		}
+		n += offset
+		if size < 0 {
+			if time.Since(last) >= plainProgressInterval {
+				last = time.Now()
+				fmt.Fprintf(w, "%s: %s\n", label, ioprogress.DrawTextFormatBytes(n, -1))
+			}
+			return nil
+		}
+		size += offset
+		percent := int64(100)
+		if size > 0 {
+			percent = n * 100 / size
+		}
+		if time.Since(last) >= plainProgressInterval || percent/10 > lastPercent/10 {
+			last, lastPercent = time.Now(), percent
+			fmt.Fprintf(w, "%s: %s (%d%%)\n", label, ioprogress.DrawTextFormatBytes(n, size), percent)
+		}
+		return nil
+	}
+}
+
+// verboseProgress is plainProgress that speaks in complete sentences ("Downloaded 45 of 190 megabytes of
+// jdk.tar.gz (23 percent).") (no units screen readers would spell letter by letter, no URLs, nothing is redrawn).
+func verboseProgress(w io.Writer, label string, offset int64) ioprogress.DrawFunc {
+	name := label
+	if i := strings.IndexAny(name, "?#"); i != -1 {
+		name = name[:i]
+	}
+	if i := strings.LastIndexAny(strings.TrimRight(name, "/\\"), "/\\"); i != -1 {
+		name = name[i+1:]
+	}
+	var last time.Time
+	started := false
+	lastPercent := int64(-1)
+	var lastN, lastSize int64
+	return func(n int64, size int64) error {
+		if n < 0 {
+			if started {
+				fmt.Fprintf(w, "Finished downloading %s (%s).\n", name, spokenSize(lastN, lastN))
+			}
+			return nil
+		}
+		n += offset
+		if size >= 0 {
+			size += offset
+		}
+		lastN, lastSize = n, size
+		if !started {
+			started = true
+			last = time.Now()
+			if size < 0 {
+				fmt.Fprintf(w, "Downloading %s (size is unknown).\n", name)
+			} else {
+				fmt.Fprintf(w, "Downloading %s (%s).\n", name, spokenSize(size, size))
+			}
+			return nil
+		}
+		if size < 0 {
+			if time.Since(last) >= plainProgressInterval {
+				last = time.Now()
+				fmt.Fprintf(w, "Downloaded %s of %s so far.\n", spokenSize(n, n), name)
+			}
+			return nil
+		}
+		percent := int64(100)
+		if size > 0 {
+			percent = n * 100 / size
+		}
+		if (time.Since(last) >= plainProgressInterval || percent/10 > lastPercent/10) && percent < 100 {
+			last, lastPercent = time.Now(), percent
+			fmt.Fprintf(w, "Downloaded %s of %s (%d percent).\n", spokenSizeOf(n, lastSize), name, percent)
+		}
+		return nil
+	}
+}
+
+var spokenSizeUnits = []string{"bytes", "kilobytes", "megabytes", "gigabytes", "terabytes"}
+
+// spokenSize formats n in units of scale ("190 megabytes", "1.5 gigabytes").
+func spokenSize(n int64, scale int64) string {
+	value, unit := spokenValue(n, scale)
+	return value + " " + unit
+}
+
+// spokenSizeOf formats n out of total ("45 of 190 megabytes").
+func spokenSizeOf(n int64, total int64) string {
+	value, _ := spokenValue(n, total)
+	return value + " of " + spokenSize(total, total)
+}
+
+// spokenValue returns n in units of scale (rounded to one decimal place (dropped if it's zero)) & the unit.
+func spokenValue(n int64, scale int64) (string, string) {
+	value, unit := float64(n), 0
+	for s := float64(scale); s >= 1024 && unit < len(spokenSizeUnits)-1; s /= 1024 {
+		value /= 1024
+		unit++
+	}
+	r := strings.TrimSuffix(strconv.FormatFloat(value, 'f', 1, 64), ".0")
+	if unit == 0 {
+		r = strconv.FormatInt(n, 10)
+	}
+	name := spokenSizeUnits[unit]
+	if r == "1" {
+		name = strings.TrimSuffix(name, "s")
+	}
+	return r, name
+}
+
+// ProgressEvent is what --progress=json emits (one JSON object per line).
+type ProgressEvent struct {
+	// "progress" or "done"
+	Event string `json:"event"`
+	URL   string `json:"url"`
+	// bytes read so far
+	Downloaded int64 `json:"downloaded"`
+	// -1 if unknown
+	Total int64 `json:"total"`
+}
+
+func jsonProgress(w io.Writer, label string, offset int64) ioprogress.DrawFunc {
+	var last ProgressEvent
+	enc := json.NewEncoder(w)
+	return func(n int64, size int64) error {
+		if n < 0 {
+			last.Event = "done"
+			return enc.Encode(last)
+		}
+		if size >= 0 {
+			size += offset
+		}
+		last = ProgressEvent{Event: "progress", URL: label, Downloaded: offset + n, Total: size}
+		return enc.Encode(last)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
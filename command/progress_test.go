@@ -0,0 +1,91 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlainProgress(t *testing.T) {
+	prevInterval := plainProgressInterval
+	defer func() { plainProgressInterval = prevInterval }()
+	plainProgressInterval = time.Hour
+	var b bytes.Buffer
+	draw := plainProgress(&b, "jdk.tar.gz", 0)
+	for n := int64(0); n <= 1000; n += 50 {
+		draw(n, 1000)
+	}
+	draw(-1, -1)
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	// 0%, 10%, ..., 100%
+	if len(lines) != 11 || strings.Contains(b.String(), "\r") {
+		t.Fatalf("unexpected output: %q", b.String())
+	}
+	if expected := "jdk.tar.gz: 1 KB/1 KB (100%)"; lines[10] != expected {
+		t.Fatalf("actual: %v != expected: %v", lines[10], expected)
+	}
+}
+
+func TestJSONProgress(t *testing.T) {
+	var b bytes.Buffer
+	// resumed download
+	draw := jsonProgress(&b, "https://example.com/jdk.tar.gz", 100)
+	draw(0, 900)
+	draw(900, 900)
+	draw(-1, -1)
+	var events []ProgressEvent
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var e ProgressEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+	url := "https://example.com/jdk.tar.gz"
+	expected := []ProgressEvent{{"progress", url, 100, 1000}, {"progress", url, 1000, 1000}, {"done", url, 1000, 1000}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("actual: %v != expected: %v", events, expected)
+	}
+}
+
+func TestSetProgress(t *testing.T) {
+	defer SetProgress("auto")
+	if err := SetProgress("json"); err != nil || progressMode != "json" {
+		t.Fatalf("actual: %v (%v)", progressMode, err)
+	}
+	if err := SetProgress("fancy"); err == nil {
+		t.Fatal("expected unsupported mode to be rejected")
+	}
+}
+
+func TestVerboseProgress(t *testing.T) {
+	prevInterval := plainProgressInterval
+	defer func() { plainProgressInterval = prevInterval }()
+	plainProgressInterval = time.Hour
+	var b bytes.Buffer
+	draw := verboseProgress(&b, "https://example.com/dist/jdk.tar.gz?token=secret", 0)
+	size := int64(190 * 1024 * 1024)
+	for n := int64(0); n <= size; n += size / 20 {
+		draw(n, size)
+	}
+	draw(-1, -1)
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	// start, 10%, ..., 90%, end
+	if len(lines) != 11 || strings.ContainsAny(b.String(), "\r%") || strings.Contains(b.String(), "secret") {
+		t.Fatalf("unexpected output: %q", b.String())
+	}
+	expected := []string{
+		"Downloading jdk.tar.gz (190 megabytes).",
+		"Downloaded 19 of 190 megabytes of jdk.tar.gz (10 percent).",
+		"Finished downloading jdk.tar.gz (190 megabytes).",
+	}
+	if actual := []string{lines[0], lines[1], lines[10]}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %q != expected: %q", actual, expected)
+	}
+	if actual := spokenSizeOf(1536*1024*1024, 2*1024*1024*1024); actual != "1.5 of 2 gigabytes" {
+		t.Fatalf("actual: %v != expected: 1.5 of 2 gigabytes", actual)
+	}
+}
@@ -0,0 +1,67 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+// ProjectSelector returns what the project (dir or its closest parent with .jabbarc, .java-version or
+// .tool-versions (see ProjectVersion)) pins JDK to, in a form that can be passed to Install: alias is resolved to
+// its value and, if project has jabba.lock (next to the file version was taken from) with a matching entry (for this
+// platform), the selector refers to that exact archive (so that everyone working on the project gets the same bytes).
+// file is the path to the file version was taken from.
+func ProjectSelector(dir string) (selector string, file string, err error) {
+	ver, file, err := ProjectVersion(dir)
+	if err != nil {
+		return "", "", err
+	}
+	if file == "" {
+		abs, _ := filepath.Abs(dir)
+		return "", "", fmt.Errorf("neither %s nor any of its parents has .jabbarc, .java-version or .tool-versions", abs)
+	}
+	selector, err = ResolveAlias(ver)
+	if err != nil {
+		return "", file, err
+	}
+	rng, err := semver.ParseRange(selector)
+	if err != nil {
+		return "", file, fmt.Errorf("%s: \"%s\" is neither a version (range) nor an alias defined on this machine",
+			file, ver)
+	}
+	lockfile := filepath.Join(filepath.Dir(file), "jabba.lock")
+	if _, err := os.Stat(lockfile); err != nil {
+		return selector, file, nil
+	}
+	lock, err := ReadLockfile(lockfile)
+	if err != nil {
+		return "", file, err
+	}
+	if jdk := lockedMatch(lock, rng); jdk != nil {
+		log.Debug(ver, " is locked to ", jdk.Version, " (", jdk.URL, ") in ", lockfile)
+		return jdk.Version + "=" + jdk.URL + "#sha256=" + jdk.SHA256, file, nil
+	}
+	return selector, file, nil
+}
+
+// lockedMatch returns the latest entry of the lockfile (locked for this platform) matching the range (nil if none).
+func lockedMatch(lock *Lockfile, rng *semver.Range) *LockedJDK {
+	hostOS, _ := TargetOS(runtime.GOOS, "")
+	var match *LockedJDK
+	var matchVersion *semver.Version
+	for i, jdk := range lock.JDKs {
+		v, err := semver.ParseVersion(jdk.Version)
+		if err != nil || !rng.Contains(v) || jdk.SHA256 == "" ||
+			jdk.OS != "" && jdk.OS != runtime.GOOS && jdk.OS != hostOS || jdk.Arch != "" && jdk.Arch != HostArch() {
+			continue
+		}
+		if match == nil || matchVersion.LessThan(v) {
+			match, matchVersion = &lock.JDKs[i], v
+		}
+	}
+	return match
+}
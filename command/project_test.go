@@ -0,0 +1,62 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestProjectSelector(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	project := filepath.Join(home, "repo")
+	service := filepath.Join(project, "services", "api")
+	ok(os.MkdirAll(service, 0755))
+	if _, _, err := ProjectSelector(service); err == nil {
+		t.Fatal("expected ProjectSelector to fail (nothing is pinned)")
+	}
+	ok(ioutil.WriteFile(filepath.Join(project, ".java-version"), []byte("zulu-17\n"), 0644))
+	selector, file, err := ProjectSelector(service)
+	ok(err)
+	if selector != "zulu@1.17" || file != filepath.Join(project, ".java-version") {
+		t.Fatalf("actual: %v (%v) != expected: zulu@1.17", selector, file)
+	}
+	// the closest pin wins, alias is resolved
+	ok(ioutil.WriteFile(filepath.Join(service, ".jabbarc"), []byte("alias: work/backend\n"), 0644))
+	if _, _, err := ProjectSelector(service); err == nil || !strings.Contains(err.Error(), "work/backend") {
+		t.Fatalf("expected ProjectSelector to fail (alias is not defined) (got %v)", err)
+	}
+	ok(SetAlias("work/backend", "zulu@~1.21.1"))
+	if selector, _, err := ProjectSelector(service); err != nil || selector != "zulu@~1.21.1" {
+		t.Fatalf("actual: %v (%v) != expected: zulu@~1.21.1", selector, err)
+	}
+	// exact archive is taken from jabba.lock
+	sum := strings.Repeat("a", 64)
+	ok(ioutil.WriteFile(filepath.Join(project, "jabba.lock"), []byte(`{"jdks": [
+		{"version": "zulu@1.17.2", "url": "tgz+https://example.com/zulu-17.0.2.tar.gz", "sha256": "`+sum+`",
+		 "os": "`+runtime.GOOS+`", "arch": "`+HostArch()+`"},
+		{"version": "zulu@1.17.3", "url": "zip+https://example.com/zulu-17.0.3.zip", "sha256": "`+sum+`",
+		 "os": "other"},
+		{"version": "zulu@1.21.0", "url": "tgz+https://example.com/zulu-21.0.0.tar.gz", "sha256": "`+sum+`"}
+	]}`), 0644))
+	selector, _, err = ProjectSelector(project)
+	ok(err)
+	if expected := "zulu@1.17.2=tgz+https://example.com/zulu-17.0.2.tar.gz#sha256=" + sum; selector != expected {
+		t.Fatalf("actual: %v != expected: %v", selector, expected)
+	}
+	// jabba.lock of the parent doesn't apply
+	if selector, _, err := ProjectSelector(service); err != nil || selector != "zulu@~1.21.1" {
+		t.Fatalf("actual: %v (%v) != expected: zulu@~1.21.1", selector, err)
+	}
+}
@@ -0,0 +1,93 @@
+package command
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+var adoptiumAPI = "https://api.adoptium.net/v3"
+
+// https://api.adoptium.net/q/swagger-ui/
+type adoptiumProvider struct{}
+
+type adoptiumRelease struct {
+	Binaries []struct {
+		Package struct {
+			Link     string `json:"link"`
+			Checksum string `json:"checksum"`
+		} `json:"package"`
+	} `json:"binaries"`
+	VersionData struct {
+		Major    int `json:"major"`
+		Minor    int `json:"minor"`
+		Security int `json:"security"`
+		Patch    int `json:"patch"`
+	} `json:"version_data"`
+}
+
+func (adoptiumProvider) Name() string {
+	return "adoptium"
+}
+
+func (adoptiumProvider) ListReleases(os, arch string) (map[*semver.Version]Release, error) {
+	if arch == "386" {
+		arch = "x32"
+	} else {
+		arch = vendorArch(arch)
+	}
+	releaseMap := make(map[*semver.Version]Release)
+	const pageSize = 50
+	for page := 0; ; page++ {
+		query := url.Values{
+			"architecture": {arch},
+			"heap_size":    {"normal"},
+			"image_type":   {"jdk"},
+			"jvm_impl":     {"hotspot"},
+			"os":           {vendorOS(os)},
+			"page":         {fmt.Sprint(page)},
+			"page_size":    {fmt.Sprint(pageSize)},
+			"project":      {"jdk"},
+			"release_type": {"ga"},
+			"vendor":       {"eclipse"},
+		}
+		var releases []adoptiumRelease
+		err := fetchJSON(adoptiumAPI+"/assets/version/"+url.PathEscape("[1.0,1000.0)")+"?"+query.Encode(), &releases)
+		if err != nil {
+			// API responds with 404 when there are no more pages
+			if page > 0 && strings.HasSuffix(err.Error(), " 404") {
+				break
+			}
+			return nil, err
+		}
+		for _, r := range releases {
+			for _, binary := range r.Binaries {
+				fileType := fileTypeOf(binary.Package.Link)
+				if fileType == "" {
+					continue
+				}
+				vd := r.VersionData
+				var extra []int
+				if vd.Patch != 0 {
+					extra = append(extra, vd.Patch)
+				}
+				v, err := vendorVersion("temurin", vd.Major, vd.Minor, vd.Security, extra...)
+				if err != nil {
+					return nil, err
+				}
+				link := fileType + "+" + binary.Package.Link
+				if binary.Package.Checksum != "" {
+					link += "#sha256=" + binary.Package.Checksum
+				}
+				releaseMap[v] = Release{URL: link}
+				break
+			}
+		}
+		if len(releases) < pageSize {
+			break
+		}
+	}
+	return releaseMap, nil
+}
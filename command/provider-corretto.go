@@ -0,0 +1,67 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+// https://github.com/corretto (each major version is released from a separate corretto-<major> repo)
+type correttoProvider struct{}
+
+var correttoRepoRegexp = regexp.MustCompile(`^corretto-(\d+)$`)
+
+func (correttoProvider) Name() string {
+	return "corretto"
+}
+
+func (correttoProvider) ListReleases(os, arch string) (map[*semver.Version]Release, error) {
+	var repos []struct {
+		Name string `json:"name"`
+	}
+	if err := fetchJSON(githubAPI+"/orgs/corretto/repos?per_page=100", &repos); err != nil {
+		return nil, err
+	}
+	suffix := ".tar.gz"
+	switch os {
+	case "darwin":
+		os = "macosx"
+	case "linux-musl":
+		os = "alpine-linux"
+	case "windows":
+		suffix = "-jdk.zip"
+	}
+	releaseMap := make(map[*semver.Version]Release)
+	for _, repo := range repos {
+		if !correttoRepoRegexp.MatchString(repo.Name) {
+			continue
+		}
+		releases, err := githubReleases("corretto/" + repo.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, release := range releases {
+			v, err := correttoVersion(release.TagName)
+			if err != nil {
+				continue // not a release tag
+			}
+			tag := release.TagName
+			url := fmt.Sprintf("https://corretto.aws/downloads/resources/%s/amazon-corretto-%s-%s-%s%s",
+				tag, tag, os, vendorArch(arch), suffix)
+			releaseMap[v] = Release{URL: fileTypeOf(url) + "+" + url}
+		}
+	}
+	return releaseMap, nil
+}
+
+// 8.292.10.1 -> amazon-corretto@1.8.292-10.1, 17.0.8.8.1 -> amazon-corretto@1.17.0-8.8.1
+// (same as in the index)
+func correttoVersion(tag string) (*semver.Version, error) {
+	split := strings.Split(tag, ".")
+	if len(split) < 3 {
+		return nil, fmt.Errorf("%s is not a valid version", tag)
+	}
+	return semver.ParseVersion("amazon-corretto@1." + split[0] + "." + split[1] + "-" + strings.Join(split[2:], "."))
+}
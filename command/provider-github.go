@@ -0,0 +1,42 @@
+package command
+
+import (
+	"fmt"
+)
+
+var githubAPI = "https://api.github.com"
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
+	Assets     []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+		// e.g. "sha256:<hex>" (not set for assets uploaded before GitHub started recording digests)
+		Digest string `json:"digest"`
+	} `json:"assets"`
+}
+
+// githubReleases lists (non-draft, non-prerelease) releases of the GitHub repo (e.g. "SAP/SapMachine").
+func githubReleases(repo string) ([]githubRelease, error) {
+	var r []githubRelease
+	const pageSize = 100
+	for page := 1; ; page++ {
+		var releases []githubRelease
+		err := fetchJSON(fmt.Sprintf("%s/repos/%s/releases?per_page=%d&page=%d", githubAPI, repo, pageSize, page),
+			&releases)
+		if err != nil {
+			return nil, err
+		}
+		for _, release := range releases {
+			if !release.Draft && !release.Prerelease {
+				r = append(r, release)
+			}
+		}
+		if len(releases) < pageSize {
+			break
+		}
+	}
+	return r, nil
+}
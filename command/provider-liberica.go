@@ -0,0 +1,77 @@
+package command
+
+import (
+	"net/url"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+var libericaAPI = "https://api.bell-sw.com/v1"
+
+// https://api.bell-sw.com/api.html
+type libericaProvider struct{}
+
+type libericaRelease struct {
+	FeatureVersion int    `json:"featureVersion"`
+	InterimVersion int    `json:"interimVersion"`
+	UpdateVersion  int    `json:"updateVersion"`
+	PatchVersion   int    `json:"patchVersion"`
+	DownloadURL    string `json:"downloadUrl"`
+	GA             bool   `json:"GA"`
+}
+
+func (libericaProvider) Name() string {
+	return "liberica"
+}
+
+func (libericaProvider) ListReleases(os, arch string) (map[*semver.Version]Release, error) {
+	packageType := "tar.gz"
+	if os == "windows" {
+		packageType = "zip"
+	}
+	if os == "darwin" {
+		os = "macos"
+	}
+	var bitness string
+	switch arch {
+	case "amd64":
+		arch, bitness = "x86", "64"
+	case "386":
+		arch, bitness = "x86", "32"
+	case "arm64":
+		arch, bitness = "arm", "64"
+	case "arm":
+		arch, bitness = "arm", "32"
+	}
+	query := url.Values{
+		"os":           {os},
+		"arch":         {arch},
+		"bitness":      {bitness},
+		"package-type": {packageType},
+		"bundle-type":  {"jdk"},
+	}
+	var releases []libericaRelease
+	if err := fetchJSON(libericaAPI+"/liberica/releases?"+query.Encode(), &releases); err != nil {
+		return nil, err
+	}
+	releaseMap := make(map[*semver.Version]Release)
+	for _, r := range releases {
+		if !r.GA {
+			continue
+		}
+		var extra []int
+		if r.PatchVersion != 0 {
+			extra = append(extra, r.PatchVersion)
+		}
+		v, err := vendorVersion("liberica", r.FeatureVersion, r.InterimVersion, r.UpdateVersion, extra...)
+		if err != nil {
+			return nil, err
+		}
+		fileType := fileTypeOf(r.DownloadURL)
+		if fileType == "" {
+			continue
+		}
+		releaseMap[v] = Release{URL: fileType + "+" + r.DownloadURL}
+	}
+	return releaseMap, nil
+}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+// https://github.com/SAP/SapMachine/releases
+type sapMachineProvider struct{}
+
+// sapmachine-jdk-17.0.8_linux-x64_bin.tar.gz
+var sapMachineAssetRegexp = regexp.MustCompile(`^sapmachine-jdk-([0-9.]+)_(linux|macos|windows)-(\w+)_bin[.](tar[.]gz|zip)$`)
+
+func (sapMachineProvider) Name() string {
+	return "sapmachine"
+}
+
+func (sapMachineProvider) ListReleases(os, arch string) (map[*semver.Version]Release, error) {
+	if os == "darwin" {
+		os = "macos"
+	}
+	arch = vendorArch(arch)
+	releases, err := githubReleases("SAP/SapMachine")
+	if err != nil {
+		return nil, err
+	}
+	releaseMap := make(map[*semver.Version]Release)
+	for _, release := range releases {
+		for _, asset := range release.Assets {
+			m := sapMachineAssetRegexp.FindStringSubmatch(asset.Name)
+			if m == nil || m[2] != os || m[3] != arch {
+				continue
+			}
+			var parts []int
+			for _, p := range strings.Split(m[1], ".") {
+				n, _ := strconv.Atoi(p)
+				parts = append(parts, n)
+			}
+			parts = append(parts, 0, 0, 0)
+			var extra []int
+			if len(parts) > 6 { // 4th+ components (e.g. 11.0.20.1)
+				extra = parts[3 : len(parts)-3]
+			}
+			v, err := vendorVersion("sapmachine", parts[0], parts[1], parts[2], extra...)
+			if err != nil {
+				return nil, err
+			}
+			releaseMap[v] = Release{URL: fileTypeOf(asset.BrowserDownloadURL) + "+" + asset.BrowserDownloadURL}
+		}
+	}
+	return releaseMap, nil
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/shyiko/jabba/semver"
+)
+
+var zuluAPI = "https://api.azul.com/metadata/v1"
+
+// https://api.azul.com/metadata/v1/docs/swagger
+type zuluProvider struct{}
+
+type zuluPackage struct {
+	JavaVersion []int  `json:"java_version"`
+	DownloadURL string `json:"download_url"`
+}
+
+func (zuluProvider) Name() string {
+	return "zulu"
+}
+
+func (zuluProvider) ListReleases(os, arch string) (map[*semver.Version]Release, error) {
+	archiveType := "tar.gz"
+	if os == "windows" {
+		archiveType = "zip"
+	}
+	switch os {
+	case "darwin":
+		os = "macos"
+	case "linux":
+		// "linux" matches musl builds too
+		os = "linux_glibc"
+	case "linux-musl":
+		os = "linux_musl"
+	}
+	releaseMap := make(map[*semver.Version]Release)
+	const pageSize = 1000
+	for page := 1; ; page++ {
+		query := url.Values{
+			"os":                 {os},
+			"arch":               {vendorArch(arch)},
+			"archive_type":       {archiveType},
+			"java_package_type":  {"jdk"},
+			"javafx_bundled":     {"false"},
+			"release_status":     {"ga"},
+			"availability_types": {"CA"},
+			"page":               {fmt.Sprint(page)},
+			"page_size":          {fmt.Sprint(pageSize)},
+		}
+		var packages []zuluPackage
+		if err := fetchJSON(zuluAPI+"/zulu/packages/?"+query.Encode(), &packages); err != nil {
+			return nil, err
+		}
+		for _, p := range packages {
+			jv := append(p.JavaVersion, 0, 0, 0)
+			v, err := vendorVersion("zulu", jv[0], jv[1], jv[2])
+			if err != nil {
+				return nil, err
+			}
+			fileType := fileTypeOf(p.DownloadURL)
+			if fileType == "" {
+				continue
+			}
+			releaseMap[v] = Release{URL: fileType + "+" + p.DownloadURL}
+		}
+		if len(packages) < pageSize {
+			break
+		}
+	}
+	return releaseMap, nil
+}
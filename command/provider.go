@@ -0,0 +1,147 @@
+package command
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+	"github.com/shyiko/jabba/w32"
+)
+
+// Provider is a source of JDK releases (e.g. jabba's index or a vendor API).
+// os & arch are in runtime.GOOS / runtime.GOARCH format ("linux-musl" is used for musl-based Linux distributions
+// (e.g. Alpine)).
+type Provider interface {
+	Name() string
+	ListReleases(os, arch string) (map[*semver.Version]Release, error)
+}
+
+var providers = map[string]Provider{}
+
+func registerProvider(provider Provider) {
+	providers[provider.Name()] = provider
+}
+
+func init() {
+	registerProvider(indexProvider{})
+	registerProvider(adoptiumProvider{})
+	registerProvider(zuluProvider{})
+	registerProvider(correttoProvider{})
+	registerProvider(libericaProvider{})
+	registerProvider(sapMachineProvider{})
+}
+
+func GetProvider(name string) (Provider, error) {
+	provider, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown provider \"%s\" (expected one of: %s)",
+			name, strings.Join(ProviderNames(), ", "))
+	}
+	return provider, nil
+}
+
+func ProviderNames() []string {
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// vendorVersion converts vendor version (e.g. 17.0.8) to the format used by jabba (1.17.0-8).
+// qualifier is prepended as "<qualifier>@".
+func vendorVersion(qualifier string, major, minor, security int, extra ...int) (*semver.Version, error) {
+	raw := fmt.Sprintf("%s@1.%d.%d-%d", qualifier, major, minor, security)
+	for _, v := range extra {
+		raw += fmt.Sprintf(".%d", v)
+	}
+	return semver.ParseVersion(raw)
+}
+
+// fileTypeOf maps archive extension to the install qualifier ("" if not supported).
+func fileTypeOf(url string) string {
+	switch {
+	case strings.HasSuffix(url, ".tar.gz"), strings.HasSuffix(url, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(url, ".tar.xz"):
+		return "txz"
+	case strings.HasSuffix(url, ".tar.zst"):
+		return "tzst"
+	case strings.HasSuffix(url, ".zip"):
+		return "zip"
+	case strings.HasSuffix(url, ".dmg"):
+		return "dmg"
+	}
+	return ""
+}
+
+// NormalizeArch maps common architecture names (e.g. x86_64, aarch64, x86) to their runtime.GOARCH equivalents.
+func NormalizeArch(arch string) string {
+	switch strings.ToLower(arch) {
+	case "x86_64", "x64", "amd64":
+		return "amd64"
+	case "aarch64", "arm64":
+		return "arm64"
+	case "x86", "i386", "i686", "386":
+		return "386"
+	}
+	return arch
+}
+
+// nativeArch is replaced in tests.
+var nativeArch = detectNativeArch
+
+// HostArch returns architecture of the machine jabba is running on, which is not necessarily runtime.GOARCH
+// (amd64 build of jabba runs on Windows on ARM too (under x64 emulation)).
+func HostArch() string {
+	return nativeArch()
+}
+
+func detectNativeArch() string {
+	if runtime.GOOS == "windows" {
+		// GetCurrentProcess() pseudo handle
+		_, machine, err := w32.IsWow64Process2(w32.HANDLE(^uintptr(0)))
+		if err != nil {
+			log.Debug("Failed to determine native architecture (", err, ")")
+		} else if machine == w32.IMAGE_FILE_MACHINE_ARM64 {
+			return "arm64"
+		}
+	}
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "amd64" {
+		// amd64 build of jabba running under Rosetta 2 on Apple Silicon
+		if out, err := exec.Command("sysctl", "-in", "hw.optional.arm64").Output(); err == nil &&
+			strings.TrimSpace(string(out)) == "1" {
+			return "arm64"
+		}
+	}
+	return runtime.GOARCH
+}
+
+// vendorOS / vendorArch map runtime.GOOS / runtime.GOARCH to the names most vendor APIs use.
+
+func vendorOS(os string) string {
+	switch os {
+	case "darwin":
+		return "mac"
+	case "linux-musl":
+		return "alpine-linux"
+	}
+	return os
+}
+
+func vendorArch(arch string) string {
+	switch arch {
+	case "amd64":
+		return "x64"
+	case "386":
+		return "x86"
+	case "arm64":
+		return "aarch64"
+	}
+	return arch
+}
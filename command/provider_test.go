@@ -0,0 +1,62 @@
+package command
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestAdoptiumProvider(t *testing.T) {
+	prevAdoptiumAPI := adoptiumAPI
+	defer func() { adoptiumAPI = prevAdoptiumAPI }()
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("os") + "/" + r.URL.Query().Get("architecture")
+		w.Write([]byte(`[
+			{
+				"binaries": [{"package": {"link": "https://example.com/OpenJDK17U-jdk_aarch64_mac_hotspot_17.0.8.1_1.tar.gz", "checksum": "abc"}}],
+				"version_data": {"major": 17, "minor": 0, "security": 8, "patch": 1}
+			},
+			{
+				"binaries": [{"package": {"link": "https://example.com/OpenJDK8U-jdk_aarch64_mac_hotspot_8u382b05.pkg"}}],
+				"version_data": {"major": 8, "minor": 0, "security": 382}
+			}
+		]`))
+	}))
+	defer server.Close()
+	adoptiumAPI = server.URL
+	releaseMap, err := adoptiumProvider{}.ListReleases("darwin", "arm64")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if query != "mac/aarch64" {
+		t.Fatalf("actual: %v != expected: %v", query, "mac/aarch64")
+	}
+	actual := make(map[string]string)
+	for v, release := range releaseMap {
+		actual[v.String()] = release.URL
+	}
+	expected := map[string]string{
+		"temurin@1.17.0-8.1": "tgz+https://example.com/OpenJDK17U-jdk_aarch64_mac_hotspot_17.0.8.1_1.tar.gz#sha256=abc",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestCorrettoVersion(t *testing.T) {
+	for tag, expected := range map[string]string{
+		"8.292.10.1":  "amazon-corretto@1.8.292-10.1",
+		"17.0.8.8.1":  "amazon-corretto@1.17.0-8.8.1",
+		"11.0.20.9.1": "amazon-corretto@1.11.0-20.9.1",
+	} {
+		v, err := correttoVersion(tag)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if v.String() != expected {
+			t.Fatalf("actual: %v != expected: %v", v, expected)
+		}
+	}
+}
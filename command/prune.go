@@ -0,0 +1,117 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Prune uninstalls JDKs that are not referenced by any of the aliases (default included), current shell or project
+// files (.jabbarc, .java-version, .tool-versions) found under dirs, returning the versions that were removed.
+// Links to system JDKs and pinned JDKs (see Pin) are left alone.
+func Prune(dirs []string) ([]string, error) {
+	return prune(dirs, &CleanReport{})
+}
+
+// PlanPrune returns what Prune would remove (without removing anything).
+func PlanPrune(dirs []string) (*CleanReport, error) {
+	r := &CleanReport{DryRun: true}
+	if _, err := prune(dirs, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func prune(dirs []string, r *CleanReport) ([]string, error) {
+	keep, err := referencedVersions(dirs)
+	if err != nil {
+		return nil, err
+	}
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, v := range vs {
+		ver := v.String()
+		if strings.HasPrefix(ver, "system@") {
+			continue
+		}
+		if reason, ok := keep[ver]; ok {
+			log.Info("Keeping ", ver, " (", reason, ")")
+			continue
+		}
+		if err := uninstall(ver, "prune", r); err != nil {
+			if veto, ok := err.(*PolicyVetoError); ok {
+				log.Info("Keeping ", ver, " (", veto.Reason, ")")
+				continue
+			}
+			return removed, err
+		}
+		removed = append(removed, ver)
+	}
+	return removed, nil
+}
+
+// referencedVersions returns installed versions that are still in use (version -> reason).
+func referencedVersions(dirs []string) (map[string]string, error) {
+	r := make(map[string]string)
+	pins, err := Pinned()
+	if err != nil {
+		return nil, err
+	}
+	for _, ver := range pins {
+		r[ver] = "pinned"
+	}
+	names, err := Aliases()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if ver, err := Resolve(name); err == nil && r[ver] == "" {
+			r[ver] = "alias " + name
+		}
+	}
+	if current := Current(); current != "" {
+		r[current] = "used by current shell"
+	}
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != dir && (strings.HasPrefix(info.Name(), ".") || info.Name() == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			for _, vf := range versionFiles {
+				if info.Name() != vf.name {
+					continue
+				}
+				b, err := ioutil.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				selector, err := vf.parse(b)
+				if err != nil {
+					// better safe than sorry (file might be referencing one of the JDKs that are about to be removed)
+					return fmt.Errorf("%s is not valid (%v)", path, err)
+				}
+				if ver, err := Resolve(selector); selector != "" && err == nil {
+					r[ver] = path
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
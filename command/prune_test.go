@@ -0,0 +1,53 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	prevLookPath := lookPath
+	defer func() { lookPath = prevLookPath }()
+	lookPath = func(file string) (string, error) {
+		return filepath.Join(home, "jdk", "1.11.0", "bin", "java"), nil
+	}
+	installFakeJDKs(t, home, "1.8.0", "1.11.0", "zulu@1.17.0", "zulu@1.17.1", "temurin@1.21.0", "1.6.0")
+	if err := SetAlias("default", "1.8"); err != nil {
+		t.Fatal(err)
+	}
+	projects := filepath.Join(home, "projects")
+	for file, content := range map[string]string{
+		filepath.Join(projects, "a", ".jabbarc"):                 "zulu@1.17",
+		filepath.Join(projects, "b", "nested", ".tool-versions"): "java temurin-21.0\n",
+		filepath.Join(projects, "c", "node_modules", ".jabbarc"): "1.6",
+		filepath.Join(projects, "d", ".java-version"):            "24",
+	} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	removed, err := Prune([]string{projects})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"1.6.0", "zulu@1.17.0"}; !reflect.DeepEqual(removed, expected) {
+		t.Fatalf("actual: %v != expected: %v", removed, expected)
+	}
+	expected := []string{"1.11.0", "1.8.0", "temurin@1.21.0", "zulu@1.17.1"}
+	if actual := installed(t); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type jabbarc struct {
+	JDK string
+	// name of the alias (e.g. work/backend) to use instead of JDK
+	Alias string
+}
+
+// files checked (in order) in each directory (see ProjectVersion)
+var versionFiles = []struct {
+	name  string
+	parse func([]byte) (string, error)
+}{
+	{".jabbarc", parseJabbarc},
+	{".java-version", parseJavaVersionFile},
+	{".tool-versions", parseToolVersions},
+}
+
+// ProjectVersion looks for .jabbarc, .java-version (jenv) or .tool-versions (asdf) in dir and its parents
+// (the closest directory wins).
+// file is the path to the file version was taken from ("" if none was found).
+func ProjectVersion(dir string) (ver string, file string, err error) {
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return
+	}
+	for {
+		for _, vf := range versionFiles {
+			file = filepath.Join(dir, vf.name)
+			b, err := ioutil.ReadFile(file)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return "", file, err
+			}
+			ver, err := vf.parse(b)
+			if err != nil {
+				return "", file, fmt.Errorf("%s is not valid (%v)", file, err)
+			}
+			if ver != "" {
+				return ver, file, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+func parseJabbarc(b []byte) (string, error) {
+	var rc jabbarc
+	// content can be a string (jdk version)
+	if err := yaml.Unmarshal(b, &rc.JDK); err != nil {
+		// or a struct
+		if err := yaml.Unmarshal(b, &rc); err != nil {
+			return "", err
+		}
+	}
+	if rc.Alias != "" {
+		if rc.JDK != "" {
+			return "", errors.New("jdk and alias are mutually exclusive")
+		}
+		return rc.Alias, nil
+	}
+	return rc.JDK, nil
+}
+
+func parseJavaVersionFile(b []byte) (string, error) {
+	value := strings.TrimSpace(string(b))
+	if value == "" {
+		return "", nil
+	}
+	return fromForeignVersion(value)
+}
+
+// "java temurin-17.0.1+12 zulu-17.0.1" -> "temurin@1.17.0-1" (fallback versions are ignored)
+func parseToolVersions(b []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "java" {
+			continue
+		}
+		return fromForeignVersion(fields[1])
+	}
+	return "", scanner.Err()
+}
+
+// asdf / jenv vendor -> jabba qualifier
+var foreignVendors = map[string]string{
+	"adoptopenjdk":        "adopt",
+	"adoptopenjdk-openj9": "adopt-openj9",
+	"corretto":            "amazon-corretto",
+	"oracle":              "",
+}
+
+var foreignVersionRegexp = regexp.MustCompile(`^(?:([a-z][a-z0-9-]*?)(?:64)?-)?(\d+(?:[._]\d+)*)(?:\+.*)?$`)
+
+// fromForeignVersion converts jenv/asdf-style version (e.g. "17", "1.8.0_292", "11.0.2", "openjdk64-11.0.2",
+// "temurin-17.0.1+12") to jabba's one ("1.17", "1.8.0-292", "1.11.0-2", "openjdk@1.11.0-2", "temurin@1.17.0-1").
+// Version that's already in jabba's format is returned as is.
+func fromForeignVersion(value string) (string, error) {
+	if strings.Contains(value, "@") || strings.ContainsAny(value, "~^<>=*x ") {
+		return value, nil
+	}
+	m := foreignVersionRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return "", fmt.Errorf("unsupported version \"%s\"", value)
+	}
+	vendor, parts := m[1], strings.FieldsFunc(m[2], func(r rune) bool { return r == '.' || r == '_' })
+	if q, ok := foreignVendors[vendor]; ok {
+		vendor = q
+	}
+	if parts[0] == "1" && len(parts) > 1 {
+		parts = parts[1:]
+	}
+	ver := "1." + parts[0]
+	if len(parts) > 1 {
+		ver += "." + parts[1]
+	}
+	if len(parts) > 2 {
+		ver += "-" + parts[2]
+	}
+	if vendor != "" {
+		ver = vendor + "@" + ver
+	}
+	return ver, nil
+}
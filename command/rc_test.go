@@ -0,0 +1,77 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFromForeignVersion(t *testing.T) {
+	for value, expected := range map[string]string{
+		"17":                         "1.17",
+		"1.8":                        "1.8",
+		"1.8.0_292":                  "1.8.0-292",
+		"11.0.2":                     "1.11.0-2",
+		"openjdk64-11.0.2":           "openjdk@1.11.0-2",
+		"oracle64-1.8.0.292":         "1.8.0-292",
+		"temurin-17.0.1+12":          "temurin@1.17.0-1",
+		"adoptopenjdk-openj9-11.0.2": "adopt-openj9@1.11.0-2",
+		"corretto-8.0.292":           "amazon-corretto@1.8.0-292",
+		"zulu@~1.17":                 "zulu@~1.17",
+		"1.8.x":                      "1.8.x",
+	} {
+		actual, err := fromForeignVersion(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", value, actual, expected)
+		}
+	}
+	if _, err := fromForeignVersion("system"); err == nil {
+		t.Fatal("\"system\" should have been rejected")
+	}
+}
+
+func TestProjectVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-rc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	nested := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(file string, content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expect := func(expected string, expectedFile string) {
+		actual, file, err := ProjectVersion(nested)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected || file != expectedFile {
+			t.Fatalf("actual: %v (%v) != expected: %v (%v)", actual, file, expected, expectedFile)
+		}
+	}
+	write(filepath.Join(dir, ".tool-versions"), "nodejs 16.13.0\njava temurin-17.0.1+12 zulu-17.0.1 # comment\n")
+	expect("temurin@1.17.0-1", filepath.Join(dir, ".tool-versions"))
+	write(filepath.Join(dir, ".jabbarc"), "jdk: 1.8\n")
+	expect("1.8", filepath.Join(dir, ".jabbarc"))
+	// the closest directory wins
+	write(filepath.Join(dir, "a", ".java-version"), "11.0\n")
+	expect("1.11.0", filepath.Join(dir, "a", ".java-version"))
+	// .tool-versions without java is skipped
+	write(filepath.Join(nested, ".tool-versions"), "nodejs 16.13.0\n")
+	expect("1.11.0", filepath.Join(dir, "a", ".java-version"))
+	write(filepath.Join(nested, ".jabbarc"), "alias: work/backend\n")
+	expect("work/backend", filepath.Join(nested, ".jabbarc"))
+	write(filepath.Join(nested, ".jabbarc"), "jdk: 1.8\nalias: work/backend\n")
+	if _, _, err := ProjectVersion(nested); err == nil {
+		t.Fatal("jdk & alias should have been rejected")
+	}
+}
@@ -0,0 +1,23 @@
+package command
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// Refresh re-fetches index (ignoring cached copy), returning URL it was fetched from.
+func Refresh() (string, error) {
+	if cfg.Offline() {
+		return "", fmt.Errorf("refresh is not possible in offline mode")
+	}
+	var err error
+	for _, url := range cfg.Registry() {
+		if _, err = fetchIndex(url, true); err == nil {
+			return url, nil
+		}
+		log.Warn("Failed to fetch ", url, " (", err, ")")
+	}
+	return "", err
+}
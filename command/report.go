@@ -0,0 +1,254 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+// `jabba report` aggregates `jabba facts` of the fleet (e.g. build agents), either piped / read from files or
+// collected over ssh (see RemoteFacts).
+
+// FleetReport is what `jabba report` outputs.
+type FleetReport struct {
+	Hosts []Facts `json:"hosts"`
+	// from the newest to the oldest
+	Versions []FleetVersion `json:"versions"`
+	// hosts facts couldn't be collected from
+	Errors []FleetError `json:"errors,omitempty"`
+}
+
+type FleetVersion struct {
+	Version string `json:"version"`
+	// hosts version is installed on
+	Installed []string `json:"installed"`
+	// hosts "default" alias of which resolves to version
+	Default []string `json:"default,omitempty"`
+}
+
+type FleetError struct {
+	Host  string `json:"host"`
+	Error string `json:"error"`
+}
+
+// RemoteFactsOptions configure how RemoteFacts reaches hosts.
+type RemoteFactsOptions struct {
+	// ssh command (e.g. "ssh -F ~/.ssh/agents.config") (target & remote command are appended to it)
+	SSH string
+	// command to run on the remote host (through the login shell)
+	Command string
+	// how many hosts to query concurrently
+	Jobs int
+	// per host (0 - none)
+	Timeout time.Duration
+}
+
+const (
+	DefaultReportSSH           = "ssh -o BatchMode=yes -o ConnectTimeout=10"
+	DefaultReportRemoteCommand = `"${JABBA_HOME:-$HOME/.jabba}/bin/jabba" facts`
+)
+
+// ReadFacts decodes `jabba facts` outputs (concatenated (e.g. `cat *.json`)). Arrays of facts (e.g.
+// `jabba report --output=json | jq .hosts`) are accepted too.
+func ReadFacts(r io.Reader) ([]Facts, error) {
+	var all []Facts
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return all, nil
+			}
+			return nil, err
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) != 0 && raw[0] == '[' {
+			var facts []Facts
+			if err := json.Unmarshal(raw, &facts); err != nil {
+				return nil, err
+			}
+			all = append(all, facts...)
+			continue
+		}
+		var facts Facts
+		if err := json.Unmarshal(raw, &facts); err != nil {
+			return nil, err
+		}
+		if facts.Host == "" && facts.OS == "" && facts.JDKs == nil {
+			return nil, fmt.Errorf("%s doesn't look like `jabba facts` output", truncate(string(raw), 60))
+		}
+		all = append(all, facts)
+	}
+}
+
+// ReadTargets reads ssh destinations (e.g. "ci@agent-01", "ssh://agent-02:2222"), one per line, from file ("#"
+// starts a comment).
+func ReadTargets(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var targets []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			targets = append(targets, line)
+		}
+	}
+	return targets, scanner.Err()
+}
+
+// RemoteFacts runs `jabba facts` on every target over ssh. Hosts facts couldn't be collected from are returned as
+// errors (in the order of targets).
+func RemoteFacts(targets []string, opts RemoteFactsOptions) ([]Facts, []FleetError) {
+	ssh := strings.Fields(opts.SSH)
+	if len(ssh) == 0 {
+		ssh = strings.Fields(DefaultReportSSH)
+	}
+	command := opts.Command
+	if command == "" {
+		command = DefaultReportRemoteCommand
+	}
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	facts := make([]*Facts, len(targets))
+	errs := make([]error, len(targets))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, target string) {
+			defer func() { <-sem; wg.Done() }()
+			facts[i], errs[i] = remoteFacts(ssh, target, command, opts.Timeout)
+		}(i, target)
+	}
+	wg.Wait()
+	var r []Facts
+	var failed []FleetError
+	for i, target := range targets {
+		if errs[i] != nil {
+			failed = append(failed, FleetError{Host: target, Error: errs[i].Error()})
+			continue
+		}
+		r = append(r, *facts[i])
+	}
+	return r, failed
+}
+
+func remoteFacts(ssh []string, target string, command string, timeout time.Duration) (*Facts, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if strings.HasPrefix(target, "-") {
+		// would be taken for an option (e.g. -oProxyCommand=...) by ssh implementations that don't support "--"
+		return nil, fmt.Errorf("%s is not a valid ssh destination", target)
+	}
+	args := append(append([]string{}, ssh[1:]...), "--", target, command)
+	cmd := exec.CommandContext(ctx, ssh[0], args...)
+	log.Debug("Running ", ssh[0], " ", strings.Join(args, " "))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %v", timeout)
+		}
+		if msg := lastLine(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v (%s)", err, msg)
+		}
+		return nil, err
+	}
+	facts, err := ReadFacts(&stdout)
+	if err == nil && len(facts) != 1 {
+		err = fmt.Errorf("expected facts of one host, got %d", len(facts))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unexpected output of '%s' (%v)", command, err)
+	}
+	if facts[0].Host == "" {
+		facts[0].Host = target
+	}
+	return &facts[0], nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// NewFleetReport aggregates facts (facts of the same host reported more than once (e.g. files collected on
+// different days) are superseded by the last ones).
+func NewFleetReport(facts []Facts, errors []FleetError) *FleetReport {
+	report := &FleetReport{Hosts: []Facts{}, Versions: []FleetVersion{}, Errors: errors}
+	index := make(map[string]int)
+	for _, f := range facts {
+		if i, ok := index[f.Host]; ok {
+			report.Hosts[i] = f
+			continue
+		}
+		index[f.Host] = len(report.Hosts)
+		report.Hosts = append(report.Hosts, f)
+	}
+	sort.SliceStable(report.Hosts, func(i, j int) bool { return report.Hosts[i].Host < report.Hosts[j].Host })
+	byVersion := make(map[string]*FleetVersion)
+	get := func(ver string) *FleetVersion {
+		v, ok := byVersion[ver]
+		if !ok {
+			v = &FleetVersion{Version: ver, Installed: []string{}}
+			byVersion[ver] = v
+		}
+		return v
+	}
+	for _, f := range report.Hosts {
+		for _, jdk := range f.JDKs {
+			v := get(jdk.Version)
+			v.Installed = append(v.Installed, f.Host)
+		}
+		if f.Default != "" {
+			v := get(f.Default)
+			v.Default = append(v.Default, f.Host)
+		}
+	}
+	var vs semver.VersionSlice
+	var unparsable []string
+	for ver := range byVersion {
+		if v, err := semver.ParseVersion(ver); err == nil {
+			vs = append(vs, v)
+		} else {
+			unparsable = append(unparsable, ver)
+		}
+	}
+	sort.Sort(sort.Reverse(vs))
+	sort.Strings(unparsable)
+	for _, v := range vs {
+		report.Versions = append(report.Versions, *byVersion[v.String()])
+	}
+	for _, ver := range unparsable {
+		report.Versions = append(report.Versions, *byVersion[ver])
+	}
+	return report
+}
@@ -0,0 +1,126 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestReadFacts(t *testing.T) {
+	input := `{"host": "agent-01", "os": "linux", "jdks": [{"version": "zulu@1.17.0"}]}
+{"host": "agent-02", "os": "linux", "jdks": []}
+[{"host": "agent-03", "os": "darwin", "jdks": []}]`
+	facts, err := ReadFacts(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hosts []string
+	for _, f := range facts {
+		hosts = append(hosts, f.Host)
+	}
+	expected := []string{"agent-01", "agent-02", "agent-03"}
+	if !reflect.DeepEqual(hosts, expected) {
+		t.Fatalf("actual: %v != expected: %v", hosts, expected)
+	}
+	if _, err := ReadFacts(strings.NewReader(`{"version": "zulu@1.17.0"}`)); err == nil {
+		t.Fatal("output of something other than `jabba facts` should have been rejected")
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "agents.txt")
+	content := "# build agents\nci@agent-01\n\n  ssh://agent-02:2222  # arm64\n"
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := ReadTargets(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"ci@agent-01", "ssh://agent-02:2222"}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Fatalf("actual: %v != expected: %v", targets, expected)
+	}
+}
+
+func TestNewFleetReport(t *testing.T) {
+	jdks := func(vs ...string) []JDK {
+		r := []JDK{}
+		for _, v := range vs {
+			r = append(r, JDK{Version: v})
+		}
+		return r
+	}
+	report := NewFleetReport([]Facts{
+		{Host: "b", Default: "zulu@1.17.0", JDKs: jdks("zulu@1.8.0")},
+		{Host: "a", Default: "zulu@1.17.0", JDKs: jdks("zulu@1.17.0", "zulu@1.8.0")},
+		{Host: "c", JDKs: jdks("zulu@1.8.0")},
+		// newer facts of "b"
+		{Host: "b", Default: "zulu@1.17.0", JDKs: jdks("zulu@1.17.0", "zulu@1.11.0")},
+	}, []FleetError{{Host: "d", Error: "unreachable"}})
+	var hosts []string
+	for _, f := range report.Hosts {
+		hosts = append(hosts, f.Host)
+	}
+	if expected := []string{"a", "b", "c"}; !reflect.DeepEqual(hosts, expected) {
+		t.Fatalf("actual: %v != expected: %v", hosts, expected)
+	}
+	expected := []FleetVersion{
+		{Version: "zulu@1.17.0", Installed: []string{"a", "b"}, Default: []string{"a", "b"}},
+		{Version: "zulu@1.11.0", Installed: []string{"b"}},
+		{Version: "zulu@1.8.0", Installed: []string{"a", "c"}},
+	}
+	if !reflect.DeepEqual(report.Versions, expected) {
+		t.Fatalf("actual: %+v != expected: %+v", report.Versions, expected)
+	}
+	if len(report.Errors) != 1 {
+		t.Fatalf("actual: %v", report.Errors)
+	}
+}
+
+func TestRemoteFacts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ssh stub is a shell script")
+	}
+	dir, err := ioutil.TempDir("", "jabba-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ssh := filepath.Join(dir, "ssh")
+	// ssh -p <port> -- <destination> <command>
+	stub := "#!/bin/sh\n" +
+		"[ \"$1\" = \"-p\" ] && [ \"$3\" = \"--\" ] && [ \"$5\" = \"jabba facts\" ] || exit 2\n" +
+		"case \"$4\" in\n" +
+		"  agent-01) echo '{\"host\": \"agent-01\", \"os\": \"linux\", \"jdks\": [{\"version\": \"zulu@1.17.0\"}]}' ;;\n" +
+		"  agent-02) echo '{\"os\": \"linux\", \"jdks\": []}' ;;\n" +
+		"  *) echo \"ssh: Could not resolve hostname $4\" >&2; exit 255 ;;\n" +
+		"esac\n"
+	if err := ioutil.WriteFile(ssh, []byte(stub), 0755); err != nil {
+		t.Fatal(err)
+	}
+	facts, failed := RemoteFacts([]string{"agent-01", "agent-02", "agent-03", "-oProxyCommand=touch " + dir + "/pwned"},
+		RemoteFactsOptions{SSH: ssh + " -p 2222", Command: "jabba facts", Jobs: 2})
+	var hosts []string
+	for _, f := range facts {
+		hosts = append(hosts, f.Host)
+	}
+	// host that didn't report its name is named after destination
+	if expected := []string{"agent-01", "agent-02"}; !reflect.DeepEqual(hosts, expected) {
+		t.Fatalf("actual: %v != expected: %v", hosts, expected)
+	}
+	if len(failed) != 2 || failed[0].Host != "agent-03" ||
+		!strings.Contains(failed[0].Error, "Could not resolve hostname agent-03") ||
+		!strings.Contains(failed[1].Error, "not a valid ssh destination") {
+		t.Fatalf("actual: %v", failed)
+	}
+}
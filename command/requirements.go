@@ -0,0 +1,137 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	log "github.com/Sirupsen/logrus"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errNotGlibc = errors.New("not a glibc-based system")
+
+var hostGlibcVersion = func() (string, error) {
+	// "glibc 2.31"
+	if out, err := exec.Command("getconf", "GNU_LIBC_VERSION").Output(); err == nil {
+		if fields := strings.Fields(string(out)); len(fields) == 2 && fields[0] == "glibc" {
+			return fields[1], nil
+		}
+	}
+	// "ldd (Ubuntu GLIBC 2.31-0ubuntu9.9) 2.31" / "ldd (GNU libc) 2.34" / "musl libc (x86_64)"
+	out, _ := exec.Command("ldd", "--version").CombinedOutput()
+	firstLine := strings.SplitN(string(out), "\n", 2)[0]
+	if strings.Contains(strings.ToLower(firstLine), "musl") {
+		return "", errNotGlibc
+	}
+	if m := regexp.MustCompile(`(\d+[.]\d+)\s*$`).FindStringSubmatch(firstLine); m != nil {
+		return m[1], nil
+	}
+	return "", errors.New("unable to determine glibc version")
+}
+
+var hostIsMusl = func() bool {
+	if matches, _ := filepath.Glob("/lib/ld-musl-*.so.1"); len(matches) != 0 {
+		return true
+	}
+	_, err := hostGlibcVersion()
+	return err == errNotGlibc
+}
+
+// TargetOS returns OS to look for releases for ("linux-musl" if libc is "musl" or
+// if it's not specified and host is a musl-based Linux distribution).
+func TargetOS(goos string, libc string) (string, error) {
+	switch libc {
+	case "musl":
+		if goos == "linux" {
+			return "linux-musl", nil
+		}
+	case "glibc":
+	case "":
+		if goos == "linux" && runtime.GOOS == "linux" && hostIsMusl() {
+			return "linux-musl", nil
+		}
+	default:
+		return "", fmt.Errorf("Unsupported libc \"%s\" (expected either \"glibc\" or \"musl\")", libc)
+	}
+	return goos, nil
+}
+
+var hostMacOSVersion = func() (string, error) {
+	out, err := exec.Command("sw_vers", "-productVersion").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkRequirements verifies that host satisfies runtime constraints declared by the index entry
+// (e.g. {"glibc": "2.17"} is "glibc >= 2.17").
+func checkRequirements(requires map[string]string) error {
+	return checkRequirementsOn(runtime.GOOS, requires)
+}
+
+func checkRequirementsOn(goos string, requires map[string]string) error {
+	var keys []string
+	for key := range requires {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		required := requires[key]
+		var detect func() (string, error)
+		var name string
+		switch key {
+		case "glibc":
+			if goos != "linux" {
+				continue
+			}
+			detect, name = hostGlibcVersion, "glibc"
+		case "macos":
+			if goos != "darwin" {
+				continue
+			}
+			detect, name = hostMacOSVersion, "macOS"
+		default:
+			log.Debug("Ignoring unknown requirement ", key, "=", required)
+			continue
+		}
+		actual, err := detect()
+		if err == errNotGlibc {
+			return fmt.Errorf("%s >= %s is required (host is %s)", name, required, err)
+		}
+		if err != nil {
+			log.Warn("Skipping ", name, " >= ", required, " check (", err, ")")
+			continue
+		}
+		if compareDotted(actual, required) < 0 {
+			return fmt.Errorf("%s >= %s is required (found %s)", name, required, actual)
+		}
+	}
+	return nil
+}
+
+// compareDotted compares numeric dot-separated versions ("2.17" < "2.28", "10.9" < "10.12").
+func compareDotted(l, r string) int {
+	ls, rs := strings.Split(l, "."), strings.Split(r, ".")
+	for i := 0; i < len(ls) || i < len(rs); i++ {
+		var lv, rv int
+		if i < len(ls) {
+			lv, _ = strconv.Atoi(ls[i])
+		}
+		if i < len(rs) {
+			rv, _ = strconv.Atoi(rs[i])
+		}
+		if lv != rv {
+			if lv < rv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestCheckRequirements(t *testing.T) {
+	prevHostGlibcVersion := hostGlibcVersion
+	defer func() { hostGlibcVersion = prevHostGlibcVersion }()
+	prevHostMacOSVersion := hostMacOSVersion
+	defer func() { hostMacOSVersion = prevHostMacOSVersion }()
+	hostGlibcVersion = func() (string, error) { return "2.12", nil }
+	hostMacOSVersion = func() (string, error) { return "10.9.5", nil }
+	for _, scenario := range []struct {
+		os       string
+		requires map[string]string
+		err      string
+	}{
+		{"linux", nil, ""},
+		{"linux", map[string]string{"glibc": "2.12"}, ""},
+		{"linux", map[string]string{"glibc": "2.17"}, "glibc >= 2.17 is required (found 2.12)"},
+		{"linux", map[string]string{"macos": "10.12"}, ""},
+		{"darwin", map[string]string{"glibc": "2.17"}, ""},
+		{"darwin", map[string]string{"macos": "10.9"}, ""},
+		{"darwin", map[string]string{"macos": "10.12"}, "macOS >= 10.12 is required (found 10.9.5)"},
+		{"linux", map[string]string{"unknown": "1"}, ""},
+	} {
+		err := checkRequirementsOn(scenario.os, scenario.requires)
+		var actual string
+		if err != nil {
+			actual = err.Error()
+		}
+		if actual != scenario.err {
+			t.Fatalf("%v %v: actual: %q != expected: %q", scenario.os, scenario.requires, actual, scenario.err)
+		}
+	}
+	hostGlibcVersion = func() (string, error) { return "", errNotGlibc }
+	if err := checkRequirementsOn("linux", map[string]string{"glibc": "2.17"}); err == nil {
+		t.Fatal("expected musl-based host to fail glibc requirement")
+	}
+}
+
+func TestReleaseUnmarshalJSON(t *testing.T) {
+	var actual map[string]Release
+	err := json.Unmarshal([]byte(`{
+		"1.8.0": "tgz+https://example.com/1.8.0.tar.gz",
+		"1.8.1": {"url": "tgz+https://example.com/1.8.1.tar.gz", "requires": {"glibc": "2.17"}}
+	}`), &actual)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[string]Release{
+		"1.8.0": {URL: "tgz+https://example.com/1.8.0.tar.gz"},
+		"1.8.1": {URL: "tgz+https://example.com/1.8.1.tar.gz", Requires: map[string]string{"glibc": "2.17"}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestTargetOS(t *testing.T) {
+	prevHostIsMusl := hostIsMusl
+	defer func() { hostIsMusl = prevHostIsMusl }()
+	for _, musl := range []bool{true, false} {
+		hostIsMusl = func() bool { return musl }
+		autoDetected := runtime.GOOS
+		if musl && runtime.GOOS == "linux" {
+			autoDetected = "linux-musl"
+		}
+		for _, scenario := range []struct{ goos, libc, expected string }{
+			{runtime.GOOS, "", autoDetected},
+			{"linux", "musl", "linux-musl"},
+			{"linux", "glibc", "linux"},
+			{"darwin", "musl", "darwin"},
+		} {
+			actual, err := TargetOS(scenario.goos, scenario.libc)
+			if err != nil || actual != scenario.expected {
+				t.Fatalf("%v (musl: %v): actual: %v (%v) != expected: %v", scenario, musl, actual, err, scenario.expected)
+			}
+		}
+	}
+	if _, err := TargetOS("linux", "uclibc"); err == nil {
+		t.Fatal("expected uclibc to be rejected")
+	}
+}
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"strings"
+)
+
+// Resolution is what `jabba resolve` (and `jabba install --dry-run`) outputs.
+type Resolution struct {
+	Selector string `json:"selector"`
+	Version  string `json:"version"`
+	URL      string `json:"url"`
+	// archive type (e.g. "tgz")
+	Type string `json:"type"`
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// sha256 archive is going to be verified against ("" if neither index nor URL specify one)
+	SHA256 string `json:"sha256,omitempty"`
+	// true if exactly this version is installed already
+	Installed bool `json:"installed"`
+}
+
+// ResolveRemote resolves selector to the exact version & archive the same way Install does, without downloading
+// anything (index aside).
+func ResolveRemote(selector string, opts InstallOptions) (*Resolution, error) {
+	ver, release, err := resolveRelease(selector, opts)
+	if err != nil {
+		return nil, err
+	}
+	r := &Resolution{Selector: selector, Version: ver.String(), OS: release.os, Arch: release.arch}
+	url := release.URL
+	// <type>+<url>
+	if i := strings.Index(url, "+"); i != -1 && !strings.Contains(url[:i], "://") {
+		r.Type, url = url[:i], url[i+1:]
+	}
+	url, f, err := splitFragment(url)
+	if err != nil {
+		return nil, err
+	}
+	r.URL, r.SHA256 = url, strings.TrimPrefix(f.checksum, "sha256=")
+	if !opts.crossOS() {
+		if err := checkCompat(ver, release.arch, r.Type, opts.IgnoreCompat); err != nil {
+			return nil, err
+		}
+	}
+	if local, err := Ls(); err == nil {
+		for _, v := range local {
+			if ver.Equals(v) {
+				r.Installed = true
+			}
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,55 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestResolveRemote(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	sum := strings.Repeat("a", 64)
+	index := filepath.Join(home, "index.json")
+	if err := ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"`+runtime.GOARCH+`": {"jdk@zulu": {
+		"1.17.0": "tgz+https://example.com/1.17.0.tar.gz#sha256=`+sum+`",
+		"1.17.1": "zip+https://example.com/1.17.1.zip"
+	}}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	installFakeJDKs(t, home, "zulu@1.17.0")
+	for selector, expected := range map[string]Resolution{
+		"zulu@1.17.0": {Selector: "zulu@1.17.0", Version: "zulu@1.17.0", URL: "https://example.com/1.17.0.tar.gz",
+			Type: "tgz", OS: runtime.GOOS, Arch: HostArch(), SHA256: sum, Installed: true},
+		"zulu@1.17": {Selector: "zulu@1.17", Version: "zulu@1.17.1", URL: "https://example.com/1.17.1.zip",
+			Type: "zip", OS: runtime.GOOS, Arch: HostArch()},
+	} {
+		actual, err := ResolveRemote(selector, InstallOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(*actual, expected) {
+			t.Fatalf("actual: %+v != expected: %+v", *actual, expected)
+		}
+	}
+	// nothing is downloaded
+	if files, _ := ioutil.ReadDir(filepath.Join(home, "jdk")); len(files) != 1 {
+		t.Fatalf("unexpected %s content: %v", filepath.Join(home, "jdk"), files)
+	}
+	if _, err := ResolveRemote("zulu@1.21", InstallOptions{}); err == nil {
+		t.Fatal("expected ResolveRemote to fail (no such release)")
+	}
+}
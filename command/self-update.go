@@ -0,0 +1,145 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// GitHub repo jabba releases are published to (see `make publish`)
+const selfRepo = "shyiko/jabba"
+
+// SelfRelease is a release of jabba itself (binary for the platform jabba is running on).
+type SelfRelease struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+	SHA256  string `json:"sha256"`
+}
+
+var errSelfUpdateDisabled = errors.New("Self-update is disabled (\"self_update: false\" in config.yaml or " +
+	"JABBA_SELF_UPDATE=0)")
+
+// LatestSelfRelease looks up the latest release of jabba.
+// Releases without a checksum (either recorded by GitHub or published as <binary>.sha256) are rejected.
+func LatestSelfRelease() (*SelfRelease, error) {
+	if !cfg.SelfUpdate() {
+		return nil, errSelfUpdateDisabled
+	}
+	var release githubRelease
+	if err := fetchJSON(githubAPI+"/repos/"+selfRepo+"/releases/latest", &release); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("jabba-%s-%s-%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	r := &SelfRelease{Version: strings.TrimPrefix(release.TagName, "v")}
+	var checksumURL string
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case name:
+			r.URL = asset.BrowserDownloadURL
+			if strings.HasPrefix(asset.Digest, "sha256:") {
+				r.SHA256 = strings.TrimPrefix(asset.Digest, "sha256:")
+			}
+		case name + ".sha256":
+			checksumURL = asset.BrowserDownloadURL
+		}
+	}
+	if r.URL == "" {
+		return nil, fmt.Errorf("jabba %s has no binary for %s/%s", r.Version, runtime.GOOS, runtime.GOARCH)
+	}
+	if r.SHA256 == "" && checksumURL != "" {
+		content, err := fetch(checksumURL)
+		if err != nil {
+			return nil, err
+		}
+		// "<sha256>  <file>" (sha256sum output) or just "<sha256>"
+		if fields := strings.Fields(string(content)); len(fields) != 0 {
+			r.SHA256 = strings.ToLower(fields[0])
+		}
+	}
+	if r.SHA256 == "" {
+		return nil, fmt.Errorf("jabba %s has no checksum published for %s (refusing to install unverified binary)",
+			r.Version, name)
+	}
+	return r, nil
+}
+
+// CheckSelfUpdate returns the latest release of jabba & whether it's newer than current version
+// ("" (development build) is considered to be older than any release).
+func CheckSelfUpdate(current string) (release *SelfRelease, newer bool, err error) {
+	release, err = LatestSelfRelease()
+	if err != nil {
+		return nil, false, err
+	}
+	if current == "" {
+		return release, true, nil
+	}
+	l, lerr := semver.ParseVersion(release.Version)
+	c, cerr := semver.ParseVersion(strings.TrimPrefix(current, "v"))
+	if lerr != nil || cerr != nil {
+		return release, release.Version != strings.TrimPrefix(current, "v"), nil
+	}
+	return release, c.LessThan(l), nil
+}
+
+// SelfUpdate replaces running executable with the latest release of jabba (unless it's already the latest one
+// (force reinstalls it anyway)).
+func SelfUpdate(current string, force bool) (release *SelfRelease, updated bool, err error) {
+	release, newer, err := CheckSelfUpdate(current)
+	if err != nil || !newer && !force {
+		return release, false, err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, false, err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return nil, false, err
+	}
+	if err := replaceExecutable(exe, release); err != nil {
+		return nil, false, err
+	}
+	return release, true, nil
+}
+
+// replaceExecutable downloads release next to exe (so that it could be renamed into place atomically),
+// verifies its checksum & replaces exe with it.
+func replaceExecutable(exe string, release *SelfRelease) error {
+	tmp := exe + ".new"
+	os.Remove(tmp)
+	// left behind by the previous update on Windows
+	os.Remove(exe + ".old")
+	log.Info("Downloading jabba ", release.Version, " (", release.URL, ")")
+	sum, err := downloadWithRetry(release.URL, tmp, 0755, nil)
+	if err == nil {
+		if err = verifyChecksum(sum, "sha256="+release.SHA256); err != nil {
+			err = fmt.Errorf("%s (%s)", err, release.URL)
+		}
+	}
+	if err == nil {
+		// OpenFile is subject to umask
+		err = chmod(tmp, 0755)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		// running executable cannot be overwritten (but it can be renamed)
+		if err := os.Rename(exe, exe+".old"); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	log.Debug("Moving ", tmp, " to ", exe)
+	return rename(tmp, exe)
+}
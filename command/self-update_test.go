@@ -0,0 +1,75 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSelfUpdate(t *testing.T) {
+	binary := []byte("jabba 0.12.0")
+	checksum := sha256Hex(binary)
+	name := fmt.Sprintf("jabba-0.12.0-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/shyiko/jabba/releases/latest":
+			fmt.Fprintf(w, `{"tag_name": "0.12.0", "assets": [{"name": "%s", "browser_download_url": "%s/%s"},
+				{"name": "%s.sha256", "browser_download_url": "%s/%s.sha256"}]}`,
+				name, server.URL, name, name, server.URL, name)
+		case "/" + name:
+			w.Write(binary)
+		case "/" + name + ".sha256":
+			fmt.Fprintf(w, "%s  %s\n", checksum, name)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defer func(prev string) { githubAPI = prev }(githubAPI)
+	githubAPI = server.URL
+	release, newer, err := CheckSelfUpdate("0.11.2")
+	if err != nil || !newer || release.Version != "0.12.0" || release.SHA256 != checksum {
+		t.Fatalf("actual: %+v %v (%v)", release, newer, err)
+	}
+	if _, newer, _ := CheckSelfUpdate("0.12.0"); newer {
+		t.Fatal("0.12.0 is not supposed to be newer than itself")
+	}
+	dir, err := ioutil.TempDir("", "self-update_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := filepath.Join(dir, "jabba")
+	if err := ioutil.WriteFile(exe, []byte("jabba 0.11.2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tampered := *release
+	tampered.SHA256 = strings.Repeat("0", 64)
+	if err := replaceExecutable(exe, &tampered); err == nil || !strings.Contains(err.Error(), "Checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if b, _ := ioutil.ReadFile(exe); string(b) != "jabba 0.11.2" {
+		t.Fatalf("executable was not supposed to be replaced (actual: %q)", b)
+	}
+	if err := replaceExecutable(exe, release); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(exe); string(b) != string(binary) {
+		t.Fatalf("actual: %q != expected: %q", b, binary)
+	}
+	os.Setenv("JABBA_SELF_UPDATE", "0")
+	defer os.Unsetenv("JABBA_SELF_UPDATE")
+	if _, _, err := CheckSelfUpdate("0.11.2"); err != errSelfUpdateDisabled {
+		t.Fatalf("actual: %v != expected: %v", err, errSelfUpdateDisabled)
+	}
+}
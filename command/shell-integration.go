@@ -0,0 +1,122 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// jabba writes commands that modify environment of the current shell (see EnvChange.Script) to fd 3
+// (or --fd3 file), it's up to the shell function (`jabba`) to evaluate them.
+var shellIntegrations = map[string]string{
+	"bash": posixShellIntegration,
+	"zsh":  posixShellIntegration,
+	"fish": `function jabba
+    set -l fd3 (mktemp /tmp/jabba-fd3.XXXXXX)
+    env JABBA_SHELL_INTEGRATION=fish %[1]s $argv 3> $fd3
+    set -l exit_code $status
+    source $fd3
+    rm -f $fd3
+    return $exit_code
+end
+
+[ ! -z (echo (jabba alias default)) ]; and jabba use default
+`,
+	"pwsh": `function jabba
+{
+    $fd3 = [System.IO.Path]::GetTempFileName()
+    $env:JABBA_SHELL_INTEGRATION = "pwsh"
+    & %[1]s @args --fd3 "$fd3"
+    $exitCode = $LASTEXITCODE
+    Remove-Item env:JABBA_SHELL_INTEGRATION
+    $script = Get-Content -Raw $fd3
+    if ($script) { Invoke-Expression $script }
+    Remove-Item -Force $fd3
+    $global:LASTEXITCODE = $exitCode
+}
+
+if (jabba alias default) { jabba use default }
+`,
+	"nushell": `def --env --wrapped jabba [...args] {
+    let bin = %[1]s
+    let fd3 = (mktemp -t jabba-fd3.XXXXXX)
+    with-env {JABBA_SHELL_INTEGRATION: "nushell"} { ^$bin ...$args --fd3 $fd3 }
+    let change = (open --raw $fd3 | str trim)
+    rm -f $fd3
+    if ($change | is-not-empty) {
+        let change = ($change | from json)
+        load-env $change.set
+        if ($change.unset | is-not-empty) { hide-env -i ...$change.unset }
+    }
+}
+
+if ((^(%[1]s) alias default | str trim) | is-not-empty) { jabba use default }
+`,
+}
+
+const posixShellIntegration = `jabba() {
+    local fd3="$(mktemp /tmp/jabba-fd3.XXXXXX)"
+    (JABBA_SHELL_INTEGRATION=sh %[1]s "$@" 3>| "${fd3}")
+    local exit_code=$?
+    eval "$(cat "${fd3}")"
+    rm -f "${fd3}"
+    return ${exit_code}
+}
+
+if [ ! -z "$(jabba alias default)" ]; then
+    jabba use default
+fi
+`
+
+var shellAliases = map[string]string{"sh": "bash", "powershell": "pwsh", "nu": "nushell"}
+
+// ShellIntegration returns `jabba` shell function (+ activation of the "default" alias) for the specified shell
+// (bash, zsh, fish, pwsh or nushell). bin is the path to jabba executable (referenced through $JABBA_HOME if
+// it's located in $JABBA_HOME/bin).
+func ShellIntegration(shell string, bin string) (string, error) {
+	if alias, ok := shellAliases[shell]; ok {
+		shell = alias
+	}
+	script, ok := shellIntegrations[shell]
+	if !ok {
+		return "", fmt.Errorf("Unsupported shell \"%s\" (must be one of bash, zsh, fish, pwsh, nushell)", shell)
+	}
+	return fmt.Sprintf(script, binExpr(shell, bin)), nil
+}
+
+// binExpr returns shell expression evaluating to the path of jabba executable. $JABBA_BIN, if set (by
+// `jabba home use` switching to a home without jabba in it), takes precedence over $JABBA_HOME/bin.
+func binExpr(shell string, bin string) string {
+	home := os.Getenv("JABBA_HOME")
+	if home != "" && filepath.Dir(bin) == filepath.Join(home, "bin") {
+		name := filepath.Base(bin)
+		switch shell {
+		case "fish":
+			// (command substitution can't be a command name)
+			return `env (set -q JABBA_BIN; and echo $JABBA_BIN; or echo "$JABBA_HOME/bin/` + name + `")`
+		case "pwsh":
+			return `$(if ($env:JABBA_BIN) { $env:JABBA_BIN } else { "$env:JABBA_HOME/bin/` + name + `" })`
+		case "nushell":
+			return `($env.JABBA_BIN? | default ($env.JABBA_HOME | path join bin ` + quoteShell(shell, name) + `))`
+		default:
+			return `"${JABBA_BIN:-$JABBA_HOME/bin/` + name + `}"`
+		}
+	}
+	return quoteShell(shell, bin)
+}
+
+// quoteShell single-quotes value so that shell would take it literally.
+func quoteShell(shell string, value string) string {
+	switch shell {
+	case "fish":
+		return "'" + strings.Replace(strings.Replace(value, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+	case "pwsh":
+		return "'" + strings.Replace(value, "'", "''", -1) + "'"
+	case "nushell":
+		// raw string
+		return "r#'" + value + "'#"
+	default:
+		return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+	}
+}
@@ -0,0 +1,39 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellIntegration(t *testing.T) {
+	home := filepath.Join(os.TempDir(), "jabba-home")
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	for shell, expected := range map[string]string{
+		"sh":         `"${JABBA_BIN:-$JABBA_HOME/bin/jabba}" "$@"`,
+		"fish":       `env (set -q JABBA_BIN; and echo $JABBA_BIN; or echo "$JABBA_HOME/bin/jabba") $argv`,
+		"powershell": `& $(if ($env:JABBA_BIN) { $env:JABBA_BIN } else { "$env:JABBA_HOME/bin/jabba" }) @args`,
+		"nu":         `let bin = ($env.JABBA_BIN? | default ($env.JABBA_HOME | path join bin r#'jabba'#))`,
+	} {
+		script, err := ShellIntegration(shell, filepath.Join(home, "bin", "jabba"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(script, expected) {
+			t.Fatalf("%s integration does not contain %s:\n%s", shell, expected, script)
+		}
+	}
+	// binary outside of $JABBA_HOME is referenced by absolute path
+	script, err := ShellIntegration("zsh", "/usr/local/bin/jabba")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `'/usr/local/bin/jabba' "$@"`; !strings.Contains(script, expected) {
+		t.Fatalf("zsh integration does not contain %s:\n%s", expected, script)
+	}
+	if _, err := ShellIntegration("tcsh", "jabba"); err == nil {
+		t.Fatal("expected tcsh to be rejected")
+	}
+}
@@ -0,0 +1,79 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+	"golang.org/x/crypto/openpgp"
+)
+
+// verifySignature checks detached OpenPGP signature (either binary (.sig) or ASCII-armored (.asc)) of the file
+// downloaded from url, returning fingerprint of the key file was signed with.
+// sig is either a URL of the signature or a suffix to append to the url (e.g. ".asc").
+// key is either a name of the trusted key ($JABBA_HOME/keys/<key>.asc) or a path / URL of the public key(s).
+func verifySignature(file string, url string, sig string, key string) (string, error) {
+	keyring, err := loadKeyring(key)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(sig, ".") {
+		sig = url + sig
+	}
+	signature, err := fetch(sig)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	var signer *openpgp.Entity
+	if isArmored(signature) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keyring, f, bytes.NewReader(signature))
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keyring, f, bytes.NewReader(signature))
+	}
+	if err != nil {
+		return "", fmt.Errorf("Signature verification failed (%v)", err)
+	}
+	return fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint), nil
+}
+
+func loadKeyring(key string) (openpgp.EntityList, error) {
+	var b []byte
+	var err error
+	switch {
+	case strings.Contains(key, "://"):
+		b, err = fetch(key)
+	case strings.ContainsAny(key, "/\\"):
+		b, err = ioutil.ReadFile(key)
+	default:
+		file := filepath.Join(cfg.Dir(), "keys", key+".asc")
+		if b, err = ioutil.ReadFile(file); os.IsNotExist(err) {
+			return nil, fmt.Errorf("Key \"%s\" is not trusted (%s does not exist)", key, file)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keyring openpgp.EntityList
+	if isArmored(b) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(b))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid OpenPGP key (%v)", key, err)
+	}
+	return keyring, nil
+}
+
+func isArmored(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN "))
+}
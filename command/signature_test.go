@@ -0,0 +1,69 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func TestVerifySignature(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	vendor, err := openpgp.NewEntity("vendor", "", "vendor@example.com", nil)
+	ok(err)
+	stranger, err := openpgp.NewEntity("stranger", "", "stranger@example.com", nil)
+	ok(err)
+	// trusted key
+	ok(os.MkdirAll(filepath.Join(home, "keys"), 0755))
+	var key bytes.Buffer
+	w, err := armor.Encode(&key, openpgp.PublicKeyType, nil)
+	ok(err)
+	ok(vendor.Serialize(w))
+	ok(w.Close())
+	ok(ioutil.WriteFile(filepath.Join(home, "keys", "vendor.asc"), key.Bytes(), 0644))
+	// archive & signatures
+	archive := filepath.Join(home, "jdk.tar.gz")
+	ok(ioutil.WriteFile(archive, []byte("jdk"), 0644))
+	for name, signer := range map[string]*openpgp.Entity{"vendor": vendor, "stranger": stranger} {
+		var sig bytes.Buffer
+		ok(openpgp.ArmoredDetachSign(&sig, signer, bytes.NewReader([]byte("jdk")), nil))
+		ok(ioutil.WriteFile(archive+"."+name+".asc", sig.Bytes(), 0644))
+		sig.Reset()
+		ok(openpgp.DetachSign(&sig, signer, bytes.NewReader([]byte("jdk")), nil))
+		ok(ioutil.WriteFile(archive+"."+name+".sig", sig.Bytes(), 0644))
+	}
+	url := "file://" + filepath.ToSlash(archive)
+	for _, sig := range []string{".vendor.asc", ".vendor.sig", url + ".vendor.asc"} {
+		signer, err := verifySignature(archive, url, sig, "vendor")
+		if expected := fmt.Sprintf("%X", vendor.PrimaryKey.Fingerprint); err != nil || signer != expected {
+			t.Fatalf("%s: actual: %v (%v) != expected: %v", sig, signer, err, expected)
+		}
+	}
+	for _, scenario := range []struct{ sig, key string }{
+		{".stranger.asc", "vendor"},
+		{".stranger.sig", "vendor"},
+		{".vendor.asc", "unknown"},
+	} {
+		if _, err := verifySignature(archive, url, scenario.sig, scenario.key); err == nil {
+			t.Fatalf("%v: expected verification to fail", scenario)
+		}
+	}
+	ok(ioutil.WriteFile(archive, []byte("jdk (tampered)"), 0644))
+	if _, err := verifySignature(archive, url, ".vendor.asc", "vendor"); err == nil {
+		t.Fatal("expected verification of a tampered archive to fail")
+	}
+}
@@ -0,0 +1,72 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type SizeBudgetReport struct {
+	Budget int64 `json:"budget"`
+	Total  int64 `json:"total"`
+	// largest first
+	JDKs []JDK `json:"jdks"`
+}
+
+func (r *SizeBudgetReport) Exceeded() bool {
+	return r.Total > r.Budget
+}
+
+// SizeBudget measures disk usage of JDKs installed under $JABBA_HOME/jdk (links to system JDKs are not counted).
+func SizeBudget(budget int64) (*SizeBudgetReport, error) {
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	report := &SizeBudgetReport{Budget: budget, JDKs: []JDK{}}
+	for _, v := range vs {
+		if strings.HasPrefix(v.String(), "system@") {
+			continue
+		}
+		jdk := DescribeInstalled(v)
+		report.Total += jdk.Size
+		report.JDKs = append(report.JDKs, jdk)
+	}
+	sort.SliceStable(report.JDKs, func(i, j int) bool { return report.JDKs[i].Size > report.JDKs[j].Size })
+	return report, nil
+}
+
+var sizeUnits = []string{"B", "K", "M", "G", "T"}
+
+// ParseSize parses "1073741824", "1024M", "1G", "1.5GB", "2GiB", etc. (units are binary (1K = 1024)).
+func ParseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "IB"), "B")
+	multiplier := int64(1)
+	for i, unit := range sizeUnits[1:] {
+		if strings.HasSuffix(s, unit) {
+			s = strings.TrimSuffix(s, unit)
+			multiplier = int64(1) << (10 * uint(i+1))
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("\"%s\" is not a valid size (expected something like 500M or 2G)", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize formats size in bytes as "1.5G", "300M", etc.
+func FormatSize(size int64) string {
+	value, unit := float64(size), 0
+	for value >= 1024 && unit < len(sizeUnits)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return strconv.FormatInt(size, 10) + "B"
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + sizeUnits[unit]
+}
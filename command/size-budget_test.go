@@ -0,0 +1,64 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"512":   512,
+		"100B":  100,
+		"1K":    1024,
+		"500M":  500 << 20,
+		"1.5G":  3 << 29,
+		"2GB":   2 << 30,
+		"2gib":  2 << 30,
+		"1T":    1 << 40,
+		" 3 G ": 3 << 30,
+	} {
+		actual, err := ParseSize(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", value, actual, expected)
+		}
+	}
+	for _, value := range []string{"", "G", "-1G", "1X"} {
+		if _, err := ParseSize(value); err == nil {
+			t.Fatalf("expected \"%s\" to be rejected", value)
+		}
+	}
+	if actual, expected := FormatSize(3<<29), "1.5G"; actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestSizeBudget(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	for ver, size := range map[string]int{"1.8.0": 100, "zulu@1.17.0": 300} {
+		dir := filepath.Join(home, "jdk", ver, "bin")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "java"), make([]byte, size), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	report, err := SizeBudget(350)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 400 || !report.Exceeded() || report.JDKs[0].Version != "zulu@1.17.0" {
+		t.Fatalf("unexpected %+v", report)
+	}
+}
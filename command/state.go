@@ -0,0 +1,208 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// `jabba which` & `jabba current` are called from shell prompts & build scripts (many times per second at times,
+// with jabba home on NFS in some setups), so instead of loading config, listing jdk dir & reading aliases on every
+// call they read a single state file ($JABBA_HOME/state.json) holding all of the above. The file is rewritten (if
+// anything changed) at the end of every other command (see RefreshState) and removed by the ones that change the set
+// of JDKs / aliases (see invalidateState) (until then hot path commands take the slow path), which means that changes
+// made behind jabba's back (e.g. `rm -rf ~/.jabba/jdk/1.8.0`, config.yaml edits) are picked up by the next "regular"
+// command (e.g. `jabba ls`).
+
+// bumped whenever State changes in an incompatible way
+const stateFormat = 1
+
+// State is a snapshot of everything hot path commands need to know (see ReadState).
+type State struct {
+	Format int `json:"format"`
+	// $JABBA_CONFIG, $JABBA_OVERLAY & $JABBA_ALIAS_FILES snapshot was taken with (it's ignored if they are different
+	// now)
+	JabbaConfig     string `json:"jabbaConfig,omitempty"`
+	JabbaOverlay    string `json:"jabbaOverlay,omitempty"`
+	JabbaAliasFiles string `json:"jabbaAliasFiles,omitempty"`
+	// merged config (see cfg.Load) (without credentials ("auth", "proxy", user:password@ of URLs) (hot path commands
+	// don't make requests))
+	Config *cfg.Config `json:"config"`
+	// installed JDKs (latest first (see Ls))
+	Versions []string `json:"versions"`
+	// name -> value (see GetAlias)
+	Aliases map[string]string `json:"aliases"`
+}
+
+func stateFile() string {
+	// not cfg.StateDir() (which has to check whether jabba home is writable)
+	if overlay := os.Getenv("JABBA_OVERLAY"); overlay != "" {
+		return filepath.Join(overlay, "state.json")
+	}
+	return filepath.Join(cfg.Dir(), "state.json")
+}
+
+// ReadState returns snapshot taken by RefreshState (nil if there is none or it can't be used).
+func ReadState() *State {
+	b, err := ioutil.ReadFile(stateFile())
+	if err != nil {
+		return nil
+	}
+	var s State
+	if err := json.Unmarshal(b, &s); err != nil || s.Format != stateFormat || s.Config == nil ||
+		s.JabbaConfig != os.Getenv("JABBA_CONFIG") || s.JabbaOverlay != os.Getenv("JABBA_OVERLAY") ||
+		s.JabbaAliasFiles != os.Getenv("JABBA_ALIAS_FILES") {
+		return nil
+	}
+	return &s
+}
+
+// RefreshState snapshots config, installed JDKs & aliases (file is only written if anything changed).
+// Errors are ignored (state is an optimization).
+func RefreshState() {
+	s, err := takeState()
+	if err != nil {
+		log.Debug("Failed to take state snapshot (", err, ")")
+		return
+	}
+	if reflect.DeepEqual(ReadState(), s) {
+		return
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	file := stateFile()
+	// (.tmp left behind by older versions of jabba might be world-readable)
+	os.Remove(file + ".tmp")
+	if err := ioutil.WriteFile(file+".tmp", b, 0600); err == nil {
+		os.Rename(file+".tmp", file)
+	}
+}
+
+func takeState() (*State, error) {
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	names, err := Aliases()
+	if err != nil {
+		return nil, err
+	}
+	config := *cfg.Load()
+	config.Auth, config.Proxy = nil, ""
+	registry := make(cfg.StringList, len(config.Registry))
+	for i, u := range config.Registry {
+		registry[i] = withoutUserinfo(u)
+	}
+	config.Registry, config.EOLData = registry, withoutUserinfo(config.EOLData)
+	s := &State{Format: stateFormat, JabbaConfig: os.Getenv("JABBA_CONFIG"),
+		JabbaOverlay: os.Getenv("JABBA_OVERLAY"), JabbaAliasFiles: os.Getenv("JABBA_ALIAS_FILES"), Config: &config,
+		Versions: []string{}, Aliases: make(map[string]string)}
+	for _, v := range vs {
+		s.Versions = append(s.Versions, v.String())
+	}
+	for _, name := range names {
+		s.Aliases[name] = strings.TrimSpace(GetAlias(name))
+	}
+	return s, nil
+}
+
+// withoutUserinfo returns URL with "user:password@" removed (value as is if it's not a URL).
+func withoutUserinfo(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	u.User = nil
+	return u.String()
+}
+
+// invalidateState is called whenever set of installed JDKs or aliases changes.
+func invalidateState() {
+	if err := os.Remove(stateFile()); err != nil && !os.IsNotExist(err) {
+		log.Debug("Failed to remove ", stateFile(), " (", err, ")")
+	}
+}
+
+// Resolve is Resolve against the snapshot.
+func (s *State) Resolve(selector string) (string, error) {
+	var vs []*semver.Version
+	for _, ver := range s.Versions {
+		v, err := semver.ParseVersion(ver)
+		if err != nil {
+			return "", err
+		}
+		vs = append(vs, v)
+	}
+	return resolveIn(vs, selector, func(name string) string { return s.Aliases[name] })
+}
+
+// Which is Which against the snapshot.
+func (s *State) Which(selector string, home bool) (string, error) {
+	ver, err := s.Resolve(selector)
+	if err != nil {
+		return "", err
+	}
+	return jdkPath(ver, home), nil
+}
+
+// WhichBin is WhichBin against the snapshot.
+func (s *State) WhichBin(selector string, tool string) (string, error) {
+	if err := validateToolName(tool); err != nil {
+		return "", err
+	}
+	ver, err := s.Resolve(selector)
+	if err != nil {
+		return "", err
+	}
+	return binOf(ver, tool)
+}
+
+// Current is Current against the snapshot.
+func (s *State) Current() string {
+	return currentIn(cfg.JDKDir(), os.Getenv("PATH"), func(ver string) bool {
+		for _, v := range s.Versions {
+			if v == ver {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// currentIn returns JDK (installed, according to the installed func) the first java on the path comes from (same as
+// Current, except that only directories preceding the JDK are checked for java).
+func currentIn(jdkDir string, path string, installed func(ver string) bool) string {
+	java := "java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	prefix := jdkDir + string(os.PathSeparator)
+	for _, dir := range filepath.SplitList(path) {
+		if strings.HasPrefix(dir, prefix) {
+			rel := strings.Split(strings.TrimSuffix(dir[len(prefix):], string(os.PathSeparator)),
+				string(os.PathSeparator))
+			if rel[len(rel)-1] == "bin" && (len(rel) == 2 || len(rel) == 4 && rel[1] == "Contents" &&
+				rel[2] == "Home") && installed(rel[0]) {
+				return rel[0]
+			}
+		}
+		if dir == "" {
+			continue
+		}
+		if stat, err := os.Stat(filepath.Join(dir, java)); err == nil && !stat.IsDir() {
+			return ""
+		}
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/mitchellh/ioprogress"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/command/trace"
+)
+
+// canStream returns true if archive can be extracted as it's being downloaded, i.e. it's a tar archive
+// (zip has its central directory at the end of the file, installers need the whole file), there is nothing to
+// reuse (cached archive / partial download) and signature doesn't have to be verified before extraction.
+func canStream(plan *InstallPlan) bool {
+	if _, ok := tarDecompressors[plan.Type]; !ok || strings.HasPrefix(plan.URL, "file://") || plan.Cached ||
+		plan.Sig != "" {
+		return false
+	}
+	_, err := os.Stat(plan.Archive)
+	return os.IsNotExist(err)
+}
+
+// streamInstall pipes HTTP response straight into tar extractor (calculating sha256 along the way),
+// so that archive is never written to disk. JDK is moved into place only if checksum matches.
+func streamInstall(plan *InstallPlan, opts InstallOptions) (*InstallResult, error) {
+	if opts.Dst == "" {
+		if err := ensureWritableDir(cfg.JDKDir()); err != nil {
+			return nil, err
+		}
+	}
+	url := plan.URL
+	result := &InstallResult{Version: plan.Version, Path: plan.Target, URL: url, Type: plan.Type, OS: plan.OS,
+		Arch: plan.Arch, Notice: plan.Notice, startedAt: time.Now().UTC()}
+	log.Info("Downloading ", plan.Version, " (", url, ")")
+	err := stage(plan, opts, func(target string) (err error) {
+		defer func() {
+			if err != nil {
+				os.RemoveAll(target)
+			}
+		}()
+		body, err := openResumableBody(url)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		log.Info("Extracting ", url, " to ", target, " (as it's being downloaded)")
+		h := sha256.New()
+		r := io.TeeReader(&ioprogress.Reader{
+			Reader:   body,
+			Size:     body.size,
+			DrawFunc: progressDrawFunc(url, 0, opts.Progress),
+		}, h)
+		cr, err := tarDecompressors[plan.Type](r)
+		if err != nil {
+			return &CorruptArchiveError{File: url, Err: err}
+		}
+		err = extractTar(url, cr, target, "")
+		// (before the rest of r is consumed below)
+		cr.Close()
+		if err != nil {
+			return err
+		}
+		// whatever follows tar's end-of-archive marker (padding) counts towards sha256 too
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			return err
+		}
+		result.SHA256 = hex.EncodeToString(h.Sum(nil))
+		if plan.SHA256 != "" {
+			validateSpan := trace.Start("validate", "checksum", "sha256="+plan.SHA256)
+			err := verifyChecksum(result.SHA256, "sha256="+plan.SHA256)
+			validateSpan.End(err)
+			if err != nil {
+				return fmt.Errorf("%s (%s)", err, url)
+			}
+			result.ChecksumVerified = true
+		}
+		if err := hoistSingleDir(target); err != nil {
+			return err
+		}
+		return normalizePathToBinJava(target, opts.targetOS())
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordManagedInstall(result, opts)
+	return result, nil
+}
+
+// hoistSingleDir replaces dir with its only subdirectory (for as long as there is one), which is what stripping
+// of the common directory prefix (see untar) amounts to when archive cannot be read twice.
+func hoistSingleDir(dir string) error {
+	for {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+			return err
+		}
+		tmp := dir + "~"
+		if err := rename(filepath.Join(dir, entries[0].Name()), tmp); err != nil {
+			return err
+		}
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+		if err := rename(tmp, dir); err != nil {
+			return err
+		}
+	}
+}
+
+// resumableBody is a body of GET response that re-requests the rest of the file (using HTTP Range) when
+// connection drops (up to downloadAttempts times).
+type resumableBody struct {
+	url string
+	res *http.Response
+	// -1 if unknown
+	size     int64
+	offset   int64
+	attempts int
+	backoff  time.Duration
+}
+
+func openResumableBody(url string) (*resumableBody, error) {
+	b := &resumableBody{url: url, backoff: downloadBackoff}
+	for {
+		b.attempts++
+		retry, err := b.open()
+		if err == nil {
+			b.size = b.res.ContentLength
+			return b, nil
+		}
+		if !retry || b.attempts >= downloadAttempts {
+			return nil, err
+		}
+		b.wait(err)
+	}
+}
+
+// open sends GET request for the part of the file starting at b.offset.
+func (b *resumableBody) open() (retry bool, err error) {
+	req, err := newDownloadRequest(b.url, b.offset, "")
+	if err != nil {
+		return false, err
+	}
+	res, err := newDownloadClient().Do(req)
+	if err != nil {
+		return true, err
+	}
+	switch {
+	case b.offset != 0 && res.StatusCode == http.StatusPartialContent:
+		if start, _ := parseContentRange(res.Header.Get("Content-Range")); start != b.offset {
+			res.Body.Close()
+			return false, fmt.Errorf("unable to resume download of %s (unexpected Content-Range %s)",
+				b.url, res.Header.Get("Content-Range"))
+		}
+	case b.offset != 0 && res.StatusCode < 300:
+		// bytes that were already extracted cannot be "un-read"
+		res.Body.Close()
+		return false, fmt.Errorf("unable to resume download of %s (range requests are not supported)", b.url)
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		res.Body.Close()
+		return true, fmt.Errorf("GET %s returned %d", b.url, res.StatusCode)
+	case res.StatusCode >= 400:
+		res.Body.Close()
+		return false, fmt.Errorf("GET %s returned %d", b.url, res.StatusCode)
+	}
+	b.res = res
+	return false, nil
+}
+
+func (b *resumableBody) wait(err error) {
+	log.Warn("Download of ", b.url, " failed (", err, "). Retrying in ", b.backoff)
+	time.Sleep(b.backoff)
+	b.backoff *= 2
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	n, err := b.res.Body.Read(p)
+	b.offset += int64(n)
+	if err == io.EOF && b.size >= 0 && b.offset < b.size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil || err == io.EOF || b.attempts >= downloadAttempts {
+		return n, err
+	}
+	b.res.Body.Close()
+	for {
+		b.attempts++
+		b.wait(err)
+		retry, rerr := b.open()
+		if rerr == nil {
+			return n, nil
+		}
+		if !retry || b.attempts >= downloadAttempts {
+			return n, rerr
+		}
+		err = rerr
+	}
+}
+
+func (b *resumableBody) Close() error {
+	return b.res.Body.Close()
+}
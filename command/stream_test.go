@@ -0,0 +1,107 @@
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamInstall(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	prevBackoff := downloadBackoff
+	defer func() { downloadBackoff = prevBackoff }()
+	downloadBackoff = time.Millisecond
+	java := "jdk-17/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	// incompressible, so that the connection could be dropped mid-way
+	lib := make([]byte, 256*1024)
+	rand.New(rand.NewSource(1)).Read(lib)
+	var archive bytes.Buffer
+	gw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gw)
+	for _, file := range []string{java, "jdk-17/release"} {
+		ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755}))
+	}
+	ok(tw.WriteHeader(&tar.Header{Name: "jdk-17/lib/modules", Typeflag: tar.TypeReg, Mode: 0644,
+		Size: int64(len(lib))}))
+	_, err = tw.Write(lib)
+	ok(err)
+	ok(tw.Close())
+	ok(gw.Close())
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			// drop the connection half way through
+			w.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+			w.Write(archive.Bytes()[:archive.Len()/2])
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "jdk.tar.gz", time.Time{}, bytes.NewReader(archive.Bytes()))
+	}))
+	defer server.Close()
+	prevProgressMode := progressMode
+	defer func() { progressMode = prevProgressMode }()
+	progressMode = "none"
+	url := server.URL + "/jdk.tar.gz"
+	sum := sha256Hex(archive.Bytes())
+	selector := "1.17.0-custom=tgz+" + url + "#sha256=" + sum
+	plan, err := planInstall(selector, InstallOptions{})
+	ok(err)
+	if !plan.Streamed || plan.Archive != "" {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	result, err := Install(selector, InstallOptions{})
+	ok(err)
+	if !result.ChecksumVerified || result.SHA256 != sum || requests != 2 {
+		t.Fatalf("unexpected result: %+v (%d requests)", result, requests)
+	}
+	jdk := filepath.Join(home, "jdk", "1.17.0-custom")
+	for _, name := range []string{strings.TrimPrefix(java, "jdk-17/"), "release", "lib/modules"} {
+		ok(file(jdk, name))
+	}
+	if stat, err := os.Stat(filepath.Join(jdk, "lib", "modules")); err != nil || stat.Size() != int64(len(lib)) {
+		t.Fatalf("lib/modules wasn't extracted in full (%v)", err)
+	}
+	if file, _ := downloadPath(url, "tgz", "sha256="+sum); fileExists(file) {
+		t.Fatalf("%s was not expected to exist", file)
+	}
+	// checksum mismatch
+	_, err = Install("1.17.0-other=tgz+"+url+"#sha256="+strings.Repeat("0", 64), InstallOptions{})
+	if err == nil || !strings.Contains(err.Error(), "Checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	for _, dir := range []string{filepath.Join(home, "jdk", "1.17.0-other"),
+		filepath.Join(home, "jdk", ".staging", "1.17.0-other")} {
+		if fileExists(dir) {
+			t.Fatalf("%s was not expected to exist", dir)
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// runCmd runs command (arguments are passed as is (no shell is involved)), logging its output if it fails.
+func runCmd(cmd *exec.Cmd) error {
+	out, err := outputOf(cmd)
+	if err != nil {
+		log.Error(strings.TrimSpace(out))
+		return fmt.Errorf("'%s' failed: %v", strings.Join(cmd.Args, " "), err)
+	}
+	return nil
+}
+
+// outputOf runs command and returns its combined output, which is also logged at debug level (line by line, as it's
+// produced (so that `jabba -v install ...` shows what installer is doing while it's doing it)).
+func outputOf(cmd *exec.Cmd) (string, error) {
+	log.Debug("Running ", strings.Join(cmd.Args, " "))
+	var b bytes.Buffer
+	lw := &lineLogger{prefix: filepath.Base(cmd.Path) + ": "}
+	// same writer for both, so that exec doesn't write to it concurrently
+	w := io.MultiWriter(&b, lw)
+	cmd.Stdout, cmd.Stderr = w, w
+	err := cmd.Run()
+	lw.Flush()
+	return b.String(), err
+}
+
+// lineLogger logs whatever is written to it at debug level (one entry per line).
+type lineLogger struct {
+	mu     sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i == -1 {
+			break
+		}
+		log.Debug(l.prefix, strings.TrimRight(string(l.buf[:i]), "\r"))
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush logs the last line (if it wasn't terminated by \n).
+func (l *lineLogger) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) != 0 {
+		log.Debug(l.prefix, strings.TrimRight(string(l.buf), "\r"))
+		l.buf = nil
+	}
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"os/exec"
+	"reflect"
+	"runtime"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+type recordingHook struct {
+	messages []string
+}
+
+func (h *recordingHook) Levels() []log.Level {
+	return []log.Level{log.DebugLevel}
+}
+
+func (h *recordingHook) Fire(entry *log.Entry) error {
+	h.messages = append(h.messages, entry.Message)
+	return nil
+}
+
+func TestOutputOf(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh is required")
+	}
+	hook := &recordingHook{}
+	logger := log.StandardLogger()
+	hooks, level := logger.Hooks, logger.Level
+	defer func() { logger.Hooks, logger.Level = hooks, level }()
+	logger.Hooks = make(log.LevelHooks)
+	logger.Hooks.Add(hook)
+	logger.Level = log.DebugLevel
+	out, err := outputOf(exec.Command("sh", "-c", "echo a; echo b >&2; printf c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "a\nb\nc" {
+		t.Fatalf("actual: %q != expected: %q", out, "a\nb\nc")
+	}
+	expected := []string{"Running sh -c echo a; echo b >&2; printf c", "sh: a", "sh: b", "sh: c"}
+	if !reflect.DeepEqual(hook.messages, expected) {
+		t.Fatalf("actual: %q != expected: %q", hook.messages, expected)
+	}
+	if err := runCmd(exec.Command("sh", "-c", "echo failed; exit 3")); err == nil {
+		t.Fatal("expected runCmd to fail")
+	}
+}
@@ -0,0 +1,212 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/command/flock"
+)
+
+// Temporary files (downloads that are not cached, installers' scratch space, JDKs of `jabba try`) are kept in
+// $JABBA_HOME/tmp (see cfg.TempDir), every entry accompanied by <entry>.lock, which is held for as long as entry is
+// in use. Leftovers (of the processes that got killed) are removed by the next jabba invocation once they are old
+// enough (see CleanTemp), entries that are locked are left alone whatever their age.
+
+// StaleTempAge is how old (unused) entries of cfg.TempDir() have to be to be removed on startup.
+const StaleTempAge = 24 * time.Hour
+
+// where older versions of jabba kept temporary files (in os.TempDir())
+var legacyTempPatterns = []string{"jabba-d-*", "jabba-i-*", "jabba-try-*"}
+
+// tempDir returns (existing) cfg.TempDir() (os.TempDir() if it can't be created (e.g. jabba home is read-only)).
+func tempDir() string {
+	dir := cfg.TempDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Debug("Failed to create ", dir, " (", err, "). Using ", os.TempDir(), " instead")
+		return os.TempDir()
+	}
+	return dir
+}
+
+// mkTempDir creates (locked) directory in tempDir(). release removes it.
+func mkTempDir(prefix string) (dir string, release func(), err error) {
+	if dir, err = ioutil.TempDir(tempDir(), prefix); err != nil {
+		return "", nil, err
+	}
+	lock := flock.New(dir + ".lock")
+	if err := lock.Lock(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	return dir, func() {
+		os.RemoveAll(dir)
+		lock.UnlockAndRemove()
+	}, nil
+}
+
+// CleanReport lists what CleanTemp / CleanCache / GC / Uninstall / Prune removed (or would remove if DryRun is true
+// (see PlanCleanCache, PlanGC, PlanUninstall & PlanPrune)).
+type CleanReport struct {
+	DryRun  bool     `json:"dryRun,omitempty"`
+	Removed []string `json:"removed"`
+	// bytes freed
+	Freed int64 `json:"freed"`
+}
+
+// remove removes path (unless r.DryRun), adding it to the report (if it exists).
+func (r *CleanReport) remove(path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	size := diskUsage(path)
+	if !r.DryRun {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		log.Debug("Removed ", path)
+	}
+	r.Removed = append(r.Removed, path)
+	r.Freed += size
+	return nil
+}
+
+// CleanTemp removes entries of cfg.TempDir() (and leftovers of older versions of jabba in os.TempDir()) that are
+// not in use and haven't been modified for maxAge.
+func CleanTemp(maxAge time.Duration) (*CleanReport, error) {
+	r := &CleanReport{}
+	if err := cleanTemp(maxAge, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func cleanTemp(maxAge time.Duration, r *CleanReport) error {
+	dir := cfg.TempDir()
+	files, err := ioutil.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, filepath.Join(dir, f.Name()))
+	}
+	for _, pattern := range legacyTempPatterns {
+		matches, _ := filepath.Glob(filepath.Join(os.TempDir(), pattern))
+		paths = append(paths, matches...)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".lock") {
+			// removed along with the entry (unless entry is gone already)
+			if _, err := os.Lstat(strings.TrimSuffix(path, ".lock")); err == nil {
+				continue
+			}
+		}
+		if err := removeUnlocked(path, maxAge, r); err != nil {
+			log.Warn("Failed to remove ", path, " (", err, ")")
+		}
+	}
+	return nil
+}
+
+// CleanCache removes archives from the download cache (see cfg.CacheDir) that haven't been used for maxAge (every
+// archive that is not being downloaded / verified right now if maxAge is 0), along with everything CleanTemp would
+// remove if maxAge was 0.
+func CleanCache(maxAge time.Duration) (*CleanReport, error) {
+	r := &CleanReport{}
+	if err := cleanCache(maxAge, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// PlanCleanCache returns what CleanCache would remove (without removing anything).
+func PlanCleanCache(maxAge time.Duration) (*CleanReport, error) {
+	r := &CleanReport{DryRun: true}
+	if err := cleanCache(maxAge, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func cleanCache(maxAge time.Duration, r *CleanReport) error {
+	if err := cleanTemp(0, r); err != nil {
+		return err
+	}
+	dir := cfg.CacheDir()
+	if dir == "" {
+		return nil
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "jabba-d-*"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+	for _, path := range matches {
+		// .sha256, .part & .validator go with the archive
+		if strings.HasSuffix(path, ".lock") || strings.HasSuffix(path, ".sha256") || strings.HasSuffix(path, ".part") ||
+			strings.HasSuffix(path, ".validator") {
+			if _, err := os.Stat(trimDownloadSuffix(path)); err == nil {
+				continue
+			}
+		}
+		if err := removeUnlocked(path, maxAge, r); err != nil {
+			log.Warn("Failed to remove ", path, " (", err, ")")
+		}
+	}
+	return nil
+}
+
+func trimDownloadSuffix(path string) string {
+	// (".part.validator" is trimmed too)
+	for _, suffix := range []string{".lock", ".sha256", ".validator", ".part"} {
+		path = strings.TrimSuffix(path, suffix)
+	}
+	return path
+}
+
+// removeUnlocked removes path (along with <path>.lock, <path>.sha256, <path>.part & .validator of the partial download
+// (see resumeDownload)) unless it's locked or has been modified within maxAge.
+func removeUnlocked(path string, maxAge time.Duration, r *CleanReport) error {
+	stat, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if maxAge != 0 && time.Since(stat.ModTime()) < maxAge {
+		return nil
+	}
+	base := trimDownloadSuffix(path)
+	lock := flock.New(base + ".lock")
+	// entry without a lock file is not in use (and dry run is not supposed to leave one behind)
+	if _, err := os.Lstat(lock.Path()); err == nil || !r.DryRun {
+		locked, err := lock.TryLock()
+		if err != nil {
+			return err
+		}
+		if !locked {
+			log.Debug("Keeping ", path, " (in use)")
+			return nil
+		}
+		defer lock.Unlock()
+	}
+	for _, p := range []string{base, base + ".sha256", base + ".part", base + ".validator", base + ".part.validator"} {
+		if err := r.remove(p); err != nil {
+			return err
+		}
+	}
+	if !r.DryRun {
+		lock.UnlockAndRemove()
+	}
+	return nil
+}
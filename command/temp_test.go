@@ -0,0 +1,123 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestCleanTemp(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	// legacy leftovers
+	prevTmpDir := os.Getenv("TMPDIR")
+	defer os.Setenv("TMPDIR", prevTmpDir)
+	os.Setenv("TMPDIR", filepath.Join(home, "system-tmp"))
+	ok(os.MkdirAll(os.TempDir(), 0755))
+	old := time.Now().Add(-2 * StaleTempAge)
+	legacy := filepath.Join(os.TempDir(), "jabba-d-legacy")
+	ok(ioutil.WriteFile(legacy, []byte("archive"), 0600))
+	ok(os.Chtimes(legacy, old, old))
+	// left behind by the process that got killed
+	abandoned, err := ioutil.TempDir(tempDir(), "jabba-i-")
+	ok(err)
+	ok(ioutil.WriteFile(abandoned+".lock", nil, 0644))
+	ok(ioutil.WriteFile(filepath.Join(abandoned, "file"), []byte("content"), 0644))
+	ok(os.Chtimes(abandoned, old, old))
+	inUse, release, err := mkTempDir("jabba-try-")
+	ok(err)
+	defer release()
+	ok(os.Chtimes(inUse, old, old))
+	if filepath.Dir(inUse) != cfg.TempDir() {
+		t.Fatalf("actual: %v != expected: %v", filepath.Dir(inUse), cfg.TempDir())
+	}
+	recent, err := ioutil.TempDir(tempDir(), "jabba-i-")
+	ok(err)
+	r, err := CleanTemp(StaleTempAge)
+	ok(err)
+	actual := r.Removed
+	sort.Strings(actual)
+	expected := []string{legacy, abandoned}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if r.Freed == 0 {
+		t.Fatal("freed space wasn't accounted for")
+	}
+	for _, path := range []string{legacy, abandoned, abandoned + ".lock"} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s was expected to be removed", path)
+		}
+	}
+	for _, path := range []string{inUse, recent} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("%s was expected to be kept (%v)", path, err)
+		}
+	}
+	// entries in use are kept whatever the age
+	r, err = CleanTemp(0)
+	ok(err)
+	if !reflect.DeepEqual(r.Removed, []string{recent}) {
+		t.Fatalf("actual: %v != expected: %v", r.Removed, []string{recent})
+	}
+}
+
+func TestCleanCache(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	os.Setenv("JABBA_KEEP_DOWNLOADS", "true")
+	defer os.Unsetenv("JABBA_KEEP_DOWNLOADS")
+	file, cached := downloadPath("https://example.com/jdk.tar.gz", "tgz", "")
+	if !cached || filepath.Dir(file) != filepath.Join(home, "cache") {
+		t.Fatalf("%s is expected to be cached in %s", file, filepath.Join(home, "cache"))
+	}
+	ok(os.MkdirAll(filepath.Dir(file), 0755))
+	unused := file
+	ok(ioutil.WriteFile(unused, []byte("archive"), 0644))
+	ok(ioutil.WriteFile(unused+".sha256", []byte("sum\n"), 0644))
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	ok(os.Chtimes(unused, old, old))
+	used, _ := downloadPath("https://example.com/jdk.tar.gz", "tgz", "sha256=0")
+	ok(ioutil.WriteFile(used, []byte("archive"), 0644))
+	plan, err := PlanCleanCache(30 * 24 * time.Hour)
+	ok(err)
+	if !reflect.DeepEqual(plan.Removed, []string{unused, unused + ".sha256"}) || plan.Freed != 11 {
+		t.Fatalf("actual: %v != expected: %v", plan, []string{unused, unused + ".sha256"})
+	}
+	if _, err := os.Stat(unused + ".lock"); !os.IsNotExist(err) {
+		t.Fatal("dry run should have left no lock file behind")
+	}
+	r, err := CleanCache(30 * 24 * time.Hour)
+	ok(err)
+	if !reflect.DeepEqual(r.Removed, []string{unused, unused + ".sha256"}) {
+		t.Fatalf("actual: %v != expected: %v", r.Removed, []string{unused, unused + ".sha256"})
+	}
+	r, err = CleanCache(0)
+	ok(err)
+	if !reflect.DeepEqual(r.Removed, []string{used}) {
+		t.Fatalf("actual: %v != expected: %v", r.Removed, []string{used})
+	}
+}
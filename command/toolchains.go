@@ -0,0 +1,209 @@
+package command
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// Toolchain is an installed JDK as seen by build tools (see MavenToolchains & GradleProperties).
+type Toolchain struct {
+	// jabba version (e.g. "zulu@1.17.0")
+	Version string
+	// Java version in the form build tools expect (e.g. "1.8", "17")
+	JavaVersion string
+	// JAVA_VERSION from the "release" file of the JDK (e.g. "17.0.9") ("" if there is none)
+	FullVersion string
+	// IMPLEMENTOR from the "release" file of the JDK (vendor jabba version is qualified with if there is none)
+	Vendor string
+	// JAVA_HOME
+	Home string
+}
+
+// Toolchains returns installed JDKs (aliases excluded, system@... ones included).
+func Toolchains() ([]Toolchain, error) {
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	r := make([]Toolchain, 0, len(vs))
+	for _, v := range vs {
+		dir := filepath.Join(cfg.JDKDir(), v.String())
+		release := readReleaseFile(dir)
+		tc := Toolchain{
+			Version:     v.String(),
+			JavaVersion: javaFeatureVersion(release["JAVA_VERSION"], v),
+			FullVersion: release["JAVA_VERSION"],
+			Vendor:      release["IMPLEMENTOR"],
+			Home:        filepath.Dir(filepath.Dir(expectedJavaPath(dir, runtime.GOOS))),
+		}
+		if tc.Vendor == "" {
+			tc.Vendor = v.Qualifier()
+		}
+		r = append(r, tc)
+	}
+	return r, nil
+}
+
+// javaFeatureVersion turns JAVA_VERSION (e.g. "1.8.0_392", "17.0.9") into "1.8" / "17"
+// (falling back to jabba version (1.8.x -> "1.8", 1.17.x -> "17") if JDK has no "release" file).
+func javaFeatureVersion(javaVersion string, v *semver.Version) string {
+	if javaVersion != "" {
+		split := strings.SplitN(javaVersion, ".", 3)
+		if split[0] == "1" && len(split) > 1 {
+			return "1." + split[1]
+		}
+		return split[0]
+	}
+	if v.Major() == 1 {
+		if v.Minor() <= 8 {
+			return fmt.Sprintf("1.%d", v.Minor())
+		}
+		return fmt.Sprint(v.Minor())
+	}
+	return fmt.Sprint(v.Major())
+}
+
+// MavenToolchainsFile returns ~/.m2/toolchains.xml.
+func MavenToolchainsFile() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".m2", "toolchains.xml")
+}
+
+// GradlePropertiesFile returns $GRADLE_USER_HOME/gradle.properties (~/.gradle/gradle.properties if not set).
+func GradlePropertiesFile() string {
+	if dir := os.Getenv("GRADLE_USER_HOME"); dir != "" {
+		return filepath.Join(dir, "gradle.properties")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".gradle", "gradle.properties")
+}
+
+// entries jabba manages are marked with <id>jabba:<version></id> (so that the rest of toolchains.xml is left intact)
+const mavenToolchainIDPrefix = "jabba:"
+
+var mavenToolchainRegexp = regexp.MustCompile(`(?s)[ \t]*<toolchain>.*?</toolchain>[ \t]*\r?\n?`)
+
+// MavenToolchains returns content of Maven's toolchains.xml (file) with entries previously generated by jabba
+// replaced by toolchains (entries added by other means are kept as is). File doesn't have to exist.
+func MavenToolchains(file string, toolchains []Toolchain) ([]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		b = []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<toolchains>\n</toolchains>\n")
+	}
+	b = mavenToolchainRegexp.ReplaceAllFunc(b, func(entry []byte) []byte {
+		if bytes.Contains(entry, []byte("<id>"+mavenToolchainIDPrefix)) {
+			return nil
+		}
+		return entry
+	})
+	end := bytes.LastIndex(b, []byte("</toolchains>"))
+	if end == -1 {
+		return nil, fmt.Errorf("%s is not valid (</toolchains> not found)", file)
+	}
+	var buf bytes.Buffer
+	buf.Write(b[:end])
+	for _, tc := range toolchains {
+		buf.WriteString("  <toolchain>\n    <type>jdk</type>\n    <provides>\n")
+		writeXMLElement(&buf, "      ", "id", mavenToolchainIDPrefix+tc.Version)
+		writeXMLElement(&buf, "      ", "version", tc.JavaVersion)
+		if tc.Vendor != "" {
+			writeXMLElement(&buf, "      ", "vendor", tc.Vendor)
+		}
+		buf.WriteString("    </provides>\n    <configuration>\n")
+		writeXMLElement(&buf, "      ", "jdkHome", tc.Home)
+		buf.WriteString("    </configuration>\n  </toolchain>\n")
+	}
+	buf.Write(b[end:])
+	return buf.Bytes(), nil
+}
+
+func writeXMLElement(buf *bytes.Buffer, indent string, name string, value string) {
+	buf.WriteString(indent + "<" + name + ">")
+	xml.EscapeText(buf, []byte(value))
+	buf.WriteString("</" + name + ">\n")
+}
+
+const gradleInstallationsPathsKey = "org.gradle.java.installations.paths"
+
+// GradleProperties returns content of gradle.properties (file) with org.gradle.java.installations.paths listing
+// toolchains (paths outside of jabba's JDK directory (cfg.JDKDir()) are kept). File doesn't have to exist.
+func GradleProperties(file string, toolchains []Toolchain) ([]byte, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var paths []string
+	for _, tc := range toolchains {
+		paths = append(paths, tc.Home)
+	}
+	prefix := cfg.JDKDir() + string(os.PathSeparator)
+	var lines []string
+	found := false
+	if len(b) != 0 {
+		lines = strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	}
+	for i, line := range lines {
+		key, value := splitProperty(line)
+		if key != gradleInstallationsPathsKey {
+			continue
+		}
+		var kept []string
+		for _, path := range strings.Split(value, ",") {
+			path = strings.TrimSpace(path)
+			if path != "" && !strings.HasPrefix(path, prefix) {
+				kept = append(kept, path)
+			}
+		}
+		lines[i] = gradleInstallationsPathsKey + "=" + escapeProperty(strings.Join(append(kept, paths...), ","))
+		found = true
+	}
+	if !found {
+		lines = append(lines, gradleInstallationsPathsKey+"="+escapeProperty(strings.Join(paths, ",")))
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// splitProperty splits "key=value" / "key: value" line of a .properties file (continuation lines are not supported).
+func splitProperty(line string) (key string, value string) {
+	line = strings.TrimLeft(line, " \t")
+	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+		return "", ""
+	}
+	i := strings.IndexAny(line, "=:")
+	if i == -1 {
+		return strings.TrimSpace(line), ""
+	}
+	value = strings.TrimLeft(line[i+1:], " \t")
+	// C:\\Program Files\\... -> C:\Program Files\...
+	value = strings.Replace(value, `\\`, `\`, -1)
+	return strings.TrimSpace(line[:i]), value
+}
+
+func escapeProperty(value string) string {
+	return strings.Replace(value, `\`, `\\`, -1)
+}
+
+// WriteToolchainsFile atomically replaces file with content (creating parent directories if necessary).
+func WriteToolchainsFile(file string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp := file + ".jabba-tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,79 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestToolchains(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("JAVA_HOME is <jdk>/Contents/Home")
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	jdk := filepath.Join(home, "jdk")
+	for _, dir := range []string{"1.8.0", "zulu@1.17.0"} {
+		if err := os.MkdirAll(filepath.Join(jdk, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ioutil.WriteFile(filepath.Join(jdk, "zulu@1.17.0", "release"),
+		[]byte("IMPLEMENTOR=\"Azul Systems, Inc.\"\nJAVA_VERSION=\"17.0.9\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolchains, err := Toolchains()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toolchains) != 2 || toolchains[0].JavaVersion != "1.8" || toolchains[0].Home != filepath.Join(jdk, "1.8.0") ||
+		toolchains[1].JavaVersion != "17" || toolchains[1].Vendor != "Azul Systems, Inc." {
+		t.Fatalf("actual: %+v", toolchains)
+	}
+
+	m2 := filepath.Join(home, "toolchains.xml")
+	custom := "  <toolchain>\n    <type>jdk</type>\n    <provides><version>11</version></provides>\n" +
+		"    <configuration><jdkHome>/opt/jdk11</jdkHome></configuration>\n  </toolchain>\n"
+	ioutil.WriteFile(m2, []byte("<toolchains>\n"+custom+"</toolchains>\n"), 0644)
+	content, err := MavenToolchains(m2, toolchains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteToolchainsFile(m2, content); err != nil {
+		t.Fatal(err)
+	}
+	// re-running replaces jabba entries (instead of adding them again)
+	content, err = MavenToolchains(m2, toolchains[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "<toolchains>\n" + custom +
+		"  <toolchain>\n    <type>jdk</type>\n    <provides>\n      <id>jabba:1.8.0</id>\n" +
+		"      <version>1.8</version>\n    </provides>\n    <configuration>\n" +
+		"      <jdkHome>" + filepath.Join(jdk, "1.8.0") + "</jdkHome>\n    </configuration>\n  </toolchain>\n" +
+		"</toolchains>\n"
+	if actual := string(content); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+
+	gradle := filepath.Join(home, "gradle.properties")
+	ioutil.WriteFile(gradle, []byte("org.gradle.daemon=false\norg.gradle.java.installations.paths=/opt/jdk11,"+
+		filepath.Join(jdk, "1.7.0")+"\n"), 0644)
+	content, err = GradleProperties(gradle, toolchains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "org.gradle.daemon=false\norg.gradle.java.installations.paths=/opt/jdk11," +
+		filepath.Join(jdk, "1.8.0") + "," + filepath.Join(jdk, "zulu@1.17.0") + "\n"
+	if actual := string(content); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
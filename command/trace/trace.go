@@ -0,0 +1,224 @@
+// Package trace records spans of jabba operations (e.g. resolve/download/extract/validate steps of install)
+// and exports them to an OpenTelemetry collector (OTLP/HTTP, JSON encoding).
+//
+// Tracing is enabled only when OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT is set.
+// OTEL_EXPORTER_OTLP_HEADERS, OTEL_SERVICE_NAME and TRACEPARENT (to join an existing trace, e.g. the one of
+// a CI job) are honored too.
+package trace
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+type Span struct {
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      error
+	parent   *Span
+}
+
+var (
+	// guards current & finished (spans can be started from multiple goroutines (e.g. parallel downloads))
+	mutex    sync.Mutex
+	current  *Span
+	finished []*Span
+	endpoint = resolveEndpoint()
+)
+
+func resolveEndpoint() string {
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); v != "" {
+		return v
+	}
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		return strings.TrimSuffix(v, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+func Enabled() bool {
+	return endpoint != ""
+}
+
+// Start starts a span (a child of the span started last and not yet ended, if any).
+// attrs is a list of key/value pairs.
+func Start(name string, attrs ...string) *Span {
+	mutex.Lock()
+	defer mutex.Unlock()
+	s := &Span{name: name, start: time.Now(), attrs: make(map[string]string), parent: current}
+	if !Enabled() {
+		return s
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		s.attrs[attrs[i]] = attrs[i+1]
+	}
+	s.spanID = randomHex(8)
+	if current != nil {
+		s.traceID, s.parentID = current.traceID, current.spanID
+	} else if traceID, parentID, ok := parseTraceparent(os.Getenv("TRACEPARENT")); ok {
+		s.traceID, s.parentID = traceID, parentID
+	} else {
+		s.traceID = randomHex(16)
+	}
+	current = s
+	return s
+}
+
+func (s *Span) SetAttr(key, value string) {
+	s.attrs[key] = value
+}
+
+// End ends the span (marking it as failed if err != nil).
+// Once the root span is ended, all the spans are exported.
+func (s *Span) End(err error) {
+	if !Enabled() {
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	finished = append(finished, s)
+	if current == s {
+		current = s.parent
+	}
+	if s.parent == nil {
+		if err := flush(); err != nil {
+			log.Debug("Failed to export trace: ", err)
+		}
+	}
+}
+
+// https://www.w3.org/TR/trace-context/#traceparent-header
+func parseTraceparent(value string) (traceID string, parentID string, ok bool) {
+	split := strings.Split(value, "-")
+	if len(split) != 4 || len(split[1]) != 32 || len(split[2]) != 16 {
+		return "", "", false
+	}
+	return split[1], split[2], true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type keyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func toKeyValues(m map[string]string) []keyValue {
+	var r []keyValue
+	for k, v := range m {
+		kv := keyValue{Key: k}
+		kv.Value.StringValue = v
+		r = append(r, kv)
+	}
+	return r
+}
+
+type status struct {
+	Code    int    `json:"code"` // 1 - OK, 2 - ERROR
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"` // 1 - INTERNAL
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/docs/specification.md#otlphttp
+func flush() error {
+	spans := make([]otlpSpan, len(finished))
+	for i, s := range finished {
+		st := status{Code: 1}
+		if s.err != nil {
+			st = status{Code: 2, Message: s.err.Error()}
+		}
+		spans[i] = otlpSpan{
+			TraceID:           s.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              1,
+			StartTimeUnixNano: fmt.Sprint(s.start.UnixNano()),
+			EndTimeUnixNano:   fmt.Sprint(s.end.UnixNano()),
+			Attributes:        toKeyValues(s.attrs),
+			Status:            st,
+		}
+	}
+	finished = nil
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		serviceName = "jabba"
+	}
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": toKeyValues(map[string]string{"service.name": serviceName}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "jabba"},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// key1=value1,key2=value2
+	for _, header := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if kv := strings.SplitN(header, "=", 2); len(kv) == 2 {
+			req.Header.Set(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		}
+	}
+	client := http.Client{Timeout: 5 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 400 {
+		return fmt.Errorf("POST %s returned %d", endpoint, res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	var payload struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []otlpSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &payload); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}))
+	defer server.Close()
+	prevEndpoint := endpoint
+	defer func() { endpoint = prevEndpoint }()
+	endpoint = server.URL
+	root := Start("install", "selector", "1.8")
+	child := Start("download")
+	child.End(errors.New("timeout"))
+	if payload.ResourceSpans != nil {
+		t.Fatal("spans are not expected to be exported until root span is ended")
+	}
+	root.End(nil)
+	spans := payload.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("actual: %v != expected: %v", len(spans), 2)
+	}
+	download, install := spans[0], spans[1]
+	if download.Name != "download" || download.ParentSpanID != install.SpanID ||
+		download.TraceID != install.TraceID || download.Status.Code != 2 {
+		t.Fatalf("unexpected span: %+v", download)
+	}
+	if install.Name != "install" || install.ParentSpanID != "" || install.Status.Code != 1 ||
+		len(install.Attributes) != 1 || install.Attributes[0].Value.StringValue != "1.8" {
+		t.Fatalf("unexpected span: %+v", install)
+	}
+}
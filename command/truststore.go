@@ -0,0 +1,216 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// "truststore" (see cfg.Truststore) makes Java processes trust CA certificates (e.g. the one of TLS-intercepting
+// proxy) without touching installed JDKs: cacerts of the JDK being activated is copied (with the certificates added)
+// into <jabba home>/truststore/<version>/, and `jabba use`, `jabba exec` & `jabba env` point javax.net.ssl.trustStore
+// at the copy (through JAVA_TOOL_OPTIONS (or JDK_JAVA_OPTIONS)). Copy is regenerated (with keytool of the JDK)
+// whenever either cacerts of the JDK or any of the certificates change.
+
+const truststoreProperty = "-Djavax.net.ssl.trustStore="
+
+// variables options could have been exported through (by this or the previous `jabba use`)
+var truststoreEnvVars = []string{"JAVA_TOOL_OPTIONS", "JDK_JAVA_OPTIONS"}
+
+func truststoreRoot() string {
+	return filepath.Join(cfg.StateDir(), "truststore")
+}
+
+// truststore returns path to the truststore of JDK at jdk (in cfg.JDKDir()) with certificates of PEM files added
+// (generating it if there is none yet).
+func truststore(jdk string, files []string) (string, error) {
+	home := filepath.Dir(filepath.Dir(expectedJavaPath(jdk, runtime.GOOS)))
+	cacerts, err := findUnder(home, cacertsPaths)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, file := range append([]string{cacerts}, files...) {
+		if err := hashFile(h, file); err != nil {
+			return "", err
+		}
+	}
+	dir := filepath.Join(truststoreRoot(), filepath.Base(jdk))
+	path := filepath.Join(dir, hex.EncodeToString(h.Sum(nil))[:16])
+	if isRegularFile(path) {
+		return path, nil
+	}
+	keytool := keytoolOf(home)
+	if !isRegularFile(keytool) {
+		return "", fmt.Errorf("%s not found", keytool)
+	}
+	log.Info("Generating truststore of ", filepath.Base(jdk), " (", strings.Join(files, ", "), ")")
+	// truststores generated for the previous versions of cacerts / certificates
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	tmp := path + ".jabba-tmp"
+	defer os.Remove(tmp)
+	if err := copyFileTo(cacerts, tmp); err != nil {
+		return "", err
+	}
+	if err := importCACertsInto(keytool, tmp, files); err != nil {
+		return "", err
+	}
+	return path, os.Rename(tmp, path)
+}
+
+func hashFile(w io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	// so that moving bytes from one file into another yields a different hash
+	fmt.Fprintf(w, "%s\x00", file)
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func copyFileTo(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// withTruststore points javax.net.ssl.trustStore of env (in "key=value" format, variables not in env are looked up
+// in the environment of jabba) at the truststore of JDK at jdk (if truststore is configured (options exported by the
+// previous `jabba use` are removed either way)). Failure to generate truststore is not fatal (JDK is activated
+// without it).
+func withTruststore(env []string, jdk string) (set []string, unset []string) {
+	c := cfg.Truststore()
+	var path string
+	// JDKs outside of jabba home (`jabba try`) are left alone
+	if len(c.CACerts) != 0 && filepath.Dir(jdk) == cfg.JDKDir() {
+		var err error
+		if path, err = truststore(jdk, c.CACerts); err != nil {
+			log.Warn("Failed to generate truststore of ", filepath.Base(jdk), " (", err, "). "+
+				"javax.net.ssl.trustStore is left as is")
+		}
+	}
+	return exportTruststore(env, path, c.Env)
+}
+
+// exportTruststore points javax.net.ssl.trustStore at path through variable envVar (options of the previous
+// `jabba use` are removed from both JAVA_TOOL_OPTIONS & JDK_JAVA_OPTIONS, path "" just removes them).
+func exportTruststore(env []string, path string, envVar string) (set []string, unset []string) {
+	set = env
+	for _, key := range truststoreEnvVars {
+		value, ok := lookupEnv(set, key)
+		updated := stripTruststoreOptions(value)
+		if key == envVar && path != "" {
+			updated = strings.TrimSpace(truststoreOptions(path) + " " + updated)
+		}
+		if updated == value {
+			continue
+		}
+		var kept []string
+		for _, kv := range set {
+			if k, _ := splitEnv(kv); k != key {
+				kept = append(kept, kv)
+			}
+		}
+		set = kept
+		if updated != "" {
+			set = append(set, key+"="+updated)
+		} else if ok {
+			unset = append(unset, key)
+		}
+	}
+	return set, unset
+}
+
+// lookupEnv returns value of the variable (the last one in env, os.LookupEnv if env doesn't have it).
+func lookupEnv(env []string, key string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if k, v := splitEnv(env[i]); k == key {
+			return v, true
+		}
+	}
+	return os.LookupEnv(key)
+}
+
+func truststoreOptions(path string) string {
+	option := truststoreProperty + path
+	if strings.IndexFunc(option, unicode.IsSpace) != -1 {
+		option = `"` + option + `"`
+	}
+	return option + " -Djavax.net.ssl.trustStorePassword=" + cacertsPassword
+}
+
+// stripTruststoreOptions removes options added by truststoreOptions (pointing into truststoreRoot()) from the
+// value of JAVA_TOOL_OPTIONS / JDK_JAVA_OPTIONS (value is returned as is if there are none).
+func stripTruststoreOptions(value string) string {
+	tokens := splitJavaOptions(value)
+	var r []string
+	stripped := false
+	for i := 0; i < len(tokens); i++ {
+		option := strings.Trim(tokens[i], `"`)
+		if strings.HasPrefix(option, truststoreProperty) &&
+			strings.HasPrefix(option[len(truststoreProperty):], truststoreRoot()+string(filepath.Separator)) {
+			if i+1 < len(tokens) && tokens[i+1] == "-Djavax.net.ssl.trustStorePassword="+cacertsPassword {
+				i++
+			}
+			stripped = true
+			continue
+		}
+		r = append(r, tokens[i])
+	}
+	if !stripped {
+		return value
+	}
+	return strings.Join(r, " ")
+}
+
+// splitJavaOptions splits value of JAVA_TOOL_OPTIONS on whitespace (quoted whitespace excluded) (quotes are kept).
+func splitJavaOptions(value string) []string {
+	var r []string
+	var token strings.Builder
+	quoted := false
+	for _, c := range value {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			token.WriteRune(c)
+		case unicode.IsSpace(c) && !quoted:
+			if token.Len() != 0 {
+				r = append(r, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(c)
+		}
+	}
+	if token.Len() != 0 {
+		r = append(r, token.String())
+	}
+	return r
+}
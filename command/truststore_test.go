@@ -0,0 +1,105 @@
+package command
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestStripTruststoreOptions(t *testing.T) {
+	os.Setenv("JABBA_HOME", filepath.FromSlash("/home/u/.jabba"))
+	defer os.Unsetenv("JABBA_HOME")
+	path := filepath.Join(truststoreRoot(), "zulu@1.17.0", "0123456789abcdef")
+	for value, expected := range map[string]string{
+		"":                                       "",
+		"-Xss4m  -Dx=y":                          "-Xss4m  -Dx=y",
+		truststoreOptions(path):                  "",
+		"-Xss4m " + truststoreOptions(path):      "-Xss4m",
+		truststoreOptions(path) + " -Dx=\"a b\"": "-Dx=\"a b\"",
+		"-Djavax.net.ssl.trustStore=/etc/corp.jks -Dx=y":                                            "-Djavax.net.ssl.trustStore=/etc/corp.jks -Dx=y",
+		truststoreOptions(filepath.Join(truststoreRoot(), "my jdk", "0123456789abcdef")) + " -Dx=y": "-Dx=y",
+	} {
+		if actual := stripTruststoreOptions(value); actual != expected {
+			t.Fatalf("%s: actual: %v != expected: %v", value, actual, expected)
+		}
+	}
+}
+
+func TestWithTruststore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("keytool stub is a shell script")
+	}
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	keytoolLog := filepath.Join(home, "keytool.log")
+	jdk := filepath.Join(cfg.JDKDir(), "zulu@1.17.0")
+	bin := filepath.Dir(expectedJavaPath(jdk, runtime.GOOS))
+	ok(os.MkdirAll(bin, 0755))
+	ok(ioutil.WriteFile(filepath.Join(bin, "keytool"), []byte("#!/bin/sh\necho \"$@\" >> "+keytoolLog+"\n"), 0755))
+	ok(os.MkdirAll(filepath.Join(filepath.Dir(bin), "lib", "security"), 0755))
+	ok(ioutil.WriteFile(filepath.Join(filepath.Dir(bin), "lib", "security", "cacerts"), []byte("cacerts"), 0644))
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("cert")})
+	ok(ioutil.WriteFile(filepath.Join(home, "proxy.pem"), block, 0644))
+	cfg.Use(&cfg.Config{Truststore: cfg.TruststoreConfig{CACerts: cfg.StringList{"proxy.pem"}}})
+	defer cfg.Use(nil)
+	os.Setenv("JAVA_TOOL_OPTIONS", "-Xss4m")
+	defer os.Unsetenv("JAVA_TOOL_OPTIONS")
+	set, unset := withTruststore([]string{"JAVA_HOME=" + jdk}, jdk)
+	if len(set) != 2 || len(unset) != 0 || !strings.HasPrefix(set[1], "JAVA_TOOL_OPTIONS="+truststoreProperty) ||
+		!strings.HasSuffix(set[1], " -Xss4m") {
+		t.Fatalf("actual: %v, %v", set, unset)
+	}
+	path := strings.TrimPrefix(strings.Fields(set[1])[0], "JAVA_TOOL_OPTIONS="+truststoreProperty)
+	b, err := ioutil.ReadFile(path)
+	ok(err)
+	if string(b) != "cacerts" {
+		t.Fatalf("actual: %v != expected: copy of cacerts", string(b))
+	}
+	b, err = ioutil.ReadFile(keytoolLog)
+	ok(err)
+	if !strings.Contains(string(b), "-importcert") || !strings.Contains(string(b), "-alias jabba-proxy -file") {
+		t.Fatalf("unexpected keytool invocations:\n%s", b)
+	}
+	// truststore is reused as long as neither cacerts nor certificates change
+	os.Setenv("JAVA_TOOL_OPTIONS", strings.TrimPrefix(set[1], "JAVA_TOOL_OPTIONS="))
+	set2, _ := withTruststore(nil, jdk)
+	if set2 != nil {
+		t.Fatalf("actual: %v != expected: nothing to change", set2)
+	}
+	ok(ioutil.WriteFile(filepath.Join(home, "proxy.pem"), append(block, block...), 0644))
+	set2, _ = withTruststore(nil, jdk)
+	if len(set2) != 1 || set2[0] == set[1] || !strings.HasSuffix(set2[0], " -Xss4m") {
+		t.Fatalf("actual: %v", set2)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected %s to be removed", path)
+	}
+	// deactivation / disabled truststore leaves the rest of JAVA_TOOL_OPTIONS alone
+	os.Setenv("JABBA_TRUSTSTORE", "off")
+	defer os.Unsetenv("JABBA_TRUSTSTORE")
+	set, unset = withTruststore(nil, jdk)
+	if expected := []string{"JAVA_TOOL_OPTIONS=-Xss4m"}; !reflect.DeepEqual(set, expected) || len(unset) != 0 {
+		t.Fatalf("actual: %v, %v != expected: %v", set, unset, expected)
+	}
+	os.Setenv("JAVA_TOOL_OPTIONS", strings.TrimSuffix(strings.TrimPrefix(set2[0], "JAVA_TOOL_OPTIONS="), " -Xss4m"))
+	change, err := Deactivate()
+	ok(err)
+	if unset := change.Unset; unset[len(unset)-1] != "JAVA_TOOL_OPTIONS" {
+		t.Fatalf("actual: %v != expected: JAVA_TOOL_OPTIONS to be unset", unset)
+	}
+}
@@ -0,0 +1,124 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+// PolicyInput is what uninstall policy command (see cfg.UninstallPolicy) receives on stdin.
+type PolicyInput struct {
+	// "uninstall"
+	Operation string `json:"operation"`
+	// jabba command JDK is being removed by ("uninstall", "prune" or "upgrade")
+	Command string `json:"command"`
+	Version string `json:"version"`
+	// "" if version is unqualified (e.g. 1.8.0)
+	Vendor string `json:"vendor,omitempty"`
+	Path   string `json:"path"`
+	// aliases resolving to the version
+	Aliases []string `json:"aliases"`
+	User    string   `json:"user"`
+	Host    string   `json:"host"`
+}
+
+// PolicyDecision is what uninstall policy command is expected to print (if anything) ({"result": {...}} (OPA REST
+// API response) is accepted too).
+type PolicyDecision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// PolicyVetoError is returned when uninstall policy command doesn't allow JDK to be removed.
+type PolicyVetoError struct {
+	Version string
+	Reason  string
+}
+
+func (e *PolicyVetoError) Error() string {
+	return fmt.Sprintf("%s cannot be uninstalled (vetoed by uninstall_policy: %s)", e.Version, e.Reason)
+}
+
+// checkUninstallPolicy runs uninstall policy command (if one is configured). Removal is vetoed if command exits with
+// non-zero code (reason being the last line it printed to stderr) or prints decision that doesn't allow it.
+// Policy that cannot be evaluated (command not found, unexpected output, ...) vetoes removal too.
+func checkUninstallPolicy(ver string, command string) error {
+	policy := cfg.UninstallPolicy()
+	if policy == "" {
+		return nil
+	}
+	input := PolicyInput{Operation: "uninstall", Command: command, Version: ver,
+		Path: filepath.Join(cfg.JDKDir(), ver), Aliases: aliasesOf(ver), User: currentUser()}
+	if v, err := semver.ParseVersion(ver); err == nil {
+		input.Vendor = v.Qualifier()
+	}
+	input.Host, _ = os.Hostname()
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", policy)
+	} else {
+		cmd = exec.Command("sh", "-c", policy)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(stdin), &stdout, &stderr
+	log.Debug("Consulting uninstall_policy (", policy, ") about ", ver)
+	if err := cmd.Run(); err != nil {
+		reason := lastLine(stderr.String())
+		if reason == "" {
+			reason = err.Error()
+		}
+		return &PolicyVetoError{Version: ver, Reason: reason}
+	}
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 {
+		return nil
+	}
+	var decision struct {
+		PolicyDecision
+		Result *PolicyDecision `json:"result"`
+	}
+	if err := json.Unmarshal(out, &decision); err != nil {
+		return &PolicyVetoError{Version: ver, Reason: "unexpected output (" + err.Error() + ")"}
+	}
+	d := decision.PolicyDecision
+	if decision.Result != nil {
+		d = *decision.Result
+	}
+	if !d.Allow {
+		if d.Reason == "" {
+			d.Reason = "not allowed"
+		}
+		return &PolicyVetoError{Version: ver, Reason: d.Reason}
+	}
+	return nil
+}
+
+// aliasesOf returns names of the aliases resolving to ver.
+func aliasesOf(ver string) []string {
+	r := []string{}
+	names, _ := Aliases()
+	for _, name := range names {
+		if resolved, err := Resolve(name); err == nil && resolved == ver {
+			r = append(r, name)
+		}
+	}
+	return r
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
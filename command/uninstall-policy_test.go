@@ -0,0 +1,73 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUninstallPolicy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("policy scripts are written for sh")
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "zulu@1.8.0", "zulu@1.11.0", "zulu@1.17.0")
+	if err := SetAlias("default", "zulu@1.17"); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(home, "input.json")
+	// zulu@1.17 is mandated, 1.8 can only be removed by prune
+	policy := `cat > "` + input + `"
+case "$(cat "` + input + `")" in
+  *'"version":"zulu@1.17.0"'*) echo '{"allow": false, "reason": "Java 17 is mandated by IT"}' ;;
+  *'"command":"uninstall","version":"zulu@1.8.0"'*) echo "use jabba prune" >&2; exit 1 ;;
+  *) echo '{"result": {"allow": true}}' ;;
+esac`
+	os.Setenv("JABBA_UNINSTALL_POLICY", policy)
+	defer os.Unsetenv("JABBA_UNINSTALL_POLICY")
+	_, err = Uninstall("zulu@1.17")
+	if veto, ok := err.(*PolicyVetoError); !ok || veto.Reason != "Java 17 is mandated by IT" {
+		t.Fatalf("expected removal to be vetoed (got %v)", err)
+	}
+	var actual PolicyInput
+	cnt, _ := ioutil.ReadFile(input)
+	if err := json.Unmarshal(cnt, &actual); err != nil {
+		t.Fatal(err)
+	}
+	expected := PolicyInput{Operation: "uninstall", Command: "uninstall", Version: "zulu@1.17.0", Vendor: "zulu",
+		Path: filepath.Join(home, "jdk", "zulu@1.17.0"), Aliases: []string{"default"}, User: currentUser(),
+		Host: actual.Host}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %+v != expected: %+v", actual, expected)
+	}
+	removed, err := Uninstall("zulu@*")
+	if err == nil || !strings.Contains(err.Error(), "zulu@1.17.0, zulu@1.8.0") {
+		t.Fatalf("expected removal of zulu@1.17.0 & zulu@1.8.0 to be vetoed (got %v)", err)
+	}
+	if expected := []string{"zulu@1.11.0"}; !reflect.DeepEqual(removed, expected) {
+		t.Fatalf("actual: %v != expected: %v", removed, expected)
+	}
+	if _, err := Uninstall("zulu@1.8"); err == nil || !strings.Contains(err.Error(), "use jabba prune") {
+		t.Fatalf("expected removal to be vetoed with reason printed to stderr (got %v)", err)
+	}
+	if removed, err := Prune(nil); err != nil || !reflect.DeepEqual(removed, []string{"zulu@1.8.0"}) {
+		t.Fatalf("actual: %v (%v) != expected: [zulu@1.8.0]", removed, err)
+	}
+	// policy that cannot be evaluated doesn't let anything through
+	installFakeJDKs(t, home, "zulu@1.11.0")
+	os.Setenv("JABBA_UNINSTALL_POLICY", "echo allow")
+	if _, err := Uninstall("zulu@1.11"); err == nil || !strings.Contains(err.Error(), "unexpected output") {
+		t.Fatalf("expected removal to be vetoed (got %v)", err)
+	}
+}
@@ -1,15 +1,98 @@
 package command
 
 import (
+	"fmt"
+	log "github.com/Sirupsen/logrus"
 	"github.com/shyiko/jabba/cfg"
-	"os"
+	"github.com/shyiko/jabba/semver"
 	"path/filepath"
+	"strings"
 )
 
-func Uninstall(selector string) error {
-	ver, err := LsBestMatch(selector)
+// Uninstall removes all installed JDKs matching the selector (e.g. "1.8.0", "1.8" (any 1.8.x), "zulu@<1.11"),
+// returning the versions that were removed. Links to system JDKs are left alone (see `jabba unlink`), as are pinned
+// JDKs (see Pin) and the ones uninstall policy vetoes removal of (see cfg.UninstallPolicy) (error is returned in the
+// latter case).
+func Uninstall(selector string) ([]string, error) {
+	return uninstallMatching(selector, &CleanReport{})
+}
+
+// PlanUninstall returns what Uninstall would remove (JDK directories & metadata) (without removing anything).
+func PlanUninstall(selector string) (*CleanReport, error) {
+	r := &CleanReport{DryRun: true}
+	if _, err := uninstallMatching(selector, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func uninstallMatching(selector string, r *CleanReport) ([]string, error) {
+	rng, err := semver.ParseRange(selector)
 	if err != nil {
+		return nil, err
+	}
+	vs, err := Ls()
+	if err != nil {
+		return nil, err
+	}
+	var removed, pinned []string
+	var vetoed []*PolicyVetoError
+	for _, v := range vs {
+		if !rng.Contains(v) || strings.HasPrefix(v.String(), "system@") {
+			continue
+		}
+		if IsPinned(v.String()) {
+			log.Warn("Keeping ", v, " (pinned)")
+			pinned = append(pinned, v.String())
+			continue
+		}
+		if err := uninstall(v.String(), "uninstall", r); err != nil {
+			if veto, ok := err.(*PolicyVetoError); ok {
+				log.Warn("Keeping ", v, " (", veto.Reason, ")")
+				vetoed = append(vetoed, veto)
+				continue
+			}
+			return removed, err
+		}
+		removed = append(removed, v.String())
+	}
+	if len(vetoed) == 1 {
+		return removed, vetoed[0]
+	}
+	if len(vetoed) != 0 {
+		var vs []string
+		for _, veto := range vetoed {
+			vs = append(vs, veto.Version)
+		}
+		return removed, fmt.Errorf("%s cannot be uninstalled (vetoed by uninstall_policy)", strings.Join(vs, ", "))
+	}
+	if len(removed) == 0 && len(pinned) == 1 {
+		return nil, fmt.Errorf("%s is pinned (run `jabba unpin %s` first)", pinned[0], pinned[0])
+	}
+	if len(removed) == 0 && len(pinned) != 0 {
+		return nil, fmt.Errorf("%s are pinned (see `jabba unpin`)", strings.Join(pinned, ", "))
+	}
+	if len(removed) == 0 {
+		return nil, fmt.Errorf("%s isn't installed", rng)
+	}
+	return removed, nil
+}
+
+// uninstall removes JDK (unless uninstall policy vetoes it (see checkUninstallPolicy)), adding what was removed to r.
+// command is the jabba command removal is part of ("uninstall", "prune", "upgrade").
+func uninstall(ver string, command string, r *CleanReport) error {
+	if err := checkUninstallPolicy(ver, command); err != nil {
+		return err
+	}
+	if !r.DryRun {
+		log.Info("Uninstalling ", ver)
+		invalidateState()
+	}
+	if err := r.remove(filepath.Join(cfg.JDKDir(), ver)); err != nil {
+		return err
+	}
+	if err := r.remove(metaFile(ver)); err != nil {
 		return err
 	}
-	return os.RemoveAll(filepath.Join(cfg.Dir(), "jdk", ver))
+	return r.remove(filepath.Join(truststoreRoot(), ver))
 }
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// installFakeJDKs creates (empty) JDKs in $JABBA_HOME/jdk.
+func installFakeJDKs(t *testing.T, home string, vs ...string) {
+	for _, v := range vs {
+		if err := os.MkdirAll(filepath.Join(home, "jdk", v), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func installed(t *testing.T) []string {
+	vs, err := Ls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r []string
+	for _, v := range vs {
+		r = append(r, v.String())
+	}
+	return r
+}
+
+func TestUninstallRange(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "zulu@1.8.0", "zulu@1.8.1", "zulu@1.11.0", "zulu@1.17.0", "1.8.0")
+	if err := ioutil.WriteFile(filepath.Join(home, "jdk", "zulu@1.8.1", "release"), []byte("JAVA_VERSION=\"1.8.0_1\"\n"),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := PlanUninstall("zulu@<1.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPlan := &CleanReport{DryRun: true, Freed: 23, Removed: []string{
+		filepath.Join(home, "jdk", "zulu@1.8.1"), filepath.Join(home, "jdk", "zulu@1.8.0")}}
+	if !reflect.DeepEqual(plan, expectedPlan) {
+		t.Fatalf("actual: %v != expected: %v", plan, expectedPlan)
+	}
+	if actual := installed(t); len(actual) != 5 {
+		t.Fatalf("actual: %v != expected: nothing removed", actual)
+	}
+	removed, err := Uninstall("zulu@<1.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"zulu@1.8.1", "zulu@1.8.0"}; !reflect.DeepEqual(removed, expected) {
+		t.Fatalf("actual: %v != expected: %v", removed, expected)
+	}
+	if actual, expected := installed(t), []string{"1.8.0", "zulu@1.17.0", "zulu@1.11.0"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if _, err := Uninstall("zulu@<1.11"); err == nil {
+		t.Fatal("expected uninstall to fail (nothing matches)")
+	}
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/semver"
+)
+
+// UpgradeResult describes a single upgrade (see `jabba upgrade`).
+type UpgradeResult struct {
+	From string `json:"from"`
+	// same as From if there was nothing to upgrade to
+	To string `json:"to"`
+	// aliases that were repointed from From to To
+	Aliases []string `json:"aliases,omitempty"`
+	// true if From was uninstalled
+	Purged bool `json:"purged"`
+}
+
+type UpgradeOptions struct {
+	// true to uninstall superseded JDK
+	Purge bool
+	// see InstallOptions.Any
+	Any bool
+}
+
+// Upgrade installs the latest release of the line (qualifier + major, e.g. "zulu@1.17") JDK referenced by target
+// (either an alias (e.g. "default") or a selector (the latest matching installed JDK is upgraded)) belongs to,
+// repointing aliases that referenced the old JDK.
+func Upgrade(target string, opts UpgradeOptions) (*UpgradeResult, error) {
+	selector, err := ResolveAlias(target)
+	if err != nil {
+		return nil, err
+	}
+	from, err := LsBestMatch(selector)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(from, "system@") {
+		return nil, fmt.Errorf("%s is a link to system JDK (use your OS package manager to upgrade it)", from)
+	}
+	ver, err := semver.ParseVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	line := upgradeRange(ver)
+	latest, _, err := resolveRelease(line, InstallOptions{Any: opts.Any})
+	if err != nil {
+		return nil, err
+	}
+	result := &UpgradeResult{From: from, To: from}
+	if !ver.LessThan(latest) {
+		log.Info(from, " is up to date")
+		return result, nil
+	}
+	// components of the old JDK go along
+	installResult, err := Install(latest.String(), InstallOptions{Any: opts.Any, Components: recordedComponents(from)})
+	if err != nil {
+		return nil, err
+	}
+	result.To = installResult.Version
+	names, err := Aliases()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		// aliases pointing to a range (e.g. "zulu@1.17") follow the latest installed JDK on their own
+		if strings.TrimSpace(GetAlias(name)) != from {
+			continue
+		}
+		if source := AliasSource(name); source != "" {
+			// overriding it would stop alias from following updates of the file
+			log.Warn("Not repointing ", name, " (defined in ", source, ")")
+			continue
+		}
+		log.Info("Repointing ", name, " to ", result.To)
+		if err := SetAlias(name, result.To); err != nil {
+			return nil, err
+		}
+		if err := LinkAlias(name); err != nil {
+			return nil, err
+		}
+		result.Aliases = append(result.Aliases, name)
+	}
+	if opts.Purge {
+		if Current() == from {
+			log.Warn("Keeping ", from, " (used by current shell)")
+		} else if IsPinned(from) {
+			log.Warn("Keeping ", from, " (pinned)")
+		} else {
+			if err := uninstall(from, "upgrade", &CleanReport{}); err != nil {
+				veto, ok := err.(*PolicyVetoError)
+				if !ok {
+					return nil, err
+				}
+				log.Warn("Keeping ", from, " (", veto.Reason, ")")
+			} else {
+				result.Purged = true
+			}
+		}
+	}
+	return result, nil
+}
+
+// upgradeRange returns range covering every release of the line ver belongs to
+// ("-0" makes "<qualifier>@1.<major>.<minor>-<security>" (pre-release per semver) releases a match).
+func upgradeRange(ver *semver.Version) string {
+	prefix := ""
+	if ver.Qualifier() != "" {
+		prefix = ver.Qualifier() + "@"
+	}
+	return fmt.Sprintf("%s>=%d.%d.0-0 <%d.%d.0-0", prefix, ver.Major(), ver.Minor(), ver.Major(), ver.Minor()+1)
+}
@@ -0,0 +1,92 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+)
+
+func TestUpgradeRange(t *testing.T) {
+	for ver, expected := range map[string]string{
+		"temurin@1.17.0-9": "temurin@>=1.17.0-0 <1.18.0-0",
+		"1.8.292":          ">=1.8.0-0 <1.9.0-0",
+	} {
+		v, err := semver.ParseVersion(ver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := upgradeRange(v); actual != expected {
+			t.Fatalf("actual: %v != expected: %v", actual, expected)
+		}
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	archive := filepath.Join(home, "jdk.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, file := range []string{java, "jdk/release"} {
+		ok(tw.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0755}))
+	}
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	url := "tgz+file://" + filepath.ToSlash(archive)
+	index := filepath.Join(home, "index.json")
+	ok(ioutil.WriteFile(index, []byte(`{"`+runtime.GOOS+`": {"`+runtime.GOARCH+`": {"jdk@zulu": {
+		"1.17.0": "`+url+`", "1.17.2": "`+url+`", "1.21.0": "`+url+`"
+	}}}}`), 0644))
+	cfg.SetRegistry([]string{"file://" + filepath.ToSlash(index)})
+	defer cfg.SetRegistry(nil)
+	installFakeJDKs(t, home, "zulu@1.17.0", "zulu@1.21.0")
+	ok(SetAlias("default", "zulu@1.17.0"))
+	ok(SetAlias("lts", "zulu@1.17"))
+	result, err := Upgrade("default", UpgradeOptions{Purge: true})
+	ok(err)
+	expected := &UpgradeResult{From: "zulu@1.17.0", To: "zulu@1.17.2", Aliases: []string{"default"}, Purged: true}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("actual: %+v != expected: %+v", result, expected)
+	}
+	if actual, expected := installed(t), []string{"zulu@1.21.0", "zulu@1.17.2"}; !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	if actual := strings.TrimSpace(GetAlias("lts")); actual != "zulu@1.17" {
+		t.Fatalf("actual: %v != expected: %v", actual, "zulu@1.17")
+	}
+	// nothing to upgrade to
+	for _, target := range []string{"lts", "zulu@1.21"} {
+		result, err = Upgrade(target, UpgradeOptions{})
+		ok(err)
+		if result.From != result.To {
+			t.Fatalf("%s: expected %s to be up to date (got %+v)", target, result.From, result)
+		}
+	}
+	if _, err := Upgrade("zulu@1.11", UpgradeOptions{}); err == nil {
+		t.Fatal("expected upgrade of zulu@1.11 to fail (not installed)")
+	}
+}
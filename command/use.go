@@ -1,34 +1,99 @@
 package command
 
 import (
+	"fmt"
 	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
 	"os"
 	"path/filepath"
-	"regexp"
 	"runtime"
 )
 
-func Use(selector string) ([]string, error) {
-	aliasValue := GetAlias(selector)
-	if aliasValue != "" {
-		selector = aliasValue
+type UseOptions struct {
+	// profiles (see cfg.Profile) to apply on top
+	Profiles []string
+	// install JDK if there is none matching the selector
+	Install bool
+	// install only versions (ranges) and aliases resolving to them, not "<version>=<url>" (as auto_install
+	// (see cfg.AutoInstall) must not download whatever a project file points to)
+	InstallRangesOnly bool
+	// directory of the project selector was taken from (see ProjectVersion): what gets installed then is what
+	// ProjectSelector resolves it to (so a URL is only installed if jabba.lock pins the version to it with a checksum)
+	ProjectDir string
+	// fail if JDK matching the selector is broken (there is no bin/java) instead of switching to it anyway
+	Strict bool
+}
+
+// Use returns change of the environment that switches PATH & JAVA_HOME to the JDK matching the selector
+// (applying profiles (see cfg.Profile) on top, if any).
+func Use(selector string, profiles ...string) (*EnvChange, error) {
+	return UseWithOptions(selector, UseOptions{Profiles: profiles})
+}
+
+// UseWithOptions is Use that can install JDK first (see UseOptions).
+func UseWithOptions(selector string, opts UseOptions) (*EnvChange, error) {
+	resolved, err := ResolveAlias(selector)
+	if err != nil {
+		return nil, err
 	}
-	ver, err := LsBestMatch(selector)
+	ver, err := LsBestMatch(resolved)
+	if err != nil {
+		if !opts.Install {
+			return nil, err
+		}
+		install := resolved
+		if opts.ProjectDir != "" {
+			if install, _, err = ProjectSelector(opts.ProjectDir); err != nil {
+				return nil, err
+			}
+		} else if opts.InstallRangesOnly {
+			if _, perr := semver.ParseRange(resolved); perr != nil {
+				return nil, fmt.Errorf("%v (auto_install only installs versions (ranges), use --install to install %s)",
+					err, selector)
+			}
+		}
+		if ver, err = installMissing(install); err != nil {
+			return nil, err
+		}
+	}
+	path := filepath.Join(cfg.JDKDir(), ver)
+	if opts.Strict {
+		if java := expectedJavaPath(path, runtime.GOOS); !isRegularFile(java) {
+			return nil, fmt.Errorf("%s is broken (%s not found)", ver, java)
+		}
+	}
+	change, err := usePath(path, selector, opts.Profiles)
+	if err == nil {
+		recordHistory("use", selector, ver)
+		WarnIfOutdated(ver)
+	}
+	return change, err
+}
+
+// usePath returns change of the environment that switches to JDK at path (in cfg.JDKDir()) selector resolved to.
+func usePath(path string, selector string, profiles []string) (*EnvChange, error) {
+	env, err := useEnv(path)
+	if err != nil {
+		return nil, err
+	}
+	env, unset := withJDKEnv(env, selector, filepath.Base(path))
+	env, truststoreUnset := withTruststore(env, path)
+	unset = append(unset, truststoreUnset...)
+	env, profileUnset, err := applyProfiles(env, profiles)
 	if err != nil {
 		return nil, err
 	}
-	return usePath(filepath.Join(cfg.Dir(), "jdk", ver))
+	return &EnvChange{Set: env, Unset: append(unset, profileUnset...)}, nil
 }
 
-func usePath(path string) ([]string, error) {
+// useEnv returns PATH, JAVA_HOME & JAVA_HOME_BEFORE_JABBA (in "key=value" format) to use JDK at the specified path.
+func useEnv(path string) ([]string, error) {
 	path, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
-	pth, _ := os.LookupEnv("PATH")
-	rgxp := regexp.MustCompile(regexp.QuoteMeta(filepath.Join(cfg.Dir(), "jdk")) + "[^:]+[:]")
 	// strip references to ~/.jabba/jdk/*, otherwise leave unchanged
-	pth = rgxp.ReplaceAllString(pth, "")
+	pth := stripJDKs(os.Getenv("PATH"))
 	if runtime.GOOS == "darwin" {
 		path = filepath.Join(path, "Contents", "Home")
 	}
@@ -37,8 +102,13 @@ func usePath(path string) ([]string, error) {
 		systemJavaHome, _ = os.LookupEnv("JAVA_HOME")
 	}
 	return []string{
-		"export PATH=\"" + filepath.Join(path, "bin") + string(os.PathListSeparator) + pth + "\"",
-		"export JAVA_HOME=\"" + path + "\"",
-		"export JAVA_HOME_BEFORE_JABBA=\"" + systemJavaHome + "\"",
+		"PATH=" + filepath.Join(path, "bin") + string(os.PathListSeparator) + pth,
+		"JAVA_HOME=" + path,
+		"JAVA_HOME_BEFORE_JABBA=" + systemJavaHome,
 	}, nil
 }
+
+func isRegularFile(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.Mode().IsRegular()
+}
@@ -1,10 +1,17 @@
 package command
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
 	"github.com/shyiko/jabba/cfg"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -43,7 +50,77 @@ func TestUse(t *testing.T) {
 		"export JAVA_HOME=\"" + cfg.Dir() + "/jdk/1.7.2" + suffix + "\"",
 		"export JAVA_HOME_BEFORE_JABBA=\"/system-jdk\"",
 	}
-	if !reflect.DeepEqual(actual, expected) {
+	if actual := strings.Split(actual.Script(""), "\n"); !reflect.DeepEqual(actual, expected) {
 		t.Fatalf("actual: %v != expected: %v", actual, expected)
 	}
 }
+
+func TestUseWithOptions(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	java := "jdk/bin/java"
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	archive := filepath.Join(home, "jdk.tar.gz")
+	f, err := os.Create(archive)
+	ok(err)
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	ok(tw.WriteHeader(&tar.Header{Name: java, Typeflag: tar.TypeReg, Mode: 0755}))
+	ok(tw.WriteHeader(&tar.Header{Name: "jdk/release", Typeflag: tar.TypeReg, Mode: 0644}))
+	ok(tw.Close())
+	ok(gw.Close())
+	ok(f.Close())
+	selector := "1.17.0=tgz+file://" + filepath.ToSlash(archive)
+	if _, err := UseWithOptions(selector, UseOptions{}); err == nil {
+		t.Fatal("expected UseWithOptions to fail (JDK isn't installed)")
+	}
+	// auto_install doesn't download archives
+	if _, err := UseWithOptions(selector, UseOptions{Install: true, InstallRangesOnly: true}); err == nil ||
+		!strings.Contains(err.Error(), "auto_install") {
+		t.Fatalf("expected UseWithOptions to refuse to install %s (%v)", selector, err)
+	}
+	// nor does anything else that comes from a project file (unless jabba.lock pins it)
+	project := filepath.Join(home, "project")
+	ok(os.Mkdir(project, 0755))
+	ok(ioutil.WriteFile(filepath.Join(project, ".jabbarc"), []byte(selector), 0644))
+	if _, err := UseWithOptions(selector, UseOptions{Install: true, ProjectDir: project}); err == nil {
+		t.Fatalf("expected UseWithOptions to refuse to install %s from .jabbarc", selector)
+	}
+	b, err := ioutil.ReadFile(archive)
+	ok(err)
+	ok(ioutil.WriteFile(filepath.Join(project, ".jabbarc"), []byte("1.17"), 0644))
+	ok(WriteLockfile(filepath.Join(project, "jabba.lock"), &Lockfile{JDKs: []LockedJDK{{Version: "1.17.0",
+		URL: "tgz+file://" + filepath.ToSlash(archive), SHA256: fmt.Sprintf("%x", sha256.Sum256(b))}}}))
+	change, err := UseWithOptions("1.17", UseOptions{Install: true, InstallRangesOnly: true, ProjectDir: project})
+	ok(err)
+	if !strings.Contains(change.Script("sh"), filepath.Join(cfg.JDKDir(), "1.17.0")) {
+		t.Fatalf("unexpected change: %s", change.Script("sh"))
+	}
+	ok(os.RemoveAll(filepath.Join(cfg.JDKDir(), "1.17.0")))
+	change, err = UseWithOptions(selector, UseOptions{Install: true, Strict: true})
+	ok(err)
+	if !strings.Contains(change.Script("sh"), filepath.Join(cfg.JDKDir(), "1.17.0")) {
+		t.Fatalf("unexpected change: %s", change.Script("sh"))
+	}
+	// installed already
+	_, err = UseWithOptions("1.17", UseOptions{Install: true})
+	ok(err)
+	// broken JDK is only rejected in strict mode
+	installFakeJDKs(t, home, "1.21.0")
+	_, err = UseWithOptions("1.21", UseOptions{})
+	ok(err)
+	if _, err := UseWithOptions("1.21", UseOptions{Strict: true}); err == nil ||
+		!strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected UseWithOptions to fail (JDK is broken) (%v)", err)
+	}
+}
@@ -0,0 +1,38 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/cfg"
+)
+
+// QualifySelector makes selector that doesn't specify vendor (e.g. "21", "~1.17.2") resolve within vendor
+// (--vendor) or cfg.DefaultVendor() (if vendor is "") ("21" -> "temurin@1.21").
+// Selectors that specify vendor (or URL) are returned as is (unless vendor is given and doesn't match).
+func QualifySelector(selector string, vendor string) (string, error) {
+	if IsURLSelector(selector) || IsDigestSelector(selector) {
+		return selector, nil
+	}
+	if i := strings.Index(selector, "@"); i != -1 {
+		if vendor != "" && selector[:i] != vendor {
+			return "", fmt.Errorf("%s doesn't match --vendor %s", selector, vendor)
+		}
+		return selector, nil
+	}
+	source := "--vendor"
+	if vendor == "" {
+		if vendor, source = cfg.DefaultVendor(); vendor == "" {
+			return selector, nil
+		}
+	}
+	// "21" -> "1.21", "17.0.9" -> "1.17.0-9" (ranges are left untouched)
+	ver, err := fromForeignVersion(selector)
+	if err != nil {
+		ver = selector
+	}
+	qualified := vendor + "@" + ver
+	log.Info("Resolving ", selector, " as ", qualified, " (vendor set by ", source, ")")
+	return qualified, nil
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"os"
+	"testing"
+)
+
+func TestQualifySelector(t *testing.T) {
+	for _, test := range []struct {
+		selector, vendor, defaultVendor, expected string
+	}{
+		{"21", "", "", "21"},
+		{"21", "", "temurin", "temurin@1.21"},
+		{"21", "zulu", "temurin", "zulu@1.21"},
+		{"17.0.9", "", "temurin", "temurin@1.17.0-9"},
+		{"~1.17.2", "", "temurin", "temurin@~1.17.2"},
+		{"1.17 !1.17.0-8", "", "temurin", "temurin@1.17 !1.17.0-8"},
+		{"zulu@1.17", "", "temurin", "zulu@1.17"},
+		{"zulu@1.17", "zulu", "", "zulu@1.17"},
+		{"1.8.0-custom=tgz+https://example.com/jdk.tar.gz", "zulu", "", "1.8.0-custom=tgz+https://example.com/jdk.tar.gz"},
+	} {
+		os.Setenv("JABBA_DEFAULT_VENDOR", test.defaultVendor)
+		actual, err := QualifySelector(test.selector, test.vendor)
+		if err != nil || actual != test.expected {
+			t.Fatalf("%+v: actual: %v (%v) != expected: %v", test, actual, err, test.expected)
+		}
+	}
+	os.Unsetenv("JABBA_DEFAULT_VENDOR")
+	if _, err := QualifySelector("zulu@1.17", "temurin"); err == nil {
+		t.Fatal("expected vendor mismatch to be reported")
+	}
+}
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Release binaries are sealed (see `make build-release`) by appending
+// "\njabba-sha256:<sha256 of everything before the trailer>\n" to them
+// (executable formats jabba is built for ignore trailing data).
+const selfChecksumPrefix = "\njabba-sha256:"
+
+const selfChecksumTrailerLen = len(selfChecksumPrefix) + sha256.Size*2 + 1
+
+// ErrNotSealed is returned by VerifySelf when jabba binary carries no checksum, which is expected of development
+// builds (and of a release binary that got truncated).
+var ErrNotSealed = errors.New("jabba binary has no embedded checksum (development build or truncated binary)")
+
+// VerifySelf checks running jabba binary against the checksum embedded into it.
+// It returns path to the binary along with its (verified) checksum.
+func VerifySelf() (exe string, sum string, err error) {
+	exe, err = os.Executable()
+	if err != nil {
+		return
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return
+	}
+	sum, err = verifySealed(exe)
+	return
+}
+
+func verifySealed(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := stat.Size() - int64(selfChecksumTrailerLen)
+	if size < 0 {
+		return "", ErrNotSealed
+	}
+	trailer := make([]byte, selfChecksumTrailerLen)
+	if _, err := f.ReadAt(trailer, size); err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(string(trailer), selfChecksumPrefix) || trailer[len(trailer)-1] != '\n' {
+		return "", ErrNotSealed
+	}
+	expected := string(trailer[len(selfChecksumPrefix) : len(trailer)-1])
+	if _, err := hex.DecodeString(expected); err != nil {
+		return "", ErrNotSealed
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, size); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != strings.ToLower(expected) {
+		return "", fmt.Errorf("%s is corrupt or has been tampered with (expected sha256=%s, got sha256=%s). "+
+			"Reinstall jabba from https://github.com/%s/releases", file, expected, sum, selfRepo)
+	}
+	return sum, nil
+}
@@ -0,0 +1,44 @@
+package command
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifySealed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-verify-self")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	binary := []byte(strings.Repeat("\x7fELF binary", 1000))
+	h := sha256.Sum256(binary)
+	checksum := hex.EncodeToString(h[:])
+	sealed := append(append([]byte{}, binary...), []byte("\njabba-sha256:"+checksum+"\n")...)
+	file := filepath.Join(dir, "jabba")
+	ioutil.WriteFile(file, sealed, 0755)
+	sum, err := verifySealed(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != checksum {
+		t.Fatalf("actual: %v != expected: %v", sum, checksum)
+	}
+	tampered := append([]byte{}, sealed...)
+	tampered[10] = 'X'
+	ioutil.WriteFile(file, tampered, 0755)
+	if _, err := verifySealed(file); err == nil || !strings.Contains(err.Error(), "tampered") {
+		t.Fatalf("actual: %v", err)
+	}
+	for _, content := range [][]byte{binary, sealed[:len(sealed)-100], nil} {
+		ioutil.WriteFile(file, content, 0755)
+		if _, err := verifySealed(file); err != ErrNotSealed {
+			t.Fatalf("actual: %v != expected: %v", err, ErrNotSealed)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// Verify checks installed JDK against the metadata recorded at the time of installation,
+// returning a short summary if nothing was changed.
+func Verify(selector string) (string, error) {
+	ver, meta, err := resolveWithMeta(selector)
+	if err != nil {
+		return "", err
+	}
+	problems, err := diffTree(filepath.Join(cfg.JDKDir(), ver), meta.Files)
+	if err != nil {
+		return "", err
+	}
+	if len(problems) != 0 {
+		return "", fmt.Errorf("%s does not match the one installed from %s:\n  %s",
+			ver, meta.URL, strings.Join(problems, "\n  "))
+	}
+	summary := fmt.Sprintf("%s is intact (%d files, archive sha256=%s", ver, len(meta.Files), meta.SHA256)
+	if meta.Signer != "" {
+		summary += ", signed by " + meta.Signer
+	}
+	return summary + ")", nil
+}
+
+// diffTree lists ("missing: <path>", "modified: <path>", "unexpected: <path>") differences between files under dir &
+// expected (see digestTree).
+func diffTree(dir string, expected map[string]string) ([]string, error) {
+	actual, err := digestTree(dir)
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for path, sum := range expected {
+		actualSum, ok := actual[path]
+		switch {
+		case !ok:
+			problems = append(problems, "missing: "+path)
+		case actualSum != sum:
+			problems = append(problems, "modified: "+path)
+		}
+	}
+	for path := range actual {
+		if _, ok := expected[path]; !ok {
+			problems = append(problems, "unexpected: "+path)
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
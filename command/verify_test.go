@@ -0,0 +1,50 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	ok := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	home, err := ioutil.TempDir("", "jabba-home")
+	ok(err)
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	dir := filepath.Join(home, "jdk", "1.8.0")
+	ok(os.MkdirAll(filepath.Join(dir, "bin"), 0755))
+	ok(ioutil.WriteFile(filepath.Join(dir, "bin", "java"), []byte("java"), 0755))
+	ok(ioutil.WriteFile(filepath.Join(dir, "release"), []byte("1.8"), 0644))
+	if _, err := Verify("1.8"); err == nil {
+		t.Fatal("expected Verify to fail (no metadata)")
+	}
+	ok(recordInstall(&InstallResult{Version: "1.8.0", Path: dir, URL: "https://example.com/jdk.tar.gz"}, nil))
+	if _, err := Verify("1.8"); err != nil {
+		t.Fatal(err)
+	}
+	ok(ioutil.WriteFile(filepath.Join(dir, "bin", "java"), []byte("evil"), 0755))
+	ok(os.Remove(filepath.Join(dir, "release")))
+	ok(ioutil.WriteFile(filepath.Join(dir, "bin", "javac"), nil, 0755))
+	_, err = Verify("1.8")
+	if err == nil {
+		t.Fatal("expected Verify to fail")
+	}
+	for _, expected := range []string{"modified: bin/java", "missing: release", "unexpected: bin/javac"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected \"%v\" to contain \"%v\"", err, expected)
+		}
+	}
+	_, err = Uninstall("1.8")
+	ok(err)
+	if _, err := os.Stat(metaFile("1.8.0")); !os.IsNotExist(err) {
+		t.Fatalf("expected metadata to be removed (%v)", err)
+	}
+}
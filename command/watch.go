@@ -0,0 +1,143 @@
+package command
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+// WatchEvent describes (re-)activation emitted by Watch.
+type WatchEvent struct {
+	// JDK version the project is pinned to (as written in the project file), "" if there is no pin
+	Selector string `json:"selector"`
+	// project file the selector came from
+	File string `json:"file,omitempty"`
+	// installed version the selector resolved to
+	Version string     `json:"version,omitempty"`
+	Change  *EnvChange `json:"-"`
+	Err     error      `json:"-"`
+}
+
+// MarshalJSON flattens Change & Err so that each event can be written as a single line of JSON.
+func (e WatchEvent) MarshalJSON() ([]byte, error) {
+	type event WatchEvent
+	v := struct {
+		event
+		Set   []string `json:"set,omitempty"`
+		Unset []string `json:"unset,omitempty"`
+		Error string   `json:"error,omitempty"`
+	}{event: event(e)}
+	if e.Change != nil {
+		v.Set, v.Unset = e.Change.Set, e.Change.Unset
+	}
+	if e.Err != nil {
+		v.Error = e.Err.Error()
+	}
+	return json.Marshal(v)
+}
+
+// Watch polls the project version file (see ProjectVersion) of dir every interval and calls emit whenever
+// the JDK the project resolves to changes (and once right away).
+// Removing the pin emits Deactivate()'s change. Errors (malformed project file, JDK that isn't installed, etc.)
+// are reported through WatchEvent.Err without stopping the watch.
+// Watch returns when stop is closed.
+func Watch(dir string, interval time.Duration, stop <-chan struct{}, emit func(WatchEvent)) {
+	var last *WatchEvent
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		e := resolveWatchEvent(dir)
+		if last == nil || e.Selector != last.Selector || e.File != last.File || e.Version != last.Version ||
+			errString(e.Err) != errString(last.Err) {
+			if e.Err == nil {
+				if e.Selector == "" {
+					e.Change, e.Err = Deactivate()
+				} else {
+					e.Change, e.Err = usePath(filepath.Join(cfg.JDKDir(), e.Version), e.Selector, nil)
+					if e.Err == nil {
+						recordHistory("use", e.Selector, e.Version)
+					}
+				}
+			}
+			emit(e)
+			last = &e
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func resolveWatchEvent(dir string) (e WatchEvent) {
+	e.Selector, e.File, e.Err = ProjectVersion(dir)
+	if e.Err != nil || e.Selector == "" {
+		return
+	}
+	resolved, err := ResolveAlias(e.Selector)
+	if err != nil {
+		e.Err = err
+		return
+	}
+	e.Version, e.Err = LsBestMatch(resolved)
+	return
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// WriteWatchEvent writes e to w, either as a single line of JSON (format "json") or
+// as a script for the specified shell (see EnvChange.Script) followed by an empty line.
+// Errors are written as shell comments (nothing is written for them to a file (see WriteWatchEventToFile)).
+func WriteWatchEvent(w io.Writer, e WatchEvent, format string) error {
+	b, err := formatWatchEvent(e, format)
+	if err != nil {
+		return err
+	}
+	if format != "json" {
+		b = append(b, '\n')
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// WriteWatchEventToFile atomically replaces file with e (see WriteWatchEvent), so that readers never observe
+// partially written content. Failed (re-)activations leave file unchanged.
+func WriteWatchEventToFile(file string, e WatchEvent, format string) error {
+	if e.Err != nil && format != "json" {
+		return nil
+	}
+	b, err := formatWatchEvent(e, format)
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func formatWatchEvent(e WatchEvent, format string) ([]byte, error) {
+	if format == "json" {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		return append(b, '\n'), nil
+	}
+	if e.Err != nil {
+		return []byte("# " + e.Err.Error() + "\n"), nil
+	}
+	return []byte(e.Change.Script(format) + "\n"), nil
+}
@@ -0,0 +1,110 @@
+package command
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shyiko/jabba/cfg"
+)
+
+func TestWatch(t *testing.T) {
+	var prevReadDir = readDir
+	defer func() { readDir = prevReadDir }()
+	readDir = func(dirname string) ([]os.FileInfo, error) {
+		return []os.FileInfo{FileInfoMock("1.8.0"), FileInfoMock("1.17.0")}, nil
+	}
+	dir, err := ioutil.TempDir("", "jabba-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rc := filepath.Join(dir, ".jabbarc")
+	if err := ioutil.WriteFile(rc, []byte("1.8"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan WatchEvent, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		Watch(dir, 10*time.Millisecond, stop, func(e WatchEvent) { events <- e })
+		close(done)
+	}()
+	next := func() WatchEvent {
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+		}
+		return WatchEvent{}
+	}
+	javaHome := func(ver string) string {
+		home := filepath.Join(cfg.JDKDir(), ver)
+		if runtime.GOOS == "darwin" {
+			home = filepath.Join(home, "Contents", "Home")
+		}
+		return "JAVA_HOME=" + home
+	}
+	e := next()
+	if e.Err != nil || e.Version != "1.8.0" || e.File != rc || e.Change.Set[1] != javaHome("1.8.0") {
+		t.Fatalf("actual: %+v", e)
+	}
+	ioutil.WriteFile(rc, []byte("1.17"), 0644)
+	if e = next(); e.Err != nil || e.Version != "1.17.0" || e.Change.Set[1] != javaHome("1.17.0") {
+		t.Fatalf("actual: %+v", e)
+	}
+	ioutil.WriteFile(rc, []byte("1.11"), 0644)
+	if e = next(); e.Err == nil || e.Selector != "1.11" {
+		t.Fatalf("actual: %+v", e)
+	}
+	os.Remove(rc)
+	if e = next(); e.Err != nil || e.Selector != "" || e.Change == nil {
+		t.Fatalf("actual: %+v", e)
+	}
+	close(stop)
+	<-done
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event: %+v", e)
+	default:
+	}
+}
+
+func TestWriteWatchEvent(t *testing.T) {
+	e := WatchEvent{Selector: "1.8", Version: "1.8.0", Change: &EnvChange{Set: []string{"JAVA_HOME=/jdk"}}}
+	var buf bytes.Buffer
+	if err := WriteWatchEvent(&buf, e, "json"); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"selector":"1.8","version":"1.8.0","set":["JAVA_HOME=/jdk"]}` + "\n"
+	if actual := buf.String(); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	buf.Reset()
+	if err := WriteWatchEvent(&buf, e, "bash"); err != nil {
+		t.Fatal(err)
+	}
+	expected = "export JAVA_HOME='/jdk'\n\n"
+	if actual := buf.String(); actual != expected {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+	file := filepath.Join(os.TempDir(), "jabba-watch-env.sh")
+	defer os.Remove(file)
+	if err := WriteWatchEventToFile(file, e, "bash"); err != nil {
+		t.Fatal(err)
+	}
+	failed := WatchEvent{Selector: "1.11", Err: os.ErrNotExist}
+	if err := WriteWatchEventToFile(file, failed, "bash"); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadFile(file)
+	if actual := string(b); !strings.HasPrefix(actual, "export JAVA_HOME=") {
+		t.Fatalf("actual: %v", actual)
+	}
+}
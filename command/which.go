@@ -1,23 +1,88 @@
 package command
 
 import (
+	"fmt"
 	"github.com/shyiko/jabba/cfg"
+	"github.com/shyiko/jabba/semver"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 func Which(selector string, home bool) (string, error) {
-	aliasValue := GetAlias(selector)
-	if aliasValue != "" {
-		selector = aliasValue
-	}
-	ver, err := LsBestMatch(selector)
+	ver, err := Resolve(selector)
 	if err != nil {
 		return "", err
 	}
-	path := filepath.Join(cfg.Dir(), "jdk", ver)
+	return jdkPath(ver, home), nil
+}
+
+func jdkPath(ver string, home bool) string {
+	path := filepath.Join(cfg.JDKDir(), ver)
 	if home && runtime.GOOS == "darwin" {
 		path = filepath.Join(path, "Contents", "Home")
 	}
+	return path
+}
+
+// WhichBin returns path to the tool (e.g. java, javac, jar) of the installed JDK matching the selector.
+func WhichBin(selector string, tool string) (string, error) {
+	if err := validateToolName(tool); err != nil {
+		return "", err
+	}
+	ver, err := Resolve(selector)
+	if err != nil {
+		return "", err
+	}
+	return binOf(ver, tool)
+}
+
+func validateToolName(tool string) error {
+	if tool == "" || strings.ContainsAny(tool, `/\`) {
+		return fmt.Errorf("invalid tool name \"%s\"", tool)
+	}
+	return nil
+}
+
+func binOf(ver string, tool string) (string, error) {
+	path := filepath.Join(jdkPath(ver, true), "bin", tool)
+	if runtime.GOOS == "windows" && filepath.Ext(tool) == "" {
+		path += ".exe"
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%s doesn't come with %s (%s not found)", ver, tool, path)
+	}
 	return path, nil
 }
+
+// Resolve returns installed version matching the selector (which can be an alias (see ResolveAlias)).
+// Versions the way vendors spell them (e.g. 17, zulu@11.0.2) are understood too (unless the selector matches as is).
+func Resolve(selector string) (string, error) {
+	vs, err := Ls()
+	if err != nil {
+		return "", err
+	}
+	return resolveIn(vs, selector, GetAlias)
+}
+
+func resolveIn(vs []*semver.Version, selector string, getAlias func(string) string) (string, error) {
+	selector, err := resolveAliasWith(selector, getAlias)
+	if err != nil {
+		return "", err
+	}
+	ver, err := LsBestMatchWithVersionSlice(vs, selector)
+	if err != nil {
+		vendor, version := "", selector
+		if i := strings.Index(selector, "@"); i != -1 {
+			vendor, version = selector[:i+1], selector[i+1:]
+		}
+		if foreign, ferr := fromForeignVersion(version); ferr == nil && foreign != version {
+			foreign = vendor + foreign
+			if ver, ferr := LsBestMatchWithVersionSlice(vs, foreign); ferr == nil {
+				return ver, nil
+			}
+		}
+	}
+	return ver, err
+}
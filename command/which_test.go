@@ -0,0 +1,57 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWhichBin(t *testing.T) {
+	home, err := ioutil.TempDir("", "jabba-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("JABBA_HOME", home)
+	defer os.Unsetenv("JABBA_HOME")
+	installFakeJDKs(t, home, "1.8.0", "zulu@1.17.2")
+	bin := filepath.Join(home, "jdk", "zulu@1.17.2")
+	if runtime.GOOS == "darwin" {
+		bin = filepath.Join(bin, "Contents", "Home")
+	}
+	bin = filepath.Join(bin, "bin")
+	java := filepath.Join(bin, "java")
+	if runtime.GOOS == "windows" {
+		java += ".exe"
+	}
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(java, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAlias("lts", "zulu@1.17"); err != nil {
+		t.Fatal(err)
+	}
+	// vendor-style major, range & alias
+	for _, selector := range []string{"zulu@17", "zulu@>=1.11", "lts"} {
+		actual, err := WhichBin(selector, "java")
+		if err != nil || actual != java {
+			t.Fatalf("%s: actual: %v (%v) != expected: %v", selector, actual, err, java)
+		}
+	}
+	if _, err := WhichBin("zulu@17", "javac"); err == nil {
+		t.Fatal("expected WhichBin to fail (no javac)")
+	}
+	if _, err := WhichBin("11", "java"); err == nil {
+		t.Fatal("expected WhichBin to fail (not installed)")
+	}
+	if _, err := WhichBin("zulu@17", "../java"); err == nil {
+		t.Fatal("expected WhichBin to fail (not a tool name)")
+	}
+	if ver, err := Resolve("8"); err != nil || ver != "1.8.0" {
+		t.Fatalf("actual: %v (%v) != expected: 1.8.0", ver, err)
+	}
+}
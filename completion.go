@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/shyiko/jabba/command"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// flag annotation holding values offered for completion
+const completionValues = "jabba_completion_values"
+
+// candidates for the positional arguments (by command name & position).
+// Candidate can be followed by a tab and a description.
+var positionalCompletions = map[string][]func() []string{
+	"install":   {remoteVersions},
+	"use":       {installedVersionsAndAliases},
+	"env":       {installedVersionsAndAliases},
+	"which":     {installedVersionsAndAliases},
+	"exec":      {installedVersionsAndAliases},
+	"try":       {remoteVersions},
+	"resolve":   {remoteVersions},
+	"uninstall": {installedVersions},
+	"upgrade":   {installedVersionsAndAliases},
+	"verify":    {installedVersions},
+	"ls":        {installedVersions},
+	"info":      {installedVersionsAndAliases},
+	"alias":     {aliases, installedVersions},
+	"unalias":   {aliases},
+	"unlink":    {systemLinks},
+	"pin":       {installedVersionsAndAliases},
+	"unpin":     {pinned},
+	"dedupe":    {installedVersions},
+	"import":    {func() []string { return []string{"sdkman"} }},
+	"home":      {func() []string { return []string{"ls", "use"} }, homeNames},
+}
+
+// commands accepting any number of arguments (completed with the last of positionalCompletions)
+var variadicCommands = map[string]bool{"install": true, "resolve": true, "pin": true, "unpin": true, "dedupe": true}
+
+func newCompletionCmds() []*cobra.Command {
+	var descriptions string
+	var ohMyZsh bool
+	completionCmd := &cobra.Command{
+		Use:   "completion [bash|zsh|fish|powershell]",
+		Short: "Print shell completion script",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell := filepath.Base(os.Getenv("SHELL"))
+			if len(args) != 0 {
+				shell = args[0]
+			}
+			bin, err := os.Executable()
+			if err != nil {
+				log.Fatal(err)
+			}
+			if descriptions != "on" && descriptions != "off" {
+				log.Fatal("--descriptions must be either \"on\" or \"off\" (got \"" + descriptions + "\")")
+			}
+			var script string
+			if ohMyZsh {
+				if shell != "zsh" {
+					log.Fatal("--oh-my-zsh can only be used with zsh")
+				}
+				script, err = command.OhMyZshPlugin(bin, descriptions == "on")
+			} else {
+				script, err = command.Completion(shell, bin, descriptions == "on")
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Print(script)
+			return nil
+		},
+		Example: "  eval \"$(jabba completion bash)\" # ~/.bashrc (after jabba.sh is sourced)\n" +
+			"  eval \"$(jabba completion zsh)\" # ~/.zshrc (after compinit)\n" +
+			"  jabba completion fish | source # ~/.config/fish/config.fish\n" +
+			"  jabba completion powershell | Out-String | Invoke-Expression # $PROFILE\n" +
+			"  jabba completion zsh --descriptions=off # candidates only\n" +
+			"  jabba completion zsh --oh-my-zsh > $ZSH_CUSTOM/plugins/jabba/jabba.plugin.zsh # replaces other jabba plugins",
+	}
+	completionCmd.Flags().StringVar(&descriptions, "descriptions", "on",
+		"Show descriptions of the candidates (\"on\" or \"off\") (bash never shows them)")
+	setCompletionValues(completionCmd.Flags(), "descriptions", "on", "off")
+	completionCmd.Flags().BoolVar(&ohMyZsh, "oh-my-zsh", false,
+		"Print oh-my-zsh plugin (shell integration + completion) instead (zsh only)")
+	var current, completeDescriptions string
+	completeCmd := &cobra.Command{
+		Use:    "__complete",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, candidate := range complete(rootCmd, args, current) {
+				if completeDescriptions == "off" {
+					candidate = candidateValue(candidate)
+				}
+				fmt.Println(candidate)
+			}
+			return nil
+		},
+	}
+	completeCmd.Flags().StringVar(&current, "current", "", "Word being completed")
+	completeCmd.Flags().StringVar(&completeDescriptions, "descriptions", "on", "Print descriptions of the candidates")
+	return []*cobra.Command{completionCmd, completeCmd}
+}
+
+// setCompletionValues sets values to offer when completing value of the flag.
+func setCompletionValues(flags *pflag.FlagSet, name string, values ...string) {
+	flags.SetAnnotation(name, completionValues, values)
+}
+
+// complete returns candidates for the word being completed (current) given the words preceding it
+// (command name excluded).
+func complete(root *cobra.Command, words []string, current string) []string {
+	cmd := root
+	var positional []string
+	var pendingFlag *pflag.Flag
+	for _, word := range words {
+		switch {
+		case pendingFlag != nil:
+			pendingFlag = nil
+		case word == "--":
+			// e.g. `jabba exec 1.8 -- <command>`
+			return nil
+		case strings.HasPrefix(word, "-"):
+			if flag := lookupFlag(cmd, word); flag != nil && flag.NoOptDefVal == "" && !strings.Contains(word, "=") {
+				pendingFlag = flag
+			}
+		case cmd == root:
+			if sub := findCommand(root, word); sub != nil {
+				cmd = sub
+			} else {
+				return nil
+			}
+		default:
+			positional = append(positional, word)
+		}
+	}
+	var candidates []string
+	switch {
+	case pendingFlag != nil:
+		candidates = pendingFlag.Annotations[completionValues]
+	case strings.HasPrefix(current, "-") && strings.Contains(current, "="):
+		split := strings.SplitN(current, "=", 2)
+		if flag := lookupFlag(cmd, split[0]); flag != nil {
+			for _, value := range flag.Annotations[completionValues] {
+				candidates = append(candidates, split[0]+"="+value)
+			}
+		}
+	case strings.HasPrefix(current, "-"):
+		visit := func(flag *pflag.Flag) {
+			if !flag.Hidden {
+				candidates = append(candidates, describe("--"+flag.Name, flag.Usage))
+			}
+		}
+		cmd.Flags().VisitAll(visit)
+		if cmd != root {
+			root.PersistentFlags().VisitAll(visit)
+		}
+		sort.Strings(candidates)
+	case cmd == root:
+		for _, sub := range root.Commands() {
+			if sub.IsAvailableCommand() {
+				candidates = append(candidates, describe(sub.Name(), sub.Short))
+			}
+		}
+	default:
+		completers := positionalCompletions[cmd.Name()]
+		if len(positional) < len(completers) {
+			candidates = completers[len(positional)]()
+		} else if variadicCommands[cmd.Name()] && len(completers) != 0 {
+			candidates = completers[len(completers)-1]()
+		}
+	}
+	var r []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidateValue(candidate), current) {
+			r = append(r, candidate)
+		}
+	}
+	return r
+}
+
+// describe appends description (if any) to the candidate.
+func describe(candidate string, description string) string {
+	if description == "" {
+		return candidate
+	}
+	// descriptions are expected to fit on a single line
+	return candidate + "\t" + strings.SplitN(description, "\n", 2)[0]
+}
+
+// candidateValue strips description (if any) off the candidate.
+func candidateValue(candidate string) string {
+	return strings.SplitN(candidate, "\t", 2)[0]
+}
+
+func findCommand(root *cobra.Command, name string) *cobra.Command {
+	for _, sub := range root.Commands() {
+		if sub.Name() == name {
+			return sub
+		}
+		for _, alias := range sub.Aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// lookupFlag finds flag by "--name[=value]" / "-n" (nil if there is no such flag).
+func lookupFlag(cmd *cobra.Command, word string) *pflag.Flag {
+	name := strings.SplitN(strings.TrimLeft(word, "-"), "=", 2)[0]
+	var r *pflag.Flag
+	for _, flags := range []*pflag.FlagSet{cmd.Flags(), cmd.PersistentFlags(), rootCmd.PersistentFlags()} {
+		flags.VisitAll(func(flag *pflag.Flag) {
+			if r == nil && (flag.Name == name && strings.HasPrefix(word, "--") ||
+				flag.Shorthand == name && !strings.HasPrefix(word, "--")) {
+				r = flag
+			}
+		})
+	}
+	return r
+}
+
+func installedVersions() []string {
+	vs, err := command.Ls()
+	if err != nil {
+		return nil
+	}
+	var r []string
+	for _, v := range vs {
+		r = append(r, v.String())
+	}
+	return r
+}
+
+func installedVersionsAndAliases() []string {
+	var r []string
+	for _, name := range aliases() {
+		r = append(r, describe(name, "alias of "+strings.TrimSpace(command.GetAlias(name))))
+	}
+	return append(r, installedVersions()...)
+}
+
+func aliases() []string {
+	names, _ := command.Aliases()
+	return names
+}
+
+func homeNames() []string {
+	var r []string
+	for _, home := range command.Homes() {
+		if home.Name != "" {
+			r = append(r, home.Name)
+		}
+	}
+	return r
+}
+
+func pinned() []string {
+	pins, _ := command.Pinned()
+	return pins
+}
+
+func systemLinks() []string {
+	var r []string
+	for _, ver := range installedVersions() {
+		if strings.HasPrefix(ver, "system@") {
+			r = append(r, ver)
+		}
+	}
+	return r
+}
+
+func remoteVersions() []string {
+	goos, err := command.TargetOS(runtime.GOOS, "")
+	if err != nil {
+		return nil
+	}
+	vs, err := command.CachedRemoteVersions(goos, command.HostArch())
+	if err != nil {
+		return nil
+	}
+	installed := make(map[string]bool)
+	for _, v := range installedVersions() {
+		installed[v] = true
+	}
+	r := make([]string, len(vs))
+	for i, v := range vs {
+		r[i] = v
+		if installed[v] {
+			r[i] = describe(v, "installed")
+		}
+	}
+	return r
+}
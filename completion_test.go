@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestComplete(t *testing.T) {
+	prevRootCmd := rootCmd
+	defer func() { rootCmd = prevRootCmd }()
+	rootCmd = &cobra.Command{Use: "jabba"}
+	rootCmd.PersistentFlags().Bool("offline", false, "")
+	installCmd := &cobra.Command{Use: "install", Run: func(*cobra.Command, []string) {}}
+	installCmd.Flags().String("arch", "", "")
+	installCmd.Flags().StringP("output", "o", "", "")
+	installCmd.Flags().Bool("any", false, "")
+	setCompletionValues(installCmd.Flags(), "arch", "amd64", "arm64", "386")
+	rootCmd.AddCommand(installCmd, &cobra.Command{Use: "ls", Run: func(*cobra.Command, []string) {}})
+	for _, scenario := range []struct {
+		words    []string
+		current  string
+		expected []string
+	}{
+		{nil, "i", []string{"install"}},
+		{[]string{"--offline"}, "l", []string{"ls"}},
+		{[]string{"install"}, "--a", []string{"--any", "--arch"}},
+		{[]string{"install"}, "--", []string{"--any", "--arch", "--offline", "--output"}},
+		{[]string{"install", "--arch"}, "a", []string{"amd64", "arm64"}},
+		{[]string{"install"}, "--arch=3", []string{"--arch=386"}},
+		// value of -o is not a positional argument
+		{[]string{"install", "-o", "/tmp/jdk", "--arch"}, "", []string{"amd64", "arm64", "386"}},
+		{[]string{"unknown"}, "", nil},
+	} {
+		actual := complete(rootCmd, scenario.words, scenario.current)
+		if !reflect.DeepEqual(actual, scenario.expected) {
+			t.Fatalf("%v %q: actual: %v != expected: %v", scenario.words, scenario.current, actual, scenario.expected)
+		}
+	}
+}
@@ -3,17 +3,18 @@ module github.com/shyiko/jabba
 go 1.15
 
 require (
-	github.com/Masterminds/semver v1.3.1
+	github.com/BurntSushi/toml v0.4.1
 	github.com/Sirupsen/logrus v0.10.0
 	github.com/hashicorp/go-rootcerts v0.0.0-20160503143440-6bb64b370b90
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
+	github.com/klauspost/compress v1.13.6
 	github.com/mitchellh/go-homedir v0.0.0-20160301183130-981ab348d865
 	github.com/mitchellh/ioprogress v0.0.0-20150521211556-816395526456
 	github.com/spf13/cobra v0.0.0-20160322171042-c678ff029ee2
 	github.com/spf13/pflag v0.0.0-20151218134703-7f60f83a2c81
 	github.com/stretchr/testify v1.7.0 // indirect
 	github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8
-	golang.org/x/sys v0.0.0-20160322232243-afce3de5756c // indirect
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v2 v2.0.0-20160928153709-a5b47d31c556
 )
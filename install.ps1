@@ -46,18 +46,7 @@ if the problem persists - please create a ticket at https://github.com/shyiko/ja
 @"
 `$env:JABBA_HOME="$jabbaHome"
 
-function jabba
-{
-    `$fd3=`$([System.IO.Path]::GetTempFileName())
-    `$command="& '$jabbaHome\bin\jabba.exe' `$args --fd3 ```"`$fd3```""
-    & { `$env:JABBA_SHELL_INTEGRATION="ON"; Invoke-Expression `$command }
-    `$fd3content=`$(Get-Content `$fd3)
-    if (`$fd3content) {
-        `$expression=`$fd3content.replace("export ","```$env:").replace("unset ","Remove-Item env:") -join "``n"
-        if (-not `$expression -eq "") { Invoke-Expression `$expression }
-    }
-    Remove-Item -Force `$fd3
-}
+$(& { $env:JABBA_HOME="$jabbaHome"; & $jabbaHome\bin\jabba.exe shell-integration --shell=pwsh } | Out-String)
 "@ | Out-File $jabbaHome/jabba.ps1
 
 $sourceJabba="if (Test-Path `"$jabbaHome\jabba.ps1`") { . `"$jabbaHome\jabba.ps1`" }"
@@ -92,6 +92,13 @@ case "$OSTYPE" in
     esac
     BINARY_URL="https://github.com/shyiko/jabba/releases/download/${JABBA_VERSION}/jabba-${JABBA_VERSION}-linux-${OSARCH}"
     ;;
+    freebsd*)
+    case "$(uname -m)" in
+        arm64|aarch64) OSARCH=arm64 ;;
+        *) OSARCH=amd64 ;;
+    esac
+    BINARY_URL="https://github.com/shyiko/jabba/releases/download/${JABBA_VERSION}/jabba-${JABBA_VERSION}-freebsd-${OSARCH}"
+    ;;
     *)
     echo "Unsupported OS $OSTYPE. If you believe this is an error -
 please create a ticket at https://github.com/shyiko/jabba/issues."
@@ -137,18 +144,7 @@ echo "# This file is intended to be \"sourced\" (i.e. \". ~/.jabba/jabba.sh\")"
 echo ""
 echo "export JABBA_HOME=\"$JABBA_HOME_TO_EXPORT\""
 echo ""
-echo "jabba() {"
-echo "    local fd3=\$(mktemp /tmp/jabba-fd3.XXXXXX)"
-echo "    (JABBA_SHELL_INTEGRATION=ON $JABBA_HOME_TO_EXPORT/bin/jabba \"\$@\" 3>| \${fd3})"
-echo "    local exit_code=\$?"
-echo "    eval \$(cat \${fd3})"
-echo "    rm -f \${fd3}"
-echo "    return \${exit_code}"
-echo "}"
-echo ""
-echo "if [ ! -z \"\$(jabba alias default)\" ]; then"
-echo "    jabba use default"
-echo "fi"
+JABBA_HOME="$JABBA_HOME" ${JABBA_HOME}/bin/jabba shell-integration --shell=bash
 } > ${JABBA_HOME}/jabba.sh
 
 SOURCE_JABBA="\n[ -s \"$JABBA_HOME/jabba.sh\" ] && source \"$JABBA_HOME/jabba.sh\""
@@ -195,16 +191,7 @@ echo "# This file is intended to be \"sourced\" (i.e. \". ~/.jabba/jabba.fish\")
 echo ""
 echo "set -xg JABBA_HOME \"$JABBA_HOME_TO_EXPORT\""
 echo ""
-echo "function jabba"
-echo "    set fd3 (mktemp /tmp/jabba-fd3.XXXXXX)"
-echo "    env JABBA_SHELL_INTEGRATION=ON $JABBA_HOME_TO_EXPORT/bin/jabba \$argv 3> \$fd3"
-echo "    set exit_code \$status"
-echo "    eval (cat \$fd3 | sed \"s/^export/set -xg/g\" | sed \"s/^unset/set -e/g\" | tr '=' ' ' | sed \"s/:/\\\" \\\"/g\" | tr '\\\\n' ';')"
-echo "    rm -f \$fd3"
-echo "    return \$exit_code"
-echo "end"
-echo ""
-echo "[ ! -z (echo (jabba alias default)) ]; and jabba use default"
+JABBA_HOME="$JABBA_HOME" ${JABBA_HOME}/bin/jabba shell-integration --shell=fish
 } > ${JABBA_HOME}/jabba.fish
 
 FISH_SOURCE_JABBA="\n[ -s \"$JABBA_HOME/jabba.fish\" ]; and source \"$JABBA_HOME/jabba.fish\""
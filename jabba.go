@@ -1,21 +1,29 @@
 package main
 
 import (
-	"bytes"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
-
-	yaml "gopkg.in/yaml.v2"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	rootcerts "github.com/hashicorp/go-rootcerts"
+	"github.com/shyiko/jabba/cfg"
 	"github.com/shyiko/jabba/command"
+	"github.com/shyiko/jabba/command/flock"
 	"github.com/shyiko/jabba/semver"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -23,10 +31,11 @@ import (
 
 var version string
 var rootCmd *cobra.Command
+var verbosity verbosityValue
 
 func init() {
+	// see configureLogging
 	log.SetFormatter(&simpleFormatter{})
-	// todo: make it configurable through the command line
 	log.SetLevel(log.InfoLevel)
 
 	tlsConfig := &tls.Config{}
@@ -41,19 +50,11 @@ func init() {
 	defTransport.TLSClientConfig = tlsConfig
 }
 
-type simpleFormatter struct{}
-
-func (f *simpleFormatter) Format(entry *log.Entry) ([]byte, error) {
-	b := &bytes.Buffer{}
-	fmt.Fprintf(b, "%s ", entry.Message)
-	for k, v := range entry.Data {
-		fmt.Fprintf(b, "%s=%+v ", k, v)
-	}
-	b.WriteByte('\n')
-	return b.Bytes(), nil
-}
-
 func main() {
+	if version != "" {
+		command.JabbaVersion = version
+	}
+	osNames := strings.Join(command.SupportedOSs(), ", ")
 	rootCmd = &cobra.Command{
 		Use:  "jabba",
 		Long: "Java Version Manager (https://github.com/shyiko/jabba).",
@@ -65,10 +66,29 @@ func main() {
 			return nil
 		},
 	}
+	// snapshot `jabba which` & `jabba current` are served from (nil if there is none yet) (see command.ReadState)
+	var state *command.State
+	fastPreRun := func(cmd *cobra.Command, args []string) {
+		applyHomeFlag(cmd)
+		if state = command.ReadState(); state != nil {
+			cfg.Use(state.Config)
+			if !cfg.VerifySelf() {
+				logFormat, _ := cmd.Flags().GetString("log-format")
+				logFile, _ := cmd.Flags().GetString("log-file")
+				if err := configureLogging(int(verbosity), logFormat, logFile); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
+		}
+		rootCmd.PersistentPreRun(cmd, args)
+	}
 	var whichHome bool
+	var whichBin string
 	whichCmd := &cobra.Command{
-		Use:   "which [version]",
-		Short: "Display path to installed JDK",
+		Use:              "which [version]",
+		Short:            "Display path to installed JDK",
+		PersistentPreRun: fastPreRun,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var ver string
 			if len(args) == 0 {
@@ -79,7 +99,34 @@ func main() {
 			} else {
 				ver = args[0]
 			}
-			dir, _ := command.Which(ver, whichHome)
+			var dir string
+			if whichBin != "" {
+				// unlike plain `jabba which`, which prints nothing if there is no match (as it always did),
+				// --bin is meant for JAVACMD=$(jabba which --bin java 17) and so fails loudly
+				whichBinFn := command.WhichBin
+				if state != nil {
+					whichBinFn = state.WhichBin
+				}
+				bin, err := whichBinFn(ver, whichBin)
+				if err != nil {
+					log.Fatal(err)
+				}
+				dir = bin
+			} else if state != nil {
+				dir, _ = state.Which(ver, whichHome)
+			} else {
+				dir, _ = command.Which(ver, whichHome)
+			}
+			if outputFormat(cmd) == "json" {
+				var jdk *command.JDK
+				if dir != "" {
+					resolved, _ := command.Resolve(ver)
+					jdk = describeInstalled(resolved)
+					jdk.Path = dir
+				}
+				printJSON(jdk)
+				return nil
+			}
 			if dir != "" {
 				fmt.Println(dir)
 			}
@@ -88,156 +135,2245 @@ func main() {
 	}
 	whichCmd.Flags().BoolVar(&whichHome, "home", false,
 		"Account for platform differences so that value could be used as JAVA_HOME (e.g. append \"/Contents/Home\" on macOS)")
+	whichCmd.Flags().StringVar(&whichBin, "bin", "",
+		"Print path to the specified tool (e.g. java, javac, jar) instead (fail if JDK isn't installed or has no such tool)")
 	var customInstallDestination string
+	var installJSON bool
+	var installProject string
+	var installOS string
+	var installArch string
+	var installLibc string
+	var installAny bool
+	var installAllowEmulation bool
+	var installCDS bool
+	var installNoPostInstall bool
+	var installIgnoreCompat bool
+	var installShowPlan bool
+	var installPlanOnly bool
+	var installDryRun bool
+	var installFromFile string
+	var installFromDir string
+	var installJobs int
+	var installInteractive bool
 	installCmd := &cobra.Command{
-		Use:   "install [version to install]",
+		Use:   "install [version to install...]",
 		Short: "Download and install JDK",
+		Long: "Download and install JDK.\n\n" +
+			"When multiple versions are specified (or listed in --from-file) archives are downloaded concurrently\n" +
+			"(up to --jobs at a time) and extracted one by one. Failure to install one JDK doesn't stop the others\n" +
+			"(exit status is non-zero if any of them failed). Current shell is left as is.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var ver string
-			if len(args) == 0 {
-				ver = rc().JDK
-				if ver == "" {
-					return pflag.ErrHelp
+			selectors := args
+			if installInteractive {
+				if len(args) > 1 || installFromFile != "" || installProject != "" {
+					log.Fatal("-i cannot be combined with multiple versions, --from-file or --project")
 				}
-			} else {
-				ver = args[0]
+				selector, err := pickRemote(cmd, args, command.InstallOptions{OS: installOS, Arch: installArch,
+					Libc: installLibc, Channel: channel(cmd), FromDir: installFromDir})
+				if err != nil {
+					log.Fatal(err)
+				}
+				if selector == "" {
+					return nil
+				}
+				selectors = []string{selector}
+			}
+			if installFromFile != "" {
+				fromFile, err := command.ReadSelectors(installFromFile)
+				if err != nil {
+					log.Fatal(err)
+				}
+				selectors = append(selectors, fromFile...)
+			}
+			if installProject != "" && len(selectors) != 0 {
+				log.Fatal("--project cannot be combined with versions to install (or --from-file)")
+			}
+			if len(selectors) == 0 {
+				dir := installProject
+				if dir == "" {
+					if rc().JDK == "" {
+						return pflag.ErrHelp
+					}
+					dir = "."
+				}
+				selector, file, err := command.ProjectSelector(dir)
+				if err != nil {
+					log.Fatal(err)
+				}
+				log.Info(file, " pins ", selector)
+				selectors = []string{selector}
+			}
+			for i, selector := range selectors {
+				// picked version is fully qualified already
+				if !installInteractive {
+					selectors[i] = qualify(cmd, selector)
+				}
+			}
+			if installOS != "" && installOS != runtime.GOOS && customInstallDestination == "" {
+				log.Fatal("--os " + installOS + " requires --output (JDKs for other OSs cannot be used on this machine)")
+			}
+			if len(selectors) > 1 && customInstallDestination != "" {
+				log.Fatal("--output can only be used to install a single JDK")
+			}
+			opts := command.InstallOptions{
+				Dst:            customInstallDestination,
+				OS:             installOS,
+				Arch:           installArch,
+				Libc:           installLibc,
+				Any:            installAny,
+				Channel:        channel(cmd),
+				AllowEmulation: installAllowEmulation,
+				CDS:            installCDS,
+				NoPostInstall:  installNoPostInstall,
+				FromDir:        installFromDir,
+				IgnoreCompat:   installIgnoreCompat,
+				// see command.InstallAll
+				NoStream: len(selectors) > 1,
+			}
+			if installDryRun {
+				var resolutions []*command.Resolution
+				for _, selector := range selectors {
+					r, err := command.ResolveRemote(selector, opts)
+					if err != nil {
+						log.Fatal(err)
+					}
+					resolutions = append(resolutions, r)
+				}
+				if installJSON {
+					printJSON(resolutions)
+				} else {
+					printResolutions(resolutions)
+				}
+				return nil
+			}
+			if installShowPlan || installPlanOnly {
+				var plans []*command.InstallPlan
+				for _, selector := range selectors {
+					plan, err := command.PlanInstall(selector, opts)
+					if err != nil {
+						log.Fatal(err)
+					}
+					if customInstallDestination == "" && !plan.AlreadyInstalled {
+						plan.Steps = append(plan.Steps, "update links in "+cfg.JDKDir())
+						if len(selectors) == 1 {
+							plan.Steps = append(plan.Steps, "switch current shell to "+plan.Version)
+						}
+					}
+					plans = append(plans, plan)
+				}
+				switch {
+				case installPlanOnly && installJSON && len(plans) == 1:
+					printJSON(plans[0])
+				case installPlanOnly && installJSON:
+					printJSON(plans)
+				default:
+					// stdout is reserved for --json
+					w := os.Stderr
+					if installPlanOnly {
+						w = os.Stdout
+					}
+					for i, plan := range plans {
+						if i != 0 {
+							fmt.Fprintln(w)
+						}
+						printPlan(w, plan)
+					}
+				}
+				if installPlanOnly {
+					return nil
+				}
+			}
+			lockHome()
+			if len(selectors) > 1 {
+				outcomes := command.InstallAll(selectors, opts, installJobs)
+				if err := command.LinkLatest(); err != nil {
+					log.Fatal(err)
+				}
+				if installJSON {
+					printJSON(outcomes)
+				}
+				failed := 0
+				for _, outcome := range outcomes {
+					if outcome.Error != "" {
+						log.Error(outcome.Selector, ": ", outcome.Error)
+						failed++
+					}
+				}
+				if failed != 0 {
+					log.Fatalf("%d of %d JDKs failed to install", failed, len(outcomes))
+				}
+				return nil
 			}
-			ver, err := command.Install(ver, customInstallDestination)
+			result, err := command.Install(selectors[0], opts)
 			if err != nil {
 				log.Fatal(err)
 			}
+			if installJSON {
+				printJSON(result)
+			}
 			if customInstallDestination == "" {
 				if err := command.LinkLatest(); err != nil {
 					log.Fatal(err)
 				}
-				return use(ver)
+				return use(result.Version)
 			} else {
 				return nil
 			}
 		},
 		Example: "  jabba install 1.8\n" +
 			"  jabba install ~1.8.73 # same as \">=1.8.73 <1.9.0\"\n" +
-			"  jabba install 1.8.73=dmg+http://.../jdk-9-ea+110_osx-x64_bin.dmg",
+			"  jabba install 1.8.73=dmg+http://.../jdk-9-ea+110_osx-x64_bin.dmg\n" +
+			"  jabba install 1.8.73=tgz+http://.../jdk.tar.gz#sha256=<hex> # see 'jabba checksum'\n" +
+			"  jabba install sha256:<hex> # index entry of the archive with that checksum\n" +
+			"  jabba install zulu@1.17 --os windows --arch amd64 -o ./image/jdk # pre-stage JDK for another platform\n" +
+			"  jabba install zulu@1.17 --plan-only --json # see what would be downloaded & where it would be extracted\n" +
+			"  jabba install 21 --vendor temurin # same as temurin@1.21 (see \"default_vendor\" in config.yaml)\n" +
+			"  jabba install zulu@1.17 temurin@1.21 --jobs 2\n" +
+			"  jabba install --from-file versions.txt # one version per line\n" +
+			"  jabba install -i temurin # pick one of temurin releases\n" +
+			"  jabba install zulu@1.17 --from-dir /mnt/jdk-artifacts # e.g. zulu@1.17.0-35_linux_amd64.tar.gz\n" +
+			"  git clone https://example.com/repo.git && cd repo && jabba install --project",
 	}
+	installCmd.Flags().StringVar(&installProject, "project", "",
+		"Install JDK the project (directory (current one if value is omitted) or its closest parent with .jabbarc, "+
+			".java-version or .tool-versions) pins (archive locked in jabba.lock next to it, if there is one). "+
+			"Same as running \"jabba install\" without arguments in project directory")
+	installCmd.Flags().Lookup("project").NoOptDefVal = "."
 	installCmd.Flags().StringVarP(&customInstallDestination, "output", "o", "",
 		"Custom destination (any JDK outside of $JABBA_HOME/jdk is considered to be unmanaged, i.e. not available to jabba ls, use, etc. (unless `jabba link`ed))")
-	var trimTo string
-	lsCmd := &cobra.Command{
-		Use:   "ls",
-		Short: "List installed versions",
+	installCmd.Flags().BoolVar(&installJSON, "json", false,
+		"Print version, path, URL & (measured) sha256 of the archive as JSON")
+	installCmd.Flags().StringVar(&installOS, "os", "",
+		"Operating System ("+osNames+") (defaults to "+runtime.GOOS+"). Other than "+runtime.GOOS+
+			" requires --output")
+	installCmd.Flags().StringVar(&installArch, "arch", "",
+		"Architecture (amd64, arm64, 386) (defaults to \"arch\" in config.yaml or "+command.HostArch()+
+			" (with fallback to amd64 (Rosetta 2) on darwin/arm64 if there is no native build))")
+	installCmd.Flags().StringVar(&installLibc, "libc", "",
+		"C standard library (glibc, musl) (auto-detected by default (with fallback to glibc builds if there are no musl ones))")
+	installCmd.Flags().BoolVar(&installAny, "any", false,
+		"Install the latest matching version even if index recommends another one")
+	installCmd.Flags().BoolVar(&installAllowEmulation, "allow-emulation", false,
+		"Fall back to amd64 JDK on Windows on ARM (x64 emulation) if there is no native (arm64) build")
+	installCmd.Flags().StringVar(&installFromFile, "from-file", "",
+		"File listing versions to install (one per line, # starts a comment, - means stdin)")
+	installCmd.Flags().StringVar(&installFromDir, "from-dir", "",
+		"Directory to pick archives (named <version>_<os>_<arch>.<ext> and/or accompanied by <archive>.json) "+
+			"from instead of the index (no network access)")
+	installCmd.Flags().IntVar(&installJobs, "jobs", 4, "How many archives to download concurrently")
+	installCmd.Flags().BoolVarP(&installInteractive, "interactive", "i", false,
+		"Pick version to install from a filterable list of remote versions (matching the range / text, if given)")
+	installCmd.Flags().BoolVar(&installCDS, "cds", false,
+		"Generate CDS archive (java -Xshare:dump) once JDK is installed (faster JVM startup) "+
+			"(defaults to \"cds\" in config.yaml)")
+	installCmd.Flags().BoolVar(&installNoPostInstall, "no-post-install", false,
+		"Don't apply post_install hooks (CA certificates, java.security, run) of config.yaml")
+	installCmd.Flags().BoolVar(&installIgnoreCompat, "ignore-compat", false,
+		"Install JDK even if it's known not to work on this OS version (e.g. JDK 6/7 .dmg on macOS 11+)")
+	installCmd.Flags().BoolVar(&installShowPlan, "show-plan", false,
+		"Print what is going to be downloaded & where JDK is going to be extracted before doing it")
+	installCmd.Flags().BoolVar(&installPlanOnly, "plan-only", false,
+		"Print the plan (as JSON if --json is specified) without installing anything")
+	installCmd.Flags().BoolVar(&installDryRun, "dry-run", false,
+		"Print exact version(s) & URL(s) selector(s) resolve to (as JSON if --json is specified) without "+
+			"downloading anything (same as \"jabba resolve\")")
+	var upgradePurge bool
+	var upgradeAny bool
+	var upgradeJSON bool
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade [alias or version]",
+		Short: "Install the latest release of the JDK line (e.g. zulu@1.17) and repoint aliases to it",
+		Long: "Install the latest release of the line (qualifier + major version) JDK referenced by an alias\n" +
+			"(all of them if none is specified) or a version belongs to, repointing aliases (default included) that\n" +
+			"referenced the old JDK.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var r *semver.Range
-			if len(args) > 0 {
-				var err error
-				r, err = semver.ParseRange(args[0])
+			targets := args
+			if len(targets) == 0 {
+				aliases, err := command.Aliases()
 				if err != nil {
 					log.Fatal(err)
 				}
+				if len(aliases) == 0 {
+					return pflag.ErrHelp
+				}
+				targets = aliases
 			}
-			vs, err := command.Ls()
+			lockHome()
+			current := command.Current()
+			var results []*command.UpgradeResult
+			for _, target := range targets {
+				result, err := command.Upgrade(target, command.UpgradeOptions{Purge: upgradePurge, Any: upgradeAny})
+				if err != nil {
+					log.Fatal(err)
+				}
+				results = append(results, result)
+			}
+			if err := command.LinkLatest(); err != nil {
+				log.Fatal(err)
+			}
+			if upgradeJSON {
+				printJSON(results)
+			}
+			for _, result := range results {
+				if result.From == current && result.To != current {
+					return use(result.To)
+				}
+			}
+			return nil
+		},
+		Example: "  jabba upgrade # every alias (default included)\n" +
+			"  jabba upgrade default\n" +
+			"  jabba upgrade zulu@1.17 --purge # uninstall superseded zulu@1.17.x",
+	}
+	upgradeCmd.Flags().BoolVar(&upgradePurge, "purge", false, "Uninstall superseded JDK")
+	upgradeCmd.Flags().BoolVar(&upgradeAny, "any", false,
+		"Upgrade to the latest release even if index recommends another one")
+	upgradeCmd.Flags().BoolVar(&upgradeJSON, "json", false, "Print upgraded versions (from, to, aliases) as JSON")
+	var execOpts command.ExecOptions
+	execCmd := &cobra.Command{
+		Use:   "exec [version to use] -- <command> [args...]",
+		Short: "Run command with PATH & JAVA_HOME pointing to specific JDK (current shell is left unchanged)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ver string
+			dash := cmd.ArgsLenAtDash()
+			if dash == 0 {
+				ver = rc().JDK
+				if ver == "" {
+					return pflag.ErrHelp
+				}
+			} else if dash == 1 || (dash == -1 && len(args) > 1) {
+				ver, args = args[0], args[1:]
+			} else {
+				return pflag.ErrHelp
+			}
+			code, err := command.Exec(ver, execOpts, args)
 			if err != nil {
 				log.Fatal(err)
 			}
-			if trimTo != "" {
-				vs = semver.VersionSlice(vs).TrimTo(parseTrimTo(trimTo))
+			os.Exit(code)
+			return nil
+		},
+		Example: "  jabba exec 1.8 -- java -version\n" +
+			"  jabba exec --install temurin@1.17 -- mvn package\n" +
+			"  jabba exec -- ./gradlew build # version is taken from .jabbarc\n" +
+			"  jabba exec --isolated --keep-env=CLASSPATH 1.17 -- ./run-tests.sh\n" +
+			"  jabba exec --env-file=auto --env-file-var='MATRIX_*' 1.17 -- ./ci.sh # ci.sh loads $JABBA_ENV_FILE",
+	}
+	execCmd.Flags().BoolVar(&execOpts.Install, "install", false, "Install JDK if it's not installed yet")
+	execCmd.Flags().BoolVar(&execOpts.Isolated, "isolated", false,
+		"Remove JAVA_OPTS, JAVA_TOOL_OPTIONS, _JAVA_OPTIONS, JDK_JAVA_OPTIONS & CLASSPATH from command's environment")
+	execCmd.Flags().StringSliceVar(&execOpts.Keep, "keep-env", nil,
+		"Environment variable --isolated should leave as is (can be repeated)")
+	execCmd.Flags().StringVar(&execOpts.EnvFile, "env-file", command.EnvFileNever,
+		"Move variables attached to the JDK (see \"jabba setenv\") & --env-file-var ones into a file (NUL-terminated "+
+			"KEY=VALUE entries) JABBA_ENV_FILE points to, removed once command exits: \"auto\" (only if environment "+
+			"would exceed OS limits otherwise (E2BIG)), \"always\" (same as --env-file) or \"never\"")
+	execCmd.Flags().Lookup("env-file").NoOptDefVal = command.EnvFileAlways
+	setCompletionValues(execCmd.Flags(), "env-file", command.EnvFileAuto, command.EnvFileAlways,
+		command.EnvFileNever)
+	execCmd.Flags().StringSliceVar(&execOpts.EnvFileVars, "env-file-var", nil,
+		"Variable (glob pattern (e.g. MATRIX_*)) --env-file can move (can be repeated)")
+	var tryAny bool
+	tryCmd := &cobra.Command{
+		Use:   "try [version] -- <command> [args...]",
+		Short: "Install JDK into a temporary directory, run command with it & remove JDK afterwards",
+		Long: "Install JDK into a temporary directory, run command with PATH & JAVA_HOME pointing to it and remove JDK\n" +
+			"once command exits (downloaded archive is kept in the cache (if any)). Installed JDKs, aliases, etc. are\n" +
+			"left untouched.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 || cmd.ArgsLenAtDash() > 1 {
+				return pflag.ErrHelp
 			}
-			for _, v := range vs {
-				if r != nil && !r.Contains(v) {
-					continue
+			code, err := command.Try(qualify(cmd, args[0]),
+				command.InstallOptions{Any: tryAny, Channel: channel(cmd)}, args[1:])
+			if err != nil {
+				log.Fatal(err)
+			}
+			os.Exit(code)
+			return nil
+		},
+		Example: "  jabba try 1.22 -- java -version\n" +
+			"  jabba try temurin@1.21 -- ./gradlew test",
+	}
+	tryCmd.Flags().BoolVar(&tryAny, "any", false, "Try the latest matching version even if index recommends another one")
+	var useProfiles []string
+	var useInstall bool
+	var useStrict bool
+	var useInteractive bool
+	useCmd := &cobra.Command{
+		Use:   "use [version to use]",
+		Short: "Modify PATH & JAVA_HOME to use specific JDK",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := command.UseOptions{Profiles: useProfiles, Install: cfg.AutoInstall(), InstallRangesOnly: true,
+				Strict: useStrict}
+			if cmd.Flags().Changed("install") {
+				opts.Install = useInstall
+				opts.InstallRangesOnly = false
+			}
+			fail := func(err error) {
+				if useStrict {
+					// so that nothing keeps running on the JDK that was in use before
+					if deactivation, err := command.Deactivate(); err == nil {
+						printForShellToEval(deactivation)
+					}
+				}
+				log.Fatal(err)
+			}
+			var ver string
+			if useInteractive {
+				selector, err := pickLocal(args)
+				if err != nil {
+					fail(err)
+				}
+				if selector == "" {
+					return nil
+				}
+				ver = selector
+			} else if len(args) == 0 {
+				ver = rc().JDK
+				opts.ProjectDir = "."
+				if ver == "" {
+					if useStrict {
+						fail(errors.New("No version specified (and there is no .jabbarc / .java-version / .tool-versions)"))
+					}
+					return pflag.ErrHelp
+				}
+			} else {
+				ver = args[0]
+			}
+			change, err := command.UseWithOptions(ver, opts)
+			if err != nil {
+				fail(err)
+			}
+			printForShellToEval(change)
+			return nil
+		},
+		Example: "  jabba use 1.8\n" +
+			"  jabba use ~1.8.73 # same as \">=1.8.73 <1.9.0\"\n" +
+			"  jabba use 1.17 --profile debug,async-profiler # see \"profiles\" in config.yaml\n" +
+			"  jabba use --install --strict # JDK specified in .jabbarc (e.g. in CI)\n" +
+			"  jabba use -i # pick one of installed JDKs",
+	}
+	useCmd.Flags().StringSliceVar(&useProfiles, "profile", nil,
+		"Profile(s) (environment variables / PATH entries defined in config.yaml) to apply on top of JDK")
+	useCmd.Flags().BoolVar(&useInstall, "install", false,
+		"Install JDK if there is none matching the selector (defaults to \"auto_install\" in config.yaml)")
+	useCmd.Flags().BoolVarP(&useInteractive, "interactive", "i", false,
+		"Pick JDK from a filterable list of installed ones (matching the range / text, if given)")
+	useCmd.Flags().BoolVar(&useStrict, "strict", false,
+		"Fail if there is no version to use or JDK is broken (not just when it can't be found), deactivating "+
+			"JDK that was in use (so that nothing keeps running on it)")
+	var envFormat string
+	envCmd := &cobra.Command{
+		Use:   "env [version]",
+		Short: "Print JAVA_HOME & PATH to use JDK with (e.g. in CI) (no shell integration required)",
+		Long: "Print environment (JAVA_HOME & PATH with JDK's bin prepended, variables attached with `jabba setenv`)\n" +
+			"JDK should be used with in the specified format (version defaults to the one in .jabbarc / .java-version /\n" +
+			".tool-versions).\n\n" +
+			"--format=github-actions appends to $GITHUB_ENV / $GITHUB_PATH when running inside GitHub Actions\n" +
+			"(subsequent steps of the job use JDK), --format=azure prints Azure Pipelines logging commands.\n" +
+			"Format defaults to github-actions inside GitHub Actions, azure inside Azure Pipelines and basename\n" +
+			"of $SHELL otherwise.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var ver string
+			if len(args) == 0 {
+				ver = rc().JDK
+				if ver == "" {
+					return pflag.ErrHelp
+				}
+			} else {
+				ver = args[0]
+			}
+			change, err := command.Env(ver)
+			if err != nil {
+				log.Fatal(err)
+			}
+			format := envFormat
+			if format == "" {
+				format = defaultEnvFormat()
+			}
+			if format == "github-actions" {
+				exported, err := change.ExportToGitHubActions()
+				if err != nil {
+					log.Fatal(err)
+				}
+				if exported {
+					log.Info("Exported JAVA_HOME & PATH to $GITHUB_ENV / $GITHUB_PATH")
+					return nil
+				}
+			}
+			out, err := change.Export(format)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(out)
+			return nil
+		},
+		Example: "  eval \"$(jabba env 1.17 --format=sh)\"\n" +
+			"  jabba env 1.17 --format=fish | source\n" +
+			"  jabba env zulu@1.21 --format=github-actions # in a GitHub Actions step\n" +
+			"  jabba env --format=azure # JDK specified in .jabbarc",
+	}
+	envCmd.Flags().StringVar(&envFormat, "format", "", strings.Join(command.EnvFormats, ", "))
+	setCompletionValues(envCmd.Flags(), "format", command.EnvFormats...)
+	var hookShell string
+	var hookResolve bool
+	hookCmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Print shell hook that switches JDK automatically when entering/leaving a project directory",
+		Long: "Print shell hook that runs `jabba use` when entering a directory containing (or nested in a directory " +
+			"containing) .jabbarc, .java-version or .tool-versions and restores JDK that was in use before when " +
+			"leaving it.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if hookResolve {
+				fmt.Println(rc().JDK)
+				return nil
+			}
+			if hookShell == "" {
+				hookShell = filepath.Base(os.Getenv("SHELL"))
+			}
+			bin, err := os.Executable()
+			if err != nil {
+				log.Fatal(err)
+			}
+			hook, err := command.Hook(hookShell, bin)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Print(hook)
+			return nil
+		},
+		Example: "  eval \"$(jabba hook --shell=bash)\" # ~/.bashrc (after jabba.sh is sourced)\n" +
+			"  eval \"$(jabba hook --shell=zsh)\" # ~/.zshrc\n" +
+			"  jabba hook --shell=fish | source # ~/.config/fish/config.fish",
+	}
+	hookCmd.Flags().StringVar(&hookShell, "shell", "", "bash, zsh or fish (defaults to basename of $SHELL)")
+	// used by the hook itself
+	hookCmd.Flags().BoolVar(&hookResolve, "resolve", false, "Print version specified in the project version file")
+	hookCmd.Flags().MarkHidden("resolve")
+	var shellIntegrationShell string
+	shellIntegrationCmd := &cobra.Command{
+		Use:   "shell-integration",
+		Short: "Print shell integration code (`jabba` function (required by `jabba use`, `deactivate`, etc.))",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if shellIntegrationShell == "" {
+				shellIntegrationShell = filepath.Base(os.Getenv("SHELL"))
+			}
+			bin, err := os.Executable()
+			if err != nil {
+				log.Fatal(err)
+			}
+			script, err := command.ShellIntegration(shellIntegrationShell, bin)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Print(script)
+			return nil
+		},
+		Example: "  eval \"$(jabba shell-integration --shell=bash)\" # ~/.bashrc\n" +
+			"  jabba shell-integration --shell=fish | source # ~/.config/fish/config.fish\n" +
+			"  jabba shell-integration --shell=pwsh | Out-String | Invoke-Expression # $PROFILE\n" +
+			"  jabba shell-integration --shell=nushell | save -f ~/.jabba/jabba.nu # source it from config.nu",
+	}
+	shellIntegrationCmd.Flags().StringVar(&shellIntegrationShell, "shell", "",
+		"bash, zsh, fish, pwsh or nushell (defaults to basename of $SHELL)")
+	var sizeBudgetMax string
+	sizeBudgetCmd := &cobra.Command{
+		Use:   "size-budget",
+		Short: "Fail if total size of installed JDKs exceeds the budget (listing the largest ones)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if sizeBudgetMax == "" {
+				sizeBudgetMax = cfg.Load().SizeBudget
+			}
+			if sizeBudgetMax == "" {
+				log.Fatal("Budget is not set (use --max or \"size_budget\" in config.yaml)")
+			}
+			budget, err := command.ParseSize(sizeBudgetMax)
+			if err != nil {
+				log.Fatal(err)
+			}
+			report, err := command.SizeBudget(budget)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(report)
+			} else {
+				fmt.Printf("%s of %s used\n", command.FormatSize(report.Total), command.FormatSize(report.Budget))
+				for _, jdk := range report.JDKs {
+					fmt.Printf("%8s  %s\n", command.FormatSize(jdk.Size), jdk.Version)
+				}
+			}
+			if report.Exceeded() {
+				log.Fatal("Size budget exceeded by " + command.FormatSize(report.Total-report.Budget))
+			}
+			return nil
+		},
+		Example: "  jabba size-budget --max=1.5G\n" +
+			"  jabba size-budget # budget is taken from \"size_budget\" in config.yaml",
+	}
+	sizeBudgetCmd.Flags().StringVar(&sizeBudgetMax, "max", "", "Budget (e.g. 500M, 2G) (defaults to \"size_budget\" in config.yaml)")
+	duCmd := &cobra.Command{
+		Use:   "du [dir...]",
+		Short: "Show disk usage of installed JDKs (total & reclaimable included)",
+		Long: "Show disk usage of installed JDKs (largest first), total and reclaimable space (JDKs that are not\n" +
+			"referenced by aliases (default included), current shell or .jabbarc / .java-version / .tool-versions\n" +
+			"under the specified directories, i.e. the ones `jabba prune` would remove).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := command.Du(args)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(report)
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, jdk := range report.JDKs {
+				referencedBy := jdk.ReferencedBy
+				if referencedBy == "" {
+					referencedBy = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", command.FormatSize(jdk.Size), jdk.Version, referencedBy)
+			}
+			fmt.Fprintf(w, "%s\ttotal\t\n", command.FormatSize(report.Total))
+			fmt.Fprintf(w, "%s\treclaimable\t(jabba prune%s)\n", command.FormatSize(report.Reclaimable),
+				strings.TrimRight(" "+strings.Join(args, " "), " "))
+			if report.Cache != 0 {
+				fmt.Fprintf(w, "%s\tdownload cache\t(%s)\n", command.FormatSize(report.Cache), cfg.CacheDir())
+			}
+			w.Flush()
+			return nil
+		},
+		Example: "  jabba du\n" +
+			"  jabba du ~/projects # JDKs referenced by projects under ~/projects are not reclaimable\n" +
+			"  jabba du --output=json",
+	}
+	infoCmd := &cobra.Command{
+		Use:   "info [version]",
+		Short: "Show metadata (vendor, Java version, source URL, sha256, platform, size, etc.) of installed JDK",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return pflag.ErrHelp
+			}
+			info, err := command.Info(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(info)
+			} else {
+				printInfo(info)
+			}
+			return nil
+		},
+		Example: "  jabba info default\n" +
+			"  jabba info zulu@1.17 --output=json",
+	}
+	var setenvUnset []string
+	setenvCmd := &cobra.Command{
+		Use:   "setenv [version or alias] [NAME=value...]",
+		Short: "Attach environment variables to installed JDK / alias (exported by use, exec & env)",
+		Long: "Attach environment variables (e.g. JAVA_TOOL_OPTIONS) to installed JDK (recorded in its metadata) or\n" +
+			"an alias (if argument is one), so that `jabba use`, `jabba exec` & `jabba env` export them alongside\n" +
+			"JAVA_HOME (the ones attached to the alias override the ones attached to the JDK, profiles override\n" +
+			"both). Values can reference other variables (e.g. $JAVA_HOME of the JDK), mind the quotes.\n\n" +
+			"GraalVM JDKs get GRAALVM_HOME=$JAVA_HOME when they are installed.\n\n" +
+			"Without NAME=value (and --unset) variables attached to JDK / alias are listed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return pflag.ErrHelp
+			}
+			var env *command.JDKEnv
+			var err error
+			if len(args) == 1 && len(setenvUnset) == 0 {
+				env, err = command.GetJDKEnv(args[0])
+			} else {
+				vars := make(map[string]string)
+				for _, name := range setenvUnset {
+					vars[name] = ""
+				}
+				for _, kv := range args[1:] {
+					i := strings.Index(kv, "=")
+					if i <= 0 || i == len(kv)-1 {
+						log.Fatal("\"" + kv + "\" is not a valid NAME=value pair (use --unset NAME to remove variable)")
+					}
+					vars[kv[:i]] = kv[i+1:]
 				}
-				fmt.Println(v)
+				lockHome()
+				env, err = command.SetJDKEnv(args[0], vars)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(env)
+				return nil
+			}
+			var keys []string
+			for key := range env.Env {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Println(key + "=" + env.Env[key])
+			}
+			return nil
+		},
+		Example: "  jabba setenv zulu@1.17.0 JAVA_TOOL_OPTIONS=-Xss4m\n" +
+			"  jabba setenv work/backend 'JDK_JAVA_OPTIONS=-Djavax.net.ssl.trustStore=$JAVA_HOME/lib/security/corp'\n" +
+			"  jabba setenv graalvm@21.0.1 # list\n" +
+			"  jabba setenv zulu@1.17.0 --unset JAVA_TOOL_OPTIONS",
+	}
+	setenvCmd.Flags().StringSliceVar(&setenvUnset, "unset", nil, "Variable(s) to remove")
+	var historySince string
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show log of JDK installs & activations",
+		Long: "Show log of JDK installs & activations (use, exec) recorded in $JABBA_HOME/history.log.\n\n" +
+			"Recording is off by default (set \"history: true\" in config.yaml (\"locked\" in machine config to\n" +
+			"enforce it) or JABBA_HISTORY=1). Entries older than \"history_retention\" (e.g. 90d) are dropped.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var since time.Time
+			if historySince != "" {
+				var err error
+				if since, err = command.ParseSince(historySince); err != nil {
+					log.Fatal(err)
+				}
+			}
+			if !cfg.History() {
+				log.Info("History is not being recorded (set \"history: true\" in config.yaml or JABBA_HISTORY=1)")
+			}
+			events, err := command.History(since)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(events)
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TIME\tEVENT\tVERSION\tSELECTOR\tUSER")
+			for _, e := range events {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Event,
+					e.Version, e.Selector, e.User)
+			}
+			w.Flush()
+			return nil
+		},
+		Example: "  jabba history --since 7d\n" +
+			"  jabba history --since 2024-01-01 --output=json",
+	}
+	historyCmd.Flags().StringVar(&historySince, "since", "",
+		"Show only events recorded since the date (e.g. 2024-01-31) or within the period (e.g. 24h, 7d)")
+	var versionCheck bool
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print version of jabba",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Println(version)
+			if !versionCheck {
+				return nil
+			}
+			release, newer, err := command.CheckSelfUpdate(version)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if newer {
+				log.Info("jabba ", release.Version, " is available (run `jabba self-update` to upgrade)")
+			} else {
+				log.Info("jabba is up to date")
+			}
+			return nil
+		},
+		Example: "  jabba version --check",
+	}
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, "Check whether there is a newer release of jabba")
+	var selfUpdateForce bool
+	selfUpdateCmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Upgrade jabba to the latest release",
+		Long: "Download the latest release of jabba (https://github.com/shyiko/jabba/releases),\n" +
+			"verify its sha256 & replace running executable with it.\n\n" +
+			"Set \"self_update: false\" in config.yaml (\"locked\" in machine config to enforce it) or\n" +
+			"JABBA_SELF_UPDATE=0 to disable self-update & update checks (e.g. when jabba is provisioned by other means).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			release, updated, err := command.SelfUpdate(version, selfUpdateForce)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if updated {
+				log.Info("jabba ", release.Version, " installed")
+			} else {
+				log.Info("jabba is up to date (", version, ")")
+			}
+			return nil
+		},
+	}
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateForce, "force", false, "Reinstall even if running the latest release")
+	verifySelfCmd := &cobra.Command{
+		Use:   "verify-self",
+		Short: "Check jabba binary against the checksum embedded into it (detects truncated / tampered binaries)",
+		Long: "Check jabba binary against the checksum embedded into it (release binaries only) to detect truncated\n" +
+			"or tampered binaries (e.g. when jabba is distributed through intermediate mirrors).\n\n" +
+			"Set \"verify_self: true\" in config.yaml (or JABBA_VERIFY_SELF=1) to run the check every time jabba starts.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exe, sum, err := command.VerifySelf()
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(exe + ": OK (sha256=" + sum + ")")
+			return nil
+		},
+	}
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check jabba home (links, installs, aliases), environment, registry & temp files for problems",
+		Long: "Check jabba home (links, installs, aliases), environment, registry & temp files for problems.\n" +
+			"Exit code is 1 if any error was found (warnings are not considered to be errors).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			findings := command.Doctor()
+			failed := false
+			for _, finding := range findings {
+				failed = failed || finding.Status == "error"
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(findings)
+			} else {
+				for _, finding := range findings {
+					line := fmt.Sprintf("[%s] %s", finding.Status, finding.Check)
+					if finding.Message != "" {
+						line += ": " + finding.Message
+					}
+					fmt.Println(line)
+					if finding.Fix != "" {
+						fmt.Println("  fix: " + finding.Fix)
+					}
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	var ideTargets []string
+	var ideFile string
+	var idePrint bool
+	ideSyncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Register installed JDKs with IntelliJ IDEA (jdk.table.xml) and/or VS Code (java.configuration.runtimes)",
+		Long: "Register installed JDKs with IntelliJ IDEA (options/jdk.table.xml of every IntelliJIdea*/IdeaIC*\n" +
+			"configuration directory) and/or VS Code (\"java.configuration.runtimes\" in user settings.json).\n\n" +
+			"IntelliJ JDKs are named after jabba versions (e.g. temurin@1.17.0), so that projects referencing them keep\n" +
+			"working across syncs. VS Code gets one runtime per execution environment (e.g. JavaSE-17).\n" +
+			"Only JDKs from jabba's JDK directory are added / removed, everything else is left as is.\n" +
+			"Restart IDE for changes to take effect.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return pflag.ErrHelp
+			}
+			if ideFile != "" && len(ideTargets) != 1 {
+				log.Fatal("--file requires a single --target")
+			}
+			toolchains, err := command.Toolchains()
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, target := range ideTargets {
+				var files []string
+				var update func(file string, toolchains []command.Toolchain) ([]byte, error)
+				switch target {
+				case "idea":
+					files, update = command.IdeaJdkTableFiles(), command.IdeaJdkTable
+				case "vscode":
+					files, update = []string{command.VSCodeSettingsFile()}, command.VSCodeSettings
+				default:
+					log.Fatalf("Unsupported --target \"%s\" (expected one of %v)", target, command.IDETargets)
+				}
+				if ideFile != "" {
+					files = []string{ideFile}
+				}
+				if len(files) == 0 {
+					log.Warn("No IntelliJ IDEA configuration directory found (use --file to specify jdk.table.xml)")
+				}
+				for _, file := range files {
+					content, err := update(file, toolchains)
+					if err != nil {
+						log.Fatal(err)
+					}
+					if idePrint {
+						os.Stdout.Write(content)
+						continue
+					}
+					if err := command.WriteToolchainsFile(file, content); err != nil {
+						log.Fatal(err)
+					}
+					log.Info(file, " updated (", len(toolchains), " JDK(s))")
+				}
+			}
+			return nil
+		},
+		Example: "  jabba ide sync\n" +
+			"  jabba ide sync --target=vscode --print\n" +
+			"  jabba ide sync --target=idea --file=~/.config/JetBrains/IntelliJIdea2023.3/options/jdk.table.xml",
+	}
+	ideSyncCmd.Flags().StringSliceVar(&ideTargets, "target", command.IDETargets,
+		"IDE(s) to register JDKs with (idea, vscode)")
+	ideSyncCmd.Flags().StringVar(&ideFile, "file", "", "Configuration file to update (instead of the default one(s))")
+	ideSyncCmd.Flags().BoolVar(&idePrint, "print", false, "Print updated file(s) instead of writing them")
+	setCompletionValues(ideSyncCmd.Flags(), "target", command.IDETargets...)
+	ideCmd := &cobra.Command{
+		Use:   "ide",
+		Short: "Manage IDE JDK configuration",
+	}
+	ideCmd.AddCommand(ideSyncCmd)
+	cdsRegenerateCmd := &cobra.Command{
+		Use:   "regenerate [version]",
+		Short: "(Re)generate CDS archive of the installed JDK (java -Xshare:dump)",
+		Long: "(Re)generate default class data sharing archive (the one AppCDS / dynamic archives are layered on top\n" +
+			"of) of the installed JDK with `java -Xshare:dump`, so that JVM starts faster. JDK's metadata is updated\n" +
+			"accordingly (`jabba verify` keeps passing).\n\n" +
+			"Use `jabba install --cds` (or \"cds: true\" in config.yaml) to do it right after JDK is installed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return pflag.ErrHelp
+			}
+			lockHome()
+			archives, err := command.RegenerateCDS(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, archive := range archives {
+				fmt.Println(archive)
+			}
+			return nil
+		},
+		Example: "  jabba cds regenerate zulu@1.17\n" +
+			"  jabba cds regenerate default",
+	}
+	cdsCmd := &cobra.Command{
+		Use:   "cds",
+		Short: "Manage class data sharing (CDS) archives of installed JDKs",
+	}
+	cdsCmd.AddCommand(cdsRegenerateCmd)
+	postInstallCmd := &cobra.Command{
+		Use:   "post-install [version]",
+		Short: "Apply post_install hooks of config.yaml to the installed JDK",
+		Long: "Apply post_install hooks (import CA certificates into cacerts, set java.security properties, run a\n" +
+			"command) to the installed JDK (e.g. the one installed before hooks were configured). JDK's metadata is\n" +
+			"updated accordingly (`jabba verify` keeps passing).\n\n" +
+			"Hooks are applied to every JDK `jabba install` installs (unless --no-post-install is specified).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return pflag.ErrHelp
+			}
+			lockHome()
+			ver, err := command.ApplyPostInstall(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Info("Applied post_install hooks to ", ver)
+			return nil
+		},
+		Example: "  jabba post-install zulu@1.17\n" +
+			"  jabba post-install default",
+	}
+	mirrorsTestCmd := &cobra.Command{
+		Use:   "test [url...]",
+		Short: "Probe registry URLs (index & its mirrors) and print them ranked by latency",
+		Long: "Probe every registry URL (--registry / JABBA_INDEX / \"registry\" in config.yaml, unless URLs are\n" +
+			"specified) - HEAD (latency), then GET of the index (bypassing the cache), which has to be a valid index\n" +
+			"matching sha256 advertised by the server (X-Checksum-Sha256), if any, and the index served by the\n" +
+			"first healthy URL (mirrors out of sync are reported as such).\n\n" +
+			"Exit status is non-zero if any of the URLs is unhealthy.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			urls := args
+			if len(urls) == 0 {
+				urls = cfg.Registry()
+			}
+			reports := command.CheckMirrors(urls)
+			if outputFormat(cmd) == "json" {
+				printJSON(reports)
+			} else {
+				printMirrorReports(reports)
+			}
+			for _, r := range reports {
+				if !r.OK {
+					os.Exit(1)
+				}
+			}
+			return nil
+		},
+		Example: "  jabba mirrors test\n" +
+			"  jabba mirrors test https://artifactory.example.com/jabba/index.json https://mirror.example.com/jabba/index.json\n" +
+			"  jabba mirrors test --output=json",
+	}
+	mirrorsCmd := &cobra.Command{
+		Use:   "mirrors",
+		Short: "Check registry mirrors",
+	}
+	mirrorsCmd.AddCommand(mirrorsTestCmd)
+	var indexLintOpts command.IndexLintOptions
+	indexLintCmd := &cobra.Command{
+		Use:   "lint [file]",
+		Short: "Validate index file (e.g. before it's published)",
+		Long: "Validate index file (\"-\" for stdin): structure (os -> arch -> jdk@<vendor> -> version), duplicate\n" +
+			"keys / versions / URLs, unparsable versions, URL qualifiers & fragments, missing (or malformed) checksums\n" +
+			"and, with --probe, URLs that are not reachable (HEAD).\n\n" +
+			"Exit status is non-zero if any errors (not just warnings) were found.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return pflag.ErrHelp
+			}
+			findings, err := command.LintIndexFile(args[0], indexLintOpts)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(findings)
+			} else {
+				printIndexFindings(findings)
+			}
+			for _, f := range findings {
+				if f.Severity == "error" {
+					os.Exit(1)
+				}
+			}
+			return nil
+		},
+		Example: "  jabba index lint index.json\n" +
+			"  jabba index lint --probe --output=json index.json",
+	}
+	indexLintCmd.Flags().BoolVar(&indexLintOpts.Probe, "probe", false, "Check that every URL is reachable (HEAD)")
+	indexLintCmd.Flags().IntVar(&indexLintOpts.Jobs, "jobs", 8, "How many URLs to probe concurrently")
+	indexCmd := &cobra.Command{
+		Use:   "index",
+		Short: "Author index files",
+	}
+	indexCmd.AddCommand(indexLintCmd)
+	pinCmd := &cobra.Command{
+		Use:   "pin [version]",
+		Short: "Protect installed JDK from prune, uninstall & upgrade --purge (list pinned JDKs if none is given)",
+		Long: "Pin installed JDK (e.g. the one an old product branch needs), so that it's never removed by\n" +
+			"`jabba prune`, `jabba uninstall` or `jabba upgrade --purge` (until `jabba unpin`ned).\n" +
+			"Pinned JDKs are marked as such in `jabba ls` output.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				pins, err := command.Pinned()
+				if err != nil {
+					log.Fatal(err)
+				}
+				for _, pin := range pins {
+					fmt.Println(pin)
+				}
+				return nil
+			}
+			lockHome()
+			for _, arg := range args {
+				ver, err := command.Pin(arg)
+				if err != nil {
+					log.Fatal(err)
+				}
+				log.Info("Pinned ", ver)
+			}
+			return nil
+		},
+		Example: "  jabba pin zulu@1.8.402\n" +
+			"  jabba pin default",
+	}
+	unpinCmd := &cobra.Command{
+		Use:   "unpin [version]",
+		Short: "Remove the pin (see `jabba pin`)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return pflag.ErrHelp
+			}
+			lockHome()
+			for _, arg := range args {
+				ver, err := command.Unpin(arg)
+				if err != nil {
+					log.Fatal(err)
+				}
+				log.Info("Unpinned ", ver)
+			}
+			return nil
+		},
+		Example: "  jabba unpin zulu@1.8.402",
+	}
+	var dedupeMode string
+	dedupeCmd := &cobra.Command{
+		Use:   "dedupe [version...]",
+		Short: "Replace identical files of installed JDKs with clones / hard links of a single copy",
+		Long: "Replace files (64K and up) of installed JDKs (all of them unless specified) that are identical to\n" +
+			"the ones of other JDKs with copy-on-write clones (APFS, btrfs, xfs) or hard links of a single copy kept in\n" +
+			"$JABBA_HOME/jdk/.store (checksums recorded at install time are used, so JDKs installed by older versions\n" +
+			"of jabba are skipped).\n\n" +
+			"Use \"dedupe: auto\" in config.yaml (or JABBA_DEDUPE=auto) to do it on every install and `jabba gc`\n" +
+			"to remove copies no JDK references anymore.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lockHome()
+			results, err := command.Dedupe(args, dedupeMode)
+			if err != nil {
+				log.Fatal(err)
+			}
+			var files int
+			var saved int64
+			for _, r := range results {
+				files += r.Files
+				saved += r.Saved
+				if r.Files != 0 {
+					fmt.Printf("%s: %d file(s), %s\n", r.Version, r.Files, command.FormatSize(r.Saved))
+				}
+			}
+			fmt.Printf("Deduplicated %d file(s) (%s saved)\n", files, command.FormatSize(saved))
+			return nil
+		},
+		Example: "  jabba dedupe\n" +
+			"  jabba dedupe --mode=hardlink zulu@1.17 temurin@1.17",
+	}
+	dedupeCmd.Flags().StringVar(&dedupeMode, "mode", defaultDedupeMode(),
+		"auto (clone, falling back to hard links), clone or hardlink")
+	setCompletionValues(dedupeCmd.Flags(), "mode", "auto", "clone", "hardlink")
+	var gcDryRun bool
+	gcCmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove deduplicated files no installed JDK references anymore",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if gcDryRun {
+				r, err := command.PlanGC()
+				if err != nil {
+					log.Fatal(err)
+				}
+				printCleanReport(cmd, r)
+				return nil
+			}
+			lockHome()
+			result, err := command.GC()
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(result)
+				return nil
+			}
+			fmt.Printf("Removed %d file(s) (%s)\n", result.Removed, command.FormatSize(result.Size))
+			return nil
+		},
+	}
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false,
+		"Print files that would be removed (& space that would be reclaimed) without removing anything")
+	var cacheOlderThan string
+	var cacheDryRun bool
+	cacheCleanCmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove cached archives & leftover temp files",
+		Long: "Remove archives kept in the download cache (cache_dir / keep_downloads in config.yaml) along with\n" +
+			"whatever interrupted downloads / installs left in $JABBA_HOME/tmp (the ones older than a day are\n" +
+			"removed automatically). Archives (temp files) that are in use by another jabba process are kept.\n\n" +
+			"With --older-than, only archives that weren't used (installed from) for that long are removed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var maxAge time.Duration
+			if cacheOlderThan != "" {
+				var err error
+				if maxAge, err = cfg.ParseDuration(cacheOlderThan); err != nil || maxAge <= 0 {
+					log.Fatal("\"" + cacheOlderThan + "\" is not a valid --older-than (expected something like 30d)")
+				}
+			}
+			clean := command.CleanCache
+			if cacheDryRun {
+				clean = command.PlanCleanCache
+			}
+			r, err := clean(maxAge)
+			if err != nil {
+				log.Fatal(err)
+			}
+			printCleanReport(cmd, r)
+			return nil
+		},
+		Example: "  jabba cache clean\n" +
+			"  jabba cache clean --older-than 30d --dry-run",
+	}
+	cacheCleanCmd.Flags().BoolVar(&cacheDryRun, "dry-run", false,
+		"Print files that would be removed (& space that would be reclaimed) without removing anything")
+	cacheCleanCmd.Flags().StringVar(&cacheOlderThan, "older-than", "",
+		"Only remove archives that haven't been used for this long (e.g. 30d)")
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage download cache & temp files",
+	}
+	cacheCmd.AddCommand(cacheCleanCmd)
+	var peekOpts command.InstallOptions
+	peekCmd := &cobra.Command{
+		Use:   "peek [version or url]",
+		Short: "Show layout, \"release\" file & size of the archive without installing it",
+		Long: "Show top-level layout, \"release\" file (JAVA_VERSION, IMPLEMENTOR, ...), number of entries & size\n" +
+			"of the archive version resolves to (the same way `jabba install` does it) without installing it\n" +
+			"(e.g. to sanity-check index entries).\n\n" +
+			"Only the central directory (& \"release\" file) of zip archives is downloaded (server has to support\n" +
+			"range requests). tar archives are streamed through (never written to disk).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return pflag.ErrHelp
+			}
+			peekOpts.Channel = channel(cmd)
+			r, err := command.Peek(qualify(cmd, args[0]), peekOpts)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(r)
+				return nil
+			}
+			size := "unknown"
+			if r.Size >= 0 {
+				size = command.FormatSize(r.Size) + " (" + command.FormatSize(r.Downloaded) + " downloaded)"
+			}
+			for _, kv := range [][2]string{
+				{"Version", r.Version},
+				{"Source", r.URL},
+				{"Type", r.Type},
+				{"Size", size},
+				{"Entries", fmt.Sprintf("%d (%s unpacked)", r.Entries, command.FormatSize(r.UnpackedSize))},
+				{"Java version", r.Release["JAVA_VERSION"]},
+				{"Vendor", r.Release["IMPLEMENTOR"]},
+			} {
+				if kv[1] != "" {
+					fmt.Printf("%-14s%s\n", kv[0]+":", kv[1])
+				}
+			}
+			fmt.Println("Layout:")
+			for _, entry := range r.Layout {
+				fmt.Println("  " + entry)
+			}
+			return nil
+		},
+		Example: "  jabba peek temurin@1.21\n" +
+			"  jabba peek --os=windows zulu@1.17 --output=json\n" +
+			"  jabba peek 1.8.0-custom=zip+https://example.com/jdk.zip",
+	}
+	peekCmd.Flags().StringVar(&peekOpts.OS, "os", "", "Operating System ("+osNames+") (defaults to "+
+		runtime.GOOS+")")
+	peekCmd.Flags().StringVar(&peekOpts.Arch, "arch", "", "Architecture (amd64, arm64, 386) (defaults to \"arch\" "+
+		"in config.yaml or "+command.HostArch()+")")
+	peekCmd.Flags().StringVar(&peekOpts.Libc, "libc", "", "C standard library (glibc, musl) (auto-detected by default)")
+	var resolveOpts command.InstallOptions
+	resolveCmd := &cobra.Command{
+		Use:   "resolve [version...]",
+		Short: "Print exact version & URL `jabba install` would pick (without downloading anything)",
+		Long: "Print exact version, platform, sha256 & URL of the archive each selector resolves to (the same way\n" +
+			"`jabba install` does it) without downloading anything (e.g. to check what a range is going to pick\n" +
+			"or to log/pin the resolution in a build script).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				ver := rc().JDK
+				if ver == "" {
+					return pflag.ErrHelp
+				}
+				args = []string{ver}
+			}
+			resolveOpts.Channel = channel(cmd)
+			var resolutions []*command.Resolution
+			for _, arg := range args {
+				r, err := command.ResolveRemote(qualify(cmd, arg), resolveOpts)
+				if err != nil {
+					log.Fatal(err)
+				}
+				resolutions = append(resolutions, r)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(resolutions)
+				return nil
+			}
+			printResolutions(resolutions)
+			return nil
+		},
+		Example: "  jabba resolve \"zulu@~1.17\"\n" +
+			"  jabba resolve temurin@1.21 --os=windows --output=json\n" +
+			"  jabba install zulu@1.17 --dry-run # same thing",
+	}
+	resolveCmd.Flags().StringVar(&resolveOpts.OS, "os", "", "Operating System ("+osNames+") (defaults to "+
+		runtime.GOOS+")")
+	resolveCmd.Flags().StringVar(&resolveOpts.Arch, "arch", "", "Architecture (amd64, arm64, 386) (defaults to "+
+		"\"arch\" in config.yaml or "+command.HostArch()+")")
+	resolveCmd.Flags().StringVar(&resolveOpts.Libc, "libc", "",
+		"C standard library (glibc, musl) (auto-detected by default)")
+	resolveCmd.Flags().BoolVar(&resolveOpts.Any, "any", false,
+		"Resolve to the latest matching version even if index recommends another one")
+	resolveCmd.Flags().StringVar(&resolveOpts.FromDir, "from-dir", "",
+		"Directory to resolve against instead of the index (see \"jabba install --from-dir\")")
+	var mavenToolchainsFile, gradlePropertiesFile string
+	var toolchainsPrint bool
+	toolchainsCmd := &cobra.Command{
+		Use:   "toolchains [maven|gradle...]",
+		Short: "Register installed JDKs with Maven (toolchains.xml) and/or Gradle (gradle.properties)",
+		Long: "Generate/update Maven toolchains.xml entries (type, version, vendor & jdkHome) and Gradle's\n" +
+			"org.gradle.java.installations.paths from the set of installed JDKs (both by default).\n\n" +
+			"Only entries added by jabba (toolchains with <id>jabba:...</id>, paths under jabba's JDK directory)\n" +
+			"are replaced, everything else is left as is. Re-run after installing / uninstalling JDKs.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"maven", "gradle"}
+			}
+			toolchains, err := command.Toolchains()
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, tool := range args {
+				var file string
+				var content []byte
+				switch tool {
+				case "maven":
+					if mavenToolchainsFile == "" {
+						mavenToolchainsFile = command.MavenToolchainsFile()
+					}
+					file = mavenToolchainsFile
+					content, err = command.MavenToolchains(file, toolchains)
+				case "gradle":
+					if gradlePropertiesFile == "" {
+						gradlePropertiesFile = command.GradlePropertiesFile()
+					}
+					file = gradlePropertiesFile
+					content, err = command.GradleProperties(file, toolchains)
+				default:
+					return pflag.ErrHelp
+				}
+				if err != nil {
+					log.Fatal(err)
+				}
+				if toolchainsPrint {
+					os.Stdout.Write(content)
+					continue
+				}
+				if err := command.WriteToolchainsFile(file, content); err != nil {
+					log.Fatal(err)
+				}
+				log.Info(file, " updated (", len(toolchains), " JDK(s))")
+			}
+			return nil
+		},
+		Example: "  jabba toolchains\n" +
+			"  jabba toolchains maven --print\n" +
+			"  jabba toolchains gradle --gradle-file=gradle.properties # project-level",
+	}
+	toolchainsCmd.Flags().StringVar(&mavenToolchainsFile, "maven-file", "",
+		"Maven toolchains file (defaults to ~/.m2/toolchains.xml)")
+	toolchainsCmd.Flags().StringVar(&gradlePropertiesFile, "gradle-file", "",
+		"Gradle properties file (defaults to $GRADLE_USER_HOME/gradle.properties or ~/.gradle/gradle.properties)")
+	toolchainsCmd.Flags().BoolVar(&toolchainsPrint, "print", false, "Print updated file(s) instead of writing them")
+	var sdkmanDir string
+	var importMove bool
+	importCmd := &cobra.Command{
+		Use:   "import [sdkman|lockfile]",
+		Short: "Install JDKs listed in the lockfile (see 'jabba export') or import JDKs installed by SDKMAN!",
+		Long: "Install JDKs listed in the lockfile (sha256 of every archive is verified) and set aliases.\n\n" +
+			"Import JDKs installed by SDKMAN! (<sdkman dir>/candidates/java).\n" +
+			"JDKs are linked into jabba home (and so remain managed by SDKMAN!) unless --move is specified,\n" +
+			"in which case they are moved into jabba home (with links left behind, so that SDKMAN! keeps working).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return pflag.ErrHelp
+			}
+			if args[0] != "sdkman" {
+				lock, err := command.ReadLockfile(args[0])
+				if err != nil {
+					log.Fatal(err)
+				}
+				lockHome()
+				results, err := command.ImportLockfile(lock)
+				if err != nil {
+					log.Fatal(err)
+				}
+				if err := command.LinkLatest(); err != nil {
+					log.Fatal(err)
+				}
+				if outputFormat(cmd) == "json" {
+					printJSON(results)
+				}
+				return nil
+			}
+			if sdkmanDir == "" {
+				sdkmanDir = command.SdkmanDir()
+			}
+			lockHome()
+			jdks, err := command.ImportSdkman(sdkmanDir, importMove)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := command.LinkLatest(); err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(jdks)
+				return nil
+			}
+			for _, jdk := range jdks {
+				if jdk.Skipped != "" {
+					fmt.Printf("%s skipped: %s\n", jdk.ID, jdk.Skipped)
+				} else {
+					fmt.Printf("%s -> %s\n", jdk.ID, jdk.Version)
+				}
+			}
+			return nil
+		},
+		Example: "  jabba import jabba.lock\n" +
+			"  jabba import sdkman # 17.0.9-tem -> temurin@1.17.0-9, 8.0.392-zulu -> zulu@1.8.0-392, ...\n" +
+			"  jabba import sdkman --move",
+	}
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Print lockfile of installed JDKs (URLs & sha256 of the archives) and aliases (see 'jabba import')",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lock, err := command.Export()
+			if err != nil {
+				log.Fatal(err)
+			}
+			printJSON(lock)
+			return nil
+		},
+		Example: "  jabba export > jabba.lock\n" +
+			"  jabba import jabba.lock # on another machine / in Dockerfile",
+	}
+	var pinLockfile string
+	pinURLCmd := &cobra.Command{
+		Use:   "pin-url <version>=<url>...",
+		Short: "Convert <version>=<url> into lockfile entries (with sha256 of the archive recorded)",
+		Long: "Convert <version>=<url> (deprecated unless pinned with #sha256=...) into lockfile entries.\n" +
+			"Every archive is fetched to calculate its sha256.\n" +
+			"Entries are printed unless --lockfile is specified, in which case they are added to the lockfile\n" +
+			"(replacing entries of the same version).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return pflag.ErrHelp
+			}
+			lock := &command.Lockfile{JDKs: []command.LockedJDK{}}
+			if pinLockfile != "" {
+				if _, err := os.Stat(pinLockfile); err == nil {
+					if lock, err = command.ReadLockfile(pinLockfile); err != nil {
+						log.Fatal(err)
+					}
+				}
+			}
+			for _, arg := range args {
+				jdk, err := command.PinURL(arg)
+				if err != nil {
+					log.Fatal(err)
+				}
+				lock.Pin(*jdk)
+			}
+			if pinLockfile == "" {
+				printJSON(lock)
+				return nil
+			}
+			if err := command.WriteLockfile(pinLockfile, lock); err != nil {
+				log.Fatal(err)
+			}
+			return nil
+		},
+		Example: "  jabba pin-url 1.8.0-custom=tgz+https://example.com/jdk.tar.gz --lockfile jabba.lock\n" +
+			"  jabba import jabba.lock",
+	}
+	pinURLCmd.Flags().StringVar(&pinLockfile, "lockfile", "", "Lockfile to add entries to (created if missing)")
+	var lockFile string
+	var lockUpdate, lockAny bool
+	lockCmd := &cobra.Command{
+		Use:   "lock [version...]",
+		Short: "Write lockfile of installed JDKs (or bump locked JDKs with --update), printing what changed",
+		Long: "Write lockfile of installed JDKs & aliases (same as `jabba export`), keeping entries that are\n" +
+			"already there in place (so that only the entries that actually changed show up in the diff).\n\n" +
+			"With --update locked JDKs (only those matching specified versions, if any) are bumped to the latest\n" +
+			"release of their line (e.g. zulu@1.17.0 -> zulu@1.17.8) instead (nothing is installed).\n" +
+			"Aliases follow.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 && !lockUpdate {
+				log.Fatal("versions can only be specified along with --update")
+			}
+			prev := &command.Lockfile{JDKs: []command.LockedJDK{}}
+			exists := false
+			if _, err := os.Stat(lockFile); err == nil {
+				if prev, err = command.ReadLockfile(lockFile); err != nil {
+					log.Fatal(err)
+				}
+				exists = true
+			} else if lockUpdate {
+				log.Fatal(err)
+			}
+			var lock *command.Lockfile
+			var err error
+			if lockUpdate {
+				var selectors []string
+				for _, arg := range args {
+					selectors = append(selectors, qualify(cmd, arg))
+				}
+				lock, err = command.UpdateLockfile(prev, selectors, lockAny)
+			} else {
+				lock, err = command.LockInstalled(prev)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			changes := command.DiffLockfiles(prev, lock)
+			if len(changes) != 0 || !exists {
+				if err := command.WriteLockfile(lockFile, lock); err != nil {
+					log.Fatal(err)
+				}
+			}
+			if outputFormat(cmd) == "json" {
+				if changes == nil {
+					changes = []command.LockChange{}
+				}
+				printJSON(changes)
+				return nil
+			}
+			if len(changes) == 0 {
+				log.Info(lockFile, " is up to date")
+			}
+			for _, change := range changes {
+				fmt.Println(change)
+			}
+			return nil
+		},
+		Example: "  jabba lock # jabba.lock\n" +
+			"  jabba lock --update # zulu@1.17.0 -> zulu@1.17.8, ...\n" +
+			"  jabba lock --update zulu@1.17 --lockfile ci/jabba.lock",
+	}
+	lockCmd.Flags().StringVar(&lockFile, "lockfile", "jabba.lock", "Lockfile to write")
+	lockCmd.Flags().BoolVar(&lockUpdate, "update", false,
+		"Bump locked JDKs to the latest release of their line (instead of locking installed JDKs)")
+	lockCmd.Flags().BoolVar(&lockAny, "any", false,
+		"Bump to the latest release even if index recommends another one (--update only)")
+	importCmd.Flags().StringVar(&sdkmanDir, "dir", "", "SDKMAN! directory (defaults to $SDKMAN_DIR or ~/.sdkman)")
+	importCmd.Flags().BoolVar(&importMove, "move", false, "Move JDKs into jabba home (instead of linking them)")
+	var apiSocket string
+	apiCmd := &cobra.Command{
+		Use:   "api",
+		Short: "Serve JSON API (list, resolve, install) over unix socket (for IDE/editor plugins)",
+		Long: "Serve JSON API over unix socket until interrupted.\n\n" +
+			"  GET  /v1/installed\n" +
+			"  GET  /v1/remote?os=&arch=&libc=&range=&channel=\n" +
+			"  GET  /v1/resolve?selector=<version, range or alias>\n" +
+			"  POST /v1/install?selector=&arch=&libc=&any=&channel= (progress is streamed as newline-delimited JSON)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if apiSocket == "" {
+				apiSocket = filepath.Join(cfg.StateDir(), "api.sock")
+			}
+			l, err := command.ListenAPI(apiSocket)
+			if err != nil {
+				log.Fatal(err)
+			}
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			stopping := make(chan struct{})
+			go func() {
+				<-signals
+				close(stopping)
+				// removes the socket
+				l.Close()
+			}()
+			log.Info("Listening on ", apiSocket)
+			if err := command.ServeAPI(l); err != nil {
+				select {
+				case <-stopping:
+				default:
+					log.Fatal(err)
+				}
+			}
+			return nil
+		},
+		Example: "  jabba api --socket=/tmp/jabba.sock\n" +
+			"  curl --unix-socket /tmp/jabba.sock http://jabba/v1/resolve?selector=default",
+	}
+	apiCmd.Flags().StringVar(&apiSocket, "socket", "", "Path to unix socket (defaults to $JABBA_HOME/api.sock)")
+	var watchInterval time.Duration
+	var watchFile, watchFormat string
+	var watchFd int
+	watchCmd := &cobra.Command{
+		Use:   "watch [project directory]",
+		Short: "Re-emit activation env whenever JDK the project is pinned to changes (until interrupted)",
+		Long: "Watch project file (.jabbarc, .java-version or .tool-versions) and write environment change\n" +
+			"(PATH, JAVA_HOME, ...) activating pinned JDK every time it changes (once at start too), so that wrapper\n" +
+			"processes (devcontainers, daemons, etc.) could hot-swap JAVA_HOME without restarting the session.\n\n" +
+			"Each change is written as a script for --format shell followed by an empty line or as a single line of\n" +
+			"JSON ({\"selector\", \"file\", \"version\", \"set\", \"unset\", \"error\"}) for --format=json.\n" +
+			"--file is replaced atomically (and only on successful activation, unless --format=json).",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return pflag.ErrHelp
+			}
+			dir := "."
+			if len(args) == 1 {
+				dir = args[0]
+			}
+			if watchInterval <= 0 {
+				log.Fatal("--interval must be positive")
+			}
+			var out io.Writer = os.Stdout
+			if watchFd > 0 {
+				out = os.NewFile(uintptr(watchFd), "fd"+fmt.Sprint(watchFd))
+			}
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+			stop := make(chan struct{})
+			go func() {
+				<-signals
+				close(stop)
+			}()
+			command.Watch(dir, watchInterval, stop, func(e command.WatchEvent) {
+				if e.Err != nil {
+					log.Warn(e.Err)
+				}
+				var err error
+				if watchFile != "" {
+					err = command.WriteWatchEventToFile(watchFile, e, watchFormat)
+				} else {
+					err = command.WriteWatchEvent(out, e, watchFormat)
+				}
+				if err != nil {
+					log.Fatal(err)
+				}
+			})
+			return nil
+		},
+		Example: "  jabba watch --format=json\n" +
+			"  jabba watch --format=bash --file=/run/jabba/env.sh /workspace\n" +
+			"  jabba watch --fd=3 3>/tmp/jabba.pipe",
+	}
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", time.Second, "How often to check project file")
+	watchCmd.Flags().StringVar(&watchFormat, "format", "bash", "bash, zsh, fish, pwsh, nushell or json")
+	watchCmd.Flags().StringVar(&watchFile, "file", "", "Replace file with activation env (instead of writing to stdout)")
+	watchCmd.Flags().IntVar(&watchFd, "fd", 0, "Write to file descriptor (instead of stdout)")
+	var trimTo string
+	var lsVerbose bool
+	lsCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List installed versions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var r *semver.Range
+			if len(args) > 0 {
+				var err error
+				r, err = semver.ParseRange(args[0])
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+			vs, err := command.Ls()
+			if err != nil {
+				log.Fatal(err)
+			}
+			if arch, _ := cmd.Flags().GetString("arch"); arch != "" {
+				var filtered []*semver.Version
+				for _, v := range vs {
+					if command.BuiltFor(v.String(), command.NormalizeArch(arch)) {
+						filtered = append(filtered, v)
+					}
+				}
+				vs = filtered
+			}
+			if trimTo != "" {
+				vs = semver.VersionSlice(vs).TrimTo(parseTrimTo(trimTo))
+			}
+			asJSON := outputFormat(cmd) == "json"
+			if lsVerbose {
+				infos := []*command.InstallInfo{}
+				for _, v := range vs {
+					if r != nil && !r.Contains(v) {
+						continue
+					}
+					info, err := command.Info(v.String())
+					if err != nil {
+						log.Fatal(err)
+					}
+					infos = append(infos, info)
+				}
+				if asJSON {
+					printJSON(infos)
+				} else {
+					printInfoTable(infos)
+				}
+				return nil
+			}
+			jdks := []command.JDK{}
+			for _, v := range vs {
+				if r != nil && !r.Contains(v) {
+					continue
+				}
+				if asJSON {
+					if trimTo != "" {
+						jdks = append(jdks, command.JDK{Version: v.String(), Vendor: v.Qualifier()})
+					} else {
+						jdks = append(jdks, command.DescribeInstalled(v))
+					}
+					continue
+				}
+				if command.IsPinned(v.String()) {
+					fmt.Println(v.String() + " (pinned)")
+				} else {
+					fmt.Println(v)
+				}
+			}
+			if asJSON {
+				printJSON(jdks)
+			}
+			return nil
+		},
+	}
+	lsRemoteCmd := &cobra.Command{
+		Use:   "ls-remote [range or text to look for]",
+		Short: "List remote versions available for install",
+		Long: "List remote versions available for install (grouped by vendor, latest first).\n\n" +
+			"Argument can be either a range (versions the way vendors spell them (e.g. zulu@17.*) are understood " +
+			"too) or\ntext to look for in versions (e.g. openj9).",
+		Example: "  jabba ls-remote \"zulu@17.*\" --arch=arm64\n" +
+			"  jabba ls-remote --vendor=temurin --lts-only --latest=minor\n" +
+			"  jabba ls-remote openj9",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vendor, _ := cmd.Flags().GetString("vendor")
+			query := command.ReleaseQuery{Channel: channel(cmd)}
+			query.LTSOnly, _ = cmd.Flags().GetBool("lts-only")
+			if len(args) > 0 {
+				if _, err := semver.ParseRange(args[0]); err == nil {
+					query.Selector = qualify(cmd, args[0])
+				} else {
+					query.Selector, query.Vendor = args[0], vendor
+				}
+			} else if vendor != "" {
+				query.Selector = vendor + "@"
+			}
+			os, _ := cmd.Flags().GetString("os")
+			arch, _ := cmd.Flags().GetString("arch")
+			if !cmd.Flags().Changed("arch") && cfg.Arch() != "" {
+				arch = cfg.Arch()
+			}
+			arch = command.NormalizeArch(arch)
+			libc, _ := cmd.Flags().GetString("libc")
+			os, err := command.TargetOS(os, libc)
+			if err != nil {
+				log.Fatal(err)
+			}
+			providers := cfg.Providers()
+			if cmd.Flags().Changed("provider") {
+				providers, _ = cmd.Flags().GetStringSlice("provider")
+			}
+			releaseMap, err := command.LsRemoteFrom(providers, os, arch)
+			if err != nil {
+				log.Fatal(err)
+			}
+			releaseMap = command.QueryReleases(releaseMap, query)
+			var vs = make([]*semver.Version, len(releaseMap))
+			var i = 0
+			for k := range releaseMap {
+				vs[i] = k
+				i++
+			}
+			sort.Sort(sort.Reverse(semver.VersionSlice(vs)))
+			if trimTo != "" {
+				vs = semver.VersionSlice(vs).TrimTo(parseTrimTo(trimTo))
+			}
+			var installed map[string]bool
+			var current string
+			if installedMarkers, _ := cmd.Flags().GetBool("installed-markers"); installedMarkers {
+				local, err := command.Ls()
+				if err != nil {
+					log.Fatal(err)
+				}
+				installed = make(map[string]bool)
+				for _, v := range local {
+					installed[v.String()] = true
+				}
+				current = command.Current()
+			}
+			if outputFormat(cmd) == "json" {
+				jdks := []command.JDK{}
+				for _, v := range vs {
+					jdks = append(jdks, command.DescribeRemote(v, releaseMap[v], os, arch))
+				}
+				printJSON(jdks)
+				return nil
+			}
+			for _, v := range vs {
+				if installed != nil {
+					fmt.Println(installedMarker(v.String(), installed, current), v)
+				} else {
+					fmt.Println(v)
+				}
+			}
+			return nil
+		},
+	}
+	lsRemoteCmd.Flags().Bool("lts-only", false, "Only list releases of LTS lines (8, 11, 17, 21, ...)")
+	lsRemoteCmd.Flags().Bool("installed-markers", false,
+		"Mark versions that are already installed (\"*\") and the one currently in use (\"->\")")
+	lsRemoteCmd.Flags().String("os", runtime.GOOS, "Operating System ("+osNames+")")
+	lsRemoteCmd.Flags().String("arch", command.HostArch(), "Architecture (amd64, arm64, 386)")
+	lsRemoteCmd.Flags().String("libc", "", "C standard library (glibc, musl) (auto-detected by default)")
+	lsRemoteCmd.Flags().StringSlice("provider", nil,
+		"Source(s) of releases ("+strings.Join(command.ProviderNames(), ", ")+"). "+
+			"Defaults to $JABBA_PROVIDERS (or \"index\" if not set)")
+	lsCmd.Flags().BoolVarP(&lsVerbose, "verbose", "v", false,
+		"Show vendor, Java version, platform, size, install date & source of every JDK (see 'jabba info')")
+	lsCmd.Flags().String("arch", "", "Only list JDKs that run natively on the architecture (amd64, arm64, 386) "+
+		"(universal builds match every architecture they contain)")
+	for _, cmd := range []*cobra.Command{lsCmd, lsRemoteCmd} {
+		cmd.Flags().StringVar(&trimTo, "latest", "",
+			"Part of the version to trim to (\"major\", \"minor\" or \"patch\")")
+	}
+	currentCmd := &cobra.Command{
+		Use:              "current",
+		Short:            "Display currently 'use'ed version",
+		PersistentPreRun: fastPreRun,
+		Run: func(cmd *cobra.Command, args []string) {
+			var ver string
+			if state != nil {
+				ver = state.Current()
+			} else {
+				ver = command.Current()
+			}
+			if outputFormat(cmd) == "json" {
+				var jdk *command.JDK
+				if ver != "" {
+					jdk = describeInstalled(ver)
+				}
+				printJSON(jdk)
+				return
+			}
+			if ver != "" {
+				fmt.Println(ver)
+				command.WarnIfOutdated(ver)
+			}
+		},
+	}
+	outdatedCmd := &cobra.Command{
+		Use:   "outdated",
+		Short: "List installed JDKs that have newer releases (of the same line) or are past end of support",
+		Long: "List installed JDKs along with the latest releases of their lines (as per index) and dates vendors stop\n" +
+			"shipping updates (security fixes included) of the lines (built-in data, optionally updated from\n" +
+			"\"eol_data\" in config.yaml).\n\n" +
+			"`jabba use` & `jabba current` warn (at most once a day per JDK) about JDKs that are past end of support\n" +
+			"or superseded (as of the last `jabba outdated`) (JABBA_EOL_WARNINGS=false / \"eol_warnings: false\" in\n" +
+			"config.yaml to turn warnings off).\n\n" +
+			"Exit status is non-zero if any of the JDKs is superseded or past end of support.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jdks, err := command.Outdated()
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(jdks)
+			} else {
+				printOutdated(jdks)
+			}
+			for _, jdk := range jdks {
+				if jdk.Superseded() || jdk.PastEOL {
+					os.Exit(1)
+				}
+			}
+			return nil
+		},
+		Example: "  jabba outdated\n" +
+			"  jabba outdated --output=json",
+	}
+	homeLsCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List named jabba homes (\"homes\" in config.yaml) (\"*\" marks the active one)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			homes := command.Homes()
+			if outputFormat(cmd) == "json" {
+				printJSON(homes)
+				return nil
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, home := range homes {
+				marker, name := " ", home.Name
+				if home.Active {
+					marker = "*"
+				}
+				if name == "" {
+					name = "-"
+				}
+				fmt.Fprintf(w, "%s %s\t%s\n", marker, name, home.Path)
+			}
+			w.Flush()
+			return nil
+		},
+	}
+	homeUseCmd := &cobra.Command{
+		Use:   "use [name or path]",
+		Short: "Switch shell to another jabba home (deactivating current JDK & activating \"default\" of the home)",
+		Long: "Switch shell to another jabba home (JDKs, aliases & config.yaml of which are kept apart from the\n" +
+			"others): JDK activated from the current home is deactivated, JABBA_HOME is updated and \"default\"\n" +
+			"alias of the new home (if any) is activated.\n\n" +
+			"Homes are named in config.yaml (\"homes\" (name -> path)), the ones named in the config of the default\n" +
+			"home (~/.jabba/config.yaml) can be switched to from any other home.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return pflag.ErrHelp
+			}
+			change, err := command.UseHome(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			printForShellToEval(change)
+			return nil
+		},
+		Example: "  jabba home use work\n" +
+			"  jabba home use ~/experiments/.jabba",
+	}
+	homeCmd := &cobra.Command{
+		Use:   "home",
+		Short: "List named jabba homes / switch shell to another one",
+	}
+	homeCmd.AddCommand(homeLsCmd, homeUseCmd)
+	factsCmd := &cobra.Command{
+		Use:   "facts",
+		Short: "Describe jabba installation (host, installed JDKs, aliases) as JSON (input of `jabba report`)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			facts, err := command.CollectFacts(version)
+			if err != nil {
+				log.Fatal(err)
+			}
+			printJSON(facts)
+			return nil
+		},
+	}
+	var reportRemote string
+	var reportByHost bool
+	var reportOpts command.RemoteFactsOptions
+	reportCmd := &cobra.Command{
+		Use:   "report [facts file...]",
+		Short: "Aggregate installed & default JDKs across hosts (`jabba facts` outputs or, with --remote, over ssh)",
+		Long: "Aggregate `jabba facts` of the fleet (e.g. build agents): which JDKs are installed where and which ones\n" +
+			"\"default\" alias points to. Facts are read from files (\"-\" or none for stdin, outputs can be\n" +
+			"concatenated) or, with --remote, collected by running `jabba facts` on every host listed in the file\n" +
+			"(one ssh destination (e.g. ci@agent-01, ssh://agent-02:2222) per line, \"#\" starts a comment).\n\n" +
+			"Exit status is non-zero if facts of any of the hosts couldn't be collected.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var facts []command.Facts
+			var failed []command.FleetError
+			if reportRemote != "" {
+				if len(args) != 0 {
+					return pflag.ErrHelp
+				}
+				targets, err := command.ReadTargets(reportRemote)
+				if err != nil {
+					log.Fatal(err)
+				}
+				facts, failed = command.RemoteFacts(targets, reportOpts)
+			} else {
+				if len(args) == 0 {
+					args = []string{"-"}
+				}
+				for _, file := range args {
+					r := io.Reader(os.Stdin)
+					if file != "-" {
+						f, err := os.Open(file)
+						if err != nil {
+							log.Fatal(err)
+						}
+						defer f.Close()
+						r = f
+					}
+					ff, err := command.ReadFacts(r)
+					if err != nil {
+						log.Fatal(fmt.Errorf("%s: %v", file, err))
+					}
+					facts = append(facts, ff...)
+				}
+			}
+			report := command.NewFleetReport(facts, failed)
+			if outputFormat(cmd) == "json" {
+				printJSON(report)
+			} else {
+				printFleetReport(report, reportByHost)
+			}
+			if len(report.Errors) != 0 {
+				os.Exit(1)
+			}
+			return nil
+		},
+		Example: "  jabba report --remote agents.txt\n" +
+			"  jabba report --remote agents.txt --by-host --ssh \"ssh -F ci/ssh_config\"\n" +
+			"  jabba report facts/*.json\n" +
+			"  for h in $(cat agents.txt); do ssh $h '~/.jabba/bin/jabba facts'; done | jabba report --output=json",
+	}
+	reportCmd.Flags().StringVar(&reportRemote, "remote", "",
+		"File listing ssh destinations to collect \"jabba facts\" from")
+	reportCmd.Flags().StringVar(&reportOpts.SSH, "ssh", command.DefaultReportSSH,
+		"ssh command (destination & remote command are appended to it)")
+	reportCmd.Flags().StringVar(&reportOpts.Command, "remote-command", command.DefaultReportRemoteCommand,
+		"Command printing facts on the remote host (e.g. \"jabba.exe facts\" in case of Windows hosts)")
+	reportCmd.Flags().IntVar(&reportOpts.Jobs, "jobs", 8, "How many hosts to query concurrently")
+	reportCmd.Flags().DurationVar(&reportOpts.Timeout, "timeout", time.Minute, "How long to wait for each host")
+	reportCmd.Flags().BoolVar(&reportByHost, "by-host", false, "List hosts (instead of versions)")
+	for _, cmd := range []*cobra.Command{installCmd, tryCmd, lsRemoteCmd, peekCmd, resolveCmd} {
+		cmd.Flags().String("vendor", "",
+			"Vendor (e.g. temurin) to resolve versions that don't specify one (e.g. 21) within "+
+				"(overrides \"default_vendor\" in config.yaml)")
+		cmd.Flags().String("channel", command.ChannelGA,
+			"Release channel (\"ga\", \"ea\" (early-access / nightly builds) or \"all\" (same as --include-ea))")
+		cmd.Flags().Bool("include-ea", false, "Consider early-access / nightly builds along with GA releases")
+		setCompletionValues(cmd.Flags(), "channel", command.ChannelGA, command.ChannelEA)
+	}
+	for _, cmd := range []*cobra.Command{installCmd, lsRemoteCmd, peekCmd, resolveCmd} {
+		setCompletionValues(cmd.Flags(), "arch", "amd64", "arm64", "386")
+		setCompletionValues(cmd.Flags(), "libc", "glibc", "musl")
+		setCompletionValues(cmd.Flags(), "os", command.SupportedOSs()...)
+	}
+	setCompletionValues(lsCmd.Flags(), "arch", "amd64", "arm64", "386")
+	setCompletionValues(hookCmd.Flags(), "shell", "bash", "zsh", "fish")
+	setCompletionValues(whichCmd.Flags(), "bin", "java", "javac", "jar", "jshell", "keytool", "jlink", "jcmd", "jstack")
+	setCompletionValues(shellIntegrationCmd.Flags(), "shell", "bash", "zsh", "fish", "pwsh", "nushell")
+	componentAddCmd := &cobra.Command{
+		Use:   "add [version or alias] [component...]",
+		Short: "Install GraalVM component(s) (e.g. native-image, js) with gu",
+		Long: "Install GraalVM component(s) with gu (GraalVM Updater) of the JDK. Components are recorded in the\n" +
+			"JDK's metadata and are added again whenever JDK is replaced by `jabba upgrade` or installed from\n" +
+			"`jabba export`ed lockfile.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return pflag.ErrHelp
+			}
+			lockHome()
+			if _, err := command.AddComponents(args[0], args[1:]); err != nil {
+				log.Fatal(err)
+			}
+			return nil
+		},
+		Example: "  jabba component add graalvm-ce-java17@22.3.0 native-image js",
+	}
+	componentLsCmd := &cobra.Command{
+		Use:   "ls [version or alias]",
+		Short: "List GraalVM components (\"*\" marks the ones added with `jabba component add`)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return pflag.ErrHelp
+			}
+			components, err := command.ListComponents(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(components)
+				return nil
+			}
+			for _, c := range components {
+				mark := " "
+				if c.Recorded {
+					mark = "*"
+				}
+				fmt.Printf("%s %s %s\n", mark, c.ID, c.Version)
+			}
+			return nil
+		},
+	}
+	componentRmCmd := &cobra.Command{
+		Use:   "rm [version or alias] [component...]",
+		Short: "Remove GraalVM component(s) with gu",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return pflag.ErrHelp
+			}
+			lockHome()
+			if _, err := command.RemoveComponents(args[0], args[1:]); err != nil {
+				log.Fatal(err)
+			}
+			return nil
+		},
+	}
+	componentCmd := &cobra.Command{
+		Use:   "component",
+		Short: "Manage GraalVM components (gu)",
+	}
+	componentCmd.AddCommand(componentAddCmd, componentLsCmd, componentRmCmd)
+	attestCmd := &cobra.Command{
+		Use:   "attest [version]",
+		Short: "Print provenance of installed JDK (in-toto statement with SLSA provenance predicate)",
+		Long: "Print provenance of installed JDK (as recorded at install time) as in-toto statement with SLSA\n" +
+			"provenance predicate: archive JDK was installed from (URL, sha256, signer), jabba / extractor versions,\n" +
+			"timestamps and sha256 of every file (subjects). Keep it (or sign it (e.g. `cosign attest-blob`))\n" +
+			"outside of jabba home to be able to `jabba verify-tree --attestation` the JDK later.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return pflag.ErrHelp
+			}
+			a, err := command.Attest(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			printJSON(a)
+			return nil
+		},
+		Example: "  jabba attest temurin@1.21.0-1 > temurin-21.intoto.json",
+	}
+	var verifyTreeAttestation string
+	verifyTreeCmd := &cobra.Command{
+		Use:   "verify-tree [version]",
+		Short: "Re-hash files of installed JDK & compare them to the attestation (or metadata recorded at install time)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return pflag.ErrHelp
+			}
+			var a *command.Attestation
+			if verifyTreeAttestation != "" {
+				var err error
+				if a, err = command.ReadAttestation(verifyTreeAttestation); err != nil {
+					log.Fatal(err)
+				}
+			}
+			summary, err := command.VerifyTree(args[0], a)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println(summary)
+			return nil
+		},
+		Example: "  jabba verify-tree temurin@1.21.0-1 --attestation temurin-21.intoto.json",
+	}
+	verifyTreeCmd.Flags().StringVar(&verifyTreeAttestation, "attestation", "",
+		"File \"jabba attest\" output was saved to (defaults to metadata recorded at install time)")
+	var uninstallDryRun bool
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall [version or range to uninstall]",
+		Short: "Uninstall JDK(s)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return pflag.ErrHelp
+			}
+			if strings.HasPrefix(args[0], "system@") {
+				log.Fatal("Link to system JDK can only be removed with 'unlink'" +
+					" (e.g. 'jabba unlink " + args[0] + "')")
+			}
+			if uninstallDryRun {
+				r, err := command.PlanUninstall(args[0])
+				if err != nil {
+					log.Fatal(err)
+				}
+				printCleanReport(cmd, r)
+				return nil
+			}
+			lockHome()
+			removed, err := command.Uninstall(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := command.LinkLatest(); err != nil {
+				log.Fatal(err)
+			}
+			if outputFormat(cmd) == "json" {
+				printJSON(removed)
 			}
 			return nil
 		},
+		Example: "  jabba uninstall 1.8.0\n" +
+			"  jabba uninstall 1.8 # all 1.8.x\n" +
+			"  jabba uninstall \"zulu@<1.11\"\n" +
+			"  jabba uninstall \"zulu@<1.11\" --dry-run",
 	}
-	lsRemoteCmd := &cobra.Command{
-		Use:   "ls-remote",
-		Short: "List remote versions available for install",
+	uninstallCmd.Flags().BoolVar(&uninstallDryRun, "dry-run", false,
+		"Print paths that would be removed (& space that would be reclaimed) without uninstalling anything")
+	var pruneDryRun bool
+	pruneCmd := &cobra.Command{
+		Use:   "prune [project dir...]",
+		Short: "Uninstall JDKs that are not in use",
+		Long: "Uninstall JDKs that are not referenced by aliases (default included), current shell or\n" +
+			"project files (.jabbarc, .java-version, .tool-versions) found under the specified directories.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var r *semver.Range
-			if len(args) > 0 {
-				var err error
-				r, err = semver.ParseRange(args[0])
+			if pruneDryRun {
+				r, err := command.PlanPrune(args)
 				if err != nil {
 					log.Fatal(err)
 				}
+				printCleanReport(cmd, r)
+				return nil
 			}
-			os, _ := cmd.Flags().GetString("os")
-			arch, _ := cmd.Flags().GetString("arch")
-			releaseMap, err := command.LsRemote(os, arch)
+			lockHome()
+			removed, err := command.Prune(args)
 			if err != nil {
 				log.Fatal(err)
 			}
-			var vs = make([]*semver.Version, len(releaseMap))
-			var i = 0
-			for k := range releaseMap {
-				vs[i] = k
-				i++
-			}
-			sort.Sort(sort.Reverse(semver.VersionSlice(vs)))
-			if trimTo != "" {
-				vs = semver.VersionSlice(vs).TrimTo(parseTrimTo(trimTo))
+			if err := command.LinkLatest(); err != nil {
+				log.Fatal(err)
 			}
-			for _, v := range vs {
-				if r != nil && !r.Contains(v) {
-					continue
-				}
-				fmt.Println(v)
+			if outputFormat(cmd) == "json" {
+				printJSON(removed)
 			}
 			return nil
 		},
+		Example: "  jabba prune ~/projects\n" +
+			"  jabba prune --dry-run ~/projects",
 	}
-	lsRemoteCmd.Flags().String("os", runtime.GOOS, "Operating System (darwin, linux, windows)")
-	lsRemoteCmd.Flags().String("arch", runtime.GOARCH, "Architecture (amd64, 386)")
-	for _, cmd := range []*cobra.Command{lsCmd, lsRemoteCmd} {
-		cmd.Flags().StringVar(&trimTo, "latest", "",
-			"Part of the version to trim to (\"major\", \"minor\" or \"patch\")")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false,
+		"Print paths that would be removed (& space that would be reclaimed) without uninstalling anything")
+	for _, cmd := range []*cobra.Command{lsCmd, lsRemoteCmd, currentCmd, whichCmd, sizeBudgetCmd, doctorCmd, importCmd,
+		infoCmd, historyCmd, peekCmd, setenvCmd, duCmd, resolveCmd, lockCmd, mirrorsTestCmd, cacheCleanCmd,
+		gcCmd, uninstallCmd, pruneCmd, componentLsCmd, indexLintCmd, outdatedCmd, reportCmd, homeLsCmd} {
+		cmd.Flags().String("output", "plain",
+			"Output format (\"plain\" or \"json\") (defaults to \"output\" in config.yaml or \"plain\")")
+		setCompletionValues(cmd.Flags(), "output", "plain", "json")
 	}
+	rootCmd.AddCommand(newCompletionCmds()...)
 	rootCmd.AddCommand(
 		installCmd,
-		&cobra.Command{
-			Use:   "uninstall [version to uninstall]",
-			Short: "Uninstall JDK",
-			RunE: func(cmd *cobra.Command, args []string) error {
-				if len(args) == 0 {
-					return pflag.ErrHelp
-				}
-				if strings.HasPrefix(args[0], "system@") {
-					log.Fatal("Link to system JDK can only be removed with 'unlink'" +
-						" (e.g. 'jabba unlink " + args[0] + "')")
-				}
-				err := command.Uninstall(args[0])
-				if err != nil {
-					log.Fatal(err)
-				}
-				if err := command.LinkLatest(); err != nil {
-					log.Fatal(err)
-				}
-				return nil
-			},
-			Example: "  jabba uninstall 1.8",
-		},
+		uninstallCmd,
+		pruneCmd,
+		upgradeCmd,
+		outdatedCmd,
+		factsCmd,
+		reportCmd,
+		homeCmd,
+		infoCmd,
+		setenvCmd,
+		componentCmd,
+		historyCmd,
+		versionCmd,
+		selfUpdateCmd,
+		verifySelfCmd,
+		exportCmd,
+		pinURLCmd,
+		lockCmd,
+		mirrorsCmd,
+		indexCmd,
 		&cobra.Command{
 			Use:   "link [name] [path]",
 			Short: "Resolve or update a link",
 			RunE: func(cmd *cobra.Command, args []string) error {
 				if len(args) == 0 {
+					lockHome()
 					if err := command.LinkLatest(); err != nil {
 						log.Fatal(err)
 					}
 					return nil
 				}
 				if len(args) == 1 {
-					if value := command.GetLink(args[0]); value != "" {
+					value := command.GetLink(args[0])
+					if args[0] == "system" {
+						value = command.GetSystemLink()
+					}
+					if value != "" {
 						fmt.Println(value)
 					}
-				} else if err := command.Link(args[0], args[1]); err != nil {
-					log.Fatal(err)
+				} else if args[0] == "system" {
+					// JABBA_HOME is left intact (and so not locked (to avoid root-owned lock file when run with sudo))
+					ver, err := command.LinkSystem(args[1])
+					if err != nil {
+						log.Fatal(err)
+					}
+					log.Info(ver + " is now the system-wide default (" + cfg.SystemLink() + ")")
+				} else {
+					lockHome()
+					if err := command.Link(args[0], args[1]); err != nil {
+						log.Fatal(err)
+					}
 				}
 				return nil
 			},
+			Long: "`jabba link system <selector>` points /usr/local/opt/jabba-default (JABBA_SYSTEM_LINK / system_link) " +
+				"at the JDK so that processes which don't read shell profiles (e.g. GUI applications) could use it\n" +
+				"(on macOS JDK is also linked into /Library/Java/JavaVirtualMachines, on Linux java & javac are " +
+				"registered with update-alternatives). Usually requires sudo.",
 			Example: "  jabba link system@1.8.20 /Library/Java/JavaVirtualMachines/jdk1.8.0_20.jdk\n" +
-				"  jabba link system@1.8.20 # show link target",
+				"  jabba link system@1.8.20 # show link target\n" +
+				"  sudo JABBA_HOME=$JABBA_HOME jabba link system zulu@1.17\n" +
+				"  jabba link system # show JDK system-wide link points at",
 		},
 		&cobra.Command{
 			Use:   "unlink [name]",
@@ -246,61 +2382,77 @@ func main() {
 				if len(args) == 0 {
 					return pflag.ErrHelp
 				}
+				if args[0] == "system" {
+					if err := command.UnlinkSystem(); err != nil {
+						log.Fatal(err)
+					}
+					return nil
+				}
+				lockHome()
 				if err := command.Link(args[0], ""); err != nil {
 					log.Fatal(err)
 				}
 				return nil
 			},
-			Example: "  jabba unlink system@1.8.20",
+			Example: "  jabba unlink system@1.8.20\n" +
+				"  sudo JABBA_HOME=$JABBA_HOME jabba unlink system",
 		},
+		useCmd,
+		currentCmd,
+		lsCmd,
+		lsRemoteCmd,
 		&cobra.Command{
-			Use:   "use [version to use]",
-			Short: "Modify PATH & JAVA_HOME to use specific JDK",
+			Use:   "refresh",
+			Short: "Re-fetch (and cache) index of remote versions",
+			Long: "Index is cached under $JABBA_HOME/cache/index and revalidated (ETag / Last-Modified) on every " +
+				"`jabba ls-remote` / `jabba install`.\n" +
+				"Cached copy is used as is when --offline is specified or index cannot be reached.",
 			RunE: func(cmd *cobra.Command, args []string) error {
-				var ver string
-				if len(args) == 0 {
-					ver = rc().JDK
-					if ver == "" {
-						return pflag.ErrHelp
-					}
-				} else {
-					ver = args[0]
-				}
-				return use(ver)
-			},
-			Example: "  jabba use 1.8\n" +
-				"  jabba use ~1.8.73 # same as \">=1.8.73 <1.9.0\"",
-		},
-		&cobra.Command{
-			Use:   "current",
-			Short: "Display currently 'use'ed version",
-			Run: func(cmd *cobra.Command, args []string) {
-				ver := command.Current()
-				if ver != "" {
-					fmt.Println(ver)
+				url, err := command.Refresh()
+				if err != nil {
+					log.Fatal(err)
 				}
+				fmt.Println("Refreshed " + url)
+				return nil
 			},
 		},
-		lsCmd,
-		lsRemoteCmd,
 		&cobra.Command{
 			Use:   "deactivate",
 			Short: "Undo effects of `jabba` on current shell",
 			RunE: func(cmd *cobra.Command, args []string) error {
-				out, err := command.Deactivate()
+				change, err := command.Deactivate()
 				if err != nil {
 					log.Fatal(err)
 				}
-				printForShellToEval(out)
+				printForShellToEval(change)
 				return nil
 			},
 		},
 		&cobra.Command{
 			Use:   "alias [name] [version]",
 			Short: "Resolve or update an alias",
+			Long: "Resolve or update an alias.\n\n" +
+				"Aliases can be namespaced (e.g. work/backend) and point to other aliases (e.g. lts -> work/backend)\n" +
+				"(`jabba use lts` follows the chain). `jabba alias` (`jabba alias work/`) lists all the aliases\n" +
+				"(the ones in the namespace).\n\n" +
+				"Aliases can also come from files listed under \"alias_files\" in config.yaml (see README.md)\n" +
+				"(aliases defined with `jabba alias` take precedence).",
 			RunE: func(cmd *cobra.Command, args []string) error {
-				if len(args) == 0 {
-					return pflag.ErrHelp
+				if len(args) == 0 || (len(args) == 1 && strings.HasSuffix(args[0], "/")) {
+					names, err := command.Aliases()
+					if err != nil {
+						log.Fatal(err)
+					}
+					for _, name := range names {
+						if len(args) == 0 || strings.HasPrefix(name, args[0]) {
+							line := name + " -> " + strings.TrimSpace(command.GetAlias(name))
+							if source := command.AliasSource(name); source != "" {
+								line += " (" + source + ")"
+							}
+							fmt.Println(line)
+						}
+					}
+					return nil
 				}
 				name := args[0]
 				if len(args) == 1 {
@@ -309,6 +2461,7 @@ func main() {
 					}
 					return nil
 				}
+				lockHome()
 				if err := command.SetAlias(name, args[1]); err != nil {
 					log.Fatal(err)
 				}
@@ -318,7 +2471,10 @@ func main() {
 				return nil
 			},
 			Example: "  jabba alias default 1.8\n" +
-				"  jabba alias default # show value bound to an alias",
+				"  jabba alias default # show value bound to an alias\n" +
+				"  jabba alias work/backend zulu@1.17\n" +
+				"  jabba alias lts work/backend\n" +
+				"  jabba alias work/ # list aliases in the namespace",
 		},
 		&cobra.Command{
 			Use:   "unalias [name]",
@@ -327,6 +2483,7 @@ func main() {
 				if len(args) == 0 {
 					return pflag.ErrHelp
 				}
+				lockHome()
 				if err := command.SetAlias(args[0], ""); err != nil {
 					log.Fatal(err)
 				}
@@ -334,8 +2491,164 @@ func main() {
 			},
 		},
 		whichCmd,
+		execCmd,
+		tryCmd,
+		apiCmd,
+		watchCmd,
+		hookCmd,
+		shellIntegrationCmd,
+		sizeBudgetCmd,
+		duCmd,
+		doctorCmd,
+		importCmd,
+		toolchainsCmd,
+		peekCmd,
+		ideCmd,
+		cdsCmd,
+		postInstallCmd,
+		dedupeCmd,
+		gcCmd,
+		cacheCmd,
+		pinCmd,
+		unpinCmd,
+		envCmd,
+		resolveCmd,
+		&cobra.Command{
+			Use:   "checksum [file or url]",
+			Short: "Calculate checksum of a JDK archive (in a form accepted by install/index)",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if len(args) == 0 {
+					return pflag.ErrHelp
+				}
+				checksum, err := command.Checksum(args[0])
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(checksum)
+				return nil
+			},
+			Example: "  jabba checksum https://example.com/distribution.tar.gz\n" +
+				"  jabba checksum /opt/distribution.zip\n" +
+				"  jabba install 1.8.0-custom=tgz+https://example.com/distribution.tar.gz#sha256=<hex>",
+		},
+		&cobra.Command{
+			Use:   "verify [version]",
+			Short: "Check installed JDK against metadata recorded at install time (sha256 of every file)",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if len(args) == 0 {
+					return pflag.ErrHelp
+				}
+				summary, err := command.Verify(args[0])
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(summary)
+				return nil
+			},
+			Example: "  jabba verify zulu@1.8\n" +
+				"  # signature of the archive is verified before JDK is installed if sig & key are specified\n" +
+				"  jabba install 1.8.0-custom=tgz+https://example.com/jdk.tar.gz#sig=.asc&key=vendor # $JABBA_HOME/keys/vendor.asc",
+		},
+		attestCmd,
+		verifyTreeCmd,
 	)
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		applyHomeFlag(cmd)
+		logFormat, _ := cmd.Flags().GetString("log-format")
+		logFile, _ := cmd.Flags().GetString("log-file")
+		// `jabba ls -v` doesn't count (--verbose there is a flag of its own)
+		if err := configureLogging(int(verbosity), logFormat, logFile); err != nil {
+			log.Fatal(err)
+		}
+		if cmd != verifySelfCmd && cfg.VerifySelf() {
+			if _, _, err := command.VerifySelf(); err != nil {
+				// development builds are not sealed
+				if err != command.ErrNotSealed || version != "" {
+					log.Fatal(err)
+				}
+				log.Debug(err)
+			}
+		}
+		if registry, _ := cmd.Flags().GetStringSlice("registry"); len(registry) != 0 {
+			cfg.SetRegistry(registry)
+		}
+		if offline, _ := cmd.Flags().GetBool("offline"); offline {
+			cfg.SetOffline(true)
+		}
+		if cacert, _ := cmd.Flags().GetStringSlice("cacert"); len(cacert) != 0 {
+			cfg.SetCACerts(cacert)
+		}
+		if insecure, _ := cmd.Flags().GetBool("insecure"); insecure {
+			cfg.SetInsecure(true)
+		}
+		if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+			command.SetProgress("none")
+		} else if cmd.Flags().Changed("progress") {
+			progress, _ := cmd.Flags().GetString("progress")
+			if err := command.SetProgress(progress); err != nil {
+				log.Fatal(err)
+			}
+		}
+		for _, key := range []string{"connect_timeout", "read_timeout", "timeout"} {
+			flag := strings.Replace(key, "_", "-", -1)
+			if cmd.Flags().Changed(flag) {
+				timeout, _ := cmd.Flags().GetDuration(flag)
+				cfg.SetTimeout(key, timeout)
+			}
+		}
+		if err := command.CleanStaging(); err != nil {
+			log.Debug("Failed to clean up ", filepath.Join(cfg.JDKDir(), ".staging"), " (", err, ")")
+		}
+		if _, err := command.CleanTemp(command.StaleTempAge); err != nil {
+			log.Debug("Failed to clean up ", cfg.TempDir(), " (", err, ")")
+		}
+	}
+	rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		// only after commands that talk to the index anyway (not the ones run from shell prompt hooks / scripts)
+		switch cmd.Name() {
+		case "install", "ls-remote", "upgrade":
+			command.NotifyLTS()
+		}
+		// hot path commands leave the snapshot alone (unless there was none to begin with)
+		if (cmd != whichCmd && cmd != currentCmd) || state == nil {
+			command.RefreshState()
+		}
+	}
 	rootCmd.Flags().Bool("version", false, "version of jabba")
+	rootCmd.PersistentFlags().StringSlice("registry", nil,
+		"Index URL(s) (tried in order). Overrides $JABBA_INDEX and \"registry\" in $JABBA_HOME/config.yaml")
+	rootCmd.PersistentFlags().Bool("offline", false,
+		"Use cached index only (see \"jabba refresh\"). Same as JABBA_OFFLINE=1")
+	rootCmd.PersistentFlags().StringSlice("cacert", nil,
+		"PEM file(s) with root certificates to trust (in addition to the system ones) when talking to index/download servers")
+	rootCmd.PersistentFlags().Bool("insecure", false,
+		"Do not verify TLS certificates (discouraged, use --cacert instead)")
+	rootCmd.PersistentFlags().Duration("connect-timeout", 30*time.Second,
+		"How long to wait for connection to index/download server to be established (0 to disable). "+
+			"Overrides \"connect_timeout\" in $JABBA_HOME/config.yaml")
+	rootCmd.PersistentFlags().Duration("read-timeout", time.Minute,
+		"How long to wait for data before giving up on a stalled connection (0 to disable). "+
+			"Overrides \"read_timeout\" in $JABBA_HOME/config.yaml")
+	rootCmd.PersistentFlags().Duration("timeout", 30*time.Minute,
+		"How long a single HTTP request (e.g. download of a JDK) may take (0 to disable). "+
+			"Overrides \"timeout\" in $JABBA_HOME/config.yaml")
+	rootCmd.PersistentFlags().String("progress", "auto",
+		"How to report download progress: "+strings.Join(command.ProgressModes, ", ")+
+			" (auto = tty if stderr is a terminal, plain (a line every 10s / 10%) otherwise; plain-verbose = plain in "+
+			"complete sentences (screen readers); json = one event per line)")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Do not report download progress (same as --progress=none)")
+	setCompletionValues(rootCmd.PersistentFlags(), "progress", command.ProgressModes...)
+	rootCmd.PersistentFlags().VarPF(&verbosity, "verbose", "v",
+		"Print debug messages (including output of external commands (installers, hdiutil, java -Xshare:dump, ...)) "+
+			"(-vv to prefix every line with time & level)").NoOptDefVal = "true"
+	rootCmd.PersistentFlags().String("log-format", "text", "Log format (\"text\" or \"json\" (one entry per line))")
+	rootCmd.PersistentFlags().String("log-file", "",
+		"File to append log (debug messages included, whatever the verbosity) to")
+	setCompletionValues(rootCmd.PersistentFlags(), "log-format", logFormats...)
+	// not --home (`jabba which --home` is taken)
+	rootCmd.PersistentFlags().String("jabba-home", "",
+		"Named home (see \"homes\" in config.yaml) or path to run the command against (instead of $JABBA_HOME) "+
+			"(use \"jabba home use\" to switch the shell to another home)")
 	rootCmd.PersistentFlags().String("fd3", "", "")
 	rootCmd.PersistentFlags().MarkHidden("fd3")
 	if err := rootCmd.Execute(); err != nil {
@@ -357,44 +2670,441 @@ func parseTrimTo(value string) semver.VersionPart {
 	}
 }
 
+func installedMarker(ver string, installed map[string]bool, current string) string {
+	switch {
+	case ver == current:
+		return "->"
+	case installed[ver]:
+		return " *"
+	default:
+		return "  "
+	}
+}
+
 type jabbarc struct {
 	JDK string
 }
 
+// rc returns version specified in .jabbarc, .java-version or .tool-versions
+// (in current directory or any of its parents).
 func rc() (rc jabbarc) {
-	b, err := ioutil.ReadFile(".jabbarc")
+	ver, file, err := command.ProjectVersion(".")
 	if err != nil {
+		log.Fatal(err)
+	}
+	if file != "" {
+		log.Debug("Using ", ver, " from ", file)
+	}
+	rc.JDK = ver
+	return
+}
+
+// outputFormat returns value of --output ("plain" or "json").
+func outputFormat(cmd *cobra.Command) string {
+	output, _ := cmd.Flags().GetString("output")
+	if !cmd.Flags().Changed("output") {
+		output = cfg.Output()
+	}
+	if output != "plain" && output != "json" {
+		log.Fatal("--output must be either \"plain\" or \"json\" (got \"" + output + "\")")
+	}
+	return output
+}
+
+// printCleanReport prints paths that were (would be (if r.DryRun)) removed & space reclaimed.
+func printCleanReport(cmd *cobra.Command, r *command.CleanReport) {
+	if outputFormat(cmd) == "json" {
+		printJSON(r)
+		return
+	}
+	for _, path := range r.Removed {
+		fmt.Println(path)
+	}
+	if r.DryRun {
+		fmt.Printf("Would remove %d path(s) (%s)\n", len(r.Removed), command.FormatSize(r.Freed))
+	} else {
+		fmt.Printf("Removed %d file(s) (%s)\n", len(r.Removed), command.FormatSize(r.Freed))
+	}
+}
+
+func printPlan(w io.Writer, plan *command.InstallPlan) {
+	if plan.AlreadyInstalled {
+		fmt.Fprintln(w, plan.Version+" is already installed ("+plan.Target+")")
 		return
 	}
-	// content can be a string (jdk version)
-	err = yaml.Unmarshal(b, &rc.JDK)
+	size := "unknown"
+	if plan.Size >= 0 {
+		size = fmt.Sprintf("%.1f MB", float64(plan.Size)/1024/1024)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Version:\t%s\n", plan.Version)
+	fmt.Fprintf(tw, "URL:\t%s (%s)\n", plan.URL, plan.Type)
+	fmt.Fprintf(tw, "Size:\t%s\n", size)
+	if plan.SHA256 != "" {
+		fmt.Fprintf(tw, "SHA256:\t%s\n", plan.SHA256)
+	}
+	fmt.Fprintf(tw, "Archive:\t%s\n", plan.Archive)
+	if plan.TempDir != "" {
+		fmt.Fprintf(tw, "Temp dir:\t%s\n", plan.TempDir)
+	}
+	if plan.Staging != "" {
+		fmt.Fprintf(tw, "Staging dir:\t%s\n", plan.Staging)
+	}
+	fmt.Fprintf(tw, "Target:\t%s\n", plan.Target)
+	if plan.Notice != "" {
+		fmt.Fprintf(tw, "Notice:\t%s\n", plan.Notice)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "Steps:")
+	for i, step := range plan.Steps {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, step)
+	}
+}
+
+func printJSON(v interface{}) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(b))
+}
+
+func describeInstalled(ver string) *command.JDK {
+	v, err := semver.ParseVersion(ver)
 	if err != nil {
-		// or a struct
-		err = yaml.Unmarshal(b, &rc)
-		if err != nil {
-			log.Fatal(".jabbarc is not valid")
+		log.Fatal(err)
+	}
+	jdk := command.DescribeInstalled(v)
+	return &jdk
+}
+
+// platformOf returns "<os>/<arch>" ("darwin/amd64+arm64" in case of universal build).
+func platformOf(info *command.InstallInfo) string {
+	arch := info.Arch
+	if len(info.Archs) != 0 {
+		arch = strings.Join(info.Archs, "+")
+	}
+	if info.OS != "" && arch != "" {
+		return info.OS + "/" + arch
+	}
+	return info.OS + arch
+}
+
+func printInfo(info *command.InstallInfo) {
+	platform := platformOf(info)
+	var installedAt string
+	if info.InstalledAt != nil {
+		installedAt = info.InstalledAt.Local().Format(time.RFC3339)
+	}
+	for _, kv := range [][2]string{
+		{"Version", info.Version},
+		{"Path", info.Path},
+		{"Vendor", info.Vendor},
+		{"Java version", info.JavaVersion},
+		{"Platform", platform},
+		{"Size", command.FormatSize(info.Size)},
+		{"Installed at", installedAt},
+		{"Source", info.URL},
+		{"Type", info.Type},
+		{"SHA-256", info.SHA256},
+		{"Signed by", info.Signer},
+		{"Environment", formatEnv(info.Env)},
+		{"Components", strings.Join(info.Components, ", ")},
+		{"Notice", info.Notice},
+	} {
+		if kv[1] != "" {
+			fmt.Printf("%-14s%s\n", kv[0]+":", kv[1])
 		}
 	}
-	return
 }
 
-func use(ver string) error {
-	out, err := command.Use(ver)
+// {"B": "2", "A": "1"} -> "A=1 B=2"
+func formatEnv(env map[string]string) string {
+	var r []string
+	for key, value := range env {
+		r = append(r, key+"="+value)
+	}
+	sort.Strings(r)
+	return strings.Join(r, " ")
+}
+
+func printInfoTable(infos []*command.InstallInfo) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tJAVA\tVENDOR\tPLATFORM\tSIZE\tINSTALLED\tSOURCE")
+	orDash := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+	for _, info := range infos {
+		platform := platformOf(info)
+		version := info.Version
+		if info.Pinned {
+			version += " (pinned)"
+		}
+		var installedAt string
+		if info.InstalledAt != nil {
+			installedAt = info.InstalledAt.Local().Format("2006-01-02")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", version, orDash(info.JavaVersion), orDash(info.Vendor),
+			orDash(platform), command.FormatSize(info.Size), orDash(installedAt), orDash(info.URL))
+	}
+	w.Flush()
+}
+
+func printResolutions(resolutions []*command.Resolution) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tPLATFORM\tSHA256\tURL")
+	for _, r := range resolutions {
+		version := r.Version
+		if r.Installed {
+			version += " (installed)"
+		}
+		sha256 := r.SHA256
+		if sha256 == "" {
+			sha256 = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\n", version, r.OS, r.Arch, sha256, r.URL)
+	}
+	w.Flush()
+}
+
+func printMirrorReports(reports []command.MirrorReport) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tURL\tLATENCY\tFETCH\tSIZE\tSHA256")
+	for _, r := range reports {
+		rank, sha256 := "-", "-"
+		if r.OK {
+			rank = strconv.Itoa(r.Rank)
+		}
+		if r.SHA256 != "" {
+			sha256 = r.SHA256[:12]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%dms\t%dms\t%s\t%s\n", rank, r.URL, r.LatencyMillis, r.FetchMillis,
+			command.FormatSize(r.Size), sha256)
+	}
+	w.Flush()
+	for _, r := range reports {
+		for _, problem := range r.Problems {
+			log.Error(r.URL, ": ", problem)
+		}
+	}
+}
+
+func printFleetReport(report *command.FleetReport, byHost bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if byHost {
+		fmt.Fprintln(w, "HOST\tPLATFORM\tDEFAULT\tJDKS")
+		for _, host := range report.Hosts {
+			var vs []string
+			for _, jdk := range host.JDKs {
+				vs = append(vs, jdk.Version)
+			}
+			def := host.Default
+			if def == "" {
+				def = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\n", host.Host, host.OS, host.Arch, def, strings.Join(vs, ", "))
+		}
+	} else {
+		fmt.Fprintln(w, "VERSION\tINSTALLED\tDEFAULT\tHOSTS")
+		for _, v := range report.Versions {
+			fmt.Fprintf(w, "%s\t%d/%d\t%d\t%s\n", v.Version, len(v.Installed), len(report.Hosts), len(v.Default),
+				strings.Join(v.Installed, ", "))
+		}
+	}
+	w.Flush()
+	for _, e := range report.Errors {
+		log.Error(e.Host, ": ", e.Error)
+	}
+}
+
+func printOutdated(jdks []command.OutdatedJDK) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tLATEST\tEND OF SUPPORT\tSTATUS")
+	for _, jdk := range jdks {
+		latest, eol := jdk.Latest, jdk.EOL
+		if latest == "" {
+			latest = "?"
+		}
+		if eol == "" {
+			eol = "?"
+		}
+		var status []string
+		if jdk.Superseded() {
+			status = append(status, "superseded")
+		}
+		if jdk.PastEOL {
+			status = append(status, "past end of support")
+		}
+		if len(status) == 0 {
+			status = append(status, "ok")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", jdk.Version, latest, eol, strings.Join(status, ", "))
+	}
+	w.Flush()
+	for _, jdk := range jdks {
+		if jdk.Problem != "" {
+			log.Warn(jdk.Version, ": ", jdk.Problem)
+		}
+	}
+}
+
+func printIndexFindings(findings []command.IndexFinding) {
+	errors := 0
+	for _, f := range findings {
+		path := f.Path
+		if path == "" {
+			path = "(index)"
+		}
+		fmt.Printf("%s\t%s\t%s: %s\n", f.Severity, f.Check, path, f.Message)
+		if f.Severity == "error" {
+			errors++
+		}
+	}
+	fmt.Printf("%d error(s), %d warning(s)\n", errors, len(findings)-errors)
+}
+
+// pickRemote lets user pick one of the remote versions matching args[0] (range or text (see `jabba ls-remote`)),
+// returning "" if user cancelled.
+func pickRemote(cmd *cobra.Command, args []string, opts command.InstallOptions) (string, error) {
+	var query string
+	if len(args) != 0 {
+		query = args[0]
+		if _, err := semver.ParseRange(query); err == nil {
+			query = qualify(cmd, query)
+		}
+	} else if vendor, _ := cmd.Flags().GetString("vendor"); vendor != "" {
+		query = vendor + "@"
+	}
+	items, err := command.RemotePickerItems(query, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", errors.New("No remote versions to pick from (see `jabba ls-remote`)")
+	}
+	return pick("Pick a version to install", items, "jabba install <version>")
+}
+
+// pickLocal lets user pick one of the installed JDKs matching args[0] (if any), returning "" if user cancelled.
+func pickLocal(args []string) (string, error) {
+	var query string
+	if len(args) != 0 {
+		query = args[0]
+	}
+	items, err := command.LocalPickerItems(query)
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "", errors.New("No installed JDKs to pick from (see `jabba install -i`)")
+	}
+	return pick("Pick a JDK to use", items, "jabba use <version>")
+}
+
+// pick shows the picker, listing items on stderr instead if there is no terminal.
+func pick(title string, items []command.PickerItem, usage string) (string, error) {
+	selector, err := command.Pick(title, items)
+	if err == command.ErrNotATerminal {
+		command.PrintPickerItems(os.Stderr, items)
+		return "", fmt.Errorf("-i requires a terminal (run `%s` with one of the versions listed above instead)",
+			usage)
+	}
+	return selector, err
+}
+
+// qualify applies --vendor (or default vendor) to the selector (see command.QualifySelector).
+func qualify(cmd *cobra.Command, selector string) string {
+	vendor, _ := cmd.Flags().GetString("vendor")
+	selector, err := command.QualifySelector(selector, vendor)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return selector
+}
+
+// channel returns release channel selected with --channel / --include-ea.
+func channel(cmd *cobra.Command) string {
+	if includeEA, _ := cmd.Flags().GetBool("include-ea"); includeEA {
+		return command.ChannelAll
+	}
+	value, _ := cmd.Flags().GetString("channel")
+	if err := command.ValidateChannel(value); err != nil {
+		log.Fatal(err)
+	}
+	return value
+}
+
+func use(ver string, profiles ...string) error {
+	change, err := command.Use(ver, profiles...)
 	if err != nil {
 		log.Fatal(err)
 	}
-	printForShellToEval(out)
+	printForShellToEval(change)
 	return nil
 }
 
-func printForShellToEval(out []string) {
+// held until jabba exits (referenced so that lock file wouldn't be closed (and so unlocked) by GC)
+var homeLock *flock.Lock
+
+// defaultEnvFormat returns `jabba env` format matching the environment jabba is running in.
+func defaultEnvFormat() string {
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		return "github-actions"
+	}
+	if strings.EqualFold(os.Getenv("TF_BUILD"), "true") {
+		return "azure"
+	}
+	shell := filepath.Base(os.Getenv("SHELL"))
+	for _, format := range command.EnvFormats {
+		if format == shell {
+			return shell
+		}
+	}
+	return "sh"
+}
+
+// defaultDedupeMode returns value of "dedupe" from config.yaml (JABBA_DEDUPE) ("auto" if not set).
+func defaultDedupeMode() string {
+	if mode := cfg.Dedupe(); mode != "" {
+		return mode
+	}
+	return "auto"
+}
+
+// lockHome acquires exclusive lock of jabba home (see command.LockHome).
+func lockHome() {
+	lock, err := command.LockHome()
+	if err != nil {
+		log.Fatal(err)
+	}
+	homeLock = lock
+}
+
+// applyHomeFlag points JABBA_HOME at --jabba-home (if given) for the duration of the command.
+func applyHomeFlag(cmd *cobra.Command) {
+	home, _ := cmd.Flags().GetString("jabba-home")
+	if home == "" {
+		return
+	}
+	path, err := command.ResolveHome(home)
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Setenv("JABBA_HOME", path)
+	cfg.Use(nil)
+}
+
+// printForShellToEval writes change to fd 3 (or --fd3 file) in the format of the shell `jabba` shell function
+// was generated for (passed in JABBA_SHELL_INTEGRATION).
+func printForShellToEval(change *command.EnvChange) {
+	script := change.Script(os.Getenv("JABBA_SHELL_INTEGRATION"))
 	fd3, _ := rootCmd.Flags().GetString("fd3")
 	if fd3 != "" {
-		ioutil.WriteFile(fd3, []byte(strings.Join(out, "\n")), 0666)
+		ioutil.WriteFile(fd3, []byte(script), 0666)
 	} else {
-		fd3 := os.NewFile(3, "fd3")
-		for _, line := range out {
-			fmt.Fprintln(fd3, line)
-		}
+		fmt.Fprintln(os.NewFile(3, "fd3"), script)
 	}
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+var logFormats = []string{"text", "json"}
+
+// configureLogging routes log entries to stderr (info and above, debug with -v, + timestamps & levels with -vv) and,
+// if file is specified, to that file (everything down to debug, whatever the verbosity).
+func configureLogging(verbosity int, format string, file string) error {
+	var formatter log.Formatter
+	switch format {
+	case "text":
+		formatter = &simpleFormatter{timestamps: verbosity > 1}
+	case "json":
+		formatter = &log.JSONFormatter{TimestampFormat: time.RFC3339Nano}
+	default:
+		return fmt.Errorf("Unsupported log format \"%s\" (expected text or json)", format)
+	}
+	level := log.InfoLevel
+	if verbosity > 0 {
+		level = log.DebugLevel
+	}
+	logger := log.StandardLogger()
+	logger.Hooks = make(log.LevelHooks)
+	logger.Out = ioutil.Discard
+	logger.Hooks.Add(&writerHook{w: os.Stderr, level: level, formatter: formatter})
+	if file != "" {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		fileFormatter := formatter
+		if format == "text" {
+			fileFormatter = &simpleFormatter{timestamps: true}
+		}
+		logger.Hooks.Add(&writerHook{w: f, level: log.DebugLevel, formatter: fileFormatter})
+		level = log.DebugLevel
+	}
+	log.SetLevel(level)
+	if file != "" {
+		log.Debug("Running ", strings.Join(os.Args, " "), " (jabba ", version, ")")
+	}
+	return nil
+}
+
+// verbosityValue is -v (-vv, ...) counter. It claims to be bool, which is what (this version of) cobra looks for to
+// tell that -v in `jabba -v install ...` doesn't take a value.
+type verbosityValue int
+
+func (v *verbosityValue) Set(value string) error {
+	switch value {
+	case "true":
+		*v++
+	case "false":
+		*v = 0
+	default:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*v = verbosityValue(n)
+	}
+	return nil
+}
+
+func (v *verbosityValue) String() string {
+	return strconv.Itoa(int(*v))
+}
+
+func (v *verbosityValue) Type() string {
+	return "bool"
+}
+
+func (v *verbosityValue) IsBoolFlag() bool {
+	return true
+}
+
+// writerHook writes entries of the level (and above) to w.
+type writerHook struct {
+	mu        sync.Mutex
+	w         io.Writer
+	level     log.Level
+	formatter log.Formatter
+}
+
+func (h *writerHook) Levels() []log.Level {
+	var levels []log.Level
+	for _, level := range []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel, log.InfoLevel,
+		log.DebugLevel} {
+		if level <= h.level {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+func (h *writerHook) Fire(entry *log.Entry) error {
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.w.Write(b)
+	return err
+}
+
+type simpleFormatter struct {
+	// true to prefix each line with time & level
+	timestamps bool
+}
+
+func (f *simpleFormatter) Format(entry *log.Entry) ([]byte, error) {
+	b := &bytes.Buffer{}
+	if f.timestamps {
+		fmt.Fprintf(b, "%s %-7s ", entry.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+			strings.ToUpper(entry.Level.String()))
+	}
+	fmt.Fprintf(b, "%s ", entry.Message)
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s=%+v ", k, entry.Data[k])
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jabba-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer configureLogging(0, "text", "")
+	file := filepath.Join(dir, "jabba.log")
+	if err := configureLogging(0, "json", file); err != nil {
+		t.Fatal(err)
+	}
+	log.Debug("resolving zulu@1.17")
+	log.WithField("url", "https://example.com").Info("downloading")
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("%s is not valid JSON (%v)", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	// debug messages end up in the log file whatever the verbosity
+	last := entries[len(entries)-1]
+	if len(entries) < 2 || entries[len(entries)-2]["msg"] != "resolving zulu@1.17" ||
+		last["msg"] != "downloading" || last["level"] != "info" || last["url"] != "https://example.com" {
+		t.Fatalf("unexpected %s content:\n%s", file, b)
+	}
+	if err := configureLogging(0, "xml", ""); err == nil {
+		t.Fatal("expected configureLogging to fail (unsupported format)")
+	}
+}
+
+func TestSimpleFormatter(t *testing.T) {
+	entry := log.WithFields(log.Fields{"b": 2, "a": 1})
+	entry.Message = "message"
+	for formatter, expected := range map[*simpleFormatter]string{
+		{}:                 "message a=1 b=2 \n",
+		{timestamps: true}: "WARNING message a=1 b=2 \n",
+	} {
+		entry.Level = log.WarnLevel
+		b, err := formatter.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(string(b), expected) || (formatter.timestamps && len(b) == len(expected)) {
+			t.Fatalf("actual: %q != expected: %q", string(b), expected)
+		}
+	}
+}
+
+func TestVerbosityValue(t *testing.T) {
+	var v verbosityValue
+	for _, value := range []string{"true", "true", "true"} {
+		if err := v.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v != 3 {
+		t.Fatalf("actual: %v != expected: %v", v, 3)
+	}
+	if err := v.Set("false"); err != nil || v != 0 {
+		t.Fatalf("actual: %v != expected: %v (%v)", v, 0, err)
+	}
+}
@@ -2,53 +2,337 @@ package semver
 
 import (
 	"fmt"
-	"github.com/Masterminds/semver"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
-var pre070CompatRegexp = regexp.MustCompile("(^|,\\s*)\\d+([.]\\d+)?[.]?")
-var pre070CompatRepl = func(input string) string {
-	if strings.HasSuffix(input, ".") {
-		return input
-	}
-	return input + ".x"
-}
-
-func pre070Compat(version string) string {
-	// 1.2 -> 1.2.x
-	s := pre070CompatRegexp.ReplaceAllStringFunc(version, pre070CompatRepl)
-	// >= 1.2 <= 2.4 -> >= 1.2, <= 2.4
-	split := regexp.MustCompile("\\s+").Split(s, -1)
-	for i, v := range split {
-		if i > 0 {
-			switch v[0] {
-			case '!', '=', '<', '>', '~', '^':
-				pv := split[i-1]
-				pvc := pv[len(pv)-1]
-				if pvc != '|' && pvc != ',' {
-					split[i-1] = pv + ","
-				}
+// [<operator>]<major>[.<minor>[.<patch>]][-<pre-release>][+<build metadata>], where any of the version
+// numbers can be "x", "X" or "*"
+var comparatorRegexp = regexp.MustCompile("^(<=|>=|=<|=>|!=|~>|<|>|=|~|\\^)?v?(\\d+|[xX*])" +
+	"(?:\\.(\\d+|[xX*]))?(?:\\.(\\d+|[xX*]))?" +
+	"(?:-([0-9A-Za-z-]+(?:\\.[0-9A-Za-z-]+)*))?(?:\\+[0-9A-Za-z-]+(?:\\.[0-9A-Za-z-]+)*)?$")
+
+var operatorRegexp = regexp.MustCompile("^(<=|>=|=<|=>|!=|~>|<|>|=|~|\\^)$")
+
+type comparator struct {
+	op  string // "<", "<=", ">", ">=", "=" or "!="
+	ver *version
+	// compare major.minor.patch only (upper bound of "1.8", "~1.8.2", "^1.8", etc. excludes 1.9.0-ea as well
+	// as 1.9.0-1, lower bound of "1.8-0" includes 1.8.0-ea)
+	tuple bool
+	// same as tuple but only when pre-releases are included (see ContainsPrerelease) (lower bound of a partial
+	// version (e.g. "1.22") includes 1.22.0-ea.5 then)
+	floor bool
+	// "!=<x-range>" (e.g. "!=1.8") - upper bound (exclusive, tuple only) of the excluded range
+	upper *version
+}
+
+func (c comparator) test(v *version, prerelease bool) bool {
+	var cmp int
+	if c.tuple || c.floor && prerelease {
+		cmp = compareTuples(v, c.ver)
+	} else {
+		cmp = compareVersions(v, c.ver)
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "!=":
+		if c.upper != nil {
+			return cmp < 0 || compareTuples(v, c.upper) >= 0
+		}
+		return cmp != 0
+	}
+	return cmp == 0
+}
+
+// comparatorSet is satisfied when all of the comparators are.
+type comparatorSet struct {
+	comparators []comparator
+	// whether any of the comparators (other than "!=") has a pre-release
+	pre bool
+}
+
+func (s comparatorSet) test(v *version, prerelease bool) bool {
+	// unlike node-semver, which only lets in pre-releases of the same major.minor.patch as the comparator,
+	// any pre-release is accepted as it's how index marks builds (i.e. "1.8-0" matches 1.8.0-252 and 1.8.1-3)
+	if len(v.pre) != 0 && !s.pre && !prerelease {
+		return false
+	}
+	for _, c := range s.comparators {
+		if !c.test(v, prerelease) {
+			return false
+		}
+	}
+	return true
+}
+
+// partial is a (possibly incomplete) version from the range (e.g. "1.8" in "~1.8", "1.x" or "*").
+type partial struct {
+	major, minor, patch int64
+	// number of version numbers specified (0 - "*", 1 - "1" or "1.x", 2 - "1.8" or "1.8.x", 3 - "1.8.0")
+	n   int
+	pre []string
+}
+
+func (p partial) version() *version {
+	return &version{major: p.major, minor: p.minor, patch: p.patch, pre: p.pre}
+}
+
+// next returns the lowest version above p (e.g. 1.8 -> 1.9.0, 1 -> 2.0.0)
+func (p partial) next() *version {
+	switch p.n {
+	case 1:
+		return &version{major: p.major + 1}
+	case 2:
+		return &version{major: p.major, minor: p.minor + 1}
+	}
+	return &version{major: p.major, minor: p.minor, patch: p.patch + 1}
+}
+
+func (p partial) lower() comparator {
+	// "-0" stands for the lowest pre-release (which includes 1.8.0-ea, as it precedes 1.8.0-0)
+	tuple := len(p.pre) == 1 && p.pre[0] == "0"
+	return comparator{op: ">=", ver: p.version(), tuple: tuple, floor: p.n < 3 && len(p.pre) == 0}
+}
+
+func parsePartial(m []string) (partial, error) {
+	var p partial
+	for i, n := range []*int64{&p.major, &p.minor, &p.patch} {
+		s := m[i]
+		if s == "" || s == "x" || s == "X" || s == "*" {
+			// 1.x.3 is the same as 1.x
+			break
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return p, err
+		}
+		*n = v
+		p.n++
+	}
+	if m[3] != "" {
+		p.pre = strings.Split(m[3], ".")
+	}
+	return p, nil
+}
+
+// parseComparator desugars x-ranges ("1.8", "1.8.x"), "~", "^" and partial versions in "<", "<=", ">", ">=" &
+// "!=" the same way node-semver does (e.g. "^1.8" -> ">=1.8.0 <2.0.0", "<=1.8" -> "<1.9.0").
+func parseComparator(value string) ([]comparator, error) {
+	m := comparatorRegexp.FindStringSubmatch(value)
+	if m == nil {
+		return nil, fmt.Errorf("\"%s\" is not a valid version (or range)", value)
+	}
+	p, err := parsePartial(m[2:6])
+	if err != nil {
+		return nil, fmt.Errorf("\"%s\" is not a valid version (or range)", value)
+	}
+	none := []comparator{{op: "<", ver: &version{}, tuple: true}}
+	switch m[1] {
+	case "", "=":
+		if p.n == 0 {
+			return nil, nil
+		}
+		if p.n == 3 {
+			return []comparator{{op: "=", ver: p.version()}}, nil
+		}
+		return []comparator{p.lower(), {op: "<", ver: p.next(), tuple: true}}, nil
+	case "!=":
+		if p.n == 0 {
+			return none, nil
+		}
+		if p.n == 3 {
+			return []comparator{{op: "!=", ver: p.version()}}, nil
+		}
+		return []comparator{{op: "!=", ver: p.version(), tuple: true, upper: p.next()}}, nil
+	case ">=", "=>":
+		return []comparator{p.lower()}, nil
+	case ">":
+		if p.n == 0 {
+			return none, nil
+		}
+		if p.n == 3 {
+			return []comparator{{op: ">", ver: p.version()}}, nil
+		}
+		return []comparator{{op: ">=", ver: p.next(), tuple: true}}, nil
+	case "<":
+		if p.n == 0 {
+			return none, nil
+		}
+		if p.n == 3 && !(len(p.pre) == 1 && p.pre[0] == "0") {
+			return []comparator{{op: "<", ver: p.version()}}, nil
+		}
+		return []comparator{{op: "<", ver: p.version(), tuple: true}}, nil
+	case "<=", "=<":
+		if p.n == 0 {
+			return nil, nil
+		}
+		if p.n == 3 {
+			return []comparator{{op: "<=", ver: p.version()}}, nil
+		}
+		return []comparator{{op: "<", ver: p.next(), tuple: true}}, nil
+	case "~", "~>":
+		if p.n == 0 {
+			return nil, nil
+		}
+		upper := &version{major: p.major, minor: p.minor + 1}
+		if p.n == 1 {
+			upper = &version{major: p.major + 1}
+		}
+		return []comparator{p.lower(), {op: "<", ver: upper, tuple: true}}, nil
+	case "^":
+		if p.n == 0 {
+			return nil, nil
+		}
+		// the left-most non-zero number (among the specified ones) must not change
+		var upper *version
+		switch {
+		case p.major != 0 || p.n == 1:
+			upper = &version{major: p.major + 1}
+		case p.minor != 0 || p.n == 2:
+			upper = &version{minor: p.minor + 1}
+		default:
+			upper = &version{patch: p.patch + 1}
+		}
+		return []comparator{p.lower(), {op: "<", ver: upper, tuple: true}}, nil
+	}
+	return nil, fmt.Errorf("\"%s\" is not a valid version (or range)", value)
+}
+
+// parseHyphenRange parses "<from> - <to>" (inclusive on both ends; partial <to> includes everything up to the
+// next version (e.g. "1.8 - 1.11" -> ">=1.8.0 <1.12.0")).
+func parseHyphenRange(from, to string) ([]comparator, error) {
+	fm := comparatorRegexp.FindStringSubmatch(from)
+	tm := comparatorRegexp.FindStringSubmatch(to)
+	if fm == nil || tm == nil || fm[1] != "" || tm[1] != "" {
+		return nil, fmt.Errorf("\"%s - %s\" is not a valid range", from, to)
+	}
+	f, err := parsePartial(fm[2:6])
+	if err != nil {
+		return nil, fmt.Errorf("\"%s - %s\" is not a valid range", from, to)
+	}
+	t, err := parsePartial(tm[2:6])
+	if err != nil {
+		return nil, fmt.Errorf("\"%s - %s\" is not a valid range", from, to)
+	}
+	var r []comparator
+	if f.n != 0 {
+		r = append(r, f.lower())
+	}
+	switch {
+	case t.n == 3:
+		r = append(r, comparator{op: "<=", ver: t.version()})
+	case t.n != 0:
+		r = append(r, comparator{op: "<", ver: t.next(), tuple: true})
+	}
+	return r, nil
+}
+
+// parseComparatorSet parses space (or comma) separated comparators (e.g. ">=1.8 <1.11", ">= 1.8, < 1.11").
+func parseComparatorSet(raw string) (comparatorSet, error) {
+	var s comparatorSet
+	var tokens []string
+	for _, v := range strings.Fields(strings.Replace(raw, ",", " ", -1)) {
+		if n := len(tokens); n != 0 && operatorRegexp.MatchString(tokens[n-1]) {
+			// ">= 1.8" -> ">=1.8"
+			tokens[n-1] += v
+			continue
+		}
+		tokens = append(tokens, v)
+	}
+	for i := 0; i < len(tokens); i++ {
+		var comparators []comparator
+		var err error
+		if i+2 < len(tokens) && tokens[i+1] == "-" {
+			comparators, err = parseHyphenRange(tokens[i], tokens[i+2])
+			i += 2
+		} else {
+			comparators, err = parseComparator(tokens[i])
+		}
+		if err != nil {
+			return s, err
+		}
+		for _, c := range comparators {
+			if c.op != "!=" && len(c.ver.pre) != 0 {
+				s.pre = true
 			}
 		}
+		s.comparators = append(s.comparators, comparators...)
 	}
-	return strings.Join(split, " ")
+	return s, nil
 }
 
 type Range struct {
 	qualifier string
 	raw       string
-	rng       *semver.Constraints
+	// "||"-separated comparator sets (any of which has to be satisfied)
+	sets []comparatorSet
+	// "!<version or range>"s (e.g. "1.17.x !1.17.0-8"), which take precedence over sets
+	exclusions []*Range
 }
 
 func (l *Range) Contains(r *Version) bool {
-	return (l.qualifier == r.qualifier || l.qualifier == "*") && l.rng.Check(r.ver)
+	return l.containsWith(r, false)
+}
+
+// ContainsPrerelease is the same as Contains except that pre-release versions are matched even if range doesn't
+// opt into them (node-semver's includePrerelease) (e.g. "1.22" contains 1.22.0-ea.5).
+func (l *Range) ContainsPrerelease(r *Version) bool {
+	return l.containsWith(r, true)
+}
+
+func (l *Range) containsWith(r *Version, prerelease bool) bool {
+	if !l.contains(r, prerelease) {
+		return false
+	}
+	for _, e := range l.exclusions {
+		// !1.17.0-8 excludes 1.17.0-8 as well as its builds (e.g. 1.17.0-8.1)
+		if e.contains(r, prerelease) ||
+			(e.qualifies(r) && strings.HasPrefix(unqualified(r.raw), unqualified(e.raw)+".")) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Range) contains(r *Version, prerelease bool) bool {
+	if !l.qualifies(r) {
+		return false
+	}
+	for _, s := range l.sets {
+		if s.test(r.ver, prerelease) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Range) qualifies(r *Version) bool {
+	return l.qualifier == r.qualifier || l.qualifier == "*"
+}
+
+// "<qualifier>@<version>" -> "<version>"
+func unqualified(raw string) string {
+	return raw[strings.Index(raw, "@")+1:]
 }
 
 func (t *Range) String() string {
 	return t.raw
 }
 
+// ParseRange parses [<qualifier>@]<range>, where range follows node-semver syntax - "1.8" (same as "1.8.x"),
+// "~1.8.2", "^1.8", ">=1.8 <1.11", "1.8 - 1.11", "1.8 || 1.11", etc. (optionally followed by the exclusions
+// ("!<version or range>")).
+// Pre-release versions (which is what builds (e.g. 1.8.0-252) are in the index) are matched only if
+// range opts into them (e.g. "1.8-0").
 func ParseRange(raw string) (*Range, error) {
 	p := new(Range)
 	p.raw = raw
@@ -56,16 +340,41 @@ func ParseRange(raw string) (*Range, error) {
 	if strings.Contains(raw, "@") {
 		p.qualifier = raw[0:strings.Index(raw, "@")]
 		raw = raw[strings.Index(raw, "@")+1:]
-		if raw == "" {
-			// `jabba ls-remote zulu@` convenience
-			raw = ">=0.0.0-0"
+	}
+	var include []string
+	for _, v := range strings.Fields(raw) {
+		if !strings.HasPrefix(v, "!") || strings.HasPrefix(v, "!=") {
+			include = append(include, v)
+			continue
+		}
+		exclusion := v[1:]
+		if exclusion == "" || strings.Contains(exclusion, "@") {
+			return nil, fmt.Errorf("%s is not a valid version (\"%s\" is not a valid exclusion)", p.raw, v)
+		}
+		if p.qualifier != "" {
+			// exclusions inherit qualifier of the range
+			exclusion = p.qualifier + "@" + exclusion
 		}
+		e, err := ParseRange(exclusion)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid version (\"%s\" is not a valid exclusion)", p.raw, v)
+		}
+		p.exclusions = append(p.exclusions, e)
 	}
-	constraint := pre070Compat(raw)
-	parsed, err := semver.NewConstraint(constraint)
-	if err != nil {
+	raw = strings.Join(include, " ")
+	if raw == "" && (p.qualifier != "" || len(p.exclusions) != 0) {
+		// `jabba ls-remote zulu@` convenience (same goes for "!<version>" (i.e. anything but <version>))
+		raw = ">=0.0.0-0"
+	}
+	if strings.TrimSpace(raw) == "" {
 		return nil, fmt.Errorf("%s is not a valid version", p.raw)
 	}
-	p.rng = parsed
+	for _, v := range strings.Split(raw, "||") {
+		s, err := parseComparatorSet(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid version (%s)", p.raw, err)
+		}
+		p.sets = append(p.sets, s)
+	}
 	return p, nil
 }
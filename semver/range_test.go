@@ -21,11 +21,76 @@ func TestContains(t *testing.T) {
 	assertWithinRange(t, "a@>=1.7 <=1.8.75", "a@1.8.80", false)
 }
 
-func TestPre070Compat(t *testing.T) {
-	actual := pre070Compat("1.1, >= 1.2 <= 1.3.0, 4, 1.5,1.6.0, ~1.2")
-	expected := "1.1.x, >= 1.2, <= 1.3.0, 4.x, 1.5.x,1.6.0, ~1.2"
-	if actual != expected {
-		t.Fatalf("actual: %#v != expected: %#v", actual, expected)
+func TestContainsPre070Compat(t *testing.T) {
+	// "1.2" used to mean "1.2.x" (which is how partial versions are treated by node-semver too)
+	assertWithinRange(t, "1.1, 1.5", "1.1.9", false)
+	assertWithinRange(t, ">= 1.2 <= 1.3.0", "1.2.9", true)
+	assertWithinRange(t, ">= 1.2, <= 1.3.0", "1.3.0", true)
+	assertWithinRange(t, ">= 1.2, <= 1.3.0", "1.3.1", false)
+	assertWithinRange(t, "4", "4.1.2", true)
+}
+
+func TestContainsNodeSemver(t *testing.T) {
+	for _, c := range []struct {
+		rng   string
+		ver   string
+		value bool
+	}{
+		{"^1.8", "1.9.1", true},
+		{"^1.8", "2.0.0", false},
+		{"^1.8", "1.7.9", false},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.0.3", "0.0.4", false},
+		{"~17.0.2", "17.0.9", true},
+		{"~17.0.2", "17.0.1", false},
+		{"~17.0.2", "17.1.0", false},
+		{"~>1.8", "1.8.3", true},
+		{"17.x", "17.0.2", true},
+		{"17.x", "18.0.0", false},
+		{"1.X.3", "1.9.0", true},
+		{"*", "1.17.0", true},
+		{"v1.8", "1.8.1", true},
+		{"=1.8", "1.8.1", true},
+		{">1.8", "1.8.5", false},
+		{">1.8", "1.9.0", true},
+		{">=1.8", "1.8.0", true},
+		{"<1.9", "1.8.99", true},
+		{"<1.9", "1.9.0", false},
+		{"<=1.8", "1.8.5", true},
+		{"<=1.8", "1.9.0", false},
+		{"<=1.8.5", "1.8.5", true},
+		{"!=1.8", "1.8.5", false},
+		{"!=1.8", "1.9.0", true},
+		{"1.8 - 1.11", "1.11.4", true},
+		{"1.8 - 1.11", "1.12.0", false},
+		{"1.8 - 1.11", "1.7.9", false},
+		{"1.8.2 - 1.11.0", "1.11.1", false},
+		{"1.8 || 1.11", "1.11.4", true},
+		{"1.8 || 1.11", "1.10.0", false},
+		{">=1.8 <1.9 || >=1.11", "1.17.0", true},
+		// pre-releases are matched only if range opts into them
+		{"1.17", "1.17.0-ea.12", false},
+		{"^1.17", "1.17.1-8", false},
+		{"1.17-0", "1.17.0-ea.12", true},
+		{"1.17-0", "1.17.1-8", true},
+		{"^1.17.0-ea", "1.17.0-ea.1", true},
+		{"^1.17.0-ea", "1.17.0-8", true},
+		{"^1.17.0-ea", "2.0.0-ea.1", false},
+		{"1.17.0-rc.1", "1.17.0-rc.1", true},
+		{">=1.17.0-rc.1", "1.17.0-ea.9", false},
+		{"<1.18-0", "1.18.0-ea.1", false},
+		{"zulu@", "zulu@1.18.0-ea.1", true},
+		// build metadata is ignored
+		{"1.17.0-ea.2", "1.17.0-ea.2+b5", true},
+		{"1.17.0+b1", "1.17.0+b2", true},
+	} {
+		assertWithinRange(t, c.rng, c.ver, c.value)
+	}
+	for _, rng := range []string{"", ">=", "1.8 -", "1.8.0.1", "^a", "1.8 ||| 1.9"} {
+		if _, err := ParseRange(rng); err == nil {
+			t.Fatalf("expected %q to be rejected", rng)
+		}
 	}
 }
 
@@ -42,3 +107,55 @@ func assertWithinRange(t *testing.T, rng string, ver string, value bool) {
 		t.Fatalf("expected range %v to contain %v (%v)", rng, ver, value)
 	}
 }
+
+func TestContainsWithExclusions(t *testing.T) {
+	assertWithinRange(t, "1.17.x !1.17.0-8", "1.17.1", true)
+	assertWithinRange(t, ">=1.17.0-0 !1.17.0-8", "1.17.0-7", true)
+	assertWithinRange(t, ">=1.17.0-0 !1.17.0-8", "1.17.0-8", false)
+	// builds of the excluded version are excluded too
+	assertWithinRange(t, ">=1.17.0-0 !1.17.0-8", "1.17.0-8.1", false)
+	assertWithinRange(t, ">=1.17.0-0 !1.17.0-8", "1.17.0-80", true)
+	// exclusions take precedence (regardless of position)
+	assertWithinRange(t, "!1.17.0-8 >=1.17.0-0", "1.17.0-8", false)
+	assertWithinRange(t, ">=1.17.0-0 !~1.17.1", "1.17.1", false)
+	assertWithinRange(t, ">=1.17.0-0 !~1.17.1", "1.17.2", false)
+	assertWithinRange(t, ">=1.17.0-0 !1.17.1 !1.17.2", "1.17.3", true)
+	assertWithinRange(t, ">=1.17.0-0 !=1.17.3", "1.17.3", false)
+	// exclusions inherit qualifier
+	assertWithinRange(t, "temurin@>=1.17.0-0 !1.17.0-8", "temurin@1.17.0-8.1", false)
+	assertWithinRange(t, "temurin@>=1.17.0-0 !1.17.0-8", "temurin@1.17.0-9", true)
+	assertWithinRange(t, "*@>=1.17.0-0 !1.17.0-8", "zulu@1.17.0-8", false)
+	assertWithinRange(t, "*@>=1.17.0-0 !1.17.0-8", "zulu@1.17.0-9", true)
+	// anything but
+	assertWithinRange(t, "zulu@!1.17.0-8", "zulu@1.11.0-1", true)
+	for _, rng := range []string{"1.17.x !", "temurin@1.17.x !zulu@1.17.1", "1.17.x !foo"} {
+		if _, err := ParseRange(rng); err == nil {
+			t.Fatalf("expected %s to be rejected", rng)
+		}
+	}
+}
+
+func TestContainsPrerelease(t *testing.T) {
+	for _, c := range []struct {
+		rng   string
+		ver   string
+		value bool
+	}{
+		{"openjdk@1.22", "openjdk@1.22.0-ea.5", true},
+		{"openjdk@1.22", "openjdk@1.23.0-ea.1", false},
+		{"openjdk@^1.22", "openjdk@1.23.0-ea.1", true},
+		{"openjdk@1.22 !1.22.0-ea.5", "openjdk@1.22.0-ea.5", false},
+	} {
+		r, err := ParseRange(c.rng)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		v, err := ParseVersion(c.ver)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if r.ContainsPrerelease(v) != c.value {
+			t.Fatalf("expected range %v to contain %v (%v)", c.rng, c.ver, c.value)
+		}
+	}
+}
@@ -2,19 +2,133 @@ package semver
 
 import (
 	"fmt"
-	"github.com/Masterminds/semver"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
+var versionRegexp = regexp.MustCompile("^v?(\\d+)(?:\\.(\\d+))?(?:\\.(\\d+))?" +
+	"(?:-([0-9A-Za-z-]+(?:\\.[0-9A-Za-z-]+)*))?(?:\\+([0-9A-Za-z-]+(?:\\.[0-9A-Za-z-]+)*))?$")
+
+// version is a parsed <major>[.<minor>[.<patch>]][-<pre-release>][+<build metadata>].
+type version struct {
+	major, minor, patch int64
+	pre                 []string
+	metadata            string
+}
+
+func parseVersion(raw string) (*version, error) {
+	m := versionRegexp.FindStringSubmatch(raw)
+	if m == nil {
+		return nil, fmt.Errorf("%s is not a valid version", raw)
+	}
+	v := &version{metadata: m[5]}
+	for i, p := range []*int64{&v.major, &v.minor, &v.patch} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a valid version", raw)
+		}
+		*p = n
+	}
+	if m[4] != "" {
+		v.pre = strings.Split(m[4], ".")
+	}
+	return v, nil
+}
+
+// compareVersions follows semver 2.0.0 precedence rules (build metadata is ignored) except that
+// pre-release starting with an alphanumeric identifier (e.g. 1.17.0-ea.12, 1.17.0-rc.1) precedes the one
+// starting with a numeric identifier, as the latter is how index marks GA builds (e.g. 1.17.0-8).
+func compareVersions(l, r *version) int {
+	if c := compareTuples(l, r); c != 0 {
+		return c
+	}
+	switch {
+	case len(l.pre) == 0 && len(r.pre) == 0:
+		return 0
+	case len(l.pre) == 0:
+		return 1
+	case len(r.pre) == 0:
+		return -1
+	}
+	if ln, rn := isNumeric(l.pre[0]), isNumeric(r.pre[0]); ln != rn {
+		if ln {
+			return 1
+		}
+		return -1
+	}
+	for i := 0; i < len(l.pre) && i < len(r.pre); i++ {
+		if c := compareIdentifiers(l.pre[i], r.pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(int64(len(l.pre)), int64(len(r.pre)))
+}
+
+// compareTuples compares major.minor.patch only.
+func compareTuples(l, r *version) int {
+	if c := compareInts(l.major, r.major); c != 0 {
+		return c
+	}
+	if c := compareInts(l.minor, r.minor); c != 0 {
+		return c
+	}
+	return compareInts(l.patch, r.patch)
+}
+
+func compareInts(l, r int64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+// numeric identifiers always have lower precedence than alphanumeric ones
+func compareIdentifiers(l, r string) int {
+	ln, rn := isNumeric(l), isNumeric(r)
+	switch {
+	case ln && rn:
+		// no leading zeros (per spec) & arbitrary length
+		l, r = strings.TrimLeft(l, "0"), strings.TrimLeft(r, "0")
+		if c := compareInts(int64(len(l)), int64(len(r))); c != 0 {
+			return c
+		}
+	case ln:
+		return -1
+	case rn:
+		return 1
+	}
+	return strings.Compare(l, r)
+}
+
+func isNumeric(identifier string) bool {
+	for _, c := range identifier {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return identifier != ""
+}
+
 type Version struct {
 	qualifier string
 	raw       string
-	ver       *semver.Version
+	ver       *version
 }
 
 func (l *Version) LessThan(r *Version) bool {
 	if l.qualifier == r.qualifier {
-		return l.ver.LessThan(r.ver)
+		if c := compareVersions(l.ver, r.ver); c != 0 {
+			return c < 0
+		}
+		// same precedence, build metadata only makes the order stable
+		return l.ver.metadata < r.ver.metadata
 	}
 	return l.qualifier > r.qualifier
 }
@@ -34,29 +148,39 @@ func (t *Version) TrimTo(part VersionPart) string {
 	}
 	switch part {
 	case VPMajor:
-		return fmt.Sprintf("%v%v", prefix, t.ver.Major())
+		return fmt.Sprintf("%v%v", prefix, t.ver.major)
 	case VPMinor:
-		return fmt.Sprintf("%v%v.%v", prefix, t.ver.Major(), t.ver.Minor())
+		return fmt.Sprintf("%v%v.%v", prefix, t.ver.major, t.ver.minor)
 	case VPPatch:
-		return fmt.Sprintf("%v%v.%v.%v", prefix, t.ver.Major(), t.ver.Minor(), t.ver.Patch())
+		return fmt.Sprintf("%v%v.%v.%v", prefix, t.ver.major, t.ver.minor, t.ver.patch)
 	}
 	return t.raw
 }
 
 func (t *Version) Major() int64 {
-	return t.ver.Major()
+	return t.ver.major
 }
 
 func (t *Version) Minor() int64 {
-	return t.ver.Minor()
+	return t.ver.minor
 }
 
 func (t *Version) Patch() int64 {
-	return t.ver.Patch()
+	return t.ver.patch
 }
 
 func (t *Version) Prerelease() string {
-	return t.ver.Prerelease()
+	return strings.Join(t.ver.pre, ".")
+}
+
+// Metadata returns "<build metadata>" part of "<version>+<build metadata>" (which doesn't affect precedence).
+func (t *Version) Metadata() string {
+	return t.ver.metadata
+}
+
+// Qualifier returns "<qualifier>" part of "<qualifier>@<version>" ("" if version is not qualified).
+func (t *Version) Qualifier() string {
+	return t.qualifier
 }
 
 func ParseVersion(raw string) (*Version, error) {
@@ -67,9 +191,9 @@ func ParseVersion(raw string) (*Version, error) {
 		p.qualifier = raw[0:strings.Index(raw, "@")]
 		raw = raw[strings.Index(raw, "@")+1:]
 	}
-	parsed, err := semver.NewVersion(raw)
+	parsed, err := parseVersion(raw)
 	if err != nil {
-		return nil, fmt.Errorf("%s is not a valid version", raw)
+		return nil, err
 	}
 	p.ver = parsed
 	return p, nil
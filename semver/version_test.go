@@ -27,3 +27,28 @@ func asVersionSlice(t *testing.T, slice ...string) (r []*Version) {
 	}
 	return
 }
+
+func TestSortPrerelease(t *testing.T) {
+	actual := asVersionSlice(t,
+		"1.17.0", "1.17.0-8", "1.17.0-ea.12", "1.17.0-rc.1", "1.17.0-ea.2", "1.17.0-0", "1.17.0-ea", "1.17.0-8.1",
+		"1.16.0-10", "1.17.0-ea.beta", "1.17.0-beta.11", "1.17.0-beta.2")
+	sort.Sort(VersionSlice(actual))
+	expected := asVersionSlice(t,
+		"1.16.0-10", "1.17.0-beta.2", "1.17.0-beta.11", "1.17.0-ea", "1.17.0-ea.2", "1.17.0-ea.12", "1.17.0-ea.beta",
+		"1.17.0-rc.1", "1.17.0-0", "1.17.0-8", "1.17.0-8.1", "1.17.0")
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("actual: %v != expected: %v", actual, expected)
+	}
+}
+
+func TestBuildMetadata(t *testing.T) {
+	v := asVersionSlice(t, "1.17.0-8+b2", "1.17.0-8+b1", "1.17.0-8")
+	if v[0].Metadata() != "b2" || v[0].Prerelease() != "8" {
+		t.Fatalf("actual: %v != expected: %v", v[0].Metadata()+" "+v[0].Prerelease(), "b2 8")
+	}
+	sort.Sort(VersionSlice(v))
+	expected := asVersionSlice(t, "1.17.0-8", "1.17.0-8+b1", "1.17.0-8+b2")
+	if !reflect.DeepEqual(v, expected) {
+		t.Fatalf("actual: %v != expected: %v", v, expected)
+	}
+}
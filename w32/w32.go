@@ -53,3 +53,24 @@ type SHELLEXECUTEINFO struct {
 	hIconOrMonitor HANDLE
 	hProcess       HANDLE
 }
+
+// https://docs.microsoft.com/en-us/windows/win32/api/fileapi/nf-fileapi-lockfileex
+const (
+	LOCKFILE_FAIL_IMMEDIATELY = 0x00000001
+	LOCKFILE_EXCLUSIVE_LOCK   = 0x00000002
+)
+
+type OVERLAPPED struct {
+	Internal     uintptr
+	InternalHigh uintptr
+	Offset       DWORD
+	OffsetHigh   DWORD
+	HEvent       HANDLE
+}
+
+// https://docs.microsoft.com/en-us/windows/win32/sysinfo/image-file-machine-constants
+const (
+	IMAGE_FILE_MACHINE_I386  = 0x014c
+	IMAGE_FILE_MACHINE_AMD64 = 0x8664
+	IMAGE_FILE_MACHINE_ARM64 = 0xAA64
+)
@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package w32
+
+func ShellExecuteAndWait(hwnd HWND, lpOperation, lpFile, lpParameters, lpDirectory string, nShowCmd int) error {
+	panic("Unsupported OS")
+}
+
+func ShellExecuteEx(pExecInfo *SHELLEXECUTEINFO) error {
+	panic("Unsupported OS")
+}
+
+func LockFileEx(hFile HANDLE, dwFlags DWORD) error {
+	panic("Unsupported OS")
+}
+
+func UnlockFileEx(hFile HANDLE) error {
+	panic("Unsupported OS")
+}
+
+func IsWow64Process2(hProcess HANDLE) (processMachine uint16, nativeMachine uint16, err error) {
+	panic("Unsupported OS")
+}
+
+func GetDiskFreeSpaceEx(dir string) (uint64, error) {
+	panic("Unsupported OS")
+}
@@ -12,6 +12,15 @@ var (
 	modshell32 = syscall.NewLazyDLL("shell32.dll")
 	// https://msdn.microsoft.com/en-us/library/windows/desktop/bb762154(v=vs.85).aspx
 	procShellExecuteEx = modshell32.NewProc("ShellExecuteExW")
+	modkernel32        = syscall.NewLazyDLL("kernel32.dll")
+	// https://docs.microsoft.com/en-us/windows/win32/api/fileapi/nf-fileapi-lockfileex
+	procLockFileEx = modkernel32.NewProc("LockFileEx")
+	// https://docs.microsoft.com/en-us/windows/win32/api/fileapi/nf-fileapi-unlockfileex
+	procUnlockFileEx = modkernel32.NewProc("UnlockFileEx")
+	// https://docs.microsoft.com/en-us/windows/win32/api/wow64apiset/nf-wow64apiset-iswow64process2
+	procIsWow64Process2 = modkernel32.NewProc("IsWow64Process2")
+	// https://docs.microsoft.com/en-us/windows/win32/api/fileapi/nf-fileapi-getdiskfreespaceexw
+	procGetDiskFreeSpaceEx = modkernel32.NewProc("GetDiskFreeSpaceExW")
 )
 
 // some of the code below was borrowed from
@@ -89,3 +98,51 @@ func ShellExecuteEx(pExecInfo *SHELLEXECUTEINFO) error {
 	}
 	return errors.New(errorMsg)
 }
+
+// LockFileEx locks the whole file (blocking unless LOCKFILE_FAIL_IMMEDIATELY is set).
+func LockFileEx(hFile HANDLE, dwFlags DWORD) error {
+	ol := new(OVERLAPPED)
+	ret, _, e := procLockFileEx.Call(uintptr(hFile), uintptr(dwFlags), 0, 0xFFFFFFFF, 0xFFFFFFFF,
+		uintptr(unsafe.Pointer(ol)))
+	if ret == 0 {
+		return os.NewSyscallError("LockFileEx", e)
+	}
+	return nil
+}
+
+func UnlockFileEx(hFile HANDLE) error {
+	ol := new(OVERLAPPED)
+	ret, _, e := procUnlockFileEx.Call(uintptr(hFile), 0, 0xFFFFFFFF, 0xFFFFFFFF, uintptr(unsafe.Pointer(ol)))
+	if ret == 0 {
+		return os.NewSyscallError("UnlockFileEx", e)
+	}
+	return nil
+}
+
+// IsWow64Process2 returns architecture (IMAGE_FILE_MACHINE_*) of the process (0 if it's not running under WOW64)
+// and of the machine (available since Windows 10 1709).
+func IsWow64Process2(hProcess HANDLE) (processMachine uint16, nativeMachine uint16, err error) {
+	if err = procIsWow64Process2.Find(); err != nil {
+		return
+	}
+	ret, _, e := procIsWow64Process2.Call(uintptr(hProcess), uintptr(unsafe.Pointer(&processMachine)),
+		uintptr(unsafe.Pointer(&nativeMachine)))
+	if ret == 0 {
+		err = os.NewSyscallError("IsWow64Process2", e)
+	}
+	return
+}
+
+// GetDiskFreeSpaceEx returns number of bytes available to the calling user on the volume dir is on.
+func GetDiskFreeSpaceEx(dir string) (uint64, error) {
+	p, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	ret, _, e := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&available)), 0, 0)
+	if ret == 0 {
+		return 0, os.NewSyscallError("GetDiskFreeSpaceExW", e)
+	}
+	return available, nil
+}